@@ -1,7 +1,10 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"io"
+	"net/http"
 	"os"
 	"strconv"
 	"strings"
@@ -9,10 +12,13 @@ import (
 
 	"github.com/dustin/go-humanize"
 	"github.com/olekukonko/tablewriter"
+	"github.com/twitchtv/twirp"
 	"github.com/urfave/cli/v2"
-	"gopkg.in/yaml.v3"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
 
 	"github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
 	"github.com/livekit/protocol/utils"
 
 	"github.com/livekit/livekit-server/pkg/config"
@@ -88,34 +94,9 @@ func createToken(c *cli.Context) error {
 	}
 
 	// use the first API key from config
-	if len(conf.Keys) == 0 {
-		// try to load from file
-		if _, err := os.Stat(conf.KeyFile); err != nil {
-			return err
-		}
-		f, err := os.Open(conf.KeyFile)
-		if err != nil {
-			return err
-		}
-		defer func() {
-			_ = f.Close()
-		}()
-		decoder := yaml.NewDecoder(f)
-		if err = decoder.Decode(conf.Keys); err != nil {
-			return err
-		}
-
-		if len(conf.Keys) == 0 {
-			return fmt.Errorf("keys are not configured")
-		}
-	}
-
-	var apiKey string
-	var apiSecret string
-	for k, v := range conf.Keys {
-		apiKey = k
-		apiSecret = v
-		break
+	apiKey, apiSecret, err := getAPIKey(conf, "")
+	if err != nil {
+		return err
 	}
 
 	grant := &auth.VideoGrant{
@@ -144,6 +125,29 @@ func createToken(c *cli.Context) error {
 	return nil
 }
 
+// getAPIKey returns the secret of apiKey, or the first configured key when apiKey is empty
+func getAPIKey(conf *config.Config, apiKey string) (string, string, error) {
+	keyProvider, err := service.NewKeyProvider(conf)
+	if err != nil {
+		return "", "", err
+	}
+	defer keyProvider.Stop()
+
+	if apiKey != "" {
+		apiSecret := keyProvider.GetSecret(apiKey)
+		if apiSecret == "" {
+			return "", "", fmt.Errorf("API key %s is %s", apiKey, keyProvider.GetKeyStatus(apiKey))
+		}
+		return apiKey, apiSecret, nil
+	}
+
+	apiKey, apiSecret := keyProvider.GetActiveKey()
+	if apiKey == "" {
+		return "", "", fmt.Errorf("no active keys are configured")
+	}
+	return apiKey, apiSecret, nil
+}
+
 func listNodes(c *cli.Context) error {
 	conf, err := getConfig(c)
 	if err != nil {
@@ -227,3 +231,381 @@ func listNodes(c *cli.Context) error {
 
 	return nil
 }
+
+// adminClient talks to the Twirp services of a running server, signing requests with a configured key
+type adminClient struct {
+	url       string
+	apiKey    string
+	apiSecret string
+	json      bool
+	// where results are printed, the writer of the app
+	out io.Writer
+}
+
+func newAdminClient(c *cli.Context) (*adminClient, error) {
+	conf, err := getConfig(c)
+	if err != nil {
+		return nil, err
+	}
+
+	apiKey, apiSecret, err := getAPIKey(conf, c.String("api-key"))
+	if err != nil {
+		return nil, err
+	}
+
+	url := c.String("url")
+	if url == "" {
+		url = fmt.Sprintf("http://localhost:%d", conf.Port)
+	}
+
+	return &adminClient{
+		url:       strings.TrimSuffix(url, "/"),
+		apiKey:    apiKey,
+		apiSecret: apiSecret,
+		json:      c.Bool("json"),
+		out:       c.App.Writer,
+	}, nil
+}
+
+// context returns a context with an authorization header carrying grant
+func (a *adminClient) context(c *cli.Context, grant *auth.VideoGrant) (context.Context, error) {
+	token, err := auth.NewAccessToken(a.apiKey, a.apiSecret).
+		AddGrant(grant).
+		SetValidFor(time.Minute).
+		ToJWT()
+	if err != nil {
+		return nil, err
+	}
+
+	header := make(http.Header)
+	header.Set("Authorization", "Bearer "+token)
+	return twirp.WithHTTPRequestHeaders(c.Context, header)
+}
+
+func (a *adminClient) roomService() livekit.RoomService {
+	return livekit.NewRoomServiceProtobufClient(a.url, &http.Client{})
+}
+
+func (a *adminClient) egressService() livekit.Egress {
+	return livekit.NewEgressProtobufClient(a.url, &http.Client{})
+}
+
+func (a *adminClient) ingressService() livekit.Ingress {
+	return livekit.NewIngressProtobufClient(a.url, &http.Client{})
+}
+
+// print outputs res as JSON when requested, or calls printTable otherwise
+func (a *adminClient) print(res proto.Message, printTable func(table *tablewriter.Table)) error {
+	if a.json {
+		b, err := protojson.MarshalOptions{Multiline: true, Indent: "  "}.Marshal(res)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(a.out, string(b))
+		return err
+	}
+
+	if printTable == nil {
+		_, err := fmt.Fprintln(a.out, "OK")
+		return err
+	}
+	table := tablewriter.NewWriter(a.out)
+	table.SetAutoWrapText(false)
+	printTable(table)
+	table.Render()
+	return nil
+}
+
+func formatUnixTime(seconds int64) string {
+	if seconds == 0 {
+		return ""
+	}
+	return time.Unix(seconds, 0).UTC().Format("2006-01-02 15:04:05")
+}
+
+func formatUnixNanoTime(nanos int64) string {
+	if nanos == 0 {
+		return ""
+	}
+	return time.Unix(0, nanos).UTC().Format("2006-01-02 15:04:05")
+}
+
+func listRooms(c *cli.Context) error {
+	a, err := newAdminClient(c)
+	if err != nil {
+		return err
+	}
+	ctx, err := a.context(c, &auth.VideoGrant{RoomList: true})
+	if err != nil {
+		return err
+	}
+
+	res, err := a.roomService().ListRooms(ctx, &livekit.ListRoomsRequest{Names: c.StringSlice("room")})
+	if err != nil {
+		return err
+	}
+
+	return a.print(res, func(table *tablewriter.Table) {
+		table.SetHeader([]string{"SID", "Name", "Participants", "Max Participants", "Recording", "Created At", "Metadata"})
+		for _, room := range res.Rooms {
+			table.Append([]string{
+				room.Sid, room.Name,
+				strconv.Itoa(int(room.NumParticipants)), strconv.Itoa(int(room.MaxParticipants)),
+				strconv.FormatBool(room.ActiveRecording),
+				formatUnixTime(room.CreationTime),
+				room.Metadata,
+			})
+		}
+	})
+}
+
+func listParticipants(c *cli.Context) error {
+	a, err := newAdminClient(c)
+	if err != nil {
+		return err
+	}
+	room := c.String("room")
+	ctx, err := a.context(c, &auth.VideoGrant{RoomAdmin: true, Room: room})
+	if err != nil {
+		return err
+	}
+
+	res, err := a.roomService().ListParticipants(ctx, &livekit.ListParticipantsRequest{Room: room})
+	if err != nil {
+		return err
+	}
+
+	return a.print(res, func(table *tablewriter.Table) {
+		table.SetRowLine(true)
+		table.SetHeader([]string{"SID", "Identity", "Name", "State", "Tracks", "Joined At", "Metadata"})
+		for _, p := range res.Participants {
+			tracks := make([]string, 0, len(p.Tracks))
+			for _, t := range p.Tracks {
+				track := fmt.Sprintf("%s %s %s", t.Sid, t.Type, t.Source)
+				if t.Muted {
+					track += " (muted)"
+				}
+				tracks = append(tracks, track)
+			}
+			table.Append([]string{
+				p.Sid, p.Identity, p.Name, p.State.String(),
+				strings.Join(tracks, "\n"),
+				formatUnixTime(p.JoinedAt),
+				p.Metadata,
+			})
+		}
+	})
+}
+
+func removeParticipant(c *cli.Context) error {
+	a, err := newAdminClient(c)
+	if err != nil {
+		return err
+	}
+	room := c.String("room")
+	ctx, err := a.context(c, &auth.VideoGrant{RoomAdmin: true, Room: room})
+	if err != nil {
+		return err
+	}
+
+	res, err := a.roomService().RemoveParticipant(ctx, &livekit.RoomParticipantIdentity{
+		Room:     room,
+		Identity: c.String("identity"),
+	})
+	if err != nil {
+		return err
+	}
+	return a.print(res, nil)
+}
+
+func muteTrack(c *cli.Context) error {
+	a, err := newAdminClient(c)
+	if err != nil {
+		return err
+	}
+	room := c.String("room")
+	ctx, err := a.context(c, &auth.VideoGrant{RoomAdmin: true, Room: room})
+	if err != nil {
+		return err
+	}
+
+	res, err := a.roomService().MutePublishedTrack(ctx, &livekit.MuteRoomTrackRequest{
+		Room:     room,
+		Identity: c.String("identity"),
+		TrackSid: c.String("track"),
+		Muted:    !c.Bool("unmute"),
+	})
+	if err != nil {
+		return err
+	}
+	return a.print(res, func(table *tablewriter.Table) {
+		table.SetHeader([]string{"SID", "Name", "Type", "Muted"})
+		if t := res.Track; t != nil {
+			table.Append([]string{t.Sid, t.Name, t.Type.String(), strconv.FormatBool(t.Muted)})
+		}
+	})
+}
+
+func deleteRoom(c *cli.Context) error {
+	a, err := newAdminClient(c)
+	if err != nil {
+		return err
+	}
+	ctx, err := a.context(c, &auth.VideoGrant{RoomCreate: true})
+	if err != nil {
+		return err
+	}
+
+	res, err := a.roomService().DeleteRoom(ctx, &livekit.DeleteRoomRequest{Room: c.String("room")})
+	if err != nil {
+		return err
+	}
+	return a.print(res, nil)
+}
+
+func sendData(c *cli.Context) error {
+	a, err := newAdminClient(c)
+	if err != nil {
+		return err
+	}
+	room := c.String("room")
+	ctx, err := a.context(c, &auth.VideoGrant{RoomAdmin: true, Room: room})
+	if err != nil {
+		return err
+	}
+
+	req := &livekit.SendDataRequest{
+		Room: room,
+		Data: []byte(c.String("data")),
+		Kind: livekit.DataPacket_RELIABLE,
+	}
+	if c.Bool("lossy") {
+		req.Kind = livekit.DataPacket_LOSSY
+	}
+
+	// the API addresses participants by SID
+	if identities := c.StringSlice("identity"); len(identities) > 0 {
+		participants, err := a.roomService().ListParticipants(ctx, &livekit.ListParticipantsRequest{Room: room})
+		if err != nil {
+			return err
+		}
+		for _, identity := range identities {
+			var sid string
+			for _, p := range participants.Participants {
+				if p.Identity == identity {
+					sid = p.Sid
+					break
+				}
+			}
+			if sid == "" {
+				return fmt.Errorf("participant %s is not in room %s", identity, room)
+			}
+			req.DestinationSids = append(req.DestinationSids, sid)
+		}
+	}
+
+	res, err := a.roomService().SendData(ctx, req)
+	if err != nil {
+		return err
+	}
+	return a.print(res, nil)
+}
+
+func updateMetadata(c *cli.Context) error {
+	a, err := newAdminClient(c)
+	if err != nil {
+		return err
+	}
+	room := c.String("room")
+	ctx, err := a.context(c, &auth.VideoGrant{RoomAdmin: true, Room: room})
+	if err != nil {
+		return err
+	}
+
+	// updates the participant when identity is given, the room otherwise
+	if identity := c.String("identity"); identity != "" {
+		res, err := a.roomService().UpdateParticipant(ctx, &livekit.UpdateParticipantRequest{
+			Room:     room,
+			Identity: identity,
+			Metadata: c.String("metadata"),
+		})
+		if err != nil {
+			return err
+		}
+		return a.print(res, func(table *tablewriter.Table) {
+			table.SetHeader([]string{"SID", "Identity", "Metadata"})
+			table.Append([]string{res.Sid, res.Identity, res.Metadata})
+		})
+	}
+
+	res, err := a.roomService().UpdateRoomMetadata(ctx, &livekit.UpdateRoomMetadataRequest{
+		Room:     room,
+		Metadata: c.String("metadata"),
+	})
+	if err != nil {
+		return err
+	}
+	return a.print(res, func(table *tablewriter.Table) {
+		table.SetHeader([]string{"SID", "Name", "Metadata"})
+		table.Append([]string{res.Sid, res.Name, res.Metadata})
+	})
+}
+
+func listEgress(c *cli.Context) error {
+	a, err := newAdminClient(c)
+	if err != nil {
+		return err
+	}
+	ctx, err := a.context(c, &auth.VideoGrant{RoomRecord: true})
+	if err != nil {
+		return err
+	}
+
+	res, err := a.egressService().ListEgress(ctx, &livekit.ListEgressRequest{RoomName: c.String("room")})
+	if err != nil {
+		return err
+	}
+
+	return a.print(res, func(table *tablewriter.Table) {
+		table.SetHeader([]string{"Egress ID", "Room", "Status", "Started At", "Ended At", "Error"})
+		for _, info := range res.Items {
+			table.Append([]string{
+				info.EgressId, info.RoomName, info.Status.String(),
+				formatUnixNanoTime(info.StartedAt), formatUnixNanoTime(info.EndedAt),
+				info.Error,
+			})
+		}
+	})
+}
+
+func listIngress(c *cli.Context) error {
+	a, err := newAdminClient(c)
+	if err != nil {
+		return err
+	}
+	ctx, err := a.context(c, &auth.VideoGrant{IngressAdmin: true})
+	if err != nil {
+		return err
+	}
+
+	res, err := a.ingressService().ListIngress(ctx, &livekit.ListIngressRequest{RoomName: c.String("room")})
+	if err != nil {
+		return err
+	}
+
+	return a.print(res, func(table *tablewriter.Table) {
+		table.SetHeader([]string{"Ingress ID", "Name", "Room", "Identity", "Input", "Status", "Started At", "Error"})
+		for _, info := range res.Items {
+			var status, startedAt, errorMsg string
+			if info.State != nil {
+				status = info.State.Status.String()
+				startedAt = formatUnixNanoTime(info.State.StartedAt)
+				errorMsg = info.State.Error
+			}
+			table.Append([]string{
+				info.IngressId, info.Name, info.RoomName, info.ParticipantIdentity,
+				info.InputType.String(), status, startedAt, errorMsg,
+			})
+		}
+	})
+}
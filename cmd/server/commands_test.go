@@ -0,0 +1,340 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/twitchtv/twirp"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/config"
+)
+
+const (
+	testAPIKey    = "APIkey"
+	testAPISecret = "secretsecretsecretsecretsecret"
+)
+
+// testAdminServer answers Twirp requests of admin subcommands with responses set by method, recording the requests
+// and the grants of their tokens
+type testAdminServer struct {
+	*httptest.Server
+
+	lock      sync.Mutex
+	responses map[string]proto.Message
+	requests  []proto.Message
+	grants    []*auth.VideoGrant
+}
+
+func newTestAdminServer(t *testing.T, responses map[string]proto.Message) *testAdminServer {
+	s := &testAdminServer{responses: responses}
+	hooks := twirp.WithServerInterceptors(s.intercept)
+
+	mux := http.NewServeMux()
+	for _, server := range []livekit.TwirpServer{
+		livekit.NewRoomServiceServer(struct{ livekit.RoomService }{}, hooks),
+		livekit.NewEgressServer(struct{ livekit.Egress }{}, hooks),
+		livekit.NewIngressServer(struct{ livekit.Ingress }{}, hooks),
+	} {
+		mux.Handle(server.PathPrefix(), server)
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		v, err := auth.ParseAPIToken(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "))
+		require.NoError(t, err)
+		require.Equal(t, testAPIKey, v.APIKey())
+		grants, err := v.Verify(testAPISecret)
+		require.NoError(t, err)
+
+		s.lock.Lock()
+		s.grants = append(s.grants, grants.Video)
+		s.lock.Unlock()
+		mux.ServeHTTP(w, r)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *testAdminServer) intercept(_ twirp.Method) twirp.Method {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		method, _ := twirp.MethodName(ctx)
+
+		s.lock.Lock()
+		defer s.lock.Unlock()
+		s.requests = append(s.requests, req.(proto.Message))
+		res, ok := s.responses[method]
+		if !ok {
+			return nil, twirp.NewError(twirp.NotFound, "requested room does not exist")
+		}
+		return res, nil
+	}
+}
+
+func TestAdminCommands(t *testing.T) {
+	generatedFlags, err := config.GenerateCLIFlags(baseFlags, true)
+	require.NoError(t, err)
+
+	participants := &livekit.ListParticipantsResponse{
+		Participants: []*livekit.ParticipantInfo{
+			{
+				Sid:      "PA_1",
+				Identity: "p1",
+				State:    livekit.ParticipantInfo_ACTIVE,
+				JoinedAt: 1672531200,
+				Tracks: []*livekit.TrackInfo{
+					{Sid: "TR_1", Type: livekit.TrackType_AUDIO, Source: livekit.TrackSource_MICROPHONE, Muted: true},
+				},
+			},
+			{Sid: "PA_2", Identity: "p2", State: livekit.ParticipantInfo_JOINED},
+		},
+	}
+	rooms := &livekit.ListRoomsResponse{
+		Rooms: []*livekit.Room{
+			{Sid: "RM_a", Name: "a", NumParticipants: 2, MaxParticipants: 10, CreationTime: 1672531200, Metadata: "meta"},
+		},
+	}
+
+	tests := []struct {
+		name      string
+		args      []string
+		responses map[string]proto.Message
+		// requests the server expects, along with grants of their tokens
+		requests []proto.Message
+		grants   []*auth.VideoGrant
+		// lines the output contains, or the whole output when json is printed
+		output []string
+		json   proto.Message
+		err    string
+	}{
+		{
+			name:      "list rooms",
+			args:      []string{"list-rooms", "--room", "a", "--room", "b"},
+			responses: map[string]proto.Message{"ListRooms": rooms},
+			requests:  []proto.Message{&livekit.ListRoomsRequest{Names: []string{"a", "b"}}},
+			grants:    []*auth.VideoGrant{{RoomList: true}},
+			output:    []string{"SID", "RM_a", "2023-01-01 00:00:00", "meta"},
+		},
+		{
+			name:      "list rooms as json",
+			args:      []string{"list-rooms", "--json"},
+			responses: map[string]proto.Message{"ListRooms": rooms},
+			requests:  []proto.Message{&livekit.ListRoomsRequest{}},
+			grants:    []*auth.VideoGrant{{RoomList: true}},
+			json:      rooms,
+		},
+		{
+			name:      "list participants",
+			args:      []string{"list-participants", "--room", "a"},
+			responses: map[string]proto.Message{"ListParticipants": participants},
+			requests:  []proto.Message{&livekit.ListParticipantsRequest{Room: "a"}},
+			grants:    []*auth.VideoGrant{{RoomAdmin: true, Room: "a"}},
+			output:    []string{"PA_1", "TR_1 AUDIO MICROPHONE (muted)", "PA_2", "JOINED"},
+		},
+		{
+			name: "list participants without a room",
+			args: []string{"list-participants"},
+			err:  `Required flag "room" not set`,
+		},
+		{
+			name:      "remove participant",
+			args:      []string{"remove-participant", "--room", "a", "--identity", "p1"},
+			responses: map[string]proto.Message{"RemoveParticipant": &livekit.RemoveParticipantResponse{}},
+			requests:  []proto.Message{&livekit.RoomParticipantIdentity{Room: "a", Identity: "p1"}},
+			grants:    []*auth.VideoGrant{{RoomAdmin: true, Room: "a"}},
+			output:    []string{"OK"},
+		},
+		{
+			name: "mute track",
+			args: []string{"mute-track", "--room", "a", "--identity", "p1", "--track", "TR_1"},
+			responses: map[string]proto.Message{"MutePublishedTrack": &livekit.MuteRoomTrackResponse{
+				Track: &livekit.TrackInfo{Sid: "TR_1", Name: "mic", Type: livekit.TrackType_AUDIO, Muted: true},
+			}},
+			requests: []proto.Message{&livekit.MuteRoomTrackRequest{Room: "a", Identity: "p1", TrackSid: "TR_1", Muted: true}},
+			grants:   []*auth.VideoGrant{{RoomAdmin: true, Room: "a"}},
+			output:   []string{"TR_1", "mic", "AUDIO", "true"},
+		},
+		{
+			name: "unmute track",
+			args: []string{"mute-track", "--room", "a", "--identity", "p1", "--track", "TR_1", "--unmute"},
+			responses: map[string]proto.Message{"MutePublishedTrack": &livekit.MuteRoomTrackResponse{
+				Track: &livekit.TrackInfo{Sid: "TR_1", Name: "mic", Type: livekit.TrackType_AUDIO},
+			}},
+			requests: []proto.Message{&livekit.MuteRoomTrackRequest{Room: "a", Identity: "p1", TrackSid: "TR_1"}},
+			grants:   []*auth.VideoGrant{{RoomAdmin: true, Room: "a"}},
+			output:   []string{"TR_1", "false"},
+		},
+		{
+			name: "mute track without a track",
+			args: []string{"mute-track", "--room", "a", "--identity", "p1"},
+			err:  `Required flag "track" not set`,
+		},
+		{
+			name:      "delete room",
+			args:      []string{"delete-room", "--room", "a"},
+			responses: map[string]proto.Message{"DeleteRoom": &livekit.DeleteRoomResponse{}},
+			requests:  []proto.Message{&livekit.DeleteRoomRequest{Room: "a"}},
+			grants:    []*auth.VideoGrant{{RoomCreate: true}},
+			output:    []string{"OK"},
+		},
+		{
+			name:     "delete room that does not exist",
+			args:     []string{"delete-room", "--room", "a"},
+			requests: []proto.Message{&livekit.DeleteRoomRequest{Room: "a"}},
+			grants:   []*auth.VideoGrant{{RoomCreate: true}},
+			err:      "requested room does not exist",
+		},
+		{
+			name:      "send data to the room",
+			args:      []string{"send-data", "--room", "a", "--data", "hello"},
+			responses: map[string]proto.Message{"SendData": &livekit.SendDataResponse{}},
+			requests: []proto.Message{
+				&livekit.SendDataRequest{Room: "a", Data: []byte("hello"), Kind: livekit.DataPacket_RELIABLE},
+			},
+			grants: []*auth.VideoGrant{{RoomAdmin: true, Room: "a"}},
+			output: []string{"OK"},
+		},
+		{
+			name: "send lossy data to participants",
+			args: []string{"send-data", "--room", "a", "--data", "hello", "--identity", "p2", "--identity", "p1", "--lossy"},
+			responses: map[string]proto.Message{
+				"ListParticipants": participants,
+				"SendData":         &livekit.SendDataResponse{},
+			},
+			requests: []proto.Message{
+				&livekit.ListParticipantsRequest{Room: "a"},
+				&livekit.SendDataRequest{
+					Room:            "a",
+					Data:            []byte("hello"),
+					Kind:            livekit.DataPacket_LOSSY,
+					DestinationSids: []string{"PA_2", "PA_1"},
+				},
+			},
+			grants: []*auth.VideoGrant{{RoomAdmin: true, Room: "a"}, {RoomAdmin: true, Room: "a"}},
+			output: []string{"OK"},
+		},
+		{
+			name:      "send data to participants not in the room",
+			args:      []string{"send-data", "--room", "a", "--data", "hello", "--identity", "p3"},
+			responses: map[string]proto.Message{"ListParticipants": participants},
+			requests:  []proto.Message{&livekit.ListParticipantsRequest{Room: "a"}},
+			grants:    []*auth.VideoGrant{{RoomAdmin: true, Room: "a"}},
+			err:       "participant p3 is not in room a",
+		},
+		{
+			name: "update metadata of the room",
+			args: []string{"update-metadata", "--room", "a", "--metadata", "new"},
+			responses: map[string]proto.Message{
+				"UpdateRoomMetadata": &livekit.Room{Sid: "RM_a", Name: "a", Metadata: "new"},
+			},
+			requests: []proto.Message{&livekit.UpdateRoomMetadataRequest{Room: "a", Metadata: "new"}},
+			grants:   []*auth.VideoGrant{{RoomAdmin: true, Room: "a"}},
+			output:   []string{"RM_a", "new"},
+		},
+		{
+			name: "update metadata of a participant",
+			args: []string{"update-metadata", "--room", "a", "--identity", "p1", "--metadata", "new"},
+			responses: map[string]proto.Message{
+				"UpdateParticipant": &livekit.ParticipantInfo{Sid: "PA_1", Identity: "p1", Metadata: "new"},
+			},
+			requests: []proto.Message{&livekit.UpdateParticipantRequest{Room: "a", Identity: "p1", Metadata: "new"}},
+			grants:   []*auth.VideoGrant{{RoomAdmin: true, Room: "a"}},
+			output:   []string{"PA_1", "p1", "new"},
+		},
+		{
+			name: "list egress",
+			args: []string{"list-egress", "--room", "a"},
+			responses: map[string]proto.Message{"ListEgress": &livekit.ListEgressResponse{
+				Items: []*livekit.EgressInfo{
+					{EgressId: "EG_1", RoomName: "a", Status: livekit.EgressStatus_EGRESS_FAILED, StartedAt: 1672531200e9, Error: "failed"},
+				},
+			}},
+			requests: []proto.Message{&livekit.ListEgressRequest{RoomName: "a"}},
+			grants:   []*auth.VideoGrant{{RoomRecord: true}},
+			output:   []string{"EG_1", "EGRESS_FAILED", "2023-01-01 00:00:00", "failed"},
+		},
+		{
+			name: "list ingress",
+			args: []string{"list-ingress"},
+			responses: map[string]proto.Message{"ListIngress": &livekit.ListIngressResponse{
+				Items: []*livekit.IngressInfo{
+					{
+						IngressId:           "IN_1",
+						Name:                "stream",
+						RoomName:            "a",
+						ParticipantIdentity: "streamer",
+						InputType:           livekit.IngressInput_RTMP_INPUT,
+						State:               &livekit.IngressState{Status: livekit.IngressState_ENDPOINT_PUBLISHING},
+					},
+					{IngressId: "IN_2", RoomName: "b"},
+				},
+			}},
+			requests: []proto.Message{&livekit.ListIngressRequest{}},
+			grants:   []*auth.VideoGrant{{IngressAdmin: true}},
+			output:   []string{"IN_1", "streamer", "RTMP_INPUT", "ENDPOINT_PUBLISHING", "IN_2"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := newTestAdminServer(t, test.responses)
+
+			var out bytes.Buffer
+			app := newApp(generatedFlags)
+			app.Writer = &out
+			app.ErrWriter = ioutil.Discard
+			args := append([]string{"livekit-server", "--keys", testAPIKey + ": " + testAPISecret}, test.args...)
+			err := app.Run(append(args, "--url", server.URL))
+			if test.err != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), test.err)
+			} else {
+				require.NoError(t, err)
+			}
+
+			require.Len(t, server.requests, len(test.requests))
+			for i, req := range test.requests {
+				require.True(t, proto.Equal(req, server.requests[i]), "request %d: %v", i, server.requests[i])
+			}
+			require.Equal(t, test.grants, server.grants)
+
+			if test.json != nil {
+				res := proto.Clone(test.json)
+				proto.Reset(res)
+				require.NoError(t, protojson.Unmarshal(out.Bytes(), res))
+				require.True(t, proto.Equal(test.json, res))
+			}
+			for _, line := range test.output {
+				require.Contains(t, out.String(), line)
+			}
+		})
+	}
+}
+
+func TestFormatUnixTime(t *testing.T) {
+	tests := []struct {
+		name     string
+		format   func(int64) string
+		time     int64
+		expected string
+	}{
+		{"seconds", formatUnixTime, 1672531200, "2023-01-01 00:00:00"},
+		{"no seconds", formatUnixTime, 0, ""},
+		{"nanoseconds", formatUnixNanoTime, 1672531200e9 + 1, "2023-01-01 00:00:00"},
+		{"no nanoseconds", formatUnixNanoTime, 0, ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require.Equal(t, test.expected, test.format(test.time))
+		})
+	}
+}
@@ -107,7 +107,15 @@ func main() {
 		fmt.Println(err)
 	}
 
-	app := &cli.App{
+	app := newApp(generatedFlags)
+	if err := app.Run(os.Args); err != nil {
+		fmt.Println(err)
+	}
+}
+
+// newApp returns the app running the server and its subcommands, taking generatedFlags along with baseFlags
+func newApp(generatedFlags []cli.Flag) *cli.App {
+	return &cli.App{
 		Name:        "livekit-server",
 		Usage:       "High performance WebRTC server",
 		Description: "run without subcommands to start the server",
@@ -153,6 +161,109 @@ func main() {
 				Usage:  "list all nodes",
 				Action: listNodes,
 			},
+			{
+				Name:   "list-rooms",
+				Usage:  "list rooms on a running server",
+				Action: listRooms,
+				Flags: withAdminFlags(
+					&cli.StringSliceFlag{
+						Name:  "room",
+						Usage: "names of rooms to list, all rooms when not set",
+					},
+				),
+			},
+			{
+				Name:   "list-participants",
+				Usage:  "list participants of a room",
+				Action: listParticipants,
+				Flags:  withAdminFlags(roomFlag),
+			},
+			{
+				Name:   "remove-participant",
+				Usage:  "remove a participant from a room",
+				Action: removeParticipant,
+				Flags:  withAdminFlags(roomFlag, identityFlag),
+			},
+			{
+				Name:   "mute-track",
+				Usage:  "mute a track published by a participant",
+				Action: muteTrack,
+				Flags: withAdminFlags(roomFlag, identityFlag,
+					&cli.StringFlag{
+						Name:     "track",
+						Usage:    "SID of the track",
+						Required: true,
+					},
+					&cli.BoolFlag{
+						Name:  "unmute",
+						Usage: "unmutes the track instead",
+					},
+				),
+			},
+			{
+				Name:   "delete-room",
+				Usage:  "delete a room, disconnecting all participants",
+				Action: deleteRoom,
+				Flags:  withAdminFlags(roomFlag),
+			},
+			{
+				Name:   "send-data",
+				Usage:  "send a data packet to participants of a room",
+				Action: sendData,
+				Flags: withAdminFlags(roomFlag,
+					&cli.StringFlag{
+						Name:     "data",
+						Usage:    "payload of the packet",
+						Required: true,
+					},
+					&cli.StringSliceFlag{
+						Name:  "identity",
+						Usage: "identities of participants to send to, everyone in the room when not set",
+					},
+					&cli.BoolFlag{
+						Name:  "lossy",
+						Usage: "send as lossy instead of reliable",
+					},
+				),
+			},
+			{
+				Name:   "update-metadata",
+				Usage:  "update metadata of a room, or of a participant when identity is set",
+				Action: updateMetadata,
+				Flags: withAdminFlags(roomFlag,
+					&cli.StringFlag{
+						Name:  "identity",
+						Usage: "identity of the participant to update",
+					},
+					&cli.StringFlag{
+						Name:     "metadata",
+						Usage:    "new metadata",
+						Required: true,
+					},
+				),
+			},
+			{
+				Name:   "list-egress",
+				Usage:  "list egress requests",
+				Action: listEgress,
+				Flags: withAdminFlags(
+					&cli.StringFlag{
+						Name:  "room",
+						Usage: "only list egress of this room",
+					},
+				),
+			},
+			{
+				Name:   "list-ingress",
+				Usage:  "list ingress endpoints",
+				Action: listIngress,
+				Flags: withAdminFlags(
+					&cli.StringFlag{
+						Name:  "room",
+						Usage: "only list ingress of this room",
+					},
+				),
+			},
 			{
 				Name:   "help-verbose",
 				Usage:  "prints app help, including all generated configuration flags",
@@ -161,13 +272,52 @@ func main() {
 		},
 		Version: version.Version,
 	}
+}
 
-	if err := app.Run(os.Args); err != nil {
-		fmt.Println(err)
+var (
+	// flags of subcommands that call the API of a running server
+	adminFlags = []cli.Flag{
+		&cli.StringFlag{
+			Name:    "url",
+			Usage:   "URL of the server, defaults to localhost on the configured port",
+			EnvVars: []string{"LIVEKIT_URL"},
+		},
+		&cli.StringFlag{
+			Name:  "api-key",
+			Usage: "key to sign requests with, defaults to the first configured key",
+		},
+		&cli.BoolFlag{
+			Name:  "json",
+			Usage: "print results as JSON",
+		},
+	}
+	roomFlag = &cli.StringFlag{
+		Name:     "room",
+		Usage:    "name of the room",
+		Required: true,
+	}
+	identityFlag = &cli.StringFlag{
+		Name:     "identity",
+		Usage:    "identity of the participant",
+		Required: true,
 	}
+)
+
+func withAdminFlags(flags ...cli.Flag) []cli.Flag {
+	return append(flags, adminFlags...)
 }
 
 func getConfig(c *cli.Context) (*config.Config, error) {
+	conf, err := loadConfig(c)
+	if err != nil {
+		return nil, err
+	}
+	serverlogger.InitFromConfig(conf.Logging)
+	return conf, nil
+}
+
+// loadConfig reads config from file and flags without applying any of it
+func loadConfig(c *cli.Context) (*config.Config, error) {
 	confString, err := getConfigString(c.String("config"), c.String("config-body"))
 	if err != nil {
 		return nil, err
@@ -182,7 +332,6 @@ func getConfig(c *cli.Context) (*config.Config, error) {
 	if err != nil {
 		return nil, err
 	}
-	serverlogger.InitFromConfig(conf.Logging)
 
 	if c.String("config") == "" && c.String("config-body") == "" && conf.Development {
 		// use single port UDP when no config is provided
@@ -246,6 +395,10 @@ func startServer(c *cli.Context) error {
 		return err
 	}
 
+	server.ConfigReloader().SetLoader(func() (*config.Config, error) {
+		return loadConfig(c)
+	})
+
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
 
@@ -255,6 +408,18 @@ func startServer(c *cli.Context) error {
 		server.Stop(false)
 	}()
 
+	reloadChan := make(chan os.Signal, 1)
+	signal.Notify(reloadChan, syscall.SIGHUP)
+
+	go func() {
+		for range reloadChan {
+			logger.Infow("reloading config")
+			if _, err := server.ConfigReloader().Reload(); err != nil {
+				logger.Errorw("could not reload config", err)
+			}
+		}
+	}()
+
 	return server.Start()
 }
 
@@ -0,0 +1,258 @@
+package audit
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+	"github.com/livekit/protocol/utils"
+
+	"github.com/livekit/livekit-server/pkg/config"
+)
+
+const (
+	RecordPrefix = "AR_"
+
+	// records kept for another attempt while the sink fails, oldest are dropped beyond it, leaving a gap in the chain
+	maxPendingRecords = 10000
+
+	writeTimeout     = 10 * time.Second
+	minRetryInterval = time.Second
+	maxRetryInterval = 30 * time.Second
+)
+
+var (
+	ErrRecordModified = errors.New("record does not match its hash")
+	ErrRecordMissing  = errors.New("record before it is missing")
+)
+
+// Record is a privileged operation made by an API client
+type Record struct {
+	ID string `json:"id"`
+	// request the operation was made in
+	RequestID string `json:"request_id,omitempty"`
+	NodeID    string `json:"node_id"`
+	// unix time, in milliseconds
+	Time int64 `json:"time"`
+
+	Service   string `json:"service"`
+	Operation string `json:"operation"`
+	APIKey    string `json:"api_key,omitempty"`
+	SourceIP  string `json:"source_ip,omitempty"`
+
+	Room        string `json:"room,omitempty"`
+	Participant string `json:"participant,omitempty"`
+	TrackID     string `json:"track_id,omitempty"`
+	EgressID    string `json:"egress_id,omitempty"`
+	IngressID   string `json:"ingress_id,omitempty"`
+	// values the operation sets
+	Details map[string]string `json:"details,omitempty"`
+	// error the operation failed with, empty when it succeeded
+	Error string `json:"error,omitempty"`
+
+	// HMAC of the record before this one on the node, and of this one, keyed with the secret of the audit API key
+	PrevHash string `json:"prev_hash"`
+	Hash     string `json:"hash"`
+}
+
+// Logger chains records of privileged operations, and writes them to a sink in order. Records are written by a
+// worker, retrying while the sink fails, so that operations don't wait on the sink
+type Logger struct {
+	nodeID livekit.NodeID
+	sink   Sink
+	secret []byte
+
+	lock     sync.Mutex
+	lastHash string
+	// records chained but not written yet, in order
+	pending []*Record
+	started bool
+
+	wake       chan struct{}
+	done       chan struct{}
+	workerDone chan struct{}
+}
+
+// NewLogger returns a logger continuing the chain of records of the node in sink, nil when audit is not enabled.
+// Records are hashed with secret, held by the server, so that the chain can't be rewritten by those with access to
+// the sink alone
+func NewLogger(conf *config.Config, nodeID livekit.NodeID, secret string, sink Sink) (*Logger, error) {
+	if !conf.Audit.Enabled || sink == nil {
+		return nil, nil
+	}
+
+	lastHash, err := sink.LastHash(context.Background(), nodeID)
+	if err != nil {
+		return nil, err
+	}
+	return &Logger{
+		nodeID:     nodeID,
+		sink:       sink,
+		secret:     []byte(secret),
+		lastHash:   lastHash,
+		wake:       make(chan struct{}, 1),
+		done:       make(chan struct{}),
+		workerDone: make(chan struct{}),
+	}, nil
+}
+
+func (l *Logger) Start() {
+	if l == nil {
+		return
+	}
+
+	l.lock.Lock()
+	l.started = true
+	l.lock.Unlock()
+	go l.worker()
+}
+
+// Stop writes records that are pending, and stops writing
+func (l *Logger) Stop() {
+	if l == nil {
+		return
+	}
+	select {
+	case <-l.done:
+		return
+	default:
+		close(l.done)
+	}
+
+	l.lock.Lock()
+	started := l.started
+	l.lock.Unlock()
+	if started {
+		<-l.workerDone
+	}
+	if !l.writePending() {
+		l.lock.Lock()
+		logger.Errorw("could not write audit records before stopping", nil, "records", len(l.pending))
+		l.lock.Unlock()
+	}
+}
+
+// Log chains record to the last one, and queues it to be written
+func (l *Logger) Log(record *Record) {
+	if l == nil {
+		return
+	}
+
+	l.lock.Lock()
+	record.ID = utils.NewGuid(RecordPrefix)
+	record.NodeID = string(l.nodeID)
+	if record.Time == 0 {
+		record.Time = time.Now().UnixMilli()
+	}
+	record.PrevHash = l.lastHash
+	record.Hash = hashRecord(record, l.secret)
+	l.lastHash = record.Hash
+
+	if len(l.pending) >= maxPendingRecords {
+		dropped := l.pending[0]
+		l.pending = l.pending[1:]
+		logger.Errorw("could not write audit record, dropping", nil,
+			"service", dropped.Service,
+			"operation", dropped.Operation,
+			"requestID", dropped.RequestID,
+		)
+	}
+	l.pending = append(l.pending, record)
+	l.lock.Unlock()
+
+	select {
+	case l.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (l *Logger) worker() {
+	defer close(l.workerDone)
+
+	retryInterval := minRetryInterval
+	for {
+		if l.writePending() {
+			retryInterval = minRetryInterval
+			select {
+			case <-l.done:
+				return
+			case <-l.wake:
+			}
+			continue
+		}
+
+		select {
+		case <-l.done:
+			return
+		case <-time.After(retryInterval):
+		}
+		if retryInterval *= 2; retryInterval > maxRetryInterval {
+			retryInterval = maxRetryInterval
+		}
+	}
+}
+
+// writePending writes pending records in order, returning false when the sink fails. The lock isn't held while
+// writing, for records to be logged meanwhile
+func (l *Logger) writePending() bool {
+	for {
+		l.lock.Lock()
+		if len(l.pending) == 0 {
+			l.lock.Unlock()
+			return true
+		}
+		record := l.pending[0]
+		l.lock.Unlock()
+
+		ctx, cancel := context.WithTimeout(context.Background(), writeTimeout)
+		err := l.sink.Write(ctx, record)
+		cancel()
+		if err != nil {
+			logger.Warnw("could not write audit record, retrying", err,
+				"service", record.Service,
+				"operation", record.Operation,
+				"requestID", record.RequestID,
+			)
+			return false
+		}
+
+		l.lock.Lock()
+		// unless it was dropped meanwhile
+		if len(l.pending) > 0 && l.pending[0] == record {
+			l.pending = l.pending[1:]
+		}
+		l.lock.Unlock()
+	}
+}
+
+// Verify checks that records of a node, in the order they were logged, are unchanged and that none is missing
+// between them. secret is the one records were logged with
+func Verify(records []*Record, secret string) error {
+	for i, record := range records {
+		if !hmac.Equal([]byte(hashRecord(record, []byte(secret))), []byte(record.Hash)) {
+			return fmt.Errorf("%w: %s", ErrRecordModified, record.ID)
+		}
+		if i > 0 && record.PrevHash != records[i-1].Hash {
+			return fmt.Errorf("%w: %s", ErrRecordMissing, record.ID)
+		}
+	}
+	return nil
+}
+
+func hashRecord(record *Record, secret []byte) string {
+	unhashed := *record
+	unhashed.Hash = ""
+	// fields are marshalled in order, and details by key
+	data, _ := json.Marshal(&unhashed)
+	mac := hmac.New(sha256.New, secret)
+	_, _ = mac.Write(data)
+	return hex.EncodeToString(mac.Sum(nil))
+}
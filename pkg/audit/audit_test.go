@@ -0,0 +1,164 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/config"
+)
+
+const testSecret = "secret"
+
+type testSink struct {
+	records []*Record
+	err     error
+}
+
+func (s *testSink) Write(_ context.Context, record *Record) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.records = append(s.records, record)
+	return nil
+}
+
+func (s *testSink) LastHash(_ context.Context, _ livekit.NodeID) (string, error) {
+	if len(s.records) == 0 {
+		return "", nil
+	}
+	return s.records[len(s.records)-1].Hash, nil
+}
+
+func newTestConfig(t *testing.T) *config.Config {
+	conf, err := config.NewConfig("", true, nil, nil)
+	require.NoError(t, err)
+	conf.Audit.Enabled = true
+	return conf
+}
+
+func TestLogger(t *testing.T) {
+	t.Run("not enabled", func(t *testing.T) {
+		conf, err := config.NewConfig("", true, nil, nil)
+		require.NoError(t, err)
+		l, err := NewLogger(conf, "node", testSecret, &testSink{})
+		require.NoError(t, err)
+		require.Nil(t, l)
+		l.Start()
+		l.Log(&Record{})
+		l.Stop()
+	})
+
+	t.Run("chains records", func(t *testing.T) {
+		sink := &testSink{}
+		l, err := NewLogger(newTestConfig(t), "node", testSecret, sink)
+		require.NoError(t, err)
+
+		for _, operation := range []string{"CreateRoom", "UpdateRoomMetadata", "DeleteRoom"} {
+			l.Log(&Record{Service: "RoomService", Operation: operation, Room: "room"})
+		}
+		l.Stop()
+		records := sink.records
+		require.Len(t, records, 3)
+		require.Empty(t, records[0].PrevHash)
+		require.Equal(t, records[0].Hash, records[1].PrevHash)
+		require.Equal(t, "node", records[2].NodeID)
+		require.NoError(t, Verify(records, testSecret))
+
+		// chain continues from the sink
+		l, err = NewLogger(newTestConfig(t), "node", testSecret, sink)
+		require.NoError(t, err)
+		l.Log(&Record{Service: "RoomService", Operation: "CreateRoom"})
+		l.Stop()
+		require.NoError(t, Verify(sink.records, testSecret))
+	})
+
+	t.Run("writes records in order", func(t *testing.T) {
+		sink := &testSink{}
+		l, err := NewLogger(newTestConfig(t), "node", testSecret, sink)
+		require.NoError(t, err)
+		l.Start()
+		for i := 0; i < 100; i++ {
+			l.Log(&Record{Service: "RoomService", Operation: "MutePublishedTrack", TrackID: "TR_1"})
+		}
+		l.Stop()
+		require.Len(t, sink.records, 100)
+		require.NoError(t, Verify(sink.records, testSecret))
+	})
+
+	t.Run("writes records again when writing fails", func(t *testing.T) {
+		sink := &testSink{err: errors.New("unavailable")}
+		l, err := NewLogger(newTestConfig(t), "node", testSecret, sink)
+		require.NoError(t, err)
+		l.Log(&Record{Service: "RoomService", Operation: "CreateRoom"})
+		l.Log(&Record{Service: "RoomService", Operation: "DeleteRoom"})
+		require.False(t, l.writePending())
+		require.Empty(t, sink.records)
+
+		sink.err = nil
+		require.True(t, l.writePending())
+		require.Len(t, sink.records, 2)
+		require.Equal(t, "CreateRoom", sink.records[0].Operation)
+		require.NoError(t, Verify(sink.records, testSecret))
+	})
+
+	t.Run("changes are evident", func(t *testing.T) {
+		sink := &testSink{}
+		l, err := NewLogger(newTestConfig(t), "node", testSecret, sink)
+		require.NoError(t, err)
+		for i := 0; i < 3; i++ {
+			l.Log(&Record{Service: "RoomService", Operation: "RemoveParticipant", Participant: "p"})
+		}
+		l.Stop()
+
+		modified := *sink.records[1]
+		modified.Participant = "other"
+		require.ErrorIs(t, Verify([]*Record{sink.records[0], &modified, sink.records[2]}, testSecret), ErrRecordModified)
+		require.ErrorIs(t, Verify([]*Record{sink.records[0], sink.records[2]}, testSecret), ErrRecordMissing)
+
+		// records rehashed without the secret are told apart
+		modified.Hash = hashRecord(&modified, []byte("other"))
+		require.ErrorIs(t, Verify([]*Record{sink.records[0], &modified, sink.records[2]}, testSecret), ErrRecordModified)
+	})
+}
+
+func TestFileSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	sink, err := NewFileSink(path, 600, 2)
+	require.NoError(t, err)
+	l, err := NewLogger(newTestConfig(t), "node", testSecret, sink)
+	require.NoError(t, err)
+
+	var last *Record
+	for i := 0; i < 10; i++ {
+		last = &Record{Service: "Egress", Operation: "StopEgress", EgressID: "EG_1"}
+		l.Log(last)
+	}
+	l.Stop()
+
+	// rotated, keeping 2 backups
+	for _, p := range []string{path, path + ".1", path + ".2"} {
+		info, err := os.Stat(p)
+		require.NoError(t, err)
+		require.LessOrEqual(t, info.Size(), int64(600))
+	}
+	_, err = os.Stat(path + ".3")
+	require.True(t, os.IsNotExist(err))
+
+	// reopened, the chain continues from the last record
+	sink, err = NewFileSink(path, 600, 2)
+	require.NoError(t, err)
+	hash, err := sink.LastHash(context.Background(), "node")
+	require.NoError(t, err)
+	require.Equal(t, last.Hash, hash)
+
+	record, err := readLastRecord(path)
+	require.NoError(t, err)
+	require.Equal(t, last.ID, record.ID)
+}
@@ -0,0 +1,238 @@
+package audit
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/go-redis/redis/v8"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/webhook"
+)
+
+const (
+	SinkKindFile    = "file"
+	SinkKindRedis   = "redis"
+	SinkKindWebhook = "webhook"
+
+	// stream records are appended to by the redis sink, and hash of the last hash of each node
+	AuditLogKey      = "audit_log"
+	AuditLogHeadsKey = "audit_log_heads"
+	// name of the spool posts of the webhook sink are kept in
+	SpoolName = "audit"
+
+	// longest record read back from a file
+	maxRecordSize = 1024 * 1024
+)
+
+// Sink writes records in the order they're logged
+type Sink interface {
+	Write(ctx context.Context, record *Record) error
+	// LastHash returns the hash of the last record of a node, for the chain to continue across restarts. Empty when
+	// the sink has none, or can't tell
+	LastHash(ctx context.Context, nodeID livekit.NodeID) (string, error)
+}
+
+// ---------------------------------
+
+type fileSink struct {
+	path       string
+	maxSize    int64
+	maxBackups int
+
+	lock sync.Mutex
+	file *os.File
+	size int64
+}
+
+// NewFileSink appends records to the file at path, one JSON object per line. Once the file reaches maxSize, it's
+// rotated to path.1, path.1 to path.2 and so on, up to maxBackups
+func NewFileSink(path string, maxSize int64, maxBackups int) (Sink, error) {
+	s := &fileSink{
+		path:       path,
+		maxSize:    maxSize,
+		maxBackups: maxBackups,
+	}
+	if err := s.open(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *fileSink) open() error {
+	file, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return err
+	}
+	s.file = file
+	s.size = info.Size()
+	return nil
+}
+
+func (s *fileSink) Write(_ context.Context, record *Record) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.maxSize > 0 && s.size > 0 && s.size+int64(len(line)) > s.maxSize {
+		if err = s.rotate(); err != nil {
+			return err
+		}
+	}
+	n, err := s.file.Write(line)
+	s.size += int64(n)
+	if err != nil {
+		return err
+	}
+	return s.file.Sync()
+}
+
+func (s *fileSink) rotate() error {
+	if err := s.file.Close(); err != nil {
+		return err
+	}
+
+	if s.maxBackups > 0 {
+		for i := s.maxBackups - 1; i > 0; i-- {
+			if err := os.Rename(s.backupPath(i), s.backupPath(i+1)); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+		if err := os.Rename(s.path, s.backupPath(1)); err != nil {
+			return err
+		}
+	} else if err := os.Remove(s.path); err != nil {
+		return err
+	}
+	return s.open()
+}
+
+func (s *fileSink) backupPath(i int) string {
+	return fmt.Sprintf("%s.%d", s.path, i)
+}
+
+func (s *fileSink) LastHash(_ context.Context, _ livekit.NodeID) (string, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	// the file is new right after rotation
+	for _, path := range []string{s.path, s.backupPath(1)} {
+		record, err := readLastRecord(path)
+		if err != nil {
+			return "", err
+		}
+		if record != nil {
+			return record.Hash, nil
+		}
+	}
+	return "", nil
+}
+
+func readLastRecord(path string) (*Record, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var last []byte
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxRecordSize)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) > 0 {
+			last = append(last[:0], scanner.Bytes()...)
+		}
+	}
+	if err = scanner.Err(); err != nil || last == nil {
+		return nil, err
+	}
+
+	record := &Record{}
+	if err = json.Unmarshal(last, record); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// ---------------------------------
+
+type redisSink struct {
+	rc redis.UniversalClient
+}
+
+// NewRedisSink appends records to a stream shared by nodes, each of them chaining its own records
+func NewRedisSink(rc redis.UniversalClient) Sink {
+	return &redisSink{rc: rc}
+}
+
+func (s *redisSink) Write(ctx context.Context, record *Record) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.rc.TxPipelined(ctx, func(p redis.Pipeliner) error {
+		p.XAdd(ctx, &redis.XAddArgs{
+			Stream: AuditLogKey,
+			Values: map[string]interface{}{"record": data},
+		})
+		p.HSet(ctx, AuditLogHeadsKey, record.NodeID, record.Hash)
+		return nil
+	})
+	return err
+}
+
+func (s *redisSink) LastHash(ctx context.Context, nodeID livekit.NodeID) (string, error) {
+	hash, err := s.rc.HGet(ctx, AuditLogHeadsKey, string(nodeID)).Result()
+	if err == redis.Nil {
+		return "", nil
+	}
+	return hash, err
+}
+
+// ---------------------------------
+
+// AuditEvent is the payload records are posted in by the webhook sink
+type AuditEvent struct {
+	Event  string  `json:"event"`
+	Record *Record `json:"record"`
+}
+
+const EventAudit = "audit"
+
+type webhookSink struct {
+	notifier webhook.Notifier
+}
+
+// NewWebhookSink posts records through notifier, which signs and retries posts as it does for webhooks. Chains of
+// records posted start over when the node restarts
+func NewWebhookSink(notifier webhook.Notifier) Sink {
+	return &webhookSink{notifier: notifier}
+}
+
+func (s *webhookSink) Write(ctx context.Context, record *Record) error {
+	return s.notifier.Notify(ctx, &AuditEvent{
+		Event:  EventAudit,
+		Record: record,
+	})
+}
+
+func (s *webhookSink) LastHash(_ context.Context, _ livekit.NodeID) (string, error) {
+	return "", nil
+}
@@ -30,6 +30,9 @@ const (
 	CongestionControlProbeModePadding CongestionControlProbeMode = "padding"
 	CongestionControlProbeModeMedia   CongestionControlProbeMode = "media"
 
+	RelayModeRegion = "region"
+	RelayModeAlways = "always"
+
 	StatsUpdateInterval = time.Second * 10
 )
 
@@ -39,6 +42,7 @@ type Config struct {
 	PrometheusPort uint32                   `yaml:"prometheus_port,omitempty"`
 	RTC            RTCConfig                `yaml:"rtc,omitempty"`
 	Redis          redisLiveKit.RedisConfig `yaml:"redis,omitempty"`
+	Store          StoreConfig              `yaml:"store,omitempty"`
 	Audio          AudioConfig              `yaml:"audio,omitempty"`
 	Video          VideoConfig              `yaml:"video,omitempty"`
 	Room           RoomConfig               `yaml:"room,omitempty"`
@@ -48,11 +52,25 @@ type Config struct {
 	NodeSelector   NodeSelectorConfig       `yaml:"node_selector,omitempty"`
 	KeyFile        string                   `yaml:"key_file,omitempty"`
 	Keys           map[string]string        `yaml:"keys,omitempty"`
-	Region         string                   `yaml:"region,omitempty"`
+	KeyProvider    KeyProviderConfig        `yaml:"key_provider,omitempty"`
+	// API keys whose tokens may reload config through the admin endpoint
+	AdminKeys []string `yaml:"admin_keys,omitempty"`
+	// addresses and CIDR ranges of proxies whose forwarding headers tell the address of clients
+	TrustedProxies []string `yaml:"trusted_proxies,omitempty"`
+	Region         string   `yaml:"region,omitempty"`
 	// LogLevel is deprecated
-	LogLevel string        `yaml:"log_level,omitempty"`
-	Logging  LoggingConfig `yaml:"logging,omitempty"`
-	Limit    LimitConfig   `yaml:"limit,omitempty"`
+	LogLevel  string          `yaml:"log_level,omitempty"`
+	Logging   LoggingConfig   `yaml:"logging,omitempty"`
+	Limit     LimitConfig     `yaml:"limit,omitempty"`
+	Capture   CaptureConfig   `yaml:"capture,omitempty"`
+	RTPIngest RTPIngestConfig `yaml:"rtp_ingest,omitempty"`
+	Recorder  RecorderConfig  `yaml:"recorder,omitempty"`
+	Relay     RelayConfig     `yaml:"relay,omitempty"`
+	Admission AdmissionConfig `yaml:"admission,omitempty"`
+	Tenancy   TenancyConfig   `yaml:"tenancy,omitempty"`
+	Metering  MeteringConfig  `yaml:"metering,omitempty"`
+	RateLimit RateLimitConfig `yaml:"rate_limit,omitempty"`
+	Audit     AuditConfig     `yaml:"audit,omitempty"`
 
 	Development bool `yaml:"development,omitempty"`
 }
@@ -130,6 +148,8 @@ type AudioConfig struct {
 	// smoothing for audioLevel values sent to the client.
 	// audioLevel will be an average of `smooth_intervals`, 0 to disable
 	SmoothIntervals uint32 `yaml:"smooth_intervals"`
+	// forward only the loudest audio tracks to each subscriber, 0 to forward all
+	MaxForwardedTracks uint32 `yaml:"max_forwarded_tracks,omitempty"`
 }
 
 type VideoConfig struct {
@@ -144,6 +164,48 @@ type RoomConfig struct {
 	EmptyTimeout       uint32      `yaml:"empty_timeout"`
 	EnableRemoteUnmute bool        `yaml:"enable_remote_unmute"`
 	MaxMetadataSize    uint32      `yaml:"max_metadata_size"`
+	// limit of downstream bitrate per participant in bps, 0 if unlimited
+	MaxSubscribeBitrate int64 `yaml:"max_subscribe_bitrate,omitempty"`
+	// longest a room may run once a participant joined it, 0 for no limit
+	MaxDuration time.Duration `yaml:"max_duration,omitempty"`
+	// longest a participant session may run, 0 for no limit
+	MaxParticipantDuration time.Duration `yaml:"max_participant_duration,omitempty"`
+	// how long before either limit is reached participants are warned, 0 to not warn them
+	DurationWarning time.Duration     `yaml:"duration_warning,omitempty"`
+	Data            DataConfig        `yaml:"data,omitempty"`
+	WaitingRoom     WaitingRoomConfig `yaml:"waiting_room,omitempty"`
+}
+
+// KeyProviderConfig sets sources of API keys in addition to keys and key_file
+type KeyProviderConfig struct {
+	// directory with a file per key, named after the key and containing its secret
+	Dir string `yaml:"dir,omitempty"`
+	// endpoint returning keys in the key file format, typically served locally by a secrets agent
+	URL string `yaml:"url,omitempty"`
+	// how often key_file, dir and url are checked for changes
+	RefreshInterval time.Duration `yaml:"refresh_interval,omitempty"`
+}
+
+// DataConfig limits data packets sent by participants
+type DataConfig struct {
+	// maximum number of packets a participant can send per second, 0 for no limit
+	MaxPacketsPerSec int `yaml:"max_packets_per_sec,omitempty"`
+	// maximum number of payload bytes a participant can send per second, 0 for no limit
+	MaxBytesPerSec int `yaml:"max_bytes_per_sec,omitempty"`
+	// maximum payload size of a packet, unless overridden by the topic, 0 for no limit
+	MaxPacketSize int               `yaml:"max_packet_size,omitempty"`
+	Topics        []DataTopicConfig `yaml:"topics,omitempty"`
+}
+
+type DataTopicConfig struct {
+	Name          string `yaml:"name"`
+	MaxPacketSize int    `yaml:"max_packet_size"`
+}
+
+// WaitingRoomConfig gates rooms, participants joining them wait until they are admitted
+type WaitingRoomConfig struct {
+	// patterns of names of gated rooms, as matched by path.Match
+	Rooms []string `yaml:"rooms,omitempty"`
 }
 
 type CodecSpec struct {
@@ -169,9 +231,42 @@ type TURNConfig struct {
 }
 
 type WebHookConfig struct {
+	// URLs notified of every event, signed with APIKey
 	URLs []string `yaml:"urls"`
 	// key to use for webhook
 	APIKey string `yaml:"api_key"`
+	// endpoints with their own key and filters, notified along with URLs
+	Endpoints []WebHookEndpointConfig `yaml:"endpoints,omitempty"`
+	// number of delivery attempts before an event is moved to the dead-letter list
+	MaxAttempts int `yaml:"max_attempts,omitempty"`
+	// delay before retrying a failed delivery, doubled after every failed attempt up to MaxBackoff
+	InitialBackoff time.Duration      `yaml:"initial_backoff,omitempty"`
+	MaxBackoff     time.Duration      `yaml:"max_backoff,omitempty"`
+	Spool          WebHookSpoolConfig `yaml:"spool,omitempty"`
+}
+
+type WebHookEndpointConfig struct {
+	URL string `yaml:"url"`
+	// key to sign events with, defaults to the webhook api_key
+	APIKey string `yaml:"api_key,omitempty"`
+	// types of events to send, such as room_finished. all events when empty
+	Events []string `yaml:"events,omitempty"`
+	// patterns of names of rooms to send events of, as matched by path.Match. all rooms when empty
+	Rooms []string `yaml:"rooms,omitempty"`
+	// timeout of each delivery attempt, defaults to 10s
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+}
+
+// WebHookSpoolConfig configures where undelivered webhook events are kept
+type WebHookSpoolConfig struct {
+	// memory, file or redis. when empty, redis is used if configured, then file if Dir is set, memory otherwise
+	Kind string `yaml:"kind,omitempty"`
+	// directory to keep events in, for file spool
+	Dir string `yaml:"dir,omitempty"`
+	// maximum number of undelivered events, new events are dropped when the spool is full
+	MaxEvents int `yaml:"max_events,omitempty"`
+	// maximum number of events kept in the dead-letter list, oldest are discarded first
+	MaxDeadLetters int `yaml:"max_dead_letters,omitempty"`
 }
 
 type NodeSelectorConfig struct {
@@ -180,6 +275,19 @@ type NodeSelectorConfig struct {
 	CPULoadLimit float32        `yaml:"cpu_load_limit"`
 	SysloadLimit float32        `yaml:"sysload_limit"`
 	Regions      []RegionConfig `yaml:"regions"`
+	// used by the weighted selector
+	Weights NodeSelectorWeights `yaml:"weights,omitempty"`
+}
+
+// NodeSelectorWeights sets how much each metric contributes to a node's score, 0 to ignore a metric
+type NodeSelectorWeights struct {
+	CPULoad     float32 `yaml:"cpu_load,omitempty"`
+	Sysload     float32 `yaml:"sysload,omitempty"`
+	Rooms       float32 `yaml:"rooms,omitempty"`
+	Clients     float32 `yaml:"clients,omitempty"`
+	Tracks      float32 `yaml:"tracks,omitempty"`
+	BytesPerSec float32 `yaml:"bytes_per_sec,omitempty"`
+	Region      float32 `yaml:"region,omitempty"`
 }
 
 // RegionConfig lists available regions and their latitude/longitude, so the selector would prefer
@@ -195,6 +303,176 @@ type LimitConfig struct {
 	BytesPerSec float32 `yaml:"bytes_per_sec"`
 }
 
+// StoreConfig configures persistence of rooms, participants, egress and ingress when Redis is not configured
+type StoreConfig struct {
+	// path of the file to persist state into, state is kept in memory only when empty
+	FilePath string `yaml:"file_path,omitempty"`
+}
+
+// CaptureConfig configures admin triggered packet captures of tracks, used to debug media issues
+type CaptureConfig struct {
+	// directory capture files are written to, captures are disabled when empty
+	Dir string `yaml:"dir,omitempty"`
+	// pcap or rtpdump
+	Format string `yaml:"format,omitempty"`
+	// captures end once they reach either limit, requests may ask for lower limits
+	MaxDuration time.Duration `yaml:"max_duration,omitempty"`
+	MaxSize     int64         `yaml:"max_size,omitempty"`
+}
+
+// RTPIngestConfig configures plain RTP and SRTP ingest, each session receives RTP and RTCP on its own UDP port
+type RTPIngestConfig struct {
+	// ports allocated to sessions, ingest is disabled when not set
+	PortRangeStart uint16 `yaml:"port_range_start,omitempty"`
+	PortRangeEnd   uint16 `yaml:"port_range_end,omitempty"`
+	// sessions end after receiving nothing for this long
+	IdleTimeout time.Duration `yaml:"idle_timeout,omitempty"`
+}
+
+// RecorderConfig configures recording of track egress on the node hosting the room, without an egress worker
+type RecorderConfig struct {
+	// directory recordings are written to, file paths of requests are relative to it. disabled when empty
+	Dir string `yaml:"dir,omitempty"`
+	// how long packets are held to be put back in order, before missing ones are considered lost
+	Latency time.Duration `yaml:"latency,omitempty"`
+}
+
+// RelayConfig lets rooms span nodes. Participants are hosted by the node they connect to, and tracks published there
+// are relayed to the other nodes hosting the room. Requires redis
+type RelayConfig struct {
+	Enabled bool `yaml:"enabled,omitempty"`
+	// when participants are hosted by the node they connect to instead of the node hosting the room: "region", when
+	// that node is in another region (default), or "always"
+	Mode string `yaml:"mode,omitempty"`
+	// UDP ports tracks relayed from other nodes are received on, one per track. nodes have to reach each other on them
+	PortRangeStart uint16 `yaml:"port_range_start,omitempty"`
+	PortRangeEnd   uint16 `yaml:"port_range_end,omitempty"`
+}
+
+// AdmissionConfig configures a callback that decides on joins, and may override grants of the token
+type AdmissionConfig struct {
+	// disabled when not set
+	URL string `yaml:"url,omitempty"`
+	// key to sign requests with, as webhooks are
+	APIKey string `yaml:"api_key,omitempty"`
+	// how long to wait for a decision
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+	// allow joins when the callback fails or times out, instead of denying them
+	FailOpen bool `yaml:"fail_open,omitempty"`
+}
+
+// TenancyConfig isolates rooms, egress and ingress of tenants sharing the server, and limits what each of them uses
+type TenancyConfig struct {
+	Enabled bool `yaml:"enabled,omitempty"`
+	// tenant of API keys, keys that are not listed are tenants of their own
+	Keys map[string]string `yaml:"keys,omitempty"`
+	// API keys that act for any tenant, named by a "tenant" claim in their tokens
+	ClaimKeys []string `yaml:"claim_keys,omitempty"`
+	// quotas of tenants that are not listed in Quotas
+	DefaultQuota TenantQuota `yaml:"default_quota,omitempty"`
+	// quotas by tenant
+	Quotas map[string]TenantQuota `yaml:"quotas,omitempty"`
+}
+
+// TenantQuota limits what a tenant uses across the cluster, 0 for no limit
+type TenantQuota struct {
+	// concurrent rooms
+	MaxRooms int `yaml:"max_rooms,omitempty"`
+	// concurrent participants, hidden participants are not counted
+	MaxParticipants int `yaml:"max_participants,omitempty"`
+	// published tracks
+	MaxTracks int `yaml:"max_tracks,omitempty"`
+}
+
+// MeteringConfig records usage of participants, rooms and API keys, as participant and track minutes and bytes
+// transferred, to be billed on
+type MeteringConfig struct {
+	Enabled bool `yaml:"enabled,omitempty"`
+	// period usage is rolled up over, into a record per participant, room and API key
+	Interval time.Duration `yaml:"interval,omitempty"`
+	// file, redis or webhook
+	Sink string `yaml:"sink,omitempty"`
+	// file records are appended to as JSON lines, for file sink
+	Path string `yaml:"path,omitempty"`
+	// URLs records are posted to, for webhook sink. Posts are signed as webhooks are, and retried as configured
+	// for webhooks
+	URLs   []string `yaml:"urls,omitempty"`
+	APIKey string   `yaml:"api_key,omitempty"`
+	// where posts are kept until delivered, for webhook sink. it has to survive restarts, in redis or a directory
+	Spool WebHookSpoolConfig `yaml:"spool,omitempty"`
+	// approximate length the stream of records is capped at, for redis sink
+	MaxRecords int64 `yaml:"max_records,omitempty"`
+}
+
+// AuditConfig records privileged operations of API clients, as a chain of records each carrying the hash of the one
+// before it, for changes to the trail to be evident
+type AuditConfig struct {
+	Enabled bool `yaml:"enabled,omitempty"`
+	// file, redis or webhook
+	Sink string `yaml:"sink,omitempty"`
+	// file records are appended to as JSON lines, for file sink. It's rotated once it reaches max size, keeping
+	// max backups of rotated files
+	Path       string `yaml:"path,omitempty"`
+	MaxSize    int64  `yaml:"max_size,omitempty"`
+	MaxBackups int    `yaml:"max_backups,omitempty"`
+	// URLs records are posted to, for webhook sink
+	URLs []string `yaml:"urls,omitempty"`
+	// API key whose secret keys hashes of records, and signs posts of webhook sink
+	APIKey string `yaml:"api_key,omitempty"`
+	// where posts are kept until delivered, for webhook sink. it has to survive restarts, in redis or a directory
+	Spool WebHookSpoolConfig `yaml:"spool,omitempty"`
+}
+
+// RateLimitConfig limits rates of API requests, signal connections and signal messages, with token buckets.
+// Limits with a rate of 0 are not enforced
+type RateLimitConfig struct {
+	API    APIRateLimitConfig    `yaml:"api,omitempty"`
+	RTC    RTCRateLimitConfig    `yaml:"rtc,omitempty"`
+	Signal SignalRateLimitConfig `yaml:"signal,omitempty"`
+}
+
+// APIRateLimitConfig limits Twirp API requests
+type APIRateLimitConfig struct {
+	APIKey   RateLimit `yaml:"api_key,omitempty"`
+	ClientIP RateLimit `yaml:"client_ip,omitempty"`
+	// requests made on a room, such as on its participants, egress or ingress
+	Room RateLimit `yaml:"room,omitempty"`
+}
+
+// RTCRateLimitConfig limits signal connections to /rtc
+type RTCRateLimitConfig struct {
+	APIKey   RateLimit `yaml:"api_key,omitempty"`
+	ClientIP RateLimit `yaml:"client_ip,omitempty"`
+	Room     RateLimit `yaml:"room,omitempty"`
+}
+
+// SignalRateLimitConfig limits signal messages of each participant, and of all participants of a room
+type SignalRateLimitConfig struct {
+	Participant RateLimit `yaml:"participant,omitempty"`
+	Room        RateLimit `yaml:"room,omitempty"`
+}
+
+type RateLimit struct {
+	// per second
+	Rate float32 `yaml:"rate,omitempty"`
+	// defaults to rate
+	Burst float32 `yaml:"burst,omitempty"`
+}
+
+func (l RateLimit) Enabled() bool {
+	return l.Rate > 0
+}
+
+func (l RateLimit) GetBurst() float64 {
+	if l.Burst >= 1 {
+		return float64(l.Burst)
+	}
+	if l.Rate < 1 {
+		return 1
+	}
+	return float64(l.Rate)
+}
+
 type IngressConfig struct {
 	RTMPBaseURL string `yaml:"rtmp_base_url"`
 }
@@ -242,7 +520,8 @@ func NewConfig(confString string, strictMode bool, c *cli.Context, baseFlags []c
 				// {Mime: webrtc.MimeTypeAV1},
 				// {Mime: webrtc.MimeTypeVP9},
 			},
-			EmptyTimeout: 5 * 60,
+			EmptyTimeout:    5 * 60,
+			DurationWarning: time.Minute,
 		},
 		Logging: LoggingConfig{
 			PionLevel: "error",
@@ -250,13 +529,50 @@ func NewConfig(confString string, strictMode bool, c *cli.Context, baseFlags []c
 		TURN: TURNConfig{
 			Enabled: false,
 		},
+		WebHook: WebHookConfig{
+			MaxAttempts:    20,
+			InitialBackoff: time.Second,
+			MaxBackoff:     5 * time.Minute,
+			Spool: WebHookSpoolConfig{
+				MaxEvents:      100000,
+				MaxDeadLetters: 10000,
+			},
+		},
 		NodeSelector: NodeSelectorConfig{
 			Kind:         "any",
 			SortBy:       "random",
 			SysloadLimit: 0.9,
 			CPULoadLimit: 0.9,
 		},
+		Capture: CaptureConfig{
+			Format:      "pcap",
+			MaxDuration: time.Minute,
+			MaxSize:     100 * 1024 * 1024,
+		},
+		RTPIngest: RTPIngestConfig{
+			IdleTimeout: 30 * time.Second,
+		},
+		Recorder: RecorderConfig{
+			Latency: 500 * time.Millisecond,
+		},
+		Relay: RelayConfig{
+			Mode: RelayModeRegion,
+		},
+		Admission: AdmissionConfig{
+			Timeout: 3 * time.Second,
+		},
+		Metering: MeteringConfig{
+			Interval:   time.Minute,
+			MaxRecords: 1000000,
+		},
+		Audit: AuditConfig{
+			MaxSize:    100 * 1024 * 1024,
+			MaxBackups: 10,
+		},
 		Keys: map[string]string{},
+		KeyProvider: KeyProviderConfig{
+			RefreshInterval: 10 * time.Second,
+		},
 	}
 
 	if confString != "" {
@@ -279,6 +595,30 @@ func NewConfig(confString string, strictMode bool, c *cli.Context, baseFlags []c
 		return nil, err
 	}
 	conf.KeyFile = file
+	if conf.KeyProvider.Dir, err = homedir.Expand(os.ExpandEnv(conf.KeyProvider.Dir)); err != nil {
+		return nil, err
+	}
+
+	if conf.Store.FilePath != "" {
+		if conf.Store.FilePath, err = homedir.Expand(os.ExpandEnv(conf.Store.FilePath)); err != nil {
+			return nil, err
+		}
+	}
+	if conf.WebHook.Spool.Dir != "" {
+		if conf.WebHook.Spool.Dir, err = homedir.Expand(os.ExpandEnv(conf.WebHook.Spool.Dir)); err != nil {
+			return nil, err
+		}
+	}
+	if conf.Capture.Dir != "" {
+		if conf.Capture.Dir, err = homedir.Expand(os.ExpandEnv(conf.Capture.Dir)); err != nil {
+			return nil, err
+		}
+	}
+	if conf.Recorder.Dir != "" {
+		if conf.Recorder.Dir, err = homedir.Expand(os.ExpandEnv(conf.Recorder.Dir)); err != nil {
+			return nil, err
+		}
+	}
 
 	// set defaults for ports if none are set
 	if conf.RTC.UDPPort == 0 && conf.RTC.ICEPortRangeStart == 0 {
@@ -0,0 +1,105 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// settings that can be changed without restarting, by path or path prefix.
+// anything else is held by components that are set up once at startup, such as listeners and the webhook spool
+var reloadableSettings = []string{
+	"webhook.urls",
+	"webhook.api_key",
+	"webhook.endpoints",
+	"webhook.max_attempts",
+	"webhook.initial_backoff",
+	"webhook.max_backoff",
+	"limit",
+	"log_level",
+	"logging",
+	"node_selector",
+	// key_file and key_provider sources are refreshed by the key provider itself
+	"keys",
+	// defaults of new rooms
+	"room.enabled_codecs",
+	"room.max_participants",
+	"room.empty_timeout",
+}
+
+type RestartRequiredError struct {
+	Settings []string
+}
+
+func (e *RestartRequiredError) Error() string {
+	return fmt.Sprintf("changed settings require a restart: %s", strings.Join(e.Settings, ", "))
+}
+
+// CheckReload returns settings that differ between conf and updated, by their yaml path.
+// It returns a *RestartRequiredError when any of them can't be changed on a running server
+func (conf *Config) CheckReload(updated *Config) ([]string, error) {
+	changed := DiffConfig(conf, updated)
+
+	var unsafe []string
+	for _, setting := range changed {
+		if !IsReloadable(setting) {
+			unsafe = append(unsafe, setting)
+		}
+	}
+	if len(unsafe) > 0 {
+		return changed, &RestartRequiredError{Settings: unsafe}
+	}
+	return changed, nil
+}
+
+func IsReloadable(setting string) bool {
+	for _, s := range reloadableSettings {
+		if setting == s || strings.HasPrefix(setting, s+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// DiffConfig returns yaml paths of settings that differ, down to the first field that isn't a struct
+func DiffConfig(a, b *Config) []string {
+	var changed []string
+	diffStruct("", reflect.ValueOf(a).Elem(), reflect.ValueOf(b).Elem(), &changed)
+	return changed
+}
+
+func diffStruct(prefix string, a, b reflect.Value, changed *[]string) {
+	for i := 0; i < a.NumField(); i++ {
+		field := a.Type().Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+
+		tag := strings.Split(field.Tag.Get("yaml"), ",")
+		name := tag[0]
+		if name == "-" {
+			continue
+		}
+		inline := len(tag) > 1 && tag[1] == "inline"
+		if name == "" && !inline {
+			name = strings.ToLower(field.Name)
+		}
+
+		path := prefix
+		if !inline {
+			if path != "" {
+				path += "."
+			}
+			path += name
+		}
+
+		fa, fb := a.Field(i), b.Field(i)
+		if fa.Kind() == reflect.Struct {
+			diffStruct(path, fa, fb, changed)
+			continue
+		}
+		if !reflect.DeepEqual(fa.Interface(), fb.Interface()) {
+			*changed = append(*changed, path)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package config
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig_CheckReload(t *testing.T) {
+	conf, err := NewConfig(`webhook:
+  api_key: key
+  urls:
+    - http://localhost:8080`, true, nil, nil)
+	require.NoError(t, err)
+
+	t.Run("reloadable changes", func(t *testing.T) {
+		updated, err := NewConfig(`webhook:
+  api_key: key
+  urls:
+    - http://localhost:8081
+limit:
+  num_tracks: 10
+logging:
+  level: debug
+room:
+  empty_timeout: 10`, true, nil, nil)
+		require.NoError(t, err)
+
+		changed, err := conf.CheckReload(updated)
+		require.NoError(t, err)
+		require.ElementsMatch(t, []string{"webhook.urls", "limit.num_tracks", "logging.level", "room.empty_timeout"}, changed)
+	})
+
+	t.Run("requires restart", func(t *testing.T) {
+		updated, err := NewConfig(`port: 7890
+webhook:
+  api_key: key
+  urls:
+    - http://localhost:8080
+  spool:
+    kind: memory
+room:
+  auto_create: false`, true, nil, nil)
+		require.NoError(t, err)
+
+		_, err = conf.CheckReload(updated)
+		var restartErr *RestartRequiredError
+		require.True(t, errors.As(err, &restartErr))
+		require.ElementsMatch(t, []string{"port", "webhook.spool.kind", "room.auto_create"}, restartErr.Settings)
+	})
+
+	t.Run("unchanged", func(t *testing.T) {
+		updated, err := NewConfig(`webhook:
+  api_key: key
+  urls:
+    - http://localhost:8080`, true, nil, nil)
+		require.NoError(t, err)
+
+		changed, err := conf.CheckReload(updated)
+		require.NoError(t, err)
+		require.Empty(t, changed)
+	})
+}
@@ -3,6 +3,7 @@ package routing
 import (
 	"context"
 	"encoding/json"
+	"time"
 
 	"github.com/go-redis/redis/v8"
 	"google.golang.org/protobuf/proto"
@@ -10,6 +11,8 @@ import (
 	"github.com/livekit/protocol/auth"
 	"github.com/livekit/protocol/livekit"
 	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/config"
 )
 
 //go:generate go run github.com/maxbrunsfeld/counterfeiter/v6 -generate
@@ -39,6 +42,37 @@ type ParticipantInit struct {
 	Region         string
 	AdaptiveStream bool
 	ID             livekit.ParticipantID
+	// limit of downstream bitrate granted by the token, 0 if unlimited
+	MaxSubscribeBitrate int64
+	// limit of the session granted by the token, 0 if unlimited
+	MaxSessionDuration time.Duration
+	// data topics granted by the token, nil when the participant declares them in its metadata
+	DataTopics []string
+	// number of video slots requested, 0 to subscribe to video tracks individually
+	VideoSlots int
+	// client offers the subscriber connection, once, as WHEP viewers do
+	ClientOffersSubscriber bool
+	// signaled over HTTP, as WHIP and WHEP clients are, which may not open data channels
+	HTTPSignaled bool
+	// media is received outside of PeerConnections, as with RTP ingest. Such sessions are only started on the node
+	// hosting the room, so it isn't carried in StartSession
+	ExternalMedia bool
+	// participant is hosted by another node, which relays its tracks. Started by the relay on this node only
+	Relayed bool
+	// API key the token is signed with, usage of the participant is metered by it
+	APIKey string
+}
+
+// grants carried in StartSession, including those that aren't part of auth.ClaimGrants
+type startSessionGrants struct {
+	*auth.ClaimGrants
+	MaxSubscribeBitrate    int64         `json:"maxSubscribeBitrate,omitempty"`
+	MaxSessionDuration     time.Duration `json:"maxSessionDuration,omitempty"`
+	DataTopics             []string      `json:"dataTopics,omitempty"`
+	VideoSlots             int           `json:"videoSlots,omitempty"`
+	ClientOffersSubscriber bool          `json:"clientOffersSubscriber,omitempty"`
+	HTTPSignaled           bool          `json:"httpSignaled,omitempty"`
+	APIKey                 string        `json:"apiKey,omitempty"`
 }
 
 type NewParticipantCallback func(
@@ -93,9 +127,9 @@ type MessageRouter interface {
 	WriteRoomRTC(ctx context.Context, roomName livekit.RoomName, msg *livekit.RTCNodeMessage) error
 }
 
-func CreateRouter(rc redis.UniversalClient, node LocalNode) Router {
+func CreateRouter(conf *config.Config, rc redis.UniversalClient, node LocalNode) Router {
 	if rc != nil {
-		return NewRedisRouter(node, rc)
+		return NewRedisRouter(node, rc, conf.Relay)
 	}
 
 	// local routing and store
@@ -104,7 +138,16 @@ func CreateRouter(rc redis.UniversalClient, node LocalNode) Router {
 }
 
 func (pi *ParticipantInit) ToStartSession(roomName livekit.RoomName, connectionID livekit.ConnectionID) (*livekit.StartSession, error) {
-	claims, err := json.Marshal(pi.Grants)
+	claims, err := json.Marshal(&startSessionGrants{
+		ClaimGrants:            pi.Grants,
+		MaxSubscribeBitrate:    pi.MaxSubscribeBitrate,
+		MaxSessionDuration:     pi.MaxSessionDuration,
+		DataTopics:             pi.DataTopics,
+		VideoSlots:             pi.VideoSlots,
+		ClientOffersSubscriber: pi.ClientOffersSubscriber,
+		HTTPSignaled:           pi.HTTPSignaled,
+		APIKey:                 pi.APIKey,
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -125,7 +168,7 @@ func (pi *ParticipantInit) ToStartSession(roomName livekit.RoomName, connectionI
 }
 
 func ParticipantInitFromStartSession(ss *livekit.StartSession, region string) (*ParticipantInit, error) {
-	claims := &auth.ClaimGrants{}
+	claims := &startSessionGrants{ClaimGrants: &auth.ClaimGrants{}}
 	if err := json.Unmarshal([]byte(ss.GrantsJson), claims); err != nil {
 		return nil, err
 	}
@@ -136,9 +179,17 @@ func ParticipantInitFromStartSession(ss *livekit.StartSession, region string) (*
 		Reconnect:      ss.Reconnect,
 		Client:         ss.Client,
 		AutoSubscribe:  ss.AutoSubscribe,
-		Grants:         claims,
+		Grants:         claims.ClaimGrants,
 		Region:         region,
 		AdaptiveStream: ss.AdaptiveStream,
 		ID:             livekit.ParticipantID(ss.ParticipantId),
+
+		MaxSubscribeBitrate:    claims.MaxSubscribeBitrate,
+		MaxSessionDuration:     claims.MaxSessionDuration,
+		DataTopics:             claims.DataTopics,
+		VideoSlots:             claims.VideoSlots,
+		ClientOffersSubscriber: claims.ClientOffersSubscriber,
+		HTTPSignaled:           claims.HTTPSignaled,
+		APIKey:                 claims.APIKey,
 	}, nil
 }
@@ -16,6 +16,7 @@ import (
 	"github.com/livekit/protocol/logger"
 	"github.com/livekit/protocol/utils"
 
+	"github.com/livekit/livekit-server/pkg/config"
 	"github.com/livekit/livekit-server/pkg/routing/selector"
 	"github.com/livekit/livekit-server/pkg/telemetry/prometheus"
 )
@@ -34,6 +35,7 @@ type RedisRouter struct {
 	LocalRouter
 
 	rc        redis.UniversalClient
+	relayConf config.RelayConfig
 	ctx       context.Context
 	isStarted atomic.Bool
 	nodeMu    sync.RWMutex
@@ -44,10 +46,11 @@ type RedisRouter struct {
 	cancel func()
 }
 
-func NewRedisRouter(currentNode LocalNode, rc redis.UniversalClient) *RedisRouter {
+func NewRedisRouter(currentNode LocalNode, rc redis.UniversalClient, relayConf config.RelayConfig) *RedisRouter {
 	rr := &RedisRouter{
 		LocalRouter: *NewLocalRouter(currentNode),
 		rc:          rc,
+		relayConf:   relayConf,
 	}
 	rr.ctx, rr.cancel = context.WithCancel(context.Background())
 	return rr
@@ -141,10 +144,11 @@ func (r *RedisRouter) ListNodes() ([]*livekit.Node, error) {
 // StartParticipantSignal signal connection sets up paths to the RTC node, and starts to route messages to that message queue
 func (r *RedisRouter) StartParticipantSignal(ctx context.Context, roomName livekit.RoomName, pi ParticipantInit) (connectionID livekit.ConnectionID, reqSink MessageSink, resSource MessageSource, err error) {
 	// find the node where the room is hosted at
-	rtcNode, err := r.GetNodeForRoom(ctx, roomName)
+	roomNode, err := r.GetNodeForRoom(ctx, roomName)
 	if err != nil {
 		return
 	}
+	rtcNode := r.getRTCNodeForParticipant(roomName, pi, roomNode)
 
 	// create a new connection id
 	connectionID = livekit.ConnectionID(utils.NewGuid("CO_"))
@@ -207,7 +211,8 @@ func (r *RedisRouter) startParticipantRTC(ss *livekit.StartSession, participantK
 		return err
 	}
 
-	if rtcNode.Id != r.currentNode.Id {
+	// with relay, participants may be hosted by nodes other than the room's
+	if rtcNode.Id != r.currentNode.Id && !r.relayConf.Enabled {
 		err = ErrIncorrectRTCNode
 		logger.Errorw("called participant on incorrect node", err,
 			"rtcNode", rtcNode,
@@ -215,7 +220,7 @@ func (r *RedisRouter) startParticipantRTC(ss *livekit.StartSession, participantK
 		return err
 	}
 
-	if err := r.setParticipantRTCNode(participantKey, rtcNode.Id); err != nil {
+	if err := r.setParticipantRTCNode(participantKey, r.currentNode.Id); err != nil {
 		return err
 	}
 
@@ -268,6 +273,27 @@ func (r *RedisRouter) startParticipantRTC(ss *livekit.StartSession, participantK
 	return nil
 }
 
+// getRTCNodeForParticipant returns the node to host a participant. With relay, that is the node it connected to when
+// the room is hosted in another region, or always, depending on mode. A reconnecting participant stays on its node
+func (r *RedisRouter) getRTCNodeForParticipant(roomName livekit.RoomName, pi ParticipantInit, roomNode *livekit.Node) *livekit.Node {
+	if !r.relayConf.Enabled || roomNode.Id == r.currentNode.Id {
+		return roomNode
+	}
+
+	if pi.Reconnect {
+		if nodeID, err := r.getParticipantRTCNode(participantKey(roomName, pi.Identity)); err == nil {
+			if node, err := r.GetNode(livekit.NodeID(nodeID)); err == nil && selector.IsAvailable(node) {
+				return node
+			}
+		}
+	}
+
+	if r.relayConf.Mode == config.RelayModeAlways || roomNode.Region != r.currentNode.Region {
+		return (*livekit.Node)(r.currentNode)
+	}
+	return roomNode
+}
+
 func (r *RedisRouter) Start() error {
 	if r.isStarted.Swap(true) {
 		return nil
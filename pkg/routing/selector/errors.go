@@ -8,4 +8,6 @@ var (
 	ErrCurrentRegionUnknownLatLon = errors.New("unknown lat and lon for the current region")
 	ErrSortByNotSet               = errors.New("sort by option cannot be blank")
 	ErrSortByUnknown              = errors.New("unknown sort by option")
+	ErrWeightsNotSet              = errors.New("at least one weight must be set")
+	ErrNodeLimitsReached          = errors.New("all available nodes have reached their limits")
 )
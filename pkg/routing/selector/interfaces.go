@@ -41,6 +41,8 @@ func CreateNodeSelector(conf *config.Config) (NodeSelector, error) {
 		}
 		s.SysloadLimit = conf.NodeSelector.SysloadLimit
 		return s, nil
+	case "weighted":
+		return NewWeightedSelector(conf.Region, conf.NodeSelector, conf.Limit)
 	case "random":
 		logger.Warnw("random node selector is deprecated, please switch to \"any\" or another selector", nil)
 		return &AnySelector{conf.NodeSelector.SortBy}, nil
@@ -22,15 +22,24 @@ func NewRegionAwareSelector(currentRegion string, regions []config.RegionConfig,
 		return nil, ErrCurrentRegionNotSet
 	}
 	// build internal map of distances
-	s := &RegionAwareSelector{
+	regionDistances, err := getRegionDistances(currentRegion, regions)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RegionAwareSelector{
 		CurrentRegion:   currentRegion,
-		regionDistances: make(map[string]float64),
+		regionDistances: regionDistances,
 		regions:         regions,
 		SortBy:          sortBy,
-	}
+	}, nil
+}
 
-	var currentRC *config.RegionConfig
+// getRegionDistances returns distances of regions from the current region
+func getRegionDistances(currentRegion string, regions []config.RegionConfig) (map[string]float64, error) {
+	regionDistances := make(map[string]float64)
 
+	var currentRC *config.RegionConfig
 	for _, region := range regions {
 		if region.Name == currentRegion {
 			currentRC = &region
@@ -44,11 +53,11 @@ func NewRegionAwareSelector(currentRegion string, regions []config.RegionConfig,
 
 	if currentRC != nil {
 		for _, region := range regions {
-			s.regionDistances[region.Name] = distanceBetween(currentRC.Lat, currentRC.Lon, region.Lat, region.Lon)
+			regionDistances[region.Name] = distanceBetween(currentRC.Lat, currentRC.Lon, region.Lat, region.Lon)
 		}
 	}
 
-	return s, nil
+	return regionDistances, nil
 }
 
 func (s *RegionAwareSelector) SelectNode(nodes []*livekit.Node) (*livekit.Node, error) {
@@ -0,0 +1,167 @@
+package selector
+
+import (
+	"math"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/config"
+)
+
+// scores within this of the lowest are considered tied
+const weightedScoreTolerance = 1e-6
+
+// WeightedSelector scores available nodes on a weighted blend of metrics and selects the node with the lowest score.
+// CPU load and system load per CPU are scored as they are, other metrics relative to the highest value among nodes.
+// Nodes that reached limits are never selected, nodes above CPULoadLimit or SysloadLimit only when all others are.
+// Ties are broken with SortBy
+type WeightedSelector struct {
+	Weights      config.NodeSelectorWeights
+	Limit        config.LimitConfig
+	CPULoadLimit float32
+	SysloadLimit float32
+	SortBy       string
+
+	regionDistances map[string]float64
+}
+
+func NewWeightedSelector(currentRegion string, conf config.NodeSelectorConfig, limit config.LimitConfig) (*WeightedSelector, error) {
+	w := conf.Weights
+	if w.CPULoad <= 0 && w.Sysload <= 0 && w.Rooms <= 0 && w.Clients <= 0 && w.Tracks <= 0 && w.BytesPerSec <= 0 && w.Region <= 0 {
+		return nil, ErrWeightsNotSet
+	}
+
+	s := &WeightedSelector{
+		Weights:      w,
+		Limit:        limit,
+		CPULoadLimit: conf.CPULoadLimit,
+		SysloadLimit: conf.SysloadLimit,
+		SortBy:       conf.SortBy,
+	}
+	if w.Region > 0 {
+		if currentRegion == "" {
+			return nil, ErrCurrentRegionNotSet
+		}
+		regionDistances, err := getRegionDistances(currentRegion, conf.Regions)
+		if err != nil {
+			return nil, err
+		}
+		s.regionDistances = regionDistances
+	}
+
+	return s, nil
+}
+
+func (s *WeightedSelector) filterNodes(nodes []*livekit.Node) ([]*livekit.Node, error) {
+	nodes = GetAvailableNodes(nodes)
+	if len(nodes) == 0 {
+		return nil, ErrNoAvailableNodes
+	}
+
+	var withinLimits, lowLoad []*livekit.Node
+	for _, node := range nodes {
+		if LimitsReached(s.Limit, node.Stats) {
+			continue
+		}
+		withinLimits = append(withinLimits, node)
+
+		if node.Stats == nil || ((s.CPULoadLimit <= 0 || node.Stats.CpuLoad < s.CPULoadLimit) &&
+			(s.SysloadLimit <= 0 || GetNodeSysload(node) < s.SysloadLimit)) {
+			lowLoad = append(lowLoad, node)
+		}
+	}
+	if len(withinLimits) == 0 {
+		return nil, ErrNodeLimitsReached
+	}
+	if len(lowLoad) > 0 {
+		return lowLoad, nil
+	}
+	return withinLimits, nil
+}
+
+func (s *WeightedSelector) SelectNode(nodes []*livekit.Node) (*livekit.Node, error) {
+	nodes, err := s.filterNodes(nodes)
+	if err != nil {
+		return nil, err
+	}
+
+	scores := s.scoreNodes(nodes)
+	minScore := math.MaxFloat64
+	for _, score := range scores {
+		minScore = math.Min(minScore, score)
+	}
+
+	var tied []*livekit.Node
+	for i, node := range nodes {
+		if scores[i]-minScore <= weightedScoreTolerance {
+			tied = append(tied, node)
+		}
+	}
+	if len(tied) == 1 {
+		return tied[0], nil
+	}
+
+	return SelectSortedNode(tied, s.SortBy)
+}
+
+// scoreNodes returns the score of each node, lower is better
+func (s *WeightedSelector) scoreNodes(nodes []*livekit.Node) []float64 {
+	type nodeMetrics struct {
+		cpuLoad, sysload, rooms, clients, tracks, bytesPerSec, distance float64
+	}
+
+	metrics := make([]nodeMetrics, len(nodes))
+	var highest nodeMetrics
+	for i, node := range nodes {
+		m := &metrics[i]
+		if stats := node.Stats; stats != nil {
+			m.cpuLoad = float64(stats.CpuLoad)
+			m.sysload = float64(GetNodeSysload(node))
+			m.rooms = float64(stats.NumRooms)
+			m.clients = float64(stats.NumClients)
+			m.tracks = float64(stats.NumTracksIn + stats.NumTracksOut)
+			m.bytesPerSec = float64(stats.BytesInPerSec + stats.BytesOutPerSec)
+		}
+		if s.Weights.Region > 0 {
+			if distance, ok := s.regionDistances[node.Region]; ok {
+				m.distance = distance
+			} else {
+				// unknown regions are farthest
+				m.distance = math.Inf(1)
+			}
+		}
+
+		highest.rooms = math.Max(highest.rooms, m.rooms)
+		highest.clients = math.Max(highest.clients, m.clients)
+		highest.tracks = math.Max(highest.tracks, m.tracks)
+		highest.bytesPerSec = math.Max(highest.bytesPerSec, m.bytesPerSec)
+		if !math.IsInf(m.distance, 1) {
+			highest.distance = math.Max(highest.distance, m.distance)
+		}
+	}
+
+	w := s.Weights
+	scores := make([]float64, len(nodes))
+	for i, m := range metrics {
+		distance := relativeTo(m.distance, highest.distance)
+		if math.IsInf(m.distance, 1) {
+			distance = 1
+		}
+
+		scores[i] = float64(w.CPULoad)*m.cpuLoad +
+			float64(w.Sysload)*m.sysload +
+			float64(w.Rooms)*relativeTo(m.rooms, highest.rooms) +
+			float64(w.Clients)*relativeTo(m.clients, highest.clients) +
+			float64(w.Tracks)*relativeTo(m.tracks, highest.tracks) +
+			float64(w.BytesPerSec)*relativeTo(m.bytesPerSec, highest.bytesPerSec) +
+			float64(w.Region)*distance
+	}
+	return scores
+}
+
+func relativeTo(value, highest float64) float64 {
+	if highest <= 0 {
+		return 0
+	}
+	return value / highest
+}
@@ -0,0 +1,119 @@
+package selector_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/routing/selector"
+)
+
+func TestWeightedSelector(t *testing.T) {
+	newNode := func(id string, region string, cpuLoad float32, numTracks int32) *livekit.Node {
+		return &livekit.Node{
+			Id:     id,
+			Region: region,
+			State:  livekit.NodeState_SERVING,
+			Stats: &livekit.NodeStats{
+				UpdatedAt:   time.Now().Unix(),
+				NumCpus:     1,
+				CpuLoad:     cpuLoad,
+				NumTracksIn: numTracks,
+			},
+		}
+	}
+
+	t.Run("requires weights", func(t *testing.T) {
+		_, err := selector.NewWeightedSelector("", config.NodeSelectorConfig{SortBy: sortBy}, config.LimitConfig{})
+		require.ErrorIs(t, err, selector.ErrWeightsNotSet)
+	})
+
+	t.Run("blends metrics", func(t *testing.T) {
+		s, err := selector.NewWeightedSelector("", config.NodeSelectorConfig{
+			SortBy:  sortBy,
+			Weights: config.NodeSelectorWeights{CPULoad: 1, Tracks: 1},
+		}, config.LimitConfig{})
+		require.NoError(t, err)
+
+		// idle by CPU, but saturated by tracks
+		idleCPU := newNode("idle-cpu", "", 0.1, 100)
+		balanced := newNode("balanced", "", 0.3, 20)
+		busyCPU := newNode("busy-cpu", "", 0.8, 10)
+
+		node, err := s.SelectNode([]*livekit.Node{idleCPU, balanced, busyCPU})
+		require.NoError(t, err)
+		require.Equal(t, "balanced", node.Id)
+	})
+
+	t.Run("skips nodes that reached limits", func(t *testing.T) {
+		s, err := selector.NewWeightedSelector("", config.NodeSelectorConfig{
+			SortBy:  sortBy,
+			Weights: config.NodeSelectorWeights{CPULoad: 1},
+		}, config.LimitConfig{NumTracks: 50})
+		require.NoError(t, err)
+
+		node, err := s.SelectNode([]*livekit.Node{newNode("full", "", 0.1, 50), newNode("busy", "", 0.8, 10)})
+		require.NoError(t, err)
+		require.Equal(t, "busy", node.Id)
+
+		_, err = s.SelectNode([]*livekit.Node{newNode("full", "", 0.1, 50)})
+		require.ErrorIs(t, err, selector.ErrNodeLimitsReached)
+	})
+
+	t.Run("prefers nodes below load limit", func(t *testing.T) {
+		s, err := selector.NewWeightedSelector("", config.NodeSelectorConfig{
+			SortBy:       sortBy,
+			CPULoadLimit: 0.5,
+			Weights:      config.NodeSelectorWeights{Tracks: 1},
+		}, config.LimitConfig{})
+		require.NoError(t, err)
+
+		node, err := s.SelectNode([]*livekit.Node{newNode("overloaded", "", 0.9, 0), newNode("loaded", "", 0.4, 100)})
+		require.NoError(t, err)
+		require.Equal(t, "loaded", node.Id)
+	})
+
+	t.Run("scores region distance", func(t *testing.T) {
+		s, err := selector.NewWeightedSelector(regionWest, config.NodeSelectorConfig{
+			SortBy: sortBy,
+			Regions: []config.RegionConfig{
+				{Name: regionWest, Lat: 37.64046607830567, Lon: -120.88026233189062},
+				{Name: regionEast, Lat: 40.68914362140307, Lon: -74.04445748616385},
+				{Name: regionSeattle, Lat: 47.620426730945454, Lon: -122.34938468973702},
+			},
+			Weights: config.NodeSelectorWeights{CPULoad: 1, Region: 1},
+		}, config.LimitConfig{})
+		require.NoError(t, err)
+
+		nodes := []*livekit.Node{
+			newNode("east", regionEast, 0.1, 0),
+			newNode("seattle", regionSeattle, 0.2, 0),
+			newNode("west", regionWest, 0.9, 0),
+		}
+		node, err := s.SelectNode(nodes)
+		require.NoError(t, err)
+		require.Equal(t, "seattle", node.Id)
+
+		_, err = selector.NewWeightedSelector("", config.NodeSelectorConfig{
+			SortBy:  sortBy,
+			Weights: config.NodeSelectorWeights{Region: 1},
+		}, config.LimitConfig{})
+		require.ErrorIs(t, err, selector.ErrCurrentRegionNotSet)
+	})
+
+	t.Run("breaks ties with sort by", func(t *testing.T) {
+		s, err := selector.NewWeightedSelector("", config.NodeSelectorConfig{
+			SortBy:  "tracks",
+			Weights: config.NodeSelectorWeights{CPULoad: 1},
+		}, config.LimitConfig{})
+		require.NoError(t, err)
+
+		node, err := s.SelectNode([]*livekit.Node{newNode("more", "", 0.5, 20), newNode("fewer", "", 0.5, 10)})
+		require.NoError(t, err)
+		require.Equal(t, "fewer", node.Id)
+	})
+}
@@ -0,0 +1,5 @@
+// Package rpc holds the Twirp services and messages of the server that are not part of the protocol.
+// LIVEKIT_PROTOCOL_DIR is a checkout of github.com/livekit/protocol, for the protocol's messages.
+package rpc
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --twirp_out=. --twirp_opt=paths=source_relative -I=. -I=$LIVEKIT_PROTOCOL_DIR waiting_room.proto
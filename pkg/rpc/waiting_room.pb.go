@@ -0,0 +1,772 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.28.1
+// 	protoc        (unknown)
+// source: waiting_room.proto
+
+package rpc
+
+import (
+	livekit "github.com/livekit/protocol/livekit"
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type ListWaitingParticipantsRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// name of the room
+	Room string `protobuf:"bytes,1,opt,name=room,proto3" json:"room,omitempty"`
+}
+
+func (x *ListWaitingParticipantsRequest) Reset() {
+	*x = ListWaitingParticipantsRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_waiting_room_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ListWaitingParticipantsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListWaitingParticipantsRequest) ProtoMessage() {}
+
+func (x *ListWaitingParticipantsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_waiting_room_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListWaitingParticipantsRequest.ProtoReflect.Descriptor instead.
+func (*ListWaitingParticipantsRequest) Descriptor() ([]byte, []int) {
+	return file_waiting_room_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *ListWaitingParticipantsRequest) GetRoom() string {
+	if x != nil {
+		return x.Room
+	}
+	return ""
+}
+
+type ListWaitingParticipantsResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Participants []*livekit.ParticipantInfo `protobuf:"bytes,1,rep,name=participants,proto3" json:"participants,omitempty"`
+}
+
+func (x *ListWaitingParticipantsResponse) Reset() {
+	*x = ListWaitingParticipantsResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_waiting_room_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ListWaitingParticipantsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListWaitingParticipantsResponse) ProtoMessage() {}
+
+func (x *ListWaitingParticipantsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_waiting_room_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListWaitingParticipantsResponse.ProtoReflect.Descriptor instead.
+func (*ListWaitingParticipantsResponse) Descriptor() ([]byte, []int) {
+	return file_waiting_room_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *ListWaitingParticipantsResponse) GetParticipants() []*livekit.ParticipantInfo {
+	if x != nil {
+		return x.Participants
+	}
+	return nil
+}
+
+type AdmitParticipantRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Room     string `protobuf:"bytes,1,opt,name=room,proto3" json:"room,omitempty"`
+	Identity string `protobuf:"bytes,2,opt,name=identity,proto3" json:"identity,omitempty"`
+	// metadata to join with, replacing the one of the participant's token
+	Metadata string `protobuf:"bytes,3,opt,name=metadata,proto3" json:"metadata,omitempty"`
+	// permission to join with, replacing the one of the participant's token
+	Permission *livekit.ParticipantPermission `protobuf:"bytes,4,opt,name=permission,proto3" json:"permission,omitempty"`
+	// display name to join with, replacing the one of the participant's token
+	Name string `protobuf:"bytes,5,opt,name=name,proto3" json:"name,omitempty"`
+}
+
+func (x *AdmitParticipantRequest) Reset() {
+	*x = AdmitParticipantRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_waiting_room_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *AdmitParticipantRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AdmitParticipantRequest) ProtoMessage() {}
+
+func (x *AdmitParticipantRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_waiting_room_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AdmitParticipantRequest.ProtoReflect.Descriptor instead.
+func (*AdmitParticipantRequest) Descriptor() ([]byte, []int) {
+	return file_waiting_room_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *AdmitParticipantRequest) GetRoom() string {
+	if x != nil {
+		return x.Room
+	}
+	return ""
+}
+
+func (x *AdmitParticipantRequest) GetIdentity() string {
+	if x != nil {
+		return x.Identity
+	}
+	return ""
+}
+
+func (x *AdmitParticipantRequest) GetMetadata() string {
+	if x != nil {
+		return x.Metadata
+	}
+	return ""
+}
+
+func (x *AdmitParticipantRequest) GetPermission() *livekit.ParticipantPermission {
+	if x != nil {
+		return x.Permission
+	}
+	return nil
+}
+
+func (x *AdmitParticipantRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+type RejectParticipantRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Room     string `protobuf:"bytes,1,opt,name=room,proto3" json:"room,omitempty"`
+	Identity string `protobuf:"bytes,2,opt,name=identity,proto3" json:"identity,omitempty"`
+}
+
+func (x *RejectParticipantRequest) Reset() {
+	*x = RejectParticipantRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_waiting_room_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *RejectParticipantRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RejectParticipantRequest) ProtoMessage() {}
+
+func (x *RejectParticipantRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_waiting_room_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RejectParticipantRequest.ProtoReflect.Descriptor instead.
+func (*RejectParticipantRequest) Descriptor() ([]byte, []int) {
+	return file_waiting_room_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *RejectParticipantRequest) GetRoom() string {
+	if x != nil {
+		return x.Room
+	}
+	return ""
+}
+
+func (x *RejectParticipantRequest) GetIdentity() string {
+	if x != nil {
+		return x.Identity
+	}
+	return ""
+}
+
+type RejectParticipantResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *RejectParticipantResponse) Reset() {
+	*x = RejectParticipantResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_waiting_room_proto_msgTypes[4]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *RejectParticipantResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RejectParticipantResponse) ProtoMessage() {}
+
+func (x *RejectParticipantResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_waiting_room_proto_msgTypes[4]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RejectParticipantResponse.ProtoReflect.Descriptor instead.
+func (*RejectParticipantResponse) Descriptor() ([]byte, []int) {
+	return file_waiting_room_proto_rawDescGZIP(), []int{4}
+}
+
+// participant waiting to join a gated room, as stored
+type WaitingParticipant struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Participant *livekit.ParticipantInfo `protobuf:"bytes,1,opt,name=participant,proto3" json:"participant,omitempty"`
+	// node the participant is connected to, and waiting on
+	NodeId string `protobuf:"bytes,2,opt,name=node_id,json=nodeId,proto3" json:"node_id,omitempty"`
+}
+
+func (x *WaitingParticipant) Reset() {
+	*x = WaitingParticipant{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_waiting_room_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *WaitingParticipant) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WaitingParticipant) ProtoMessage() {}
+
+func (x *WaitingParticipant) ProtoReflect() protoreflect.Message {
+	mi := &file_waiting_room_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WaitingParticipant.ProtoReflect.Descriptor instead.
+func (*WaitingParticipant) Descriptor() ([]byte, []int) {
+	return file_waiting_room_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *WaitingParticipant) GetParticipant() *livekit.ParticipantInfo {
+	if x != nil {
+		return x.Participant
+	}
+	return nil
+}
+
+func (x *WaitingParticipant) GetNodeId() string {
+	if x != nil {
+		return x.NodeId
+	}
+	return ""
+}
+
+// message to the node participants of a room wait on, or to the nodes hosting the room
+type WaitingRoomMessage struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Room string `protobuf:"bytes,1,opt,name=room,proto3" json:"room,omitempty"`
+	// Types that are assignable to Message:
+	//	*WaitingRoomMessage_Admit
+	//	*WaitingRoomMessage_Reject
+	//	*WaitingRoomMessage_Update
+	Message isWaitingRoomMessage_Message `protobuf_oneof:"message"`
+}
+
+func (x *WaitingRoomMessage) Reset() {
+	*x = WaitingRoomMessage{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_waiting_room_proto_msgTypes[6]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *WaitingRoomMessage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WaitingRoomMessage) ProtoMessage() {}
+
+func (x *WaitingRoomMessage) ProtoReflect() protoreflect.Message {
+	mi := &file_waiting_room_proto_msgTypes[6]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WaitingRoomMessage.ProtoReflect.Descriptor instead.
+func (*WaitingRoomMessage) Descriptor() ([]byte, []int) {
+	return file_waiting_room_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *WaitingRoomMessage) GetRoom() string {
+	if x != nil {
+		return x.Room
+	}
+	return ""
+}
+
+func (m *WaitingRoomMessage) GetMessage() isWaitingRoomMessage_Message {
+	if m != nil {
+		return m.Message
+	}
+	return nil
+}
+
+func (x *WaitingRoomMessage) GetAdmit() *AdmitParticipantRequest {
+	if x, ok := x.GetMessage().(*WaitingRoomMessage_Admit); ok {
+		return x.Admit
+	}
+	return nil
+}
+
+func (x *WaitingRoomMessage) GetReject() *RejectParticipantRequest {
+	if x, ok := x.GetMessage().(*WaitingRoomMessage_Reject); ok {
+		return x.Reject
+	}
+	return nil
+}
+
+func (x *WaitingRoomMessage) GetUpdate() *livekit.ParticipantInfo {
+	if x, ok := x.GetMessage().(*WaitingRoomMessage_Update); ok {
+		return x.Update
+	}
+	return nil
+}
+
+type isWaitingRoomMessage_Message interface {
+	isWaitingRoomMessage_Message()
+}
+
+type WaitingRoomMessage_Admit struct {
+	// admits a participant waiting on the node
+	Admit *AdmitParticipantRequest `protobuf:"bytes,2,opt,name=admit,proto3,oneof"`
+}
+
+type WaitingRoomMessage_Reject struct {
+	// turns away a participant waiting on the node
+	Reject *RejectParticipantRequest `protobuf:"bytes,3,opt,name=reject,proto3,oneof"`
+}
+
+type WaitingRoomMessage_Update struct {
+	// a participant started or stopped waiting, to be announced to room admins hosted by the node
+	Update *livekit.ParticipantInfo `protobuf:"bytes,4,opt,name=update,proto3,oneof"`
+}
+
+func (*WaitingRoomMessage_Admit) isWaitingRoomMessage_Message() {}
+
+func (*WaitingRoomMessage_Reject) isWaitingRoomMessage_Message() {}
+
+func (*WaitingRoomMessage_Update) isWaitingRoomMessage_Message() {}
+
+// sent to a participant when it starts waiting to join a gated room. It's carried by the SignalResponse with the
+// participant's own JOINING update, in field 1000 which the protocol leaves unused
+type WaitingResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// name of the room
+	Room        string                   `protobuf:"bytes,1,opt,name=room,proto3" json:"room,omitempty"`
+	Participant *livekit.ParticipantInfo `protobuf:"bytes,2,opt,name=participant,proto3" json:"participant,omitempty"`
+}
+
+func (x *WaitingResponse) Reset() {
+	*x = WaitingResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_waiting_room_proto_msgTypes[7]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *WaitingResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WaitingResponse) ProtoMessage() {}
+
+func (x *WaitingResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_waiting_room_proto_msgTypes[7]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WaitingResponse.ProtoReflect.Descriptor instead.
+func (*WaitingResponse) Descriptor() ([]byte, []int) {
+	return file_waiting_room_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *WaitingResponse) GetRoom() string {
+	if x != nil {
+		return x.Room
+	}
+	return ""
+}
+
+func (x *WaitingResponse) GetParticipant() *livekit.ParticipantInfo {
+	if x != nil {
+		return x.Participant
+	}
+	return nil
+}
+
+var File_waiting_room_proto protoreflect.FileDescriptor
+
+var file_waiting_room_proto_rawDesc = []byte{
+	0x0a, 0x12, 0x77, 0x61, 0x69, 0x74, 0x69, 0x6e, 0x67, 0x5f, 0x72, 0x6f, 0x6f, 0x6d, 0x2e, 0x70,
+	0x72, 0x6f, 0x74, 0x6f, 0x12, 0x07, 0x6c, 0x69, 0x76, 0x65, 0x6b, 0x69, 0x74, 0x1a, 0x14, 0x6c,
+	0x69, 0x76, 0x65, 0x6b, 0x69, 0x74, 0x5f, 0x6d, 0x6f, 0x64, 0x65, 0x6c, 0x73, 0x2e, 0x70, 0x72,
+	0x6f, 0x74, 0x6f, 0x22, 0x34, 0x0a, 0x1e, 0x4c, 0x69, 0x73, 0x74, 0x57, 0x61, 0x69, 0x74, 0x69,
+	0x6e, 0x67, 0x50, 0x61, 0x72, 0x74, 0x69, 0x63, 0x69, 0x70, 0x61, 0x6e, 0x74, 0x73, 0x52, 0x65,
+	0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x12, 0x0a, 0x04, 0x72, 0x6f, 0x6f, 0x6d, 0x18, 0x01, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x04, 0x72, 0x6f, 0x6f, 0x6d, 0x22, 0x5f, 0x0a, 0x1f, 0x4c, 0x69, 0x73,
+	0x74, 0x57, 0x61, 0x69, 0x74, 0x69, 0x6e, 0x67, 0x50, 0x61, 0x72, 0x74, 0x69, 0x63, 0x69, 0x70,
+	0x61, 0x6e, 0x74, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x3c, 0x0a, 0x0c,
+	0x70, 0x61, 0x72, 0x74, 0x69, 0x63, 0x69, 0x70, 0x61, 0x6e, 0x74, 0x73, 0x18, 0x01, 0x20, 0x03,
+	0x28, 0x0b, 0x32, 0x18, 0x2e, 0x6c, 0x69, 0x76, 0x65, 0x6b, 0x69, 0x74, 0x2e, 0x50, 0x61, 0x72,
+	0x74, 0x69, 0x63, 0x69, 0x70, 0x61, 0x6e, 0x74, 0x49, 0x6e, 0x66, 0x6f, 0x52, 0x0c, 0x70, 0x61,
+	0x72, 0x74, 0x69, 0x63, 0x69, 0x70, 0x61, 0x6e, 0x74, 0x73, 0x22, 0xb9, 0x01, 0x0a, 0x17, 0x41,
+	0x64, 0x6d, 0x69, 0x74, 0x50, 0x61, 0x72, 0x74, 0x69, 0x63, 0x69, 0x70, 0x61, 0x6e, 0x74, 0x52,
+	0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x12, 0x0a, 0x04, 0x72, 0x6f, 0x6f, 0x6d, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x72, 0x6f, 0x6f, 0x6d, 0x12, 0x1a, 0x0a, 0x08, 0x69, 0x64,
+	0x65, 0x6e, 0x74, 0x69, 0x74, 0x79, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x69, 0x64,
+	0x65, 0x6e, 0x74, 0x69, 0x74, 0x79, 0x12, 0x1a, 0x0a, 0x08, 0x6d, 0x65, 0x74, 0x61, 0x64, 0x61,
+	0x74, 0x61, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x6d, 0x65, 0x74, 0x61, 0x64, 0x61,
+	0x74, 0x61, 0x12, 0x3e, 0x0a, 0x0a, 0x70, 0x65, 0x72, 0x6d, 0x69, 0x73, 0x73, 0x69, 0x6f, 0x6e,
+	0x18, 0x04, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1e, 0x2e, 0x6c, 0x69, 0x76, 0x65, 0x6b, 0x69, 0x74,
+	0x2e, 0x50, 0x61, 0x72, 0x74, 0x69, 0x63, 0x69, 0x70, 0x61, 0x6e, 0x74, 0x50, 0x65, 0x72, 0x6d,
+	0x69, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x52, 0x0a, 0x70, 0x65, 0x72, 0x6d, 0x69, 0x73, 0x73, 0x69,
+	0x6f, 0x6e, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x05, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x22, 0x4a, 0x0a, 0x18, 0x52, 0x65, 0x6a, 0x65, 0x63, 0x74,
+	0x50, 0x61, 0x72, 0x74, 0x69, 0x63, 0x69, 0x70, 0x61, 0x6e, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65,
+	0x73, 0x74, 0x12, 0x12, 0x0a, 0x04, 0x72, 0x6f, 0x6f, 0x6d, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x04, 0x72, 0x6f, 0x6f, 0x6d, 0x12, 0x1a, 0x0a, 0x08, 0x69, 0x64, 0x65, 0x6e, 0x74, 0x69,
+	0x74, 0x79, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x69, 0x64, 0x65, 0x6e, 0x74, 0x69,
+	0x74, 0x79, 0x22, 0x1b, 0x0a, 0x19, 0x52, 0x65, 0x6a, 0x65, 0x63, 0x74, 0x50, 0x61, 0x72, 0x74,
+	0x69, 0x63, 0x69, 0x70, 0x61, 0x6e, 0x74, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22,
+	0x69, 0x0a, 0x12, 0x57, 0x61, 0x69, 0x74, 0x69, 0x6e, 0x67, 0x50, 0x61, 0x72, 0x74, 0x69, 0x63,
+	0x69, 0x70, 0x61, 0x6e, 0x74, 0x12, 0x3a, 0x0a, 0x0b, 0x70, 0x61, 0x72, 0x74, 0x69, 0x63, 0x69,
+	0x70, 0x61, 0x6e, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x18, 0x2e, 0x6c, 0x69, 0x76,
+	0x65, 0x6b, 0x69, 0x74, 0x2e, 0x50, 0x61, 0x72, 0x74, 0x69, 0x63, 0x69, 0x70, 0x61, 0x6e, 0x74,
+	0x49, 0x6e, 0x66, 0x6f, 0x52, 0x0b, 0x70, 0x61, 0x72, 0x74, 0x69, 0x63, 0x69, 0x70, 0x61, 0x6e,
+	0x74, 0x12, 0x17, 0x0a, 0x07, 0x6e, 0x6f, 0x64, 0x65, 0x5f, 0x69, 0x64, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x06, 0x6e, 0x6f, 0x64, 0x65, 0x49, 0x64, 0x22, 0xde, 0x01, 0x0a, 0x12, 0x57,
+	0x61, 0x69, 0x74, 0x69, 0x6e, 0x67, 0x52, 0x6f, 0x6f, 0x6d, 0x4d, 0x65, 0x73, 0x73, 0x61, 0x67,
+	0x65, 0x12, 0x12, 0x0a, 0x04, 0x72, 0x6f, 0x6f, 0x6d, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x04, 0x72, 0x6f, 0x6f, 0x6d, 0x12, 0x38, 0x0a, 0x05, 0x61, 0x64, 0x6d, 0x69, 0x74, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x0b, 0x32, 0x20, 0x2e, 0x6c, 0x69, 0x76, 0x65, 0x6b, 0x69, 0x74, 0x2e, 0x41,
+	0x64, 0x6d, 0x69, 0x74, 0x50, 0x61, 0x72, 0x74, 0x69, 0x63, 0x69, 0x70, 0x61, 0x6e, 0x74, 0x52,
+	0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x48, 0x00, 0x52, 0x05, 0x61, 0x64, 0x6d, 0x69, 0x74, 0x12,
+	0x3b, 0x0a, 0x06, 0x72, 0x65, 0x6a, 0x65, 0x63, 0x74, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0b, 0x32,
+	0x21, 0x2e, 0x6c, 0x69, 0x76, 0x65, 0x6b, 0x69, 0x74, 0x2e, 0x52, 0x65, 0x6a, 0x65, 0x63, 0x74,
+	0x50, 0x61, 0x72, 0x74, 0x69, 0x63, 0x69, 0x70, 0x61, 0x6e, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65,
+	0x73, 0x74, 0x48, 0x00, 0x52, 0x06, 0x72, 0x65, 0x6a, 0x65, 0x63, 0x74, 0x12, 0x32, 0x0a, 0x06,
+	0x75, 0x70, 0x64, 0x61, 0x74, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x18, 0x2e, 0x6c,
+	0x69, 0x76, 0x65, 0x6b, 0x69, 0x74, 0x2e, 0x50, 0x61, 0x72, 0x74, 0x69, 0x63, 0x69, 0x70, 0x61,
+	0x6e, 0x74, 0x49, 0x6e, 0x66, 0x6f, 0x48, 0x00, 0x52, 0x06, 0x75, 0x70, 0x64, 0x61, 0x74, 0x65,
+	0x42, 0x09, 0x0a, 0x07, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x22, 0x61, 0x0a, 0x0f, 0x57,
+	0x61, 0x69, 0x74, 0x69, 0x6e, 0x67, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x12,
+	0x0a, 0x04, 0x72, 0x6f, 0x6f, 0x6d, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x72, 0x6f,
+	0x6f, 0x6d, 0x12, 0x3a, 0x0a, 0x0b, 0x70, 0x61, 0x72, 0x74, 0x69, 0x63, 0x69, 0x70, 0x61, 0x6e,
+	0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x18, 0x2e, 0x6c, 0x69, 0x76, 0x65, 0x6b, 0x69,
+	0x74, 0x2e, 0x50, 0x61, 0x72, 0x74, 0x69, 0x63, 0x69, 0x70, 0x61, 0x6e, 0x74, 0x49, 0x6e, 0x66,
+	0x6f, 0x52, 0x0b, 0x70, 0x61, 0x72, 0x74, 0x69, 0x63, 0x69, 0x70, 0x61, 0x6e, 0x74, 0x32, 0xa7,
+	0x02, 0x0a, 0x0b, 0x57, 0x61, 0x69, 0x74, 0x69, 0x6e, 0x67, 0x52, 0x6f, 0x6f, 0x6d, 0x12, 0x6c,
+	0x0a, 0x17, 0x4c, 0x69, 0x73, 0x74, 0x57, 0x61, 0x69, 0x74, 0x69, 0x6e, 0x67, 0x50, 0x61, 0x72,
+	0x74, 0x69, 0x63, 0x69, 0x70, 0x61, 0x6e, 0x74, 0x73, 0x12, 0x27, 0x2e, 0x6c, 0x69, 0x76, 0x65,
+	0x6b, 0x69, 0x74, 0x2e, 0x4c, 0x69, 0x73, 0x74, 0x57, 0x61, 0x69, 0x74, 0x69, 0x6e, 0x67, 0x50,
+	0x61, 0x72, 0x74, 0x69, 0x63, 0x69, 0x70, 0x61, 0x6e, 0x74, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65,
+	0x73, 0x74, 0x1a, 0x28, 0x2e, 0x6c, 0x69, 0x76, 0x65, 0x6b, 0x69, 0x74, 0x2e, 0x4c, 0x69, 0x73,
+	0x74, 0x57, 0x61, 0x69, 0x74, 0x69, 0x6e, 0x67, 0x50, 0x61, 0x72, 0x74, 0x69, 0x63, 0x69, 0x70,
+	0x61, 0x6e, 0x74, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x4e, 0x0a, 0x10,
+	0x41, 0x64, 0x6d, 0x69, 0x74, 0x50, 0x61, 0x72, 0x74, 0x69, 0x63, 0x69, 0x70, 0x61, 0x6e, 0x74,
+	0x12, 0x20, 0x2e, 0x6c, 0x69, 0x76, 0x65, 0x6b, 0x69, 0x74, 0x2e, 0x41, 0x64, 0x6d, 0x69, 0x74,
+	0x50, 0x61, 0x72, 0x74, 0x69, 0x63, 0x69, 0x70, 0x61, 0x6e, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65,
+	0x73, 0x74, 0x1a, 0x18, 0x2e, 0x6c, 0x69, 0x76, 0x65, 0x6b, 0x69, 0x74, 0x2e, 0x50, 0x61, 0x72,
+	0x74, 0x69, 0x63, 0x69, 0x70, 0x61, 0x6e, 0x74, 0x49, 0x6e, 0x66, 0x6f, 0x12, 0x5a, 0x0a, 0x11,
+	0x52, 0x65, 0x6a, 0x65, 0x63, 0x74, 0x50, 0x61, 0x72, 0x74, 0x69, 0x63, 0x69, 0x70, 0x61, 0x6e,
+	0x74, 0x12, 0x21, 0x2e, 0x6c, 0x69, 0x76, 0x65, 0x6b, 0x69, 0x74, 0x2e, 0x52, 0x65, 0x6a, 0x65,
+	0x63, 0x74, 0x50, 0x61, 0x72, 0x74, 0x69, 0x63, 0x69, 0x70, 0x61, 0x6e, 0x74, 0x52, 0x65, 0x71,
+	0x75, 0x65, 0x73, 0x74, 0x1a, 0x22, 0x2e, 0x6c, 0x69, 0x76, 0x65, 0x6b, 0x69, 0x74, 0x2e, 0x52,
+	0x65, 0x6a, 0x65, 0x63, 0x74, 0x50, 0x61, 0x72, 0x74, 0x69, 0x63, 0x69, 0x70, 0x61, 0x6e, 0x74,
+	0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x42, 0x2b, 0x5a, 0x29, 0x67, 0x69, 0x74, 0x68,
+	0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x6c, 0x69, 0x76, 0x65, 0x6b, 0x69, 0x74, 0x2f, 0x6c,
+	0x69, 0x76, 0x65, 0x6b, 0x69, 0x74, 0x2d, 0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x2f, 0x70, 0x6b,
+	0x67, 0x2f, 0x72, 0x70, 0x63, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_waiting_room_proto_rawDescOnce sync.Once
+	file_waiting_room_proto_rawDescData = file_waiting_room_proto_rawDesc
+)
+
+func file_waiting_room_proto_rawDescGZIP() []byte {
+	file_waiting_room_proto_rawDescOnce.Do(func() {
+		file_waiting_room_proto_rawDescData = protoimpl.X.CompressGZIP(file_waiting_room_proto_rawDescData)
+	})
+	return file_waiting_room_proto_rawDescData
+}
+
+var file_waiting_room_proto_msgTypes = make([]protoimpl.MessageInfo, 8)
+var file_waiting_room_proto_goTypes = []interface{}{
+	(*ListWaitingParticipantsRequest)(nil),  // 0: livekit.ListWaitingParticipantsRequest
+	(*ListWaitingParticipantsResponse)(nil), // 1: livekit.ListWaitingParticipantsResponse
+	(*AdmitParticipantRequest)(nil),         // 2: livekit.AdmitParticipantRequest
+	(*RejectParticipantRequest)(nil),        // 3: livekit.RejectParticipantRequest
+	(*RejectParticipantResponse)(nil),       // 4: livekit.RejectParticipantResponse
+	(*WaitingParticipant)(nil),              // 5: livekit.WaitingParticipant
+	(*WaitingRoomMessage)(nil),              // 6: livekit.WaitingRoomMessage
+	(*WaitingResponse)(nil),                 // 7: livekit.WaitingResponse
+	(*livekit.ParticipantInfo)(nil),         // 8: livekit.ParticipantInfo
+	(*livekit.ParticipantPermission)(nil),   // 9: livekit.ParticipantPermission
+}
+var file_waiting_room_proto_depIdxs = []int32{
+	8,  // 0: livekit.ListWaitingParticipantsResponse.participants:type_name -> livekit.ParticipantInfo
+	9,  // 1: livekit.AdmitParticipantRequest.permission:type_name -> livekit.ParticipantPermission
+	8,  // 2: livekit.WaitingParticipant.participant:type_name -> livekit.ParticipantInfo
+	2,  // 3: livekit.WaitingRoomMessage.admit:type_name -> livekit.AdmitParticipantRequest
+	3,  // 4: livekit.WaitingRoomMessage.reject:type_name -> livekit.RejectParticipantRequest
+	8,  // 5: livekit.WaitingRoomMessage.update:type_name -> livekit.ParticipantInfo
+	8,  // 6: livekit.WaitingResponse.participant:type_name -> livekit.ParticipantInfo
+	0,  // 7: livekit.WaitingRoom.ListWaitingParticipants:input_type -> livekit.ListWaitingParticipantsRequest
+	2,  // 8: livekit.WaitingRoom.AdmitParticipant:input_type -> livekit.AdmitParticipantRequest
+	3,  // 9: livekit.WaitingRoom.RejectParticipant:input_type -> livekit.RejectParticipantRequest
+	1,  // 10: livekit.WaitingRoom.ListWaitingParticipants:output_type -> livekit.ListWaitingParticipantsResponse
+	8,  // 11: livekit.WaitingRoom.AdmitParticipant:output_type -> livekit.ParticipantInfo
+	4,  // 12: livekit.WaitingRoom.RejectParticipant:output_type -> livekit.RejectParticipantResponse
+	10, // [10:13] is the sub-list for method output_type
+	7,  // [7:10] is the sub-list for method input_type
+	7,  // [7:7] is the sub-list for extension type_name
+	7,  // [7:7] is the sub-list for extension extendee
+	0,  // [0:7] is the sub-list for field type_name
+}
+
+func init() { file_waiting_room_proto_init() }
+func file_waiting_room_proto_init() {
+	if File_waiting_room_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_waiting_room_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ListWaitingParticipantsRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_waiting_room_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ListWaitingParticipantsResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_waiting_room_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*AdmitParticipantRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_waiting_room_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*RejectParticipantRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_waiting_room_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*RejectParticipantResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_waiting_room_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*WaitingParticipant); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_waiting_room_proto_msgTypes[6].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*WaitingRoomMessage); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_waiting_room_proto_msgTypes[7].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*WaitingResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	file_waiting_room_proto_msgTypes[6].OneofWrappers = []interface{}{
+		(*WaitingRoomMessage_Admit)(nil),
+		(*WaitingRoomMessage_Reject)(nil),
+		(*WaitingRoomMessage_Update)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_waiting_room_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   8,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_waiting_room_proto_goTypes,
+		DependencyIndexes: file_waiting_room_proto_depIdxs,
+		MessageInfos:      file_waiting_room_proto_msgTypes,
+	}.Build()
+	File_waiting_room_proto = out.File
+	file_waiting_room_proto_rawDesc = nil
+	file_waiting_room_proto_goTypes = nil
+	file_waiting_room_proto_depIdxs = nil
+}
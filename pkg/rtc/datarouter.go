@@ -0,0 +1,223 @@
+package rtc
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/utils"
+)
+
+const (
+	// UserPacket fields that are not part of the protocol version in use yet.
+	// Clients may set them, they are read from unknown fields and forwarded as is.
+	userPacketTopicField                 = 4
+	userPacketDestinationIdentitiesField = 6
+)
+
+var (
+	ErrDataPacketTooLarge    = errors.New("data packet exceeds size limit")
+	ErrDataPacketRateLimited = errors.New("data packet rate limit exceeded")
+)
+
+type userPacketRouting struct {
+	topic                 string
+	destinationIdentities []livekit.ParticipantIdentity
+}
+
+func getUserPacketRouting(up *livekit.UserPacket) userPacketRouting {
+	var routing userPacketRouting
+	if up == nil {
+		return routing
+	}
+
+	unknown := up.ProtoReflect().GetUnknown()
+	for len(unknown) > 0 {
+		num, typ, n := protowire.ConsumeTag(unknown)
+		if n < 0 {
+			return routing
+		}
+		unknown = unknown[n:]
+
+		if typ == protowire.BytesType && (num == userPacketTopicField || num == userPacketDestinationIdentitiesField) {
+			value, n := protowire.ConsumeBytes(unknown)
+			if n < 0 {
+				return routing
+			}
+			unknown = unknown[n:]
+
+			if num == userPacketTopicField {
+				routing.topic = string(value)
+			} else {
+				routing.destinationIdentities = append(routing.destinationIdentities, livekit.ParticipantIdentity(value))
+			}
+			continue
+		}
+
+		n = protowire.ConsumeFieldValue(num, typ, unknown)
+		if n < 0 {
+			return routing
+		}
+		unknown = unknown[n:]
+	}
+
+	return routing
+}
+
+// setUserPacketTopic sets topic of a packet sent by the server
+func setUserPacketTopic(up *livekit.UserPacket, topic string) {
+	unknown := protowire.AppendTag(up.ProtoReflect().GetUnknown(), userPacketTopicField, protowire.BytesType)
+	up.ProtoReflect().SetUnknown(protowire.AppendString(unknown, topic))
+}
+
+// getDataTopicsFromMetadata returns topics listed under "data_topics" when metadata is a JSON object,
+// nil when metadata does not declare any
+func getDataTopicsFromMetadata(metadata string) map[string]struct{} {
+	if !strings.HasPrefix(strings.TrimSpace(metadata), "{") {
+		return nil
+	}
+
+	var declared struct {
+		DataTopics []string `json:"data_topics"`
+	}
+	if err := json.Unmarshal([]byte(metadata), &declared); err != nil || declared.DataTopics == nil {
+		return nil
+	}
+
+	topics := make(map[string]struct{}, len(declared.DataTopics))
+	for _, topic := range declared.DataTopics {
+		topics[topic] = struct{}{}
+	}
+	return topics
+}
+
+type dataSenderLimiter struct {
+	packets *utils.TokenBucket
+	bytes   *utils.TokenBucket
+}
+
+type dataSubscription struct {
+	granted  bool
+	metadata string
+	topics   map[string]struct{}
+}
+
+// dataPacketRouter enforces data packet limits of senders, and tracks topics receivers are subscribed to
+type dataPacketRouter struct {
+	config      *config.DataConfig
+	topicLimits map[string]int
+
+	lock          sync.RWMutex
+	limiters      map[livekit.ParticipantID]*dataSenderLimiter
+	subscriptions map[livekit.ParticipantID]*dataSubscription
+}
+
+func newDataPacketRouter(conf *config.DataConfig) *dataPacketRouter {
+	if conf == nil {
+		conf = &config.DataConfig{}
+	}
+
+	d := &dataPacketRouter{
+		config:        conf,
+		topicLimits:   make(map[string]int),
+		limiters:      make(map[livekit.ParticipantID]*dataSenderLimiter),
+		subscriptions: make(map[livekit.ParticipantID]*dataSubscription),
+	}
+	for _, topic := range conf.Topics {
+		d.topicLimits[topic.Name] = topic.MaxPacketSize
+	}
+	return d
+}
+
+// checkSender returns an error when a packet of a participant should not be forwarded
+func (d *dataPacketRouter) checkSender(participantID livekit.ParticipantID, topic string, size int) error {
+	maxSize := d.config.MaxPacketSize
+	if topicLimit, ok := d.topicLimits[topic]; ok && topic != "" {
+		maxSize = topicLimit
+	}
+	if maxSize > 0 && size > maxSize {
+		return ErrDataPacketTooLarge
+	}
+
+	if d.config.MaxPacketsPerSec <= 0 && d.config.MaxBytesPerSec <= 0 {
+		return nil
+	}
+
+	d.lock.Lock()
+	limiter := d.limiters[participantID]
+	if limiter == nil {
+		limiter = &dataSenderLimiter{}
+		if d.config.MaxPacketsPerSec > 0 {
+			limiter.packets = utils.NewTokenBucket(float64(d.config.MaxPacketsPerSec), float64(d.config.MaxPacketsPerSec))
+		}
+		if d.config.MaxBytesPerSec > 0 {
+			limiter.bytes = utils.NewTokenBucket(float64(d.config.MaxBytesPerSec), float64(d.config.MaxBytesPerSec))
+		}
+		d.limiters[participantID] = limiter
+	}
+	d.lock.Unlock()
+
+	if limiter.packets != nil && !limiter.packets.Allow(1) {
+		return ErrDataPacketRateLimited
+	}
+	if limiter.bytes != nil && !limiter.bytes.Allow(float64(size)) {
+		return ErrDataPacketRateLimited
+	}
+	return nil
+}
+
+// updateSubscription refreshes topics of a participant. Topics granted by its token are kept, those declared in its
+// metadata are refreshed when it changes
+func (d *dataPacketRouter) updateSubscription(participantID livekit.ParticipantID, grantedTopics []string, metadata string) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	sub, ok := d.subscriptions[participantID]
+	if ok && (sub.granted || sub.metadata == metadata) {
+		return
+	}
+	if len(grantedTopics) > 0 {
+		topics := make(map[string]struct{}, len(grantedTopics))
+		for _, topic := range grantedTopics {
+			topics[topic] = struct{}{}
+		}
+		d.subscriptions[participantID] = &dataSubscription{granted: true, topics: topics}
+		return
+	}
+	d.subscriptions[participantID] = &dataSubscription{
+		metadata: metadata,
+		topics:   getDataTopicsFromMetadata(metadata),
+	}
+}
+
+// isSubscribed returns true when a participant should receive packets on topic.
+// Packets without a topic go to everyone, and participants that have not declared topics receive all of them.
+func (d *dataPacketRouter) isSubscribed(participantID livekit.ParticipantID, topic string) bool {
+	if topic == "" {
+		return true
+	}
+
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+
+	sub := d.subscriptions[participantID]
+	if sub == nil || sub.topics == nil {
+		return true
+	}
+	_, ok := sub.topics[topic]
+	return ok
+}
+
+func (d *dataPacketRouter) removeParticipant(participantID livekit.ParticipantID) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	delete(d.limiters, participantID)
+	delete(d.subscriptions, participantID)
+}
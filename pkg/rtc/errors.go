@@ -3,15 +3,24 @@ package rtc
 import "errors"
 
 var (
-	ErrRoomClosed              = errors.New("room has already closed")
-	ErrPermissionDenied        = errors.New("no permissions to access the room")
-	ErrMaxParticipantsExceeded = errors.New("room has exceeded its max participants")
-	ErrLimitExceeded           = errors.New("node has exceeded its configured limit")
-	ErrAlreadyJoined           = errors.New("a participant with the same identity is already in the room")
-	ErrUnexpectedOffer         = errors.New("expected answer SDP, received offer")
-	ErrDataChannelUnavailable  = errors.New("data channel is not available")
-	ErrCannotSubscribe         = errors.New("participant does not have permission to subscribe")
-	ErrEmptyIdentity           = errors.New("participant identity cannot be empty")
-	ErrEmptyParticipantID      = errors.New("participant ID cannot be empty")
-	ErrMissingGrants           = errors.New("VideoGrant is missing")
+	ErrRoomClosed                    = errors.New("room has already closed")
+	ErrPermissionDenied              = errors.New("no permissions to access the room")
+	ErrMaxParticipantsExceeded       = errors.New("room has exceeded its max participants")
+	ErrMaxTenantParticipantsExceeded = errors.New("tenant has exceeded its max participants")
+	ErrMaxTenantTracksExceeded       = errors.New("tenant has exceeded its max tracks")
+	ErrLimitExceeded                 = errors.New("node has exceeded its configured limit")
+	ErrAlreadyJoined                 = errors.New("a participant with the same identity is already in the room")
+	ErrUnexpectedOffer               = errors.New("expected answer SDP, received offer")
+	ErrDataChannelUnavailable        = errors.New("data channel is not available")
+	ErrCannotSubscribe               = errors.New("participant does not have permission to subscribe")
+	ErrEmptyIdentity                 = errors.New("participant identity cannot be empty")
+	ErrEmptyParticipantID            = errors.New("participant ID cannot be empty")
+	ErrMissingGrants                 = errors.New("VideoGrant is missing")
+	ErrNotSubscribed                 = errors.New("participant is not subscribed to the track")
+	ErrNotDownTrack                  = errors.New("video slot can only be sent through a DownTrack")
+	ErrParticipantDisconnected       = errors.New("participant is disconnected")
+	ErrTrackAlreadyPublished         = errors.New("a track with the same cid is already published")
+	ErrInvalidSRTPKey                = errors.New("SRTP key does not match the length of key and salt of its profile")
+	ErrUnsupportedSRTPProfile        = errors.New("SRTP profile is not supported")
+	ErrRTPIngestSourceRequired       = errors.New("plain RTP can only be received from an expected source address")
 )
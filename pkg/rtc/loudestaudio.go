@@ -0,0 +1,136 @@
+package rtc
+
+import (
+	"sort"
+	"time"
+
+	"github.com/livekit/protocol/livekit"
+)
+
+const (
+	// minimum time a track stays forwarded once selected
+	loudestAudioMinHold = 2 * time.Second
+	// a candidate needs to be this much louder than a forwarded track that is still active to replace it
+	loudestAudioSwitchRatio = 1.5
+)
+
+type audioTrackLevel struct {
+	trackID     livekit.TrackID
+	publisherID livekit.ParticipantID
+	level       float64
+	active      bool
+}
+
+type selectedAudioTrack struct {
+	level      float64
+	active     bool
+	selectedAt time.Time
+}
+
+// loudestAudioSelector picks the audio tracks to forward to a subscriber, keeping up to maxTracks of the loudest.
+// Selected tracks are held for a minimum time and are only replaced by a noticeably louder track,
+// so forwarding doesn't flap between speakers of similar level
+type loudestAudioSelector struct {
+	maxTracks int
+	selected  map[livekit.TrackID]*selectedAudioTrack
+}
+
+func newLoudestAudioSelector() *loudestAudioSelector {
+	return &loudestAudioSelector{
+		selected: make(map[livekit.TrackID]*selectedAudioTrack),
+	}
+}
+
+func (s *loudestAudioSelector) SetMaxTracks(maxTracks int) {
+	s.maxTracks = maxTracks
+}
+
+func (s *loudestAudioSelector) IsSelected(trackID livekit.TrackID) bool {
+	_, ok := s.selected[trackID]
+	return ok
+}
+
+// Update refreshes selection with current levels of all published audio tracks,
+// returns true when selection has changed
+func (s *loudestAudioSelector) Update(levels []audioTrackLevel, now time.Time) bool {
+	changed := false
+
+	present := make(map[livekit.TrackID]bool, len(levels))
+	for _, l := range levels {
+		present[l.trackID] = true
+		if sel := s.selected[l.trackID]; sel != nil {
+			sel.level = l.level
+			sel.active = l.active
+		}
+	}
+	for trackID := range s.selected {
+		if !present[trackID] {
+			delete(s.selected, trackID)
+			changed = true
+		}
+	}
+
+	// loudest candidates first
+	candidates := make([]audioTrackLevel, 0, len(levels))
+	for _, l := range levels {
+		if l.active && s.selected[l.trackID] == nil {
+			candidates = append(candidates, l)
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].level > candidates[j].level
+	})
+
+	for _, c := range candidates {
+		if len(s.selected) < s.maxTracks {
+			s.selected[c.trackID] = &selectedAudioTrack{level: c.level, active: true, selectedAt: now}
+			changed = true
+			continue
+		}
+
+		weakestID, weakest := s.weakestReplaceable(now)
+		if weakest == nil {
+			break
+		}
+		if weakest.active && c.level <= weakest.level*loudestAudioSwitchRatio {
+			// candidates are sorted, none of the rest would qualify either
+			break
+		}
+		delete(s.selected, weakestID)
+		s.selected[c.trackID] = &selectedAudioTrack{level: c.level, active: true, selectedAt: now}
+		changed = true
+	}
+
+	// drop extra tracks when the limit has been lowered
+	for len(s.selected) > s.maxTracks {
+		weakestID, _ := s.weakest(func(*selectedAudioTrack) bool { return true })
+		delete(s.selected, weakestID)
+		changed = true
+	}
+
+	return changed
+}
+
+// weakestReplaceable returns the quietest selected track that has been held long enough, inactive tracks first
+func (s *loudestAudioSelector) weakestReplaceable(now time.Time) (livekit.TrackID, *selectedAudioTrack) {
+	return s.weakest(func(sel *selectedAudioTrack) bool {
+		return now.Sub(sel.selectedAt) >= loudestAudioMinHold
+	})
+}
+
+func (s *loudestAudioSelector) weakest(eligible func(*selectedAudioTrack) bool) (livekit.TrackID, *selectedAudioTrack) {
+	var weakestID livekit.TrackID
+	var weakest *selectedAudioTrack
+	for trackID, sel := range s.selected {
+		if !eligible(sel) {
+			continue
+		}
+		if weakest == nil ||
+			(weakest.active && !sel.active) ||
+			(weakest.active == sel.active && sel.level < weakest.level) ||
+			(weakest.active == sel.active && sel.level == weakest.level && trackID < weakestID) {
+			weakestID, weakest = trackID, sel
+		}
+	}
+	return weakestID, weakest
+}
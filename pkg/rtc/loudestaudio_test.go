@@ -0,0 +1,96 @@
+package rtc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+)
+
+func TestLoudestAudioSelector(t *testing.T) {
+	newSelector := func(maxTracks int) *loudestAudioSelector {
+		s := newLoudestAudioSelector()
+		s.SetMaxTracks(maxTracks)
+		return s
+	}
+	selected := func(s *loudestAudioSelector, trackIDs ...livekit.TrackID) []livekit.TrackID {
+		var ids []livekit.TrackID
+		for _, trackID := range trackIDs {
+			if s.IsSelected(trackID) {
+				ids = append(ids, trackID)
+			}
+		}
+		return ids
+	}
+	now := time.Now()
+
+	t.Run("selects loudest active tracks", func(t *testing.T) {
+		s := newSelector(2)
+		changed := s.Update([]audioTrackLevel{
+			{trackID: "a", level: 0.2, active: true},
+			{trackID: "b", level: 0.5, active: true},
+			{trackID: "c", level: 0.4, active: true},
+			{trackID: "d", level: 0.9, active: false},
+		}, now)
+		require.True(t, changed)
+		require.Equal(t, []livekit.TrackID{"b", "c"}, selected(s, "a", "b", "c", "d"))
+
+		// same levels, nothing changes
+		require.False(t, s.Update([]audioTrackLevel{
+			{trackID: "a", level: 0.2, active: true},
+			{trackID: "b", level: 0.5, active: true},
+			{trackID: "c", level: 0.4, active: true},
+		}, now))
+	})
+
+	t.Run("holds selected tracks for a minimum time", func(t *testing.T) {
+		s := newSelector(1)
+		s.Update([]audioTrackLevel{{trackID: "a", level: 0.3, active: true}}, now)
+
+		levels := []audioTrackLevel{
+			{trackID: "a", level: 0.1, active: false},
+			{trackID: "b", level: 0.8, active: true},
+		}
+		require.False(t, s.Update(levels, now.Add(loudestAudioMinHold/2)))
+		require.Equal(t, []livekit.TrackID{"a"}, selected(s, "a", "b"))
+
+		require.True(t, s.Update(levels, now.Add(loudestAudioMinHold)))
+		require.Equal(t, []livekit.TrackID{"b"}, selected(s, "a", "b"))
+	})
+
+	t.Run("switches only to a noticeably louder track", func(t *testing.T) {
+		s := newSelector(1)
+		s.Update([]audioTrackLevel{{trackID: "a", level: 0.4, active: true}}, now)
+		later := now.Add(loudestAudioMinHold)
+
+		require.False(t, s.Update([]audioTrackLevel{
+			{trackID: "a", level: 0.4, active: true},
+			{trackID: "b", level: 0.5, active: true},
+		}, later))
+		require.Equal(t, []livekit.TrackID{"a"}, selected(s, "a", "b"))
+
+		require.True(t, s.Update([]audioTrackLevel{
+			{trackID: "a", level: 0.4, active: true},
+			{trackID: "b", level: 0.7, active: true},
+		}, later))
+		require.Equal(t, []livekit.TrackID{"b"}, selected(s, "a", "b"))
+	})
+
+	t.Run("removes unpublished tracks and applies lowered limit", func(t *testing.T) {
+		s := newSelector(3)
+		s.Update([]audioTrackLevel{
+			{trackID: "a", level: 0.2, active: true},
+			{trackID: "b", level: 0.5, active: true},
+			{trackID: "c", level: 0.4, active: true},
+		}, now)
+
+		s.SetMaxTracks(1)
+		require.True(t, s.Update([]audioTrackLevel{
+			{trackID: "a", level: 0.2, active: true},
+			{trackID: "b", level: 0.5, active: true},
+		}, now))
+		require.Equal(t, []livekit.TrackID{"b"}, selected(s, "a", "b", "c"))
+	})
+}
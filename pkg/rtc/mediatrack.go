@@ -4,6 +4,7 @@ import (
 	"context"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/pion/rtcp"
 	"github.com/pion/webrtc/v3"
@@ -33,7 +34,8 @@ type MediaTrack struct {
 
 	dynacastManager *DynacastManager
 
-	lock sync.RWMutex
+	lock    sync.RWMutex
+	capture buffer.PacketCapture
 }
 
 type MediaTrackParams struct {
@@ -54,6 +56,8 @@ type MediaTrackParams struct {
 	Telemetry         telemetry.TelemetryService
 	Logger            logger.Logger
 	SimTracks         map[uint32]SimulcastTrackInfo
+	// received from the node hosting the publisher, which relays it while subscribed
+	IsRelayed bool
 }
 
 func NewMediaTrack(params MediaTrackParams) *MediaTrack {
@@ -64,7 +68,7 @@ func NewMediaTrack(params MediaTrackParams) *MediaTrack {
 	t.MediaTrackReceiver = NewMediaTrackReceiver(MediaTrackReceiverParams{
 		TrackInfo:           params.TrackInfo,
 		MediaTrack:          t,
-		IsRelayed:           false,
+		IsRelayed:           params.IsRelayed,
 		ParticipantID:       params.ParticipantID,
 		ParticipantIdentity: params.ParticipantIdentity,
 		ParticipantVersion:  params.ParticipantVersion,
@@ -147,6 +151,14 @@ func (t *MediaTrack) OnSubscribedMaxQualityChange(
 	t.dynacastManager.OnSubscribedMaxQualityChange(handler)
 }
 
+// NotifySubscriberMaxQuality accounts for subscribers receiving this track other than through a subscription,
+// such as video slots
+func (t *MediaTrack) NotifySubscriberMaxQuality(subscriberID livekit.ParticipantID, mime string, quality livekit.VideoQuality) {
+	if t.dynacastManager != nil {
+		t.dynacastManager.NotifySubscriberMaxQuality(subscriberID, mime, quality)
+	}
+}
+
 func (t *MediaTrack) NotifySubscriberNodeMaxQuality(nodeID livekit.NodeID, qualities []types.SubscribedCodecQuality) {
 	if t.dynacastManager != nil {
 		t.dynacastManager.NotifySubscriberNodeMaxQuality(nodeID, qualities)
@@ -192,7 +204,7 @@ func (t *MediaTrack) SetPendingCodecSid(codecs []*livekit.SimulcastCodec) {
 }
 
 // AddReceiver adds a new RTP receiver to the track, returns true when receiver represents a new codec
-func (t *MediaTrack) AddReceiver(receiver *webrtc.RTPReceiver, track *webrtc.TrackRemote, twcc *twcc.Responder, mid string) bool {
+func (t *MediaTrack) AddReceiver(receiver sfu.RTPReceiver, track sfu.TrackRemote, twcc *twcc.Responder, mid string) bool {
 	var newCodec bool
 	buff, rtcpReader := t.params.BufferFactory.GetBufferPair(uint32(track.SSRC()))
 	if buff == nil || rtcpReader == nil {
@@ -215,6 +227,10 @@ func (t *MediaTrack) AddReceiver(receiver *webrtc.RTPReceiver, track *webrtc.Tra
 				buff.SetSenderReportData(pkt.RTPTime, pkt.NTPTime)
 			}
 		}
+
+		if capture := t.getCapture(); capture != nil {
+			capture.WriteRTCP(bytes, time.Now().UnixNano())
+		}
 	})
 
 	t.lock.Lock()
@@ -286,6 +302,9 @@ func (t *MediaTrack) AddReceiver(receiver *webrtc.RTPReceiver, track *webrtc.Tra
 		}
 
 		newWR.OnMaxLayerChange(t.onMaxLayerChange)
+		if t.capture != nil {
+			newWR.SetCapture(t.capture)
+		}
 
 		t.buffer = buff
 
@@ -322,6 +341,40 @@ func (t *MediaTrack) AddReceiver(receiver *webrtc.RTPReceiver, track *webrtc.Tra
 	return newCodec
 }
 
+// SetCapture tees packets received from the publisher to c, nil stops capturing.
+// When subscriberID is set, packets forwarded to that subscriber are captured instead.
+func (t *MediaTrack) SetCapture(c buffer.PacketCapture, subscriberID livekit.ParticipantID) error {
+	if subscriberID != "" {
+		subTrack := t.MediaTrackSubscriptions.getSubscribedTrack(subscriberID)
+		if subTrack == nil {
+			return ErrNotSubscribed
+		}
+		subTrack.DownTrack().SetCapture(c)
+		return nil
+	}
+
+	t.lock.Lock()
+	t.capture = c
+	t.lock.Unlock()
+
+	for _, r := range t.MediaTrackReceiver.Receivers() {
+		if dr, ok := r.(*DummyReceiver); ok {
+			r = dr.Receiver()
+		}
+		if wr, ok := r.(*sfu.WebRTCReceiver); ok {
+			wr.SetCapture(c)
+		}
+	}
+	return nil
+}
+
+func (t *MediaTrack) getCapture() buffer.PacketCapture {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	return t.capture
+}
+
 func (t *MediaTrack) GetConnectionScore() float32 {
 	receiver := t.PrimaryReceiver()
 	if rtcReceiver, ok := receiver.(*sfu.WebRTCReceiver); ok {
@@ -41,6 +41,38 @@ func TestTrackInfo(t *testing.T) {
 	require.True(t, mt.ToProto().Simulcast)
 }
 
+func TestRelaySubscription(t *testing.T) {
+	mt := NewMediaTrack(MediaTrackParams{
+		TrackInfo: &livekit.TrackInfo{Sid: "TR_origin", Type: livekit.TrackType_AUDIO},
+		IsRelayed: true,
+	})
+	mt.addPendingSubscribeOp("PA_1")
+
+	var changes []bool
+	mt.OnRelaySubscriptionChanged(func(subscribed bool) {
+		changes = append(changes, subscribed)
+	})
+	// already subscribed
+	require.Equal(t, []bool{true}, changes)
+
+	mt.addPendingSubscribeOp("PA_2")
+	mt.removePendingSubscribeOp("PA_1")
+	require.Equal(t, []bool{true}, changes)
+
+	mt.removePendingSubscribeOp("PA_2")
+	require.Equal(t, []bool{true, false}, changes)
+
+	// tracks published on this node are not relayed
+	local := NewMediaTrack(MediaTrackParams{
+		TrackInfo: &livekit.TrackInfo{Sid: "TR_local", Type: livekit.TrackType_AUDIO},
+	})
+	local.OnRelaySubscriptionChanged(func(subscribed bool) {
+		changes = append(changes, subscribed)
+	})
+	local.addPendingSubscribeOp("PA_1")
+	require.Len(t, changes, 2)
+}
+
 func TestGetQualityForDimension(t *testing.T) {
 	t.Run("landscape source", func(t *testing.T) {
 		mt := NewMediaTrack(MediaTrackParams{TrackInfo: &livekit.TrackInfo{
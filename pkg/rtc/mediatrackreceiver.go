@@ -3,6 +3,7 @@ package rtc
 import (
 	"errors"
 	"fmt"
+	"math/rand"
 	"sort"
 	"strings"
 	"sync"
@@ -89,11 +90,13 @@ type MediaTrackReceiver struct {
 	potentialCodecs    []webrtc.RTPCodecParameters
 	pendingSubscribeOp map[livekit.ParticipantID]int
 	state              mediaTrackReceiverState
+	relaySubscribed    bool
 
-	onSetupReceiver     func(mime string)
-	onMediaLossFeedback func(dt *sfu.DownTrack, report *rtcp.ReceiverReport)
-	onVideoLayerUpdate  func(layers []*livekit.VideoLayer)
-	onClose             []func()
+	onSetupReceiver            func(mime string)
+	onRelaySubscriptionChanged func(subscribed bool)
+	onMediaLossFeedback        func(dt *sfu.DownTrack, report *rtcp.ReceiverReport)
+	onVideoLayerUpdate         func(layers []*livekit.VideoLayer)
+	onClose                    []func()
 
 	*MediaTrackSubscriptions
 }
@@ -415,6 +418,8 @@ func (t *MediaTrackReceiver) addPendingSubscribeOp(subscriberID livekit.Particip
 		t.pendingSubscribeOp[subscriberID] = c + 1
 	}
 	t.lock.Unlock()
+
+	t.updateRelaySubscription()
 }
 
 func (t *MediaTrackReceiver) removePendingSubscribeOp(subscriberID livekit.ParticipantID) {
@@ -426,6 +431,37 @@ func (t *MediaTrackReceiver) removePendingSubscribeOp(subscriberID livekit.Parti
 		}
 	}
 	t.lock.Unlock()
+
+	t.updateRelaySubscription()
+}
+
+// OnRelaySubscriptionChanged is called when a relayed track gains its first subscriber, or loses its last, so that
+// the node hosting its publisher relays it only while it's subscribed. It's called right away if already subscribed
+func (t *MediaTrackReceiver) OnRelaySubscriptionChanged(f func(subscribed bool)) {
+	t.lock.Lock()
+	t.onRelaySubscriptionChanged = f
+	t.relaySubscribed = false
+	t.lock.Unlock()
+
+	t.updateRelaySubscription()
+}
+
+func (t *MediaTrackReceiver) updateRelaySubscription() {
+	if !t.params.IsRelayed {
+		return
+	}
+
+	t.lock.Lock()
+	subscribed := t.MediaTrackSubscriptions.GetNumSubscribers() != 0 || len(t.pendingSubscribeOp) != 0
+	if subscribed == t.relaySubscribed || t.onRelaySubscriptionChanged == nil {
+		t.lock.Unlock()
+		return
+	}
+	t.relaySubscribed = subscribed
+	onRelaySubscriptionChanged := t.onRelaySubscriptionChanged
+	t.lock.Unlock()
+
+	onRelaySubscriptionChanged(subscribed)
 }
 
 // AddSubscriber subscribes sub to current mediaTrack
@@ -524,6 +560,127 @@ func (t *MediaTrackReceiver) removeSubscriber(subscriberID livekit.ParticipantID
 	return
 }
 
+// AddLocalSubscriber forwards the track to writer on the server, for consumers such as recorders.
+// codecs are the ones the consumer accepts. Forwarding stops when the returned DownTrack is closed,
+// which also happens when the track is closed.
+func (t *MediaTrackReceiver) AddLocalSubscriber(
+	subscriberID livekit.ParticipantID,
+	codecs []webrtc.RTPCodecParameters,
+	writer webrtc.TrackLocalWriter,
+) (*sfu.DownTrack, error) {
+	downTrack, err := t.addLocalDownTrack(subscriberID, codecs, writer, webrtc.SSRC(rand.Uint32()), 0, func(dt *sfu.DownTrack, layer int32) {
+		if t.onSubscriberMaxQualityChange != nil {
+			t.onSubscriberMaxQualityChange(subscriberID, dt.Codec(), layer)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if downTrack.Kind() == webrtc.RTPCodecTypeVideo {
+		downTrack.SetMaxSpatialLayer(sfu.DefaultMaxLayerSpatial)
+		downTrack.SetMaxTemporalLayer(sfu.DefaultMaxLayerTemporal)
+		downTrack.AllocateOptimal(true)
+	}
+	return downTrack, nil
+}
+
+// addLocalDownTrack creates and binds a DownTrack writing to writer with ssrc, layers of video are set by the caller.
+// Audio levels are forwarded in the header extension with audioLevelID when it's not 0
+func (t *MediaTrackReceiver) addLocalDownTrack(
+	subscriberID livekit.ParticipantID,
+	codecs []webrtc.RTPCodecParameters,
+	writer webrtc.TrackLocalWriter,
+	ssrc webrtc.SSRC,
+	audioLevelID int,
+	onMaxLayerChanged func(dt *sfu.DownTrack, layer int32),
+) (*sfu.DownTrack, error) {
+	t.lock.RLock()
+	if t.state != mediaTrackReceiverStateOpen {
+		t.lock.RUnlock()
+		return nil, ErrNotOpen
+	}
+
+	receivers := t.receiversShadow
+	potentialCodecs := make([]webrtc.RTPCodecParameters, len(t.potentialCodecs))
+	copy(potentialCodecs, t.potentialCodecs)
+	t.lock.RUnlock()
+
+	if len(receivers) == 0 {
+		return nil, ErrNoReceiver
+	}
+
+	for _, receiver := range receivers {
+		codec := receiver.Codec()
+		var found bool
+		for _, pc := range potentialCodecs {
+			if codec.MimeType == pc.MimeType {
+				found = true
+				break
+			}
+		}
+		if !found {
+			potentialCodecs = append(potentialCodecs, codec)
+		}
+	}
+
+	tLogger := LoggerWithParticipant(LoggerWithTrack(t.params.Logger, t.ID(), t.params.IsRelayed), "", subscriberID, false)
+	wr := NewWrappedReceiver(WrappedReceiverParams{
+		Receivers:      receivers,
+		TrackID:        t.ID(),
+		StreamId:       string(t.PublisherID()),
+		UpstreamCodecs: potentialCodecs,
+		Logger:         tLogger,
+		DisableRed:     true,
+	})
+	downTrack, err := sfu.NewDownTrack(
+		wr.Codecs(),
+		wr,
+		t.params.BufferFactory,
+		subscriberID,
+		t.params.ReceiverConfig.PacketBufferSize,
+		tLogger,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	downTrack.OnBind(func() {
+		wr.DetermineReceiver(downTrack.Codec())
+		if err := wr.AddDownTrack(downTrack); err != nil {
+			tLogger.Errorw("could not add down track", err)
+		}
+	})
+	if onMaxLayerChanged != nil {
+		downTrack.OnMaxLayerChanged(onMaxLayerChanged)
+	}
+	if audioLevelID != 0 {
+		downTrack.ForwardAudioLevel(audioLevelID)
+	}
+
+	var bindErr error
+	for _, codec := range codecs {
+		if bindErr = downTrack.BindLocal(codec, ssrc, writer); bindErr == nil {
+			break
+		}
+	}
+	if bindErr != nil {
+		downTrack.Close()
+		return nil, bindErr
+	}
+	downTrack.SetConnected()
+
+	if downTrack.Kind() == webrtc.RTPCodecTypeVideo {
+		// there is no congestion control, forward the best layer available
+		allocate := func(dt *sfu.DownTrack) {
+			dt.AllocateOptimal(true)
+		}
+		downTrack.OnAvailableLayersChanged(allocate)
+		downTrack.OnBitrateAvailabilityChanged(allocate)
+	}
+	return downTrack, nil
+}
+
 func (t *MediaTrackReceiver) removeAllSubscribersForMime(mime string, willBeResumed bool) {
 	t.params.Logger.Infow("removing all subscribers for mime", "mime", mime)
 	for _, subscriberID := range t.MediaTrackSubscriptions.GetAllSubscribersForMime(mime) {
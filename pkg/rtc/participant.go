@@ -36,6 +36,8 @@ const (
 
 	disconnectCleanupDuration = 15 * time.Second
 	migrationWaitDuration     = 3 * time.Second
+	// maximum time subscriptions are waited for before answering a client's subscriber offer
+	subscriberOfferWait = 5 * time.Second
 )
 
 type pendingTrackInfo struct {
@@ -86,7 +88,21 @@ type ParticipantParams struct {
 	AdaptiveStream          bool
 	AllowTCPFallback        bool
 	TURNSEnabled            bool
-	GetParticipantInfo      func(pID livekit.ParticipantID) *livekit.ParticipantInfo
+	// limit of downstream bitrate, from server config and token grants, 0 if unlimited
+	MaxSubscribeBitrate int64
+	// data topics granted by the token, nil when the participant declares them in its metadata
+	DataTopics []string
+	// number of video slots requested, 0 to subscribe to video tracks individually
+	VideoSlots int
+	// client offers the subscriber connection, once, as WHEP viewers do
+	ClientOffersSubscriber bool
+	// signaled over HTTP, as WHIP and WHEP clients are, which may not open data channels
+	HTTPSignaled bool
+	// media is received outside of PeerConnections, as with RTP ingest. The participant is active once joined
+	ExternalMedia bool
+	// hosted by another node, which relays its tracks here with the IDs they have there
+	Relayed            bool
+	GetParticipantInfo func(pID livekit.ParticipantID) *livekit.ParticipantInfo
 }
 
 type ParticipantImpl struct {
@@ -100,6 +116,8 @@ type ParticipantImpl struct {
 	grants       *auth.ClaimGrants
 	isPublisher  atomic.Bool
 
+	videoSlots *VideoSlots
+
 	// when first connected
 	connectedAt time.Time
 	// timer that's set when disconnect is detected on primary PC
@@ -155,6 +173,10 @@ type ParticipantImpl struct {
 	subscriptionInProgress    map[livekit.TrackID]bool
 	subscriptionRequestsQueue map[livekit.TrackID][]SubscribeRequest
 	trackPublisherVersion     map[livekit.TrackID]uint32
+	// closed when no subscription is queued or in progress
+	subscriptionsSettled []chan struct{}
+	// set once a client offered subscriber has been answered, later subscriptions cannot be negotiated
+	subscriberAnswered bool
 
 	supervisor *supervisor.ParticipantSupervisor
 }
@@ -204,6 +226,16 @@ func NewParticipant(params ParticipantParams) (*ParticipantImpl, error) {
 
 	p.setupUpTrackManager()
 
+	if params.VideoSlots > 0 {
+		p.videoSlots = NewVideoSlots(VideoSlotsParams{
+			NumSlots:         params.VideoSlots,
+			Subscriber:       p,
+			Config:           params.Config,
+			TransportManager: p.TransportManager,
+			Logger:           params.Logger,
+		})
+	}
+
 	return p, nil
 }
 
@@ -271,6 +303,22 @@ func (p *ParticipantImpl) SetMetadata(metadata string) {
 	}
 }
 
+// NumVideoSlots returns the number of video slots the participant receives, 0 when it subscribes to video tracks
+func (p *ParticipantImpl) NumVideoSlots() int {
+	if p.videoSlots == nil {
+		return 0
+	}
+	return p.videoSlots.NumSlots()
+}
+
+// UpdateVideoSlots fills video slots with tracks of active speakers, loudest first, and of other publishers
+func (p *ParticipantImpl) UpdateVideoSlots(speakers []types.MediaTrack, others []types.MediaTrack) {
+	if p.videoSlots == nil || p.State() != livekit.ParticipantInfo_ACTIVE {
+		return
+	}
+	p.videoSlots.Update(speakers, others)
+}
+
 func (p *ParticipantImpl) ClaimGrants() *auth.ClaimGrants {
 	p.lock.RLock()
 	defer p.lock.RUnlock()
@@ -278,6 +326,11 @@ func (p *ParticipantImpl) ClaimGrants() *auth.ClaimGrants {
 	return p.grants.Clone()
 }
 
+// DataTopics returns data topics granted by the token, nil when it doesn't grant any
+func (p *ParticipantImpl) DataTopics() []string {
+	return p.params.DataTopics
+}
+
 func (p *ParticipantImpl) SetPermission(permission *livekit.ParticipantPermission) bool {
 	if permission == nil {
 		return false
@@ -402,6 +455,12 @@ func (p *ParticipantImpl) OnClaimsChanged(callback func(types.LocalParticipant))
 
 // HandleOffer an offer from remote participant, used when clients make the initial connection
 func (p *ParticipantImpl) HandleOffer(offer webrtc.SessionDescription) {
+	if p.params.ClientOffersSubscriber {
+		p.params.Logger.Infow("received offer", "transport", livekit.SignalTarget_SUBSCRIBER)
+		go p.handleSubscriberOffer(offer)
+		return
+	}
+
 	p.params.Logger.Infow("received offer", "transport", livekit.SignalTarget_PUBLISHER)
 	shouldPend := false
 	if p.MigrateState() == types.MigrateStateInit {
@@ -429,6 +488,61 @@ func (p *ParticipantImpl) HandleAnswer(answer webrtc.SessionDescription) {
 	p.TransportManager.HandleAnswer(answer)
 }
 
+// handleSubscriberOffer answers the client's offer for subscribed tracks. As the answer can't be followed by
+// another negotiation, subscriptions requested before the offer are given time to complete and
+// subscriptions requested after it are rejected.
+func (p *ParticipantImpl) handleSubscriberOffer(offer webrtc.SessionDescription) {
+	if !p.waitForSubscriptions(subscriberOfferWait) {
+		p.params.Logger.Warnw("answering subscriber offer with subscriptions pending", nil)
+	}
+
+	p.TransportManager.HandleSubscriberOffer(offer)
+}
+
+// waitForSubscriptions waits until no subscription is queued or in progress and marks the subscriber as
+// answered. It returns false if subscriptions are still pending when the timeout expires.
+func (p *ParticipantImpl) waitForSubscriptions(timeout time.Duration) bool {
+	p.lock.Lock()
+	if !p.hasPendingSubscriptionsLocked() {
+		p.subscriberAnswered = true
+		p.lock.Unlock()
+		return true
+	}
+	settled := make(chan struct{})
+	p.subscriptionsSettled = append(p.subscriptionsSettled, settled)
+	p.lock.Unlock()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-settled:
+	case <-timer.C:
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.subscriberAnswered = true
+	return !p.hasPendingSubscriptionsLocked()
+}
+
+func (p *ParticipantImpl) hasPendingSubscriptionsLocked() bool {
+	return len(p.subscriptionRequestsQueue) != 0 || len(p.subscriptionInProgress) != 0
+}
+
+func (p *ParticipantImpl) onSubscriberAnswer(answer webrtc.SessionDescription) error {
+	if p.State() == livekit.ParticipantInfo_DISCONNECTED {
+		return nil
+	}
+
+	p.params.Logger.Infow("sending answer", "transport", livekit.SignalTarget_SUBSCRIBER)
+	return p.writeMessage(&livekit.SignalResponse{
+		Message: &livekit.SignalResponse_Answer{
+			Answer: ToProtoSessionDescription(answer),
+		},
+	})
+}
+
 func (p *ParticipantImpl) onPublisherAnswer(answer webrtc.SessionDescription) error {
 	p.params.Logger.Infow("sending answer", "transport", livekit.SignalTarget_PUBLISHER)
 	answer = p.configurePublisherAnswer(answer)
@@ -501,7 +615,7 @@ func (p *ParticipantImpl) AddTrack(req *livekit.AddTrackRequest) {
 		return
 	}
 
-	ti := p.addPendingTrackLocked(req)
+	ti := p.addPendingTrackLocked(req, "")
 	if ti == nil {
 		return
 	}
@@ -593,6 +707,9 @@ func (p *ParticipantImpl) Close(sendLeave bool, reason types.ParticipantCloseRea
 		for _, dt := range downTracksToClose {
 			dt.CloseWithFlush(sendLeave)
 		}
+		if p.videoSlots != nil {
+			p.videoSlots.Close()
+		}
 
 		p.TransportManager.Close()
 	}()
@@ -1033,6 +1150,8 @@ func (p *ParticipantImpl) setupTransportManager() error {
 		Migration:               p.params.Migration,
 		AllowTCPFallback:        p.params.AllowTCPFallback,
 		TURNSEnabled:            p.params.TURNSEnabled,
+		ClientOffersSubscriber:  p.params.ClientOffersSubscriber,
+		AllowMediaOnly:          p.params.HTTPSignaled,
 		Logger:                  p.params.Logger,
 	})
 	if err != nil {
@@ -1067,11 +1186,15 @@ func (p *ParticipantImpl) setupTransportManager() error {
 	tm.OnPublisherInitialConnected(p.onPublisherInitialConnected)
 
 	tm.OnSubscriberOffer(p.onSubscriberOffer)
+	tm.OnSubscriberAnswer(p.onSubscriberAnswer)
 	tm.OnSubscriberICECandidate(func(c *webrtc.ICECandidate) error {
 		return p.onICECandidate(c, livekit.SignalTarget_SUBSCRIBER)
 	})
 	tm.OnSubscriberInitialConnected(p.onSubscriberInitialConnected)
 	tm.OnSubscriberStreamStateChange(p.onStreamStateChange)
+	if p.params.MaxSubscribeBitrate > 0 {
+		tm.SetSubscriberMaxChannelCapacity(p.params.MaxSubscribeBitrate)
+	}
 
 	tm.OnPrimaryTransportInitialConnected(p.onPrimaryTransportInitialConnected)
 	tm.OnPrimaryTransportFullyEstablished(p.onPrimaryTransportFullyEstablished)
@@ -1418,7 +1541,8 @@ func (p *ParticipantImpl) onSubscribedMaxQualityChange(trackID livekit.TrackID,
 	})
 }
 
-func (p *ParticipantImpl) addPendingTrackLocked(req *livekit.AddTrackRequest) *livekit.TrackInfo {
+// addPendingTrackLocked adds a track to publish with trackID, or with a generated one when empty
+func (p *ParticipantImpl) addPendingTrackLocked(req *livekit.AddTrackRequest, trackID livekit.TrackID) *livekit.TrackInfo {
 	p.pendingTracksLock.Lock()
 	defer p.pendingTracksLock.Unlock()
 
@@ -1446,7 +1570,11 @@ func (p *ParticipantImpl) addPendingTrackLocked(req *livekit.AddTrackRequest) *l
 		DisableRed: req.DisableRed,
 		Stereo:     req.Stereo,
 	}
-	p.setStableTrackID(req.Cid, ti)
+	if trackID != "" {
+		ti.Sid = string(trackID)
+	} else {
+		p.setStableTrackID(req.Cid, ti)
+	}
 	for _, codec := range req.SimulcastCodecs {
 		mime := codec.Codec
 		if req.Type == livekit.TrackType_VIDEO && !strings.HasPrefix(mime, "video/") {
@@ -1558,7 +1686,7 @@ func (p *ParticipantImpl) mediaTrackReceived(track *webrtc.TrackRemote, rtpRecei
 		}
 
 		ti.MimeType = track.Codec().MimeType
-		mt = p.addMediaTrack(signalCid, track.ID(), ti)
+		mt = p.addMediaTrack(signalCid, track.ID(), ti, p.rtcpCh)
 		newTrack = true
 	}
 
@@ -1578,6 +1706,47 @@ func (p *ParticipantImpl) mediaTrackReceived(track *webrtc.TrackRemote, rtpRecei
 	return mt, newTrack
 }
 
+// AddExternalTrack publishes a track received outside of the publisher PeerConnection, as with RTP ingest.
+// The caller writes its packets to the buffer factory, as the PeerConnection would, and receives RTCP for the
+// publisher on rtcpCh. The track is published with trackID when set.
+func (p *ParticipantImpl) AddExternalTrack(
+	req *livekit.AddTrackRequest,
+	trackID livekit.TrackID,
+	receiver sfu.RTPReceiver,
+	track sfu.TrackRemote,
+	rtcpCh chan []rtcp.Packet,
+) (types.MediaTrack, error) {
+	if p.State() == livekit.ParticipantInfo_DISCONNECTED {
+		return nil, ErrParticipantDisconnected
+	}
+
+	p.lock.Lock()
+	if !p.grants.Video.GetCanPublish() {
+		p.lock.Unlock()
+		return nil, ErrPermissionDenied
+	}
+	ti := p.addPendingTrackLocked(req, trackID)
+	p.lock.Unlock()
+	if ti == nil {
+		return nil, ErrTrackAlreadyPublished
+	}
+
+	p.pendingTracksLock.Lock()
+	ti.MimeType = track.Codec().MimeType
+	mt := p.addMediaTrack(req.Cid, track.ID(), ti, rtcpCh)
+	p.pendingTracksLock.Unlock()
+
+	p.params.Logger.Infow("external mediaTrack published",
+		"kind", track.Kind().String(),
+		"trackID", mt.ID(),
+		"SSRC", track.SSRC(),
+		"mime", track.Codec().MimeType)
+	if mt.AddReceiver(receiver, track, nil, "") {
+		p.handleTrackPublished(mt)
+	}
+	return mt, nil
+}
+
 func (p *ParticipantImpl) addMigrateMutedTrack(cid string, ti *livekit.TrackInfo) *MediaTrack {
 	p.params.Logger.Debugw("add migrate muted track", "cid", cid, "track", ti.String())
 	rtpReceiver := p.TransportManager.GetPublisherRTPReceiver(ti.Mid)
@@ -1586,7 +1755,7 @@ func (p *ParticipantImpl) addMigrateMutedTrack(cid string, ti *livekit.TrackInfo
 		return nil
 	}
 
-	mt := p.addMediaTrack(cid, cid, ti)
+	mt := p.addMediaTrack(cid, cid, ti, p.rtcpCh)
 
 	potentialCodecs := make([]webrtc.RTPCodecParameters, 0, len(ti.Codecs))
 	parameters := rtpReceiver.GetParameters()
@@ -1613,7 +1782,7 @@ func (p *ParticipantImpl) addMigrateMutedTrack(cid string, ti *livekit.TrackInfo
 	return mt
 }
 
-func (p *ParticipantImpl) addMediaTrack(signalCid string, sdpCid string, ti *livekit.TrackInfo) *MediaTrack {
+func (p *ParticipantImpl) addMediaTrack(signalCid string, sdpCid string, ti *livekit.TrackInfo, rtcpCh chan []rtcp.Packet) *MediaTrack {
 	mt := NewMediaTrack(MediaTrackParams{
 		TrackInfo:           proto.Clone(ti).(*livekit.TrackInfo),
 		SignalCid:           signalCid,
@@ -1621,16 +1790,17 @@ func (p *ParticipantImpl) addMediaTrack(signalCid string, sdpCid string, ti *liv
 		ParticipantID:       p.params.SID,
 		ParticipantIdentity: p.params.Identity,
 		ParticipantVersion:  p.version.Load(),
-		RTCPChan:            p.rtcpCh,
+		RTCPChan:            rtcpCh,
 		BufferFactory:       p.params.Config.BufferFactory,
 		ReceiverConfig:      p.params.Config.Receiver,
 		AudioConfig:         p.params.AudioConfig,
 		VideoConfig:         p.params.VideoConfig,
 		Telemetry:           p.params.Telemetry,
-		Logger:              LoggerWithTrack(p.params.Logger, livekit.TrackID(ti.Sid), false),
+		Logger:              LoggerWithTrack(p.params.Logger, livekit.TrackID(ti.Sid), p.params.Relayed),
 		SubscriberConfig:    p.params.Config.Subscriber,
 		PLIThrottleConfig:   p.params.PLIThrottleConfig,
 		SimTracks:           p.params.SimTracks,
+		IsRelayed:           p.params.Relayed,
 	})
 
 	mt.OnSubscribedMaxQualityChange(p.onSubscribedMaxQualityChange)
@@ -1956,15 +2126,20 @@ func (p *ParticipantImpl) EnqueueSubscribeTrack(trackID livekit.TrackID, isRelay
 
 	p.params.Logger.Debugw("queuing subscribe", "trackID", trackID, "relayed", isRelayed)
 
-	p.supervisor.UpdateSubscription(trackID, true)
-
 	p.lock.Lock()
+	if p.subscriberAnswered {
+		p.lock.Unlock()
+		p.params.Logger.Infow("rejecting subscribe, subscriber offer already answered", "trackID", trackID)
+		return false
+	}
 	p.subscriptionRequestsQueue[trackID] = append(p.subscriptionRequestsQueue[trackID], SubscribeRequest{
 		requestType: SubscribeRequestTypeAdd,
 		addCb:       f,
 	})
 	p.lock.Unlock()
 
+	p.supervisor.UpdateSubscription(trackID, true)
+
 	go p.ProcessSubscriptionRequestsQueue(trackID)
 	return true
 }
@@ -2037,6 +2212,12 @@ func (p *ParticipantImpl) ProcessSubscriptionRequestsQueue(trackID livekit.Track
 func (p *ParticipantImpl) ClearInProgressAndProcessSubscriptionRequestsQueue(trackID livekit.TrackID) {
 	p.lock.Lock()
 	delete(p.subscriptionInProgress, trackID)
+	if !p.hasPendingSubscriptionsLocked() {
+		for _, settled := range p.subscriptionsSettled {
+			close(settled)
+		}
+		p.subscriptionsSettled = nil
+	}
 	p.lock.Unlock()
 
 	go p.ProcessSubscriptionRequestsQueue(trackID)
@@ -428,12 +428,77 @@ func TestDisableCodecs(t *testing.T) {
 	require.False(t, found264)
 }
 
+func TestClientOffersSubscriber(t *testing.T) {
+	t.Run("answers once subscriptions complete", func(t *testing.T) {
+		p := newParticipantForTestWithOpts("sub", &participantOpts{clientOffersSubscriber: true})
+		sink := p.params.Sink.(*routingfakes.FakeMessageSink)
+
+		release := make(chan struct{})
+		require.True(t, p.EnqueueSubscribeTrack("TR_video", false, func(sub types.LocalParticipant) error {
+			<-release
+			return errAlreadySubscribed
+		}))
+
+		offerer, err := webrtc.NewPeerConnection(webrtc.Configuration{})
+		require.NoError(t, err)
+		defer offerer.Close()
+		_, err = offerer.AddTransceiverFromKind(webrtc.RTPCodecTypeVideo, webrtc.RTPTransceiverInit{
+			Direction: webrtc.RTPTransceiverDirectionRecvonly,
+		})
+		require.NoError(t, err)
+		offer, err := offerer.CreateOffer(nil)
+		require.NoError(t, err)
+		require.NoError(t, offerer.SetLocalDescription(offer))
+
+		p.HandleOffer(offer)
+
+		// the answer is held while the subscription is in progress
+		time.Sleep(100 * time.Millisecond)
+		require.Zero(t, sink.WriteMessageCallCount())
+
+		close(release)
+		require.Eventually(t, func() bool {
+			for i := 0; i < sink.WriteMessageCallCount(); i++ {
+				if sink.WriteMessageArgsForCall(i).(*livekit.SignalResponse).GetAnswer() != nil {
+					return true
+				}
+			}
+			return false
+		}, 5*time.Second, 10*time.Millisecond)
+
+		// tracks subscribed after the answer cannot be negotiated
+		require.False(t, p.EnqueueSubscribeTrack("TR_late", false, func(sub types.LocalParticipant) error {
+			return nil
+		}))
+	})
+
+	t.Run("gives up waiting on timeout", func(t *testing.T) {
+		p := newParticipantForTestWithOpts("sub", &participantOpts{clientOffersSubscriber: true})
+
+		release := make(chan struct{})
+		defer close(release)
+		require.True(t, p.EnqueueSubscribeTrack("TR_video", false, func(sub types.LocalParticipant) error {
+			<-release
+			return errAlreadySubscribed
+		}))
+		require.Eventually(t, func() bool {
+			p.lock.RLock()
+			defer p.lock.RUnlock()
+			return p.subscriptionInProgress["TR_video"]
+		}, time.Second, 10*time.Millisecond)
+
+		require.False(t, p.waitForSubscriptions(50*time.Millisecond))
+	})
+}
+
 type participantOpts struct {
 	permissions     *livekit.ParticipantPermission
 	protocolVersion types.ProtocolVersion
 	publisher       bool
 	clientConf      *livekit.ClientConfiguration
 	clientInfo      *livekit.ClientInfo
+	// client sends the subscriber offer
+	clientOffersSubscriber bool
 }
 
 func newParticipantForTestWithOpts(identity livekit.ParticipantIdentity, opts *participantOpts) *ParticipantImpl {
@@ -468,16 +533,17 @@ func newParticipantForTestWithOpts(identity livekit.ParticipantIdentity, opts *p
 		})
 	}
 	p, _ := NewParticipant(ParticipantParams{
-		SID:               livekit.ParticipantID(utils.NewGuid(utils.ParticipantPrefix)),
-		Identity:          identity,
-		Config:            rtcConf,
-		Sink:              &routingfakes.FakeMessageSink{},
-		ProtocolVersion:   opts.protocolVersion,
-		PLIThrottleConfig: conf.RTC.PLIThrottle,
-		Grants:            grants,
-		EnabledCodecs:     enabledCodecs,
-		ClientConf:        opts.clientConf,
-		ClientInfo:        ClientInfo{ClientInfo: opts.clientInfo},
+		SID:                    livekit.ParticipantID(utils.NewGuid(utils.ParticipantPrefix)),
+		Identity:               identity,
+		Config:                 rtcConf,
+		Sink:                   &routingfakes.FakeMessageSink{},
+		ProtocolVersion:        opts.protocolVersion,
+		PLIThrottleConfig:      conf.RTC.PLIThrottle,
+		Grants:                 grants,
+		EnabledCodecs:          enabledCodecs,
+		ClientConf:             opts.clientConf,
+		ClientInfo:             ClientInfo{ClientInfo: opts.clientInfo},
+		ClientOffersSubscriber: opts.clientOffersSubscriber,
 	})
 	p.isPublisher.Store(opts.publisher)
 
@@ -31,7 +31,12 @@ func (p *ParticipantImpl) SetResponseSink(sink routing.MessageSink) {
 
 func (p *ParticipantImpl) SendJoinResponse(joinResponse *livekit.JoinResponse) error {
 	if p.State() == livekit.ParticipantInfo_JOINING {
-		p.updateState(livekit.ParticipantInfo_JOINED)
+		if p.params.ExternalMedia {
+			// no transport to wait for
+			p.updateState(livekit.ParticipantInfo_ACTIVE)
+		} else {
+			p.updateState(livekit.ParticipantInfo_JOINED)
+		}
 	}
 
 	// send Join response
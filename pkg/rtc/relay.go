@@ -0,0 +1,343 @@
+package rtc
+
+import (
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
+	"github.com/pion/sdp/v3"
+	"github.com/pion/srtp/v2"
+	"github.com/pion/webrtc/v3"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+	"github.com/livekit/livekit-server/pkg/sfu"
+	"github.com/livekit/livekit-server/pkg/sfu/buffer"
+)
+
+const (
+	RelaySubscriberPrefix = "RELAY_"
+
+	relayPayloadType          = 96
+	relayAudioLevelID         = 1
+	relaySenderReportInterval = time.Second
+)
+
+// RelayCodec returns the codec a track is relayed to other nodes in, false until the track has a receiver.
+// Audio is relayed without redundancy
+func RelayCodec(track types.MediaTrack) (webrtc.RTPCodecParameters, bool) {
+	mt, ok := track.(*MediaTrack)
+	if !ok {
+		return webrtc.RTPCodecParameters{}, false
+	}
+	receiver := mt.PrimaryReceiver()
+	if receiver == nil {
+		return webrtc.RTPCodecParameters{}, false
+	}
+
+	codec := receiver.Codec()
+	if strings.EqualFold(codec.MimeType, sfu.MimeTypeAudioRed) {
+		codec.RTPCodecCapability = webrtc.RTPCodecCapability{
+			MimeType:    webrtc.MimeTypeOpus,
+			ClockRate:   48000,
+			Channels:    2,
+			SDPFmtpLine: "minptime=10;useinbandfec=1",
+		}
+	}
+	codec.PayloadType = relayPayloadType
+	codec.RTCPFeedback = nil
+	return codec, true
+}
+
+// RelayHeaderExtensions returns header extensions of relayed tracks. Audio levels are relayed, so that participants
+// hosted on other nodes can be active speakers
+func RelayHeaderExtensions(kind livekit.TrackType) []webrtc.RTPHeaderExtensionParameter {
+	if kind != livekit.TrackType_AUDIO {
+		return nil
+	}
+	return []webrtc.RTPHeaderExtensionParameter{
+		{URI: sdp.AudioLevelURI, ID: relayAudioLevelID},
+	}
+}
+
+type TrackRelayParams struct {
+	Track *MediaTrack
+	// node the track is relayed to
+	NodeID     livekit.NodeID
+	Codec      webrtc.RTPCodecParameters
+	Conn       net.PacketConn
+	RemoteAddr net.Addr
+	// SSRC the RTPIngest receiving the track published it with
+	SSRC uint32
+	// master key followed by master salt, shared with the RTPIngest receiving the track
+	SRTPKey     []byte
+	SRTPProfile srtp.ProtectionProfile
+	Logger      logger.Logger
+}
+
+// TrackRelay forwards a track to another node hosting the room, as SRTP sent to an RTPIngest there. RTCP sent back,
+// NACKs, PLIs and receiver reports, is handled by its DownTrack as a subscriber's would be. Video starts at the lowest
+// layer, and then follows the qualities subscribed on that node. Relaying pauses while nothing subscribes there
+type TrackRelay struct {
+	params    TrackRelayParams
+	transport *relayTransport
+	downTrack *sfu.DownTrack
+
+	lock       sync.Mutex
+	paused     bool
+	maxQuality livekit.VideoQuality
+	onClose    func()
+
+	closeOnce sync.Once
+	done      chan struct{}
+}
+
+func NewTrackRelay(params TrackRelayParams) (*TrackRelay, error) {
+	transport, err := newRelayTransport(params.Conn, params.RemoteAddr, params.SRTPKey, params.SRTPProfile)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &TrackRelay{
+		params:     params,
+		transport:  transport,
+		maxQuality: livekit.VideoQuality_LOW,
+		done:       make(chan struct{}),
+	}
+	audioLevelID := 0
+	if params.Track.Kind() == livekit.TrackType_AUDIO {
+		audioLevelID = relayAudioLevelID
+	}
+	r.downTrack, err = params.Track.addLocalDownTrack(
+		livekit.ParticipantID(RelaySubscriberPrefix+string(params.NodeID)),
+		[]webrtc.RTPCodecParameters{params.Codec},
+		transport,
+		webrtc.SSRC(params.SSRC),
+		audioLevelID,
+		nil,
+	)
+	if err != nil {
+		transport.close()
+		return nil, err
+	}
+	r.downTrack.OnCloseHandler(func(_ bool) {
+		r.Close()
+	})
+
+	go transport.readWorker(r.downTrack.HandleRTCP)
+	go r.senderReportWorker()
+
+	if r.downTrack.Kind() == webrtc.RTPCodecTypeVideo {
+		r.downTrack.SetMaxTemporalLayer(sfu.DefaultMaxLayerTemporal)
+	}
+	r.update()
+	if r.downTrack.IsClosed() {
+		r.Close()
+	}
+	return r, nil
+}
+
+func (r *TrackRelay) OnClose(f func()) {
+	r.lock.Lock()
+	r.onClose = f
+	r.lock.Unlock()
+}
+
+func (r *TrackRelay) SSRC() uint32 {
+	return r.params.SSRC
+}
+
+// SetMaxQuality forwards the video layer of the highest quality subscribed on the node relayed to, pausing at OFF
+func (r *TrackRelay) SetMaxQuality(quality livekit.VideoQuality) {
+	r.lock.Lock()
+	r.maxQuality = quality
+	r.lock.Unlock()
+
+	r.update()
+}
+
+// SetPaused pauses relaying while nothing subscribes to the track on the node relayed to
+func (r *TrackRelay) SetPaused(paused bool) {
+	r.lock.Lock()
+	r.paused = paused
+	r.lock.Unlock()
+
+	r.update()
+}
+
+func (r *TrackRelay) update() {
+	r.lock.Lock()
+	paused := r.paused
+	quality := r.maxQuality
+	r.lock.Unlock()
+
+	if r.downTrack.Kind() != webrtc.RTPCodecTypeVideo {
+		r.downTrack.Mute(paused)
+		return
+	}
+
+	if paused {
+		quality = livekit.VideoQuality_OFF
+	}
+	r.params.Track.NotifySubscriberNodeMaxQuality(r.params.NodeID, []types.SubscribedCodecQuality{
+		{CodecMime: r.downTrack.Codec().MimeType, Quality: quality},
+	})
+
+	layer := buffer.VideoQualityToSpatialLayer(quality, r.params.Track.ToProto())
+	if layer == buffer.InvalidLayerSpatial {
+		r.downTrack.Mute(true)
+		return
+	}
+	r.downTrack.Mute(false)
+	r.downTrack.SetMaxSpatialLayer(layer)
+	r.downTrack.AllocateOptimal(true)
+}
+
+// Close stops relaying, the node relayed to unpublishes the track once its ingest is closed
+func (r *TrackRelay) Close() {
+	r.closeOnce.Do(func() {
+		close(r.done)
+		r.downTrack.Close()
+		r.transport.close()
+		if r.downTrack.Kind() == webrtc.RTPCodecTypeVideo {
+			r.params.Track.NotifySubscriberNodeMaxQuality(r.params.NodeID, []types.SubscribedCodecQuality{
+				{CodecMime: r.downTrack.Codec().MimeType, Quality: livekit.VideoQuality_OFF},
+			})
+		}
+
+		r.lock.Lock()
+		onClose := r.onClose
+		r.lock.Unlock()
+		if onClose != nil {
+			onClose()
+		}
+	})
+}
+
+func (r *TrackRelay) Done() <-chan struct{} {
+	return r.done
+}
+
+// senderReportWorker sends sender reports, which the node relayed to needs to synchronize tracks
+func (r *TrackRelay) senderReportWorker() {
+	defer Recover()
+
+	ticker := time.NewTicker(relaySenderReportInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.done:
+			return
+		case <-ticker.C:
+			sr := r.downTrack.CreateSenderReport()
+			if sr == nil {
+				continue
+			}
+			if err := r.transport.writeRTCP([]rtcp.Packet{sr}); err != nil {
+				r.params.Logger.Debugw("could not write sender report", "error", err)
+			}
+		}
+	}
+}
+
+// ---------------------------------------------------------------
+
+// relayTransport sends SRTP to a remote address from its own socket, and reads SRTCP sent back to it.
+// SRTP contexts hold state of a direction, RTCP received is decrypted with the same keys
+type relayTransport struct {
+	conn   net.PacketConn
+	remote net.Addr
+
+	lock      sync.Mutex
+	encryptor *srtp.Context
+	decryptor *srtp.Context
+
+	closeOnce sync.Once
+}
+
+func newRelayTransport(conn net.PacketConn, remote net.Addr, key []byte, profile srtp.ProtectionProfile) (*relayTransport, error) {
+	encryptor, err := newSRTPContext(key, profile)
+	if err != nil {
+		return nil, err
+	}
+	decryptor, err := newSRTPContext(key, profile)
+	if err != nil {
+		return nil, err
+	}
+	return &relayTransport{
+		conn:      conn,
+		remote:    remote,
+		encryptor: encryptor,
+		decryptor: decryptor,
+	}, nil
+}
+
+// WriteRTP implements webrtc.TrackLocalWriter
+func (t *relayTransport) WriteRTP(header *rtp.Header, payload []byte) (int, error) {
+	b, err := (&rtp.Packet{Header: *header, Payload: payload}).Marshal()
+	if err != nil {
+		return 0, err
+	}
+	return t.Write(b)
+}
+
+// Write implements webrtc.TrackLocalWriter, b is a marshaled RTP packet
+func (t *relayTransport) Write(b []byte) (int, error) {
+	t.lock.Lock()
+	encrypted, err := t.encryptor.EncryptRTP(nil, b, nil)
+	t.lock.Unlock()
+	if err != nil {
+		return 0, err
+	}
+	if _, err = t.conn.WriteTo(encrypted, t.remote); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+func (t *relayTransport) writeRTCP(pkts []rtcp.Packet) error {
+	b, err := rtcp.Marshal(pkts)
+	if err != nil {
+		return err
+	}
+	t.lock.Lock()
+	encrypted, err := t.encryptor.EncryptRTCP(nil, b, nil)
+	t.lock.Unlock()
+	if err != nil {
+		return err
+	}
+	_, err = t.conn.WriteTo(encrypted, t.remote)
+	return err
+}
+
+// readWorker passes RTCP received from the remote address to onRTCP, until the transport is closed
+func (t *relayTransport) readWorker(onRTCP func(pkt []byte)) {
+	defer Recover()
+
+	buf := make([]byte, rtpIngestMaxPacketSize)
+	for {
+		n, addr, err := t.conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		if addr.String() != t.remote.String() || !isRTCPPacket(buf[:n]) {
+			continue
+		}
+		pkt, err := t.decryptor.DecryptRTCP(nil, buf[:n], nil)
+		if err != nil {
+			continue
+		}
+		onRTCP(pkt)
+	}
+}
+
+func (t *relayTransport) close() {
+	t.closeOnce.Do(func() {
+		_ = t.conn.Close()
+	})
+}
@@ -0,0 +1,109 @@
+package rtc
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
+	"github.com/pion/srtp/v2"
+	"github.com/pion/webrtc/v3"
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/sfu/buffer"
+)
+
+func TestRelayTransport(t *testing.T) {
+	key := make([]byte, 30)
+	for i := range key {
+		key[i] = byte(30 - i)
+	}
+
+	// the relay on the receiving node
+	ingestConn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	publisher := &testExternalPublisher{
+		requests: make(chan *livekit.AddTrackRequest, 5),
+		rtcpCh:   make(chan chan []rtcp.Packet, 5),
+	}
+	bufferFactory := buffer.NewFactoryOfBufferFactory(500).CreateBufferFactory()
+	ingest, err := NewRTPIngest(RTPIngestParams{
+		Conn: ingestConn,
+		Tracks: []RTPIngestTrack{
+			{
+				Name:   "microphone",
+				Source: livekit.TrackSource_MICROPHONE,
+				Codec: webrtc.RTPCodecParameters{
+					RTPCodecCapability: webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeOpus, ClockRate: 48000, Channels: 2},
+					PayloadType:        relayPayloadType,
+				},
+				HeaderExtensions: RelayHeaderExtensions(livekit.TrackType_AUDIO),
+				SSRC:             4321,
+			},
+		},
+		SRTPKey:       key,
+		SRTPProfile:   srtp.ProtectionProfileAes128CmHmacSha1_80,
+		Publisher:     publisher,
+		BufferFactory: bufferFactory,
+		Logger:        logger.GetDefaultLogger(),
+	})
+	require.NoError(t, err)
+	ingest.Start()
+	t.Cleanup(ingest.Close)
+
+	// and the one on the node the track is published on
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	transport, err := newRelayTransport(conn, ingestConn.LocalAddr(), key, srtp.ProtectionProfileAes128CmHmacSha1_80)
+	require.NoError(t, err)
+	t.Cleanup(transport.close)
+	received := make(chan []byte, 5)
+	go transport.readWorker(func(pkt []byte) {
+		received <- pkt
+	})
+
+	header := &rtp.Header{
+		Version:        2,
+		PayloadType:    relayPayloadType,
+		SequenceNumber: 1,
+		Timestamp:      960,
+		SSRC:           4321,
+	}
+	require.NoError(t, header.SetExtension(relayAudioLevelID, []byte{0x80 | 30}))
+	_, err = transport.WriteRTP(header, []byte{0xfc, 0x01, 0x02})
+	require.NoError(t, err)
+
+	var rtcpCh chan []rtcp.Packet
+	select {
+	case rtcpCh = <-publisher.rtcpCh:
+	case <-time.After(time.Second):
+		require.Fail(t, "track not published")
+	}
+	req := <-publisher.requests
+	require.Equal(t, livekit.TrackType_AUDIO, req.Type)
+
+	buff := bufferFactory.GetBuffer(4321)
+	require.NotNil(t, buff)
+	b := make([]byte, 1500)
+	n, err := buff.Read(b)
+	require.NoError(t, err)
+	pkt := &rtp.Packet{}
+	require.NoError(t, pkt.Unmarshal(b[:n]))
+	require.Equal(t, []byte{0x80 | 30}, pkt.GetExtension(relayAudioLevelID))
+	require.Equal(t, []byte{0xfc, 0x01, 0x02}, pkt.Payload)
+
+	// feedback of the receiving node reaches the relayed DownTrack
+	rtcpCh <- []rtcp.Packet{&rtcp.PictureLossIndication{MediaSSRC: 4321}}
+	select {
+	case decrypted := <-received:
+		pkts, err := rtcp.Unmarshal(decrypted)
+		require.NoError(t, err)
+		require.Equal(t, uint32(4321), pkts[0].(*rtcp.PictureLossIndication).MediaSSRC)
+	case <-time.After(time.Second):
+		require.Fail(t, "feedback not received")
+	}
+}
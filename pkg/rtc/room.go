@@ -2,6 +2,7 @@ package rtc
 
 import (
 	"context"
+	"encoding/json"
 	"math"
 	"sort"
 	"sync"
@@ -28,8 +29,22 @@ const (
 	AudioLevelQuantization    = 8 // ideally power of 2 to minimize float decimal
 	invAudioLevelQuantization = 1.0 / AudioLevelQuantization
 	subscriberUpdateInterval  = 3 * time.Second
+	durationCheckInterval     = time.Second
+
+	// type of data packets warning participants ahead of a duration limit
+	DurationWarningType = "duration_warning"
+	// scopes of duration warnings, the room ends or the session of the participant does
+	DurationWarningScopeRoom        = "room"
+	DurationWarningScopeParticipant = "participant"
 )
 
+// DurationWarning is the payload of a data packet sent by the server ahead of a duration limit
+type DurationWarning struct {
+	Type             string `json:"type"`
+	Scope            string `json:"scope"`
+	RemainingSeconds int64  `json:"remaining_seconds"`
+}
+
 type broadcastOptions struct {
 	skipSource bool
 	immediate  bool
@@ -40,13 +55,18 @@ type Room struct {
 
 	protoRoom *livekit.Room
 	internal  *livekit.RoomInternal
+	options   RoomOptions
 	Logger    logger.Logger
 
 	config         WebRTCConfig
 	audioConfig    *config.AudioConfig
+	roomConfig     *config.RoomConfig
+	dataRouter     *dataPacketRouter
 	serverInfo     *livekit.ServerInfo
 	telemetry      telemetry.TelemetryService
 	egressLauncher EgressLauncher
+	tenantQuota    TenantQuota
+	signalLimiter  *signalLimiter
 
 	// map of identity -> Participant
 	participants    map[livekit.ParticipantIdentity]types.LocalParticipant
@@ -64,20 +84,48 @@ type Room struct {
 	leftAt atomic.Int64
 	closed chan struct{}
 
+	// forward only the loudest audio tracks when set, from room metadata or config
+	maxForwardedAudioTracks int
+
 	onParticipantChanged func(p types.LocalParticipant)
 	onMetadataUpdate     func(metadata string)
+	onDataPacketSent     func(source types.LocalParticipant, dp *livekit.DataPacket)
 	onClose              func()
 }
 
 type ParticipantOptions struct {
 	AutoSubscribe bool
+	// counted against quotas of the tenant by the node hosting it
+	Relayed bool
+}
+
+// RoomOptions are limits set on a room by the request that created it
+type RoomOptions struct {
+	// limit of downstream bitrate of each participant, in bps
+	MaxSubscribeBitrate int64 `json:"maxSubscribeBitrate,omitempty"`
+	// forward only the loudest audio tracks to each subscriber, overriding audio config when set
+	MaxForwardedAudioTracks int `json:"maxForwardedAudioTracks,omitempty"`
+	// limit of how long the room may run once a participant joined it, and of how long participant sessions may run
+	MaxDuration            time.Duration `json:"maxDuration,omitempty"`
+	MaxParticipantDuration time.Duration `json:"maxParticipantDuration,omitempty"`
+}
+
+// TenantQuota limits participants and published tracks of the tenant a room belongs to, across its rooms
+type TenantQuota interface {
+	ParticipantsExceeded() bool
+	TracksExceeded() bool
+	// ParticipantChanged counts a participant and tracks it publishes against the quotas, until ParticipantLeft
+	ParticipantChanged(participant *livekit.ParticipantInfo)
+	ParticipantLeft(participant *livekit.ParticipantInfo)
 }
 
 func NewRoom(
 	room *livekit.Room,
 	internal *livekit.RoomInternal,
+	options *RoomOptions,
 	config WebRTCConfig,
 	audioConfig *config.AudioConfig,
+	roomConfig *config.RoomConfig,
 	serverInfo *livekit.ServerInfo,
 	telemetry telemetry.TelemetryService,
 	egressLauncher EgressLauncher,
@@ -88,6 +136,8 @@ func NewRoom(
 		Logger:          LoggerWithRoom(logger.GetDefaultLogger(), livekit.RoomName(room.Name), livekit.RoomID(room.Sid)),
 		config:          config,
 		audioConfig:     audioConfig,
+		roomConfig:      roomConfig,
+		dataRouter:      newDataPacketRouter(&roomConfig.Data),
 		telemetry:       telemetry,
 		egressLauncher:  egressLauncher,
 		serverInfo:      serverInfo,
@@ -97,16 +147,24 @@ func NewRoom(
 		batchedUpdates:  make(map[livekit.ParticipantIdentity]*livekit.ParticipantInfo),
 		closed:          make(chan struct{}),
 	}
+	if options != nil {
+		r.options = *options
+	}
 	if r.protoRoom.EmptyTimeout == 0 {
 		r.protoRoom.EmptyTimeout = DefaultEmptyTimeout
 	}
 	if r.protoRoom.CreationTime == 0 {
 		r.protoRoom.CreationTime = time.Now().Unix()
 	}
+	r.maxForwardedAudioTracks = r.options.MaxForwardedAudioTracks
+	if r.maxForwardedAudioTracks == 0 {
+		r.maxForwardedAudioTracks = int(audioConfig.MaxForwardedTracks)
+	}
 
 	go r.audioUpdateWorker()
 	go r.connectionQualityWorker()
 	go r.subscriberBroadcastWorker()
+	go r.durationWorker()
 
 	return r
 }
@@ -198,6 +256,10 @@ func (r *Room) Internal() *livekit.RoomInternal {
 	return r.internal
 }
 
+func (r *Room) Options() RoomOptions {
+	return r.options
+}
+
 func (r *Room) Hold() bool {
 	r.lock.Lock()
 	defer r.lock.Unlock()
@@ -215,6 +277,12 @@ func (r *Room) Release() {
 }
 
 func (r *Room) Join(participant types.LocalParticipant, opts *ParticipantOptions, iceServers []*livekit.ICEServer) error {
+	// quotas are looked up across rooms, before locking this one
+	if r.tenantQuota != nil && !participant.Hidden() && (opts == nil || !opts.Relayed) && r.tenantQuota.ParticipantsExceeded() {
+		prometheus.ServiceOperationCounter.WithLabelValues("participant_join", "error", "tenant_max_exceeded").Add(1)
+		return ErrMaxTenantParticipantsExceeded
+	}
+
 	r.lock.Lock()
 	defer r.lock.Unlock()
 
@@ -273,6 +341,7 @@ func (r *Room) Join(participant types.LocalParticipant, opts *ParticipantOptions
 	participant.OnTrackUpdated(r.onTrackUpdated)
 	participant.OnParticipantUpdate(r.onParticipantUpdate)
 	participant.OnDataPacket(r.onDataPacket)
+	r.dataRouter.updateSubscription(participant.ID(), participant.DataTopics(), participant.ToProto().Metadata)
 	participant.OnSubscribedTo(func(p types.LocalParticipant, publisherID livekit.ParticipantID) {
 		go func() {
 			// when a participant subscribes to another participant,
@@ -398,6 +467,8 @@ func (r *Room) RemoveParticipant(identity livekit.ParticipantIdentity, reason ty
 	p.OnParticipantUpdate(nil)
 	p.OnDataPacket(nil)
 	p.OnSubscribedTo(nil)
+	r.dataRouter.removeParticipant(p.ID())
+	r.signalLimiter.removeParticipant(p.ID())
 
 	// close participant as well
 	r.Logger.Infow("closing participant for removal", "pID", p.ID(), "participant", p.Identity())
@@ -580,6 +651,77 @@ func (r *Room) SendDataPacket(up *livekit.UserPacket, kind livekit.DataPacket_Ki
 	r.onDataPacket(nil, dp)
 }
 
+// SetTenantQuota limits participants and published tracks of the room by quotas of its tenant
+func (r *Room) SetTenantQuota(quota TenantQuota) {
+	r.tenantQuota = quota
+}
+
+// TenantQuota returns quotas of the tenant of the room, nil if it has none
+func (r *Room) TenantQuota() TenantQuota {
+	return r.tenantQuota
+}
+
+// TracksQuotaExceeded returns true when the tenant of the room can't publish more tracks
+func (r *Room) TracksQuotaExceeded() bool {
+	return r.tenantQuota != nil && r.tenantQuota.TracksExceeded()
+}
+
+// SetSignalRateLimit limits rates of signal messages of each participant, and of all participants of the room
+func (r *Room) SetSignalRateLimit(conf config.SignalRateLimitConfig) {
+	r.signalLimiter = newSignalLimiter(conf)
+}
+
+// AllowSignal returns false when a signal message of a participant exceeds rate limits, and is to be dropped
+func (r *Room) AllowSignal(participantID livekit.ParticipantID) bool {
+	if by := r.signalLimiter.allow(participantID); by != "" {
+		prometheus.IncrementRateLimited("signal", by)
+		return false
+	}
+	return true
+}
+
+// MaxDuration returns how long the room may run once a participant joined it, 0 if unlimited
+func (r *Room) MaxDuration() time.Duration {
+	maxDuration := r.roomConfig.MaxDuration
+	if limit := r.options.MaxDuration; limit > 0 && (maxDuration <= 0 || limit < maxDuration) {
+		maxDuration = limit
+	}
+	return maxDuration
+}
+
+// MaxParticipantDuration returns how long sessions of participants may run, 0 if unlimited
+func (r *Room) MaxParticipantDuration() time.Duration {
+	maxParticipantDuration := r.roomConfig.MaxParticipantDuration
+	if limit := r.options.MaxParticipantDuration; limit > 0 && (maxParticipantDuration <= 0 || limit < maxParticipantDuration) {
+		maxParticipantDuration = limit
+	}
+	return maxParticipantDuration
+}
+
+// DurationWarning returns how long before a duration limit participants are warned, 0 to not warn them
+func (r *Room) DurationWarning() time.Duration {
+	return r.roomConfig.DurationWarning
+}
+
+// SendDurationWarning warns participants of the room, or only participant when set, that a duration limit is
+// reached in remaining
+func (r *Room) SendDurationWarning(participant types.LocalParticipant, scope string, remaining time.Duration) {
+	payload, err := json.Marshal(&DurationWarning{
+		Type:             DurationWarningType,
+		Scope:            scope,
+		RemainingSeconds: int64(math.Ceil(remaining.Seconds())),
+	})
+	if err != nil {
+		return
+	}
+
+	up := &livekit.UserPacket{Payload: payload}
+	if participant != nil {
+		up.DestinationSids = []string{string(participant.ID())}
+	}
+	r.SendDataPacket(up, livekit.DataPacket_RELIABLE)
+}
+
 func (r *Room) SetMetadata(metadata string) {
 	r.lock.Lock()
 	r.protoRoom.Metadata = metadata
@@ -612,6 +754,18 @@ func (r *Room) OnMetadataUpdate(f func(metadata string)) {
 	r.onMetadataUpdate = f
 }
 
+// OnDataPacketSent is called with data packets sent by participants of the room, and by the server through the room,
+// once they are delivered. Source is nil for packets from the server
+func (r *Room) OnDataPacketSent(f func(source types.LocalParticipant, dp *livekit.DataPacket)) {
+	r.onDataPacketSent = f
+}
+
+// DeliverDataPacket sends a data packet to participants as if source sent it, without limits checked on senders
+// and without calling OnDataPacketSent, as with packets relayed from another node. Source is nil for the server
+func (r *Room) DeliverDataPacket(source types.LocalParticipant, dp *livekit.DataPacket) {
+	r.deliverDataPacket(source, dp, getUserPacketRouting(dp.GetUser()))
+}
+
 func (r *Room) SimulateScenario(participant types.LocalParticipant, simulateScenario *livekit.SimulateScenario) error {
 	switch scenario := simulateScenario.Scenario.(type) {
 	case *livekit.SimulateScenario_SpeakerUpdate:
@@ -716,6 +870,10 @@ func (r *Room) onTrackPublished(participant types.LocalParticipant, track types.
 		if !r.autoSubscribe(existingParticipant) {
 			continue
 		}
+		if existingParticipant.NumVideoSlots() > 0 && IsVideoSlotSource(track) {
+			// shown in video slots
+			continue
+		}
 
 		r.Logger.Debugw("subscribing to new track",
 			"participant", existingParticipant.Identity(),
@@ -763,6 +921,8 @@ func (r *Room) onTrackUpdated(p types.LocalParticipant, _ types.MediaTrack) {
 }
 
 func (r *Room) onParticipantUpdate(p types.LocalParticipant) {
+	r.dataRouter.updateSubscription(p.ID(), p.DataTopics(), p.ToProto().Metadata)
+
 	// immediately notify when permissions or metadata changed
 	r.broadcastParticipantState(p, broadcastOptions{immediate: true})
 	if r.onParticipantChanged != nil {
@@ -771,8 +931,23 @@ func (r *Room) onParticipantUpdate(p types.LocalParticipant) {
 }
 
 func (r *Room) onDataPacket(source types.LocalParticipant, dp *livekit.DataPacket) {
-	dest := dp.GetUser().GetDestinationSids()
+	routing := getUserPacketRouting(dp.GetUser())
 
+	if source != nil {
+		if err := r.dataRouter.checkSender(source.ID(), routing.topic, len(dp.GetUser().GetPayload())); err != nil {
+			r.Logger.Debugw("dropping data packet", "error", err, "participant", source.Identity(), "topic", routing.topic)
+			return
+		}
+	}
+
+	r.deliverDataPacket(source, dp, routing)
+	if r.onDataPacketSent != nil {
+		r.onDataPacketSent(source, dp)
+	}
+}
+
+func (r *Room) deliverDataPacket(source types.LocalParticipant, dp *livekit.DataPacket, routing userPacketRouting) {
+	dest := dp.GetUser().GetDestinationSids()
 	for _, op := range r.GetParticipants() {
 		if op.State() != livekit.ParticipantInfo_ACTIVE {
 			continue
@@ -780,7 +955,7 @@ func (r *Room) onDataPacket(source types.LocalParticipant, dp *livekit.DataPacke
 		if source != nil && op.ID() == source.ID() {
 			continue
 		}
-		if len(dest) > 0 {
+		if len(dest) > 0 || len(routing.destinationIdentities) > 0 {
 			found := false
 			for _, dID := range dest {
 				if op.ID() == livekit.ParticipantID(dID) {
@@ -788,10 +963,19 @@ func (r *Room) onDataPacket(source types.LocalParticipant, dp *livekit.DataPacke
 					break
 				}
 			}
+			for _, dIdentity := range routing.destinationIdentities {
+				if op.Identity() == dIdentity {
+					found = true
+					break
+				}
+			}
 			if !found {
 				continue
 			}
 		}
+		if !r.dataRouter.isSubscribed(op.ID(), routing.topic) {
+			continue
+		}
 		err := op.SendDataPacket(dp)
 		if err != nil {
 			r.Logger.Infow("send data packet error", "error", err, "participant", op.Identity())
@@ -814,8 +998,20 @@ func (r *Room) subscribeToExistingTracks(p types.LocalParticipant) int {
 			continue
 		}
 
-		// subscribe to all
-		n, err := op.AddSubscriber(p, types.AddSubscriberParams{AllTracks: true})
+		// subscribe to all, except tracks shown in video slots
+		params := types.AddSubscriberParams{AllTracks: true}
+		if p.NumVideoSlots() > 0 {
+			params.AllTracks = false
+			for _, track := range op.GetPublishedTracks() {
+				if !IsVideoSlotSource(track) {
+					params.TrackIDs = append(params.TrackIDs, track.ID())
+				}
+			}
+			if len(params.TrackIDs) == 0 {
+				continue
+			}
+		}
+		n, err := op.AddSubscriber(p, params)
 		if err != nil {
 			// TODO: log error? or disconnect?
 			r.Logger.Errorw("could not subscribe to publisher", err,
@@ -964,11 +1160,16 @@ func (r *Room) subscriberBroadcastWorker() {
 
 func (r *Room) audioUpdateWorker() {
 	lastActiveMap := make(map[livekit.ParticipantID]*livekit.SpeakerInfo)
+	loudestAudio := make(map[livekit.ParticipantID]*loudestAudioSelector)
 	for {
 		if r.IsClosed() {
 			return
 		}
 
+		if r.maxForwardedAudioTracks > 0 {
+			r.forwardLoudestAudio(loudestAudio, r.getAudioTrackLevels(), time.Now())
+		}
+
 		activeSpeakers := r.GetActiveSpeakers()
 		changedSpeakers := make([]*livekit.SpeakerInfo, 0, len(activeSpeakers))
 		nextActiveMap := make(map[livekit.ParticipantID]*livekit.SpeakerInfo, len(activeSpeakers))
@@ -995,6 +1196,7 @@ func (r *Room) audioUpdateWorker() {
 			r.sendActiveSpeakers(activeSpeakers)
 			r.sendSpeakerChanges(changedSpeakers)
 		}
+		r.updateVideoSlots(activeSpeakers)
 
 		lastActiveMap = nextActiveMap
 
@@ -1002,6 +1204,168 @@ func (r *Room) audioUpdateWorker() {
 	}
 }
 
+func (r *Room) getAudioTrackLevels() []audioTrackLevel {
+	var levels []audioTrackLevel
+	for _, p := range r.GetParticipants() {
+		for _, track := range p.GetPublishedTracks() {
+			if track.Kind() != livekit.TrackType_AUDIO {
+				continue
+			}
+			mediaTrack, ok := track.(types.LocalMediaTrack)
+			if !ok {
+				continue
+			}
+			level, active := mediaTrack.GetAudioLevel()
+			levels = append(levels, audioTrackLevel{
+				trackID:     track.ID(),
+				publisherID: p.ID(),
+				level:       level,
+				active:      active && !track.IsMuted(),
+			})
+		}
+	}
+	return levels
+}
+
+// forwardLoudestAudio selects the loudest audio tracks for each subscriber, out of tracks it doesn't publish itself,
+// and pauses the others at the DownTrack so subscriptions are kept. Hidden participants, such as recorders,
+// receive all audio
+func (r *Room) forwardLoudestAudio(selectors map[livekit.ParticipantID]*loudestAudioSelector, levels []audioTrackLevel, now time.Time) {
+	participants := r.GetParticipants()
+	present := make(map[livekit.ParticipantID]bool, len(participants))
+	for _, p := range participants {
+		if p.Hidden() {
+			continue
+		}
+		present[p.ID()] = true
+
+		selector := selectors[p.ID()]
+		if selector == nil {
+			selector = newLoudestAudioSelector()
+			selector.SetMaxTracks(r.maxForwardedAudioTracks)
+			selectors[p.ID()] = selector
+		}
+		others := make([]audioTrackLevel, 0, len(levels))
+		for _, l := range levels {
+			if l.publisherID != p.ID() {
+				others = append(others, l)
+			}
+		}
+		selector.Update(others, now)
+
+		for _, st := range p.GetSubscribedTracks() {
+			if st.MediaTrack().Kind() != livekit.TrackType_AUDIO {
+				continue
+			}
+			st.SetPaused(!selector.IsSelected(st.MediaTrack().ID()))
+		}
+	}
+
+	for pID := range selectors {
+		if !present[pID] {
+			delete(selectors, pID)
+		}
+	}
+}
+
+// updateVideoSlots fills video slots of participants that requested them. Active speakers are shown first,
+// loudest first, followed by other publishers in the order they joined
+func (r *Room) updateVideoSlots(activeSpeakers []*livekit.SpeakerInfo) {
+	participants := r.GetParticipants()
+	var subscribers []types.LocalParticipant
+	for _, p := range participants {
+		if p.NumVideoSlots() > 0 && p.CanSubscribe() {
+			subscribers = append(subscribers, p)
+		}
+	}
+	if len(subscribers) == 0 {
+		return
+	}
+
+	sort.Slice(participants, func(i, j int) bool {
+		return participants[i].ConnectedAt().Before(participants[j].ConnectedAt())
+	})
+	videoTracks := make(map[livekit.ParticipantID]types.MediaTrack)
+	for _, p := range participants {
+		if p.Hidden() {
+			continue
+		}
+		for _, track := range p.GetPublishedTracks() {
+			if IsVideoSlotSource(track) && !track.IsMuted() {
+				videoTracks[p.ID()] = track
+				break
+			}
+		}
+	}
+
+	for _, sub := range subscribers {
+		allowedTrack := func(pID livekit.ParticipantID) types.MediaTrack {
+			track := videoTracks[pID]
+			if pID == sub.ID() || track == nil {
+				return nil
+			}
+			if publisher := r.GetParticipantBySid(pID); publisher == nil || !publisher.HasPermission(track.ID(), sub.Identity()) {
+				return nil
+			}
+			return track
+		}
+
+		var speakers, others []types.MediaTrack
+		speaking := make(map[livekit.ParticipantID]bool, len(activeSpeakers))
+		for _, speaker := range activeSpeakers {
+			pID := livekit.ParticipantID(speaker.Sid)
+			speaking[pID] = true
+			if track := allowedTrack(pID); track != nil {
+				speakers = append(speakers, track)
+			}
+		}
+		for _, p := range participants {
+			if speaking[p.ID()] {
+				continue
+			}
+			if track := allowedTrack(p.ID()); track != nil {
+				others = append(others, track)
+			}
+		}
+		sub.UpdateVideoSlots(speakers, others)
+	}
+}
+
+// durationWorker ends the room once it has run for its max duration, warning participants beforehand
+func (r *Room) durationWorker() {
+	ticker := time.NewTicker(durationCheckInterval)
+	defer ticker.Stop()
+
+	warned := false
+	for !r.IsClosed() {
+		<-ticker.C
+
+		maxDuration := r.MaxDuration()
+		if maxDuration <= 0 || r.FirstJoinedAt() == 0 {
+			continue
+		}
+		remaining := time.Until(time.Unix(r.FirstJoinedAt(), 0).Add(maxDuration))
+		if remaining > 0 {
+			if warning := r.DurationWarning(); !warned && warning > 0 && remaining <= warning {
+				warned = true
+				r.SendDurationWarning(nil, DurationWarningScopeRoom, remaining)
+			}
+			continue
+		}
+
+		r.Logger.Infow("room reached its max duration", "maxDuration", maxDuration)
+		r.telemetry.NotifyEvent(context.Background(), &livekit.WebhookEvent{
+			Event: telemetry.EventRoomDurationExceeded,
+			Room:  r.ToProto(),
+		})
+		for _, p := range r.GetParticipants() {
+			_ = p.Close(true, types.ParticipantCloseReasonRoomDurationExceeded)
+		}
+		r.Close()
+		return
+	}
+}
+
 func (r *Room) connectionQualityWorker() {
 	ticker := time.NewTicker(connectionquality.UpdateInterval)
 	defer ticker.Stop()
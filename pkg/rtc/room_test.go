@@ -1,11 +1,14 @@
 package rtc
 
 import (
+	"encoding/json"
 	"fmt"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/require"
+	"go.uber.org/atomic"
+	"google.golang.org/protobuf/encoding/protowire"
 	"google.golang.org/protobuf/proto"
 
 	"github.com/livekit/livekit-server/version"
@@ -146,8 +149,38 @@ func TestRoomJoin(t *testing.T) {
 		err := rm.Join(p, nil, iceServersForRoom)
 		require.Equal(t, ErrMaxParticipantsExceeded, err)
 	})
+
+	t.Run("cannot exceed max participants of tenant", func(t *testing.T) {
+		rm := newRoomWithParticipants(t, testRoomOpts{num: 1})
+		rm.SetTenantQuota(&testTenantQuota{participantsExceeded: true})
+		p := newMockParticipant("second", types.ProtocolVersion(0), false, false)
+
+		err := rm.Join(p, nil, iceServersForRoom)
+		require.Equal(t, ErrMaxTenantParticipantsExceeded, err)
+
+		// relayed participants are counted by the node hosting them
+		err = rm.Join(p, &ParticipantOptions{Relayed: true}, iceServersForRoom)
+		require.NoError(t, err)
+	})
+}
+
+type testTenantQuota struct {
+	participantsExceeded bool
+	tracksExceeded       bool
+}
+
+func (q *testTenantQuota) ParticipantsExceeded() bool {
+	return q.participantsExceeded
+}
+
+func (q *testTenantQuota) TracksExceeded() bool {
+	return q.tracksExceeded
 }
 
+func (q *testTenantQuota) ParticipantChanged(_ *livekit.ParticipantInfo) {}
+
+func (q *testTenantQuota) ParticipantLeft(_ *livekit.ParticipantInfo) {}
+
 // various state changes to participant and that others are receiving update
 func TestParticipantUpdate(t *testing.T) {
 	tests := []struct {
@@ -355,6 +388,37 @@ func TestRoomClosure(t *testing.T) {
 		require.False(t, isClosed)
 	})
 
+	t.Run("room closes after max duration", func(t *testing.T) {
+		rm := newRoomWithParticipants(t, testRoomOpts{
+			num:             2,
+			durationWarning: 5 * time.Second,
+			options:         &RoomOptions{MaxDuration: 2 * time.Second},
+		})
+		isClosed := atomic.NewBool(false)
+		rm.OnClose(func() {
+			isClosed.Store(true)
+		})
+		require.Equal(t, 2*time.Second, rm.MaxDuration())
+
+		participants := rm.GetParticipants()
+		require.Eventually(t, func() bool {
+			return isClosed.Load()
+		}, 5*time.Second, 50*time.Millisecond)
+		for _, p := range participants {
+			fp := p.(*typesfakes.FakeLocalParticipant)
+			// warned once before closing
+			require.Equal(t, 1, fp.SendDataPacketCallCount())
+			payload := fp.SendDataPacketArgsForCall(0).GetUser().Payload
+			warning := &DurationWarning{}
+			require.NoError(t, json.Unmarshal(payload, warning))
+			require.Equal(t, DurationWarningScopeRoom, warning.Scope)
+			require.Positive(t, warning.RemainingSeconds)
+
+			_, reason := fp.CloseArgsForCall(0)
+			require.Equal(t, types.ParticipantCloseReasonRoomDurationExceeded, reason)
+		}
+	})
+
 	t.Run("room closes after empty timeout", func(t *testing.T) {
 		rm := newRoomWithParticipants(t, testRoomOpts{num: 0})
 		isClosed := false
@@ -613,6 +677,174 @@ func TestDataChannel(t *testing.T) {
 			require.Zero(t, fp.SendDataPacketCallCount())
 		}
 	})
+
+	t.Run("topic is only forwarded to subscribers", func(t *testing.T) {
+		rm := newRoomWithParticipants(t, testRoomOpts{num: 3})
+		defer rm.Close()
+		participants := rm.GetParticipants()
+		p := participants[0].(*typesfakes.FakeLocalParticipant)
+		chat := participants[1].(*typesfakes.FakeLocalParticipant)
+		cursor := participants[2].(*typesfakes.FakeLocalParticipant)
+
+		for fp, metadata := range map[*typesfakes.FakeLocalParticipant]string{
+			chat:   `{"data_topics": ["chat"]}`,
+			cursor: `{"data_topics": ["cursor"]}`,
+		} {
+			fp.ToProtoReturns(&livekit.ParticipantInfo{Sid: string(fp.ID()), Identity: string(fp.Identity()), Metadata: metadata})
+			fp.SetMetadata(metadata)
+		}
+
+		packet := livekit.DataPacket{
+			Kind: livekit.DataPacket_LOSSY,
+			Value: &livekit.DataPacket_User{
+				User: newTestUserPacket(t, []byte("hello"), "chat"),
+			},
+		}
+		p.OnDataPacketArgsForCall(0)(p, &packet)
+
+		require.Equal(t, 1, chat.SendDataPacketCallCount())
+		require.Zero(t, cursor.SendDataPacketCallCount())
+		// topic is forwarded
+		require.Equal(t, "chat", getUserPacketRouting(chat.SendDataPacketArgsForCall(0).GetUser()).topic)
+	})
+
+	t.Run("topics granted by token take precedence", func(t *testing.T) {
+		rm := newRoomWithParticipants(t, testRoomOpts{num: 2})
+		defer rm.Close()
+		participants := rm.GetParticipants()
+		p := participants[0].(*typesfakes.FakeLocalParticipant)
+		p1 := participants[1].(*typesfakes.FakeLocalParticipant)
+
+		p1.DataTopicsReturns([]string{"chat"})
+		metadata := `{"data_topics": ["cursor"]}`
+		p1.ToProtoReturns(&livekit.ParticipantInfo{Sid: string(p1.ID()), Identity: string(p1.Identity()), Metadata: metadata})
+		p1.SetMetadata(metadata)
+
+		for _, topic := range []string{"cursor", "chat"} {
+			p.OnDataPacketArgsForCall(0)(p, &livekit.DataPacket{
+				Kind: livekit.DataPacket_LOSSY,
+				Value: &livekit.DataPacket_User{
+					User: newTestUserPacket(t, []byte("hello"), topic),
+				},
+			})
+		}
+		require.Equal(t, 1, p1.SendDataPacketCallCount())
+		require.Equal(t, "chat", getUserPacketRouting(p1.SendDataPacketArgsForCall(0).GetUser()).topic)
+	})
+
+	t.Run("destination by identity", func(t *testing.T) {
+		rm := newRoomWithParticipants(t, testRoomOpts{num: 3})
+		defer rm.Close()
+		participants := rm.GetParticipants()
+		p := participants[0].(*typesfakes.FakeLocalParticipant)
+		p1 := participants[1].(*typesfakes.FakeLocalParticipant)
+		p2 := participants[2].(*typesfakes.FakeLocalParticipant)
+
+		packet := livekit.DataPacket{
+			Kind: livekit.DataPacket_RELIABLE,
+			Value: &livekit.DataPacket_User{
+				User: newTestUserPacket(t, []byte("message to p2.."), "", p2.Identity()),
+			},
+		}
+		p.OnDataPacketArgsForCall(0)(p, &packet)
+
+		require.Zero(t, p1.SendDataPacketCallCount())
+		require.Equal(t, 1, p2.SendDataPacketCallCount())
+	})
+
+	t.Run("limits are enforced per sender", func(t *testing.T) {
+		rm := newRoomWithParticipants(t, testRoomOpts{
+			num: 2,
+			dataConfig: config.DataConfig{
+				MaxPacketsPerSec: 2,
+				Topics:           []config.DataTopicConfig{{Name: "cursor", MaxPacketSize: 4}},
+			},
+		})
+		defer rm.Close()
+		participants := rm.GetParticipants()
+		p := participants[0].(*typesfakes.FakeLocalParticipant)
+		p1 := participants[1].(*typesfakes.FakeLocalParticipant)
+
+		send := func(payload []byte, topic string) {
+			p.OnDataPacketArgsForCall(0)(p, &livekit.DataPacket{
+				Kind: livekit.DataPacket_LOSSY,
+				Value: &livekit.DataPacket_User{
+					User: newTestUserPacket(t, payload, topic),
+				},
+			})
+		}
+
+		// too large for topic
+		send([]byte("too large"), "cursor")
+		require.Zero(t, p1.SendDataPacketCallCount())
+
+		send([]byte("1"), "cursor")
+		send([]byte("2"), "")
+		send([]byte("3"), "")
+		require.Equal(t, 2, p1.SendDataPacketCallCount())
+	})
+}
+
+func TestSignalRateLimit(t *testing.T) {
+	rm := newRoomWithParticipants(t, testRoomOpts{num: 2})
+	defer rm.Close()
+	rm.SetSignalRateLimit(config.SignalRateLimitConfig{
+		Participant: config.RateLimit{Rate: 1, Burst: 2},
+		Room:        config.RateLimit{Rate: 1, Burst: 3},
+	})
+	participants := rm.GetParticipants()
+	p0 := participants[0].(*typesfakes.FakeLocalParticipant)
+	p1 := participants[1].(*typesfakes.FakeLocalParticipant)
+
+	mute := &livekit.SignalRequest{
+		Message: &livekit.SignalRequest_Mute{Mute: &livekit.MuteTrackRequest{Sid: "track", Muted: true}},
+	}
+	for i := 0; i < 3; i++ {
+		require.NoError(t, HandleParticipantSignal(rm, p0, mute, rm.Logger))
+	}
+	// limited by participant
+	require.Equal(t, 2, p0.SetTrackMutedCallCount())
+
+	// limited by room
+	require.NoError(t, HandleParticipantSignal(rm, p1, mute, rm.Logger))
+	require.NoError(t, HandleParticipantSignal(rm, p1, mute, rm.Logger))
+	require.Equal(t, 1, p1.SetTrackMutedCallCount())
+
+	// negotiating is not limited
+	offer := &livekit.SignalRequest{
+		Message: &livekit.SignalRequest_Offer{Offer: &livekit.SessionDescription{Type: "offer", Sdp: "sdp"}},
+	}
+	answer := &livekit.SignalRequest{
+		Message: &livekit.SignalRequest_Answer{Answer: &livekit.SessionDescription{Type: "answer", Sdp: "sdp"}},
+	}
+	require.NoError(t, HandleParticipantSignal(rm, p1, offer, rm.Logger))
+	require.NoError(t, HandleParticipantSignal(rm, p1, answer, rm.Logger))
+	require.Equal(t, 1, p1.HandleOfferCallCount())
+	require.Equal(t, 1, p1.HandleAnswerCallCount())
+
+	// leaving is not limited
+	leave := &livekit.SignalRequest{Message: &livekit.SignalRequest_Leave{Leave: &livekit.LeaveRequest{}}}
+	require.NoError(t, HandleParticipantSignal(rm, p0, leave, rm.Logger))
+	require.Equal(t, 1, p0.CloseCallCount())
+}
+
+func newTestUserPacket(t *testing.T, payload []byte, topic string, identities ...livekit.ParticipantIdentity) *livekit.UserPacket {
+	data, err := proto.Marshal(&livekit.UserPacket{Payload: payload})
+	require.NoError(t, err)
+
+	// fields unknown to the protocol version in use
+	if topic != "" {
+		data = protowire.AppendTag(data, userPacketTopicField, protowire.BytesType)
+		data = protowire.AppendString(data, topic)
+	}
+	for _, identity := range identities {
+		data = protowire.AppendTag(data, userPacketDestinationIdentitiesField, protowire.BytesType)
+		data = protowire.AppendString(data, string(identity))
+	}
+
+	up := &livekit.UserPacket{}
+	require.NoError(t, proto.Unmarshal(data, up))
+	return up
 }
 
 func TestHiddenParticipants(t *testing.T) {
@@ -659,6 +891,59 @@ func TestHiddenParticipants(t *testing.T) {
 	})
 }
 
+func TestForwardLoudestAudio(t *testing.T) {
+	rm := newRoomWithParticipants(t, testRoomOpts{num: 3, options: &RoomOptions{MaxForwardedAudioTracks: 1}})
+	defer rm.Close()
+
+	participants := rm.GetParticipants()
+	tracks := make(map[livekit.ParticipantID]*typesfakes.FakeLocalMediaTrack)
+	for i, p := range participants {
+		track := &typesfakes.FakeLocalMediaTrack{}
+		track.IDReturns(livekit.TrackID(fmt.Sprintf("TR_%d", i)))
+		track.KindReturns(livekit.TrackType_AUDIO)
+		// the first participant is the loudest, the last one is silent
+		track.GetAudioLevelReturns(float64(len(participants)-i), i < len(participants)-1)
+		p.(*typesfakes.FakeLocalParticipant).GetPublishedTracksReturns([]types.MediaTrack{track})
+		tracks[p.ID()] = track
+	}
+
+	subscribed := make(map[livekit.ParticipantID]map[livekit.ParticipantID]*typesfakes.FakeSubscribedTrack)
+	for _, p := range participants {
+		subscribed[p.ID()] = make(map[livekit.ParticipantID]*typesfakes.FakeSubscribedTrack)
+		var sts []types.SubscribedTrack
+		for _, publisher := range participants {
+			if publisher == p {
+				continue
+			}
+			st := &typesfakes.FakeSubscribedTrack{}
+			st.MediaTrackReturns(tracks[publisher.ID()])
+			subscribed[p.ID()][publisher.ID()] = st
+			sts = append(sts, st)
+		}
+		p.(*typesfakes.FakeLocalParticipant).GetSubscribedTracksReturns(sts)
+	}
+
+	isForwarded := func(subscriber, publisher types.LocalParticipant) bool {
+		st := subscribed[subscriber.ID()][publisher.ID()]
+		return st.SetPausedCallCount() > 0 && !st.SetPausedArgsForCall(st.SetPausedCallCount()-1)
+	}
+	loudest, second, silent := participants[0], participants[1], participants[2]
+	testutils.WithTimeout(t, func() string {
+		// the loudest speaker hears the next loudest one, instead of nobody
+		if !isForwarded(loudest, second) || isForwarded(loudest, silent) {
+			return "loudest speaker does not receive the next loudest"
+		}
+		for _, p := range []types.LocalParticipant{second, silent} {
+			for _, publisher := range participants {
+				if publisher != p && isForwarded(p, publisher) != (publisher == loudest) {
+					return "subscriber does not receive only the loudest speaker"
+				}
+			}
+		}
+		return ""
+	})
+}
+
 func TestRoomUpdate(t *testing.T) {
 	t.Run("participants should receive metadata update", func(t *testing.T) {
 		rm := newRoomWithParticipants(t, testRoomOpts{num: 2})
@@ -678,17 +963,22 @@ type testRoomOpts struct {
 	numHidden            int
 	protocol             types.ProtocolVersion
 	audioSmoothIntervals uint32
+	dataConfig           config.DataConfig
+	durationWarning      time.Duration
+	options              *RoomOptions
 }
 
 func newRoomWithParticipants(t *testing.T, opts testRoomOpts) *Room {
 	rm := NewRoom(
 		&livekit.Room{Name: "room"},
 		nil,
+		opts.options,
 		WebRTCConfig{},
 		&config.AudioConfig{
 			UpdateInterval:  audioUpdateInterval,
 			SmoothIntervals: opts.audioSmoothIntervals,
 		},
+		&config.RoomConfig{Data: opts.dataConfig, DurationWarning: opts.durationWarning},
 		&livekit.ServerInfo{
 			Edition:  livekit.ServerInfo_Standard,
 			Version:  version.Version,
@@ -696,7 +986,7 @@ func newRoomWithParticipants(t *testing.T, opts testRoomOpts) *Room {
 			NodeId:   "testnode",
 			Region:   "testregion",
 		},
-		telemetry.NewTelemetryService(webhook.NewNotifier("", "", nil), &telemetryfakes.FakeAnalyticsService{}),
+		telemetry.NewTelemetryService(webhook.NewNotifier("", "", nil), &telemetryfakes.FakeAnalyticsService{}, nil),
 		nil,
 	)
 	for i := 0; i < opts.num+opts.numHidden; i++ {
@@ -0,0 +1,528 @@
+package rtc
+
+import (
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
+	"github.com/pion/srtp/v2"
+	"github.com/pion/transport/packetio"
+	"github.com/pion/webrtc/v3"
+	"go.uber.org/atomic"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+	"github.com/livekit/livekit-server/pkg/sfu"
+	"github.com/livekit/livekit-server/pkg/sfu/buffer"
+)
+
+const (
+	rtpIngestMaxPacketSize = 1500
+	rtpIngestStreamID      = "rtp_ingest"
+)
+
+// ExternalTrackPublisher publishes tracks received outside of its PeerConnections
+type ExternalTrackPublisher interface {
+	AddExternalTrack(
+		req *livekit.AddTrackRequest,
+		trackID livekit.TrackID,
+		receiver sfu.RTPReceiver,
+		track sfu.TrackRemote,
+		rtcpCh chan []rtcp.Packet,
+	) (types.MediaTrack, error)
+}
+
+// RTPIngestTrack is a track sent to an RTP ingest, its packets are recognized by the payload type of the codec
+type RTPIngestTrack struct {
+	Name   string
+	Source livekit.TrackSource
+	Codec  webrtc.RTPCodecParameters
+	// header extensions of received packets, as negotiated by a PeerConnection
+	HeaderExtensions []webrtc.RTPHeaderExtensionParameter
+	// published muted, and described with the dimensions and layers of its source
+	Muted  bool
+	Width  uint32
+	Height uint32
+	Layers []*livekit.VideoLayer
+	// published with this ID, as relayed tracks keep the ID they have on the node hosting their publisher.
+	// Generated when empty
+	TrackID livekit.TrackID
+	// published on Start with this SSRC, before any packet is received. Otherwise the first SSRC seen with the
+	// payload type is published
+	SSRC uint32
+}
+
+type RTPIngestParams struct {
+	Conn   net.PacketConn
+	Tracks []RTPIngestTrack
+	// master key followed by master salt, as in SDES. Plain RTP is received when empty
+	SRTPKey     []byte
+	SRTPProfile srtp.ProtectionProfile
+	// packets are only accepted from this address, from any of its ports when Port is 0. Required for plain RTP,
+	// which can't be authenticated
+	Source    *net.UDPAddr
+	Publisher ExternalTrackPublisher
+	// buffer factory of the room, where the publisher's PeerConnection would write packets
+	BufferFactory *buffer.Factory
+	// ingest ends when nothing is received for this long, 0 to wait indefinitely
+	IdleTimeout time.Duration
+	Logger      logger.Logger
+}
+
+type RTPIngestStreamStats struct {
+	PayloadType uint8
+	SSRC        uint32
+	TrackID     livekit.TrackID
+}
+
+type RTPIngestStats struct {
+	RemoteAddr string
+	Packets    uint64
+	Bytes      uint64
+	Dropped    uint64
+	Streams    []RTPIngestStreamStats
+}
+
+// RTPIngest publishes media received as plain RTP, or SRTP keyed out of band, on a UDP socket shared by RTP and
+// RTCP. Packets are written to buffers as the publisher's PeerConnection would, so tracks are forwarded like any other.
+// The first SSRC seen with the payload type of a track is published as it, unless the track is published on Start
+// with a known SSRC. The socket then only accepts
+// packets from the address that sent the first one which was authenticated, or came from the expected source.
+type RTPIngest struct {
+	params RTPIngestParams
+
+	// SRTP contexts hold state of a direction, RTCP sent back is encrypted with the same keys
+	decryptor *srtp.Context
+	encryptor *srtp.Context
+
+	lock      sync.RWMutex
+	remote    net.Addr
+	streams   map[uint32]*rtpIngestStream
+	published []bool
+	ignored   map[uint32]bool
+
+	rtcpCh chan []rtcp.Packet
+
+	packets atomic.Uint64
+	bytes   atomic.Uint64
+	dropped atomic.Uint64
+
+	closeOnce sync.Once
+	done      chan struct{}
+	onClose   func()
+}
+
+func NewRTPIngest(params RTPIngestParams) (*RTPIngest, error) {
+	if len(params.SRTPKey) == 0 && params.Source == nil {
+		return nil, ErrRTPIngestSourceRequired
+	}
+
+	r := &RTPIngest{
+		params:    params,
+		streams:   make(map[uint32]*rtpIngestStream),
+		published: make([]bool, len(params.Tracks)),
+		ignored:   make(map[uint32]bool),
+		rtcpCh:    make(chan []rtcp.Packet, 50),
+		done:      make(chan struct{}),
+	}
+
+	if len(params.SRTPKey) != 0 {
+		var err error
+		if r.decryptor, err = newSRTPContext(params.SRTPKey, params.SRTPProfile); err != nil {
+			return nil, err
+		}
+		if r.encryptor, err = newSRTPContext(params.SRTPKey, params.SRTPProfile); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+func newSRTPContext(key []byte, profile srtp.ProtectionProfile) (*srtp.Context, error) {
+	keyLen, saltLen := 16, 14
+	switch profile {
+	case srtp.ProtectionProfileAes128CmHmacSha1_80, srtp.ProtectionProfileAes128CmHmacSha1_32:
+	case srtp.ProtectionProfileAeadAes128Gcm:
+		saltLen = 12
+	default:
+		return nil, ErrUnsupportedSRTPProfile
+	}
+	if len(key) != keyLen+saltLen {
+		return nil, ErrInvalidSRTPKey
+	}
+	return srtp.CreateContext(key[:keyLen], key[keyLen:], profile)
+}
+
+func (r *RTPIngest) Start() {
+	for i, track := range r.params.Tracks {
+		if track.SSRC == 0 {
+			continue
+		}
+		r.published[i] = true
+		if stream := r.publish(track.SSRC, track); stream != nil {
+			r.lock.Lock()
+			r.streams[track.SSRC] = stream
+			r.lock.Unlock()
+		}
+	}
+
+	go r.readWorker()
+	go r.rtcpWorker()
+}
+
+func (r *RTPIngest) OnClose(f func()) {
+	r.lock.Lock()
+	r.onClose = f
+	r.lock.Unlock()
+}
+
+// Close stops receiving, tracks are unpublished as their buffers close
+func (r *RTPIngest) Close() {
+	r.closeOnce.Do(func() {
+		close(r.done)
+		_ = r.params.Conn.Close()
+
+		r.lock.Lock()
+		streams := make([]*rtpIngestStream, 0, len(r.streams))
+		for _, s := range r.streams {
+			streams = append(streams, s)
+		}
+		onClose := r.onClose
+		r.lock.Unlock()
+
+		for _, s := range streams {
+			_ = s.buffer.Close()
+			_ = s.rtcpReader.Close()
+		}
+		if onClose != nil {
+			onClose()
+		}
+	})
+}
+
+func (r *RTPIngest) Done() <-chan struct{} {
+	return r.done
+}
+
+func (r *RTPIngest) Stats() RTPIngestStats {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	stats := RTPIngestStats{
+		Packets: r.packets.Load(),
+		Bytes:   r.bytes.Load(),
+		Dropped: r.dropped.Load(),
+	}
+	if r.remote != nil {
+		stats.RemoteAddr = r.remote.String()
+	}
+	for ssrc, s := range r.streams {
+		stats.Streams = append(stats.Streams, RTPIngestStreamStats{
+			PayloadType: uint8(s.codec.PayloadType),
+			SSRC:        ssrc,
+			TrackID:     s.trackID,
+		})
+	}
+	return stats
+}
+
+func (r *RTPIngest) readWorker() {
+	defer r.Close()
+	defer Recover()
+
+	buf := make([]byte, rtpIngestMaxPacketSize)
+	decrypted := make([]byte, rtpIngestMaxPacketSize)
+	for {
+		if r.params.IdleTimeout > 0 {
+			_ = r.params.Conn.SetReadDeadline(time.Now().Add(r.params.IdleTimeout))
+		}
+		n, addr, err := r.params.Conn.ReadFrom(buf)
+		if err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				r.params.Logger.Infow("rtp ingest idle, closing")
+			}
+			return
+		}
+
+		pkt := buf[:n]
+		if isRTCPPacket(pkt) {
+			r.handleRTCP(pkt, decrypted, addr)
+		} else {
+			r.handleRTP(pkt, decrypted, addr)
+		}
+	}
+}
+
+// acceptFrom latches the address of the first packet from the expected source, or authenticated by SRTP, so that
+// others can't inject packets. RTCP is sent back to it.
+func (r *RTPIngest) acceptFrom(addr net.Addr) bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.remote != nil {
+		return r.remote.String() == addr.String()
+	}
+	if source := r.params.Source; source != nil {
+		udpAddr, ok := addr.(*net.UDPAddr)
+		if !ok || !udpAddr.IP.Equal(source.IP) || (source.Port != 0 && udpAddr.Port != source.Port) {
+			return false
+		}
+	}
+
+	r.params.Logger.Infow("rtp ingest receiving", "remote", addr.String())
+	r.remote = addr
+	return true
+}
+
+func (r *RTPIngest) handleRTP(pkt []byte, decrypted []byte, addr net.Addr) {
+	header := rtp.Header{}
+	if _, err := header.Unmarshal(pkt); err != nil {
+		r.dropped.Inc()
+		return
+	}
+	if r.decryptor != nil {
+		var err error
+		if pkt, err = r.decryptor.DecryptRTP(decrypted, pkt, &header); err != nil {
+			r.dropped.Inc()
+			return
+		}
+	}
+	if !r.acceptFrom(addr) {
+		r.dropped.Inc()
+		return
+	}
+
+	stream := r.getStream(header.SSRC, header.PayloadType)
+	if stream == nil {
+		r.dropped.Inc()
+		return
+	}
+	if _, err := stream.buffer.Write(pkt); err != nil {
+		r.dropped.Inc()
+		return
+	}
+	r.packets.Inc()
+	r.bytes.Add(uint64(len(pkt)))
+}
+
+func (r *RTPIngest) handleRTCP(pkt []byte, decrypted []byte, addr net.Addr) {
+	if r.decryptor != nil {
+		var err error
+		if pkt, err = r.decryptor.DecryptRTCP(decrypted, pkt, nil); err != nil {
+			r.dropped.Inc()
+			return
+		}
+	}
+	pkts, err := rtcp.Unmarshal(pkt)
+	if err != nil {
+		r.dropped.Inc()
+		return
+	}
+	if !r.acceptFrom(addr) {
+		r.dropped.Inc()
+		return
+	}
+
+	// sender reports carry timing needed to sync tracks, the track's RTCP reader handles them.
+	// Each is written on its own to its track, readers take any sender report they are given
+	for _, p := range pkts {
+		sr, ok := p.(*rtcp.SenderReport)
+		if !ok {
+			continue
+		}
+		r.lock.RLock()
+		stream := r.streams[sr.SSRC]
+		r.lock.RUnlock()
+		if stream == nil {
+			continue
+		}
+		b, err := sr.Marshal()
+		if err != nil {
+			continue
+		}
+		_, _ = stream.rtcpReader.Write(b)
+	}
+}
+
+// getStream returns the stream of an SSRC, publishing it as the first unpublished track of its payload type
+func (r *RTPIngest) getStream(ssrc uint32, payloadType uint8) *rtpIngestStream {
+	r.lock.RLock()
+	stream := r.streams[ssrc]
+	ignored := r.ignored[ssrc]
+	r.lock.RUnlock()
+	if stream != nil || ignored {
+		return stream
+	}
+
+	for i, track := range r.params.Tracks {
+		if uint8(track.Codec.PayloadType) != payloadType || r.published[i] {
+			continue
+		}
+		r.published[i] = true
+		stream = r.publish(ssrc, track)
+		break
+	}
+
+	r.lock.Lock()
+	if stream != nil {
+		r.streams[ssrc] = stream
+	} else {
+		r.params.Logger.Infow("ignoring rtp stream", "ssrc", ssrc, "payloadType", payloadType)
+		r.ignored[ssrc] = true
+	}
+	r.lock.Unlock()
+	return stream
+}
+
+func (r *RTPIngest) publish(ssrc uint32, track RTPIngestTrack) *rtpIngestStream {
+	if r.params.BufferFactory.GetBuffer(ssrc) != nil {
+		r.params.Logger.Warnw("ssrc is in use by another track", nil, "ssrc", ssrc)
+		return nil
+	}
+
+	s := &rtpIngestStream{
+		cid:              strconv.FormatUint(uint64(ssrc), 10),
+		ssrc:             ssrc,
+		codec:            track.Codec,
+		headerExtensions: track.HeaderExtensions,
+	}
+	if s.Kind() == webrtc.RTPCodecTypeVideo && len(s.codec.RTCPFeedback) == 0 {
+		// packets are buffered for retransmission and key frames are requested when subscribers need them
+		s.codec.RTCPFeedback = []webrtc.RTCPFeedback{
+			{Type: webrtc.TypeRTCPFBNACK},
+			{Type: webrtc.TypeRTCPFBNACK, Parameter: "pli"},
+		}
+	}
+	s.buffer = r.params.BufferFactory.GetOrNew(packetio.RTPBufferPacket, ssrc).(*buffer.Buffer)
+	s.rtcpReader = r.params.BufferFactory.GetOrNew(packetio.RTCPBufferPacket, ssrc).(*buffer.RTCPReader)
+
+	req := &livekit.AddTrackRequest{
+		Cid:    s.cid,
+		Name:   track.Name,
+		Type:   ToProtoTrackKind(s.Kind()),
+		Source: track.Source,
+		Muted:  track.Muted,
+		Width:  track.Width,
+		Height: track.Height,
+		Layers: track.Layers,
+		SimulcastCodecs: []*livekit.SimulcastCodec{
+			{Codec: s.codec.MimeType, Cid: s.cid},
+		},
+	}
+	mt, err := r.params.Publisher.AddExternalTrack(req, track.TrackID, s, s, r.rtcpCh)
+	if err != nil {
+		r.params.Logger.Warnw("could not publish rtp stream", err, "ssrc", ssrc, "name", track.Name)
+		_ = s.buffer.Close()
+		_ = s.rtcpReader.Close()
+		return nil
+	}
+	if mt != nil {
+		s.trackID = mt.ID()
+	}
+	return s
+}
+
+// rtcpWorker sends RTCP generated for published tracks, receiver reports, NACKs and PLIs, to the sender
+func (r *RTPIngest) rtcpWorker() {
+	defer Recover()
+
+	for {
+		select {
+		case <-r.done:
+			return
+		case pkts := <-r.rtcpCh:
+			if len(pkts) == 0 {
+				continue
+			}
+			if err := r.writeRTCP(pkts); err != nil {
+				r.params.Logger.Debugw("could not write RTCP", "error", err)
+			}
+		}
+	}
+}
+
+func (r *RTPIngest) writeRTCP(pkts []rtcp.Packet) error {
+	r.lock.RLock()
+	remote := r.remote
+	r.lock.RUnlock()
+	if remote == nil {
+		return nil
+	}
+
+	b, err := rtcp.Marshal(pkts)
+	if err != nil {
+		return err
+	}
+	if r.encryptor != nil {
+		if b, err = r.encryptor.EncryptRTCP(nil, b, nil); err != nil {
+			return err
+		}
+	}
+	_, err = r.params.Conn.WriteTo(b, remote)
+	return err
+}
+
+// RTCP packet types are in a range not used by RTP payload types, so both can share a port (RFC 5761)
+func isRTCPPacket(pkt []byte) bool {
+	return len(pkt) >= 2 && pkt[1] >= 192 && pkt[1] <= 223
+}
+
+// ---------------------------------------------------------------
+
+// rtpIngestStream is an SSRC received by RTPIngest, standing in for the webrtc.TrackRemote and webrtc.RTPReceiver
+// a PeerConnection would create
+type rtpIngestStream struct {
+	cid              string
+	ssrc             uint32
+	codec            webrtc.RTPCodecParameters
+	headerExtensions []webrtc.RTPHeaderExtensionParameter
+	trackID          livekit.TrackID
+	buffer           *buffer.Buffer
+	rtcpReader       *buffer.RTCPReader
+}
+
+func (s *rtpIngestStream) ID() string {
+	return s.cid
+}
+
+func (s *rtpIngestStream) StreamID() string {
+	return rtpIngestStreamID
+}
+
+func (s *rtpIngestStream) RID() string {
+	return ""
+}
+
+func (s *rtpIngestStream) Msid() string {
+	return rtpIngestStreamID + " " + s.cid
+}
+
+func (s *rtpIngestStream) SSRC() webrtc.SSRC {
+	return webrtc.SSRC(s.ssrc)
+}
+
+func (s *rtpIngestStream) Kind() webrtc.RTPCodecType {
+	if strings.HasPrefix(strings.ToLower(s.codec.MimeType), "video/") {
+		return webrtc.RTPCodecTypeVideo
+	}
+	return webrtc.RTPCodecTypeAudio
+}
+
+func (s *rtpIngestStream) Codec() webrtc.RTPCodecParameters {
+	return s.codec
+}
+
+func (s *rtpIngestStream) GetParameters() webrtc.RTPParameters {
+	return webrtc.RTPParameters{
+		HeaderExtensions: s.headerExtensions,
+		Codecs:           []webrtc.RTPCodecParameters{s.codec},
+	}
+}
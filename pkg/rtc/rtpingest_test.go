@@ -0,0 +1,260 @@
+package rtc
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
+	"github.com/pion/srtp/v2"
+	"github.com/pion/webrtc/v3"
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+	"github.com/livekit/livekit-server/pkg/rtc/types/typesfakes"
+	"github.com/livekit/livekit-server/pkg/sfu"
+	"github.com/livekit/livekit-server/pkg/sfu/buffer"
+)
+
+type testExternalPublisher struct {
+	requests chan *livekit.AddTrackRequest
+	rtcpCh   chan chan []rtcp.Packet
+}
+
+func (p *testExternalPublisher) AddExternalTrack(
+	req *livekit.AddTrackRequest,
+	trackID livekit.TrackID,
+	_ sfu.RTPReceiver,
+	_ sfu.TrackRemote,
+	rtcpCh chan []rtcp.Packet,
+) (types.MediaTrack, error) {
+	p.requests <- req
+	p.rtcpCh <- rtcpCh
+	mt := &typesfakes.FakeMediaTrack{}
+	if trackID == "" {
+		trackID = "TR_" + livekit.TrackID(req.Cid)
+	}
+	mt.IDReturns(trackID)
+	return mt, nil
+}
+
+func TestRTPIngest(t *testing.T) {
+	key := make([]byte, 30)
+	for i := range key {
+		key[i] = byte(i)
+	}
+
+	camera := RTPIngestTrack{
+		Name:   "camera",
+		Source: livekit.TrackSource_CAMERA,
+		Codec: webrtc.RTPCodecParameters{
+			RTPCodecCapability: webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeVP8, ClockRate: 90000},
+			PayloadType:        96,
+		},
+	}
+
+	newIngest := func(t *testing.T, track RTPIngestTrack, srtpKey []byte, source *net.UDPAddr) (*RTPIngest, *testExternalPublisher, *buffer.Factory, net.Conn) {
+		conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+		require.NoError(t, err)
+
+		publisher := &testExternalPublisher{
+			requests: make(chan *livekit.AddTrackRequest, 5),
+			rtcpCh:   make(chan chan []rtcp.Packet, 5),
+		}
+		bufferFactory := buffer.NewFactoryOfBufferFactory(500).CreateBufferFactory()
+		ingest, err := NewRTPIngest(RTPIngestParams{
+			Conn:          conn,
+			Tracks:        []RTPIngestTrack{track},
+			SRTPKey:       srtpKey,
+			SRTPProfile:   srtp.ProtectionProfileAes128CmHmacSha1_80,
+			Source:        source,
+			Publisher:     publisher,
+			BufferFactory: bufferFactory,
+			Logger:        logger.GetDefaultLogger(),
+		})
+		require.NoError(t, err)
+		ingest.Start()
+		t.Cleanup(ingest.Close)
+
+		sender, err := net.Dial("udp", conn.LocalAddr().String())
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = sender.Close() })
+		return ingest, publisher, bufferFactory, sender
+	}
+
+	marshalRTP := func(t *testing.T, payloadType uint8, ssrc uint32) []byte {
+		pkt := &rtp.Packet{
+			Header: rtp.Header{
+				Version:        2,
+				PayloadType:    payloadType,
+				SequenceNumber: 1,
+				Timestamp:      3000,
+				SSRC:           ssrc,
+			},
+			Payload: []byte{0x10, 0x02, 0x03},
+		}
+		b, err := pkt.Marshal()
+		require.NoError(t, err)
+		return b
+	}
+
+	readBuffer := func(t *testing.T, bufferFactory *buffer.Factory, ssrc uint32) []byte {
+		buff := bufferFactory.GetBuffer(ssrc)
+		require.NotNil(t, buff)
+		b := make([]byte, 1500)
+		n, err := buff.Read(b)
+		require.NoError(t, err)
+		return b[:n]
+	}
+
+	t.Run("publishes streams of declared payload types", func(t *testing.T) {
+		ingest, publisher, bufferFactory, sender := newIngest(t, camera, nil, &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+
+		// unknown payload type is ignored
+		_, err := sender.Write(marshalRTP(t, 100, 5678))
+		require.NoError(t, err)
+		pkt := marshalRTP(t, 96, 1234)
+		_, err = sender.Write(pkt)
+		require.NoError(t, err)
+
+		select {
+		case req := <-publisher.requests:
+			require.Equal(t, "1234", req.Cid)
+			require.Equal(t, "camera", req.Name)
+			require.Equal(t, livekit.TrackType_VIDEO, req.Type)
+			require.Equal(t, livekit.TrackSource_CAMERA, req.Source)
+		case <-time.After(time.Second):
+			require.Fail(t, "track not published")
+		}
+		require.Equal(t, pkt, readBuffer(t, bufferFactory, 1234))
+		require.Nil(t, bufferFactory.GetBuffer(5678))
+
+		stats := ingest.Stats()
+		require.Equal(t, uint64(1), stats.Packets)
+		require.Equal(t, uint64(1), stats.Dropped)
+		require.Equal(t, []RTPIngestStreamStats{{PayloadType: 96, SSRC: 1234, TrackID: "TR_1234"}}, stats.Streams)
+	})
+
+	t.Run("publishes tracks with an SSRC on start", func(t *testing.T) {
+		relayed := camera
+		relayed.TrackID = "TR_origin"
+		relayed.SSRC = 4321
+		ingest, publisher, bufferFactory, sender := newIngest(t, relayed, key, nil)
+
+		select {
+		case req := <-publisher.requests:
+			require.Equal(t, "4321", req.Cid)
+		default:
+			require.Fail(t, "track not published")
+		}
+		require.Equal(t, []RTPIngestStreamStats{{PayloadType: 96, SSRC: 4321, TrackID: "TR_origin"}}, ingest.Stats().Streams)
+
+		// other SSRCs are not published as the track
+		local, err := srtp.CreateContext(key[:16], key[16:], srtp.ProtectionProfileAes128CmHmacSha1_80)
+		require.NoError(t, err)
+		for _, ssrc := range []uint32{1234, 4321} {
+			encrypted, err := local.EncryptRTP(nil, marshalRTP(t, 96, ssrc), nil)
+			require.NoError(t, err)
+			_, err = sender.Write(encrypted)
+			require.NoError(t, err)
+		}
+		require.Equal(t, marshalRTP(t, 96, 4321), readBuffer(t, bufferFactory, 4321))
+		require.Nil(t, bufferFactory.GetBuffer(1234))
+		require.Equal(t, uint64(1), ingest.Stats().Dropped)
+	})
+
+	t.Run("decrypts SRTP and encrypts feedback", func(t *testing.T) {
+		_, publisher, bufferFactory, sender := newIngest(t, camera, key, nil)
+		local, err := srtp.CreateContext(key[:16], key[16:], srtp.ProtectionProfileAes128CmHmacSha1_80)
+		require.NoError(t, err)
+
+		pkt := marshalRTP(t, 96, 1234)
+		encrypted, err := local.EncryptRTP(nil, pkt, nil)
+		require.NoError(t, err)
+		_, err = sender.Write(encrypted)
+		require.NoError(t, err)
+
+		var rtcpCh chan []rtcp.Packet
+		select {
+		case rtcpCh = <-publisher.rtcpCh:
+		case <-time.After(time.Second):
+			require.Fail(t, "track not published")
+		}
+		require.Equal(t, pkt, readBuffer(t, bufferFactory, 1234))
+
+		// key frame requests of subscribers are sent back to the encoder
+		rtcpCh <- []rtcp.Packet{&rtcp.PictureLossIndication{MediaSSRC: 1234}}
+		b := make([]byte, 1500)
+		require.NoError(t, sender.SetReadDeadline(time.Now().Add(time.Second)))
+		n, err := sender.Read(b)
+		require.NoError(t, err)
+		decrypted, err := local.DecryptRTCP(nil, b[:n], nil)
+		require.NoError(t, err)
+		pkts, err := rtcp.Unmarshal(decrypted)
+		require.NoError(t, err)
+		require.Equal(t, uint32(1234), pkts[0].(*rtcp.PictureLossIndication).MediaSSRC)
+	})
+
+	t.Run("only accepts plain RTP from the source", func(t *testing.T) {
+		ingest, publisher, _, sender := newIngest(t, camera, nil, &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1})
+
+		_, err := sender.Write(marshalRTP(t, 96, 1234))
+		require.NoError(t, err)
+		require.Eventually(t, func() bool {
+			return ingest.Stats().Dropped == 1
+		}, time.Second, 10*time.Millisecond)
+		require.Empty(t, publisher.requests)
+		require.Empty(t, ingest.Stats().RemoteAddr)
+
+		_, err = NewRTPIngest(RTPIngestParams{})
+		require.ErrorIs(t, err, ErrRTPIngestSourceRequired)
+	})
+
+	t.Run("writes each sender report to its track", func(t *testing.T) {
+		_, publisher, bufferFactory, sender := newIngest(t, camera, nil, &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+
+		_, err := sender.Write(marshalRTP(t, 96, 1234))
+		require.NoError(t, err)
+		select {
+		case <-publisher.requests:
+		case <-time.After(time.Second):
+			require.Fail(t, "track not published")
+		}
+
+		received := make(chan []byte, 5)
+		bufferFactory.GetRTCPReader(1234).OnPacket(func(b []byte) {
+			received <- append([]byte{}, b...)
+		})
+		sr := &rtcp.SenderReport{SSRC: 1234, NTPTime: 1 << 32, RTPTime: 3000}
+		compound, err := rtcp.Marshal([]rtcp.Packet{
+			sr,
+			&rtcp.SenderReport{SSRC: 5678, NTPTime: 2 << 32, RTPTime: 6000},
+			&rtcp.SourceDescription{Chunks: []rtcp.SourceDescriptionChunk{{Source: 1234}}},
+		})
+		require.NoError(t, err)
+		_, err = sender.Write(compound)
+		require.NoError(t, err)
+
+		select {
+		case b := <-received:
+			expected, err := sr.Marshal()
+			require.NoError(t, err)
+			require.Equal(t, expected, b)
+		case <-time.After(time.Second):
+			require.Fail(t, "sender report not received")
+		}
+		require.Never(t, func() bool { return len(received) != 0 }, 100*time.Millisecond, 10*time.Millisecond)
+	})
+
+	t.Run("rejects keys of the wrong length", func(t *testing.T) {
+		_, err := NewRTPIngest(RTPIngestParams{
+			SRTPKey:     key[:16],
+			SRTPProfile: srtp.ProtectionProfileAes128CmHmacSha1_80,
+		})
+		require.ErrorIs(t, err, ErrInvalidSRTPKey)
+	})
+}
@@ -1,6 +1,8 @@
 package rtc
 
 import (
+	"fmt"
+
 	"github.com/livekit/protocol/livekit"
 	"github.com/livekit/protocol/logger"
 
@@ -8,6 +10,17 @@ import (
 )
 
 func HandleParticipantSignal(room types.Room, participant types.LocalParticipant, req *livekit.SignalRequest, pLogger logger.Logger) error {
+	// leaving and negotiating are never limited, dropping offers, answers or candidates would break the connection
+	// of the participant without telling it
+	switch req.Message.(type) {
+	case *livekit.SignalRequest_Leave, *livekit.SignalRequest_Offer, *livekit.SignalRequest_Answer, *livekit.SignalRequest_Trickle:
+	default:
+		if !room.AllowSignal(participant.ID()) {
+			pLogger.Debugw("signal message rate limited, dropping", "type", fmt.Sprintf("%T", req.Message))
+			return nil
+		}
+	}
+
 	switch msg := req.Message.(type) {
 	case *livekit.SignalRequest_Offer:
 		participant.HandleOffer(FromProtoSessionDescription(msg.Offer))
@@ -22,6 +35,12 @@ func HandleParticipantSignal(room types.Room, participant types.LocalParticipant
 		participant.AddICECandidate(candidateInit, msg.Trickle.Target)
 	case *livekit.SignalRequest_AddTrack:
 		pLogger.Debugw("add track request", "trackID", msg.AddTrack.Cid)
+		if room.TracksQuotaExceeded() {
+			// the client is told why it's disconnected, rather than left waiting for the track to be published
+			pLogger.Infow("tenant has exceeded its max tracks, removing participant", "trackID", msg.AddTrack.Cid)
+			room.RemoveParticipant(participant.Identity(), types.ParticipantCloseReasonTenantQuotaExceeded)
+			return ErrMaxTenantTracksExceeded
+		}
 		participant.AddTrack(msg.AddTrack)
 	case *livekit.SignalRequest_Mute:
 		participant.SetTrackMuted(livekit.TrackID(msg.Mute.Sid), msg.Mute.Muted, false)
@@ -0,0 +1,84 @@
+package rtc
+
+import (
+	"sync"
+	"time"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/utils"
+)
+
+const (
+	signalLimitedByParticipant = "participant"
+	signalLimitedByRoom        = "room"
+)
+
+// signalLimiter limits rates of signal messages of each participant of a room, and of all of them
+type signalLimiter struct {
+	config config.SignalRateLimitConfig
+	room   *utils.TokenBucket
+
+	lock         sync.Mutex
+	participants map[livekit.ParticipantID]*utils.TokenBucket
+}
+
+// newSignalLimiter returns nil when signal messages are not limited
+func newSignalLimiter(conf config.SignalRateLimitConfig) *signalLimiter {
+	if !conf.Participant.Enabled() && !conf.Room.Enabled() {
+		return nil
+	}
+
+	s := &signalLimiter{
+		config:       conf,
+		participants: make(map[livekit.ParticipantID]*utils.TokenBucket),
+	}
+	if conf.Room.Enabled() {
+		s.room = utils.NewTokenBucket(float64(conf.Room.Rate), conf.Room.GetBurst())
+	}
+	return s
+}
+
+// allow takes a token for a message of participant, returning what it's limited by when there are none. Messages
+// limited by the room don't take tokens of the participant, nor the other way around
+func (s *signalLimiter) allow(participantID livekit.ParticipantID) string {
+	if s == nil {
+		return ""
+	}
+
+	now := time.Now()
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	var bucket *utils.TokenBucket
+	if s.config.Participant.Enabled() {
+		bucket = s.participants[participantID]
+		if bucket == nil {
+			bucket = utils.NewTokenBucket(float64(s.config.Participant.Rate), s.config.Participant.GetBurst())
+			s.participants[participantID] = bucket
+		}
+		if !bucket.AvailableAt(now, 1) {
+			return signalLimitedByParticipant
+		}
+	}
+	if s.room != nil && !s.room.AllowAt(now, 1) {
+		return signalLimitedByRoom
+	}
+	if bucket != nil {
+		bucket.AllowAt(now, 1)
+	}
+	return ""
+}
+
+func (s *signalLimiter) removeParticipant(participantID livekit.ParticipantID) {
+	if s == nil {
+		return
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	delete(s.participants, participantID)
+}
@@ -32,6 +32,7 @@ type SubscribedTrack struct {
 	params   SubscribedTrackParams
 	subMuted atomic.Bool
 	pubMuted atomic.Bool
+	paused   atomic.Bool
 	settings atomic.Value // *livekit.UpdateTrackSettings
 
 	onBind atomic.Value // func()
@@ -117,6 +118,13 @@ func (t *SubscribedTrack) SetPublisherMuted(muted bool) {
 	t.updateDownTrackMute()
 }
 
+// SetPaused stops forwarding without unsubscribing, when the server decides the track isn't needed
+func (t *SubscribedTrack) SetPaused(paused bool) {
+	if t.paused.Swap(paused) != paused {
+		t.updateDownTrackMute()
+	}
+}
+
 func (t *SubscribedTrack) UpdateSubscriberSettings(settings *livekit.UpdateTrackSettings) {
 	prevDisabled := t.subMuted.Swap(settings.Disabled)
 	t.settings.Store(settings)
@@ -153,6 +161,6 @@ func (t *SubscribedTrack) UpdateVideoLayer() {
 }
 
 func (t *SubscribedTrack) updateDownTrackMute() {
-	muted := t.subMuted.Load() || t.pubMuted.Load()
+	muted := t.subMuted.Load() || t.pubMuted.Load() || t.paused.Load()
 	t.DownTrack().Mute(muted)
 }
@@ -152,7 +152,9 @@ type PCTransport struct {
 	reliableDCOpened bool
 	lossyDC          *webrtc.DataChannel
 	lossyDCOpened    bool
-	onDataPacket     func(kind livekit.DataPacket_Kind, data []byte)
+	// remote description has no data channels, as with WHIP and WHEP clients
+	mediaOnly    bool
+	onDataPacket func(kind livekit.DataPacket_Kind, data []byte)
 
 	iceConnectedAt time.Time
 	connectedAt    time.Time
@@ -217,6 +219,8 @@ type TransportParams struct {
 	ClientInfo              ClientInfo
 	IsOfferer               bool
 	IsSendSide              bool
+	// fully established without data channels, when the remote description has none
+	AllowMediaOnly bool
 }
 
 func newPeerConnection(params TransportParams, onBandwidthEstimator func(estimator cc.BandwidthEstimator)) (*webrtc.PeerConnection, *webrtc.MediaEngine, error) {
@@ -536,7 +540,7 @@ func (t *PCTransport) onDataChannel(dc *webrtc.DataChannel) {
 
 func (t *PCTransport) maybeNotifyFullyEstablished() {
 	t.lock.RLock()
-	fullyEstablished := t.reliableDCOpened && t.lossyDCOpened && !t.connectedAt.IsZero()
+	fullyEstablished := (t.mediaOnly || (t.reliableDCOpened && t.lossyDCOpened)) && !t.connectedAt.IsZero()
 	t.lock.RUnlock()
 
 	if fullyEstablished {
@@ -942,24 +946,40 @@ func (t *PCTransport) OnStreamStateChange(f func(update *sfu.StreamStateUpdate)
 	t.streamAllocator.OnStreamStateChange(f)
 }
 
-func (t *PCTransport) AddTrackToStreamAllocator(subTrack types.SubscribedTrack) {
+func (t *PCTransport) SetMaxChannelCapacity(maxChannelCapacity int64) {
 	if t.streamAllocator == nil {
 		return
 	}
 
-	t.streamAllocator.AddTrack(subTrack.DownTrack(), sfu.AddTrackParams{
+	t.streamAllocator.SetMaxChannelCapacity(maxChannelCapacity)
+}
+
+func (t *PCTransport) AddTrackToStreamAllocator(subTrack types.SubscribedTrack) {
+	t.AddDownTrackToStreamAllocator(subTrack.DownTrack(), sfu.AddTrackParams{
 		Source:      subTrack.MediaTrack().Source(),
 		IsSimulcast: subTrack.MediaTrack().IsSimulcast(),
 		PublisherID: subTrack.MediaTrack().PublisherID(),
 	})
 }
 
+func (t *PCTransport) AddDownTrackToStreamAllocator(downTrack *sfu.DownTrack, params sfu.AddTrackParams) {
+	if t.streamAllocator == nil {
+		return
+	}
+
+	t.streamAllocator.AddTrack(downTrack, params)
+}
+
 func (t *PCTransport) RemoveTrackFromStreamAllocator(subTrack types.SubscribedTrack) {
+	t.RemoveDownTrackFromStreamAllocator(subTrack.DownTrack())
+}
+
+func (t *PCTransport) RemoveDownTrackFromStreamAllocator(downTrack *sfu.DownTrack) {
 	if t.streamAllocator == nil {
 		return
 	}
 
-	t.streamAllocator.RemoveTrack(subTrack.DownTrack())
+	t.streamAllocator.RemoveTrack(downTrack)
 }
 
 func (t *PCTransport) GetICEConnectionType() types.ICEConnectionType {
@@ -1589,6 +1609,19 @@ func (t *PCTransport) setRemoteDescription(sd webrtc.SessionDescription) error {
 		t.lock.Unlock()
 	}
 
+	if parsed, err := sd.Unmarshal(); err == nil && t.params.AllowMediaOnly {
+		mediaOnly := true
+		for _, m := range parsed.MediaDescriptions {
+			if m.MediaName.Media == "application" {
+				mediaOnly = false
+				break
+			}
+		}
+		t.lock.Lock()
+		t.mediaOnly = mediaOnly
+		t.lock.Unlock()
+	}
+
 	for _, c := range t.pendingRemoteCandidates {
 		if err := t.pc.AddICECandidate(*c); err != nil {
 			return errors.Wrap(err, "add ice candidate failed")
@@ -44,7 +44,11 @@ type TransportManagerParams struct {
 	Migration               bool
 	AllowTCPFallback        bool
 	TURNSEnabled            bool
-	Logger                  logger.Logger
+	// subscriber connection is offered by the client, once, and can't be renegotiated
+	ClientOffersSubscriber bool
+	// transports are established without data channels when the client doesn't offer any
+	AllowMediaOnly bool
+	Logger         logger.Logger
 }
 
 type TransportManager struct {
@@ -109,6 +113,7 @@ func NewTransportManager(params TransportManagerParams) (*TransportManager, erro
 		Logger:                  LoggerWithPCTarget(params.Logger, livekit.SignalTarget_PUBLISHER),
 		SimTracks:               params.SimTracks,
 		ClientInfo:              params.ClientInfo,
+		AllowMediaOnly:          params.AllowMediaOnly,
 	})
 	if err != nil {
 		return nil, err
@@ -140,8 +145,9 @@ func NewTransportManager(params TransportManagerParams) (*TransportManager, erro
 		EnabledCodecs:           enabledCodecs,
 		Logger:                  LoggerWithPCTarget(params.Logger, livekit.SignalTarget_SUBSCRIBER),
 		ClientInfo:              params.ClientInfo,
-		IsOfferer:               true,
+		IsOfferer:               !params.ClientOffersSubscriber,
 		IsSendSide:              true,
+		AllowMediaOnly:          params.AllowMediaOnly,
 	})
 	if err != nil {
 		return nil, err
@@ -226,6 +232,10 @@ func (t *TransportManager) OnSubscriberOffer(f func(offer webrtc.SessionDescript
 	t.subscriber.OnOffer(f)
 }
 
+func (t *TransportManager) OnSubscriberAnswer(f func(answer webrtc.SessionDescription) error) {
+	t.subscriber.OnAnswer(f)
+}
+
 func (t *TransportManager) OnSubscriberInitialConnected(f func()) {
 	t.onSubscriberInitialConnected = f
 }
@@ -234,6 +244,10 @@ func (t *TransportManager) OnSubscriberStreamStateChange(f func(update *sfu.Stre
 	t.subscriber.OnStreamStateChange(f)
 }
 
+func (t *TransportManager) SetSubscriberMaxChannelCapacity(maxChannelCapacity int64) {
+	t.subscriber.SetMaxChannelCapacity(maxChannelCapacity)
+}
+
 func (t *TransportManager) HasSubscriberEverConnected() bool {
 	return t.subscriber.HasEverConnected()
 }
@@ -279,6 +293,14 @@ func (t *TransportManager) RemoveSubscribedTrack(subTrack types.SubscribedTrack)
 	t.subscriber.RemoveTrackFromStreamAllocator(subTrack)
 }
 
+func (t *TransportManager) AddDownTrackToStreamAllocator(downTrack *sfu.DownTrack, params sfu.AddTrackParams) {
+	t.subscriber.AddDownTrackToStreamAllocator(downTrack, params)
+}
+
+func (t *TransportManager) RemoveDownTrackFromStreamAllocator(downTrack *sfu.DownTrack) {
+	t.subscriber.RemoveDownTrackFromStreamAllocator(downTrack)
+}
+
 func (t *TransportManager) OnDataMessage(f func(kind livekit.DataPacket_Kind, data []byte)) {
 	// upstream data is always comes in via publisher peer connection irrespective of which is primary
 	t.publisher.OnDataPacket(f)
@@ -409,6 +431,10 @@ func (t *TransportManager) ProcessPendingPublisherOffer() {
 	}
 }
 
+func (t *TransportManager) HandleSubscriberOffer(offer webrtc.SessionDescription) {
+	t.subscriber.HandleRemoteDescription(offer)
+}
+
 func (t *TransportManager) HandleAnswer(answer webrtc.SessionDescription) {
 	t.subscriber.HandleRemoteDescription(answer)
 }
@@ -427,6 +453,9 @@ func (t *TransportManager) AddICECandidate(candidate webrtc.ICECandidateInit, ta
 }
 
 func (t *TransportManager) NegotiateSubscriber(force bool) {
+	if t.params.ClientOffersSubscriber {
+		return
+	}
 	t.subscriber.Negotiate(force)
 }
 
@@ -435,6 +464,9 @@ func (t *TransportManager) ICERestart(iceConfig *types.IceConfig) {
 		t.SetICEConfig(*iceConfig)
 	}
 
+	if t.params.ClientOffersSubscriber {
+		return
+	}
 	t.subscriber.ICERestart()
 }
 
@@ -83,6 +83,9 @@ const (
 	ParticipantCloseReasonNegotiateFailed
 	ParticipantCloseReasonMigrationRequested
 	ParticipantCloseReasonOvercommitted
+	ParticipantCloseReasonRoomDurationExceeded
+	ParticipantCloseReasonSessionDurationExceeded
+	ParticipantCloseReasonTenantQuotaExceeded
 )
 
 func (p ParticipantCloseReason) String() string {
@@ -125,6 +128,12 @@ func (p ParticipantCloseReason) String() string {
 		return "OVERCOMMITTED"
 	case ParticipantCloseReasonMigrationRequested:
 		return "MIGRATION_REQUESTED"
+	case ParticipantCloseReasonRoomDurationExceeded:
+		return "ROOM_DURATION_EXCEEDED"
+	case ParticipantCloseReasonSessionDurationExceeded:
+		return "SESSION_DURATION_EXCEEDED"
+	case ParticipantCloseReasonTenantQuotaExceeded:
+		return "TENANT_QUOTA_EXCEEDED"
 	default:
 		return fmt.Sprintf("%d", int(p))
 	}
@@ -143,9 +152,10 @@ func (p ParticipantCloseReason) ToDisconnectReason() livekit.DisconnectReason {
 		return livekit.DisconnectReason_STATE_MISMATCH
 	case ParticipantCloseReasonDuplicateIdentity, ParticipantCloseReasonMigrationComplete, ParticipantCloseReasonStale:
 		return livekit.DisconnectReason_DUPLICATE_IDENTITY
-	case ParticipantCloseReasonServiceRequestRemoveParticipant:
+	case ParticipantCloseReasonServiceRequestRemoveParticipant, ParticipantCloseReasonSessionDurationExceeded,
+		ParticipantCloseReasonTenantQuotaExceeded:
 		return livekit.DisconnectReason_PARTICIPANT_REMOVED
-	case ParticipantCloseReasonServiceRequestDeleteRoom:
+	case ParticipantCloseReasonServiceRequestDeleteRoom, ParticipantCloseReasonRoomDurationExceeded:
 		return livekit.DisconnectReason_ROOM_DELETED
 	case ParticipantCloseReasonSimulateMigration:
 		return livekit.DisconnectReason_DUPLICATE_IDENTITY
@@ -189,6 +199,7 @@ type Participant interface {
 	Close(sendLeave bool, reason ParticipantCloseReason) error
 
 	SubscriptionPermission() (*livekit.SubscriptionPermission, *livekit.TimedVersion)
+	HasPermission(trackID livekit.TrackID, subscriberIdentity livekit.ParticipantIdentity) bool
 
 	// updates from remotes
 	UpdateSubscriptionPermission(
@@ -250,6 +261,7 @@ type LocalParticipant interface {
 
 	// permissions
 	ClaimGrants() *auth.ClaimGrants
+	DataTopics() []string
 	SetPermission(permission *livekit.ParticipantPermission) bool
 	CanPublish() bool
 	CanSubscribe() bool
@@ -274,6 +286,8 @@ type LocalParticipant interface {
 	UpdateSubscribedTrackSettings(trackID livekit.TrackID, settings *livekit.UpdateTrackSettings) error
 	GetSubscribedTracks() []SubscribedTrack
 	VerifySubscribeParticipantInfo(pID livekit.ParticipantID, version uint32)
+	NumVideoSlots() int
+	UpdateVideoSlots(speakers []MediaTrack, others []MediaTrack)
 
 	// returns list of participant identities that the current participant is subscribed to
 	GetSubscribedParticipants() []livekit.ParticipantID
@@ -349,6 +363,8 @@ type Room interface {
 	SimulateScenario(participant LocalParticipant, scenario *livekit.SimulateScenario) error
 	SetParticipantPermission(participant LocalParticipant, permission *livekit.ParticipantPermission) error
 	UpdateVideoLayers(participant Participant, updateVideoLayers *livekit.UpdateVideoLayers) error
+	TracksQuotaExceeded() bool
+	AllowSignal(participantID livekit.ParticipantID) bool
 }
 
 // MediaTrack represents a media track
@@ -429,6 +445,7 @@ type SubscribedTrack interface {
 	MediaTrack() MediaTrack
 	IsMuted() bool
 	SetPublisherMuted(muted bool)
+	SetPaused(paused bool)
 	UpdateSubscriberSettings(settings *livekit.UpdateTrackSettings)
 	// selects appropriate video layer according to subscriber preferences
 	UpdateVideoLayer()
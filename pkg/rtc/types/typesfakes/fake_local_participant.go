@@ -156,6 +156,16 @@ type FakeLocalParticipant struct {
 	connectedAtReturnsOnCall map[int]struct {
 		result1 time.Time
 	}
+	DataTopicsStub        func() []string
+	dataTopicsMutex       sync.RWMutex
+	dataTopicsArgsForCall []struct {
+	}
+	dataTopicsReturns struct {
+		result1 []string
+	}
+	dataTopicsReturnsOnCall map[int]struct {
+		result1 []string
+	}
 	DebugInfoStub        func() map[string]interface{}
 	debugInfoMutex       sync.RWMutex
 	debugInfoArgsForCall []struct {
@@ -319,6 +329,18 @@ type FakeLocalParticipant struct {
 	handleOfferArgsForCall []struct {
 		arg1 webrtc.SessionDescription
 	}
+	HasPermissionStub        func(livekit.TrackID, livekit.ParticipantIdentity) bool
+	hasPermissionMutex       sync.RWMutex
+	hasPermissionArgsForCall []struct {
+		arg1 livekit.TrackID
+		arg2 livekit.ParticipantIdentity
+	}
+	hasPermissionReturns struct {
+		result1 bool
+	}
+	hasPermissionReturnsOnCall map[int]struct {
+		result1 bool
+	}
 	HiddenStub        func() bool
 	hiddenMutex       sync.RWMutex
 	hiddenArgsForCall []struct {
@@ -422,6 +444,16 @@ type FakeLocalParticipant struct {
 	negotiateArgsForCall []struct {
 		arg1 bool
 	}
+	NumVideoSlotsStub        func() int
+	numVideoSlotsMutex       sync.RWMutex
+	numVideoSlotsArgsForCall []struct {
+	}
+	numVideoSlotsReturns struct {
+		result1 int
+	}
+	numVideoSlotsReturnsOnCall map[int]struct {
+		result1 int
+	}
 	OnClaimsChangedStub        func(func(types.LocalParticipant))
 	onClaimsChangedMutex       sync.RWMutex
 	onClaimsChangedArgsForCall []struct {
@@ -767,6 +799,12 @@ type FakeLocalParticipant struct {
 	updateVideoLayersReturnsOnCall map[int]struct {
 		result1 error
 	}
+	UpdateVideoSlotsStub        func([]types.MediaTrack, []types.MediaTrack)
+	updateVideoSlotsMutex       sync.RWMutex
+	updateVideoSlotsArgsForCall []struct {
+		arg1 []types.MediaTrack
+		arg2 []types.MediaTrack
+	}
 	VerifySubscribeParticipantInfoStub        func(livekit.ParticipantID, uint32)
 	verifySubscribeParticipantInfoMutex       sync.RWMutex
 	verifySubscribeParticipantInfoArgsForCall []struct {
@@ -1492,6 +1530,59 @@ func (fake *FakeLocalParticipant) ConnectedAtReturnsOnCall(i int, result1 time.T
 	}{result1}
 }
 
+func (fake *FakeLocalParticipant) DataTopics() []string {
+	fake.dataTopicsMutex.Lock()
+	ret, specificReturn := fake.dataTopicsReturnsOnCall[len(fake.dataTopicsArgsForCall)]
+	fake.dataTopicsArgsForCall = append(fake.dataTopicsArgsForCall, struct {
+	}{})
+	stub := fake.DataTopicsStub
+	fakeReturns := fake.dataTopicsReturns
+	fake.recordInvocation("DataTopics", []interface{}{})
+	fake.dataTopicsMutex.Unlock()
+	if stub != nil {
+		return stub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeLocalParticipant) DataTopicsCallCount() int {
+	fake.dataTopicsMutex.RLock()
+	defer fake.dataTopicsMutex.RUnlock()
+	return len(fake.dataTopicsArgsForCall)
+}
+
+func (fake *FakeLocalParticipant) DataTopicsCalls(stub func() []string) {
+	fake.dataTopicsMutex.Lock()
+	defer fake.dataTopicsMutex.Unlock()
+	fake.DataTopicsStub = stub
+}
+
+func (fake *FakeLocalParticipant) DataTopicsReturns(result1 []string) {
+	fake.dataTopicsMutex.Lock()
+	defer fake.dataTopicsMutex.Unlock()
+	fake.DataTopicsStub = nil
+	fake.dataTopicsReturns = struct {
+		result1 []string
+	}{result1}
+}
+
+func (fake *FakeLocalParticipant) DataTopicsReturnsOnCall(i int, result1 []string) {
+	fake.dataTopicsMutex.Lock()
+	defer fake.dataTopicsMutex.Unlock()
+	fake.DataTopicsStub = nil
+	if fake.dataTopicsReturnsOnCall == nil {
+		fake.dataTopicsReturnsOnCall = make(map[int]struct {
+			result1 []string
+		})
+	}
+	fake.dataTopicsReturnsOnCall[i] = struct {
+		result1 []string
+	}{result1}
+}
+
 func (fake *FakeLocalParticipant) DebugInfo() map[string]interface{} {
 	fake.debugInfoMutex.Lock()
 	ret, specificReturn := fake.debugInfoReturnsOnCall[len(fake.debugInfoArgsForCall)]
@@ -2341,6 +2432,68 @@ func (fake *FakeLocalParticipant) HandleOfferArgsForCall(i int) webrtc.SessionDe
 	return argsForCall.arg1
 }
 
+func (fake *FakeLocalParticipant) HasPermission(arg1 livekit.TrackID, arg2 livekit.ParticipantIdentity) bool {
+	fake.hasPermissionMutex.Lock()
+	ret, specificReturn := fake.hasPermissionReturnsOnCall[len(fake.hasPermissionArgsForCall)]
+	fake.hasPermissionArgsForCall = append(fake.hasPermissionArgsForCall, struct {
+		arg1 livekit.TrackID
+		arg2 livekit.ParticipantIdentity
+	}{arg1, arg2})
+	stub := fake.HasPermissionStub
+	fakeReturns := fake.hasPermissionReturns
+	fake.recordInvocation("HasPermission", []interface{}{arg1, arg2})
+	fake.hasPermissionMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeLocalParticipant) HasPermissionCallCount() int {
+	fake.hasPermissionMutex.RLock()
+	defer fake.hasPermissionMutex.RUnlock()
+	return len(fake.hasPermissionArgsForCall)
+}
+
+func (fake *FakeLocalParticipant) HasPermissionCalls(stub func(livekit.TrackID, livekit.ParticipantIdentity) bool) {
+	fake.hasPermissionMutex.Lock()
+	defer fake.hasPermissionMutex.Unlock()
+	fake.HasPermissionStub = stub
+}
+
+func (fake *FakeLocalParticipant) HasPermissionArgsForCall(i int) (livekit.TrackID, livekit.ParticipantIdentity) {
+	fake.hasPermissionMutex.RLock()
+	defer fake.hasPermissionMutex.RUnlock()
+	argsForCall := fake.hasPermissionArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeLocalParticipant) HasPermissionReturns(result1 bool) {
+	fake.hasPermissionMutex.Lock()
+	defer fake.hasPermissionMutex.Unlock()
+	fake.HasPermissionStub = nil
+	fake.hasPermissionReturns = struct {
+		result1 bool
+	}{result1}
+}
+
+func (fake *FakeLocalParticipant) HasPermissionReturnsOnCall(i int, result1 bool) {
+	fake.hasPermissionMutex.Lock()
+	defer fake.hasPermissionMutex.Unlock()
+	fake.HasPermissionStub = nil
+	if fake.hasPermissionReturnsOnCall == nil {
+		fake.hasPermissionReturnsOnCall = make(map[int]struct {
+			result1 bool
+		})
+	}
+	fake.hasPermissionReturnsOnCall[i] = struct {
+		result1 bool
+	}{result1}
+}
+
 func (fake *FakeLocalParticipant) Hidden() bool {
 	fake.hiddenMutex.Lock()
 	ret, specificReturn := fake.hiddenReturnsOnCall[len(fake.hiddenArgsForCall)]
@@ -2899,6 +3052,59 @@ func (fake *FakeLocalParticipant) NegotiateArgsForCall(i int) bool {
 	return argsForCall.arg1
 }
 
+func (fake *FakeLocalParticipant) NumVideoSlots() int {
+	fake.numVideoSlotsMutex.Lock()
+	ret, specificReturn := fake.numVideoSlotsReturnsOnCall[len(fake.numVideoSlotsArgsForCall)]
+	fake.numVideoSlotsArgsForCall = append(fake.numVideoSlotsArgsForCall, struct {
+	}{})
+	stub := fake.NumVideoSlotsStub
+	fakeReturns := fake.numVideoSlotsReturns
+	fake.recordInvocation("NumVideoSlots", []interface{}{})
+	fake.numVideoSlotsMutex.Unlock()
+	if stub != nil {
+		return stub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeLocalParticipant) NumVideoSlotsCallCount() int {
+	fake.numVideoSlotsMutex.RLock()
+	defer fake.numVideoSlotsMutex.RUnlock()
+	return len(fake.numVideoSlotsArgsForCall)
+}
+
+func (fake *FakeLocalParticipant) NumVideoSlotsCalls(stub func() int) {
+	fake.numVideoSlotsMutex.Lock()
+	defer fake.numVideoSlotsMutex.Unlock()
+	fake.NumVideoSlotsStub = stub
+}
+
+func (fake *FakeLocalParticipant) NumVideoSlotsReturns(result1 int) {
+	fake.numVideoSlotsMutex.Lock()
+	defer fake.numVideoSlotsMutex.Unlock()
+	fake.NumVideoSlotsStub = nil
+	fake.numVideoSlotsReturns = struct {
+		result1 int
+	}{result1}
+}
+
+func (fake *FakeLocalParticipant) NumVideoSlotsReturnsOnCall(i int, result1 int) {
+	fake.numVideoSlotsMutex.Lock()
+	defer fake.numVideoSlotsMutex.Unlock()
+	fake.NumVideoSlotsStub = nil
+	if fake.numVideoSlotsReturnsOnCall == nil {
+		fake.numVideoSlotsReturnsOnCall = make(map[int]struct {
+			result1 int
+		})
+	}
+	fake.numVideoSlotsReturnsOnCall[i] = struct {
+		result1 int
+	}{result1}
+}
+
 func (fake *FakeLocalParticipant) OnClaimsChanged(arg1 func(types.LocalParticipant)) {
 	fake.onClaimsChangedMutex.Lock()
 	fake.onClaimsChangedArgsForCall = append(fake.onClaimsChangedArgsForCall, struct {
@@ -4826,6 +5032,39 @@ func (fake *FakeLocalParticipant) UpdateVideoLayersReturnsOnCall(i int, result1
 	}{result1}
 }
 
+func (fake *FakeLocalParticipant) UpdateVideoSlots(arg1 []types.MediaTrack, arg2 []types.MediaTrack) {
+	fake.updateVideoSlotsMutex.Lock()
+	fake.updateVideoSlotsArgsForCall = append(fake.updateVideoSlotsArgsForCall, struct {
+		arg1 []types.MediaTrack
+		arg2 []types.MediaTrack
+	}{arg1, arg2})
+	stub := fake.UpdateVideoSlotsStub
+	fake.recordInvocation("UpdateVideoSlots", []interface{}{arg1, arg2})
+	fake.updateVideoSlotsMutex.Unlock()
+	if stub != nil {
+		fake.UpdateVideoSlotsStub(arg1, arg2)
+	}
+}
+
+func (fake *FakeLocalParticipant) UpdateVideoSlotsCallCount() int {
+	fake.updateVideoSlotsMutex.RLock()
+	defer fake.updateVideoSlotsMutex.RUnlock()
+	return len(fake.updateVideoSlotsArgsForCall)
+}
+
+func (fake *FakeLocalParticipant) UpdateVideoSlotsCalls(stub func([]types.MediaTrack, []types.MediaTrack)) {
+	fake.updateVideoSlotsMutex.Lock()
+	defer fake.updateVideoSlotsMutex.Unlock()
+	fake.UpdateVideoSlotsStub = stub
+}
+
+func (fake *FakeLocalParticipant) UpdateVideoSlotsArgsForCall(i int) ([]types.MediaTrack, []types.MediaTrack) {
+	fake.updateVideoSlotsMutex.RLock()
+	defer fake.updateVideoSlotsMutex.RUnlock()
+	argsForCall := fake.updateVideoSlotsArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
 func (fake *FakeLocalParticipant) VerifySubscribeParticipantInfo(arg1 livekit.ParticipantID, arg2 uint32) {
 	fake.verifySubscribeParticipantInfoMutex.Lock()
 	fake.verifySubscribeParticipantInfoArgsForCall = append(fake.verifySubscribeParticipantInfoArgsForCall, struct {
@@ -4892,6 +5131,8 @@ func (fake *FakeLocalParticipant) Invocations() map[string][][]interface{} {
 	defer fake.closeSignalConnectionMutex.RUnlock()
 	fake.connectedAtMutex.RLock()
 	defer fake.connectedAtMutex.RUnlock()
+	fake.dataTopicsMutex.RLock()
+	defer fake.dataTopicsMutex.RUnlock()
 	fake.debugInfoMutex.RLock()
 	defer fake.debugInfoMutex.RUnlock()
 	fake.enqueueSubscribeTrackMutex.RLock()
@@ -4924,6 +5165,8 @@ func (fake *FakeLocalParticipant) Invocations() map[string][][]interface{} {
 	defer fake.handleAnswerMutex.RUnlock()
 	fake.handleOfferMutex.RLock()
 	defer fake.handleOfferMutex.RUnlock()
+	fake.hasPermissionMutex.RLock()
+	defer fake.hasPermissionMutex.RUnlock()
 	fake.hiddenMutex.RLock()
 	defer fake.hiddenMutex.RUnlock()
 	fake.iCERestartMutex.RLock()
@@ -4946,6 +5189,8 @@ func (fake *FakeLocalParticipant) Invocations() map[string][][]interface{} {
 	defer fake.migrateStateMutex.RUnlock()
 	fake.negotiateMutex.RLock()
 	defer fake.negotiateMutex.RUnlock()
+	fake.numVideoSlotsMutex.RLock()
+	defer fake.numVideoSlotsMutex.RUnlock()
 	fake.onClaimsChangedMutex.RLock()
 	defer fake.onClaimsChangedMutex.RUnlock()
 	fake.onCloseMutex.RLock()
@@ -5032,6 +5277,8 @@ func (fake *FakeLocalParticipant) Invocations() map[string][][]interface{} {
 	defer fake.updateSubscriptionPermissionMutex.RUnlock()
 	fake.updateVideoLayersMutex.RLock()
 	defer fake.updateVideoLayersMutex.RUnlock()
+	fake.updateVideoSlotsMutex.RLock()
+	defer fake.updateVideoSlotsMutex.RUnlock()
 	fake.verifySubscribeParticipantInfoMutex.RLock()
 	defer fake.verifySubscribeParticipantInfoMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
@@ -9,6 +9,17 @@ import (
 )
 
 type FakeRoom struct {
+	AllowSignalStub        func(livekit.ParticipantID) bool
+	allowSignalMutex       sync.RWMutex
+	allowSignalArgsForCall []struct {
+		arg1 livekit.ParticipantID
+	}
+	allowSignalReturns struct {
+		result1 bool
+	}
+	allowSignalReturnsOnCall map[int]struct {
+		result1 bool
+	}
 	IDStub        func() livekit.RoomID
 	iDMutex       sync.RWMutex
 	iDArgsForCall []struct {
@@ -71,6 +82,16 @@ type FakeRoom struct {
 	syncStateReturnsOnCall map[int]struct {
 		result1 error
 	}
+	TracksQuotaExceededStub        func() bool
+	tracksQuotaExceededMutex       sync.RWMutex
+	tracksQuotaExceededArgsForCall []struct {
+	}
+	tracksQuotaExceededReturns struct {
+		result1 bool
+	}
+	tracksQuotaExceededReturnsOnCall map[int]struct {
+		result1 bool
+	}
 	UpdateSubscriptionPermissionStub        func(types.LocalParticipant, *livekit.SubscriptionPermission) error
 	updateSubscriptionPermissionMutex       sync.RWMutex
 	updateSubscriptionPermissionArgsForCall []struct {
@@ -113,6 +134,67 @@ type FakeRoom struct {
 	invocationsMutex sync.RWMutex
 }
 
+func (fake *FakeRoom) AllowSignal(arg1 livekit.ParticipantID) bool {
+	fake.allowSignalMutex.Lock()
+	ret, specificReturn := fake.allowSignalReturnsOnCall[len(fake.allowSignalArgsForCall)]
+	fake.allowSignalArgsForCall = append(fake.allowSignalArgsForCall, struct {
+		arg1 livekit.ParticipantID
+	}{arg1})
+	stub := fake.AllowSignalStub
+	fakeReturns := fake.allowSignalReturns
+	fake.recordInvocation("AllowSignal", []interface{}{arg1})
+	fake.allowSignalMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeRoom) AllowSignalCallCount() int {
+	fake.allowSignalMutex.RLock()
+	defer fake.allowSignalMutex.RUnlock()
+	return len(fake.allowSignalArgsForCall)
+}
+
+func (fake *FakeRoom) AllowSignalCalls(stub func(livekit.ParticipantID) bool) {
+	fake.allowSignalMutex.Lock()
+	defer fake.allowSignalMutex.Unlock()
+	fake.AllowSignalStub = stub
+}
+
+func (fake *FakeRoom) AllowSignalArgsForCall(i int) livekit.ParticipantID {
+	fake.allowSignalMutex.RLock()
+	defer fake.allowSignalMutex.RUnlock()
+	argsForCall := fake.allowSignalArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeRoom) AllowSignalReturns(result1 bool) {
+	fake.allowSignalMutex.Lock()
+	defer fake.allowSignalMutex.Unlock()
+	fake.AllowSignalStub = nil
+	fake.allowSignalReturns = struct {
+		result1 bool
+	}{result1}
+}
+
+func (fake *FakeRoom) AllowSignalReturnsOnCall(i int, result1 bool) {
+	fake.allowSignalMutex.Lock()
+	defer fake.allowSignalMutex.Unlock()
+	fake.AllowSignalStub = nil
+	if fake.allowSignalReturnsOnCall == nil {
+		fake.allowSignalReturnsOnCall = make(map[int]struct {
+			result1 bool
+		})
+	}
+	fake.allowSignalReturnsOnCall[i] = struct {
+		result1 bool
+	}{result1}
+}
+
 func (fake *FakeRoom) ID() livekit.RoomID {
 	fake.iDMutex.Lock()
 	ret, specificReturn := fake.iDReturnsOnCall[len(fake.iDArgsForCall)]
@@ -438,6 +520,59 @@ func (fake *FakeRoom) SyncStateReturnsOnCall(i int, result1 error) {
 	}{result1}
 }
 
+func (fake *FakeRoom) TracksQuotaExceeded() bool {
+	fake.tracksQuotaExceededMutex.Lock()
+	ret, specificReturn := fake.tracksQuotaExceededReturnsOnCall[len(fake.tracksQuotaExceededArgsForCall)]
+	fake.tracksQuotaExceededArgsForCall = append(fake.tracksQuotaExceededArgsForCall, struct {
+	}{})
+	stub := fake.TracksQuotaExceededStub
+	fakeReturns := fake.tracksQuotaExceededReturns
+	fake.recordInvocation("TracksQuotaExceeded", []interface{}{})
+	fake.tracksQuotaExceededMutex.Unlock()
+	if stub != nil {
+		return stub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeRoom) TracksQuotaExceededCallCount() int {
+	fake.tracksQuotaExceededMutex.RLock()
+	defer fake.tracksQuotaExceededMutex.RUnlock()
+	return len(fake.tracksQuotaExceededArgsForCall)
+}
+
+func (fake *FakeRoom) TracksQuotaExceededCalls(stub func() bool) {
+	fake.tracksQuotaExceededMutex.Lock()
+	defer fake.tracksQuotaExceededMutex.Unlock()
+	fake.TracksQuotaExceededStub = stub
+}
+
+func (fake *FakeRoom) TracksQuotaExceededReturns(result1 bool) {
+	fake.tracksQuotaExceededMutex.Lock()
+	defer fake.tracksQuotaExceededMutex.Unlock()
+	fake.TracksQuotaExceededStub = nil
+	fake.tracksQuotaExceededReturns = struct {
+		result1 bool
+	}{result1}
+}
+
+func (fake *FakeRoom) TracksQuotaExceededReturnsOnCall(i int, result1 bool) {
+	fake.tracksQuotaExceededMutex.Lock()
+	defer fake.tracksQuotaExceededMutex.Unlock()
+	fake.TracksQuotaExceededStub = nil
+	if fake.tracksQuotaExceededReturnsOnCall == nil {
+		fake.tracksQuotaExceededReturnsOnCall = make(map[int]struct {
+			result1 bool
+		})
+	}
+	fake.tracksQuotaExceededReturnsOnCall[i] = struct {
+		result1 bool
+	}{result1}
+}
+
 func (fake *FakeRoom) UpdateSubscriptionPermission(arg1 types.LocalParticipant, arg2 *livekit.SubscriptionPermission) error {
 	fake.updateSubscriptionPermissionMutex.Lock()
 	ret, specificReturn := fake.updateSubscriptionPermissionReturnsOnCall[len(fake.updateSubscriptionPermissionArgsForCall)]
@@ -639,6 +774,8 @@ func (fake *FakeRoom) UpdateVideoLayersReturnsOnCall(i int, result1 error) {
 func (fake *FakeRoom) Invocations() map[string][][]interface{} {
 	fake.invocationsMutex.RLock()
 	defer fake.invocationsMutex.RUnlock()
+	fake.allowSignalMutex.RLock()
+	defer fake.allowSignalMutex.RUnlock()
 	fake.iDMutex.RLock()
 	defer fake.iDMutex.RUnlock()
 	fake.nameMutex.RLock()
@@ -651,6 +788,8 @@ func (fake *FakeRoom) Invocations() map[string][][]interface{} {
 	defer fake.simulateScenarioMutex.RUnlock()
 	fake.syncStateMutex.RLock()
 	defer fake.syncStateMutex.RUnlock()
+	fake.tracksQuotaExceededMutex.RLock()
+	defer fake.tracksQuotaExceededMutex.RUnlock()
 	fake.updateSubscriptionPermissionMutex.RLock()
 	defer fake.updateSubscriptionPermissionMutex.RUnlock()
 	fake.updateSubscriptionsMutex.RLock()
@@ -85,6 +85,11 @@ type FakeSubscribedTrack struct {
 	publisherVersionReturnsOnCall map[int]struct {
 		result1 uint32
 	}
+	SetPausedStub        func(bool)
+	setPausedMutex       sync.RWMutex
+	setPausedArgsForCall []struct {
+		arg1 bool
+	}
 	SetPublisherMutedStub        func(bool)
 	setPublisherMutedMutex       sync.RWMutex
 	setPublisherMutedArgsForCall []struct {
@@ -536,6 +541,38 @@ func (fake *FakeSubscribedTrack) PublisherVersionReturnsOnCall(i int, result1 ui
 	}{result1}
 }
 
+func (fake *FakeSubscribedTrack) SetPaused(arg1 bool) {
+	fake.setPausedMutex.Lock()
+	fake.setPausedArgsForCall = append(fake.setPausedArgsForCall, struct {
+		arg1 bool
+	}{arg1})
+	stub := fake.SetPausedStub
+	fake.recordInvocation("SetPaused", []interface{}{arg1})
+	fake.setPausedMutex.Unlock()
+	if stub != nil {
+		fake.SetPausedStub(arg1)
+	}
+}
+
+func (fake *FakeSubscribedTrack) SetPausedCallCount() int {
+	fake.setPausedMutex.RLock()
+	defer fake.setPausedMutex.RUnlock()
+	return len(fake.setPausedArgsForCall)
+}
+
+func (fake *FakeSubscribedTrack) SetPausedCalls(stub func(bool)) {
+	fake.setPausedMutex.Lock()
+	defer fake.setPausedMutex.Unlock()
+	fake.SetPausedStub = stub
+}
+
+func (fake *FakeSubscribedTrack) SetPausedArgsForCall(i int) bool {
+	fake.setPausedMutex.RLock()
+	defer fake.setPausedMutex.RUnlock()
+	argsForCall := fake.setPausedArgsForCall[i]
+	return argsForCall.arg1
+}
+
 func (fake *FakeSubscribedTrack) SetPublisherMuted(arg1 bool) {
 	fake.setPublisherMutedMutex.Lock()
 	fake.setPublisherMutedArgsForCall = append(fake.setPublisherMutedArgsForCall, struct {
@@ -802,6 +839,8 @@ func (fake *FakeSubscribedTrack) Invocations() map[string][][]interface{} {
 	defer fake.publisherIdentityMutex.RUnlock()
 	fake.publisherVersionMutex.RLock()
 	defer fake.publisherVersionMutex.RUnlock()
+	fake.setPausedMutex.RLock()
+	defer fake.setPausedMutex.RUnlock()
 	fake.setPublisherMutedMutex.RLock()
 	defer fake.setPublisherMutedMutex.RUnlock()
 	fake.subscriberMutex.RLock()
@@ -396,6 +396,14 @@ func (u *UpTrackManager) parseSubscriptionPermissionsLocked(
 	return nil
 }
 
+// HasPermission returns true when subscriber is allowed to receive track
+func (u *UpTrackManager) HasPermission(trackID livekit.TrackID, subscriberIdentity livekit.ParticipantIdentity) bool {
+	u.lock.RLock()
+	defer u.lock.RUnlock()
+
+	return u.hasPermissionLocked(trackID, subscriberIdentity)
+}
+
 func (u *UpTrackManager) hasPermissionLocked(trackID livekit.TrackID, subscriberIdentity livekit.ParticipantIdentity) bool {
 	if u.subscriberPermissions == nil {
 		return true
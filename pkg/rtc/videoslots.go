@@ -0,0 +1,741 @@
+package rtc
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pion/rtcp"
+	"github.com/pion/webrtc/v3"
+	"go.uber.org/atomic"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+	"github.com/livekit/protocol/utils"
+
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+	"github.com/livekit/livekit-server/pkg/sfu"
+	"github.com/livekit/livekit-server/pkg/sfu/buffer"
+)
+
+const (
+	// video slot tracks are presented to the subscriber as tracks of this participant, which only the subscriber
+	// is told about, through participant updates
+	VideoSlotsParticipantID       = livekit.ParticipantID(utils.ParticipantPrefix + "VIDEO_SLOTS")
+	VideoSlotsParticipantIdentity = livekit.ParticipantIdentity(VideoSlotsParticipantID)
+	// topic of data packets telling the subscriber which track is shown in each slot
+	VideoSlotsTopic = "video_slots"
+	MaxVideoSlots   = 16
+
+	videoSlotTrackPrefix = utils.TrackPrefix + "VS_"
+	// minimum time a track is shown in a slot before a louder speaker can take it over
+	videoSlotMinHold = 3 * time.Second
+)
+
+// IsVideoSlotSource returns true for tracks that are shown in video slots instead of being subscribed to
+func IsVideoSlotSource(track types.MediaTrack) bool {
+	return track.Kind() == livekit.TrackType_VIDEO && track.Source() != livekit.TrackSource_SCREEN_SHARE
+}
+
+type VideoSlotInfo struct {
+	TrackSid            string `json:"track_sid"`
+	ParticipantSid      string `json:"participant_sid,omitempty"`
+	ParticipantIdentity string `json:"participant_identity,omitempty"`
+	SourceTrackSid      string `json:"source_track_sid,omitempty"`
+}
+
+type VideoSlotsUpdate struct {
+	Slots []*VideoSlotInfo `json:"slots"`
+}
+
+type VideoSlotsParams struct {
+	NumSlots         int
+	Subscriber       types.LocalParticipant
+	Config           *WebRTCConfig
+	TransportManager *TransportManager
+	Logger           logger.Logger
+}
+
+type videoSlot struct {
+	trackID     livekit.TrackID
+	source      types.MediaTrack
+	assignedAt  time.Time
+	lastSpokeAt time.Time
+
+	receiver  *videoSlotReceiver
+	downTrack *sfu.DownTrack
+	sender    *webrtc.RTPSender
+}
+
+// VideoSlots is a fixed set of video tracks sent to a subscriber, each showing one of the room's publishers.
+// Slots are filled from the active speaker ranking, switching the publisher behind a slot without renegotiation.
+type VideoSlots struct {
+	params VideoSlotsParams
+
+	lock        sync.Mutex
+	slots       []*videoSlot
+	mime        string
+	infoVersion uint32
+	joinedAt    int64
+	isClosed    bool
+}
+
+func NewVideoSlots(params VideoSlotsParams) *VideoSlots {
+	if params.NumSlots > MaxVideoSlots {
+		params.NumSlots = MaxVideoSlots
+	}
+
+	v := &VideoSlots{
+		params:   params,
+		slots:    make([]*videoSlot, params.NumSlots),
+		joinedAt: time.Now().Unix(),
+	}
+	for i := range v.slots {
+		v.slots[i] = &videoSlot{
+			trackID: livekit.TrackID(fmt.Sprintf("%s%d", videoSlotTrackPrefix, i)),
+		}
+	}
+	return v
+}
+
+func (v *VideoSlots) NumSlots() int {
+	return len(v.slots)
+}
+
+// Update assigns tracks of active speakers, loudest first, and of other publishers, in a stable order, to slots
+func (v *VideoSlots) Update(speakers []types.MediaTrack, others []types.MediaTrack) {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+
+	if v.isClosed {
+		return
+	}
+
+	tracks := make(map[livekit.TrackID]types.MediaTrack, len(speakers)+len(others))
+	speakerIDs := v.compatibleTrackIDs(speakers, tracks)
+	otherIDs := v.compatibleTrackIDs(others, tracks)
+
+	assignments := make([]videoSlotAssignment, len(v.slots))
+	for i, slot := range v.slots {
+		assignments[i] = videoSlotAssignment{assignedAt: slot.assignedAt, lastSpokeAt: slot.lastSpokeAt}
+		if slot.source != nil {
+			assignments[i].trackID = slot.source.ID()
+		}
+	}
+
+	changed := assignVideoSlots(assignments, speakerIDs, otherIDs, time.Now())
+	for i, a := range assignments {
+		v.slots[i].assignedAt = a.assignedAt
+		v.slots[i].lastSpokeAt = a.lastSpokeAt
+	}
+	if len(changed) == 0 {
+		return
+	}
+
+	for _, i := range changed {
+		v.setSource(v.slots[i], tracks[assignments[i].trackID])
+	}
+	v.sendUpdate()
+}
+
+func (v *VideoSlots) Close() {
+	v.lock.Lock()
+	v.isClosed = true
+	var downTracks []*sfu.DownTrack
+	for _, slot := range v.slots {
+		if slot.downTrack != nil {
+			downTracks = append(downTracks, slot.downTrack)
+		}
+		v.notifyMaxQuality(slot, livekit.VideoQuality_OFF)
+	}
+	if len(downTracks) != 0 {
+		v.sendParticipantInfo(livekit.ParticipantInfo_DISCONNECTED)
+	}
+	v.lock.Unlock()
+
+	for _, dt := range downTracks {
+		dt.Close()
+	}
+}
+
+// slots use a single codec, that of the first track shown, tracks not published with it are skipped
+func (v *VideoSlots) compatibleTrackIDs(candidates []types.MediaTrack, tracks map[livekit.TrackID]types.MediaTrack) []livekit.TrackID {
+	ids := make([]livekit.TrackID, 0, len(candidates))
+	for _, track := range candidates {
+		if v.mime != "" && getReceiverForMime(track, v.mime) == nil {
+			continue
+		}
+		tracks[track.ID()] = track
+		ids = append(ids, track.ID())
+	}
+	return ids
+}
+
+func (v *VideoSlots) setSource(slot *videoSlot, source types.MediaTrack) {
+	v.notifyMaxQuality(slot, livekit.VideoQuality_OFF)
+	slot.source = source
+	if source == nil {
+		if slot.receiver != nil {
+			slot.receiver.SetSource(nil)
+		}
+		return
+	}
+
+	if slot.downTrack == nil {
+		if err := v.createDownTrack(slot); err != nil {
+			v.params.Logger.Warnw("could not create video slot", err, "slot", slot.trackID, "trackID", source.ID())
+			slot.source = nil
+			return
+		}
+	}
+	slot.receiver.SetSource(getReceiverForMime(source, v.mime))
+	v.notifyMaxQuality(slot, buffer.SpatialLayerToVideoQuality(slot.downTrack.MaxLayers().Spatial, source.ToProto()))
+}
+
+func (v *VideoSlots) createDownTrack(slot *videoSlot) error {
+	receivers := slot.source.Receivers()
+	if len(receivers) == 0 {
+		return ErrNoReceiver
+	}
+	if v.mime == "" {
+		v.mime = receivers[0].Codec().MimeType
+	}
+	receiver := getReceiverForMime(slot.source, v.mime)
+	if receiver == nil {
+		return webrtc.ErrUnsupportedCodec
+	}
+
+	codec := receiver.Codec()
+	codec.RTCPFeedback = v.params.Config.Subscriber.RTCPFeedback.Video
+	sub := v.params.Subscriber
+	slot.receiver = newVideoSlotReceiver(
+		slot.trackID,
+		PackStreamID(VideoSlotsParticipantID, slot.trackID),
+		livekit.ParticipantID(fmt.Sprintf("%s|%s", sub.ID(), slot.trackID)),
+		receiver,
+	)
+	downTrack, err := sfu.NewDownTrack(
+		[]webrtc.RTPCodecParameters{codec},
+		slot.receiver,
+		v.params.Config.BufferFactory,
+		sub.ID(),
+		v.params.Config.Receiver.PacketBufferSize,
+		LoggerWithTrack(sub.GetLogger(), slot.trackID, false),
+	)
+	if err != nil {
+		return err
+	}
+
+	downTrack.OnBind(func() {
+		if err := slot.receiver.AddDownTrack(downTrack); err != nil {
+			sub.GetLogger().Errorw("could not add video slot down track", err, "slot", slot.trackID)
+		}
+		go downTrack.SetMaxSpatialLayer(sfu.DefaultMaxLayerSpatial)
+	})
+	downTrack.OnMaxLayerChanged(func(dt *sfu.DownTrack, layer int32) {
+		v.lock.Lock()
+		defer v.lock.Unlock()
+		if slot.source != nil {
+			v.notifyMaxQuality(slot, buffer.SpatialLayerToVideoQuality(layer, slot.source.ToProto()))
+		}
+	})
+	downTrack.OnRttUpdate(func(_ *sfu.DownTrack, rtt uint32) {
+		go sub.UpdateRTT(rtt)
+	})
+	downTrack.AddReceiverReportListener(func(dt *sfu.DownTrack, report *rtcp.ReceiverReport) {
+		sub.OnReceiverReport(dt, report)
+	})
+
+	var sender *webrtc.RTPSender
+	var transceiver *webrtc.RTPTransceiver
+	if sub.ProtocolVersion().SupportsTransceiverReuse() {
+		sender, transceiver, err = sub.AddTrackToSubscriber(downTrack, types.AddTrackParams{})
+	} else {
+		sender, transceiver, err = sub.AddTransceiverFromTrackToSubscriber(downTrack, types.AddTrackParams{})
+	}
+	if err != nil {
+		return err
+	}
+	downTrack.SetRTPHeaderExtensions(sender.GetParameters().HeaderExtensions)
+	downTrack.SetTransceiver(transceiver)
+	v.params.TransportManager.AddDownTrackToStreamAllocator(downTrack, sfu.AddTrackParams{
+		Source:      livekit.TrackSource_CAMERA,
+		IsSimulcast: slot.source.IsSimulcast(),
+		PublisherID: VideoSlotsParticipantID,
+	})
+	downTrack.OnCloseHandler(func(_ bool) {
+		v.params.TransportManager.RemoveDownTrackFromStreamAllocator(downTrack)
+		if err := sub.RemoveTrackFromSubscriber(sender); err != nil {
+			sub.GetLogger().Debugw("could not remove video slot track", "error", err, "slot", slot.trackID)
+		}
+	})
+
+	slot.downTrack = downTrack
+	slot.sender = sender
+	v.sendParticipantInfo(livekit.ParticipantInfo_ACTIVE)
+	go sub.Negotiate(false)
+	return nil
+}
+
+// sendParticipantInfo describes slot tracks created so far, for the subscriber to match them with the participant
+// they are presented under
+func (v *VideoSlots) sendParticipantInfo(state livekit.ParticipantInfo_State) {
+	v.infoVersion++
+	info := &livekit.ParticipantInfo{
+		Sid:         string(VideoSlotsParticipantID),
+		Identity:    string(VideoSlotsParticipantIdentity),
+		State:       state,
+		JoinedAt:    v.joinedAt,
+		Version:     v.infoVersion,
+		IsPublisher: true,
+	}
+	for _, slot := range v.slots {
+		if slot.downTrack == nil {
+			continue
+		}
+		info.Tracks = append(info.Tracks, &livekit.TrackInfo{
+			Sid:      string(slot.trackID),
+			Type:     livekit.TrackType_VIDEO,
+			Name:     string(slot.trackID),
+			Source:   livekit.TrackSource_CAMERA,
+			MimeType: v.mime,
+		})
+	}
+	if err := v.params.Subscriber.SendParticipantUpdate([]*livekit.ParticipantInfo{info}); err != nil {
+		v.params.Logger.Debugw("could not send video slots participant", "error", err)
+	}
+}
+
+// subscribers of slots are accounted for by dynacast of the track shown, under the slot's own subscriber ID
+func (v *VideoSlots) notifyMaxQuality(slot *videoSlot, quality livekit.VideoQuality) {
+	if slot.source == nil || slot.receiver == nil {
+		return
+	}
+	if notifier, ok := slot.source.(interface {
+		NotifySubscriberMaxQuality(subscriberID livekit.ParticipantID, mime string, quality livekit.VideoQuality)
+	}); ok {
+		notifier.NotifySubscriberMaxQuality(slot.receiver.subscriberID, v.mime, quality)
+	}
+}
+
+func (v *VideoSlots) sendUpdate() {
+	update := &VideoSlotsUpdate{Slots: make([]*VideoSlotInfo, 0, len(v.slots))}
+	for _, slot := range v.slots {
+		info := &VideoSlotInfo{TrackSid: string(slot.trackID)}
+		if slot.source != nil {
+			info.ParticipantSid = string(slot.source.PublisherID())
+			info.ParticipantIdentity = string(slot.source.PublisherIdentity())
+			info.SourceTrackSid = string(slot.source.ID())
+		}
+		update.Slots = append(update.Slots, info)
+	}
+	payload, err := json.Marshal(update)
+	if err != nil {
+		return
+	}
+
+	up := &livekit.UserPacket{Payload: payload}
+	setUserPacketTopic(up, VideoSlotsTopic)
+	if err = v.params.Subscriber.SendDataPacket(&livekit.DataPacket{
+		Kind:  livekit.DataPacket_RELIABLE,
+		Value: &livekit.DataPacket_User{User: up},
+	}); err != nil {
+		v.params.Logger.Debugw("could not send video slots update", "error", err)
+	}
+}
+
+func getReceiverForMime(track types.MediaTrack, mime string) sfu.TrackReceiver {
+	for _, r := range track.Receivers() {
+		if strings.EqualFold(r.Codec().MimeType, mime) {
+			return r
+		}
+	}
+	return nil
+}
+
+// ------------------------------------------------
+
+type videoSlotAssignment struct {
+	trackID     livekit.TrackID
+	assignedAt  time.Time
+	lastSpokeAt time.Time
+}
+
+// assignVideoSlots updates slots with speakers, loudest first, and other available tracks, returning indexes of slots
+// that have changed. A slot keeps its track while it is available. Once shown for videoSlotMinHold, it can be taken
+// over by a speaker that isn't shown, when the slot's track is not speaking or is speaking less loudly.
+func assignVideoSlots(slots []videoSlotAssignment, speakers []livekit.TrackID, others []livekit.TrackID, now time.Time) []int {
+	speakerRank := make(map[livekit.TrackID]int, len(speakers))
+	for i, id := range speakers {
+		speakerRank[id] = i
+	}
+	available := make(map[livekit.TrackID]bool, len(speakers)+len(others))
+	for _, id := range speakers {
+		available[id] = true
+	}
+	for _, id := range others {
+		available[id] = true
+	}
+
+	changed := make(map[int]bool)
+	shown := make(map[livekit.TrackID]bool, len(slots))
+	for i := range slots {
+		if slots[i].trackID == "" {
+			continue
+		}
+		if !available[slots[i].trackID] {
+			slots[i] = videoSlotAssignment{}
+			changed[i] = true
+			continue
+		}
+		if _, ok := speakerRank[slots[i].trackID]; ok {
+			slots[i].lastSpokeAt = now
+		}
+		shown[slots[i].trackID] = true
+	}
+
+	assign := func(i int, id livekit.TrackID, spoke bool) {
+		delete(shown, slots[i].trackID)
+		slots[i] = videoSlotAssignment{trackID: id, assignedAt: now}
+		if spoke {
+			slots[i].lastSpokeAt = now
+		}
+		shown[id] = true
+		changed[i] = true
+	}
+
+	for rank, id := range speakers {
+		if shown[id] {
+			continue
+		}
+		i := emptyVideoSlot(slots)
+		if i < 0 {
+			if i = replaceableVideoSlot(slots, speakerRank, rank, now); i < 0 {
+				// quieter speakers couldn't replace any either
+				break
+			}
+		}
+		assign(i, id, true)
+	}
+
+	for _, id := range others {
+		if shown[id] {
+			continue
+		}
+		i := emptyVideoSlot(slots)
+		if i < 0 {
+			break
+		}
+		assign(i, id, false)
+	}
+
+	indexes := make([]int, 0, len(changed))
+	for i := range changed {
+		indexes = append(indexes, i)
+	}
+	sort.Ints(indexes)
+	return indexes
+}
+
+func emptyVideoSlot(slots []videoSlotAssignment) int {
+	for i := range slots {
+		if slots[i].trackID == "" {
+			return i
+		}
+	}
+	return -1
+}
+
+// replaceableVideoSlot returns the least relevant slot a speaker of rank can take over, -1 if none.
+// Slots not speaking come first, those that haven't spoken for longest, then the quietest speaker below rank.
+func replaceableVideoSlot(slots []videoSlotAssignment, speakerRank map[livekit.TrackID]int, rank int, now time.Time) int {
+	found := -1
+	foundRank := -1
+	for i := range slots {
+		if now.Sub(slots[i].assignedAt) < videoSlotMinHold {
+			continue
+		}
+
+		slotRank, speaking := speakerRank[slots[i].trackID]
+		if !speaking {
+			slotRank = len(speakerRank)
+		} else if slotRank < rank {
+			continue
+		}
+
+		if found < 0 ||
+			slotRank > foundRank ||
+			(slotRank == foundRank && slots[i].lastSpokeAt.Before(slots[found].lastSpokeAt)) {
+			found, foundRank = i, slotRank
+		}
+	}
+	return found
+}
+
+// ------------------------------------------------
+
+// videoSlotReceiver feeds a slot's DownTrack from the receiver of the track shown in the slot, under the slot's
+// own track and stream IDs. It is added to that receiver under subscriberID, unique to the slot.
+type videoSlotReceiver struct {
+	trackID      livekit.TrackID
+	streamID     string
+	subscriberID livekit.ParticipantID
+
+	lock      sync.RWMutex
+	source    sfu.TrackReceiver
+	downTrack *sfu.DownTrack
+	sender    *videoSlotSender
+
+	trackInfoAvailable atomic.Bool
+}
+
+func newVideoSlotReceiver(trackID livekit.TrackID, streamID string, subscriberID livekit.ParticipantID, source sfu.TrackReceiver) *videoSlotReceiver {
+	return &videoSlotReceiver{
+		trackID:      trackID,
+		streamID:     streamID,
+		subscriberID: subscriberID,
+		source:       source,
+	}
+}
+
+// SetSource switches the receiver the DownTrack is fed from, nil to stop forwarding
+func (r *videoSlotReceiver) SetSource(source sfu.TrackReceiver) {
+	r.lock.Lock()
+	prev := r.source
+	prevSender := r.sender
+	if prev == source && prevSender != nil {
+		r.lock.Unlock()
+		return
+	}
+	r.source = source
+	r.sender = nil
+	downTrack := r.downTrack
+	r.lock.Unlock()
+
+	if prev != nil && prevSender != nil {
+		prev.DeleteDownTrack(r.subscriberID)
+	}
+	if downTrack != nil {
+		downTrack.SwitchSource()
+		r.attach(source)
+	}
+}
+
+func (r *videoSlotReceiver) attach(source sfu.TrackReceiver) {
+	if source == nil {
+		return
+	}
+
+	r.lock.Lock()
+	if r.source != source || r.downTrack == nil {
+		r.lock.Unlock()
+		return
+	}
+	sender := &videoSlotSender{receiver: r}
+	r.sender = sender
+	r.lock.Unlock()
+
+	if err := source.AddDownTrack(sender); err != nil {
+		r.lock.Lock()
+		if r.sender == sender {
+			r.sender = nil
+		}
+		r.lock.Unlock()
+	}
+}
+
+func (r *videoSlotReceiver) getSource() sfu.TrackReceiver {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	return r.source
+}
+
+func (r *videoSlotReceiver) TrackID() livekit.TrackID {
+	return r.trackID
+}
+
+func (r *videoSlotReceiver) StreamID() string {
+	return r.streamID
+}
+
+func (r *videoSlotReceiver) Codec() webrtc.RTPCodecParameters {
+	if source := r.getSource(); source != nil {
+		return source.Codec()
+	}
+	return webrtc.RTPCodecParameters{}
+}
+
+func (r *videoSlotReceiver) HeaderExtensions() []webrtc.RTPHeaderExtensionParameter {
+	if source := r.getSource(); source != nil {
+		return source.HeaderExtensions()
+	}
+	return nil
+}
+
+func (r *videoSlotReceiver) ReadRTP(buf []byte, layer uint8, sn uint16) (int, error) {
+	if source := r.getSource(); source != nil {
+		return source.ReadRTP(buf, layer, sn)
+	}
+	return 0, ErrNoReceiver
+}
+
+func (r *videoSlotReceiver) GetLayeredBitrate() sfu.Bitrates {
+	if source := r.getSource(); source != nil {
+		return source.GetLayeredBitrate()
+	}
+	return sfu.Bitrates{}
+}
+
+func (r *videoSlotReceiver) GetAudioLevel() (float64, bool) {
+	return 0, false
+}
+
+func (r *videoSlotReceiver) SendPLI(layer int32, force bool) {
+	if source := r.getSource(); source != nil {
+		source.SendPLI(layer, force)
+	}
+}
+
+// publisher side settings are managed by the track shown, through dynacast
+func (r *videoSlotReceiver) SetUpTrackPaused(_ bool) {}
+
+func (r *videoSlotReceiver) SetMaxExpectedSpatialLayer(_ int32) {}
+
+func (r *videoSlotReceiver) AddDownTrack(track sfu.TrackSender) error {
+	downTrack, ok := track.(*sfu.DownTrack)
+	if !ok {
+		return ErrNotDownTrack
+	}
+
+	r.lock.Lock()
+	r.downTrack = downTrack
+	source := r.source
+	r.lock.Unlock()
+
+	r.attach(source)
+	return nil
+}
+
+func (r *videoSlotReceiver) DeleteDownTrack(_ livekit.ParticipantID) {
+	r.lock.Lock()
+	source := r.source
+	sender := r.sender
+	r.downTrack = nil
+	r.sender = nil
+	r.lock.Unlock()
+
+	if source != nil && sender != nil {
+		source.DeleteDownTrack(r.subscriberID)
+	}
+}
+
+func (r *videoSlotReceiver) DebugInfo() map[string]interface{} {
+	if source := r.getSource(); source != nil {
+		return source.DebugInfo()
+	}
+	return nil
+}
+
+func (r *videoSlotReceiver) GetLayerDimension(layer int32) (uint32, uint32) {
+	if source := r.getSource(); source != nil {
+		return source.GetLayerDimension(layer)
+	}
+	return 0, 0
+}
+
+func (r *videoSlotReceiver) TrackInfo() *livekit.TrackInfo {
+	if source := r.getSource(); source != nil {
+		return source.TrackInfo()
+	}
+	return nil
+}
+
+func (r *videoSlotReceiver) GetPrimaryReceiverForRed() sfu.TrackReceiver {
+	return r
+}
+
+func (r *videoSlotReceiver) GetRedReceiver() sfu.TrackReceiver {
+	return r
+}
+
+func (r *videoSlotReceiver) GetTemporalLayerFpsForSpatial(layer int32) []float32 {
+	if source := r.getSource(); source != nil {
+		return source.GetTemporalLayerFpsForSpatial(layer)
+	}
+	return nil
+}
+
+// videoSlotSender is added to the receiver of the track shown in a slot. It only forwards while it is current,
+// and closing it, when that track's receiver closes, leaves the slot's DownTrack open for the next track.
+type videoSlotSender struct {
+	receiver *videoSlotReceiver
+	closed   atomic.Bool
+}
+
+func (s *videoSlotSender) currentDownTrack() *sfu.DownTrack {
+	s.receiver.lock.RLock()
+	defer s.receiver.lock.RUnlock()
+
+	if s.receiver.sender != s {
+		return nil
+	}
+	return s.receiver.downTrack
+}
+
+func (s *videoSlotSender) UpTrackLayersChange(availableLayers []int32, exemptedLayers []int32) {
+	if dt := s.currentDownTrack(); dt != nil {
+		dt.UpTrackLayersChange(availableLayers, exemptedLayers)
+	}
+}
+
+func (s *videoSlotSender) UpTrackBitrateAvailabilityChange() {
+	if dt := s.currentDownTrack(); dt != nil {
+		dt.UpTrackBitrateAvailabilityChange()
+	}
+}
+
+func (s *videoSlotSender) WriteRTP(p *buffer.ExtPacket, layer int32) error {
+	// hold lock while writing so that packets of a previous source are not written after a switch
+	s.receiver.lock.RLock()
+	defer s.receiver.lock.RUnlock()
+
+	if s.receiver.sender != s || s.receiver.downTrack == nil {
+		return nil
+	}
+	return s.receiver.downTrack.WriteRTP(p, layer)
+}
+
+func (s *videoSlotSender) Close() {
+	s.closed.Store(true)
+
+	s.receiver.lock.Lock()
+	if s.receiver.sender == s {
+		s.receiver.sender = nil
+	}
+	s.receiver.lock.Unlock()
+}
+
+func (s *videoSlotSender) IsClosed() bool {
+	return s.closed.Load()
+}
+
+func (s *videoSlotSender) ID() string {
+	return string(s.receiver.trackID)
+}
+
+func (s *videoSlotSender) SubscriberID() livekit.ParticipantID {
+	return s.receiver.subscriberID
+}
+
+func (s *videoSlotSender) TrackInfoAvailable() {
+	if s.receiver.trackInfoAvailable.Swap(true) {
+		return
+	}
+	if dt := s.currentDownTrack(); dt != nil {
+		dt.TrackInfoAvailable()
+	}
+}
@@ -0,0 +1,207 @@
+package rtc
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pion/webrtc/v3"
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/sfu"
+)
+
+func TestAssignVideoSlots(t *testing.T) {
+	shown := func(slots []videoSlotAssignment) []livekit.TrackID {
+		ids := make([]livekit.TrackID, 0, len(slots))
+		for _, s := range slots {
+			ids = append(ids, s.trackID)
+		}
+		return ids
+	}
+	now := time.Now()
+
+	t.Run("fills slots with speakers first", func(t *testing.T) {
+		slots := make([]videoSlotAssignment, 3)
+		changed := assignVideoSlots(slots, []livekit.TrackID{"s1"}, []livekit.TrackID{"o1", "o2", "o3"}, now)
+		require.Equal(t, []int{0, 1, 2}, changed)
+		require.Equal(t, []livekit.TrackID{"s1", "o1", "o2"}, shown(slots))
+
+		// nothing changes while the same tracks are available
+		changed = assignVideoSlots(slots, []livekit.TrackID{"s1"}, []livekit.TrackID{"o1", "o2", "o3"}, now)
+		require.Empty(t, changed)
+	})
+
+	t.Run("speaker waits for min hold", func(t *testing.T) {
+		slots := make([]videoSlotAssignment, 2)
+		assignVideoSlots(slots, nil, []livekit.TrackID{"o1", "o2", "o3"}, now)
+
+		changed := assignVideoSlots(slots, []livekit.TrackID{"o3"}, []livekit.TrackID{"o1", "o2"}, now.Add(time.Second))
+		require.Empty(t, changed)
+
+		changed = assignVideoSlots(slots, []livekit.TrackID{"o3"}, []livekit.TrackID{"o1", "o2"}, now.Add(videoSlotMinHold))
+		require.Len(t, changed, 1)
+		require.Contains(t, shown(slots), livekit.TrackID("o3"))
+	})
+
+	t.Run("replaces slot silent for longest", func(t *testing.T) {
+		slots := make([]videoSlotAssignment, 2)
+		assignVideoSlots(slots, []livekit.TrackID{"s1", "s2"}, nil, now)
+		// s2 stops speaking after s1
+		assignVideoSlots(slots, []livekit.TrackID{"s2"}, []livekit.TrackID{"s1"}, now.Add(time.Second))
+
+		later := now.Add(2 * videoSlotMinHold)
+		changed := assignVideoSlots(slots, []livekit.TrackID{"s3"}, []livekit.TrackID{"s1", "s2"}, later)
+		require.Equal(t, []int{0}, changed)
+		require.Equal(t, []livekit.TrackID{"s3", "s2"}, shown(slots))
+	})
+
+	t.Run("louder speaker replaces quieter one", func(t *testing.T) {
+		slots := make([]videoSlotAssignment, 1)
+		assignVideoSlots(slots, []livekit.TrackID{"s1"}, nil, now)
+
+		later := now.Add(videoSlotMinHold)
+		changed := assignVideoSlots(slots, []livekit.TrackID{"s1", "s2"}, nil, later)
+		require.Empty(t, changed)
+
+		changed = assignVideoSlots(slots, []livekit.TrackID{"s2", "s1"}, nil, later)
+		require.Equal(t, []int{0}, changed)
+		require.Equal(t, []livekit.TrackID{"s2"}, shown(slots))
+	})
+
+	t.Run("clears unavailable tracks", func(t *testing.T) {
+		slots := make([]videoSlotAssignment, 2)
+		assignVideoSlots(slots, nil, []livekit.TrackID{"o1", "o2"}, now)
+
+		changed := assignVideoSlots(slots, nil, []livekit.TrackID{"o2"}, now)
+		require.Equal(t, []int{0}, changed)
+		require.Equal(t, []livekit.TrackID{"", "o2"}, shown(slots))
+
+		changed = assignVideoSlots(slots, nil, []livekit.TrackID{"o2", "o3"}, now)
+		require.Equal(t, []int{0}, changed)
+		require.Equal(t, []livekit.TrackID{"o3", "o2"}, shown(slots))
+	})
+}
+
+// slotSourceReceiver records senders added by a video slot
+type slotSourceReceiver struct {
+	sfu.TrackReceiver
+
+	lock    sync.Mutex
+	senders []sfu.TrackSender
+	deleted []livekit.ParticipantID
+}
+
+func (r *slotSourceReceiver) Codec() webrtc.RTPCodecParameters {
+	return webrtc.RTPCodecParameters{
+		RTPCodecCapability: webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeVP8, ClockRate: 90000},
+	}
+}
+
+func (r *slotSourceReceiver) AddDownTrack(track sfu.TrackSender) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.senders = append(r.senders, track)
+	return nil
+}
+
+func (r *slotSourceReceiver) DeleteDownTrack(subscriberID livekit.ParticipantID) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.deleted = append(r.deleted, subscriberID)
+}
+
+func (r *slotSourceReceiver) lastSender() *videoSlotSender {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if len(r.senders) == 0 {
+		return nil
+	}
+	return r.senders[len(r.senders)-1].(*videoSlotSender)
+}
+
+func TestVideoSlotReceiver(t *testing.T) {
+	subscriberID := livekit.ParticipantID("PA_sub|TR_VS_0")
+	first := &slotSourceReceiver{}
+	second := &slotSourceReceiver{}
+	r := newVideoSlotReceiver("TR_VS_0", PackStreamID(VideoSlotsParticipantID, "TR_VS_0"), subscriberID, first)
+
+	require.Equal(t, ErrNotDownTrack, r.AddDownTrack(&videoSlotSender{receiver: r}))
+
+	downTrack, err := sfu.NewDownTrack(
+		[]webrtc.RTPCodecParameters{first.Codec()},
+		r,
+		nil,
+		"PA_sub",
+		500,
+		logger.GetDefaultLogger(),
+	)
+	require.NoError(t, err)
+	require.Equal(t, "TR_VS_0", downTrack.ID())
+	require.Equal(t, PackStreamID(VideoSlotsParticipantID, "TR_VS_0"), downTrack.StreamID())
+
+	t.Run("attaches to source once down track is added", func(t *testing.T) {
+		require.Nil(t, first.lastSender())
+		require.NoError(t, r.AddDownTrack(downTrack))
+
+		sender := first.lastSender()
+		require.NotNil(t, sender)
+		require.Equal(t, subscriberID, sender.SubscriberID())
+		require.Equal(t, "TR_VS_0", sender.ID())
+		require.Equal(t, downTrack, sender.currentDownTrack())
+	})
+
+	t.Run("switches source", func(t *testing.T) {
+		previous := first.lastSender()
+		r.SetSource(second)
+
+		require.Equal(t, []livekit.ParticipantID{subscriberID}, first.deleted)
+		require.Nil(t, previous.currentDownTrack())
+		require.Equal(t, downTrack, second.lastSender().currentDownTrack())
+
+		// closing the previous sender, as its source goes away, leaves the slot with the current one
+		previous.Close()
+		require.True(t, previous.IsClosed())
+		require.Equal(t, downTrack, second.lastSender().currentDownTrack())
+
+		// switching to the same source is a no-op
+		r.SetSource(second)
+		second.lock.Lock()
+		require.Len(t, second.senders, 1)
+		second.lock.Unlock()
+	})
+
+	t.Run("reattaches to same source once its sender is closed", func(t *testing.T) {
+		second.lastSender().Close()
+		r.SetSource(second)
+
+		second.lock.Lock()
+		require.Len(t, second.senders, 2)
+		second.lock.Unlock()
+		require.Equal(t, downTrack, second.lastSender().currentDownTrack())
+	})
+
+	t.Run("stops forwarding without source", func(t *testing.T) {
+		sender := second.lastSender()
+		r.SetSource(nil)
+
+		require.Equal(t, []livekit.ParticipantID{subscriberID}, second.deleted)
+		require.Nil(t, sender.currentDownTrack())
+		require.Equal(t, webrtc.RTPCodecParameters{}, r.Codec())
+		_, err := r.ReadRTP(nil, 0, 0)
+		require.Equal(t, ErrNoReceiver, err)
+	})
+
+	t.Run("detaches when down track is deleted", func(t *testing.T) {
+		r.SetSource(first)
+		sender := first.lastSender()
+		require.Equal(t, downTrack, sender.currentDownTrack())
+
+		r.DeleteDownTrack("PA_sub")
+		require.Nil(t, sender.currentDownTrack())
+		require.Len(t, first.deleted, 2)
+	})
+}
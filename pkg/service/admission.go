@@ -0,0 +1,190 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protojson"
+
+	"github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/routing"
+)
+
+// AdmissionRequest is sent to the admission URL before a participant joins
+type AdmissionRequest struct {
+	Room     string `json:"room"`
+	Identity string `json:"identity"`
+	Name     string `json:"name,omitempty"`
+	// ClientInfo, in protobuf JSON
+	Client json.RawMessage   `json:"client,omitempty"`
+	Grants *auth.ClaimGrants `json:"grants"`
+}
+
+// AdmissionResponse allows or denies a join
+type AdmissionResponse struct {
+	Allow bool `json:"allow"`
+	// returned to the client when denied
+	Reason string `json:"reason,omitempty"`
+	// grants to join with in place of those of the token
+	Grants *AdmissionGrants `json:"grants,omitempty"`
+}
+
+// AdmissionGrants overrides grants of a token, named as they are in it. Grants that are not set are kept
+type AdmissionGrants struct {
+	Name           *string `json:"name,omitempty"`
+	Metadata       *string `json:"metadata,omitempty"`
+	CanPublish     *bool   `json:"canPublish,omitempty"`
+	CanSubscribe   *bool   `json:"canSubscribe,omitempty"`
+	CanPublishData *bool   `json:"canPublishData,omitempty"`
+	Hidden         *bool   `json:"hidden,omitempty"`
+}
+
+// Admission asks a URL whether participants may join, once their token is validated. Joins are denied when it
+// can't be reached unless configured to fail open
+type Admission struct {
+	conf      config.AdmissionConfig
+	apiKey    string
+	apiSecret string
+	client    *http.Client
+}
+
+func NewAdmission(conf *config.Config, keyProvider auth.KeyProvider) (*Admission, error) {
+	ac := conf.Admission
+	if ac.URL == "" {
+		return nil, nil
+	}
+	secret := keyProvider.GetSecret(ac.APIKey)
+	if secret == "" {
+		return nil, ErrAdmissionMissingAPIKey
+	}
+	return &Admission{
+		conf:      ac,
+		apiKey:    ac.APIKey,
+		apiSecret: secret,
+		client:    &http.Client{Timeout: ac.Timeout},
+	}, nil
+}
+
+// Admit decides on a participant joining a room, applying grants the callback overrides to pi. It returns the
+// status code to respond with when the join is denied
+func (a *Admission) Admit(ctx context.Context, roomName livekit.RoomName, pi *routing.ParticipantInit) (int, error) {
+	if a == nil {
+		return http.StatusOK, nil
+	}
+
+	res, err := a.request(ctx, roomName, pi)
+	if err != nil {
+		if a.conf.FailOpen {
+			logger.Warnw("admission failed, allowing join", err, "room", roomName, "participant", pi.Identity)
+			return http.StatusOK, nil
+		}
+		logger.Warnw("admission failed, denying join", err, "room", roomName, "participant", pi.Identity)
+		return http.StatusServiceUnavailable, ErrAdmissionUnavailable
+	}
+
+	if !res.Allow {
+		logger.Infow("join denied", "room", roomName, "participant", pi.Identity, "reason", res.Reason)
+		if res.Reason != "" {
+			return http.StatusForbidden, fmt.Errorf("%w: %s", ErrJoinDenied, res.Reason)
+		}
+		return http.StatusForbidden, ErrJoinDenied
+	}
+	if res.Grants != nil {
+		res.Grants.apply(pi)
+	}
+	return http.StatusOK, nil
+}
+
+func (a *Admission) request(ctx context.Context, roomName livekit.RoomName, pi *routing.ParticipantInit) (*AdmissionResponse, error) {
+	req := &AdmissionRequest{
+		Room:     string(roomName),
+		Identity: string(pi.Identity),
+		Name:     string(pi.Name),
+		Grants:   pi.Grants,
+	}
+	if pi.Client != nil {
+		client, err := protojson.Marshal(pi.Client)
+		if err != nil {
+			return nil, err
+		}
+		req.Client = client
+	}
+	payload, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// signed as webhooks are, so that receivers can verify them alike
+	sum := sha256.Sum256(payload)
+	token, err := auth.NewAccessToken(a.apiKey, a.apiSecret).
+		SetValidFor(5 * time.Minute).
+		SetSha256(base64.StdEncoding.EncodeToString(sum[:])).
+		ToJWT()
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := http.NewRequestWithContext(ctx, http.MethodPost, a.conf.URL, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	r.Header.Set("Authorization", token)
+	r.Header.Set("Content-Type", "application/json")
+
+	hr, err := a.client.Do(r)
+	if err != nil {
+		return nil, err
+	}
+	defer hr.Body.Close()
+
+	if hr.StatusCode < 200 || hr.StatusCode >= 300 {
+		return nil, fmt.Errorf("unexpected status code %d", hr.StatusCode)
+	}
+	res := &AdmissionResponse{}
+	if err = json.NewDecoder(hr.Body).Decode(res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// apply overrides grants of the participant, leaving grants of the token as they are
+func (g *AdmissionGrants) apply(pi *routing.ParticipantInit) {
+	grants := *pi.Grants
+	video := grants.Video.Clone()
+	if video == nil {
+		video = &auth.VideoGrant{}
+	}
+
+	if g.Name != nil {
+		grants.Name = *g.Name
+		pi.Name = livekit.ParticipantName(*g.Name)
+	}
+	if g.Metadata != nil {
+		grants.Metadata = *g.Metadata
+	}
+	if g.CanPublish != nil {
+		video.SetCanPublish(*g.CanPublish)
+	}
+	if g.CanSubscribe != nil {
+		video.SetCanSubscribe(*g.CanSubscribe)
+	}
+	if g.CanPublishData != nil {
+		video.SetCanPublishData(*g.CanPublishData)
+	}
+	if g.Hidden != nil {
+		video.Hidden = *g.Hidden
+	}
+
+	grants.Video = video
+	pi.Grants = &grants
+}
@@ -0,0 +1,198 @@
+package service_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/atomic"
+
+	"github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/routing"
+	"github.com/livekit/livekit-server/pkg/routing/routingfakes"
+	"github.com/livekit/livekit-server/pkg/service"
+	"github.com/livekit/livekit-server/pkg/service/servicefakes"
+)
+
+func TestAdmission(t *testing.T) {
+	keyProvider := auth.NewFileBasedKeyProviderFromMap(map[string]string{"key": "secret"})
+
+	newAdmission := func(t *testing.T, url string, failOpen bool) *service.Admission {
+		conf, err := config.NewConfig("", true, nil, nil)
+		require.NoError(t, err)
+		conf.Admission = config.AdmissionConfig{
+			URL:      url,
+			APIKey:   "key",
+			Timeout:  200 * time.Millisecond,
+			FailOpen: failOpen,
+		}
+		admission, err := service.NewAdmission(conf, keyProvider)
+		require.NoError(t, err)
+		return admission
+	}
+	newParticipant := func() *routing.ParticipantInit {
+		return &routing.ParticipantInit{
+			Identity: "participant",
+			Name:     "name",
+			Grants: &auth.ClaimGrants{
+				Identity: "participant",
+				Name:     "name",
+				Video:    &auth.VideoGrant{RoomJoin: true, Room: "room"},
+			},
+			Client: &livekit.ClientInfo{Sdk: livekit.ClientInfo_JS},
+		}
+	}
+	serve := func(t *testing.T, handler http.HandlerFunc) string {
+		server := httptest.NewServer(handler)
+		t.Cleanup(server.Close)
+		return server.URL
+	}
+
+	t.Run("not configured", func(t *testing.T) {
+		admission := newAdmission(t, "", false)
+		require.Nil(t, admission)
+		code, err := admission.Admit(context.Background(), "room", newParticipant())
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, code)
+	})
+
+	t.Run("allows with overridden grants", func(t *testing.T) {
+		url := serve(t, func(w http.ResponseWriter, r *http.Request) {
+			// verifiable as webhooks are
+			_, err := auth.ParseAPIToken(r.Header.Get("Authorization"))
+			require.NoError(t, err)
+
+			req := &service.AdmissionRequest{}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(req))
+			require.Equal(t, "room", req.Room)
+			require.Equal(t, "participant", req.Identity)
+			require.True(t, req.Grants.Video.RoomJoin)
+			require.NotEmpty(t, req.Client)
+
+			canPublish := false
+			hidden := true
+			metadata := "admitted"
+			_ = json.NewEncoder(w).Encode(&service.AdmissionResponse{
+				Allow: true,
+				Grants: &service.AdmissionGrants{
+					CanPublish: &canPublish,
+					Hidden:     &hidden,
+					Metadata:   &metadata,
+				},
+			})
+		})
+
+		pi := newParticipant()
+		grants := pi.Grants
+		code, err := newAdmission(t, url, false).Admit(context.Background(), "room", pi)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, code)
+		require.False(t, pi.Grants.Video.GetCanPublish())
+		require.True(t, pi.Grants.Video.GetCanSubscribe())
+		require.True(t, pi.Grants.Video.Hidden)
+		require.Equal(t, "admitted", pi.Grants.Metadata)
+		require.Equal(t, livekit.ParticipantName("name"), pi.Name)
+		// grants of the token are left as they are
+		require.Nil(t, grants.Video.CanPublish)
+		require.Empty(t, grants.Metadata)
+	})
+
+	t.Run("denies", func(t *testing.T) {
+		url := serve(t, func(w http.ResponseWriter, r *http.Request) {
+			_ = json.NewEncoder(w).Encode(&service.AdmissionResponse{Reason: "room is full"})
+		})
+
+		code, err := newAdmission(t, url, true).Admit(context.Background(), "room", newParticipant())
+		require.ErrorIs(t, err, service.ErrJoinDenied)
+		require.Contains(t, err.Error(), "room is full")
+		require.Equal(t, http.StatusForbidden, code)
+	})
+
+	t.Run("fails closed", func(t *testing.T) {
+		url := serve(t, func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(time.Second)
+		})
+
+		code, err := newAdmission(t, url, false).Admit(context.Background(), "room", newParticipant())
+		require.ErrorIs(t, err, service.ErrAdmissionUnavailable)
+		require.Equal(t, http.StatusServiceUnavailable, code)
+	})
+
+	t.Run("fails open", func(t *testing.T) {
+		url := serve(t, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		})
+
+		pi := newParticipant()
+		code, err := newAdmission(t, url, true).Admit(context.Background(), "room", pi)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, code)
+		require.Nil(t, pi.Grants.Video.CanPublish)
+	})
+
+	t.Run("is asked when joining, not when validating tokens", func(t *testing.T) {
+		var requests atomic.Int32
+		url := serve(t, func(w http.ResponseWriter, r *http.Request) {
+			requests.Inc()
+			_ = json.NewEncoder(w).Encode(&service.AdmissionResponse{Reason: "room is full"})
+		})
+
+		conf, err := config.NewConfig("", true, nil, nil)
+		require.NoError(t, err)
+		node, err := routing.NewLocalNode(conf)
+		require.NoError(t, err)
+		ra, conf := newTestRoomAllocator(t, conf, node)
+		router := &routingfakes.FakeRouter{}
+		router.GetNodeForRoomReturns(node, nil)
+		s := service.NewRTCService(conf, ra, &servicefakes.FakeServiceStore{}, router, node, newAdmission(t, url, false), nil, nil)
+
+		newRequest := func(path string) *http.Request {
+			r := httptest.NewRequest(http.MethodGet, path+"?room=room", nil)
+			return r.WithContext(service.WithGrants(r.Context(), newParticipant().Grants))
+		}
+
+		w := httptest.NewRecorder()
+		s.Validate(w, newRequest("/rtc/validate"))
+		require.Equal(t, http.StatusOK, w.Code)
+		require.Zero(t, requests.Load())
+
+		r := newRequest("/rtc")
+		r.Header.Set("Connection", "upgrade")
+		r.Header.Set("Upgrade", "websocket")
+		w = httptest.NewRecorder()
+		s.ServeHTTP(w, r)
+		require.Equal(t, http.StatusForbidden, w.Code)
+		require.Equal(t, int32(1), requests.Load())
+		require.Zero(t, router.StartParticipantSignalCallCount())
+	})
+
+	t.Run("is told addresses forwarded by trusted proxies only", func(t *testing.T) {
+		conf, err := config.NewConfig("", true, nil, nil)
+		require.NoError(t, err)
+		conf.TrustedProxies = []string{"10.0.0.0/8"}
+		proxies, err := service.NewTrustedProxies(conf)
+		require.NoError(t, err)
+		node, err := routing.NewLocalNode(conf)
+		require.NoError(t, err)
+		ra, conf := newTestRoomAllocator(t, conf, node)
+		s := service.NewRTCService(conf, ra, &servicefakes.FakeServiceStore{}, &routingfakes.FakeRouter{}, node, nil, nil, proxies)
+
+		clientAddress := func(remoteAddr string) string {
+			r := httptest.NewRequest(http.MethodGet, "/rtc?room=room", nil)
+			r.RemoteAddr = remoteAddr
+			r.Header.Set("CF-Connecting-IP", "192.0.2.1")
+			r.Header.Set("X-Forwarded-For", "192.0.2.2")
+			return s.ParseClientInfo(r).Address
+		}
+
+		require.Equal(t, "192.0.2.1", clientAddress("10.0.0.1:1000"))
+		require.Equal(t, "192.0.2.3", clientAddress("192.0.2.3:1000"))
+	})
+}
@@ -0,0 +1,259 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protojson"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/utils"
+
+	"github.com/livekit/livekit-server/pkg/audit"
+	"github.com/livekit/livekit-server/pkg/rpc"
+)
+
+const (
+	requestIDHeader = "X-Request-ID"
+	requestIDPrefix = "RQ_"
+
+	auditServiceRoom        = "RoomService"
+	auditServiceWaitingRoom = "WaitingRoom"
+	auditServiceEgress      = "Egress"
+	auditServiceIngress     = "Ingress"
+	auditServiceCapture     = "Capture"
+	auditServiceRTPIngest   = "RTPIngest"
+	auditServiceConfig      = "Config"
+
+	adminPathPrefix = "/admin/"
+)
+
+type auditRequestKey struct{}
+
+type auditRequest struct {
+	requestID string
+	sourceIP  string
+}
+
+// AuditMiddleware identifies Twirp and admin requests, for operations made in them to be audited with their request ID
+// and source IP. IDs of requests that carry X-Request-ID are kept, others are given one
+type AuditMiddleware struct {
+	proxies *TrustedProxies
+}
+
+func NewAuditMiddleware(proxies *TrustedProxies) *AuditMiddleware {
+	return &AuditMiddleware{proxies: proxies}
+}
+
+func (m *AuditMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	if !strings.HasPrefix(r.URL.Path, twirpPathPrefix) && !strings.HasPrefix(r.URL.Path, adminPathPrefix) {
+		next(w, r)
+		return
+	}
+
+	requestID := r.Header.Get(requestIDHeader)
+	if requestID == "" {
+		requestID = utils.NewGuid(requestIDPrefix)
+	}
+	w.Header().Set(requestIDHeader, requestID)
+
+	ctx := context.WithValue(r.Context(), auditRequestKey{}, &auditRequest{
+		requestID: requestID,
+		sourceIP:  m.proxies.ClientIP(r),
+	})
+	next(w, r.WithContext(ctx))
+}
+
+// auditOperation records an operation of service made with req, along with its result. Operations that failed,
+// including ones that were not permitted, are recorded with their error
+func auditOperation(ctx context.Context, logger *audit.Logger, service string, operation string, req interface{}, res interface{}, err error) {
+	if logger == nil {
+		return
+	}
+
+	record := &audit.Record{
+		Service:   service,
+		Operation: operation,
+		APIKey:    GetAPIKey(ctx),
+		Details:   make(map[string]string),
+	}
+	if ar, ok := ctx.Value(auditRequestKey{}).(*auditRequest); ok {
+		record.RequestID = ar.requestID
+		record.SourceIP = ar.sourceIP
+	}
+	if err != nil {
+		record.Error = err.Error()
+	}
+	describeAuditRequest(record, req)
+	describeAuditResult(record, res)
+	if len(record.Details) == 0 {
+		record.Details = nil
+	}
+
+	logger.Log(record)
+}
+
+// describeAuditRequest sets what an operation is made on, and the values it sets. Payloads, egress outputs and SRTP
+// keys, which may carry credentials, are left out
+func describeAuditRequest(record *audit.Record, req interface{}) {
+	setDetail := func(key string, value string) {
+		if value != "" {
+			record.Details[key] = value
+		}
+	}
+
+	switch r := req.(type) {
+	case *livekit.CreateRoomRequest:
+		record.Room = r.Name
+		if r.EmptyTimeout > 0 {
+			setDetail("empty_timeout", strconv.Itoa(int(r.EmptyTimeout)))
+		}
+		if r.MaxParticipants > 0 {
+			setDetail("max_participants", strconv.Itoa(int(r.MaxParticipants)))
+		}
+		setDetail("node_id", r.NodeId)
+		setDetail("metadata", r.Metadata)
+	case *livekit.DeleteRoomRequest:
+		record.Room = r.Room
+	case *livekit.RoomParticipantIdentity:
+		record.Room = r.Room
+		record.Participant = r.Identity
+	case *livekit.MuteRoomTrackRequest:
+		record.Room = r.Room
+		record.Participant = r.Identity
+		record.TrackID = r.TrackSid
+		setDetail("muted", strconv.FormatBool(r.Muted))
+	case *livekit.UpdateParticipantRequest:
+		record.Room = r.Room
+		record.Participant = r.Identity
+		setDetail("metadata", r.Metadata)
+		setDetail("name", r.Name)
+		if r.Permission != nil {
+			permission, _ := protojson.Marshal(r.Permission)
+			setDetail("permission", string(permission))
+		}
+	case *livekit.UpdateSubscriptionsRequest:
+		record.Room = r.Room
+		record.Participant = r.Identity
+		setDetail("subscribe", strconv.FormatBool(r.Subscribe))
+		setDetail("track_sids", strings.Join(r.TrackSids, ","))
+	case *livekit.SendDataRequest:
+		record.Room = r.Room
+		setDetail("kind", r.Kind.String())
+		setDetail("destination_sids", strings.Join(r.DestinationSids, ","))
+	case *livekit.UpdateRoomMetadataRequest:
+		record.Room = r.Room
+		setDetail("metadata", r.Metadata)
+
+	case *rpc.AdmitParticipantRequest:
+		record.Room = r.Room
+		record.Participant = r.Identity
+		setDetail("metadata", r.Metadata)
+		setDetail("name", r.Name)
+		if r.Permission != nil {
+			permission, _ := protojson.Marshal(r.Permission)
+			setDetail("permission", string(permission))
+		}
+	case *rpc.RejectParticipantRequest:
+		record.Room = r.Room
+		record.Participant = r.Identity
+
+	case *livekit.RoomCompositeEgressRequest:
+		record.Room = r.RoomName
+		setDetail("layout", r.Layout)
+	case *livekit.TrackCompositeEgressRequest:
+		record.Room = r.RoomName
+		setDetail("audio_track_id", r.AudioTrackId)
+		setDetail("video_track_id", r.VideoTrackId)
+	case *livekit.TrackEgressRequest:
+		record.Room = r.RoomName
+		record.TrackID = r.TrackId
+	case *livekit.WebEgressRequest:
+		setDetail("url", r.Url)
+	case *livekit.UpdateLayoutRequest:
+		record.EgressID = r.EgressId
+		setDetail("layout", r.Layout)
+	case *livekit.UpdateStreamRequest:
+		record.EgressID = r.EgressId
+		setDetail("added_outputs", strconv.Itoa(len(r.AddOutputUrls)))
+		setDetail("removed_outputs", strconv.Itoa(len(r.RemoveOutputUrls)))
+	case *livekit.StopEgressRequest:
+		record.EgressID = r.EgressId
+
+	case *livekit.CreateIngressRequest:
+		record.Room = r.RoomName
+		record.Participant = r.ParticipantIdentity
+		setDetail("name", r.Name)
+		setDetail("input_type", r.InputType.String())
+	case *livekit.UpdateIngressRequest:
+		record.IngressID = r.IngressId
+		record.Room = r.RoomName
+		record.Participant = r.ParticipantIdentity
+		setDetail("name", r.Name)
+	case *livekit.DeleteIngressRequest:
+		record.IngressID = r.IngressId
+
+	case *StartCaptureRequest:
+		record.Room = r.Room
+		record.Participant = r.Participant
+		record.TrackID = r.TrackSid
+		setDetail("subscriber", r.Subscriber)
+		setDetail("format", r.Format)
+		if r.Duration > 0 {
+			setDetail("duration", strconv.Itoa(r.Duration))
+		}
+		if r.MaxSize > 0 {
+			setDetail("max_size", strconv.FormatInt(r.MaxSize, 10))
+		}
+	case *StopCaptureRequest:
+		record.Room = r.Room
+		setDetail("capture_id", r.ID)
+
+	case *StartRTPIngestRequest:
+		record.Room = r.Room
+		record.Participant = r.Identity
+		setDetail("name", r.Name)
+		setDetail("tracks", strconv.Itoa(len(r.Tracks)))
+		setDetail("srtp", strconv.FormatBool(r.SRTP || r.SRTPKey != ""))
+		setDetail("source", r.Source)
+	case *StopRTPIngestRequest:
+		record.Room = r.Room
+		setDetail("rtp_ingest_id", r.ID)
+	}
+}
+
+// describeAuditResult sets what an operation created or changed, when the request doesn't tell
+func describeAuditResult(record *audit.Record, res interface{}) {
+	switch r := res.(type) {
+	case *livekit.EgressInfo:
+		if r != nil {
+			record.EgressID = r.EgressId
+			if record.Room == "" {
+				record.Room = r.RoomName
+			}
+		}
+	case *livekit.IngressInfo:
+		if r != nil {
+			record.IngressID = r.IngressId
+			if record.Room == "" {
+				record.Room = r.RoomName
+			}
+		}
+	case *CaptureInfo:
+		if r != nil {
+			record.Details["capture_id"] = r.ID
+		}
+	case *RTPIngestInfo:
+		if r != nil {
+			record.Details["rtp_ingest_id"] = r.ID
+			// port allocated for the session
+			record.Details["address"] = r.Address
+		}
+	case *ConfigReloadResponse:
+		if r != nil && len(r.Changed) > 0 {
+			record.Details["changed"] = strings.Join(r.Changed, ",")
+		}
+	}
+}
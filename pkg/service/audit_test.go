@@ -0,0 +1,138 @@
+package service_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/audit"
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/routing/routingfakes"
+	"github.com/livekit/livekit-server/pkg/service"
+	"github.com/livekit/livekit-server/pkg/service/servicefakes"
+)
+
+type auditSink struct {
+	records []*audit.Record
+}
+
+func (s *auditSink) Write(_ context.Context, record *audit.Record) error {
+	s.records = append(s.records, record)
+	return nil
+}
+
+func (s *auditSink) LastHash(_ context.Context, _ livekit.NodeID) (string, error) {
+	return "", nil
+}
+
+func TestAuditOperations(t *testing.T) {
+	conf, err := config.NewConfig("", true, nil, nil)
+	require.NoError(t, err)
+	conf.Audit.Enabled = true
+	sink := &auditSink{}
+	logger, err := audit.NewLogger(conf, "node", "secret", sink)
+	require.NoError(t, err)
+
+	store := &servicefakes.FakeServiceStore{}
+	store.LoadRoomReturns(nil, nil, service.ErrRoomNotFound)
+	svc, err := service.NewRoomService(config.RoomConfig{}, &routingfakes.FakeRouter{}, &servicefakes.FakeRoomAllocator{}, store, nil, nil, logger)
+	require.NoError(t, err)
+
+	serve := func(requestID string, f func(ctx context.Context)) {
+		r := httptest.NewRequest("POST", "/twirp/livekit.RoomService/DeleteRoom", nil).
+			WithContext(tenantContext(t, "key-a", ""))
+		r.RemoteAddr = "10.0.0.1:1000"
+		// not set by a trusted proxy
+		r.Header.Set("X-Forwarded-For", "192.0.2.1")
+		if requestID != "" {
+			r.Header.Set("X-Request-ID", requestID)
+		}
+		w := httptest.NewRecorder()
+		service.NewAuditMiddleware(nil).ServeHTTP(w, r, func(w http.ResponseWriter, r *http.Request) {
+			f(r.Context())
+		})
+		require.NotEmpty(t, w.Header().Get("X-Request-ID"))
+	}
+
+	serve("request", func(ctx context.Context) {
+		_, err := svc.DeleteRoom(ctx, &livekit.DeleteRoomRequest{Room: "room"})
+		require.NoError(t, err)
+	})
+	serve("", func(ctx context.Context) {
+		ctx = service.WithGrants(ctx, &auth.ClaimGrants{Video: &auth.VideoGrant{}})
+		_, err := svc.MutePublishedTrack(ctx, &livekit.MuteRoomTrackRequest{Room: "room", Identity: "p", TrackSid: "TR_1", Muted: true})
+		require.Error(t, err)
+	})
+	logger.Stop()
+
+	require.Len(t, sink.records, 2)
+	deleted := sink.records[0]
+	require.Equal(t, "RoomService", deleted.Service)
+	require.Equal(t, "DeleteRoom", deleted.Operation)
+	require.Equal(t, "key-a", deleted.APIKey)
+	require.Equal(t, "10.0.0.1", deleted.SourceIP)
+	require.Equal(t, "request", deleted.RequestID)
+	require.Equal(t, "room", deleted.Room)
+	require.Empty(t, deleted.Error)
+
+	// operations that are not permitted are recorded as well
+	muted := sink.records[1]
+	require.Equal(t, "MutePublishedTrack", muted.Operation)
+	require.True(t, strings.HasPrefix(muted.RequestID, "RQ_"))
+	require.Equal(t, "p", muted.Participant)
+	require.Equal(t, "TR_1", muted.TrackID)
+	require.Equal(t, "true", muted.Details["muted"])
+	require.NotEmpty(t, muted.Error)
+
+	require.NoError(t, audit.Verify(sink.records, "secret"))
+}
+
+func TestAuditAdminOperations(t *testing.T) {
+	conf, err := config.NewConfig("", true, nil, nil)
+	require.NoError(t, err)
+	conf.Audit.Enabled = true
+	conf.Capture.Dir = t.TempDir()
+	sink := &auditSink{}
+	logger, err := audit.NewLogger(conf, "node", "secret", sink)
+	require.NoError(t, err)
+
+	serve := func(handler http.Handler, path string, body string) int {
+		r := httptest.NewRequest("POST", path, strings.NewReader(body)).WithContext(tenantContext(t, "key-a", ""))
+		r.RemoteAddr = "10.0.0.1:1000"
+		w := httptest.NewRecorder()
+		service.NewAuditMiddleware(nil).ServeHTTP(w, r, handler.ServeHTTP)
+		return w.Code
+	}
+
+	// not permitted, without room admin grants or an admin key
+	captureService := service.NewCaptureService(conf, nil, logger)
+	require.Equal(t, http.StatusUnauthorized, serve(captureService, "/admin/capture/start",
+		`{"room":"room","participant":"p","track_sid":"TR_1","format":"pcap"}`))
+	reloader := service.NewConfigReloader(conf, nil, nil, nil, nil, logger)
+	require.Equal(t, http.StatusUnauthorized, serve(reloader, "/admin/config/reload", ""))
+	logger.Stop()
+
+	require.Len(t, sink.records, 2)
+	started := sink.records[0]
+	require.Equal(t, "Capture", started.Service)
+	require.Equal(t, "StartCapture", started.Operation)
+	require.Equal(t, "key-a", started.APIKey)
+	require.Equal(t, "10.0.0.1", started.SourceIP)
+	require.True(t, strings.HasPrefix(started.RequestID, "RQ_"))
+	require.Equal(t, "room", started.Room)
+	require.Equal(t, "TR_1", started.TrackID)
+	require.Equal(t, "pcap", started.Details["format"])
+	require.NotEmpty(t, started.Error)
+
+	reloaded := sink.records[1]
+	require.Equal(t, "Config", reloaded.Service)
+	require.Equal(t, "ReloadConfig", reloaded.Operation)
+	require.NotEmpty(t, reloaded.Error)
+}
@@ -5,11 +5,16 @@ import (
 	"errors"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/twitchtv/twirp"
+	"gopkg.in/square/go-jose.v2/jwt"
 
 	"github.com/livekit/protocol/auth"
 	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/rtc"
+	"github.com/livekit/livekit-server/pkg/telemetry/prometheus"
 )
 
 const (
@@ -20,6 +25,48 @@ const (
 
 type grantsKey struct{}
 
+type apiKeyKey struct{}
+
+type maxSubscribeBitrateKey struct{}
+
+type maxSessionDurationKey struct{}
+
+type dataTopicsKey struct{}
+
+type roomOptionsKey struct{}
+
+type tenantClaimKey struct{}
+
+// claims that are not part of auth.ClaimGrants
+type extendedClaims struct {
+	Video *extendedVideoGrant `json:"video,omitempty"`
+	// tenant the token acts for, only for API keys allowed to act for any tenant
+	Tenant string `json:"tenant,omitempty"`
+}
+
+// video grant claims that are not part of auth.VideoGrant
+type extendedVideoGrant struct {
+	// limit of downstream bitrate for the participant, in bps
+	MaxSubscribeBitrate int64 `json:"maxSubscribeBitrate,omitempty"`
+	// limit of the session of the participant, in seconds
+	MaxSessionDuration int64 `json:"maxSessionDuration,omitempty"`
+	// data topics the participant receives packets on, taking precedence over topics declared in its metadata
+	DataTopics []string `json:"dataTopics,omitempty"`
+	// options of rooms created with the token, only for tokens that can create rooms
+	RoomOptions *roomOptionsGrant `json:"roomOptions,omitempty"`
+}
+
+type roomOptionsGrant struct {
+	// limit of downstream bitrate for each participant of the room, in bps
+	MaxSubscribeBitrate int64 `json:"maxSubscribeBitrate,omitempty"`
+	// forward only the loudest audio tracks to each participant of the room
+	MaxForwardedAudioTracks int `json:"maxForwardedAudioTracks,omitempty"`
+	// limit of how long the room may run once a participant joined it, in seconds
+	MaxDuration int64 `json:"maxDuration,omitempty"`
+	// limit of how long sessions of participants of the room may run, in seconds
+	MaxParticipantDuration int64 `json:"maxParticipantDuration,omitempty"`
+}
+
 var (
 	ErrPermissionDenied          = errors.New("permissions denied")
 	ErrMissingAuthorization      = errors.New("invalid authorization header. Must start with " + bearerPrefix)
@@ -66,19 +113,50 @@ func (m *APIKeyAuthMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request,
 
 		secret := m.provider.GetSecret(v.APIKey())
 		if secret == "" {
-			handleError(w, http.StatusUnauthorized, errors.New("invalid API key: "+v.APIKey()))
+			status := KeyStatusUnknown
+			if p, ok := m.provider.(*KeyProvider); ok {
+				status = p.GetKeyStatus(v.APIKey())
+			}
+			if status == KeyStatusUnknown {
+				// keys that are not known are not labeled, to keep cardinality bounded
+				prometheus.IncrementAPIKeyRequest("", string(status))
+				handleError(w, http.StatusUnauthorized, errors.New("invalid API key: "+v.APIKey()))
+			} else {
+				prometheus.IncrementAPIKeyRequest(v.APIKey(), string(status))
+				handleError(w, http.StatusUnauthorized, errors.New("API key "+string(status)+": "+v.APIKey()))
+			}
 			return
 		}
 
 		grants, err := v.Verify(secret)
 		if err != nil {
+			prometheus.IncrementAPIKeyRequest(v.APIKey(), "invalid_token")
 			handleError(w, http.StatusUnauthorized, errors.New("invalid token: "+authToken+", error: "+err.Error()))
 			return
 		}
+		prometheus.IncrementAPIKeyRequest(v.APIKey(), "success")
 
 		// set grants in context
-		ctx := r.Context()
-		r = r.WithContext(context.WithValue(ctx, grantsKey{}, grants))
+		ctx := context.WithValue(r.Context(), grantsKey{}, grants)
+		ctx = context.WithValue(ctx, apiKeyKey{}, v.APIKey())
+		if claims := parseExtendedClaims(authToken); claims != nil {
+			if video := claims.Video; video != nil && video.MaxSubscribeBitrate > 0 {
+				ctx = context.WithValue(ctx, maxSubscribeBitrateKey{}, video.MaxSubscribeBitrate)
+			}
+			if video := claims.Video; video != nil && video.MaxSessionDuration > 0 {
+				ctx = context.WithValue(ctx, maxSessionDurationKey{}, time.Duration(video.MaxSessionDuration)*time.Second)
+			}
+			if video := claims.Video; video != nil && len(video.DataTopics) > 0 {
+				ctx = context.WithValue(ctx, dataTopicsKey{}, video.DataTopics)
+			}
+			if video := claims.Video; video != nil && video.RoomOptions != nil && grants.Video != nil && grants.Video.RoomCreate {
+				ctx = context.WithValue(ctx, roomOptionsKey{}, video.RoomOptions.toRoomOptions())
+			}
+			if claims.Tenant != "" {
+				ctx = context.WithValue(ctx, tenantClaimKey{}, claims.Tenant)
+			}
+		}
+		r = r.WithContext(ctx)
 	}
 
 	next.ServeHTTP(w, r)
@@ -93,6 +171,73 @@ func GetGrants(ctx context.Context) *auth.ClaimGrants {
 	return claims
 }
 
+// GetAPIKey returns the API key the request was authenticated with
+func GetAPIKey(ctx context.Context) string {
+	apiKey, _ := ctx.Value(apiKeyKey{}).(string)
+	return apiKey
+}
+
+// GetMaxSubscribeBitrate returns the downstream bitrate limit granted by the token, 0 if unlimited
+func GetMaxSubscribeBitrate(ctx context.Context) int64 {
+	maxSubscribeBitrate, _ := ctx.Value(maxSubscribeBitrateKey{}).(int64)
+	return maxSubscribeBitrate
+}
+
+// GetMaxSessionDuration returns the session duration limit granted by the token, 0 if unlimited
+func GetMaxSessionDuration(ctx context.Context) time.Duration {
+	maxSessionDuration, _ := ctx.Value(maxSessionDurationKey{}).(time.Duration)
+	return maxSessionDuration
+}
+
+// GetDataTopics returns data topics granted by the token, nil when it doesn't grant any
+func GetDataTopics(ctx context.Context) []string {
+	dataTopics, _ := ctx.Value(dataTopicsKey{}).([]string)
+	return dataTopics
+}
+
+// GetRoomOptions returns options granted to rooms created by the token, nil when it doesn't grant any
+func GetRoomOptions(ctx context.Context) *rtc.RoomOptions {
+	options, _ := ctx.Value(roomOptionsKey{}).(*rtc.RoomOptions)
+	return options
+}
+
+// GetTenantClaim returns the tenant named by the token, to be honored only for API keys acting for any tenant
+func GetTenantClaim(ctx context.Context) string {
+	tenant, _ := ctx.Value(tenantClaimKey{}).(string)
+	return tenant
+}
+
+func (g *roomOptionsGrant) toRoomOptions() *rtc.RoomOptions {
+	options := &rtc.RoomOptions{}
+	if g.MaxSubscribeBitrate > 0 {
+		options.MaxSubscribeBitrate = g.MaxSubscribeBitrate
+	}
+	if g.MaxForwardedAudioTracks > 0 {
+		options.MaxForwardedAudioTracks = g.MaxForwardedAudioTracks
+	}
+	if g.MaxDuration > 0 {
+		options.MaxDuration = time.Duration(g.MaxDuration) * time.Second
+	}
+	if g.MaxParticipantDuration > 0 {
+		options.MaxParticipantDuration = time.Duration(g.MaxParticipantDuration) * time.Second
+	}
+	return options
+}
+
+// parseExtendedClaims reads claims that are not part of auth.ClaimGrants from an already verified token
+func parseExtendedClaims(authToken string) *extendedClaims {
+	tok, err := jwt.ParseSigned(authToken)
+	if err != nil {
+		return nil
+	}
+
+	claims := &extendedClaims{}
+	if err = tok.UnsafeClaimsWithoutVerification(claims); err != nil {
+		return nil
+	}
+	return claims
+}
+
 func WithGrants(ctx context.Context, grants *auth.ClaimGrants) context.Context {
 	return context.WithValue(ctx, grantsKey{}, grants)
 }
@@ -4,15 +4,24 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
+	"gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
 
 	"github.com/livekit/protocol/auth"
 	"github.com/livekit/protocol/auth/authfakes"
 
+	"github.com/livekit/livekit-server/pkg/rtc"
 	"github.com/livekit/livekit-server/pkg/service"
+	"github.com/livekit/livekit-server/pkg/telemetry/prometheus"
 )
 
+func init() {
+	prometheus.Init("test")
+}
+
 func TestAuthMiddleware(t *testing.T) {
 	api := "APIabcdefg"
 	secret := "somesecretencodedinbase62"
@@ -58,3 +67,97 @@ func TestAuthMiddleware(t *testing.T) {
 	require.Nil(t, grants)
 	require.Equal(t, http.StatusUnauthorized, w.Code)
 }
+
+func TestAuthMiddleware_MaxSubscribeBitrate(t *testing.T) {
+	api := "APIabcdefg"
+	secret := "somesecretencodedinbase62"
+	provider := &authfakes.FakeKeyProvider{}
+	provider.GetSecretReturns(secret)
+
+	m := service.NewAPIKeyAuthMiddleware(provider)
+	var maxSubscribeBitrate int64
+	var maxSessionDuration time.Duration
+	var dataTopics []string
+	var roomOptions *rtc.RoomOptions
+	var grants *auth.ClaimGrants
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		grants = service.GetGrants(r.Context())
+		maxSubscribeBitrate = service.GetMaxSubscribeBitrate(r.Context())
+		maxSessionDuration = service.GetMaxSessionDuration(r.Context())
+		dataTopics = service.GetDataTopics(r.Context())
+		roomOptions = service.GetRoomOptions(r.Context())
+		w.WriteHeader(http.StatusOK)
+	})
+
+	sig, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.HS256, Key: []byte(secret)},
+		(&jose.SignerOptions{}).WithType("JWT"))
+	require.NoError(t, err)
+	token, err := jwt.Signed(sig).
+		Claims(jwt.Claims{
+			Issuer:    api,
+			Subject:   "participant",
+			NotBefore: jwt.NewNumericDate(time.Now()),
+			Expiry:    jwt.NewNumericDate(time.Now().Add(time.Minute)),
+		}).
+		Claims(map[string]interface{}{
+			"video": map[string]interface{}{
+				"room":                "abcdefg",
+				"roomJoin":            true,
+				"maxSubscribeBitrate": 1000000,
+				"maxSessionDuration":  600,
+				"dataTopics":          []string{"chat"},
+				"roomOptions":         map[string]interface{}{"maxSubscribeBitrate": 500000},
+			},
+		}).
+		CompactSerialize()
+	require.NoError(t, err)
+
+	r := &http.Request{Header: http.Header{}}
+	service.SetAuthorizationToken(r, token)
+	m.ServeHTTP(httptest.NewRecorder(), r, handler)
+	require.NotNil(t, grants)
+	require.True(t, grants.Video.RoomJoin)
+	require.Equal(t, int64(1000000), maxSubscribeBitrate)
+	require.Equal(t, 10*time.Minute, maxSessionDuration)
+	require.Equal(t, []string{"chat"}, dataTopics)
+	// options of rooms are only taken from tokens that can create them
+	require.Nil(t, roomOptions)
+
+	token, err = jwt.Signed(sig).
+		Claims(jwt.Claims{
+			Issuer:    api,
+			NotBefore: jwt.NewNumericDate(time.Now()),
+			Expiry:    jwt.NewNumericDate(time.Now().Add(time.Minute)),
+		}).
+		Claims(map[string]interface{}{
+			"video": map[string]interface{}{
+				"roomCreate": true,
+				"roomOptions": map[string]interface{}{
+					"maxSubscribeBitrate":    500000,
+					"maxDuration":            3600,
+					"maxParticipantDuration": 600,
+				},
+			},
+		}).
+		CompactSerialize()
+	require.NoError(t, err)
+	r = &http.Request{Header: http.Header{}}
+	service.SetAuthorizationToken(r, token)
+	m.ServeHTTP(httptest.NewRecorder(), r, handler)
+	require.Equal(t, &rtc.RoomOptions{
+		MaxSubscribeBitrate:    500000,
+		MaxDuration:            time.Hour,
+		MaxParticipantDuration: 10 * time.Minute,
+	}, roomOptions)
+
+	// not granted
+	token, err = auth.NewAccessToken(api, secret).AddGrant(&auth.VideoGrant{Room: "abcdefg", RoomJoin: true}).ToJWT()
+	require.NoError(t, err)
+	r = &http.Request{Header: http.Header{}}
+	service.SetAuthorizationToken(r, token)
+	m.ServeHTTP(httptest.NewRecorder(), r, handler)
+	require.Equal(t, int64(0), maxSubscribeBitrate)
+	require.Zero(t, maxSessionDuration)
+	require.Nil(t, dataTopics)
+	require.Nil(t, roomOptions)
+}
@@ -0,0 +1,365 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+	"github.com/livekit/protocol/utils"
+
+	"github.com/livekit/livekit-server/pkg/audit"
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/rtc"
+	"github.com/livekit/livekit-server/pkg/sfu/capture"
+)
+
+const (
+	CapturePrefix = "CP_"
+
+	capturePathPrefix = "/admin/capture"
+
+	// completed captures are listed for this long, for their files to be found
+	completedCaptureRetention = 10 * time.Minute
+)
+
+type StartCaptureRequest struct {
+	Room string `json:"room"`
+	// identity of the publisher
+	Participant string `json:"participant"`
+	TrackSid    string `json:"track_sid"`
+	// when set, packets forwarded to this participant are captured instead of ones received from the publisher
+	Subscriber string `json:"subscriber,omitempty"`
+	Format     string `json:"format,omitempty"`
+	// in seconds, capped by the configured max_duration
+	Duration int   `json:"duration,omitempty"`
+	MaxSize  int64 `json:"max_size,omitempty"`
+}
+
+type StopCaptureRequest struct {
+	Room string `json:"room"`
+	ID   string `json:"id"`
+}
+
+type CaptureInfo struct {
+	ID          string `json:"id"`
+	Room        string `json:"room"`
+	Participant string `json:"participant"`
+	TrackSid    string `json:"track_sid"`
+	Subscriber  string `json:"subscriber,omitempty"`
+	Format      string `json:"format"`
+	Path        string `json:"path"`
+	StartedAt   int64  `json:"started_at"`
+	Active      bool   `json:"active"`
+	Packets     int64  `json:"packets"`
+	Bytes       int64  `json:"bytes"`
+	Dropped     int64  `json:"dropped"`
+}
+
+type trackCapture struct {
+	info   CaptureInfo
+	key    string
+	writer *capture.Writer
+}
+
+func (c *trackCapture) ToInfo() CaptureInfo {
+	info := c.info
+	stats := c.writer.Stats()
+	info.Packets = stats.Packets
+	info.Bytes = stats.Bytes
+	info.Dropped = stats.Dropped
+	select {
+	case <-c.writer.Done():
+	default:
+		info.Active = true
+	}
+	return info
+}
+
+// CaptureService records packets of tracks hosted on this node to disk, so that problem streams
+// can be inspected and replayed offline. Captures are requested by room admins.
+type CaptureService struct {
+	conf        config.CaptureConfig
+	roomManager *RoomManager
+	auditLogger *audit.Logger
+
+	lock sync.Mutex
+	// captures in progress and recently completed ones
+	captures map[string]*trackCapture
+	// track and subscriber => capture ID, one capture at a time for each
+	active map[string]string
+}
+
+func NewCaptureService(conf *config.Config, roomManager *RoomManager, auditLogger *audit.Logger) *CaptureService {
+	return &CaptureService{
+		conf:        conf.Capture,
+		roomManager: roomManager,
+		auditLogger: auditLogger,
+		captures:    make(map[string]*trackCapture),
+		active:      make(map[string]string),
+	}
+}
+
+func (s *CaptureService) PathPrefix() string {
+	return capturePathPrefix
+}
+
+func (s *CaptureService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if s.conf.Dir == "" {
+		handleError(w, http.StatusNotFound, ErrCaptureDisabled)
+		return
+	}
+
+	switch {
+	case r.URL.Path == capturePathPrefix+"/start" && r.Method == http.MethodPost:
+		s.handleStart(w, r)
+	case r.URL.Path == capturePathPrefix+"/stop" && r.Method == http.MethodPost:
+		s.handleStop(w, r)
+	case r.URL.Path == capturePathPrefix && r.Method == http.MethodGet:
+		s.handleList(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *CaptureService) handleStart(w http.ResponseWriter, r *http.Request) {
+	req := &StartCaptureRequest{}
+	var info *CaptureInfo
+	var err error
+	defer func() {
+		auditOperation(r.Context(), s.auditLogger, auditServiceCapture, "StartCapture", req, info, err)
+	}()
+
+	if err = json.NewDecoder(r.Body).Decode(req); err != nil {
+		handleError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err = EnsureAdminPermission(r.Context(), livekit.RoomName(req.Room)); err != nil {
+		handleError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	info, err = s.StartCapture(r.Context(), req)
+	if err != nil {
+		status := http.StatusBadRequest
+		switch err {
+		case ErrRoomNotFound, ErrParticipantNotFound, ErrTrackNotFound:
+			status = http.StatusNotFound
+		case ErrCaptureInProgress:
+			status = http.StatusConflict
+		}
+		handleError(w, status, err, "room", req.Room, "participant", req.Participant, "trackID", req.TrackSid)
+		return
+	}
+	writeJSON(w, info)
+}
+
+func (s *CaptureService) handleStop(w http.ResponseWriter, r *http.Request) {
+	req := &StopCaptureRequest{}
+	var info *CaptureInfo
+	var err error
+	defer func() {
+		auditOperation(r.Context(), s.auditLogger, auditServiceCapture, "StopCapture", req, info, err)
+	}()
+
+	if err = json.NewDecoder(r.Body).Decode(req); err != nil {
+		handleError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err = EnsureAdminPermission(r.Context(), livekit.RoomName(req.Room)); err != nil {
+		handleError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	info, err = s.StopCapture(livekit.RoomName(req.Room), req.ID)
+	if err != nil {
+		handleError(w, http.StatusNotFound, err, "captureID", req.ID)
+		return
+	}
+	writeJSON(w, info)
+}
+
+func (s *CaptureService) handleList(w http.ResponseWriter, r *http.Request) {
+	roomName := livekit.RoomName(r.URL.Query().Get("room"))
+	if err := EnsureAdminPermission(r.Context(), roomName); err != nil {
+		handleError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	writeJSON(w, s.ListCaptures(roomName))
+}
+
+func (s *CaptureService) StartCapture(ctx context.Context, req *StartCaptureRequest) (*CaptureInfo, error) {
+	room := s.roomManager.GetRoom(ctx, livekit.RoomName(req.Room))
+	if room == nil {
+		return nil, ErrRoomNotFound
+	}
+	publisher := room.GetParticipant(livekit.ParticipantIdentity(req.Participant))
+	if publisher == nil {
+		return nil, ErrParticipantNotFound
+	}
+	track, ok := publisher.GetPublishedTrack(livekit.TrackID(req.TrackSid)).(*rtc.MediaTrack)
+	if !ok {
+		return nil, ErrTrackNotFound
+	}
+	var subscriberID livekit.ParticipantID
+	if req.Subscriber != "" {
+		subscriber := room.GetParticipant(livekit.ParticipantIdentity(req.Subscriber))
+		if subscriber == nil {
+			return nil, ErrParticipantNotFound
+		}
+		subscriberID = subscriber.ID()
+	}
+
+	format := capture.Format(req.Format)
+	if format == "" {
+		format = capture.Format(s.conf.Format)
+	}
+	maxDuration := s.conf.MaxDuration
+	if d := time.Duration(req.Duration) * time.Second; d > 0 && (maxDuration <= 0 || d < maxDuration) {
+		maxDuration = d
+	}
+	maxSize := s.conf.MaxSize
+	if req.MaxSize > 0 && (maxSize <= 0 || req.MaxSize < maxSize) {
+		maxSize = req.MaxSize
+	}
+
+	captureID := utils.NewGuid(CapturePrefix)
+	key := fmt.Sprintf("%s|%s", track.ID(), subscriberID)
+
+	s.lock.Lock()
+	if _, ok := s.active[key]; ok {
+		s.lock.Unlock()
+		return nil, ErrCaptureInProgress
+	}
+
+	if err := os.MkdirAll(s.conf.Dir, 0700); err != nil {
+		s.lock.Unlock()
+		return nil, err
+	}
+	writer, err := capture.NewWriter(capture.WriterParams{
+		Path:        filepath.Join(s.conf.Dir, fmt.Sprintf("%s_%s.%s", track.ID(), captureID, format)),
+		Format:      format,
+		MaxDuration: maxDuration,
+		MaxSize:     maxSize,
+		Logger:      logger.Logger(logr.Logger(logger.GetDefaultLogger()).WithValues("captureID", captureID, "trackID", track.ID())),
+	})
+	if err != nil {
+		s.lock.Unlock()
+		return nil, err
+	}
+	if err = track.SetCapture(writer, subscriberID); err != nil {
+		s.lock.Unlock()
+		writer.Close()
+		return nil, err
+	}
+
+	tc := &trackCapture{
+		info: CaptureInfo{
+			ID:          captureID,
+			Room:        req.Room,
+			Participant: req.Participant,
+			TrackSid:    req.TrackSid,
+			Subscriber:  req.Subscriber,
+			Format:      string(format),
+			Path:        writer.Path(),
+			StartedAt:   writer.StartedAt().Unix(),
+		},
+		key:    key,
+		writer: writer,
+	}
+	s.captures[captureID] = tc
+	s.active[key] = captureID
+	s.lock.Unlock()
+
+	track.AddOnClose(writer.Close)
+	writer.OnClose(func() {
+		_ = track.SetCapture(nil, subscriberID)
+
+		s.lock.Lock()
+		if s.active[key] == captureID {
+			delete(s.active, key)
+		}
+		s.lock.Unlock()
+
+		time.AfterFunc(completedCaptureRetention, func() {
+			s.lock.Lock()
+			delete(s.captures, captureID)
+			s.lock.Unlock()
+		})
+	})
+
+	logger.Infow("capture started",
+		"captureID", captureID,
+		"room", req.Room,
+		"participant", req.Participant,
+		"trackID", req.TrackSid,
+		"subscriber", req.Subscriber,
+		"path", writer.Path(),
+	)
+
+	info := tc.ToInfo()
+	return &info, nil
+}
+
+func (s *CaptureService) StopCapture(roomName livekit.RoomName, captureID string) (*CaptureInfo, error) {
+	s.lock.Lock()
+	tc := s.captures[captureID]
+	s.lock.Unlock()
+	if tc == nil || livekit.RoomName(tc.info.Room) != roomName {
+		return nil, ErrCaptureNotFound
+	}
+
+	tc.writer.Close()
+	<-tc.writer.Done()
+
+	info := tc.ToInfo()
+	return &info, nil
+}
+
+// ListCaptures returns captures of a room, including ones completed within completedCaptureRetention
+func (s *CaptureService) ListCaptures(roomName livekit.RoomName) []CaptureInfo {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	captures := make([]CaptureInfo, 0)
+	for _, tc := range s.captures {
+		if livekit.RoomName(tc.info.Room) == roomName {
+			captures = append(captures, tc.ToInfo())
+		}
+	}
+	return captures
+}
+
+// Stop ends all captures in progress, so that files are complete
+func (s *CaptureService) Stop() {
+	s.lock.Lock()
+	captures := make([]*trackCapture, 0, len(s.active))
+	for _, captureID := range s.active {
+		captures = append(captures, s.captures[captureID])
+	}
+	s.lock.Unlock()
+
+	for _, tc := range captures {
+		tc.writer.Close()
+		<-tc.writer.Done()
+	}
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		handleError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(b)
+}
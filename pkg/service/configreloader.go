@@ -0,0 +1,191 @@
+package service
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"sync"
+
+	"github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/logger"
+	"github.com/livekit/protocol/webhook"
+
+	"github.com/livekit/livekit-server/pkg/audit"
+	"github.com/livekit/livekit-server/pkg/config"
+	serverlogger "github.com/livekit/livekit-server/pkg/logger"
+	serverwebhook "github.com/livekit/livekit-server/pkg/webhook"
+)
+
+const configReloadPath = "/admin/config/reload"
+
+type ConfigLoader func() (*config.Config, error)
+
+type ConfigReloadResponse struct {
+	Changed []string `json:"changed"`
+}
+
+// ConfigReloader applies config changes to a running server. Changes are validated as a whole,
+// and rejected when they include settings that can only take effect on restart.
+type ConfigReloader struct {
+	keyProvider   auth.KeyProvider
+	notifier      webhook.Notifier
+	roomAllocator RoomAllocator
+	rtcService    *RTCService
+	auditLogger   *audit.Logger
+
+	lock   sync.Mutex
+	config *config.Config
+	loader ConfigLoader
+}
+
+func NewConfigReloader(
+	conf *config.Config,
+	keyProvider auth.KeyProvider,
+	notifier webhook.Notifier,
+	roomAllocator RoomAllocator,
+	rtcService *RTCService,
+	auditLogger *audit.Logger,
+) *ConfigReloader {
+	return &ConfigReloader{
+		config:        conf,
+		keyProvider:   keyProvider,
+		notifier:      notifier,
+		roomAllocator: roomAllocator,
+		rtcService:    rtcService,
+		auditLogger:   auditLogger,
+	}
+}
+
+// SetLoader sets where config is read from when reloading, typically the same file and flags used at startup
+func (r *ConfigReloader) SetLoader(loader ConfigLoader) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.loader = loader
+}
+
+func (r *ConfigReloader) PathPrefix() string {
+	return configReloadPath
+}
+
+func (r *ConfigReloader) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.NotFound(w, req)
+		return
+	}
+
+	var res *ConfigReloadResponse
+	var err error
+	defer func() {
+		auditOperation(req.Context(), r.auditLogger, auditServiceConfig, "ReloadConfig", nil, res, err)
+	}()
+
+	if !r.isAdminKey(GetAPIKey(req.Context())) {
+		err = ErrPermissionDenied
+		handleError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	changed, err := r.Reload()
+	if err != nil {
+		status := http.StatusBadRequest
+		var restartErr *config.RestartRequiredError
+		if errors.As(err, &restartErr) {
+			status = http.StatusConflict
+		}
+		handleError(w, status, err)
+		return
+	}
+	res = &ConfigReloadResponse{Changed: changed}
+	writeJSON(w, res)
+}
+
+// isAdminKey returns true when tokens signed with apiKey may reload config
+func (r *ConfigReloader) isAdminKey(apiKey string) bool {
+	if apiKey == "" {
+		return false
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	for _, key := range r.config.AdminKeys {
+		if key == apiKey {
+			return true
+		}
+	}
+	return false
+}
+
+// Reload reads config through the loader and applies it, returning settings that have changed
+func (r *ConfigReloader) Reload() ([]string, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.loader == nil {
+		return nil, ErrConfigReloadUnavailable
+	}
+	updated, err := r.loader()
+	if err != nil {
+		return nil, err
+	}
+	return r.apply(updated)
+}
+
+// Apply validates and applies updated, returning settings that have changed
+func (r *ConfigReloader) Apply(updated *config.Config) ([]string, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	return r.apply(updated)
+}
+
+func (r *ConfigReloader) apply(updated *config.Config) ([]string, error) {
+	// resolved at startup, not meant to be compared
+	if updated.RTC.NodeIPAutoGenerated && r.config.RTC.NodeIPAutoGenerated {
+		updated.RTC.NodeIP = r.config.RTC.NodeIP
+	}
+
+	changed, err := r.config.CheckReload(updated)
+	if err != nil {
+		logger.Warnw("config reload rejected", err, "changed", changed)
+		return nil, err
+	}
+	if len(changed) == 0 {
+		logger.Infow("config reloaded, nothing changed")
+		return changed, nil
+	}
+
+	// validate everything before applying any of it
+	notifier, _ := r.notifier.(*serverwebhook.QueuedNotifier)
+	var endpoints []serverwebhook.Endpoint
+	if !reflect.DeepEqual(r.config.WebHook, updated.WebHook) {
+		if endpoints, err = webhookEndpoints(updated.WebHook, r.keyProvider); err != nil {
+			return nil, err
+		}
+		if notifier == nil && len(endpoints) > 0 {
+			// notifier is only created when webhooks are configured at startup
+			return nil, &config.RestartRequiredError{Settings: []string{"webhook.urls"}}
+		}
+	}
+
+	if allocator, ok := r.roomAllocator.(*StandardRoomAllocator); ok {
+		if err = allocator.UpdateConfig(updated); err != nil {
+			return nil, err
+		}
+	}
+	r.rtcService.SetLimits(updated.Limit)
+	if keyProvider, ok := r.keyProvider.(*KeyProvider); ok && !reflect.DeepEqual(r.config.Keys, updated.Keys) {
+		keyProvider.SetKeys(updated.Keys)
+	}
+	if notifier != nil && !reflect.DeepEqual(r.config.WebHook, updated.WebHook) {
+		notifier.UpdateConfig(endpoints, updated.WebHook)
+	}
+	if !reflect.DeepEqual(r.config.Logging, updated.Logging) {
+		serverlogger.InitFromConfig(updated.Logging)
+	}
+
+	r.config = updated
+	logger.Infow("config reloaded", "changed", changed)
+	return changed, nil
+}
@@ -0,0 +1,136 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/routing"
+	"github.com/livekit/livekit-server/pkg/routing/routingfakes"
+	"github.com/livekit/livekit-server/pkg/routing/selector"
+	"github.com/livekit/livekit-server/pkg/service"
+	"github.com/livekit/livekit-server/pkg/service/servicefakes"
+)
+
+func TestConfigReloader(t *testing.T) {
+	newReloader := func(t *testing.T, adminKeys ...string) (*service.ConfigReloader, service.RoomAllocator) {
+		conf, err := config.NewConfig("", true, nil, nil)
+		require.NoError(t, err)
+		conf.AdminKeys = adminKeys
+
+		node, err := routing.NewLocalNode(conf)
+		require.NoError(t, err)
+		node.Stats.NumTracksIn = 100
+		node.Stats.NumTracksOut = 100
+
+		ra, conf := newTestRoomAllocator(t, conf, node)
+		rtcService := service.NewRTCService(conf, ra, &servicefakes.FakeServiceStore{}, &routingfakes.FakeRouter{}, node, nil, nil, nil)
+		keyProvider := auth.NewFileBasedKeyProviderFromMap(map[string]string{"key": "secret"})
+		return service.NewConfigReloader(conf, keyProvider, nil, ra, rtcService, nil), ra
+	}
+
+	t.Run("applies limits", func(t *testing.T) {
+		reloader, ra := newReloader(t)
+		_, err := ra.CreateRoom(context.Background(), &livekit.CreateRoomRequest{Name: "room1"})
+		require.NoError(t, err)
+
+		updated, err := config.NewConfig("limit:\n  num_tracks: 10", true, nil, nil)
+		require.NoError(t, err)
+		changed, err := reloader.Apply(updated)
+		require.NoError(t, err)
+		require.Equal(t, []string{"limit.num_tracks"}, changed)
+
+		_, err = ra.CreateRoom(context.Background(), &livekit.CreateRoomRequest{Name: "room1"})
+		require.ErrorIs(t, err, routing.ErrNodeLimitReached)
+	})
+
+	t.Run("rejects settings that require a restart", func(t *testing.T) {
+		reloader, ra := newReloader(t)
+
+		updated, err := config.NewConfig("port: 7890\nlimit:\n  num_tracks: 10", true, nil, nil)
+		require.NoError(t, err)
+		_, err = reloader.Apply(updated)
+		var restartErr *config.RestartRequiredError
+		require.True(t, errors.As(err, &restartErr))
+		require.Equal(t, []string{"port"}, restartErr.Settings)
+
+		// nothing is applied
+		_, err = ra.CreateRoom(context.Background(), &livekit.CreateRoomRequest{Name: "room1"})
+		require.NoError(t, err)
+	})
+
+	t.Run("rejects invalid node selector", func(t *testing.T) {
+		reloader, _ := newReloader(t)
+
+		updated, err := config.NewConfig("node_selector:\n  kind: unknown", true, nil, nil)
+		require.NoError(t, err)
+		_, err = reloader.Apply(updated)
+		require.ErrorIs(t, err, selector.ErrUnsupportedSelector)
+	})
+
+	t.Run("webhooks can't be enabled without restart", func(t *testing.T) {
+		reloader, _ := newReloader(t)
+
+		updated, err := config.NewConfig("webhook:\n  api_key: key\n  urls:\n    - http://localhost:8080", true, nil, nil)
+		require.NoError(t, err)
+		_, err = reloader.Apply(updated)
+		var restartErr *config.RestartRequiredError
+		require.True(t, errors.As(err, &restartErr))
+	})
+
+	t.Run("rejects invalid webhook endpoints", func(t *testing.T) {
+		reloader, _ := newReloader(t)
+
+		updated, err := config.NewConfig("webhook:\n  endpoints:\n    - url: http://localhost:8080\n      api_key: unknown", true, nil, nil)
+		require.NoError(t, err)
+		_, err = reloader.Apply(updated)
+		require.ErrorIs(t, err, service.ErrWebHookMissingAPIKey)
+
+		updated, err = config.NewConfig(`webhook:
+  api_key: key
+  urls:
+    - http://localhost:8080
+  endpoints:
+    - url: http://localhost:8080
+      events:
+        - room_finished`, true, nil, nil)
+		require.NoError(t, err)
+		_, err = reloader.Apply(updated)
+		require.ErrorIs(t, err, service.ErrWebHookDuplicateURL)
+	})
+
+	t.Run("endpoint requires an admin key", func(t *testing.T) {
+		reloader, _ := newReloader(t, "admin")
+		reloader.SetLoader(func() (*config.Config, error) {
+			conf, err := config.NewConfig("", true, nil, nil)
+			if err == nil {
+				conf.AdminKeys = []string{"admin"}
+			}
+			return conf, err
+		})
+
+		reload := func(apiKey string) int {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodPost, reloader.PathPrefix(), nil).WithContext(tenantContext(t, apiKey, ""))
+			reloader.ServeHTTP(w, r)
+			return w.Code
+		}
+		// tokens of other keys are rejected, even with roomCreate
+		require.Equal(t, http.StatusUnauthorized, reload("key"))
+		require.Equal(t, http.StatusOK, reload("admin"))
+	})
+
+	t.Run("unavailable without loader", func(t *testing.T) {
+		reloader, _ := newReloader(t)
+		_, err := reloader.Reload()
+		require.ErrorIs(t, err, service.ErrConfigReloadUnavailable)
+	})
+}
@@ -6,8 +6,10 @@ import (
 	"errors"
 	"time"
 
+	"github.com/twitchtv/twirp"
 	"google.golang.org/protobuf/proto"
 
+	"github.com/livekit/livekit-server/pkg/audit"
 	"github.com/livekit/livekit-server/pkg/rtc"
 	"github.com/livekit/protocol/egress"
 	"github.com/livekit/protocol/livekit"
@@ -23,6 +25,9 @@ type EgressService struct {
 	roomService livekit.RoomService
 	telemetry   telemetry.TelemetryService
 	launcher    rtc.EgressLauncher
+	recorder    *TrackRecorder
+	tenancy     *Tenancy
+	auditLogger *audit.Logger
 	shutdown    chan struct{}
 }
 
@@ -30,10 +35,11 @@ type egressLauncher struct {
 	rpcClient egress.RPCClient
 	es        EgressStore
 	telemetry telemetry.TelemetryService
+	recorder  *TrackRecorder
 }
 
-func NewEgressLauncher(rpcClient egress.RPCClient, es EgressStore, ts telemetry.TelemetryService) rtc.EgressLauncher {
-	if rpcClient == nil {
+func NewEgressLauncher(rpcClient egress.RPCClient, es EgressStore, ts telemetry.TelemetryService, recorder *TrackRecorder) rtc.EgressLauncher {
+	if rpcClient == nil && !recorder.Enabled() {
 		return nil
 	}
 
@@ -41,6 +47,7 @@ func NewEgressLauncher(rpcClient egress.RPCClient, es EgressStore, ts telemetry.
 		rpcClient: rpcClient,
 		es:        es,
 		telemetry: ts,
+		recorder:  recorder,
 	}
 }
 
@@ -51,6 +58,9 @@ func NewEgressService(
 	rs livekit.RoomService,
 	ts telemetry.TelemetryService,
 	launcher rtc.EgressLauncher,
+	recorder *TrackRecorder,
+	tenancy *Tenancy,
+	auditLogger *audit.Logger,
 ) *EgressService {
 	return &EgressService{
 		rpcClient:   rpcClient,
@@ -59,6 +69,9 @@ func NewEgressService(
 		roomService: rs,
 		telemetry:   ts,
 		launcher:    launcher,
+		recorder:    recorder,
+		tenancy:     tenancy,
+		auditLogger: auditLogger,
 	}
 }
 
@@ -68,6 +81,14 @@ func (s *EgressService) Start() error {
 	}
 
 	s.shutdown = make(chan struct{})
+	if fs, ok := s.es.(*FileStore); ok {
+		// ended egress needs to be cleaned up even when there is no egress worker
+		if err := fs.Start(); err != nil {
+			logger.Errorw("failed to start file store egress worker", err)
+			return err
+		}
+	}
+
 	if s.rpcClient != nil && s.es != nil {
 		return s.startWorker()
 	}
@@ -77,9 +98,16 @@ func (s *EgressService) Start() error {
 
 func (s *EgressService) Stop() {
 	close(s.shutdown)
+	if fs, ok := s.es.(*FileStore); ok {
+		fs.Stop()
+	}
 }
 
-func (s *EgressService) StartRoomCompositeEgress(ctx context.Context, req *livekit.RoomCompositeEgressRequest) (*livekit.EgressInfo, error) {
+func (s *EgressService) StartRoomCompositeEgress(ctx context.Context, req *livekit.RoomCompositeEgressRequest) (info *livekit.EgressInfo, err error) {
+	defer func() {
+		auditOperation(ctx, s.auditLogger, auditServiceEgress, "StartRoomCompositeEgress", req, info, err)
+	}()
+
 	return s.StartEgress(ctx, livekit.RoomName(req.RoomName), &livekit.StartEgressRequest{
 		Request: &livekit.StartEgressRequest_RoomComposite{
 			RoomComposite: req,
@@ -87,7 +115,11 @@ func (s *EgressService) StartRoomCompositeEgress(ctx context.Context, req *livek
 	})
 }
 
-func (s *EgressService) StartTrackCompositeEgress(ctx context.Context, req *livekit.TrackCompositeEgressRequest) (*livekit.EgressInfo, error) {
+func (s *EgressService) StartTrackCompositeEgress(ctx context.Context, req *livekit.TrackCompositeEgressRequest) (info *livekit.EgressInfo, err error) {
+	defer func() {
+		auditOperation(ctx, s.auditLogger, auditServiceEgress, "StartTrackCompositeEgress", req, info, err)
+	}()
+
 	return s.StartEgress(ctx, livekit.RoomName(req.RoomName), &livekit.StartEgressRequest{
 		Request: &livekit.StartEgressRequest_TrackComposite{
 			TrackComposite: req,
@@ -95,7 +127,11 @@ func (s *EgressService) StartTrackCompositeEgress(ctx context.Context, req *live
 	})
 }
 
-func (s *EgressService) StartTrackEgress(ctx context.Context, req *livekit.TrackEgressRequest) (*livekit.EgressInfo, error) {
+func (s *EgressService) StartTrackEgress(ctx context.Context, req *livekit.TrackEgressRequest) (info *livekit.EgressInfo, err error) {
+	defer func() {
+		auditOperation(ctx, s.auditLogger, auditServiceEgress, "StartTrackEgress", req, info, err)
+	}()
+
 	return s.StartEgress(ctx, livekit.RoomName(req.RoomName), &livekit.StartEgressRequest{
 		Request: &livekit.StartEgressRequest_Track{
 			Track: req,
@@ -103,7 +139,11 @@ func (s *EgressService) StartTrackEgress(ctx context.Context, req *livekit.Track
 	})
 }
 
-func (s *EgressService) StartWebEgress(ctx context.Context, req *livekit.WebEgressRequest) (*livekit.EgressInfo, error) {
+func (s *EgressService) StartWebEgress(ctx context.Context, req *livekit.WebEgressRequest) (info *livekit.EgressInfo, err error) {
+	defer func() {
+		auditOperation(ctx, s.auditLogger, auditServiceEgress, "StartWebEgress", req, info, err)
+	}()
+
 	return s.StartEgress(ctx, "", &livekit.StartEgressRequest{
 		Request: &livekit.StartEgressRequest_Web{
 			Web: req,
@@ -119,6 +159,9 @@ func (s *EgressService) StartEgress(ctx context.Context, roomName livekit.RoomNa
 	}
 
 	if roomName != "" {
+		if err := s.tenancy.EnsureRoom(ctx, roomName); err != nil {
+			return nil, twirpTenancyError(err)
+		}
 		room, _, err := s.store.LoadRoom(ctx, roomName, false)
 		if err != nil {
 			return nil, err
@@ -126,10 +169,32 @@ func (s *EgressService) StartEgress(ctx context.Context, roomName livekit.RoomNa
 		req.RoomId = room.Sid
 	}
 
-	return s.launcher.StartEgress(ctx, req)
+	info, err := s.launcher.StartEgress(ctx, req)
+	if errors.Is(err, ErrRecordingRoomNotOnNode) {
+		return nil, twirp.NewError(twirp.FailedPrecondition, err.Error())
+	} else if err != nil {
+		return nil, err
+	}
+	if err = s.tenancy.ClaimEgress(ctx, info); err != nil {
+		logger.Errorw("could not store tenant of egress", err, "egressID", info.EgressId)
+	}
+	return info, nil
 }
 
 func (s *egressLauncher) StartEgress(ctx context.Context, req *livekit.StartEgressRequest) (*livekit.EgressInfo, error) {
+	if s.recorder.CanRecord(req) {
+		if s.recorder.HostsRoom(ctx, livekit.RoomName(req.GetTrack().RoomName)) {
+			return s.recorder.StartEgress(ctx, req)
+		}
+		if s.rpcClient == nil {
+			// without an egress worker, the request has to be made to the node hosting the room
+			return nil, ErrRecordingRoomNotOnNode
+		}
+	}
+	if s.rpcClient == nil {
+		return nil, ErrEgressNotConnected
+	}
+
 	info, err := s.rpcClient.SendRequest(ctx, req)
 	if err != nil {
 		return nil, err
@@ -149,7 +214,11 @@ type LayoutMetadata struct {
 	Layout string `json:"layout"`
 }
 
-func (s *EgressService) UpdateLayout(ctx context.Context, req *livekit.UpdateLayoutRequest) (*livekit.EgressInfo, error) {
+func (s *EgressService) UpdateLayout(ctx context.Context, req *livekit.UpdateLayoutRequest) (info *livekit.EgressInfo, err error) {
+	defer func() {
+		auditOperation(ctx, s.auditLogger, auditServiceEgress, "UpdateLayout", req, info, err)
+	}()
+
 	if err := EnsureRecordPermission(ctx); err != nil {
 		return nil, twirpAuthError(err)
 	}
@@ -157,10 +226,13 @@ func (s *EgressService) UpdateLayout(ctx context.Context, req *livekit.UpdateLay
 		return nil, ErrEgressNotConnected
 	}
 
-	info, err := s.es.LoadEgress(ctx, req.EgressId)
+	info, err = s.es.LoadEgress(ctx, req.EgressId)
 	if err != nil {
 		return nil, err
 	}
+	if err = s.tenancy.EnsureEgress(ctx, info); err != nil {
+		return nil, twirpTenancyError(err)
+	}
 
 	metadata, err := json.Marshal(&LayoutMetadata{Layout: req.Layout})
 	if err != nil {
@@ -183,15 +255,22 @@ func (s *EgressService) UpdateLayout(ctx context.Context, req *livekit.UpdateLay
 	return info, nil
 }
 
-func (s *EgressService) UpdateStream(ctx context.Context, req *livekit.UpdateStreamRequest) (*livekit.EgressInfo, error) {
+func (s *EgressService) UpdateStream(ctx context.Context, req *livekit.UpdateStreamRequest) (info *livekit.EgressInfo, err error) {
+	defer func() {
+		auditOperation(ctx, s.auditLogger, auditServiceEgress, "UpdateStream", req, info, err)
+	}()
+
 	if err := EnsureRecordPermission(ctx); err != nil {
 		return nil, twirpAuthError(err)
 	}
 	if s.rpcClient == nil {
 		return nil, ErrEgressNotConnected
 	}
+	if err := s.ensureEgress(ctx, req.EgressId); err != nil {
+		return nil, err
+	}
 
-	info, err := s.rpcClient.SendRequest(ctx, &livekit.EgressRequest{
+	info, err = s.rpcClient.SendRequest(ctx, &livekit.EgressRequest{
 		EgressId: req.EgressId,
 		Request: &livekit.EgressRequest_UpdateStream{
 			UpdateStream: req,
@@ -214,7 +293,7 @@ func (s *EgressService) ListEgress(ctx context.Context, req *livekit.ListEgressR
 	if err := EnsureRecordPermission(ctx); err != nil {
 		return nil, twirpAuthError(err)
 	}
-	if s.rpcClient == nil {
+	if (s.rpcClient == nil && !s.recorder.Enabled()) || s.es == nil {
 		return nil, ErrEgressNotConnected
 	}
 
@@ -222,19 +301,35 @@ func (s *EgressService) ListEgress(ctx context.Context, req *livekit.ListEgressR
 	if err != nil {
 		return nil, err
 	}
+	if infos, err = s.tenancy.FilterEgress(ctx, infos); err != nil {
+		return nil, err
+	}
 
 	return &livekit.ListEgressResponse{Items: infos}, nil
 }
 
-func (s *EgressService) StopEgress(ctx context.Context, req *livekit.StopEgressRequest) (*livekit.EgressInfo, error) {
+func (s *EgressService) StopEgress(ctx context.Context, req *livekit.StopEgressRequest) (info *livekit.EgressInfo, err error) {
+	defer func() {
+		auditOperation(ctx, s.auditLogger, auditServiceEgress, "StopEgress", req, info, err)
+	}()
+
 	if err := EnsureRecordPermission(ctx); err != nil {
 		return nil, twirpAuthError(err)
 	}
+	if err := s.ensureEgress(ctx, req.EgressId); err != nil {
+		return nil, err
+	}
+	if s.recorder.Enabled() {
+		// recorded on this node
+		if info, err := s.recorder.StopEgress(ctx, req.EgressId); err != ErrEgressNotFound {
+			return info, err
+		}
+	}
 	if s.rpcClient == nil {
 		return nil, ErrEgressNotConnected
 	}
 
-	info, err := s.rpcClient.SendRequest(ctx, &livekit.EgressRequest{
+	info, err = s.rpcClient.SendRequest(ctx, &livekit.EgressRequest{
 		EgressId: req.EgressId,
 		Request: &livekit.EgressRequest_Stop{
 			Stop: req,
@@ -253,11 +348,30 @@ func (s *EgressService) StopEgress(ctx context.Context, req *livekit.StopEgressR
 	return info, nil
 }
 
+// ensureEgress returns an error unless egress is one of the tenant of the request
+func (s *EgressService) ensureEgress(ctx context.Context, egressID string) error {
+	if s.tenancy == nil {
+		return nil
+	}
+
+	info := &livekit.EgressInfo{EgressId: egressID}
+	if s.es != nil {
+		stored, err := s.es.LoadEgress(ctx, egressID)
+		if err == nil {
+			info = stored
+		} else if err != ErrEgressNotFound {
+			return err
+		}
+	}
+	return twirpTenancyError(s.tenancy.EnsureEgress(ctx, info))
+}
+
 func (s *EgressService) startWorker() error {
-	rs := s.es.(*RedisStore)
-	if err := rs.Start(); err != nil {
-		logger.Errorw("failed to start redis egress worker", err)
-		return err
+	if rs, ok := s.es.(*RedisStore); ok {
+		if err := rs.Start(); err != nil {
+			logger.Errorw("failed to start redis egress worker", err)
+			return err
+		}
 	}
 
 	sub, err := s.rpcClient.GetUpdateChannel(context.Background())
@@ -312,7 +426,9 @@ func (s *EgressService) startWorker() error {
 
 			case <-s.shutdown:
 				_ = sub.Close()
-				rs.Stop()
+				if rs, ok := s.es.(*RedisStore); ok {
+					rs.Stop()
+				}
 				return
 			}
 		}
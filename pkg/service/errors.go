@@ -3,17 +3,57 @@ package service
 import "errors"
 
 var (
-	ErrEgressNotFound        = errors.New("egress does not exist")
-	ErrEgressNotConnected    = errors.New("egress not connected (redis required)")
-	ErrIdentityEmpty         = errors.New("identity cannot be empty")
-	ErrIngressNotConnected   = errors.New("ingress not connected (redis required)")
-	ErrIngressNotFound       = errors.New("ingress does not exist")
-	ErrMetadataExceedsLimits = errors.New("metadata size exceeds limits")
-	ErrOperationFailed       = errors.New("operation cannot be completed")
-	ErrParticipantNotFound   = errors.New("participant does not exist")
-	ErrRoomNotFound          = errors.New("requested room does not exist")
-	ErrRoomLockFailed        = errors.New("could not lock room")
-	ErrRoomUnlockFailed      = errors.New("could not unlock room, lock token does not match")
-	ErrTrackNotFound         = errors.New("track is not found")
-	ErrWebHookMissingAPIKey  = errors.New("api_key is required to use webhooks")
+	ErrAdmissionMissingAPIKey  = errors.New("api_key is required to use admission")
+	ErrAdmissionUnavailable    = errors.New("could not decide on joining, admission is unavailable")
+	ErrAuditMissingAPIKey      = errors.New("api_key is required to use audit")
+	ErrAuditMissingPath        = errors.New("path is required to use audit file sink")
+	ErrAuditNoRedis            = errors.New("redis is required to use audit redis sink")
+	ErrAuditSpoolNotDurable    = errors.New("redis or a spool dir is required to use audit webhook sink")
+	ErrCaptureDisabled         = errors.New("captures are disabled, capture dir is not configured")
+	ErrCaptureInProgress       = errors.New("track is already being captured")
+	ErrCaptureNotFound         = errors.New("capture does not exist")
+	ErrCodecNotEnabled         = errors.New("codec is not enabled for the room")
+	ErrConfigReloadUnavailable = errors.New("config reload is not available")
+	ErrEgressNotFound          = errors.New("egress does not exist")
+	ErrEgressNotConnected      = errors.New("egress not connected (redis required)")
+	ErrIdentityEmpty           = errors.New("identity cannot be empty")
+	ErrIngressNotConnected     = errors.New("ingress not connected (redis required)")
+	ErrIngressNotFound         = errors.New("ingress does not exist")
+	ErrInvalidPayloadType      = errors.New("payload_type must be between 0 and 63 or 96 and 127")
+	ErrInvalidSource           = errors.New("source must be an IP address, with an optional port")
+	ErrInvalidTrackSource      = errors.New("invalid track source")
+	ErrInvalidTrustedProxy     = errors.New("trusted_proxies must be IP addresses or CIDR ranges")
+	ErrInvalidVideoSlots       = errors.New("video_slots must be a number between 0 and 16")
+	ErrJoinDenied              = errors.New("join denied")
+	ErrMetadataExceedsLimits   = errors.New("metadata size exceeds limits")
+	ErrMeteringMissingAPIKey   = errors.New("api_key is required to use metering webhook sink")
+	ErrMeteringMissingPath     = errors.New("path is required to use metering file sink")
+	ErrMeteringNoRedis         = errors.New("redis is required to use metering redis sink")
+	ErrMeteringSpoolNotDurable = errors.New("redis or a spool dir is required to use metering webhook sink")
+	ErrNoMediaOffered          = errors.New("offer does not send any audio or video")
+	ErrNoMediaRequested        = errors.New("offer does not receive any audio or video")
+	ErrNoPortsAvailable        = errors.New("no udp ports available")
+	ErrNoTracks                = errors.New("at least one track is required")
+	ErrOperationFailed         = errors.New("operation cannot be completed")
+	ErrParticipantNotFound     = errors.New("participant does not exist")
+	ErrParticipantNotWaiting   = errors.New("participant is not waiting to be admitted")
+	ErrRateLimited             = errors.New("rate limit exceeded")
+	ErrRecordingRoomNotOnNode  = errors.New("tracks are recorded by the node hosting their room, which is another node")
+	ErrRoomNotFound            = errors.New("requested room does not exist")
+	ErrRoomNotOnNode           = errors.New("room is hosted on another node")
+	ErrRoomLockFailed          = errors.New("could not lock room")
+	ErrRoomUnlockFailed        = errors.New("could not unlock room, lock token does not match")
+	ErrRTPIngestDisabled       = errors.New("rtp ingest is disabled, port range is not configured")
+	ErrRTPIngestNotFound       = errors.New("rtp ingest does not exist")
+	ErrSessionNotFound         = errors.New("session does not exist")
+	ErrTenantLockFailed        = errors.New("could not lock tenant")
+	ErrTenantMaxRoomsExceeded  = errors.New("tenant has exceeded its max rooms")
+	ErrTenantUnlockFailed      = errors.New("could not unlock tenant, lock token does not match")
+	ErrTrackNotFound           = errors.New("track is not found")
+	ErrUnsupportedContentType  = errors.New("unsupported content type")
+	ErrWaitingRoomUnsupported  = errors.New("participants cannot wait to be admitted over HTTP sessions")
+	ErrWebHookDuplicateURL     = errors.New("webhook url is configured more than once")
+	ErrWebHookMissingAPIKey    = errors.New("api_key is required to use webhooks")
+	ErrWebHookSpoolDirEmpty    = errors.New("dir is required to use file webhook spool")
+	ErrWebHookSpoolNoRedis     = errors.New("redis is required to use redis webhook spool")
 )
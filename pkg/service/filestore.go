@@ -0,0 +1,1140 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/livekit/protocol/ingress"
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+	"github.com/livekit/protocol/utils"
+
+	"github.com/livekit/livekit-server/pkg/rpc"
+	"github.com/livekit/livekit-server/pkg/rtc"
+)
+
+// FileStore persists rooms, participants, egress and ingress into a single file on disk.
+// It is meant for single node deployments that do not run Redis, but need state (such as ingress stream keys)
+// to survive restarts. Data is organized the same way RedisStore does, with Redis keys used as bucket names.
+type FileStore struct {
+	kv   *fileKV
+	lock sync.RWMutex
+
+	// locks of rooms and tenants, by their Redis lock key
+	locksMu sync.Mutex
+	locks   map[string]fileLock
+
+	done chan struct{}
+}
+
+type fileLock struct {
+	token     string
+	expiresAt time.Time
+}
+
+func NewFileStore(path string) (*FileStore, error) {
+	kv, err := openFileKV(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// participants counted for tenants are gone along with the node, when it stops without them leaving
+	for bucket := range kv.buckets {
+		if strings.HasPrefix(bucket, TenantParticipantsPrefix) {
+			kv.deleteBucket(bucket)
+		}
+	}
+	if err = kv.flush(); err != nil {
+		return nil, err
+	}
+
+	return &FileStore{
+		kv:    kv,
+		locks: make(map[string]fileLock),
+	}, nil
+}
+
+func (s *FileStore) Start() error {
+	if s.done != nil {
+		return nil
+	}
+
+	s.done = make(chan struct{}, 1)
+	go s.egressWorker()
+	return nil
+}
+
+// Stop stops cleaning up ended egress, and closes the store. It can't be written to afterwards
+func (s *FileStore) Stop() {
+	if s.done != nil {
+		select {
+		case <-s.done:
+		default:
+			close(s.done)
+		}
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if err := s.kv.close(); err != nil {
+		logger.Warnw("could not close store log", err, "path", s.kv.path)
+	}
+}
+
+func (s *FileStore) StoreRoom(_ context.Context, room *livekit.Room, internal *livekit.RoomInternal) error {
+	if room.CreationTime == 0 {
+		room.CreationTime = time.Now().Unix()
+	}
+
+	roomData, err := proto.Marshal(room)
+	if err != nil {
+		return err
+	}
+
+	var internalData []byte
+	if internal != nil {
+		internalData, err = proto.Marshal(internal)
+		if err != nil {
+			return err
+		}
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.kv.put(RoomsKey, room.Name, roomData)
+	if internalData != nil {
+		s.kv.put(RoomInternalKey, room.Name, internalData)
+	} else {
+		s.kv.delete(RoomInternalKey, room.Name)
+	}
+
+	if err = s.kv.flush(); err != nil {
+		return errors.Wrap(err, "could not create room")
+	}
+	return nil
+}
+
+func (s *FileStore) LoadRoom(_ context.Context, roomName livekit.RoomName, includeInternal bool) (*livekit.Room, *livekit.RoomInternal, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	roomData, ok := s.kv.get(RoomsKey, string(roomName))
+	if !ok {
+		return nil, nil, ErrRoomNotFound
+	}
+
+	room := &livekit.Room{}
+	if err := proto.Unmarshal(roomData, room); err != nil {
+		return nil, nil, err
+	}
+
+	var internal *livekit.RoomInternal
+	if includeInternal {
+		if internalData, ok := s.kv.get(RoomInternalKey, string(roomName)); ok {
+			internal = &livekit.RoomInternal{}
+			if err := proto.Unmarshal(internalData, internal); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+
+	return room, internal, nil
+}
+
+func (s *FileStore) StoreRoomOptions(_ context.Context, roomName livekit.RoomName, options *rtc.RoomOptions) error {
+	data, err := json.Marshal(options)
+	if err != nil {
+		return err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.kv.put(RoomOptionsKey, string(roomName), data)
+	return s.kv.flush()
+}
+
+func (s *FileStore) LoadRoomOptions(_ context.Context, roomName livekit.RoomName) (*rtc.RoomOptions, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	data, ok := s.kv.get(RoomOptionsKey, string(roomName))
+	if !ok {
+		return nil, nil
+	}
+
+	options := &rtc.RoomOptions{}
+	if err := json.Unmarshal(data, options); err != nil {
+		return nil, err
+	}
+	return options, nil
+}
+
+func (s *FileStore) ListRooms(_ context.Context, roomNames []livekit.RoomName) ([]*livekit.Room, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	var items [][]byte
+	if roomNames == nil {
+		items = s.kv.values(RoomsKey)
+	} else {
+		for _, roomName := range roomNames {
+			if item, ok := s.kv.get(RoomsKey, string(roomName)); ok {
+				items = append(items, item)
+			}
+		}
+	}
+
+	rooms := make([]*livekit.Room, 0, len(items))
+	for _, item := range items {
+		room := &livekit.Room{}
+		if err := proto.Unmarshal(item, room); err != nil {
+			return nil, err
+		}
+		rooms = append(rooms, room)
+	}
+	return rooms, nil
+}
+
+func (s *FileStore) DeleteRoom(_ context.Context, roomName livekit.RoomName) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if _, ok := s.kv.get(RoomsKey, string(roomName)); !ok {
+		return nil
+	}
+
+	// participants left in the room are no longer counted for its tenant
+	if tenant, ok := s.kv.get(TenantsKey, string(RoomTenantResource(roomName))); ok {
+		s.kv.delete(TenantRoomsPrefix+string(tenant), string(roomName))
+		for _, item := range s.kv.values(RoomParticipantsPrefix + string(roomName)) {
+			pi := &livekit.ParticipantInfo{}
+			if err := proto.Unmarshal(item, pi); err == nil {
+				s.kv.delete(TenantParticipantsPrefix+string(tenant), pi.Sid)
+			}
+		}
+	}
+
+	s.kv.delete(RoomsKey, string(roomName))
+	s.kv.delete(RoomInternalKey, string(roomName))
+	s.kv.delete(RoomOptionsKey, string(roomName))
+	s.kv.deleteBucket(RoomParticipantsPrefix + string(roomName))
+	s.kv.deleteBucket(RoomWaitingParticipantsPrefix + string(roomName))
+	s.kv.delete(TenantsKey, string(RoomTenantResource(roomName)))
+
+	return s.kv.flush()
+}
+
+// LockRoom has the same semantics as RedisStore.LockRoom: the lock is held until it's unlocked with the returned
+// token or until duration has elapsed. When the room is already locked, it waits up to duration to acquire the lock.
+// Locks are not persisted, as they are only meaningful to the running process.
+func (s *FileStore) LockRoom(_ context.Context, roomName livekit.RoomName, duration time.Duration) (string, error) {
+	return s.lockKey(RoomLockPrefix+string(roomName), duration, ErrRoomLockFailed)
+}
+
+func (s *FileStore) UnlockRoom(_ context.Context, roomName livekit.RoomName, uid string) error {
+	return s.unlockKey(RoomLockPrefix+string(roomName), uid, ErrRoomUnlockFailed)
+}
+
+func (s *FileStore) lockKey(key string, duration time.Duration, lockErr error) (string, error) {
+	token := utils.NewGuid("LOCK")
+
+	startTime := time.Now()
+	for {
+		s.locksMu.Lock()
+		current, ok := s.locks[key]
+		if !ok || time.Now().After(current.expiresAt) {
+			s.locks[key] = fileLock{
+				token:     token,
+				expiresAt: time.Now().Add(duration),
+			}
+			s.locksMu.Unlock()
+			return token, nil
+		}
+		s.locksMu.Unlock()
+
+		// stop waiting past lock duration
+		if time.Since(startTime) > duration {
+			break
+		}
+
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	return "", lockErr
+}
+
+func (s *FileStore) unlockKey(key string, uid string, unlockErr error) error {
+	s.locksMu.Lock()
+	defer s.locksMu.Unlock()
+
+	// uid does not match, or lock has expired
+	current, ok := s.locks[key]
+	if !ok || current.token != uid || time.Now().After(current.expiresAt) {
+		return unlockErr
+	}
+
+	delete(s.locks, key)
+	return nil
+}
+
+func (s *FileStore) StoreParticipant(_ context.Context, roomName livekit.RoomName, participant *livekit.ParticipantInfo) error {
+	data, err := proto.Marshal(participant)
+	if err != nil {
+		return err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.kv.put(RoomParticipantsPrefix+string(roomName), participant.Identity, data)
+	return s.kv.flush()
+}
+
+func (s *FileStore) LoadParticipant(_ context.Context, roomName livekit.RoomName, identity livekit.ParticipantIdentity) (*livekit.ParticipantInfo, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	data, ok := s.kv.get(RoomParticipantsPrefix+string(roomName), string(identity))
+	if !ok {
+		return nil, ErrParticipantNotFound
+	}
+
+	pi := &livekit.ParticipantInfo{}
+	if err := proto.Unmarshal(data, pi); err != nil {
+		return nil, err
+	}
+	return pi, nil
+}
+
+func (s *FileStore) ListParticipants(_ context.Context, roomName livekit.RoomName) ([]*livekit.ParticipantInfo, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	items := s.kv.values(RoomParticipantsPrefix + string(roomName))
+	if len(items) == 0 {
+		return nil, nil
+	}
+
+	participants := make([]*livekit.ParticipantInfo, 0, len(items))
+	for _, item := range items {
+		pi := &livekit.ParticipantInfo{}
+		if err := proto.Unmarshal(item, pi); err != nil {
+			return nil, err
+		}
+		participants = append(participants, pi)
+	}
+	return participants, nil
+}
+
+func (s *FileStore) DeleteParticipant(_ context.Context, roomName livekit.RoomName, identity livekit.ParticipantIdentity) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.kv.delete(RoomParticipantsPrefix+string(roomName), string(identity))
+	return s.kv.flush()
+}
+
+func (s *FileStore) StoreWaitingParticipant(_ context.Context, roomName livekit.RoomName, participant *rpc.WaitingParticipant) error {
+	data, err := proto.Marshal(participant)
+	if err != nil {
+		return err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.kv.put(RoomWaitingParticipantsPrefix+string(roomName), participant.Participant.Identity, data)
+	return s.kv.flush()
+}
+
+func (s *FileStore) LoadWaitingParticipant(_ context.Context, roomName livekit.RoomName, identity livekit.ParticipantIdentity) (*rpc.WaitingParticipant, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	data, ok := s.kv.get(RoomWaitingParticipantsPrefix+string(roomName), string(identity))
+	if !ok {
+		return nil, ErrParticipantNotWaiting
+	}
+
+	wp := &rpc.WaitingParticipant{}
+	if err := proto.Unmarshal(data, wp); err != nil {
+		return nil, err
+	}
+	return wp, nil
+}
+
+func (s *FileStore) ListWaitingParticipants(_ context.Context, roomName livekit.RoomName) ([]*rpc.WaitingParticipant, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	items := s.kv.values(RoomWaitingParticipantsPrefix + string(roomName))
+	participants := make([]*rpc.WaitingParticipant, 0, len(items))
+	for _, item := range items {
+		wp := &rpc.WaitingParticipant{}
+		if err := proto.Unmarshal(item, wp); err != nil {
+			return nil, err
+		}
+		participants = append(participants, wp)
+	}
+	return participants, nil
+}
+
+func (s *FileStore) DeleteWaitingParticipant(_ context.Context, roomName livekit.RoomName, identity livekit.ParticipantIdentity) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.kv.delete(RoomWaitingParticipantsPrefix+string(roomName), string(identity))
+	return s.kv.flush()
+}
+
+func (s *FileStore) StoreEgress(_ context.Context, info *livekit.EgressInfo) error {
+	data, err := proto.Marshal(info)
+	if err != nil {
+		return err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.kv.put(EgressKey, info.EgressId, data)
+	s.kv.put(RoomEgressPrefix+info.RoomName, info.EgressId, nil)
+	if err = s.kv.flush(); err != nil {
+		return errors.Wrap(err, "could not store egress info")
+	}
+
+	return nil
+}
+
+func (s *FileStore) LoadEgress(_ context.Context, egressID string) (*livekit.EgressInfo, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return s.loadEgress(egressID)
+}
+
+func (s *FileStore) loadEgress(egressID string) (*livekit.EgressInfo, error) {
+	data, ok := s.kv.get(EgressKey, egressID)
+	if !ok {
+		return nil, ErrEgressNotFound
+	}
+
+	info := &livekit.EgressInfo{}
+	if err := proto.Unmarshal(data, info); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+func (s *FileStore) ListEgress(_ context.Context, roomName livekit.RoomName) ([]*livekit.EgressInfo, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	var infos []*livekit.EgressInfo
+	if roomName == "" {
+		for _, d := range s.kv.values(EgressKey) {
+			info := &livekit.EgressInfo{}
+			if err := proto.Unmarshal(d, info); err != nil {
+				return nil, err
+			}
+			infos = append(infos, info)
+		}
+	} else {
+		for _, egressID := range s.kv.keys(RoomEgressPrefix + string(roomName)) {
+			info, err := s.loadEgress(egressID)
+			if err == ErrEgressNotFound {
+				continue
+			} else if err != nil {
+				return nil, err
+			}
+			infos = append(infos, info)
+		}
+	}
+
+	return infos, nil
+}
+
+func (s *FileStore) UpdateEgress(_ context.Context, info *livekit.EgressInfo) error {
+	data, err := proto.Marshal(info)
+	if err != nil {
+		return err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.kv.put(EgressKey, info.EgressId, data)
+	if info.EndedAt != 0 {
+		s.kv.put(EndedEgressKey, info.EgressId, []byte(egressEndedValue(info.RoomName, info.EndedAt)))
+	}
+
+	if err = s.kv.flush(); err != nil {
+		return errors.Wrap(err, "could not update egress info")
+	}
+
+	return nil
+}
+
+// Deletes egress info 24h after the egress has ended
+func (s *FileStore) egressWorker() {
+	ticker := time.NewTicker(time.Minute * 30)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+			err := s.CleanEndedEgress()
+			if err != nil {
+				logger.Errorw("could not clean egress info", err)
+			}
+		}
+	}
+}
+
+func (s *FileStore) CleanEndedEgress() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	expiry := time.Now().Add(-24 * time.Hour).UnixNano()
+	for _, egressID := range s.kv.keys(EndedEgressKey) {
+		val, _ := s.kv.get(EndedEgressKey, egressID)
+		roomName, endedAt, err := parseEgressEnded(string(val))
+		if err != nil {
+			return err
+		}
+
+		if endedAt < expiry {
+			s.kv.delete(EndedEgressKey, egressID)
+			s.kv.delete(RoomEgressPrefix+roomName, egressID)
+			s.kv.delete(EgressKey, egressID)
+			s.kv.delete(TenantsKey, string(EgressTenantResource(egressID)))
+		}
+	}
+
+	return s.kv.flush()
+}
+
+func (s *FileStore) StoreIngress(_ context.Context, info *livekit.IngressInfo) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if err := s.storeIngress(info); err != nil {
+		return err
+	}
+	if err := s.storeIngressState(info.IngressId, nil); err != nil {
+		return err
+	}
+
+	return s.kv.flush()
+}
+
+func (s *FileStore) storeIngress(info *livekit.IngressInfo) error {
+	if info.IngressId == "" {
+		return errors.New("Missing IngressId")
+	}
+	if info.StreamKey == "" {
+		return errors.New("Missing StreamKey")
+	}
+
+	// ignore state
+	infoCopy := proto.Clone(info).(*livekit.IngressInfo)
+	infoCopy.State = nil
+
+	data, err := proto.Marshal(infoCopy)
+	if err != nil {
+		return err
+	}
+
+	var oldRoom string
+	oldInfo, err := s.loadIngress(info.IngressId)
+	switch err {
+	case ErrIngressNotFound:
+		// Ingress doesn't exist yet
+	case nil:
+		oldRoom = oldInfo.RoomName
+	default:
+		return err
+	}
+
+	s.kv.put(IngressKey, info.IngressId, data)
+	s.kv.put(StreamKeyKey, info.StreamKey, []byte(info.IngressId))
+
+	if oldRoom != info.RoomName {
+		if oldRoom != "" {
+			s.kv.delete(RoomIngressPrefix+oldRoom, info.IngressId)
+		}
+		if info.RoomName != "" {
+			s.kv.put(RoomIngressPrefix+info.RoomName, info.IngressId, nil)
+		}
+	}
+
+	return nil
+}
+
+func (s *FileStore) storeIngressState(ingressId string, state *livekit.IngressState) error {
+	if ingressId == "" {
+		return errors.New("Missing IngressId")
+	}
+
+	if state == nil {
+		state = &livekit.IngressState{}
+	}
+
+	data, err := proto.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	info, err := s.loadIngress(ingressId)
+	if err != nil {
+		return err
+	}
+
+	var oldStartedAt int64
+	oldState, err := s.loadIngressState(ingressId)
+	switch err {
+	case ErrIngressNotFound:
+		// Ingress state doesn't exist yet
+	case nil:
+		oldStartedAt = oldState.StartedAt
+	default:
+		return err
+	}
+
+	if state.StartedAt < oldStartedAt {
+		// Do not overwrite the info and state of a more recent session
+		return ingress.ErrIngressOutOfDate
+	}
+
+	s.kv.put(IngressStatePrefix, ingressId, data)
+	s.kv.put(StreamKeyKey, info.StreamKey, []byte(info.IngressId))
+
+	return nil
+}
+
+func (s *FileStore) loadIngress(ingressId string) (*livekit.IngressInfo, error) {
+	data, ok := s.kv.get(IngressKey, ingressId)
+	if !ok {
+		return nil, ErrIngressNotFound
+	}
+
+	info := &livekit.IngressInfo{}
+	if err := proto.Unmarshal(data, info); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+func (s *FileStore) loadIngressState(ingressId string) (*livekit.IngressState, error) {
+	data, ok := s.kv.get(IngressStatePrefix, ingressId)
+	if !ok {
+		return nil, ErrIngressNotFound
+	}
+
+	state := &livekit.IngressState{}
+	if err := proto.Unmarshal(data, state); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+func (s *FileStore) loadIngressWithState(ingressId string) (*livekit.IngressInfo, error) {
+	info, err := s.loadIngress(ingressId)
+	if err != nil {
+		return nil, err
+	}
+
+	state, err := s.loadIngressState(ingressId)
+	switch err {
+	case nil:
+		info.State = state
+	case ErrIngressNotFound:
+		// No state for this ingress
+	default:
+		return nil, err
+	}
+
+	return info, nil
+}
+
+func (s *FileStore) LoadIngress(_ context.Context, ingressId string) (*livekit.IngressInfo, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return s.loadIngressWithState(ingressId)
+}
+
+func (s *FileStore) LoadIngressFromStreamKey(_ context.Context, streamKey string) (*livekit.IngressInfo, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	ingressID, ok := s.kv.get(StreamKeyKey, streamKey)
+	if !ok {
+		return nil, ErrIngressNotFound
+	}
+
+	return s.loadIngressWithState(string(ingressID))
+}
+
+func (s *FileStore) ListIngress(_ context.Context, roomName livekit.RoomName) ([]*livekit.IngressInfo, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	var ingressIDs []string
+	if roomName == "" {
+		ingressIDs = s.kv.keys(IngressKey)
+	} else {
+		ingressIDs = s.kv.keys(RoomIngressPrefix + string(roomName))
+	}
+
+	var infos []*livekit.IngressInfo
+	for _, ingressID := range ingressIDs {
+		info, err := s.loadIngressWithState(ingressID)
+		if err == ErrIngressNotFound {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		infos = append(infos, info)
+	}
+
+	return infos, nil
+}
+
+func (s *FileStore) UpdateIngress(_ context.Context, info *livekit.IngressInfo) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if err := s.storeIngress(info); err != nil {
+		return err
+	}
+
+	return s.kv.flush()
+}
+
+func (s *FileStore) UpdateIngressState(_ context.Context, ingressId string, state *livekit.IngressState) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if err := s.storeIngressState(ingressId, state); err != nil {
+		return err
+	}
+
+	return s.kv.flush()
+}
+
+func (s *FileStore) DeleteIngress(_ context.Context, info *livekit.IngressInfo) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.kv.delete(RoomIngressPrefix+info.RoomName, info.IngressId)
+	s.kv.delete(StreamKeyKey, info.StreamKey)
+	s.kv.delete(IngressKey, info.IngressId)
+	s.kv.delete(IngressStatePrefix, info.IngressId)
+	s.kv.delete(TenantsKey, string(IngressTenantResource(info.IngressId)))
+	if err := s.kv.flush(); err != nil {
+		return errors.Wrap(err, "could not delete ingress info")
+	}
+
+	return nil
+}
+
+func (s *FileStore) StoreTenant(_ context.Context, resource TenantResource, tenant string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.kv.put(TenantsKey, string(resource), []byte(tenant))
+	if strings.HasPrefix(string(resource), roomTenantPrefix) {
+		s.kv.put(TenantRoomsPrefix+tenant, strings.TrimPrefix(string(resource), roomTenantPrefix), nil)
+	}
+	return s.kv.flush()
+}
+
+func (s *FileStore) DeleteTenant(_ context.Context, resource TenantResource, tenant string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.kv.delete(TenantsKey, string(resource))
+	if strings.HasPrefix(string(resource), roomTenantPrefix) {
+		s.kv.delete(TenantRoomsPrefix+tenant, strings.TrimPrefix(string(resource), roomTenantPrefix))
+	}
+	return s.kv.flush()
+}
+
+func (s *FileStore) LoadTenant(_ context.Context, resource TenantResource) (string, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	tenant, _ := s.kv.get(TenantsKey, string(resource))
+	return string(tenant), nil
+}
+
+func (s *FileStore) ListTenantRooms(_ context.Context, tenant string) ([]livekit.RoomName, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	names := s.kv.keys(TenantRoomsPrefix + tenant)
+	roomNames := make([]livekit.RoomName, 0, len(names))
+	for _, name := range names {
+		roomNames = append(roomNames, livekit.RoomName(name))
+	}
+	return roomNames, nil
+}
+
+// LockTenant has the same semantics as LockRoom
+func (s *FileStore) LockTenant(_ context.Context, tenant string, duration time.Duration) (string, error) {
+	return s.lockKey(TenantLockPrefix+tenant, duration, ErrTenantLockFailed)
+}
+
+func (s *FileStore) UnlockTenant(_ context.Context, tenant string, uid string) error {
+	return s.unlockKey(TenantLockPrefix+tenant, uid, ErrTenantUnlockFailed)
+}
+
+// StoreTenantParticipant counts participants of the single node using the store, which are dropped when the store is
+// opened again
+func (s *FileStore) StoreTenantParticipant(_ context.Context, tenant string, _ livekit.NodeID, participantID livekit.ParticipantID, tracks int) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.kv.put(TenantParticipantsPrefix+tenant, string(participantID), []byte(strconv.Itoa(tracks)))
+	return s.kv.flush()
+}
+
+func (s *FileStore) DeleteTenantParticipant(_ context.Context, tenant string, participantID livekit.ParticipantID) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.kv.delete(TenantParticipantsPrefix+tenant, string(participantID))
+	return s.kv.flush()
+}
+
+func (s *FileStore) LoadTenantUsage(_ context.Context, tenant string) (int, int, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	values := s.kv.values(TenantParticipantsPrefix + tenant)
+	tracks := 0
+	for _, value := range values {
+		n, err := strconv.Atoi(string(value))
+		if err != nil {
+			return 0, 0, err
+		}
+		tracks += n
+	}
+	return len(values), tracks, nil
+}
+
+// ---------------------------------
+
+// fileKV is a minimal embedded key-value store, with keys grouped into buckets.
+// All data is kept in memory. Changes are appended to a log next to the store file on flush, and the store file is
+// rewritten atomically once the log outgrows it, so that updates cost a small write rather than the whole store.
+// Changes that can't be flushed are rolled back, so that memory doesn't hold state that was never persisted.
+// fileKV is not safe for concurrent use, FileStore guards access to it.
+type fileKV struct {
+	path    string
+	buckets map[string]map[string][]byte
+	// changes that have not been flushed, and how to undo them in memory
+	pending []fileKVChange
+	undo    []func()
+
+	log *os.File
+	// size of the log up to the last complete flush
+	logSize int64
+	// set when a failed write couldn't be truncated, for the next one to start on a line of its own
+	logTorn      bool
+	snapshotSize int64
+}
+
+type fileKVChange struct {
+	Bucket string `json:"bucket"`
+	// empty when the whole bucket is deleted
+	Key     string `json:"key,omitempty"`
+	Value   []byte `json:"value,omitempty"`
+	Deleted bool   `json:"deleted,omitempty"`
+}
+
+const (
+	fileKVLogSuffix = ".log"
+	// the log isn't compacted into the store file until it's at least this large
+	fileKVMinCompactSize = 1 << 20
+)
+
+func openFileKV(path string) (*fileKV, error) {
+	kv := &fileKV{
+		path:    path,
+		buckets: make(map[string]map[string][]byte),
+	}
+
+	data, err := os.ReadFile(path)
+	created := os.IsNotExist(err)
+	if err != nil && !created {
+		return nil, errors.Wrap(err, "could not read store file")
+	}
+	if len(data) != 0 {
+		if err = json.Unmarshal(data, &kv.buckets); err != nil {
+			return nil, errors.Wrap(err, "could not parse store file")
+		}
+	}
+	kv.snapshotSize = int64(len(data))
+
+	if err = kv.replayLog(); err != nil {
+		return nil, err
+	}
+	kv.log, err = os.OpenFile(path+fileKVLogSuffix, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not open store log")
+	}
+
+	if created || kv.logSize > 0 {
+		// fold changes of the previous run into the store file, also creating it upfront to surface permission
+		// problems at startup
+		if err = kv.compact(); err != nil {
+			return nil, err
+		}
+	}
+	return kv, nil
+}
+
+// replayLog applies changes logged after the store file was last written. Changes that were partially written, when
+// the process stopped or a write failed, are discarded
+func (kv *fileKV) replayLog() error {
+	data, err := os.ReadFile(kv.path + fileKVLogSuffix)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return errors.Wrap(err, "could not read store log")
+	}
+
+	for len(data) > 0 {
+		end := bytes.IndexByte(data, '\n')
+		if end < 0 {
+			break
+		}
+		change := fileKVChange{}
+		if err = json.Unmarshal(data[:end], &change); err != nil {
+			logger.Warnw("discarding unreadable store log entry", err, "path", kv.path+fileKVLogSuffix)
+		} else {
+			kv.apply(change)
+		}
+		kv.logSize += int64(end + 1)
+		data = data[end+1:]
+	}
+	kv.pending = nil
+	kv.undo = nil
+	return nil
+}
+
+func (kv *fileKV) get(bucket, key string) ([]byte, bool) {
+	value, ok := kv.buckets[bucket][key]
+	return value, ok
+}
+
+func (kv *fileKV) keys(bucket string) []string {
+	keys := make([]string, 0, len(kv.buckets[bucket]))
+	for key := range kv.buckets[bucket] {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+func (kv *fileKV) values(bucket string) [][]byte {
+	values := make([][]byte, 0, len(kv.buckets[bucket]))
+	for _, value := range kv.buckets[bucket] {
+		values = append(values, value)
+	}
+	return values
+}
+
+func (kv *fileKV) put(bucket, key string, value []byte) {
+	kv.apply(fileKVChange{Bucket: bucket, Key: key, Value: value})
+}
+
+func (kv *fileKV) delete(bucket, key string) {
+	if _, ok := kv.buckets[bucket][key]; !ok {
+		return
+	}
+	kv.apply(fileKVChange{Bucket: bucket, Key: key, Deleted: true})
+}
+
+func (kv *fileKV) deleteBucket(bucket string) {
+	if _, ok := kv.buckets[bucket]; !ok {
+		return
+	}
+	kv.apply(fileKVChange{Bucket: bucket, Deleted: true})
+}
+
+func (kv *fileKV) apply(change fileKVChange) {
+	kv.pending = append(kv.pending, change)
+
+	if change.Key == "" {
+		if b, ok := kv.buckets[change.Bucket]; ok {
+			kv.undo = append(kv.undo, func() {
+				kv.buckets[change.Bucket] = b
+			})
+		}
+		delete(kv.buckets, change.Bucket)
+		return
+	}
+
+	b := kv.buckets[change.Bucket]
+	prev, existed := b[change.Key]
+	kv.undo = append(kv.undo, func() {
+		kv.restore(change.Bucket, change.Key, prev, existed)
+	})
+	if change.Deleted {
+		delete(b, change.Key)
+		if len(b) == 0 {
+			delete(kv.buckets, change.Bucket)
+		}
+		return
+	}
+	if b == nil {
+		b = make(map[string][]byte)
+		kv.buckets[change.Bucket] = b
+	}
+	if change.Value == nil {
+		change.Value = []byte{}
+	}
+	b[change.Key] = change.Value
+}
+
+func (kv *fileKV) restore(bucket, key string, value []byte, existed bool) {
+	b := kv.buckets[bucket]
+	if !existed {
+		delete(b, key)
+		if len(b) == 0 {
+			delete(kv.buckets, bucket)
+		}
+		return
+	}
+	if b == nil {
+		b = make(map[string][]byte)
+		kv.buckets[bucket] = b
+	}
+	b[key] = value
+}
+
+// rollback undoes pending changes in memory, latest first
+func (kv *fileKV) rollback() {
+	for i := len(kv.undo) - 1; i >= 0; i-- {
+		kv.undo[i]()
+	}
+	kv.pending = nil
+	kv.undo = nil
+}
+
+// flush appends pending changes to the log in a single write. Once the log is larger than the store file, it's
+// compacted into it. When the write fails, pending changes are rolled back and the log is truncated back to the last
+// complete flush
+func (kv *fileKV) flush() error {
+	if len(kv.pending) == 0 {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	if kv.logTorn {
+		buf.WriteByte('\n')
+	}
+	for _, change := range kv.pending {
+		line, err := json.Marshal(change)
+		if err != nil {
+			kv.rollback()
+			return err
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+
+	n, err := kv.log.Write(buf.Bytes())
+	if err == nil {
+		err = kv.log.Sync()
+	}
+	if err != nil {
+		kv.rollback()
+		if terr := kv.log.Truncate(kv.logSize); terr != nil {
+			// replaying skips the torn line, as long as the next write doesn't continue it
+			kv.logTorn = true
+			kv.logSize += int64(n)
+		}
+		return err
+	}
+	kv.logSize += int64(n)
+	kv.logTorn = false
+	kv.pending = nil
+	kv.undo = nil
+
+	if kv.logSize > fileKVMinCompactSize && kv.logSize > kv.snapshotSize {
+		if err = kv.compact(); err != nil {
+			// changes are safe in the log, compaction is attempted again on the next flush
+			logger.Warnw("could not compact store log", err, "path", kv.path)
+		}
+	}
+	return nil
+}
+
+// compact writes all buckets to a temporary file and renames it over the store file, so that the store file is
+// never left partially written, then truncates the log. Changes in the log are applied again if the process stops
+// before it's truncated, which is harmless
+func (kv *fileKV) compact() error {
+	data, err := json.Marshal(kv.buckets)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(kv.path), filepath.Base(kv.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmpPath := f.Name()
+	defer func() {
+		// no-op once renamed
+		_ = os.Remove(tmpPath)
+	}()
+
+	if _, err = f.Write(data); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err = f.Sync(); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	if err = os.Rename(tmpPath, kv.path); err != nil {
+		return err
+	}
+	kv.snapshotSize = int64(len(data))
+
+	if err = kv.log.Truncate(0); err != nil {
+		return err
+	}
+	kv.logSize = 0
+	kv.logTorn = false
+	kv.pending = nil
+	kv.undo = nil
+	return nil
+}
+
+func (kv *fileKV) close() error {
+	if err := kv.log.Close(); err != nil && !errors.Is(err, os.ErrClosed) {
+		return err
+	}
+	return nil
+}
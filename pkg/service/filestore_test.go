@@ -0,0 +1,339 @@
+package service_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/atomic"
+
+	"github.com/livekit/protocol/ingress"
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/utils"
+
+	"github.com/livekit/livekit-server/pkg/rtc"
+	"github.com/livekit/livekit-server/pkg/service"
+)
+
+func newTestFileStore(t *testing.T) (*service.FileStore, string) {
+	path := filepath.Join(t.TempDir(), "store.json")
+	fs, err := service.NewFileStore(path)
+	require.NoError(t, err)
+	return fs, path
+}
+
+func TestFileStorePersistence(t *testing.T) {
+	ctx := context.Background()
+	fs, path := newTestFileStore(t)
+
+	room := &livekit.Room{
+		Sid:  "RM_test",
+		Name: "test_room",
+	}
+	internal := &livekit.RoomInternal{
+		TrackEgress: &livekit.AutoTrackEgress{Filepath: "egress"},
+	}
+	require.NoError(t, fs.StoreRoom(ctx, room, internal))
+	options := &rtc.RoomOptions{MaxSubscribeBitrate: 500000}
+	require.NoError(t, fs.StoreRoomOptions(ctx, livekit.RoomName(room.Name), options))
+
+	p := &livekit.ParticipantInfo{
+		Sid:      "PA_test",
+		Identity: "test",
+		State:    livekit.ParticipantInfo_ACTIVE,
+	}
+	require.NoError(t, fs.StoreParticipant(ctx, livekit.RoomName(room.Name), p))
+
+	info := &livekit.IngressInfo{
+		IngressId: "ingressId",
+		StreamKey: "streamKey",
+		RoomName:  room.Name,
+	}
+	require.NoError(t, fs.StoreIngress(ctx, info))
+	require.NoError(t, fs.StoreTenant(ctx, service.RoomTenantResource(livekit.RoomName(room.Name)), "tenant"))
+	require.NoError(t, fs.StoreTenantParticipant(ctx, "tenant", "ND_test", livekit.ParticipantID(p.Sid), 1))
+
+	// reopen, everything should still be there
+	fs, err := service.NewFileStore(path)
+	require.NoError(t, err)
+
+	rooms, err := fs.ListTenantRooms(ctx, "tenant")
+	require.NoError(t, err)
+	require.Equal(t, []livekit.RoomName{livekit.RoomName(room.Name)}, rooms)
+	// apart from participants counted for tenants, gone along with the node
+	tenantParticipants, tracks, err := fs.LoadTenantUsage(ctx, "tenant")
+	require.NoError(t, err)
+	require.Zero(t, tenantParticipants)
+	require.Zero(t, tracks)
+
+	actualRoom, actualInternal, err := fs.LoadRoom(ctx, livekit.RoomName(room.Name), true)
+	require.NoError(t, err)
+	require.Equal(t, room.Sid, actualRoom.Sid)
+	require.Equal(t, internal.TrackEgress.Filepath, actualInternal.TrackEgress.Filepath)
+	actualOptions, err := fs.LoadRoomOptions(ctx, livekit.RoomName(room.Name))
+	require.NoError(t, err)
+	require.Equal(t, options, actualOptions)
+
+	participants, err := fs.ListParticipants(ctx, livekit.RoomName(room.Name))
+	require.NoError(t, err)
+	require.Len(t, participants, 1)
+	require.Equal(t, p.Identity, participants[0].Identity)
+
+	pulledInfo, err := fs.LoadIngressFromStreamKey(ctx, info.StreamKey)
+	require.NoError(t, err)
+	compareIngressInfo(t, pulledInfo, info)
+
+	// deleting room removes participants
+	require.NoError(t, fs.DeleteRoom(ctx, livekit.RoomName(room.Name)))
+	fs, err = service.NewFileStore(path)
+	require.NoError(t, err)
+
+	_, _, err = fs.LoadRoom(ctx, livekit.RoomName(room.Name), false)
+	require.Equal(t, service.ErrRoomNotFound, err)
+	_, err = fs.LoadParticipant(ctx, livekit.RoomName(room.Name), livekit.ParticipantIdentity(p.Identity))
+	require.Equal(t, service.ErrParticipantNotFound, err)
+	actualOptions, err = fs.LoadRoomOptions(ctx, livekit.RoomName(room.Name))
+	require.NoError(t, err)
+	require.Nil(t, actualOptions)
+}
+
+func TestFileStoreLog(t *testing.T) {
+	ctx := context.Background()
+	fs, path := newTestFileStore(t)
+	roomName := livekit.RoomName("test_room")
+	require.NoError(t, fs.StoreRoom(ctx, &livekit.Room{Sid: "RM_test", Name: string(roomName)}, nil))
+
+	stored, err := os.Stat(path)
+	require.NoError(t, err)
+	for i := 0; i < 100; i++ {
+		require.NoError(t, fs.StoreParticipant(ctx, roomName, &livekit.ParticipantInfo{
+			Sid:      "PA_test",
+			Identity: "test",
+			Metadata: strconv.Itoa(i),
+		}))
+	}
+
+	// updates are appended to the log, the store file isn't rewritten
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	require.Equal(t, stored.Size(), info.Size())
+	require.Equal(t, stored.ModTime(), info.ModTime())
+
+	// a change partially written when stopping is discarded
+	f, err := os.OpenFile(path+".log", os.O_APPEND|os.O_WRONLY, 0600)
+	require.NoError(t, err)
+	_, err = f.WriteString(`{"bucket":"room_participants:test_room","key":"te`)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	fs, err = service.NewFileStore(path)
+	require.NoError(t, err)
+	p, err := fs.LoadParticipant(ctx, roomName, "test")
+	require.NoError(t, err)
+	require.Equal(t, "99", p.Metadata)
+
+	// the log was folded into the store file
+	info, err = os.Stat(path + ".log")
+	require.NoError(t, err)
+	require.Zero(t, info.Size())
+
+	// changes logged after a torn one are kept
+	require.NoError(t, fs.StoreParticipant(ctx, roomName, &livekit.ParticipantInfo{Sid: "PA_test", Identity: "test", Metadata: "a"}))
+	logged, err := os.ReadFile(path + ".log")
+	require.NoError(t, err)
+	require.NoError(t, fs.StoreParticipant(ctx, roomName, &livekit.ParticipantInfo{Sid: "PA_test", Identity: "test", Metadata: "b"}))
+	fs.Stop()
+	f, err = os.OpenFile(path+".log", os.O_APPEND|os.O_WRONLY, 0600)
+	require.NoError(t, err)
+	_, err = f.WriteString(`{"bucket":"room_participants:test_room","key":"te` + "\n" + string(logged))
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	fs, err = service.NewFileStore(path)
+	require.NoError(t, err)
+	p, err = fs.LoadParticipant(ctx, roomName, "test")
+	require.NoError(t, err)
+	require.Equal(t, "a", p.Metadata)
+}
+
+func TestFileStoreWriteFailure(t *testing.T) {
+	ctx := context.Background()
+	fs, path := newTestFileStore(t)
+	require.NoError(t, fs.StoreRoom(ctx, &livekit.Room{Sid: "RM_1", Name: "room1"}, nil))
+
+	// writes fail once the store is closed
+	fs.Stop()
+	require.Error(t, fs.StoreRoom(ctx, &livekit.Room{Sid: "RM_2", Name: "room2"}, nil))
+	require.Error(t, fs.DeleteRoom(ctx, "room1"))
+
+	// changes that were not persisted are not seen either
+	_, _, err := fs.LoadRoom(ctx, "room2", false)
+	require.Equal(t, service.ErrRoomNotFound, err)
+	rooms, err := fs.ListRooms(ctx, nil)
+	require.NoError(t, err)
+	require.Len(t, rooms, 1)
+	require.Equal(t, "room1", rooms[0].Name)
+
+	fs, err = service.NewFileStore(path)
+	require.NoError(t, err)
+	rooms, err = fs.ListRooms(ctx, nil)
+	require.NoError(t, err)
+	require.Len(t, rooms, 1)
+}
+
+func TestFileStoreRoomLock(t *testing.T) {
+	ctx := context.Background()
+	fs, _ := newTestFileStore(t)
+	lockInterval := 5 * time.Millisecond
+	roomName := livekit.RoomName("myroom")
+
+	t.Run("normal locking", func(t *testing.T) {
+		token, err := fs.LockRoom(ctx, roomName, lockInterval)
+		require.NoError(t, err)
+		require.NotEmpty(t, token)
+		require.NoError(t, fs.UnlockRoom(ctx, roomName, token))
+	})
+
+	t.Run("waits before acquiring lock", func(t *testing.T) {
+		token, err := fs.LockRoom(ctx, roomName, lockInterval)
+		require.NoError(t, err)
+		require.NotEmpty(t, token)
+		unlocked := atomic.NewUint32(0)
+		wg := sync.WaitGroup{}
+
+		wg.Add(1)
+		go func() {
+			// attempt to lock again
+			defer wg.Done()
+			token2, err := fs.LockRoom(ctx, roomName, lockInterval)
+			require.NoError(t, err)
+			defer fs.UnlockRoom(ctx, roomName, token2)
+			require.Equal(t, uint32(1), unlocked.Load())
+		}()
+
+		// release after 2 ms
+		time.Sleep(2 * time.Millisecond)
+		unlocked.Store(1)
+		_ = fs.UnlockRoom(ctx, roomName, token)
+
+		wg.Wait()
+	})
+
+	t.Run("lock expires", func(t *testing.T) {
+		token, err := fs.LockRoom(ctx, roomName, lockInterval)
+		require.NoError(t, err)
+
+		time.Sleep(lockInterval + time.Millisecond)
+		token2, err := fs.LockRoom(ctx, roomName, lockInterval)
+		require.NoError(t, err)
+
+		// expired token cannot unlock
+		require.Equal(t, service.ErrRoomUnlockFailed, fs.UnlockRoom(ctx, roomName, token))
+		require.NoError(t, fs.UnlockRoom(ctx, roomName, token2))
+	})
+
+	t.Run("fails when lock is held", func(t *testing.T) {
+		token, err := fs.LockRoom(ctx, roomName, time.Second)
+		require.NoError(t, err)
+		defer fs.UnlockRoom(ctx, roomName, token)
+
+		_, err = fs.LockRoom(ctx, roomName, lockInterval)
+		require.Equal(t, service.ErrRoomLockFailed, err)
+	})
+}
+
+func TestFileStoreEgress(t *testing.T) {
+	ctx := context.Background()
+	fs, _ := newTestFileStore(t)
+
+	roomName := "egress-test"
+	info := &livekit.EgressInfo{
+		EgressId: utils.NewGuid(utils.EgressPrefix),
+		RoomId:   utils.NewGuid(utils.RoomPrefix),
+		RoomName: roomName,
+		Status:   livekit.EgressStatus_EGRESS_STARTING,
+	}
+	require.NoError(t, fs.StoreEgress(ctx, info))
+
+	info2 := &livekit.EgressInfo{
+		EgressId: utils.NewGuid(utils.EgressPrefix),
+		RoomId:   utils.NewGuid(utils.RoomPrefix),
+		RoomName: "another-egress-test",
+		Status:   livekit.EgressStatus_EGRESS_STARTING,
+	}
+	require.NoError(t, fs.StoreEgress(ctx, info2))
+
+	res, err := fs.LoadEgress(ctx, info.EgressId)
+	require.NoError(t, err)
+	require.Equal(t, info.EgressId, res.EgressId)
+
+	list, err := fs.ListEgress(ctx, "")
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+
+	list, err = fs.ListEgress(ctx, livekit.RoomName(roomName))
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+
+	// ended more than 24h ago
+	info.Status = livekit.EgressStatus_EGRESS_COMPLETE
+	info.EndedAt = time.Now().Add(-25 * time.Hour).UnixNano()
+	require.NoError(t, fs.UpdateEgress(ctx, info))
+
+	require.NoError(t, fs.CleanEndedEgress())
+
+	list, err = fs.ListEgress(ctx, livekit.RoomName(roomName))
+	require.NoError(t, err)
+	require.Len(t, list, 0)
+
+	_, err = fs.LoadEgress(ctx, info.EgressId)
+	require.Equal(t, service.ErrEgressNotFound, err)
+}
+
+func TestFileStoreIngress(t *testing.T) {
+	ctx := context.Background()
+	fs, _ := newTestFileStore(t)
+
+	info := &livekit.IngressInfo{
+		IngressId: "ingressId",
+		StreamKey: "streamKey",
+		State: &livekit.IngressState{
+			StartedAt: 2,
+		},
+	}
+
+	require.NoError(t, fs.StoreIngress(ctx, info))
+	require.NoError(t, fs.UpdateIngressState(ctx, info.IngressId, info.State))
+
+	info.RoomName = "room"
+	require.NoError(t, fs.UpdateIngress(ctx, info))
+
+	infos, err := fs.ListIngress(ctx, "room")
+	require.NoError(t, err)
+	require.Len(t, infos, 1)
+	compareIngressInfo(t, infos[0], info)
+	require.Equal(t, int64(2), infos[0].State.StartedAt)
+
+	info.RoomName = ""
+	require.NoError(t, fs.UpdateIngress(ctx, info))
+
+	infos, err = fs.ListIngress(ctx, "room")
+	require.NoError(t, err)
+	require.Len(t, infos, 0)
+
+	info.State.StartedAt = 1
+	err = fs.UpdateIngressState(ctx, info.IngressId, info.State)
+	require.Equal(t, ingress.ErrIngressOutOfDate, err)
+
+	require.NoError(t, fs.DeleteIngress(ctx, info))
+
+	_, err = fs.LoadIngress(ctx, info.IngressId)
+	require.Equal(t, service.ErrIngressNotFound, err)
+	_, err = fs.LoadIngressFromStreamKey(ctx, info.StreamKey)
+	require.Equal(t, service.ErrIngressNotFound, err)
+}
@@ -0,0 +1,419 @@
+package service
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pion/sdp/v3"
+	"github.com/pion/webrtc/v3"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+	"github.com/livekit/protocol/utils"
+
+	"github.com/livekit/livekit-server/pkg/routing"
+	"github.com/livekit/livekit-server/pkg/rtc"
+	"github.com/livekit/livekit-server/pkg/telemetry/prometheus"
+)
+
+const (
+	sdpContentType     = "application/sdp"
+	trickleContentType = "application/trickle-ice-sdpfrag"
+	maxSDPBodySize     = 1 << 20
+
+	// local candidates can't be trickled to HTTP clients, they are collected into the answer.
+	// They are gathered quickly, collection ends once none have arrived for candidateQuietPeriod
+	candidateQuietPeriod = 250 * time.Millisecond
+	maxCandidateWait     = 3 * time.Second
+)
+
+// httpSession is a participant signaled over HTTP, as WHIP and WHEP clients are. The client makes a single offer,
+// local candidates are sent with the answer and remote ones are trickled with PATCH.
+type httpSession struct {
+	id string
+	// part of the resource URL, known only to the client that created the session
+	secret string
+	// identity of the token that created the session, required to modify it
+	tokenIdentity string
+	roomName      livekit.RoomName
+	// transport the client offers
+	target    livekit.SignalTarget
+	reqSink   routing.MessageSink
+	resSource routing.MessageSource
+	logger    logger.Logger
+}
+
+func (s *httpSession) close() {
+	s.resSource.Close()
+	s.reqSink.Close()
+}
+
+func (s *httpSession) leave() {
+	_ = s.reqSink.WriteMessage(&livekit.SignalRequest{
+		Message: &livekit.SignalRequest_Leave{Leave: &livekit.LeaveRequest{}},
+	})
+	s.close()
+}
+
+// negotiate sends requests, followed by the offer, returning the answer with local candidates
+func (s *httpSession) negotiate(requests []*livekit.SignalRequest, offer string) (string, error) {
+	requests = append(requests, &livekit.SignalRequest{
+		Message: &livekit.SignalRequest_Offer{
+			Offer: rtc.ToProtoSessionDescription(webrtc.SessionDescription{Type: webrtc.SDPTypeOffer, SDP: offer}),
+		},
+	})
+	for _, req := range requests {
+		if err := s.reqSink.WriteMessage(req); err != nil {
+			return "", err
+		}
+	}
+
+	var answer *livekit.SessionDescription
+	var candidates []string
+	deadline := time.NewTimer(maxInitialResponseWait)
+	defer deadline.Stop()
+	quiet := time.NewTimer(maxCandidateWait)
+	defer quiet.Stop()
+	for {
+		select {
+		case <-deadline.C:
+			if answer == nil {
+				return "", errors.New("timed out while waiting for answer")
+			}
+			return addAnswerCandidates(answer.Sdp, candidates)
+		case <-quiet.C:
+			if answer != nil {
+				return addAnswerCandidates(answer.Sdp, candidates)
+			}
+		case msg := <-s.resSource.ReadChan():
+			if msg == nil {
+				return "", errors.New("connection closed by media")
+			}
+			res, ok := msg.(*livekit.SignalResponse)
+			if !ok {
+				continue
+			}
+			switch m := res.Message.(type) {
+			case *livekit.SignalResponse_Answer:
+				answer = m.Answer
+				resetTimer(deadline, maxCandidateWait)
+				resetTimer(quiet, candidateQuietPeriod)
+			case *livekit.SignalResponse_Trickle:
+				if m.Trickle.Target != s.target {
+					continue
+				}
+				candidate, err := rtc.FromProtoTrickle(m.Trickle)
+				if err != nil {
+					s.logger.Warnw("could not parse local candidate", err)
+					continue
+				}
+				candidates = append(candidates, candidate.Candidate)
+				if answer != nil {
+					resetTimer(quiet, candidateQuietPeriod)
+				}
+			case *livekit.SignalResponse_Leave:
+				return "", errors.New("participant was removed")
+			}
+		}
+	}
+}
+
+// httpSessions starts sessions and serves their resources, PATCH to trickle candidates and DELETE to end them.
+// A session is a resource on the node that accepted its offer, at <pathPrefix>/<participant sid>/<secret>.
+type httpSessions struct {
+	rtcService *RTCService
+	// protocol, for logs and metrics
+	operation  string
+	pathPrefix string
+
+	lock     sync.Mutex
+	sessions map[string]*httpSession
+}
+
+func newHTTPSessions(rtcService *RTCService, operation string, pathPrefix string) *httpSessions {
+	return &httpSessions{
+		rtcService: rtcService,
+		operation:  operation,
+		pathPrefix: pathPrefix,
+		sessions:   make(map[string]*httpSession),
+	}
+}
+
+// start joins pi to the room and negotiates the offer, responding with the answer
+func (s *httpSessions) start(
+	w http.ResponseWriter,
+	r *http.Request,
+	roomName livekit.RoomName,
+	pi routing.ParticipantInit,
+	target livekit.SignalTarget,
+	requests []*livekit.SignalRequest,
+	offer string,
+) {
+	loggerFields := []interface{}{
+		"participant", pi.Identity,
+		"room", roomName,
+		"apiKey", GetAPIKey(r.Context()),
+	}
+	pi.HTTPSignaled = true
+
+	_, connID, reqSink, resSource, code, err := s.rtcService.startParticipantSignal(r.Context(), s.operation, roomName, pi)
+	if err != nil {
+		handleError(w, code, err, loggerFields...)
+		return
+	}
+
+	initialResponse, err := readInitialResponse(resSource, maxInitialResponseWait)
+	if err == nil && getWaitingResponse(initialResponse) != nil {
+		// the room is gated, sessions end once their signal connection is closed
+		resSource.Close()
+		reqSink.Close()
+		handleError(w, http.StatusForbidden, ErrWaitingRoomUnsupported, loggerFields...)
+		return
+	}
+	if err == nil && initialResponse.GetJoin() == nil {
+		err = fmt.Errorf("unexpected initial response: %T", initialResponse.Message)
+	}
+	if err != nil {
+		prometheus.ServiceOperationCounter.WithLabelValues(s.operation, "error", "initial_response").Add(1)
+		resSource.Close()
+		reqSink.Close()
+		handleError(w, http.StatusInternalServerError, err, loggerFields...)
+		return
+	}
+
+	join := initialResponse.GetJoin()
+	session := &httpSession{
+		id:            join.GetParticipant().GetSid(),
+		secret:        utils.RandomSecret(),
+		tokenIdentity: GetGrants(r.Context()).Identity,
+		roomName:      roomName,
+		target:        target,
+		reqSink:       reqSink,
+		resSource:     resSource,
+		logger: rtc.LoggerWithParticipant(
+			rtc.LoggerWithRoom(logger.GetDefaultLogger(), roomName, livekit.RoomID(join.GetRoom().GetSid())),
+			pi.Identity,
+			livekit.ParticipantID(join.GetParticipant().GetSid()),
+			false,
+		),
+	}
+
+	answer, err := session.negotiate(requests, offer)
+	if err != nil {
+		prometheus.ServiceOperationCounter.WithLabelValues(s.operation, "error", "negotiate").Add(1)
+		session.leave()
+		handleError(w, http.StatusInternalServerError, err, loggerFields...)
+		return
+	}
+
+	s.lock.Lock()
+	s.sessions[session.id] = session
+	s.lock.Unlock()
+	go s.sessionWorker(session)
+
+	prometheus.ServiceOperationCounter.WithLabelValues(s.operation, "success", "").Add(1)
+	session.logger.Infow("new "+s.operation+" session", "connID", connID)
+
+	w.Header().Set("Content-Type", sdpContentType)
+	w.Header().Set("Location", s.pathPrefix+"/"+session.id+"/"+session.secret)
+	w.WriteHeader(http.StatusCreated)
+	_, _ = w.Write([]byte(answer))
+}
+
+// sessionWorker drains responses, which HTTP clients have no use for, until the participant leaves
+func (s *httpSessions) sessionWorker(session *httpSession) {
+	defer func() {
+		s.lock.Lock()
+		if s.sessions[session.id] == session {
+			delete(s.sessions, session.id)
+		}
+		s.lock.Unlock()
+		session.close()
+		session.logger.Infow(s.operation + " session finished")
+	}()
+	defer rtc.Recover()
+
+	for msg := range session.resSource.ReadChan() {
+		if res, ok := msg.(*livekit.SignalResponse); ok && res.GetLeave() != nil {
+			return
+		}
+	}
+}
+
+// serveResource handles requests to the session at the request's path
+func (s *httpSessions) serveResource(w http.ResponseWriter, r *http.Request) {
+	id, secret := strings.TrimPrefix(r.URL.Path, s.pathPrefix+"/"), ""
+	if i := strings.Index(id, "/"); i >= 0 {
+		id, secret = id[:i], id[i+1:]
+	}
+	switch r.Method {
+	case http.MethodPatch:
+		s.handleTrickle(w, r, id, secret)
+	case http.MethodDelete:
+		s.handleDelete(w, r, id, secret)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *httpSessions) handleTrickle(w http.ResponseWriter, r *http.Request, id string, secret string) {
+	session, code, err := s.getSession(r, id, secret)
+	if err != nil {
+		handleError(w, code, err)
+		return
+	}
+	if !strings.HasPrefix(r.Header.Get("Content-Type"), trickleContentType) {
+		handleError(w, http.StatusUnsupportedMediaType, ErrUnsupportedContentType)
+		return
+	}
+	frag, err := io.ReadAll(io.LimitReader(r.Body, maxSDPBodySize))
+	if err != nil {
+		handleError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	for _, candidate := range parseTrickleCandidates(string(frag)) {
+		trickle := rtc.ToProtoTrickle(candidate)
+		trickle.Target = session.target
+		if err = session.reqSink.WriteMessage(&livekit.SignalRequest{
+			Message: &livekit.SignalRequest_Trickle{Trickle: trickle},
+		}); err != nil {
+			handleError(w, http.StatusInternalServerError, err)
+			return
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *httpSessions) handleDelete(w http.ResponseWriter, r *http.Request, id string, secret string) {
+	session, code, err := s.getSession(r, id, secret)
+	if err != nil {
+		handleError(w, code, err)
+		return
+	}
+
+	s.lock.Lock()
+	delete(s.sessions, id)
+	s.lock.Unlock()
+
+	session.leave()
+	w.WriteHeader(http.StatusOK)
+}
+
+// getSession returns a session that was created with the request's token identity, when the request has its secret.
+// Sessions are not found without their secret, as identities of tokens may be shared by many clients.
+func (s *httpSessions) getSession(r *http.Request, id string, secret string) (*httpSession, int, error) {
+	claims := GetGrants(r.Context())
+	if claims == nil || claims.Video == nil {
+		return nil, http.StatusUnauthorized, rtc.ErrPermissionDenied
+	}
+
+	s.lock.Lock()
+	session := s.sessions[id]
+	s.lock.Unlock()
+	if session == nil || subtle.ConstantTimeCompare([]byte(secret), []byte(session.secret)) != 1 {
+		return nil, http.StatusNotFound, ErrSessionNotFound
+	}
+
+	roomName, err := EnsureJoinPermission(r.Context())
+	if err != nil {
+		return nil, http.StatusUnauthorized, err
+	}
+	if claims.Identity != session.tokenIdentity || (roomName != "" && roomName != session.roomName) {
+		return nil, http.StatusUnauthorized, rtc.ErrPermissionDenied
+	}
+	return session, http.StatusOK, nil
+}
+
+// readOffer reads the SDP offer of a request starting a session
+func readOffer(r *http.Request) (string, int, error) {
+	if !strings.HasPrefix(r.Header.Get("Content-Type"), sdpContentType) {
+		return "", http.StatusUnsupportedMediaType, ErrUnsupportedContentType
+	}
+	offer, err := io.ReadAll(io.LimitReader(r.Body, maxSDPBodySize))
+	if err != nil {
+		return "", http.StatusBadRequest, err
+	}
+	return string(offer), http.StatusOK, nil
+}
+
+// addAnswerCandidates adds local candidates to each media section of the answer
+func addAnswerCandidates(answer string, candidates []string) (string, error) {
+	sd := sdp.SessionDescription{}
+	if err := sd.Unmarshal([]byte(answer)); err != nil {
+		return "", err
+	}
+
+	for _, md := range sd.MediaDescriptions {
+		if _, ok := md.Attribute(sdp.AttrKeyCandidate); ok {
+			// already gathered
+			continue
+		}
+		for _, c := range candidates {
+			md.WithValueAttribute(sdp.AttrKeyCandidate, strings.TrimPrefix(c, "candidate:"))
+		}
+		md.WithPropertyAttribute(sdp.AttrKeyEndOfCandidates)
+	}
+
+	b, err := sd.Marshal()
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// parseTrickleCandidates reads candidates of a trickle-ice-sdpfrag, along with the media section they belong to
+func parseTrickleCandidates(frag string) []webrtc.ICECandidateInit {
+	var candidates []webrtc.ICECandidateInit
+	var mid *string
+	for _, line := range strings.Split(frag, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "a=mid:"):
+			m := strings.TrimPrefix(line, "a=mid:")
+			mid = &m
+		case strings.HasPrefix(line, "a=candidate:"):
+			candidates = append(candidates, webrtc.ICECandidateInit{
+				Candidate: strings.TrimPrefix(line, "a="),
+				SDPMid:    mid,
+			})
+		}
+	}
+	return candidates
+}
+
+// mediaSections returns sections of an offer for audio and video, skipping those that are inactive
+func mediaSections(offer string) ([]*sdp.MediaDescription, error) {
+	sd := sdp.SessionDescription{}
+	if err := sd.Unmarshal([]byte(offer)); err != nil {
+		return nil, err
+	}
+
+	var sections []*sdp.MediaDescription
+	for _, md := range sd.MediaDescriptions {
+		if md.MediaName.Media != "audio" && md.MediaName.Media != "video" {
+			continue
+		}
+		if _, ok := md.Attribute(sdp.AttrKeyInactive); ok {
+			continue
+		}
+		sections = append(sections, md)
+	}
+	return sections, nil
+}
+
+func resetTimer(t *time.Timer, d time.Duration) {
+	if !t.Stop() {
+		select {
+		case <-t.C:
+		default:
+		}
+	}
+	t.Reset(d)
+}
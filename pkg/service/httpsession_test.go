@@ -0,0 +1,129 @@
+package service_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/routing"
+	"github.com/livekit/livekit-server/pkg/routing/routingfakes"
+	"github.com/livekit/livekit-server/pkg/service"
+	"github.com/livekit/livekit-server/pkg/service/servicefakes"
+)
+
+// httpSessionTest stands in for the participant on the media node of WHIP and WHEP sessions. Each session
+// joins as participantSID and gets its own signal channels.
+type httpSessionTest struct {
+	t              *testing.T
+	participantSID string
+	// checks the participant joining
+	checkInit func(pi routing.ParticipantInit)
+	// responses to an offer
+	respond func(offer *livekit.SessionDescription) []*livekit.SignalResponse
+
+	requests chan *livekit.SignalRequest
+	reqSink  *routing.MessageChannel
+}
+
+func newHTTPSessionTest(
+	t *testing.T,
+	participantSID string,
+	checkInit func(pi routing.ParticipantInit),
+	respond func(offer *livekit.SessionDescription) []*livekit.SignalResponse,
+) *httpSessionTest {
+	return &httpSessionTest{
+		t:              t,
+		participantSID: participantSID,
+		checkInit:      checkInit,
+		respond:        respond,
+		requests:       make(chan *livekit.SignalRequest, 10),
+	}
+}
+
+// newRTCService returns a service for sessions started with store
+func (h *httpSessionTest) newRTCService(store service.ServiceStore) *service.RTCService {
+	conf, err := config.NewConfig("", true, nil, nil)
+	require.NoError(h.t, err)
+	node, err := routing.NewLocalNode(conf)
+	require.NoError(h.t, err)
+	ra, conf := newTestRoomAllocator(h.t, conf, node)
+
+	router := &routingfakes.FakeRouter{}
+	router.GetNodeForRoomReturns(node, nil)
+	router.StartParticipantSignalCalls(h.startParticipantSignal)
+	if store == nil {
+		store = &servicefakes.FakeServiceStore{}
+	}
+	return service.NewRTCService(conf, ra, store, router, node, nil, nil, nil)
+}
+
+func (h *httpSessionTest) startParticipantSignal(_ context.Context, _ livekit.RoomName, pi routing.ParticipantInit) (livekit.ConnectionID, routing.MessageSink, routing.MessageSource, error) {
+	require.True(h.t, pi.HTTPSignaled)
+	h.checkInit(pi)
+
+	reqSink := routing.NewMessageChannel(routing.DefaultMessageChannelSize)
+	resSource := routing.NewMessageChannel(routing.DefaultMessageChannelSize)
+	h.reqSink = reqSink
+	_ = resSource.WriteMessage(&livekit.SignalResponse{
+		Message: &livekit.SignalResponse_Join{Join: &livekit.JoinResponse{
+			Room:        &livekit.Room{Sid: "RM_test", Name: "room1"},
+			Participant: &livekit.ParticipantInfo{Sid: h.participantSID, Identity: string(pi.Identity)},
+		}},
+	})
+
+	go func() {
+		for msg := range reqSink.ReadChan() {
+			req := msg.(*livekit.SignalRequest)
+			h.requests <- req
+			if req.GetOffer() == nil {
+				continue
+			}
+			for _, res := range h.respond(req.GetOffer()) {
+				_ = resSource.WriteMessage(res)
+			}
+		}
+	}()
+	return "CO_test", reqSink, resSource, nil
+}
+
+func (h *httpSessionTest) nextRequest() *livekit.SignalRequest {
+	select {
+	case req := <-h.requests:
+		return req
+	case <-time.After(time.Second):
+		require.Fail(h.t, "no request received")
+		return nil
+	}
+}
+
+// newHTTPSessionRequest returns a request made with a token for identity, granted by video
+func newHTTPSessionRequest(method, path, contentType, body, identity string, video *auth.VideoGrant) *http.Request {
+	r := httptest.NewRequest(method, path, strings.NewReader(body))
+	r.Header.Set("Content-Type", contentType)
+	return r.WithContext(service.WithGrants(r.Context(), &auth.ClaimGrants{
+		Identity: identity,
+		Video:    video,
+	}))
+}
+
+func answerResponse(sdp string) *livekit.SignalResponse {
+	return &livekit.SignalResponse{
+		Message: &livekit.SignalResponse_Answer{Answer: &livekit.SessionDescription{Type: "answer", Sdp: sdp}},
+	}
+}
+
+func trickleResponse(candidate string, target livekit.SignalTarget) *livekit.SignalResponse {
+	return &livekit.SignalResponse{Message: &livekit.SignalResponse_Trickle{Trickle: &livekit.TrickleRequest{
+		CandidateInit: `{"candidate":"` + candidate + `","sdpMid":"0"}`,
+		Target:        target,
+	}}}
+}
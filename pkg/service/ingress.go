@@ -7,6 +7,7 @@ import (
 
 	"google.golang.org/protobuf/proto"
 
+	"github.com/livekit/livekit-server/pkg/audit"
 	"github.com/livekit/livekit-server/pkg/config"
 	"github.com/livekit/livekit-server/pkg/telemetry"
 	"github.com/livekit/protocol/ingress"
@@ -26,6 +27,8 @@ type IngressService struct {
 	store       IngressStore
 	roomService livekit.RoomService
 	telemetry   telemetry.TelemetryService
+	tenancy     *Tenancy
+	auditLogger *audit.Logger
 	shutdown    chan struct{}
 }
 
@@ -35,6 +38,8 @@ func NewIngressService(
 	store IngressStore,
 	rs livekit.RoomService,
 	ts telemetry.TelemetryService,
+	tenancy *Tenancy,
+	auditLogger *audit.Logger,
 ) *IngressService {
 
 	return &IngressService{
@@ -43,6 +48,8 @@ func NewIngressService(
 		store:       store,
 		roomService: rs,
 		telemetry:   ts,
+		tenancy:     tenancy,
+		auditLogger: auditLogger,
 		shutdown:    make(chan struct{}),
 	}
 }
@@ -62,15 +69,22 @@ func (s *IngressService) CreateIngress(ctx context.Context, req *livekit.CreateI
 	return s.CreateIngressWithUrlPrefix(ctx, s.conf.RTMPBaseURL, req)
 }
 
-func (s *IngressService) CreateIngressWithUrlPrefix(ctx context.Context, urlPrefix string, req *livekit.CreateIngressRequest) (*livekit.IngressInfo, error) {
-	err := EnsureIngressAdminPermission(ctx)
+func (s *IngressService) CreateIngressWithUrlPrefix(ctx context.Context, urlPrefix string, req *livekit.CreateIngressRequest) (info *livekit.IngressInfo, err error) {
+	defer func() {
+		auditOperation(ctx, s.auditLogger, auditServiceIngress, "CreateIngress", req, info, err)
+	}()
+
+	err = EnsureIngressAdminPermission(ctx)
 	if err != nil {
 		return nil, twirpAuthError(err)
 	}
+	if err = s.tenancy.EnsureRoom(ctx, livekit.RoomName(req.RoomName)); err != nil {
+		return nil, twirpTenancyError(err)
+	}
 
 	sk := utils.NewGuid("")
 
-	info := &livekit.IngressInfo{
+	info = &livekit.IngressInfo{
 		IngressId:           utils.NewGuid(utils.IngressPrefix),
 		Name:                req.Name,
 		StreamKey:           sk,
@@ -89,6 +103,10 @@ func (s *IngressService) CreateIngressWithUrlPrefix(ctx context.Context, urlPref
 		logger.Errorw("could not write ingress info", err)
 		return nil, err
 	}
+	if err := s.tenancy.ClaimIngress(ctx, info); err != nil {
+		logger.Errorw("could not store tenant of ingress", err)
+		return nil, err
+	}
 
 	return info, nil
 }
@@ -128,8 +146,12 @@ func (s *IngressService) sendRPCWithRetry(ctx context.Context, req *livekit.Ingr
 	}
 }
 
-func (s *IngressService) UpdateIngress(ctx context.Context, req *livekit.UpdateIngressRequest) (*livekit.IngressInfo, error) {
-	err := EnsureIngressAdminPermission(ctx)
+func (s *IngressService) UpdateIngress(ctx context.Context, req *livekit.UpdateIngressRequest) (info *livekit.IngressInfo, err error) {
+	defer func() {
+		auditOperation(ctx, s.auditLogger, auditServiceIngress, "UpdateIngress", req, info, err)
+	}()
+
+	err = EnsureIngressAdminPermission(ctx)
 	if err != nil {
 		return nil, twirpAuthError(err)
 	}
@@ -138,11 +160,17 @@ func (s *IngressService) UpdateIngress(ctx context.Context, req *livekit.UpdateI
 		return nil, ErrIngressNotConnected
 	}
 
-	info, err := s.store.LoadIngress(ctx, req.IngressId)
+	info, err = s.store.LoadIngress(ctx, req.IngressId)
 	if err != nil {
 		logger.Errorw("could not load ingress info", err)
 		return nil, err
 	}
+	if err = s.tenancy.EnsureIngress(ctx, info); err != nil {
+		return nil, twirpTenancyError(err)
+	}
+	if err = s.tenancy.EnsureRoom(ctx, livekit.RoomName(req.RoomName)); err != nil {
+		return nil, twirpTenancyError(err)
+	}
 
 	switch info.State.Status {
 	case livekit.IngressState_ENDPOINT_ERROR:
@@ -207,11 +235,18 @@ func (s *IngressService) ListIngress(ctx context.Context, req *livekit.ListIngre
 		logger.Errorw("could not list ingress info", err)
 		return nil, err
 	}
+	if infos, err = s.tenancy.FilterIngress(ctx, infos); err != nil {
+		return nil, err
+	}
 
 	return &livekit.ListIngressResponse{Items: infos}, nil
 }
 
-func (s *IngressService) DeleteIngress(ctx context.Context, req *livekit.DeleteIngressRequest) (*livekit.IngressInfo, error) {
+func (s *IngressService) DeleteIngress(ctx context.Context, req *livekit.DeleteIngressRequest) (info *livekit.IngressInfo, err error) {
+	defer func() {
+		auditOperation(ctx, s.auditLogger, auditServiceIngress, "DeleteIngress", req, info, err)
+	}()
+
 	if err := EnsureIngressAdminPermission(ctx); err != nil {
 		return nil, twirpAuthError(err)
 	}
@@ -220,10 +255,13 @@ func (s *IngressService) DeleteIngress(ctx context.Context, req *livekit.DeleteI
 		return nil, ErrIngressNotConnected
 	}
 
-	info, err := s.store.LoadIngress(ctx, req.IngressId)
+	info, err = s.store.LoadIngress(ctx, req.IngressId)
 	if err != nil {
 		return nil, err
 	}
+	if err = s.tenancy.EnsureIngress(ctx, info); err != nil {
+		return nil, twirpTenancyError(err)
+	}
 
 	switch info.State.Status {
 	case livekit.IngressState_ENDPOINT_BUFFERING,
@@ -5,6 +5,9 @@ import (
 	"time"
 
 	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/rpc"
+	"github.com/livekit/livekit-server/pkg/rtc"
 )
 
 //go:generate go run github.com/maxbrunsfeld/counterfeiter/v6 -generate
@@ -23,8 +26,18 @@ type ObjectStore interface {
 	StoreRoom(ctx context.Context, room *livekit.Room, internal *livekit.RoomInternal) error
 	DeleteRoom(ctx context.Context, roomName livekit.RoomName) error
 
+	// options are kept until the room is deleted. LoadRoomOptions returns nil for rooms created without them
+	StoreRoomOptions(ctx context.Context, roomName livekit.RoomName, options *rtc.RoomOptions) error
+	LoadRoomOptions(ctx context.Context, roomName livekit.RoomName) (*rtc.RoomOptions, error)
+
 	StoreParticipant(ctx context.Context, roomName livekit.RoomName, participant *livekit.ParticipantInfo) error
 	DeleteParticipant(ctx context.Context, roomName livekit.RoomName, identity livekit.ParticipantIdentity) error
+
+	// participants waiting to join gated rooms, until they are admitted or rejected, or the room is deleted
+	StoreWaitingParticipant(ctx context.Context, roomName livekit.RoomName, participant *rpc.WaitingParticipant) error
+	LoadWaitingParticipant(ctx context.Context, roomName livekit.RoomName, identity livekit.ParticipantIdentity) (*rpc.WaitingParticipant, error)
+	ListWaitingParticipants(ctx context.Context, roomName livekit.RoomName) ([]*rpc.WaitingParticipant, error)
+	DeleteWaitingParticipant(ctx context.Context, roomName livekit.RoomName, identity livekit.ParticipantIdentity) error
 }
 
 //counterfeiter:generate . ServiceStore
@@ -57,6 +70,47 @@ type IngressStore interface {
 	DeleteIngress(ctx context.Context, info *livekit.IngressInfo) error
 }
 
+// TenantResource names a room, egress or ingress that belongs to a tenant
+type TenantResource string
+
+const roomTenantPrefix = "room:"
+
+func RoomTenantResource(roomName livekit.RoomName) TenantResource {
+	return TenantResource(roomTenantPrefix + string(roomName))
+}
+
+func EgressTenantResource(egressID string) TenantResource {
+	return TenantResource("egress:" + egressID)
+}
+
+func IngressTenantResource(ingressID string) TenantResource {
+	return TenantResource("ingress:" + ingressID)
+}
+
+// records tenants that rooms, egress and ingress belong to, and participants counted in their usage. records are
+// deleted along with rooms and ingress, and with egress once it's cleaned up
+//
+//counterfeiter:generate . TenantStore
+type TenantStore interface {
+	StoreTenant(ctx context.Context, resource TenantResource, tenant string) error
+	DeleteTenant(ctx context.Context, resource TenantResource, tenant string) error
+	// LoadTenant returns an empty tenant for resources that don't belong to one
+	LoadTenant(ctx context.Context, resource TenantResource) (string, error)
+	ListTenantRooms(ctx context.Context, tenant string) ([]livekit.RoomName, error)
+
+	// LockTenant locks the tenant for its rooms to be counted and claimed at once, the same way LockRoom locks a room.
+	// returns a (lock uuid, error)
+	LockTenant(ctx context.Context, tenant string, duration time.Duration) (string, error)
+	UnlockTenant(ctx context.Context, tenant string, uid string) error
+
+	// StoreTenantParticipant counts a participant in rooms of the tenant, along with tracks it publishes, for as long
+	// as the node it's on is alive
+	StoreTenantParticipant(ctx context.Context, tenant string, nodeID livekit.NodeID, participantID livekit.ParticipantID, tracks int) error
+	DeleteTenantParticipant(ctx context.Context, tenant string, participantID livekit.ParticipantID) error
+	// LoadTenantUsage returns how many participants are counted for the tenant, and tracks they publish
+	LoadTenantUsage(ctx context.Context, tenant string) (participants int, tracks int, err error)
+}
+
 //counterfeiter:generate . RoomAllocator
 type RoomAllocator interface {
 	CreateRoom(ctx context.Context, req *livekit.CreateRoomRequest) (*livekit.Room, error)
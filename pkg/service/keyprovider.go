@@ -0,0 +1,412 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/telemetry/prometheus"
+)
+
+const keyProviderFetchTimeout = 5 * time.Second
+
+type KeyStatus string
+
+const (
+	KeyStatusActive   KeyStatus = "active"
+	KeyStatusDisabled KeyStatus = "disabled"
+	KeyStatusExpired  KeyStatus = "expired"
+	KeyStatusUnknown  KeyStatus = "unknown"
+)
+
+// APIKey is an entry of the key file, either a secret or a mapping with secret, expires_at and disabled
+type APIKey struct {
+	Secret    string    `yaml:"secret"`
+	ExpiresAt time.Time `yaml:"expires_at,omitempty"`
+	Disabled  bool      `yaml:"disabled,omitempty"`
+}
+
+func (k *APIKey) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		k.Secret = value.Value
+		return nil
+	}
+
+	type apiKey APIKey
+	return value.Decode((*apiKey)(k))
+}
+
+func (k *APIKey) Status() KeyStatus {
+	switch {
+	case k == nil || k.Secret == "":
+		return KeyStatusUnknown
+	case k.Disabled:
+		return KeyStatusDisabled
+	case !k.ExpiresAt.IsZero() && time.Now().After(k.ExpiresAt):
+		return KeyStatusExpired
+	default:
+		return KeyStatusActive
+	}
+}
+
+type keySource string
+
+// sources in order of precedence, later sources override keys of earlier ones
+const (
+	keySourceConfig keySource = "config"
+	keySourceFile   keySource = "key_file"
+	keySourceDir    keySource = "dir"
+	keySourceURL    keySource = "url"
+)
+
+var keySources = []keySource{keySourceConfig, keySourceFile, keySourceDir, keySourceURL}
+
+// KeyProvider serves API keys from config, the key file, a directory with a file per key, and an HTTP endpoint.
+// Key file, directory and endpoint are checked for changes periodically, a source that fails to load keeps its last keys
+type KeyProvider struct {
+	conf    config.KeyProviderConfig
+	keyFile string
+	client  *http.Client
+
+	lock        sync.RWMutex
+	sourceKeys  map[keySource]map[string]*APIKey
+	keys        map[string]*APIKey
+	keyFileStat os.FileInfo
+
+	done chan struct{}
+}
+
+func NewKeyProvider(conf *config.Config) (*KeyProvider, error) {
+	p := &KeyProvider{
+		conf:       conf.KeyProvider,
+		keyFile:    conf.KeyFile,
+		client:     &http.Client{Timeout: keyProviderFetchTimeout},
+		sourceKeys: make(map[keySource]map[string]*APIKey),
+		keys:       make(map[string]*APIKey),
+		done:       make(chan struct{}),
+	}
+
+	p.sourceKeys[keySourceConfig] = keysFromMap(conf.Keys)
+	for _, source := range keySources[1:] {
+		if !p.isConfigured(source) {
+			continue
+		}
+		keys, err := p.load(source)
+		if err != nil {
+			return nil, err
+		}
+		p.sourceKeys[source] = keys
+	}
+	p.update()
+
+	if len(p.keys) == 0 {
+		return nil, errors.New("one of key-file, keys or key provider must be provided in order to support a secure installation")
+	}
+
+	if p.keyFile != "" || p.conf.Dir != "" || p.conf.URL != "" {
+		go p.refreshWorker()
+	}
+	return p, nil
+}
+
+func (p *KeyProvider) Stop() {
+	select {
+	case <-p.done:
+	default:
+		close(p.done)
+	}
+}
+
+func (p *KeyProvider) GetSecret(key string) string {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	k := p.keys[key]
+	if k.Status() != KeyStatusActive {
+		return ""
+	}
+	return k.Secret
+}
+
+func (p *KeyProvider) NumKeys() int {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	return len(p.keys)
+}
+
+func (p *KeyProvider) GetKeyStatus(key string) KeyStatus {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	return p.keys[key].Status()
+}
+
+// GetActiveKey returns an active key and its secret, for tokens issued by the server
+func (p *KeyProvider) GetActiveKey() (string, string) {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	names := make([]string, 0, len(p.keys))
+	for name, k := range p.keys {
+		if k.Status() == KeyStatusActive {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return "", ""
+	}
+	sort.Strings(names)
+	return names[0], p.keys[names[0]].Secret
+}
+
+// SetKeys replaces keys set in config
+func (p *KeyProvider) SetKeys(keys map[string]string) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.sourceKeys[keySourceConfig] = keysFromMap(keys)
+	p.updateLocked()
+}
+
+// Refresh reloads the key file when it has changed, and keys from the directory and endpoint
+func (p *KeyProvider) Refresh() {
+	for _, source := range keySources[1:] {
+		if !p.isConfigured(source) {
+			continue
+		}
+		if source == keySourceFile && !p.keyFileChanged() {
+			continue
+		}
+
+		keys, err := p.load(source)
+		if err != nil {
+			logger.Warnw("could not refresh API keys", err, "source", source)
+			continue
+		}
+
+		p.lock.Lock()
+		p.sourceKeys[source] = keys
+		p.lock.Unlock()
+	}
+	p.update()
+}
+
+func (p *KeyProvider) refreshWorker() {
+	interval := p.conf.RefreshInterval
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-ticker.C:
+			p.Refresh()
+		}
+	}
+}
+
+func (p *KeyProvider) isConfigured(source keySource) bool {
+	switch source {
+	case keySourceConfig:
+		return true
+	case keySourceFile:
+		return p.keyFile != ""
+	case keySourceDir:
+		return p.conf.Dir != ""
+	case keySourceURL:
+		return p.conf.URL != ""
+	}
+	return false
+}
+
+func (p *KeyProvider) load(source keySource) (map[string]*APIKey, error) {
+	switch source {
+	case keySourceFile:
+		return p.loadKeyFile()
+	case keySourceDir:
+		return p.loadDir()
+	case keySourceURL:
+		return p.loadURL()
+	}
+	return nil, fmt.Errorf("unknown key source: %s", source)
+}
+
+func (p *KeyProvider) keyFileChanged() bool {
+	st, err := os.Stat(p.keyFile)
+	if err != nil {
+		// reported when loading
+		return true
+	}
+
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	return p.keyFileStat == nil || !st.ModTime().Equal(p.keyFileStat.ModTime()) || st.Size() != p.keyFileStat.Size()
+}
+
+func (p *KeyProvider) loadKeyFile() (map[string]*APIKey, error) {
+	st, err := os.Stat(p.keyFile)
+	if err != nil {
+		return nil, err
+	} else if st.Mode().Perm() != 0600 {
+		return nil, fmt.Errorf("key file must have permission set to 600")
+	}
+
+	data, err := os.ReadFile(p.keyFile)
+	if err != nil {
+		return nil, err
+	}
+	keys, err := decodeKeys(data)
+	if err != nil {
+		return nil, err
+	}
+
+	p.lock.Lock()
+	p.keyFileStat = st
+	p.lock.Unlock()
+	return keys, nil
+}
+
+// loadDir reads a file per key, named after the key and containing its secret, skipping hidden files
+func (p *KeyProvider) loadDir() (map[string]*APIKey, error) {
+	entries, err := os.ReadDir(p.conf.Dir)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]*APIKey)
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		// mounted secrets are commonly symlinks
+		st, err := os.Stat(filepath.Join(p.conf.Dir, entry.Name()))
+		if err != nil || st.IsDir() {
+			continue
+		}
+
+		secret, err := os.ReadFile(filepath.Join(p.conf.Dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		if s := strings.TrimSpace(string(secret)); s != "" {
+			keys[entry.Name()] = &APIKey{Secret: s}
+		}
+	}
+	return keys, nil
+}
+
+func (p *KeyProvider) loadURL() (map[string]*APIKey, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), keyProviderFetchTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.conf.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = res.Body.Close()
+	}()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status from key provider url: %d", res.StatusCode)
+	}
+
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	return decodeKeys(data)
+}
+
+func (p *KeyProvider) update() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.updateLocked()
+}
+
+func (p *KeyProvider) updateLocked() {
+	keys := make(map[string]*APIKey)
+	for _, source := range keySources {
+		for name, k := range p.sourceKeys[source] {
+			keys[name] = k
+		}
+	}
+
+	var added, removed, changed []string
+	for name, k := range keys {
+		if prev, ok := p.keys[name]; !ok {
+			added = append(added, name)
+		} else if !reflect.DeepEqual(prev, k) {
+			changed = append(changed, name)
+		}
+	}
+	for name := range p.keys {
+		if _, ok := keys[name]; !ok {
+			removed = append(removed, name)
+		}
+	}
+	p.keys = keys
+
+	if len(added)+len(removed)+len(changed) > 0 {
+		sort.Strings(added)
+		sort.Strings(removed)
+		sort.Strings(changed)
+		logger.Infow("API keys updated", "added", added, "removed", removed, "changed", changed)
+	}
+
+	counts := make(map[KeyStatus]int)
+	for _, k := range keys {
+		counts[k.Status()]++
+	}
+	for _, status := range []KeyStatus{KeyStatusActive, KeyStatusDisabled, KeyStatusExpired} {
+		prometheus.SetAPIKeys(string(status), counts[status])
+	}
+}
+
+func decodeKeys(data []byte) (map[string]*APIKey, error) {
+	keys := make(map[string]*APIKey)
+	if len(bytes.TrimSpace(data)) == 0 {
+		return keys, nil
+	}
+	if err := yaml.Unmarshal(data, &keys); err != nil {
+		return nil, err
+	}
+	for name, k := range keys {
+		if k == nil || k.Secret == "" {
+			delete(keys, name)
+		}
+	}
+	return keys, nil
+}
+
+func keysFromMap(m map[string]string) map[string]*APIKey {
+	keys := make(map[string]*APIKey, len(m))
+	for name, secret := range m {
+		keys[name] = &APIKey{Secret: secret}
+	}
+	return keys
+}
@@ -0,0 +1,108 @@
+package service_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/service"
+)
+
+func TestKeyProvider(t *testing.T) {
+	t.Run("reads key states from key file", func(t *testing.T) {
+		keyFile := filepath.Join(t.TempDir(), "keys.yaml")
+		require.NoError(t, os.WriteFile(keyFile, []byte(`key1: secret1
+key2:
+  secret: secret2
+  disabled: true
+key3:
+  secret: secret3
+  expires_at: 2020-01-01T00:00:00Z
+key4:
+  secret: secret4
+  expires_at: 2100-01-01T00:00:00Z
+`), 0600))
+
+		p, err := service.NewKeyProvider(&config.Config{KeyFile: keyFile})
+		require.NoError(t, err)
+		defer p.Stop()
+
+		require.Equal(t, "secret1", p.GetSecret("key1"))
+		require.Empty(t, p.GetSecret("key2"))
+		require.Equal(t, service.KeyStatusDisabled, p.GetKeyStatus("key2"))
+		require.Empty(t, p.GetSecret("key3"))
+		require.Equal(t, service.KeyStatusExpired, p.GetKeyStatus("key3"))
+		require.Equal(t, "secret4", p.GetSecret("key4"))
+		require.Equal(t, service.KeyStatusUnknown, p.GetKeyStatus("key5"))
+
+		key, secret := p.GetActiveKey()
+		require.Equal(t, "key1", key)
+		require.Equal(t, "secret1", secret)
+	})
+
+	t.Run("key file must be private", func(t *testing.T) {
+		keyFile := filepath.Join(t.TempDir(), "keys.yaml")
+		require.NoError(t, os.WriteFile(keyFile, []byte("key1: secret1"), 0644))
+
+		_, err := service.NewKeyProvider(&config.Config{KeyFile: keyFile})
+		require.Error(t, err)
+	})
+
+	t.Run("refreshes sources", func(t *testing.T) {
+		keyFile := filepath.Join(t.TempDir(), "keys.yaml")
+		require.NoError(t, os.WriteFile(keyFile, []byte("key1: secret1"), 0600))
+
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "key2"), []byte("secret2\n"), 0600))
+
+		urlKeys := `{"key3": "secret3"}`
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(urlKeys))
+		}))
+		defer server.Close()
+
+		p, err := service.NewKeyProvider(&config.Config{
+			KeyFile: keyFile,
+			Keys:    map[string]string{"key0": "secret0"},
+			KeyProvider: config.KeyProviderConfig{
+				Dir:             dir,
+				URL:             server.URL,
+				RefreshInterval: time.Hour,
+			},
+		})
+		require.NoError(t, err)
+		defer p.Stop()
+		require.Equal(t, 4, p.NumKeys())
+		require.Equal(t, "secret2", p.GetSecret("key2"))
+		require.Equal(t, "secret3", p.GetSecret("key3"))
+
+		// rotate
+		require.NoError(t, os.WriteFile(keyFile, []byte("key1: rotated1\n"), 0600))
+		require.NoError(t, os.Remove(filepath.Join(dir, "key2")))
+		urlKeys = `{"key3": {"secret": "secret3", "disabled": true}}`
+		p.Refresh()
+
+		require.Equal(t, "rotated1", p.GetSecret("key1"))
+		require.Equal(t, service.KeyStatusUnknown, p.GetKeyStatus("key2"))
+		require.Equal(t, service.KeyStatusDisabled, p.GetKeyStatus("key3"))
+
+		// keeps last keys of a source that fails
+		server.Close()
+		p.Refresh()
+		require.Equal(t, service.KeyStatusDisabled, p.GetKeyStatus("key3"))
+
+		p.SetKeys(nil)
+		require.Equal(t, service.KeyStatusUnknown, p.GetKeyStatus("key0"))
+	})
+
+	t.Run("requires keys", func(t *testing.T) {
+		_, err := service.NewKeyProvider(&config.Config{})
+		require.Error(t, err)
+	})
+}
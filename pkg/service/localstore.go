@@ -2,12 +2,16 @@ package service
 
 import (
 	"context"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/thoas/go-funk"
 
 	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/rpc"
+	"github.com/livekit/livekit-server/pkg/rtc"
 )
 
 // encapsulates CRUD operations for room settings
@@ -15,19 +19,34 @@ type LocalStore struct {
 	// map of roomName => room
 	rooms        map[livekit.RoomName]*livekit.Room
 	roomInternal map[livekit.RoomName]*livekit.RoomInternal
+	roomOptions  map[livekit.RoomName]*rtc.RoomOptions
 	// map of roomName => { identity: participant }
 	participants map[livekit.RoomName]map[livekit.ParticipantIdentity]*livekit.ParticipantInfo
+	// map of roomName => { identity: waiting participant }
+	waitingParticipants map[livekit.RoomName]map[livekit.ParticipantIdentity]*rpc.WaitingParticipant
+	// map of tenant resource => tenant
+	tenants map[TenantResource]string
+	// map of tenant => room names
+	tenantRooms map[string]map[livekit.RoomName]bool
+	// map of tenant => { participantID: number of tracks }
+	tenantParticipants map[string]map[livekit.ParticipantID]int
 
 	lock       sync.RWMutex
 	globalLock sync.Mutex
+	tenantLock sync.Mutex
 }
 
 func NewLocalStore() *LocalStore {
 	return &LocalStore{
-		rooms:        make(map[livekit.RoomName]*livekit.Room),
-		roomInternal: make(map[livekit.RoomName]*livekit.RoomInternal),
-		participants: make(map[livekit.RoomName]map[livekit.ParticipantIdentity]*livekit.ParticipantInfo),
-		lock:         sync.RWMutex{},
+		rooms:               make(map[livekit.RoomName]*livekit.Room),
+		roomInternal:        make(map[livekit.RoomName]*livekit.RoomInternal),
+		roomOptions:         make(map[livekit.RoomName]*rtc.RoomOptions),
+		participants:        make(map[livekit.RoomName]map[livekit.ParticipantIdentity]*livekit.ParticipantInfo),
+		waitingParticipants: make(map[livekit.RoomName]map[livekit.ParticipantIdentity]*rpc.WaitingParticipant),
+		tenants:             make(map[TenantResource]string),
+		tenantRooms:         make(map[string]map[livekit.RoomName]bool),
+		tenantParticipants:  make(map[string]map[livekit.ParticipantID]int),
+		lock:                sync.RWMutex{},
 	}
 }
 
@@ -62,6 +81,21 @@ func (s *LocalStore) LoadRoom(_ context.Context, roomName livekit.RoomName, incl
 	return room, internal, nil
 }
 
+func (s *LocalStore) StoreRoomOptions(_ context.Context, roomName livekit.RoomName, options *rtc.RoomOptions) error {
+	s.lock.Lock()
+	s.roomOptions[roomName] = options
+	s.lock.Unlock()
+
+	return nil
+}
+
+func (s *LocalStore) LoadRoomOptions(_ context.Context, roomName livekit.RoomName) (*rtc.RoomOptions, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return s.roomOptions[roomName], nil
+}
+
 func (s *LocalStore) ListRooms(_ context.Context, roomNames []livekit.RoomName) ([]*livekit.Room, error) {
 	s.lock.RLock()
 	defer s.lock.RUnlock()
@@ -85,9 +119,20 @@ func (s *LocalStore) DeleteRoom(ctx context.Context, roomName livekit.RoomName)
 	s.lock.Lock()
 	defer s.lock.Unlock()
 
+	// participants left in the room are no longer counted for its tenant
+	if tenant := s.tenants[RoomTenantResource(roomName)]; tenant != "" {
+		delete(s.tenantRooms[tenant], roomName)
+		for _, pi := range s.participants[roomName] {
+			delete(s.tenantParticipants[tenant], livekit.ParticipantID(pi.Sid))
+		}
+	}
+
 	delete(s.participants, livekit.RoomName(room.Name))
+	delete(s.waitingParticipants, livekit.RoomName(room.Name))
 	delete(s.rooms, livekit.RoomName(room.Name))
 	delete(s.roomInternal, livekit.RoomName(room.Name))
+	delete(s.roomOptions, livekit.RoomName(room.Name))
+	delete(s.tenants, RoomTenantResource(livekit.RoomName(room.Name)))
 	return nil
 }
 
@@ -157,3 +202,139 @@ func (s *LocalStore) DeleteParticipant(_ context.Context, roomName livekit.RoomN
 	}
 	return nil
 }
+
+func (s *LocalStore) StoreWaitingParticipant(_ context.Context, roomName livekit.RoomName, participant *rpc.WaitingParticipant) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	roomParticipants := s.waitingParticipants[roomName]
+	if roomParticipants == nil {
+		roomParticipants = make(map[livekit.ParticipantIdentity]*rpc.WaitingParticipant)
+		s.waitingParticipants[roomName] = roomParticipants
+	}
+	roomParticipants[livekit.ParticipantIdentity(participant.Participant.Identity)] = participant
+	return nil
+}
+
+func (s *LocalStore) LoadWaitingParticipant(_ context.Context, roomName livekit.RoomName, identity livekit.ParticipantIdentity) (*rpc.WaitingParticipant, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	participant := s.waitingParticipants[roomName][identity]
+	if participant == nil {
+		return nil, ErrParticipantNotWaiting
+	}
+	return participant, nil
+}
+
+func (s *LocalStore) ListWaitingParticipants(_ context.Context, roomName livekit.RoomName) ([]*rpc.WaitingParticipant, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	roomParticipants := s.waitingParticipants[roomName]
+	items := make([]*rpc.WaitingParticipant, 0, len(roomParticipants))
+	for _, p := range roomParticipants {
+		items = append(items, p)
+	}
+	return items, nil
+}
+
+func (s *LocalStore) DeleteWaitingParticipant(_ context.Context, roomName livekit.RoomName, identity livekit.ParticipantIdentity) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	roomParticipants := s.waitingParticipants[roomName]
+	if roomParticipants != nil {
+		delete(roomParticipants, identity)
+		if len(roomParticipants) == 0 {
+			delete(s.waitingParticipants, roomName)
+		}
+	}
+	return nil
+}
+
+func (s *LocalStore) StoreTenant(_ context.Context, resource TenantResource, tenant string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.tenants[resource] = tenant
+	if strings.HasPrefix(string(resource), roomTenantPrefix) {
+		if s.tenantRooms[tenant] == nil {
+			s.tenantRooms[tenant] = make(map[livekit.RoomName]bool)
+		}
+		s.tenantRooms[tenant][livekit.RoomName(strings.TrimPrefix(string(resource), roomTenantPrefix))] = true
+	}
+	return nil
+}
+
+func (s *LocalStore) DeleteTenant(_ context.Context, resource TenantResource, tenant string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	delete(s.tenants, resource)
+	if strings.HasPrefix(string(resource), roomTenantPrefix) {
+		delete(s.tenantRooms[tenant], livekit.RoomName(strings.TrimPrefix(string(resource), roomTenantPrefix)))
+		if len(s.tenantRooms[tenant]) == 0 {
+			delete(s.tenantRooms, tenant)
+		}
+	}
+	return nil
+}
+
+func (s *LocalStore) LoadTenant(_ context.Context, resource TenantResource) (string, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return s.tenants[resource], nil
+}
+
+func (s *LocalStore) ListTenantRooms(_ context.Context, tenant string) ([]livekit.RoomName, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	roomNames := make([]livekit.RoomName, 0, len(s.tenantRooms[tenant]))
+	for roomName := range s.tenantRooms[tenant] {
+		roomNames = append(roomNames, roomName)
+	}
+	return roomNames, nil
+}
+
+func (s *LocalStore) LockTenant(_ context.Context, _ string, _ time.Duration) (string, error) {
+	// local tenants lock & unlock globally, apart from rooms
+	s.tenantLock.Lock()
+	return "", nil
+}
+
+func (s *LocalStore) UnlockTenant(_ context.Context, _ string, _ string) error {
+	s.tenantLock.Unlock()
+	return nil
+}
+
+func (s *LocalStore) StoreTenantParticipant(_ context.Context, tenant string, _ livekit.NodeID, participantID livekit.ParticipantID, tracks int) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.tenantParticipants[tenant] == nil {
+		s.tenantParticipants[tenant] = make(map[livekit.ParticipantID]int)
+	}
+	s.tenantParticipants[tenant][participantID] = tracks
+	return nil
+}
+
+func (s *LocalStore) DeleteTenantParticipant(_ context.Context, tenant string, participantID livekit.ParticipantID) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	delete(s.tenantParticipants[tenant], participantID)
+	return nil
+}
+
+func (s *LocalStore) LoadTenantUsage(_ context.Context, tenant string) (int, int, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	tracks := 0
+	for _, n := range s.tenantParticipants[tenant] {
+		tracks += n
+	}
+	return len(s.tenantParticipants[tenant]), tracks, nil
+}
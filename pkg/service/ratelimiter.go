@@ -0,0 +1,220 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/twitchtv/twirp"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/telemetry/prometheus"
+	"github.com/livekit/livekit-server/pkg/utils"
+)
+
+const (
+	twirpPathPrefix = "/twirp/"
+
+	rateLimitedAPI = "api"
+	rateLimitedRTC = "rtc"
+
+	rateLimitByAPIKey   = "api_key"
+	rateLimitByClientIP = "client_ip"
+	rateLimitByRoom     = "room"
+
+	// how often buckets that have filled up are dropped
+	rateLimitPruneInterval = time.Minute
+)
+
+// RateLimiter limits rates of Twirp API requests and of signal connections, by API key, client IP and room. Client
+// IPs are limited before authentication, for requests with invalid tokens to be limited as well, API keys and rooms
+// once requests are authenticated
+type RateLimiter struct {
+	conf    config.RateLimitConfig
+	proxies *TrustedProxies
+
+	lock      sync.Mutex
+	buckets   map[rateLimitKey]*rateLimitBucket
+	lastPrune time.Time
+}
+
+type rateLimitKey struct {
+	limited string
+	by      string
+	key     string
+}
+
+type rateLimitBucket struct {
+	*utils.TokenBucket
+	// how long the bucket takes to fill up, after which it's no different from a new one
+	fillTime time.Duration
+	lastUsed time.Time
+}
+
+type rateLimitCheck struct {
+	by    string
+	key   string
+	limit config.RateLimit
+}
+
+// NewRateLimiter returns nil when neither API requests nor signal connections are limited
+func NewRateLimiter(conf *config.Config, proxies *TrustedProxies) *RateLimiter {
+	rl := conf.RateLimit
+	if !rl.API.APIKey.Enabled() && !rl.API.ClientIP.Enabled() && !rl.API.Room.Enabled() &&
+		!rl.RTC.APIKey.Enabled() && !rl.RTC.ClientIP.Enabled() && !rl.RTC.Room.Enabled() {
+		return nil
+	}
+
+	return &RateLimiter{
+		conf:      rl,
+		proxies:   proxies,
+		buckets:   make(map[rateLimitKey]*rateLimitBucket),
+		lastPrune: time.Now(),
+	}
+}
+
+// ServeHTTP limits Twirp API requests and signal connections by client IP. It runs before authentication
+func (l *RateLimiter) ServeHTTP(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	switch {
+	case strings.HasPrefix(r.URL.Path, twirpPathPrefix):
+		by := l.allow(rateLimitedAPI,
+			rateLimitCheck{by: rateLimitByClientIP, key: l.proxies.ClientIP(r), limit: l.conf.API.ClientIP},
+		)
+		if by != "" {
+			prometheus.IncrementRateLimited(rateLimitedAPI, by)
+			_ = twirp.WriteError(w, twirp.NewError(twirp.ResourceExhausted, ErrRateLimited.Error()))
+			return
+		}
+	case isSignalConnection(r):
+		by := l.allow(rateLimitedRTC,
+			rateLimitCheck{by: rateLimitByClientIP, key: l.proxies.ClientIP(r), limit: l.conf.RTC.ClientIP},
+		)
+		if by != "" {
+			prometheus.IncrementRateLimited(rateLimitedRTC, by)
+			handleError(w, http.StatusTooManyRequests, ErrRateLimited)
+			return
+		}
+	}
+	next(w, r)
+}
+
+// TwirpInterceptor limits Twirp API requests by API key, and by the room they're made on
+func (l *RateLimiter) TwirpInterceptor() twirp.Interceptor {
+	return func(next twirp.Method) twirp.Method {
+		if l == nil {
+			return next
+		}
+		return func(ctx context.Context, req interface{}) (interface{}, error) {
+			by := l.allow(rateLimitedAPI,
+				rateLimitCheck{by: rateLimitByAPIKey, key: GetAPIKey(ctx), limit: l.conf.API.APIKey},
+				rateLimitCheck{by: rateLimitByRoom, key: twirpRequestRoom(req), limit: l.conf.API.Room},
+			)
+			if by != "" {
+				prometheus.IncrementRateLimited(rateLimitedAPI, by)
+				return nil, twirp.NewError(twirp.ResourceExhausted, ErrRateLimited.Error())
+			}
+			return next(ctx, req)
+		}
+	}
+}
+
+// AllowSignalConnection limits signal connections, including reconnects, by API key and room joined. Their client
+// IPs are limited by ServeHTTP
+func (l *RateLimiter) AllowSignalConnection(r *http.Request) error {
+	if l == nil {
+		return nil
+	}
+
+	roomName := r.FormValue("room")
+	if claims := GetGrants(r.Context()); claims != nil && claims.Video != nil && claims.Video.Room != "" {
+		roomName = claims.Video.Room
+	}
+	by := l.allow(rateLimitedRTC,
+		rateLimitCheck{by: rateLimitByAPIKey, key: GetAPIKey(r.Context()), limit: l.conf.RTC.APIKey},
+		rateLimitCheck{by: rateLimitByRoom, key: roomName, limit: l.conf.RTC.Room},
+	)
+	if by != "" {
+		prometheus.IncrementRateLimited(rateLimitedRTC, by)
+		return ErrRateLimited
+	}
+	return nil
+}
+
+// allow takes a token from the bucket of each check, returning what the first one without tokens limits by. Tokens
+// are only taken when all buckets have one, so that rejected requests don't count against the other limits
+func (l *RateLimiter) allow(limited string, checks ...rateLimitCheck) string {
+	now := time.Now()
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.prune(now)
+	buckets := make([]*rateLimitBucket, 0, len(checks))
+	for _, check := range checks {
+		if check.key == "" || !check.limit.Enabled() {
+			continue
+		}
+
+		key := rateLimitKey{limited: limited, by: check.by, key: check.key}
+		bucket := l.buckets[key]
+		if bucket == nil {
+			burst := check.limit.GetBurst()
+			bucket = &rateLimitBucket{
+				TokenBucket: utils.NewTokenBucket(float64(check.limit.Rate), burst),
+				fillTime:    time.Duration(burst / float64(check.limit.Rate) * float64(time.Second)),
+			}
+			l.buckets[key] = bucket
+		}
+		bucket.lastUsed = now
+		if !bucket.AvailableAt(now, 1) {
+			return check.by
+		}
+		buckets = append(buckets, bucket)
+	}
+
+	for _, bucket := range buckets {
+		bucket.AllowAt(now, 1)
+	}
+	return ""
+}
+
+// isSignalConnection returns true for requests connecting to /rtc, and for ones starting WHIP or WHEP sessions
+func isSignalConnection(r *http.Request) bool {
+	switch r.URL.Path {
+	case "/rtc":
+		return true
+	case WHIPPathPrefix, WHEPPathPrefix:
+		return r.Method == http.MethodPost
+	}
+	return false
+}
+
+// twirpRequestRoom returns the room a Twirp request is made on, empty for requests not made on a room
+func twirpRequestRoom(req interface{}) string {
+	switch r := req.(type) {
+	case *livekit.CreateRoomRequest:
+		return r.Name
+	case interface{ GetRoom() string }:
+		return r.GetRoom()
+	case interface{ GetRoomName() string }:
+		return r.GetRoomName()
+	}
+	return ""
+}
+
+func (l *RateLimiter) prune(now time.Time) {
+	if now.Sub(l.lastPrune) < rateLimitPruneInterval {
+		return
+	}
+	l.lastPrune = now
+
+	for key, bucket := range l.buckets {
+		if now.Sub(bucket.lastUsed) > bucket.fillTime {
+			delete(l.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package service_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/twitchtv/twirp"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/service"
+)
+
+func TestRateLimiter(t *testing.T) {
+	newRateLimiter := func(t *testing.T, rl config.RateLimitConfig) *service.RateLimiter {
+		conf, err := config.NewConfig("", true, nil, nil)
+		require.NoError(t, err)
+		conf.RateLimit = rl
+		limiter := service.NewRateLimiter(conf, nil)
+		require.NotNil(t, limiter)
+		return limiter
+	}
+	serve := func(limiter *service.RateLimiter, r *http.Request) int {
+		w := httptest.NewRecorder()
+		limiter.ServeHTTP(w, r, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		})
+		return w.Code
+	}
+	call := func(limiter *service.RateLimiter, apiKey string, req interface{}) error {
+		method := limiter.TwirpInterceptor()(func(ctx context.Context, req interface{}) (interface{}, error) {
+			return nil, nil
+		})
+		_, err := method(tenantContext(t, apiKey, ""), req)
+		return err
+	}
+	requireLimited := func(t *testing.T, err error) {
+		var twerr twirp.Error
+		require.ErrorAs(t, err, &twerr)
+		require.Equal(t, twirp.ResourceExhausted, twerr.Code())
+	}
+
+	t.Run("not enabled", func(t *testing.T) {
+		conf, err := config.NewConfig("", true, nil, nil)
+		require.NoError(t, err)
+		limiter := service.NewRateLimiter(conf, nil)
+		require.Nil(t, limiter)
+		require.NoError(t, limiter.AllowSignalConnection(httptest.NewRequest("GET", "/rtc?room=a", nil)))
+		require.NoError(t, call(limiter, "key-a", &livekit.ListRoomsRequest{}))
+	})
+
+	t.Run("limits Twirp requests by API key", func(t *testing.T) {
+		limiter := newRateLimiter(t, config.RateLimitConfig{
+			API: config.APIRateLimitConfig{APIKey: config.RateLimit{Rate: 1, Burst: 2}},
+		})
+
+		require.NoError(t, call(limiter, "key-a", &livekit.ListRoomsRequest{}))
+		require.NoError(t, call(limiter, "key-a", &livekit.ListRoomsRequest{}))
+		requireLimited(t, call(limiter, "key-a", &livekit.ListRoomsRequest{}))
+		require.NoError(t, call(limiter, "key-b", &livekit.ListRoomsRequest{}))
+	})
+
+	t.Run("limits Twirp requests by room", func(t *testing.T) {
+		limiter := newRateLimiter(t, config.RateLimitConfig{
+			API: config.APIRateLimitConfig{Room: config.RateLimit{Rate: 1, Burst: 2}},
+		})
+
+		require.NoError(t, call(limiter, "key-a", &livekit.CreateRoomRequest{Name: "a"}))
+		require.NoError(t, call(limiter, "key-a", &livekit.ListParticipantsRequest{Room: "a"}))
+		requireLimited(t, call(limiter, "key-b", &livekit.MuteRoomTrackRequest{Room: "a"}))
+		require.NoError(t, call(limiter, "key-a", &livekit.RoomCompositeEgressRequest{RoomName: "b"}))
+		// requests not made on a room are not limited by room
+		require.NoError(t, call(limiter, "key-a", &livekit.ListRoomsRequest{}))
+		require.NoError(t, call(limiter, "key-a", &livekit.ListRoomsRequest{}))
+		require.NoError(t, call(limiter, "key-a", &livekit.ListRoomsRequest{}))
+	})
+
+	t.Run("limits Twirp requests by client IP before authentication", func(t *testing.T) {
+		limiter := newRateLimiter(t, config.RateLimitConfig{
+			API: config.APIRateLimitConfig{ClientIP: config.RateLimit{Rate: 1, Burst: 2}},
+		})
+		request := func(path string, remoteAddr string) *http.Request {
+			// no token, the limit applies to requests that'd fail authentication as well
+			r := httptest.NewRequest("POST", path, nil)
+			r.RemoteAddr = remoteAddr
+			return r
+		}
+
+		path := "/twirp/livekit.RoomService/ListRooms"
+		require.Equal(t, http.StatusOK, serve(limiter, request(path, "10.0.0.1:1000")))
+		require.Equal(t, http.StatusOK, serve(limiter, request(path, "10.0.0.1:2000")))
+		require.Equal(t, http.StatusTooManyRequests, serve(limiter, request(path, "10.0.0.1:1000")))
+		require.Equal(t, http.StatusOK, serve(limiter, request(path, "10.0.0.2:1000")))
+		// only Twirp requests and signal connections are limited
+		require.Equal(t, http.StatusOK, serve(limiter, request("/rtc/validate", "10.0.0.1:1000")))
+	})
+
+	t.Run("limits signal connections by client IP before authentication", func(t *testing.T) {
+		limiter := newRateLimiter(t, config.RateLimitConfig{
+			RTC: config.RTCRateLimitConfig{ClientIP: config.RateLimit{Rate: 1, Burst: 2}},
+		})
+		request := func(method string, path string) *http.Request {
+			r := httptest.NewRequest(method, path, nil)
+			r.RemoteAddr = "10.0.0.1:1000"
+			return r
+		}
+
+		require.Equal(t, http.StatusOK, serve(limiter, request("GET", "/rtc?room=a")))
+		require.Equal(t, http.StatusOK, serve(limiter, request("POST", service.WHIPPathPrefix)))
+		require.Equal(t, http.StatusTooManyRequests, serve(limiter, request("POST", service.WHEPPathPrefix)))
+		// requests on existing WHIP and WHEP sessions are not connections
+		require.Equal(t, http.StatusOK, serve(limiter, request("DELETE", service.WHIPPathPrefix)))
+	})
+
+	t.Run("limits signal connections by room", func(t *testing.T) {
+		limiter := newRateLimiter(t, config.RateLimitConfig{
+			RTC: config.RTCRateLimitConfig{Room: config.RateLimit{Rate: 1}},
+		})
+		connect := func(room string) error {
+			return limiter.AllowSignalConnection(httptest.NewRequest("GET", "/rtc?room="+room, nil))
+		}
+
+		require.NoError(t, connect("a"))
+		require.ErrorIs(t, connect("a"), service.ErrRateLimited)
+		require.NoError(t, connect("b"))
+	})
+
+	t.Run("does not take tokens of connections limited by another check", func(t *testing.T) {
+		limiter := newRateLimiter(t, config.RateLimitConfig{
+			RTC: config.RTCRateLimitConfig{
+				APIKey: config.RateLimit{Rate: 1, Burst: 2},
+				Room:   config.RateLimit{Rate: 1},
+			},
+		})
+		connect := func(room string) error {
+			r := httptest.NewRequest("GET", "/rtc?room="+room, nil)
+			return limiter.AllowSignalConnection(r.WithContext(tenantContext(t, "key-a", "")))
+		}
+
+		require.NoError(t, connect("a"))
+		require.ErrorIs(t, connect("a"), service.ErrRateLimited)
+		require.NoError(t, connect("b"))
+		require.ErrorIs(t, connect("c"), service.ErrRateLimited)
+	})
+
+	t.Run("limits clients behind trusted proxies", func(t *testing.T) {
+		conf, err := config.NewConfig("", true, nil, nil)
+		require.NoError(t, err)
+		conf.RateLimit = config.RateLimitConfig{
+			RTC: config.RTCRateLimitConfig{ClientIP: config.RateLimit{Rate: 1}},
+		}
+		conf.TrustedProxies = []string{"10.0.0.0/8"}
+		proxies, err := service.NewTrustedProxies(conf)
+		require.NoError(t, err)
+		limiter := service.NewRateLimiter(conf, proxies)
+		connect := func(remoteAddr string, forwardedFor string) int {
+			r := httptest.NewRequest("GET", "/rtc?room=a", nil)
+			r.RemoteAddr = remoteAddr
+			r.Header.Set("X-Forwarded-For", forwardedFor)
+			return serve(limiter, r)
+		}
+
+		require.Equal(t, http.StatusOK, connect("10.0.0.1:1000", "192.0.2.1"))
+		require.Equal(t, http.StatusTooManyRequests, connect("10.0.0.2:1000", "192.0.2.1"))
+		require.Equal(t, http.StatusOK, connect("10.0.0.1:1000", "192.0.2.2"))
+		// headers of others are ignored
+		require.Equal(t, http.StatusOK, connect("192.0.2.3:1000", "192.0.2.4"))
+		require.Equal(t, http.StatusTooManyRequests, connect("192.0.2.3:1000", "192.0.2.5"))
+	})
+}
@@ -0,0 +1,322 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/pion/webrtc/v3"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+	"github.com/livekit/protocol/utils"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/rtc"
+	"github.com/livekit/livekit-server/pkg/sfu"
+	"github.com/livekit/livekit-server/pkg/sfu/recorder"
+	"github.com/livekit/livekit-server/pkg/telemetry"
+)
+
+const defaultRecordingPath = "{room_name}-{track_id}-{time}"
+
+// TrackRecorder records track egress on the node hosting the room, in place of an egress worker.
+// It takes requests that write a single track to a file, without uploading it.
+type TrackRecorder struct {
+	conf      config.RecorderConfig
+	es        EgressStore
+	telemetry telemetry.TelemetryService
+
+	lock        sync.Mutex
+	roomManager *RoomManager
+	recordings  map[string]*trackRecording
+}
+
+type trackRecording struct {
+	lock      sync.Mutex
+	info      *livekit.EgressInfo
+	writer    *recorder.Writer
+	downTrack *sfu.DownTrack
+}
+
+func NewTrackRecorder(conf *config.Config, es EgressStore, ts telemetry.TelemetryService) *TrackRecorder {
+	return &TrackRecorder{
+		conf:       conf.Recorder,
+		es:         es,
+		telemetry:  ts,
+		recordings: make(map[string]*trackRecording),
+	}
+}
+
+// Start makes rooms of this node available for recording, rooms are created with the egress launcher the
+// recorder is part of
+func (r *TrackRecorder) Start(roomManager *RoomManager) {
+	r.lock.Lock()
+	r.roomManager = roomManager
+	r.lock.Unlock()
+}
+
+func (r *TrackRecorder) Stop() {
+	r.lock.Lock()
+	recordings := make([]*trackRecording, 0, len(r.recordings))
+	for _, rec := range r.recordings {
+		recordings = append(recordings, rec)
+	}
+	r.lock.Unlock()
+
+	for _, rec := range recordings {
+		rec.stop()
+		<-rec.writer.Done()
+	}
+}
+
+func (r *TrackRecorder) Enabled() bool {
+	return r != nil && r.conf.Dir != ""
+}
+
+// CanRecord returns whether a request is for a track written to a file that isn't uploaded
+func (r *TrackRecorder) CanRecord(req *livekit.StartEgressRequest) bool {
+	if !r.Enabled() {
+		return false
+	}
+	file := req.GetTrack().GetFile()
+	return file != nil && file.Output == nil
+}
+
+// HostsRoom returns whether a room is on this node, tracks can only be recorded where they are forwarded
+func (r *TrackRecorder) HostsRoom(ctx context.Context, roomName livekit.RoomName) bool {
+	return r.getRoom(ctx, roomName) != nil
+}
+
+func (r *TrackRecorder) StartEgress(ctx context.Context, req *livekit.StartEgressRequest) (*livekit.EgressInfo, error) {
+	trackReq := req.GetTrack()
+	if trackReq == nil || trackReq.GetFile() == nil {
+		return nil, ErrOperationFailed
+	}
+	room := r.getRoom(ctx, livekit.RoomName(trackReq.RoomName))
+	if room == nil {
+		return nil, ErrRoomNotFound
+	}
+	var track *rtc.MediaTrack
+	for _, p := range room.GetParticipants() {
+		if t, ok := p.GetPublishedTrack(livekit.TrackID(trackReq.TrackId)).(*rtc.MediaTrack); ok {
+			track = t
+			break
+		}
+	}
+	if track == nil {
+		return nil, ErrTrackNotFound
+	}
+
+	codec, ok := recordingCodec(track)
+	if !ok {
+		return nil, recorder.ErrUnsupportedCodec
+	}
+
+	egressID := utils.NewGuid(utils.EgressPrefix)
+	path, format := r.getFilePath(trackReq.GetFile().Filepath, room.Name(), track.ID(), codec.MimeType)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+
+	rec := &trackRecording{}
+	l := logger.Logger(logr.Logger(logger.GetDefaultLogger()).WithValues("egressID", egressID, "trackID", track.ID()))
+	writer, err := recorder.NewWriter(recorder.WriterParams{
+		Path:            path,
+		Format:          format,
+		Codec:           codec.RTPCodecCapability,
+		Latency:         r.conf.Latency,
+		RequestKeyFrame: rec.requestKeyFrame,
+		Logger:          l,
+	})
+	if err != nil {
+		return nil, err
+	}
+	rec.writer = writer
+
+	downTrack, err := track.AddLocalSubscriber(livekit.ParticipantID(egressID), []webrtc.RTPCodecParameters{codec}, writer)
+	if err != nil {
+		writer.Close()
+		<-writer.Done()
+		_ = os.Remove(path)
+		return nil, err
+	}
+
+	rec.lock.Lock()
+	rec.downTrack = downTrack
+	rec.info = &livekit.EgressInfo{
+		EgressId:  egressID,
+		RoomId:    req.RoomId,
+		RoomName:  trackReq.RoomName,
+		Status:    livekit.EgressStatus_EGRESS_ACTIVE,
+		StartedAt: writer.StartedAt().UnixNano(),
+		Request:   &livekit.EgressInfo_Track{Track: trackReq},
+	}
+	info := proto.Clone(rec.info).(*livekit.EgressInfo)
+	rec.lock.Unlock()
+
+	r.lock.Lock()
+	r.recordings[egressID] = rec
+	r.lock.Unlock()
+
+	if r.es != nil {
+		if err = r.es.StoreEgress(ctx, info); err != nil {
+			logger.Errorw("could not write egress info", err)
+		}
+	}
+	r.telemetry.EgressStarted(ctx, info)
+	l.Infow("recording track", "path", path)
+
+	// recording ends when the track is unpublished
+	downTrack.OnCloseHandler(func(_ bool) {
+		writer.Close()
+	})
+	if downTrack.IsClosed() {
+		writer.Close()
+	}
+	writer.OnClose(func() {
+		r.recordingEnded(egressID, rec)
+	})
+
+	return info, nil
+}
+
+// StopEgress ends a recording and returns its final state, ErrEgressNotFound is returned for egress not recorded here
+func (r *TrackRecorder) StopEgress(ctx context.Context, egressID string) (*livekit.EgressInfo, error) {
+	r.lock.Lock()
+	rec := r.recordings[egressID]
+	r.lock.Unlock()
+	if rec == nil {
+		return nil, ErrEgressNotFound
+	}
+
+	rec.stop()
+	select {
+	case <-rec.writer.Done():
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	rec.lock.Lock()
+	defer rec.lock.Unlock()
+	return proto.Clone(rec.info).(*livekit.EgressInfo), nil
+}
+
+func (r *TrackRecorder) recordingEnded(egressID string, rec *trackRecording) {
+	rec.stop()
+
+	stats := rec.writer.Stats()
+	endedAt := time.Now().UnixNano()
+
+	rec.lock.Lock()
+	rec.info.EndedAt = endedAt
+	rec.info.Status = livekit.EgressStatus_EGRESS_COMPLETE
+	if err := rec.writer.Err(); err != nil {
+		rec.info.Status = livekit.EgressStatus_EGRESS_FAILED
+		rec.info.Error = err.Error()
+	}
+	rec.info.Result = &livekit.EgressInfo_File{
+		File: &livekit.FileInfo{
+			Filename:  rec.writer.Path(),
+			StartedAt: rec.info.StartedAt,
+			EndedAt:   endedAt,
+			Duration:  int64(stats.Duration),
+			Size:      stats.Size,
+			Location:  rec.writer.Path(),
+		},
+	}
+	info := proto.Clone(rec.info).(*livekit.EgressInfo)
+	rec.lock.Unlock()
+
+	r.lock.Lock()
+	delete(r.recordings, egressID)
+	r.lock.Unlock()
+
+	if r.es != nil {
+		if err := r.es.UpdateEgress(context.Background(), info); err != nil {
+			logger.Errorw("could not update egress", err)
+		}
+	}
+	if info.Error != "" {
+		logger.Errorw("egress failed", errors.New(info.Error), "egressID", egressID)
+	} else {
+		logger.Infow("egress ended", "egressID", egressID)
+	}
+	r.telemetry.EgressEnded(context.Background(), info)
+}
+
+func (r *TrackRecorder) getRoom(ctx context.Context, roomName livekit.RoomName) *rtc.Room {
+	r.lock.Lock()
+	roomManager := r.roomManager
+	r.lock.Unlock()
+	if roomManager == nil {
+		return nil
+	}
+	return roomManager.GetRoom(ctx, roomName)
+}
+
+// getFilePath resolves a requested file path within the recording directory, and picks the format from its
+// extension. Paths ending in / are directories the recording is named within
+func (r *TrackRecorder) getFilePath(path string, roomName livekit.RoomName, trackID livekit.TrackID, mimeType string) (string, recorder.Format) {
+	if path == "" || strings.HasSuffix(path, "/") {
+		path += defaultRecordingPath
+	}
+	path = strings.NewReplacer(
+		"{room_name}", string(roomName),
+		"{track_id}", string(trackID),
+		"{time}", time.Now().Format("2006-01-02T150405"),
+	).Replace(path)
+
+	format := recorder.Format(strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), "."))
+	if !recorder.FormatSupports(format, mimeType) {
+		format = recorder.DefaultFormat(mimeType)
+		path += "." + string(format)
+	}
+
+	// rooted, so that it can't escape the directory
+	return filepath.Join(r.conf.Dir, filepath.Clean("/"+path)), format
+}
+
+// recordingCodec returns the codec a track is recorded in, Opus is recorded without redundancy
+func recordingCodec(track *rtc.MediaTrack) (webrtc.RTPCodecParameters, bool) {
+	if track.Kind() == livekit.TrackType_AUDIO {
+		return webrtc.RTPCodecParameters{
+			RTPCodecCapability: webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeOpus, ClockRate: 48000, Channels: 2},
+			PayloadType:        111,
+		}, true
+	}
+
+	receiver := track.PrimaryReceiver()
+	if receiver == nil {
+		return webrtc.RTPCodecParameters{}, false
+	}
+	codec := receiver.Codec()
+	return codec, recorder.DefaultFormat(codec.MimeType) != ""
+}
+
+func (t *trackRecording) stop() {
+	t.lock.Lock()
+	downTrack := t.downTrack
+	t.lock.Unlock()
+
+	if downTrack != nil {
+		downTrack.Close()
+	}
+	t.writer.Close()
+}
+
+func (t *trackRecording) requestKeyFrame() {
+	t.lock.Lock()
+	downTrack := t.downTrack
+	t.lock.Unlock()
+
+	if downTrack != nil {
+		downTrack.RequestKeyFrame()
+	}
+}
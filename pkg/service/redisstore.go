@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"strconv"
 	"strings"
@@ -12,6 +13,10 @@ import (
 	"github.com/pkg/errors"
 	"google.golang.org/protobuf/proto"
 
+	"github.com/livekit/livekit-server/pkg/routing"
+	"github.com/livekit/livekit-server/pkg/routing/selector"
+	"github.com/livekit/livekit-server/pkg/rpc"
+	"github.com/livekit/livekit-server/pkg/rtc"
 	"github.com/livekit/livekit-server/version"
 	"github.com/livekit/protocol/ingress"
 	"github.com/livekit/protocol/livekit"
@@ -25,6 +30,8 @@ const (
 	// RoomsKey is hash of room_name => Room proto
 	RoomsKey        = "rooms"
 	RoomInternalKey = "room_internal"
+	// RoomOptionsKey is a hash of room_name => JSON of options the room was created with
+	RoomOptionsKey = "room_options"
 
 	// EgressKey is a hash of egressID => egress info
 	EgressKey                  = "egress"
@@ -40,10 +47,22 @@ const (
 
 	// RoomParticipantsPrefix is hash of participant_name => ParticipantInfo
 	RoomParticipantsPrefix = "room_participants:"
+	// RoomWaitingParticipantsPrefix is hash of participant_name => WaitingParticipant
+	RoomWaitingParticipantsPrefix = "room_waiting_participants:"
 
 	// RoomLockPrefix is a simple key containing a provided lock uid
 	RoomLockPrefix = "room_lock:"
 
+	// TenantsKey is a hash of tenant resource => tenant
+	TenantsKey = "tenants"
+	// TenantRoomsPrefix is a set of room names of the tenant
+	TenantRoomsPrefix = "tenant_rooms:"
+	// TenantLockPrefix is a simple key containing a provided lock uid
+	TenantLockPrefix = "tenant_lock:"
+	// TenantParticipantsPrefix is a hash of participant_sid => node_id:number of tracks it publishes, in rooms of the
+	// tenant
+	TenantParticipantsPrefix = "tenant_participants:"
+
 	maxRetries = 5
 )
 
@@ -176,6 +195,29 @@ func (s *RedisStore) LoadRoom(_ context.Context, roomName livekit.RoomName, incl
 	return room, internal, nil
 }
 
+func (s *RedisStore) StoreRoomOptions(_ context.Context, roomName livekit.RoomName, options *rtc.RoomOptions) error {
+	data, err := json.Marshal(options)
+	if err != nil {
+		return err
+	}
+	return s.rc.HSet(s.ctx, RoomOptionsKey, string(roomName), data).Err()
+}
+
+func (s *RedisStore) LoadRoomOptions(_ context.Context, roomName livekit.RoomName) (*rtc.RoomOptions, error) {
+	data, err := s.rc.HGet(s.ctx, RoomOptionsKey, string(roomName)).Result()
+	if err == redis.Nil {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	options := &rtc.RoomOptions{}
+	if err = json.Unmarshal([]byte(data), options); err != nil {
+		return nil, err
+	}
+	return options, nil
+}
+
 func (s *RedisStore) ListRooms(_ context.Context, roomNames []livekit.RoomName) ([]*livekit.Room, error) {
 	var items []string
 	var err error
@@ -217,18 +259,48 @@ func (s *RedisStore) DeleteRoom(ctx context.Context, roomName livekit.RoomName)
 		return nil
 	}
 
+	// participants left in the room are no longer counted for its tenant
+	tenant, err := s.LoadTenant(ctx, RoomTenantResource(roomName))
+	if err != nil {
+		return err
+	}
+	var participants []*livekit.ParticipantInfo
+	if tenant != "" {
+		if participants, err = s.ListParticipants(ctx, roomName); err != nil {
+			return err
+		}
+	}
+
 	pp := s.rc.Pipeline()
 	pp.HDel(s.ctx, RoomsKey, string(roomName))
 	pp.HDel(s.ctx, RoomInternalKey, string(roomName))
+	pp.HDel(s.ctx, RoomOptionsKey, string(roomName))
 	pp.Del(s.ctx, RoomParticipantsPrefix+string(roomName))
+	pp.Del(s.ctx, RoomWaitingParticipantsPrefix+string(roomName))
+	pp.HDel(s.ctx, TenantsKey, string(RoomTenantResource(roomName)))
+	if tenant != "" {
+		pp.SRem(s.ctx, TenantRoomsPrefix+tenant, string(roomName))
+		for _, pi := range participants {
+			pp.HDel(s.ctx, TenantParticipantsPrefix+tenant, pi.Sid)
+		}
+	}
 
 	_, err = pp.Exec(s.ctx)
 	return err
 }
 
 func (s *RedisStore) LockRoom(_ context.Context, roomName livekit.RoomName, duration time.Duration) (string, error) {
+	return s.lock(RoomLockPrefix+string(roomName), duration, ErrRoomLockFailed)
+}
+
+func (s *RedisStore) UnlockRoom(ctx context.Context, roomName livekit.RoomName, uid string) error {
+	return s.unlock(ctx, RoomLockPrefix+string(roomName), uid, ErrRoomUnlockFailed)
+}
+
+// lock sets key to a new token when it's not set, waiting up to duration for it to be unset. It returns lockErr
+// when it's not
+func (s *RedisStore) lock(key string, duration time.Duration, lockErr error) (string, error) {
 	token := utils.NewGuid("LOCK")
-	key := RoomLockPrefix + string(roomName)
 
 	startTime := time.Now()
 	for {
@@ -248,11 +320,10 @@ func (s *RedisStore) LockRoom(_ context.Context, roomName livekit.RoomName, dura
 		time.Sleep(100 * time.Millisecond)
 	}
 
-	return "", ErrRoomLockFailed
+	return "", lockErr
 }
 
-func (s *RedisStore) UnlockRoom(ctx context.Context, roomName livekit.RoomName, uid string) error {
-	key := RoomLockPrefix + string(roomName)
+func (s *RedisStore) unlock(ctx context.Context, key string, uid string, unlockErr error) error {
 	res, err := s.unlockScript.Run(ctx, s.rc, []string{key}, uid).Result()
 	if err != nil {
 		return err
@@ -260,7 +331,7 @@ func (s *RedisStore) UnlockRoom(ctx context.Context, roomName livekit.RoomName,
 
 	// uid does not match
 	if i, ok := res.(int64); !ok || i != 1 {
-		return ErrRoomUnlockFailed
+		return unlockErr
 	}
 
 	return nil
@@ -319,6 +390,59 @@ func (s *RedisStore) DeleteParticipant(_ context.Context, roomName livekit.RoomN
 	return s.rc.HDel(s.ctx, key, string(identity)).Err()
 }
 
+func (s *RedisStore) StoreWaitingParticipant(_ context.Context, roomName livekit.RoomName, participant *rpc.WaitingParticipant) error {
+	key := RoomWaitingParticipantsPrefix + string(roomName)
+
+	data, err := proto.Marshal(participant)
+	if err != nil {
+		return err
+	}
+
+	return s.rc.HSet(s.ctx, key, participant.Participant.Identity, data).Err()
+}
+
+func (s *RedisStore) LoadWaitingParticipant(_ context.Context, roomName livekit.RoomName, identity livekit.ParticipantIdentity) (*rpc.WaitingParticipant, error) {
+	key := RoomWaitingParticipantsPrefix + string(roomName)
+	data, err := s.rc.HGet(s.ctx, key, string(identity)).Result()
+	if err == redis.Nil {
+		return nil, ErrParticipantNotWaiting
+	} else if err != nil {
+		return nil, err
+	}
+
+	wp := rpc.WaitingParticipant{}
+	if err := proto.Unmarshal([]byte(data), &wp); err != nil {
+		return nil, err
+	}
+	return &wp, nil
+}
+
+func (s *RedisStore) ListWaitingParticipants(_ context.Context, roomName livekit.RoomName) ([]*rpc.WaitingParticipant, error) {
+	key := RoomWaitingParticipantsPrefix + string(roomName)
+	items, err := s.rc.HVals(s.ctx, key).Result()
+	if err == redis.Nil {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	participants := make([]*rpc.WaitingParticipant, 0, len(items))
+	for _, item := range items {
+		wp := rpc.WaitingParticipant{}
+		if err := proto.Unmarshal([]byte(item), &wp); err != nil {
+			return nil, err
+		}
+		participants = append(participants, &wp)
+	}
+	return participants, nil
+}
+
+func (s *RedisStore) DeleteWaitingParticipant(_ context.Context, roomName livekit.RoomName, identity livekit.ParticipantIdentity) error {
+	key := RoomWaitingParticipantsPrefix + string(roomName)
+
+	return s.rc.HDel(s.ctx, key, string(identity)).Err()
+}
+
 func (s *RedisStore) StoreEgress(_ context.Context, info *livekit.EgressInfo) error {
 	data, err := proto.Marshal(info)
 	if err != nil {
@@ -458,6 +582,7 @@ func (s RedisStore) CleanEndedEgress() error {
 			tx.HDel(s.ctx, EndedEgressKey, egressID)
 			tx.SRem(s.ctx, RoomEgressPrefix+roomName, egressID)
 			tx.HDel(s.ctx, EgressKey, egressID)
+			tx.HDel(s.ctx, TenantsKey, string(EgressTenantResource(egressID)))
 			if _, err := tx.Exec(s.ctx); err != nil {
 				return err
 			}
@@ -791,6 +916,7 @@ func (s *RedisStore) DeleteIngress(_ context.Context, info *livekit.IngressInfo)
 	tx.HDel(s.ctx, StreamKeyKey, info.IngressId)
 	tx.HDel(s.ctx, IngressKey, info.IngressId)
 	tx.Del(s.ctx, IngressStatePrefix+info.IngressId)
+	tx.HDel(s.ctx, TenantsKey, string(IngressTenantResource(info.IngressId)))
 	if _, err := tx.Exec(s.ctx); err != nil {
 		return errors.Wrap(err, "could not delete ingress info")
 	}
@@ -798,6 +924,122 @@ func (s *RedisStore) DeleteIngress(_ context.Context, info *livekit.IngressInfo)
 	return nil
 }
 
+func (s *RedisStore) StoreTenant(_ context.Context, resource TenantResource, tenant string) error {
+	tx := s.rc.TxPipeline()
+	tx.HSet(s.ctx, TenantsKey, string(resource), tenant)
+	if strings.HasPrefix(string(resource), roomTenantPrefix) {
+		tx.SAdd(s.ctx, TenantRoomsPrefix+tenant, strings.TrimPrefix(string(resource), roomTenantPrefix))
+	}
+	_, err := tx.Exec(s.ctx)
+	return err
+}
+
+func (s *RedisStore) DeleteTenant(_ context.Context, resource TenantResource, tenant string) error {
+	tx := s.rc.TxPipeline()
+	tx.HDel(s.ctx, TenantsKey, string(resource))
+	if strings.HasPrefix(string(resource), roomTenantPrefix) {
+		tx.SRem(s.ctx, TenantRoomsPrefix+tenant, strings.TrimPrefix(string(resource), roomTenantPrefix))
+	}
+	_, err := tx.Exec(s.ctx)
+	return err
+}
+
+func (s *RedisStore) LoadTenant(_ context.Context, resource TenantResource) (string, error) {
+	tenant, err := s.rc.HGet(s.ctx, TenantsKey, string(resource)).Result()
+	if err == redis.Nil {
+		return "", nil
+	}
+	return tenant, err
+}
+
+func (s *RedisStore) ListTenantRooms(_ context.Context, tenant string) ([]livekit.RoomName, error) {
+	names, err := s.rc.SMembers(s.ctx, TenantRoomsPrefix+tenant).Result()
+	if err != nil && err != redis.Nil {
+		return nil, err
+	}
+
+	roomNames := make([]livekit.RoomName, 0, len(names))
+	for _, name := range names {
+		roomNames = append(roomNames, livekit.RoomName(name))
+	}
+	return roomNames, nil
+}
+
+func (s *RedisStore) LockTenant(_ context.Context, tenant string, duration time.Duration) (string, error) {
+	return s.lock(TenantLockPrefix+tenant, duration, ErrTenantLockFailed)
+}
+
+func (s *RedisStore) UnlockTenant(ctx context.Context, tenant string, uid string) error {
+	return s.unlock(ctx, TenantLockPrefix+tenant, uid, ErrTenantUnlockFailed)
+}
+
+func (s *RedisStore) StoreTenantParticipant(_ context.Context, tenant string, nodeID livekit.NodeID, participantID livekit.ParticipantID, tracks int) error {
+	return s.rc.HSet(s.ctx, TenantParticipantsPrefix+tenant, string(participantID), fmt.Sprintf("%s:%d", nodeID, tracks)).Err()
+}
+
+func (s *RedisStore) DeleteTenantParticipant(_ context.Context, tenant string, participantID livekit.ParticipantID) error {
+	return s.rc.HDel(s.ctx, TenantParticipantsPrefix+tenant, string(participantID)).Err()
+}
+
+// LoadTenantUsage counts participants on nodes that are alive. Participants left behind by nodes that went away
+// without them leaving, as when crashing, are dropped
+func (s *RedisStore) LoadTenantUsage(_ context.Context, tenant string) (int, int, error) {
+	key := TenantParticipantsPrefix + tenant
+	values, err := s.rc.HGetAll(s.ctx, key).Result()
+	if err != nil && err != redis.Nil {
+		return 0, 0, err
+	}
+	if len(values) == 0 {
+		return 0, 0, nil
+	}
+	alive, err := s.aliveNodes()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	participants, tracks := 0, 0
+	var left []string
+	for participantID, value := range values {
+		// counted before participants were known by their node
+		i := strings.LastIndexByte(value, ':')
+		if i < 0 || !alive[livekit.NodeID(value[:i])] {
+			left = append(left, participantID)
+			continue
+		}
+		n, err := strconv.Atoi(value[i+1:])
+		if err != nil {
+			return 0, 0, err
+		}
+		participants++
+		tracks += n
+	}
+	if len(left) > 0 {
+		if err = s.rc.HDel(s.ctx, key, left...).Err(); err != nil {
+			logger.Warnw("could not drop participants of tenant left by nodes", err, "tenant", tenant)
+		}
+	}
+	return participants, tracks, nil
+}
+
+func (s *RedisStore) aliveNodes() (map[livekit.NodeID]bool, error) {
+	items, err := s.rc.HVals(s.ctx, routing.NodesKey).Result()
+	if err != nil && err != redis.Nil {
+		return nil, err
+	}
+
+	alive := make(map[livekit.NodeID]bool, len(items))
+	for _, item := range items {
+		node := &livekit.Node{}
+		if err = proto.Unmarshal([]byte(item), node); err != nil {
+			return nil, err
+		}
+		if selector.IsAvailable(node) {
+			alive[livekit.NodeID(node.Id)] = true
+		}
+	}
+	return alive, nil
+}
+
 // Migration to LiveKit >= v1.1.3
 func (s *RedisStore) MigrateEgressInfo() (int, error) {
 	locked, err := s.rc.SetNX(s.ctx, "egress-migration", utils.NewGuid("LOCK"), time.Minute).Result()
@@ -14,6 +14,7 @@ import (
 	"github.com/livekit/protocol/livekit"
 	"github.com/livekit/protocol/utils"
 
+	"github.com/livekit/livekit-server/pkg/routing"
 	"github.com/livekit/livekit-server/pkg/service"
 )
 
@@ -142,6 +143,33 @@ func TestRoomLock(t *testing.T) {
 	})
 }
 
+func TestTenantParticipants(t *testing.T) {
+	ctx := context.Background()
+	rc := redisClient()
+	rs := service.NewRedisStore(rc)
+	tenant := "test_tenant"
+
+	alive, err := proto.Marshal(&livekit.Node{Id: "ND_alive", Stats: &livekit.NodeStats{UpdatedAt: time.Now().Unix()}})
+	require.NoError(t, err)
+	require.NoError(t, rc.HSet(ctx, routing.NodesKey, "ND_alive", alive).Err())
+	defer rc.HDel(ctx, routing.NodesKey, "ND_alive")
+
+	require.NoError(t, rs.StoreTenantParticipant(ctx, tenant, "ND_alive", "PA_1", 2))
+	require.NoError(t, rs.StoreTenantParticipant(ctx, tenant, "ND_crashed", "PA_2", 1))
+	participants, tracks, err := rs.LoadTenantUsage(ctx, tenant)
+	require.NoError(t, err)
+	require.Equal(t, 1, participants)
+	require.Equal(t, 2, tracks)
+
+	// participants of nodes that went away are dropped
+	n, err := rc.HLen(ctx, service.TenantParticipantsPrefix+tenant).Result()
+	require.NoError(t, err)
+	require.Equal(t, int64(1), n)
+
+	// clean up
+	require.NoError(t, rs.DeleteTenantParticipant(ctx, tenant, "PA_1"))
+}
+
 func TestEgressStore(t *testing.T) {
 	ctx := context.Background()
 	rc := redisClient()
@@ -0,0 +1,1149 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/go-redis/redis/v8"
+	"github.com/pion/rtcp"
+	"github.com/pion/srtp/v2"
+	"github.com/pion/webrtc/v3"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/routing"
+	"github.com/livekit/livekit-server/pkg/routing/selector"
+	"github.com/livekit/livekit-server/pkg/rtc"
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+	"github.com/livekit/livekit-server/pkg/sfu"
+)
+
+const (
+	relayLivenessInterval = 5 * time.Second
+	relayDataQueueSize    = 200
+
+	// node started hosting participants of a room, nodes already hosting it announce theirs
+	relayMessageJoin = "join"
+	// node stopped hosting participants of a room
+	relayMessageLeave = "leave"
+	// participant hosted by the sender joined or changed, with codecs its tracks are relayed in
+	relayMessageParticipant     = "participant"
+	relayMessageParticipantLeft = "participant_left"
+	// a track received by the sender gained its first subscriber there, or lost its last. It's relayed to the sender
+	// only while subscribed
+	relayMessageSubscribe   = "subscribe"
+	relayMessageUnsubscribe = "unsubscribe"
+	// the sender stopped receiving a track
+	relayMessageStopRelay = "stop_relay"
+	// highest quality of a video track subscribed on the sender
+	relayMessageQuality = "quality"
+	relayMessageData    = "data"
+	// room API request applying to the whole room, forwarded by the node the room is assigned to
+	relayMessageRoom = "room"
+)
+
+func relayNodeChannel(nodeID livekit.NodeID) string {
+	return "relay_channel:" + string(nodeID)
+}
+
+// nodes hosting participants of a room, set
+func roomNodesKey(roomName livekit.RoomName) string {
+	return "room_nodes:" + string(roomName)
+}
+
+type relayMessage struct {
+	Type   string           `json:"type"`
+	Room   livekit.RoomName `json:"room"`
+	NodeID livekit.NodeID   `json:"node_id"`
+	Region string           `json:"region,omitempty"`
+	// ParticipantInfo, protobuf encoded
+	Participant []byte                      `json:"participant,omitempty"`
+	Tracks      []relayTrack                `json:"tracks,omitempty"`
+	Identity    livekit.ParticipantIdentity `json:"identity,omitempty"`
+	// track ID on the node hosting its participant
+	TrackID livekit.TrackID `json:"track_id,omitempty"`
+	// host and port to relay a track to, the SSRC it's published with there, and the SRTP master key and salt to
+	// encrypt it with
+	Address string               `json:"address,omitempty"`
+	SSRC    uint32               `json:"ssrc,omitempty"`
+	SRTPKey []byte               `json:"srtp_key,omitempty"`
+	Quality livekit.VideoQuality `json:"quality,omitempty"`
+	// DataPacket or RTCNodeMessage, protobuf encoded
+	Data []byte `json:"data,omitempty"`
+}
+
+// relayTrack is the codec a track is relayed in
+type relayTrack struct {
+	TrackID     livekit.TrackID `json:"track_id"`
+	MimeType    string          `json:"mime_type"`
+	ClockRate   uint32          `json:"clock_rate"`
+	Channels    uint16          `json:"channels,omitempty"`
+	Fmtp        string          `json:"fmtp,omitempty"`
+	PayloadType uint8           `json:"payload_type"`
+}
+
+type trackRelayKey struct {
+	roomName livekit.RoomName
+	nodeID   livekit.NodeID
+	trackID  livekit.TrackID
+}
+
+// relayRoom is a room this node hosts participants of
+type relayRoom struct {
+	// other nodes hosting participants of the room
+	nodes map[livekit.NodeID]bool
+	// participants hosted by other nodes
+	participants map[livekit.ParticipantIdentity]*relayedParticipant
+}
+
+// RelayService lets rooms span nodes. Participants are hosted by the node they connect to, which announces them to
+// the other nodes hosting the room. Those start relayed participants in their place, publishing tracks that the
+// hosting node relays to an RTP ingest on each of them. Nodes exchange messages over redis. The node a room is
+// assigned to by the router keeps handling room API requests, and forwards those applying to the whole room.
+type RelayService struct {
+	conf        config.RelayConfig
+	nodeIP      string
+	rc          redis.UniversalClient
+	router      routing.Router
+	currentNode routing.LocalNode
+	ports       *udpPortRange
+
+	lock        sync.Mutex
+	roomManager *RoomManager
+	rooms       map[livekit.RoomName]*relayRoom
+	relays      map[trackRelayKey]*rtc.TrackRelay
+
+	pubsub    *redis.PubSub
+	dataQueue chan *relayMessage
+	stopOnce  sync.Once
+	done      chan struct{}
+}
+
+func NewRelayService(
+	conf *config.Config,
+	rc redis.UniversalClient,
+	router routing.Router,
+	currentNode routing.LocalNode,
+) *RelayService {
+	return &RelayService{
+		conf:        conf.Relay,
+		nodeIP:      conf.RTC.NodeIP,
+		rc:          rc,
+		router:      router,
+		currentNode: currentNode,
+		ports:       newUDPPortRange(conf.Relay.PortRangeStart, conf.Relay.PortRangeEnd),
+		rooms:       make(map[livekit.RoomName]*relayRoom),
+		relays:      make(map[trackRelayKey]*rtc.TrackRelay),
+		dataQueue:   make(chan *relayMessage, relayDataQueueSize),
+		done:        make(chan struct{}),
+	}
+}
+
+func (s *RelayService) Enabled() bool {
+	return s != nil && s.conf.Enabled && s.rc != nil &&
+		s.conf.PortRangeStart != 0 && s.conf.PortRangeEnd >= s.conf.PortRangeStart
+}
+
+// Start receives relay messages for this node, and hooks into rooms of the room manager
+func (s *RelayService) Start(roomManager *RoomManager) {
+	if !s.Enabled() {
+		if s.conf.Enabled {
+			logger.Warnw("relay is not enabled, it requires redis and a port range", nil)
+		}
+		return
+	}
+
+	s.lock.Lock()
+	s.roomManager = roomManager
+	s.lock.Unlock()
+	roomManager.setRelay(s)
+
+	s.pubsub = s.rc.Subscribe(context.Background(), relayNodeChannel(s.nodeID()))
+	go s.subscribeWorker()
+	go s.dataWorker()
+	go s.livenessWorker()
+}
+
+func (s *RelayService) Stop() {
+	if !s.Enabled() {
+		return
+	}
+	s.stopOnce.Do(func() {
+		close(s.done)
+		if s.pubsub != nil {
+			_ = s.pubsub.Close()
+		}
+
+		s.lock.Lock()
+		relays := make([]*rtc.TrackRelay, 0, len(s.relays))
+		for _, relay := range s.relays {
+			relays = append(relays, relay)
+		}
+		s.lock.Unlock()
+
+		for _, relay := range relays {
+			relay.Close()
+		}
+	})
+}
+
+// ---------------------------------------------------------------
+// hooks of the room manager
+
+// roomCreated relays data packets sent in a room of this node to the other nodes hosting it
+func (s *RelayService) roomCreated(room *rtc.Room) {
+	if !s.Enabled() {
+		return
+	}
+	roomName := room.Name()
+	room.OnDataPacketSent(func(source types.LocalParticipant, dp *livekit.DataPacket) {
+		msg := &relayMessage{Type: relayMessageData, Room: roomName}
+		if source != nil {
+			if s.isRelayed(roomName, source) {
+				return
+			}
+			msg.Identity = source.Identity()
+		}
+		data, err := proto.Marshal(dp)
+		if err != nil {
+			return
+		}
+		msg.Data = data
+
+		select {
+		case s.dataQueue <- msg:
+		default:
+			logger.Warnw("relay data queue is full, dropping packet", nil, "room", roomName)
+		}
+	})
+}
+
+// isRemoteRoom returns whether the room is assigned to another node, which handles it as a whole
+func (s *RelayService) isRemoteRoom(ctx context.Context, roomName livekit.RoomName) bool {
+	if !s.Enabled() {
+		return false
+	}
+	node, err := s.router.GetNodeForRoom(ctx, roomName)
+	return err == nil && node.Id != s.currentNode.Id
+}
+
+// participantChanged announces active participants hosted by this node to the other nodes hosting the room
+func (s *RelayService) participantChanged(room *rtc.Room, p types.LocalParticipant) {
+	if !s.Enabled() || p.State() != livekit.ParticipantInfo_ACTIVE || s.isRelayed(room.Name(), p) {
+		return
+	}
+	s.startHosting(room.Name())
+	s.announce(room.Name(), p, s.roomNodes(room.Name()))
+}
+
+// participantClosed is called for participants hosted by this node. Participants of other nodes are only relayed
+// while this node hosts participants of the room
+func (s *RelayService) participantClosed(room *rtc.Room, p types.LocalParticipant) {
+	if !s.Enabled() {
+		return
+	}
+	if info, err := proto.Marshal(p.ToProto()); err == nil {
+		for _, nodeID := range s.roomNodes(room.Name()) {
+			s.send(nodeID, &relayMessage{Type: relayMessageParticipantLeft, Room: room.Name(), Participant: info})
+		}
+	}
+
+	for _, op := range room.GetParticipants() {
+		if op != p && op.State() != livekit.ParticipantInfo_DISCONNECTED && !s.isRelayed(room.Name(), op) {
+			return
+		}
+	}
+	s.stopHosting(room)
+}
+
+// roomClosed returns whether the room is still hosted by other nodes, and so its state is to be kept. When it was
+// assigned to this node, it's assigned to one of them
+func (s *RelayService) roomClosed(ctx context.Context, room *rtc.Room) bool {
+	if !s.Enabled() {
+		return false
+	}
+	s.stopHosting(room)
+
+	roomName := room.Name()
+	node, err := s.router.GetNodeForRoom(ctx, roomName)
+	if err != nil {
+		return false
+	}
+	if node.Id != s.currentNode.Id {
+		return true
+	}
+
+	members, err := s.rc.SMembers(ctx, roomNodesKey(roomName)).Result()
+	if err != nil || len(members) == 0 {
+		return false
+	}
+	nodes, err := s.router.ListNodes()
+	if err != nil {
+		return false
+	}
+	for _, member := range members {
+		for _, n := range nodes {
+			if n.Id != member || n.Id == s.currentNode.Id || !selector.IsAvailable(n) {
+				continue
+			}
+			if err = s.router.SetNodeForRoom(ctx, roomName, livekit.NodeID(n.Id)); err != nil {
+				logger.Errorw("could not hand off room", err, "room", roomName, "nodeID", n.Id)
+				return false
+			}
+			logger.Infow("room handed off", "room", roomName, "nodeID", n.Id)
+			return true
+		}
+	}
+	return false
+}
+
+// forwardRTCMessage forwards room API requests applying to the whole room to the other nodes hosting it
+func (s *RelayService) forwardRTCMessage(ctx context.Context, roomName livekit.RoomName, msg *livekit.RTCNodeMessage) {
+	if !s.Enabled() {
+		return
+	}
+	switch msg.Message.(type) {
+	case *livekit.RTCNodeMessage_DeleteRoom, *livekit.RTCNodeMessage_UpdateRoomMetadata:
+	default:
+		return
+	}
+
+	members, err := s.rc.SMembers(ctx, roomNodesKey(roomName)).Result()
+	if err != nil {
+		logger.Errorw("could not get nodes of room", err, "room", roomName)
+		return
+	}
+	if _, ok := msg.Message.(*livekit.RTCNodeMessage_DeleteRoom); ok {
+		// so that the room isn't handed off as it closes
+		if err = s.rc.Del(ctx, roomNodesKey(roomName)).Err(); err != nil {
+			logger.Errorw("could not clear nodes of room", err, "room", roomName)
+		}
+	}
+
+	data, err := proto.Marshal(msg)
+	if err != nil {
+		return
+	}
+	for _, member := range members {
+		if member != s.currentNode.Id {
+			s.send(livekit.NodeID(member), &relayMessage{Type: relayMessageRoom, Room: roomName, Data: data})
+		}
+	}
+}
+
+// ---------------------------------------------------------------
+
+func (s *RelayService) subscribeWorker() {
+	defer rtc.Recover()
+
+	for msg := range s.pubsub.Channel() {
+		if msg == nil {
+			return
+		}
+		rm := &relayMessage{}
+		if err := json.Unmarshal([]byte(msg.Payload), rm); err != nil {
+			logger.Errorw("could not unmarshal relay message", err)
+			continue
+		}
+		s.handleMessage(rm)
+	}
+}
+
+func (s *RelayService) handleMessage(msg *relayMessage) {
+	ctx := context.Background()
+	room := s.roomManager.GetRoom(ctx, msg.Room)
+	if room == nil {
+		return
+	}
+
+	switch msg.Type {
+	case relayMessageJoin:
+		if s.addRoomNode(msg.Room, msg.NodeID) {
+			for _, p := range room.GetParticipants() {
+				if p.State() == livekit.ParticipantInfo_ACTIVE && !s.isRelayed(msg.Room, p) {
+					s.announce(msg.Room, p, []livekit.NodeID{msg.NodeID})
+				}
+			}
+		}
+	case relayMessageLeave:
+		s.removeRoomNode(room, msg.NodeID)
+	case relayMessageParticipant:
+		s.handleParticipant(ctx, room, msg)
+	case relayMessageParticipantLeft:
+		info := &livekit.ParticipantInfo{}
+		if err := proto.Unmarshal(msg.Participant, info); err != nil {
+			return
+		}
+		if rp := s.getRelayed(msg.Room, livekit.ParticipantIdentity(info.Identity)); rp != nil && rp.sid == livekit.ParticipantID(info.Sid) {
+			s.removeRelayed(room, rp)
+		}
+	case relayMessageSubscribe:
+		s.handleSubscribe(room, msg)
+	case relayMessageUnsubscribe:
+		s.lock.Lock()
+		relay := s.relays[trackRelayKey{roomName: msg.Room, nodeID: msg.NodeID, trackID: msg.TrackID}]
+		s.lock.Unlock()
+		if relay != nil {
+			relay.SetPaused(true)
+		}
+	case relayMessageStopRelay:
+		s.lock.Lock()
+		relay := s.relays[trackRelayKey{roomName: msg.Room, nodeID: msg.NodeID, trackID: msg.TrackID}]
+		s.lock.Unlock()
+		if relay != nil {
+			relay.Close()
+		}
+	case relayMessageQuality:
+		s.lock.Lock()
+		relay := s.relays[trackRelayKey{roomName: msg.Room, nodeID: msg.NodeID, trackID: msg.TrackID}]
+		s.lock.Unlock()
+		if relay != nil {
+			relay.SetMaxQuality(msg.Quality)
+		}
+	case relayMessageData:
+		dp := &livekit.DataPacket{}
+		if err := proto.Unmarshal(msg.Data, dp); err != nil {
+			return
+		}
+		var source types.LocalParticipant
+		if msg.Identity != "" {
+			if source = room.GetParticipant(msg.Identity); source == nil || !s.isRelayed(msg.Room, source) {
+				return
+			}
+		}
+		room.DeliverDataPacket(source, dp)
+	case relayMessageRoom:
+		rm := &livekit.RTCNodeMessage{}
+		if err := proto.Unmarshal(msg.Data, rm); err != nil {
+			return
+		}
+		s.roomManager.applyRTCMessage(ctx, msg.Room, "", rm)
+	}
+}
+
+// handleParticipant starts or updates the relayed participant of an announcement
+func (s *RelayService) handleParticipant(ctx context.Context, room *rtc.Room, msg *relayMessage) {
+	info := &livekit.ParticipantInfo{}
+	if err := proto.Unmarshal(msg.Participant, info); err != nil {
+		return
+	}
+	if !s.addRoomNode(msg.Room, msg.NodeID) {
+		return
+	}
+
+	rp := s.getRelayed(msg.Room, livekit.ParticipantIdentity(info.Identity))
+	if rp != nil && (rp.sid != livekit.ParticipantID(info.Sid) || rp.nodeID != msg.NodeID) {
+		// rejoined, possibly on another node
+		s.removeRelayed(room, rp)
+		rp = nil
+	}
+	if rp == nil {
+		if rp = s.startRelayed(ctx, room, msg, info); rp == nil {
+			return
+		}
+	}
+	s.updateRelayed(room, rp, info, msg.Tracks)
+}
+
+func (s *RelayService) startRelayed(ctx context.Context, room *rtc.Room, msg *relayMessage, info *livekit.ParticipantInfo) *relayedParticipant {
+	identity := livekit.ParticipantIdentity(info.Identity)
+	if p := room.GetParticipant(identity); p != nil {
+		// identity joined on two nodes, the participant that joined last remains
+		local := p.ToProto()
+		if local.JoinedAt > info.JoinedAt || (local.JoinedAt == info.JoinedAt && local.Sid > info.Sid) {
+			return nil
+		}
+		p.GetLogger().Infow("removing duplicate participant, joined on another node", "nodeID", msg.NodeID)
+		room.RemoveParticipant(identity, types.ParticipantCloseReasonDuplicateIdentity)
+	}
+
+	rp := &relayedParticipant{
+		roomName:  msg.Room,
+		identity:  identity,
+		sid:       livekit.ParticipantID(info.Sid),
+		nodeID:    msg.NodeID,
+		reqSink:   routing.NewMessageChannel(routing.DefaultMessageChannelSize),
+		resSource: routing.NewMessageChannel(routing.DefaultMessageChannelSize),
+		tracks:    make(map[livekit.TrackID]*relayedTrack),
+	}
+	s.lock.Lock()
+	rr := s.rooms[msg.Room]
+	if rr == nil {
+		s.lock.Unlock()
+		return nil
+	}
+	rr.participants[identity] = rp
+	s.lock.Unlock()
+
+	pi := routing.ParticipantInit{
+		Identity: identity,
+		Name:     livekit.ParticipantName(info.Name),
+		ID:       rp.sid,
+		Grants: &auth.ClaimGrants{
+			Identity: info.Identity,
+			Name:     info.Name,
+			Metadata: info.Metadata,
+			Video: &auth.VideoGrant{
+				RoomJoin: true,
+				Room:     string(msg.Room),
+				Hidden:   info.Permission.GetHidden(),
+				Recorder: info.Permission.GetRecorder(),
+			},
+		},
+		Client:        &livekit.ClientInfo{Protocol: types.CurrentProtocol},
+		Region:        msg.Region,
+		ExternalMedia: true,
+		Relayed:       true,
+	}
+	pi.Grants.Video.SetCanPublish(true)
+	pi.Grants.Video.SetCanSubscribe(false)
+	pi.Grants.Video.SetCanPublishData(true)
+
+	err := s.roomManager.StartSession(ctx, msg.Room, pi, rp.reqSink, rp.resSource)
+	if err == nil {
+		if rp.participant = room.GetParticipant(identity); rp.participant == nil || rp.participant.ID() != rp.sid {
+			err = ErrParticipantNotFound
+		}
+	}
+	if err != nil {
+		logger.Warnw("could not start relayed participant", err, "room", msg.Room, "participant", identity, "nodeID", msg.NodeID)
+		s.removeRelayed(room, rp)
+		return nil
+	}
+
+	go s.relayedParticipantWorker(room, rp)
+	return rp
+}
+
+// updateRelayedParticipant applies an announcement, receiving tracks that are new and closing those that are gone
+func (s *RelayService) updateRelayed(room *rtc.Room, rp *relayedParticipant, info *livekit.ParticipantInfo, relayTracks []relayTrack) {
+	if rp.participant.ToProto().Metadata != info.Metadata {
+		rp.participant.SetMetadata(info.Metadata)
+	}
+
+	codecs := make(map[livekit.TrackID]relayTrack, len(relayTracks))
+	for _, rt := range relayTracks {
+		codecs[rt.TrackID] = rt
+	}
+
+	announced := make(map[livekit.TrackID]bool, len(info.Tracks))
+	for _, ti := range info.Tracks {
+		trackID := livekit.TrackID(ti.Sid)
+		rp.lock.Lock()
+		track := rp.tracks[trackID]
+		closed := rp.closed
+		rp.lock.Unlock()
+		if closed {
+			return
+		}
+
+		if track == nil {
+			codec, ok := codecs[trackID]
+			if !ok {
+				// not received by the hosting node yet
+				continue
+			}
+			if track = s.receiveTrack(room, rp, ti, codec); track == nil {
+				continue
+			}
+			rp.lock.Lock()
+			rp.tracks[trackID] = track
+			rp.lock.Unlock()
+		} else {
+			track.setMuted(rp.participant, ti.Muted)
+		}
+		announced[trackID] = true
+	}
+
+	rp.lock.Lock()
+	removed := make(map[livekit.TrackID]*relayedTrack)
+	for trackID, track := range rp.tracks {
+		if !announced[trackID] {
+			delete(rp.tracks, trackID)
+			removed[trackID] = track
+		}
+	}
+	rp.lock.Unlock()
+	for trackID, track := range removed {
+		track.ingest.Close()
+		s.send(rp.nodeID, &relayMessage{Type: relayMessageStopRelay, Room: rp.roomName, Identity: rp.identity, TrackID: trackID})
+	}
+}
+
+// receiveTrack starts an RTP ingest for a track of a relayed participant, publishing it with the ID it has on the
+// hosting node. That node is asked to relay it while it's subscribed here
+func (s *RelayService) receiveTrack(room *rtc.Room, rp *relayedParticipant, ti *livekit.TrackInfo, codec relayTrack) *relayedTrack {
+	publisher, ok := rp.participant.(rtc.ExternalTrackPublisher)
+	if !ok {
+		return nil
+	}
+	l := logger.Logger(logr.Logger(logger.GetDefaultLogger()).WithValues(
+		"room", rp.roomName, "participant", rp.identity, "trackID", ti.Sid, "nodeID", rp.nodeID,
+	))
+
+	// 128 bit master key and 112 bit master salt, followed by the SSRC
+	b := make([]byte, 34)
+	if _, err := rand.Read(b); err != nil {
+		return nil
+	}
+	key := b[:30]
+	ssrc := binary.BigEndian.Uint32(b[30:])
+	if ssrc == 0 {
+		ssrc = 1
+	}
+	conn, port, err := s.ports.allocate()
+	if err != nil {
+		l.Warnw("could not receive relayed track", err)
+		return nil
+	}
+
+	subscribe := &relayMessage{
+		Room:     rp.roomName,
+		Identity: rp.identity,
+		TrackID:  livekit.TrackID(ti.Sid),
+		Address:  net.JoinHostPort(s.nodeIP, fmt.Sprint(port)),
+		SSRC:     ssrc,
+		SRTPKey:  key,
+	}
+	track := &relayedTrack{
+		publisher: publisher,
+		muted:     ti.Muted,
+		onSubscriptionChanged: func(subscribed bool) {
+			msg := *subscribe
+			msg.Type = relayMessageUnsubscribe
+			if subscribed {
+				msg.Type = relayMessageSubscribe
+			}
+			s.send(rp.nodeID, &msg)
+		},
+	}
+	track.ingest, err = rtc.NewRTPIngest(rtc.RTPIngestParams{
+		Conn: conn,
+		Tracks: []rtc.RTPIngestTrack{
+			{
+				Name:   ti.Name,
+				Source: ti.Source,
+				Codec: webrtc.RTPCodecParameters{
+					RTPCodecCapability: webrtc.RTPCodecCapability{
+						MimeType:    codec.MimeType,
+						ClockRate:   codec.ClockRate,
+						Channels:    codec.Channels,
+						SDPFmtpLine: codec.Fmtp,
+					},
+					PayloadType: webrtc.PayloadType(codec.PayloadType),
+				},
+				HeaderExtensions: rtc.RelayHeaderExtensions(ti.Type),
+				Width:            ti.Width,
+				Height:           ti.Height,
+				Layers:           ti.Layers,
+				TrackID:          livekit.TrackID(ti.Sid),
+				SSRC:             ssrc,
+			},
+		},
+		SRTPKey:       key,
+		SRTPProfile:   srtp.ProtectionProfileAes128CmHmacSha1_80,
+		Publisher:     track,
+		BufferFactory: room.GetBufferFactory(),
+		Logger:        l,
+	})
+	if err != nil {
+		_ = conn.Close()
+		s.ports.release(port)
+		l.Warnw("could not receive relayed track", err)
+		return nil
+	}
+	track.ingest.OnClose(func() {
+		s.ports.release(port)
+	})
+	track.ingest.Start()
+	return track
+}
+
+// relayedParticipantWorker forwards qualities subscribed on this node to the hosting node, until the participant leaves
+func (s *RelayService) relayedParticipantWorker(room *rtc.Room, rp *relayedParticipant) {
+	defer rtc.Recover()
+	defer s.removeRelayed(room, rp)
+
+	for msg := range rp.resSource.ReadChan() {
+		res, ok := msg.(*livekit.SignalResponse)
+		if !ok {
+			continue
+		}
+		if res.GetLeave() != nil {
+			return
+		}
+		if update := res.GetSubscribedQualityUpdate(); update != nil {
+			// relayed tracks are published with the ID they have on the hosting node
+			s.send(rp.nodeID, &relayMessage{
+				Type:     relayMessageQuality,
+				Room:     rp.roomName,
+				Identity: rp.identity,
+				TrackID:  livekit.TrackID(update.TrackSid),
+				Quality:  maxSubscribedQuality(update),
+			})
+		}
+	}
+}
+
+// handleSubscribe starts relaying a track of a participant hosted by this node to the sender, or resumes relaying it
+// to the same ingest
+func (s *RelayService) handleSubscribe(room *rtc.Room, msg *relayMessage) {
+	p := room.GetParticipant(msg.Identity)
+	if p == nil || s.isRelayed(msg.Room, p) {
+		return
+	}
+	track, ok := p.GetPublishedTrack(msg.TrackID).(*rtc.MediaTrack)
+	if !ok {
+		return
+	}
+	codec, ok := rtc.RelayCodec(track)
+	if !ok {
+		return
+	}
+	l := logger.Logger(logr.Logger(p.GetLogger()).WithValues("trackID", msg.TrackID, "nodeID", msg.NodeID))
+
+	key := trackRelayKey{roomName: msg.Room, nodeID: msg.NodeID, trackID: msg.TrackID}
+	s.lock.Lock()
+	previous := s.relays[key]
+	if previous != nil && previous.SSRC() == msg.SSRC {
+		s.lock.Unlock()
+		previous.SetPaused(false)
+		return
+	}
+	delete(s.relays, key)
+	s.lock.Unlock()
+	if previous != nil {
+		previous.Close()
+	}
+
+	remote, err := net.ResolveUDPAddr("udp", msg.Address)
+	if err != nil {
+		l.Warnw("could not relay track", err, "address", msg.Address)
+		return
+	}
+	conn, err := net.ListenPacket("udp", ":0")
+	if err != nil {
+		l.Warnw("could not relay track", err)
+		return
+	}
+	relay, err := rtc.NewTrackRelay(rtc.TrackRelayParams{
+		Track:       track,
+		NodeID:      msg.NodeID,
+		Codec:       codec,
+		Conn:        conn,
+		RemoteAddr:  remote,
+		SSRC:        msg.SSRC,
+		SRTPKey:     msg.SRTPKey,
+		SRTPProfile: srtp.ProtectionProfileAes128CmHmacSha1_80,
+		Logger:      l,
+	})
+	if err != nil {
+		_ = conn.Close()
+		l.Warnw("could not relay track", err)
+		return
+	}
+
+	s.lock.Lock()
+	s.relays[key] = relay
+	s.lock.Unlock()
+	relay.OnClose(func() {
+		s.deleteRelay(key, relay)
+	})
+	select {
+	case <-relay.Done():
+		s.deleteRelay(key, relay)
+	default:
+		l.Debugw("relaying track", "address", msg.Address)
+	}
+}
+
+func (s *RelayService) deleteRelay(key trackRelayKey, relay *rtc.TrackRelay) {
+	s.lock.Lock()
+	if s.relays[key] == relay {
+		delete(s.relays, key)
+	}
+	s.lock.Unlock()
+}
+
+// announce sends a participant hosted by this node, and codecs of tracks it publishes, to other nodes
+func (s *RelayService) announce(roomName livekit.RoomName, p types.LocalParticipant, nodeIDs []livekit.NodeID) {
+	if len(nodeIDs) == 0 {
+		return
+	}
+	info, err := proto.Marshal(p.ToProto())
+	if err != nil {
+		return
+	}
+	msg := &relayMessage{Type: relayMessageParticipant, Room: roomName, Participant: info}
+	for _, track := range p.GetPublishedTracks() {
+		codec, ok := rtc.RelayCodec(track)
+		if !ok {
+			continue
+		}
+		msg.Tracks = append(msg.Tracks, relayTrack{
+			TrackID:     track.ID(),
+			MimeType:    codec.MimeType,
+			ClockRate:   codec.ClockRate,
+			Channels:    codec.Channels,
+			Fmtp:        codec.SDPFmtpLine,
+			PayloadType: uint8(codec.PayloadType),
+		})
+	}
+	for _, nodeID := range nodeIDs {
+		s.send(nodeID, msg)
+	}
+}
+
+func (s *RelayService) send(nodeID livekit.NodeID, msg *relayMessage) {
+	msg.NodeID = s.nodeID()
+	msg.Region = s.currentNode.Region
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return
+	}
+	if err = s.rc.Publish(context.Background(), relayNodeChannel(nodeID), data).Err(); err != nil {
+		logger.Warnw("could not send relay message", err, "nodeID", nodeID, "type", msg.Type)
+	}
+}
+
+// startHosting registers this node as hosting participants of the room, and joins the other nodes hosting it
+func (s *RelayService) startHosting(roomName livekit.RoomName) {
+	s.lock.Lock()
+	if s.rooms[roomName] != nil {
+		s.lock.Unlock()
+		return
+	}
+	rr := &relayRoom{
+		nodes:        make(map[livekit.NodeID]bool),
+		participants: make(map[livekit.ParticipantIdentity]*relayedParticipant),
+	}
+	s.rooms[roomName] = rr
+	s.lock.Unlock()
+
+	ctx := context.Background()
+	if err := s.rc.SAdd(ctx, roomNodesKey(roomName), s.currentNode.Id).Err(); err != nil {
+		logger.Errorw("could not add node to room", err, "room", roomName)
+	}
+	members, err := s.rc.SMembers(ctx, roomNodesKey(roomName)).Result()
+	if err != nil {
+		logger.Errorw("could not get nodes of room", err, "room", roomName)
+		return
+	}
+	for _, member := range members {
+		if member == s.currentNode.Id {
+			continue
+		}
+		s.lock.Lock()
+		rr.nodes[livekit.NodeID(member)] = true
+		s.lock.Unlock()
+		s.send(livekit.NodeID(member), &relayMessage{Type: relayMessageJoin, Room: roomName})
+	}
+}
+
+// stopHosting leaves the other nodes hosting the room, which stop relaying to this node, and removes their participants
+func (s *RelayService) stopHosting(room *rtc.Room) {
+	roomName := room.Name()
+	s.lock.Lock()
+	rr := s.rooms[roomName]
+	delete(s.rooms, roomName)
+	var relays []*rtc.TrackRelay
+	for key, relay := range s.relays {
+		if key.roomName == roomName {
+			relays = append(relays, relay)
+		}
+	}
+	s.lock.Unlock()
+	if rr == nil {
+		return
+	}
+
+	if err := s.rc.SRem(context.Background(), roomNodesKey(roomName), s.currentNode.Id).Err(); err != nil {
+		logger.Errorw("could not remove node from room", err, "room", roomName)
+	}
+	for nodeID := range rr.nodes {
+		s.send(nodeID, &relayMessage{Type: relayMessageLeave, Room: roomName})
+	}
+	for _, relay := range relays {
+		relay.Close()
+	}
+	for _, rp := range rr.participants {
+		s.removeRelayed(room, rp)
+	}
+}
+
+// addRoomNode returns false when this node doesn't host participants of the room
+func (s *RelayService) addRoomNode(roomName livekit.RoomName, nodeID livekit.NodeID) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	rr := s.rooms[roomName]
+	if rr == nil {
+		return false
+	}
+	rr.nodes[nodeID] = true
+	return true
+}
+
+// removeRoomNode stops relaying to a node that left the room, and removes participants it hosts
+func (s *RelayService) removeRoomNode(room *rtc.Room, nodeID livekit.NodeID) {
+	roomName := room.Name()
+	s.lock.Lock()
+	var participants []*relayedParticipant
+	if rr := s.rooms[roomName]; rr != nil {
+		delete(rr.nodes, nodeID)
+		for _, rp := range rr.participants {
+			if rp.nodeID == nodeID {
+				participants = append(participants, rp)
+			}
+		}
+	}
+	var relays []*rtc.TrackRelay
+	for key, relay := range s.relays {
+		if key.roomName == roomName && key.nodeID == nodeID {
+			relays = append(relays, relay)
+		}
+	}
+	s.lock.Unlock()
+
+	for _, relay := range relays {
+		relay.Close()
+	}
+	for _, rp := range participants {
+		s.removeRelayed(room, rp)
+	}
+}
+
+func (s *RelayService) roomNodes(roomName livekit.RoomName) []livekit.NodeID {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	rr := s.rooms[roomName]
+	if rr == nil {
+		return nil
+	}
+	nodeIDs := make([]livekit.NodeID, 0, len(rr.nodes))
+	for nodeID := range rr.nodes {
+		nodeIDs = append(nodeIDs, nodeID)
+	}
+	return nodeIDs
+}
+
+func (s *RelayService) getRelayed(roomName livekit.RoomName, identity livekit.ParticipantIdentity) *relayedParticipant {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if rr := s.rooms[roomName]; rr != nil {
+		return rr.participants[identity]
+	}
+	return nil
+}
+
+// isRelayed returns whether a participant of a room is hosted by another node
+func (s *RelayService) isRelayed(roomName livekit.RoomName, p types.LocalParticipant) bool {
+	if !s.Enabled() {
+		return false
+	}
+	rp := s.getRelayed(roomName, p.Identity())
+	return rp != nil && rp.sid == p.ID()
+}
+
+func (s *RelayService) removeRelayed(room *rtc.Room, rp *relayedParticipant) {
+	s.lock.Lock()
+	if rr := s.rooms[rp.roomName]; rr != nil && rr.participants[rp.identity] == rp {
+		delete(rr.participants, rp.identity)
+	}
+	s.lock.Unlock()
+
+	if !rp.close() {
+		return
+	}
+	if p := room.GetParticipant(rp.identity); p != nil && p.ID() == rp.sid {
+		room.RemoveParticipant(rp.identity, types.ParticipantCloseReasonStateDisconnected)
+	}
+	rp.reqSink.Close()
+	rp.resSource.Close()
+}
+
+func (s *RelayService) dataWorker() {
+	defer rtc.Recover()
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case msg := <-s.dataQueue:
+			for _, nodeID := range s.roomNodes(msg.Room) {
+				s.send(nodeID, msg)
+			}
+		}
+	}
+}
+
+// livenessWorker stops relaying to nodes that are no longer available, and removes participants they hosted
+func (s *RelayService) livenessWorker() {
+	defer rtc.Recover()
+
+	ticker := time.NewTicker(relayLivenessInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+		}
+
+		nodes, err := s.router.ListNodes()
+		if err != nil {
+			continue
+		}
+		available := make(map[livekit.NodeID]bool, len(nodes))
+		for _, n := range nodes {
+			if selector.IsAvailable(n) {
+				available[livekit.NodeID(n.Id)] = true
+			}
+		}
+
+		s.lock.Lock()
+		unavailable := make(map[livekit.RoomName][]livekit.NodeID)
+		for roomName, rr := range s.rooms {
+			for nodeID := range rr.nodes {
+				if !available[nodeID] {
+					unavailable[roomName] = append(unavailable[roomName], nodeID)
+				}
+			}
+		}
+		s.lock.Unlock()
+
+		for roomName, nodeIDs := range unavailable {
+			room := s.roomManager.GetRoom(context.Background(), roomName)
+			if room == nil {
+				continue
+			}
+			for _, nodeID := range nodeIDs {
+				logger.Infow("node hosting room is unavailable", "room", roomName, "nodeID", nodeID)
+				_ = s.rc.SRem(context.Background(), roomNodesKey(roomName), string(nodeID)).Err()
+				s.removeRoomNode(room, nodeID)
+			}
+		}
+	}
+}
+
+func (s *RelayService) nodeID() livekit.NodeID {
+	return livekit.NodeID(s.currentNode.Id)
+}
+
+// ---------------------------------------------------------------
+
+// relayedParticipant is a participant hosted by another node, publishing its tracks on this one
+type relayedParticipant struct {
+	roomName  livekit.RoomName
+	identity  livekit.ParticipantIdentity
+	sid       livekit.ParticipantID
+	nodeID    livekit.NodeID
+	reqSink   *routing.MessageChannel
+	resSource *routing.MessageChannel
+
+	participant types.LocalParticipant
+
+	lock sync.Mutex
+	// by track ID on the hosting node
+	tracks map[livekit.TrackID]*relayedTrack
+	closed bool
+}
+
+// close closes ingests of its tracks, returning false if it was already closed
+func (rp *relayedParticipant) close() bool {
+	rp.lock.Lock()
+	if rp.closed {
+		rp.lock.Unlock()
+		return false
+	}
+	rp.closed = true
+	tracks := rp.tracks
+	rp.tracks = make(map[livekit.TrackID]*relayedTrack)
+	rp.lock.Unlock()
+
+	for _, track := range tracks {
+		track.ingest.Close()
+	}
+	return true
+}
+
+// relayedTrack is a track received from the node hosting its participant. It's published when its ingest starts,
+// with the muted state last announced, and relayed while it's subscribed
+type relayedTrack struct {
+	publisher             rtc.ExternalTrackPublisher
+	ingest                *rtc.RTPIngest
+	onSubscriptionChanged func(subscribed bool)
+
+	lock    sync.Mutex
+	muted   bool
+	trackID livekit.TrackID
+}
+
+// AddExternalTrack implements rtc.ExternalTrackPublisher
+func (t *relayedTrack) AddExternalTrack(
+	req *livekit.AddTrackRequest,
+	trackID livekit.TrackID,
+	receiver sfu.RTPReceiver,
+	track sfu.TrackRemote,
+	rtcpCh chan []rtcp.Packet,
+) (types.MediaTrack, error) {
+	t.lock.Lock()
+	req.Muted = t.muted
+	t.lock.Unlock()
+
+	mt, err := t.publisher.AddExternalTrack(req, trackID, receiver, track, rtcpCh)
+	if mt != nil {
+		t.lock.Lock()
+		t.trackID = mt.ID()
+		t.lock.Unlock()
+		if mediaTrack, ok := mt.(*rtc.MediaTrack); ok {
+			mediaTrack.OnRelaySubscriptionChanged(t.onSubscriptionChanged)
+		}
+	}
+	return mt, err
+}
+
+func (t *relayedTrack) setMuted(p types.LocalParticipant, muted bool) {
+	t.lock.Lock()
+	t.muted = muted
+	trackID := t.trackID
+	t.lock.Unlock()
+
+	if trackID == "" {
+		return
+	}
+	if track := p.GetPublishedTrack(trackID); track != nil && track.IsMuted() != muted {
+		p.SetTrackMuted(trackID, muted, false)
+	}
+}
+
+// ------------------------------------
+
+// maxSubscribedQuality returns the highest quality enabled for any codec, OFF if none is
+func maxSubscribedQuality(update *livekit.SubscribedQualityUpdate) livekit.VideoQuality {
+	qualities := append([]*livekit.SubscribedQuality{}, update.SubscribedQualities...)
+	for _, codec := range update.SubscribedCodecs {
+		qualities = append(qualities, codec.Qualities...)
+	}
+
+	maxQuality := livekit.VideoQuality_OFF
+	for _, q := range qualities {
+		if q.Enabled && (maxQuality == livekit.VideoQuality_OFF || q.Quality > maxQuality) {
+			maxQuality = q.Quality
+		}
+	}
+	return maxQuality
+}
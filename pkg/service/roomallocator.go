@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"sync"
 	"time"
 
 	"github.com/livekit/protocol/livekit"
@@ -14,13 +15,16 @@ import (
 )
 
 type StandardRoomAllocator struct {
-	config    *config.Config
 	router    routing.Router
-	selector  selector.NodeSelector
 	roomStore ObjectStore
+	tenancy   *Tenancy
+
+	lock     sync.RWMutex
+	config   *config.Config
+	selector selector.NodeSelector
 }
 
-func NewRoomAllocator(conf *config.Config, router routing.Router, rs ObjectStore) (RoomAllocator, error) {
+func NewRoomAllocator(conf *config.Config, router routing.Router, rs ObjectStore, tenancy *Tenancy) (RoomAllocator, error) {
 	ns, err := selector.CreateNodeSelector(conf)
 	if err != nil {
 		return nil, err
@@ -31,12 +35,36 @@ func NewRoomAllocator(conf *config.Config, router routing.Router, rs ObjectStore
 		router:    router,
 		selector:  ns,
 		roomStore: rs,
+		tenancy:   tenancy,
 	}, nil
 }
 
+// UpdateConfig switches node selection, node limits and defaults of new rooms to conf
+func (r *StandardRoomAllocator) UpdateConfig(conf *config.Config) error {
+	ns, err := selector.CreateNodeSelector(conf)
+	if err != nil {
+		return err
+	}
+
+	r.lock.Lock()
+	r.config = conf
+	r.selector = ns
+	r.lock.Unlock()
+	return nil
+}
+
+func (r *StandardRoomAllocator) getConfig() (*config.Config, selector.NodeSelector) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	return r.config, r.selector
+}
+
 // CreateRoom creates a new room from a request and allocates it to a node to handle
 // it'll also monitor its state, and cleans it up when appropriate
-func (r *StandardRoomAllocator) CreateRoom(ctx context.Context, req *livekit.CreateRoomRequest) (*livekit.Room, error) {
+func (r *StandardRoomAllocator) CreateRoom(ctx context.Context, req *livekit.CreateRoomRequest) (rm *livekit.Room, err error) {
+	conf, ns := r.getConfig()
+
 	token, err := r.roomStore.LockRoom(ctx, livekit.RoomName(req.Name), 5*time.Second)
 	if err != nil {
 		return nil, err
@@ -45,16 +73,30 @@ func (r *StandardRoomAllocator) CreateRoom(ctx context.Context, req *livekit.Cre
 		_ = r.roomStore.UnlockRoom(ctx, livekit.RoomName(req.Name), token)
 	}()
 
+	// rooms of other tenants are off limits, and new ones count against quota of the tenant
+	claimed, err := r.tenancy.ClaimRoom(ctx, livekit.RoomName(req.Name))
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil && claimed {
+			if releaseErr := r.tenancy.ReleaseRoom(ctx, livekit.RoomName(req.Name)); releaseErr != nil {
+				logger.Errorw("could not release claim of room", releaseErr, "room", req.Name)
+			}
+		}
+	}()
+
 	// find existing room and update it
 	rm, internal, err := r.roomStore.LoadRoom(ctx, livekit.RoomName(req.Name), true)
-	if err == ErrRoomNotFound {
+	created := err == ErrRoomNotFound
+	if created {
 		rm = &livekit.Room{
 			Sid:          utils.NewGuid(utils.RoomPrefix),
 			Name:         req.Name,
 			CreationTime: time.Now().Unix(),
 			TurnPassword: utils.RandomSecret(),
 		}
-		applyDefaultRoomConfig(rm, &r.config.Room)
+		applyDefaultRoomConfig(rm, &conf.Room)
 	} else if err != nil {
 		return nil, err
 	}
@@ -75,6 +117,12 @@ func (r *StandardRoomAllocator) CreateRoom(ctx context.Context, req *livekit.Cre
 	if err = r.roomStore.StoreRoom(ctx, rm, internal); err != nil {
 		return nil, err
 	}
+	// options are those of the room when it's created, they're left as they are for existing rooms
+	if options := GetRoomOptions(ctx); options != nil && created {
+		if err = r.roomStore.StoreRoomOptions(ctx, livekit.RoomName(rm.Name), options); err != nil {
+			return nil, err
+		}
+	}
 
 	// check if room already assigned
 	existing, err := r.router.GetNodeForRoom(ctx, livekit.RoomName(rm.Name))
@@ -85,7 +133,7 @@ func (r *StandardRoomAllocator) CreateRoom(ctx context.Context, req *livekit.Cre
 	// if already assigned and still available, keep it on that node
 	if err == nil && selector.IsAvailable(existing) {
 		// if node hosting the room is full, deny entry
-		if selector.LimitsReached(r.config.Limit, existing.Stats) {
+		if selector.LimitsReached(conf.Limit, existing.Stats) {
 			return nil, routing.ErrNodeLimitReached
 		}
 
@@ -100,7 +148,7 @@ func (r *StandardRoomAllocator) CreateRoom(ctx context.Context, req *livekit.Cre
 			return nil, err
 		}
 
-		node, err := r.selector.SelectNode(nodes)
+		node, err := ns.SelectNode(nodes)
 		if err != nil {
 			return nil, err
 		}
@@ -2,10 +2,16 @@ package service_test
 
 import (
 	"context"
+	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
+	"gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
 
+	"github.com/livekit/protocol/auth"
 	"github.com/livekit/protocol/livekit"
 
 	"github.com/livekit/livekit-server/pkg/config"
@@ -62,6 +68,92 @@ func TestCreateRoom(t *testing.T) {
 		_, err = ra.CreateRoom(context.Background(), &livekit.CreateRoomRequest{Name: "low-limit-room"})
 		require.ErrorIs(t, err, routing.ErrNodeLimitReached)
 	})
+
+	t.Run("store options of new rooms only", func(t *testing.T) {
+		conf, err := config.NewConfig("", true, nil, nil)
+		require.NoError(t, err)
+
+		node, err := routing.NewLocalNode(conf)
+		require.NoError(t, err)
+		router := &routingfakes.FakeRouter{}
+		router.GetNodeForRoomReturns(node, nil)
+
+		store := service.NewLocalStore()
+		ra, err := service.NewRoomAllocator(conf, router, store, nil)
+		require.NoError(t, err)
+
+		_, err = ra.CreateRoom(roomOptionsContext(t, 500000), &livekit.CreateRoomRequest{Name: "myroom"})
+		require.NoError(t, err)
+		// creating the room again doesn't change its options
+		_, err = ra.CreateRoom(roomOptionsContext(t, 100000), &livekit.CreateRoomRequest{Name: "myroom"})
+		require.NoError(t, err)
+
+		options, err := store.LoadRoomOptions(context.Background(), "myroom")
+		require.NoError(t, err)
+		require.NotNil(t, options)
+		require.Equal(t, int64(500000), options.MaxSubscribeBitrate)
+	})
+
+	t.Run("release claims of rooms that could not be created", func(t *testing.T) {
+		conf, err := config.NewConfig("", true, nil, nil)
+		require.NoError(t, err)
+		conf.Limit.NumTracks = 10
+		conf.Tenancy = config.TenancyConfig{Enabled: true}
+
+		node, err := routing.NewLocalNode(conf)
+		require.NoError(t, err)
+		node.Stats.NumTracksIn = 100
+		node.Stats.NumTracksOut = 100
+		router := &routingfakes.FakeRouter{}
+		router.GetNodeForRoomReturns(node, nil)
+
+		store := service.NewLocalStore()
+		ra, err := service.NewRoomAllocator(conf, router, store, service.NewTenancy(conf, store))
+		require.NoError(t, err)
+
+		ctx := tenantContext(t, "key-a", "")
+		_, err = ra.CreateRoom(ctx, &livekit.CreateRoomRequest{Name: "low-limit-room"})
+		require.ErrorIs(t, err, routing.ErrNodeLimitReached)
+		rooms, err := store.ListTenantRooms(ctx, "key-a")
+		require.NoError(t, err)
+		require.Empty(t, rooms)
+		tenant, err := store.LoadTenant(ctx, service.RoomTenantResource("low-limit-room"))
+		require.NoError(t, err)
+		require.Empty(t, tenant)
+	})
+}
+
+// roomOptionsContext returns the context of a request authenticated with a token creating rooms with a limit of
+// downstream bitrate of their participants
+func roomOptionsContext(t *testing.T, maxSubscribeBitrate int64) context.Context {
+	apiKey, secret := "key", "secret"
+	sig, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.HS256, Key: []byte(secret)},
+		(&jose.SignerOptions{}).WithType("JWT"))
+	require.NoError(t, err)
+	token, err := jwt.Signed(sig).
+		Claims(jwt.Claims{
+			Issuer:    apiKey,
+			NotBefore: jwt.NewNumericDate(time.Now()),
+			Expiry:    jwt.NewNumericDate(time.Now().Add(time.Minute)),
+		}).
+		Claims(map[string]interface{}{
+			"video": map[string]interface{}{
+				"roomCreate":  true,
+				"roomOptions": map[string]interface{}{"maxSubscribeBitrate": maxSubscribeBitrate},
+			},
+		}).
+		CompactSerialize()
+	require.NoError(t, err)
+
+	var ctx context.Context
+	r := &http.Request{Header: http.Header{}}
+	service.SetAuthorizationToken(r, token)
+	service.NewAPIKeyAuthMiddleware(auth.NewFileBasedKeyProviderFromMap(map[string]string{apiKey: secret})).
+		ServeHTTP(httptest.NewRecorder(), r, func(w http.ResponseWriter, r *http.Request) {
+			ctx = r.Context()
+		})
+	require.NotNil(t, ctx)
+	return ctx
 }
 
 func newTestRoomAllocator(t *testing.T, conf *config.Config, node *livekit.Node) (service.RoomAllocator, *config.Config) {
@@ -71,7 +163,7 @@ func newTestRoomAllocator(t *testing.T, conf *config.Config, node *livekit.Node)
 
 	router.GetNodeForRoomReturns(node, nil)
 
-	ra, err := service.NewRoomAllocator(conf, router, store)
+	ra, err := service.NewRoomAllocator(conf, router, store, nil)
 	require.NoError(t, err)
 	return ra, conf
 }
@@ -21,6 +21,7 @@ import (
 	"github.com/livekit/livekit-server/pkg/rtc"
 	"github.com/livekit/livekit-server/pkg/rtc/types"
 	"github.com/livekit/livekit-server/pkg/telemetry"
+	"github.com/livekit/livekit-server/pkg/telemetry/metering"
 )
 
 const (
@@ -49,8 +50,14 @@ type RoomManager struct {
 	telemetry         telemetry.TelemetryService
 	clientConfManager clientconfiguration.ClientConfigurationManager
 	egressLauncher    rtc.EgressLauncher
+	keyProvider       *KeyProvider
+	tenancy           *Tenancy
+	relay             *RelayService
+	waitingRoom       *WaitingRoomService
 
 	rooms map[livekit.RoomName]*rtc.Room
+	// participants of gated rooms waiting to be admitted
+	waiting map[livekit.RoomName]map[livekit.ParticipantIdentity]*waitingParticipant
 
 	iceConfigCache map[livekit.ParticipantIdentity]*iceConfigCacheEntry
 }
@@ -63,6 +70,8 @@ func NewLocalRoomManager(
 	telemetry telemetry.TelemetryService,
 	clientConfManager clientconfiguration.ClientConfigurationManager,
 	egressLauncher rtc.EgressLauncher,
+	keyProvider *KeyProvider,
+	tenancy *Tenancy,
 ) (*RoomManager, error) {
 
 	rtcConf, err := rtc.NewWebRTCConfig(conf, currentNode.Ip)
@@ -79,8 +88,11 @@ func NewLocalRoomManager(
 		telemetry:         telemetry,
 		clientConfManager: clientConfManager,
 		egressLauncher:    egressLauncher,
+		keyProvider:       keyProvider,
+		tenancy:           tenancy,
 
-		rooms: make(map[livekit.RoomName]*rtc.Room),
+		rooms:   make(map[livekit.RoomName]*rtc.Room),
+		waiting: make(map[livekit.RoomName]map[livekit.ParticipantIdentity]*waitingParticipant),
 
 		iceConfigCache: make(map[livekit.ParticipantIdentity]*iceConfigCacheEntry),
 
@@ -99,6 +111,32 @@ func NewLocalRoomManager(
 	return r, nil
 }
 
+// setRelay lets rooms of this node span nodes, participants of other nodes are then relayed
+func (r *RoomManager) setRelay(relay *RelayService) {
+	r.lock.Lock()
+	r.relay = relay
+	r.lock.Unlock()
+}
+
+func (r *RoomManager) getRelay() *RelayService {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return r.relay
+}
+
+// setWaitingRoom lets participants waiting on this node be decided on from any node
+func (r *RoomManager) setWaitingRoom(waitingRoom *WaitingRoomService) {
+	r.lock.Lock()
+	r.waitingRoom = waitingRoom
+	r.lock.Unlock()
+}
+
+func (r *RoomManager) getWaitingRoom() *WaitingRoomService {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return r.waitingRoom
+}
+
 func (r *RoomManager) GetRoom(_ context.Context, roomName livekit.RoomName) *rtc.Room {
 	r.lock.RLock()
 	defer r.lock.RUnlock()
@@ -189,6 +227,7 @@ func (r *RoomManager) Stop() {
 	r.lock.RUnlock()
 
 	for _, room := range rooms {
+		r.rejectAllWaiting(room.Name(), livekit.DisconnectReason_SERVER_SHUTDOWN)
 		for _, p := range room.GetParticipants() {
 			_ = p.Close(true, types.ParticipantCloseReasonRoomManagerStop)
 		}
@@ -237,13 +276,15 @@ func (r *RoomManager) StartSession(
 				logger.Warnw("could not resume participant", err, "participant", pi.Identity)
 				return err
 			}
-			go r.rtcSessionWorker(room, participant, requestSource)
+			go r.rtcSessionWorker(room, participant, requestSource, &pi)
 			return nil
 		} else {
 			participant.GetLogger().Infow("removing duplicate participant")
 			// we need to clean up the existing participant, so a new one can join
 			room.RemoveParticipant(participant.Identity(), types.ParticipantCloseReasonDuplicateIdentity)
 		}
+	} else if !pi.Reconnect && r.rejectWaiting(roomName, pi.Identity, livekit.DisconnectReason_DUPLICATE_IDENTITY) {
+		logger.Infow("removing duplicate waiting participant", "room", roomName, "participant", pi.Identity)
 	} else if pi.Reconnect {
 		// send leave request if participant is trying to reconnect without keep subscribe state
 		// but missing from the room
@@ -267,12 +308,34 @@ func (r *RoomManager) StartSession(
 		"protocol", pi.Client.Protocol,
 	)
 
+	sid := livekit.ParticipantID(utils.NewGuid(utils.ParticipantPrefix))
+	if pi.Relayed && pi.ID != "" {
+		// same as on the node hosting it, so that participants are referred to alike on any node
+		sid = pi.ID
+	}
+
+	// participants of gated rooms are held until they are admitted
+	if r.isGated(roomName, &pi) {
+		return r.startWaiting(ctx, room, pi, sid, requestSource, responseSink)
+	}
+	return r.startParticipant(ctx, room, pi, sid, requestSource, responseSink)
+}
+
+// startParticipant joins a participant to the room and runs its session
+func (r *RoomManager) startParticipant(
+	ctx context.Context,
+	room *rtc.Room,
+	pi routing.ParticipantInit,
+	sid livekit.ParticipantID,
+	requestSource routing.MessageSource,
+	responseSink routing.MessageSink,
+) error {
+	roomName := room.Name()
 	clientConf := r.clientConfManager.GetConfiguration(pi.Client)
 
 	pv := types.ProtocolVersion(pi.Client.Protocol)
 	rtcConf := *r.rtcConfig
 	rtcConf.SetBufferFactory(room.GetBufferFactory())
-	sid := livekit.ParticipantID(utils.NewGuid(utils.ParticipantPrefix))
 	pLogger := rtc.LoggerWithParticipant(room.Logger, pi.Identity, sid, false)
 	protoRoom := room.ToProto()
 	// default allow forceTCP
@@ -280,7 +343,7 @@ func (r *RoomManager) StartSession(
 	if r.config.RTC.AllowTCPFallback != nil {
 		allowFallback = *r.config.RTC.AllowTCPFallback
 	}
-	participant, err = rtc.NewParticipant(rtc.ParticipantParams{
+	participant, err := rtc.NewParticipant(rtc.ParticipantParams{
 		Identity:                pi.Identity,
 		Name:                    pi.Name,
 		SID:                     sid,
@@ -301,6 +364,13 @@ func (r *RoomManager) StartSession(
 		AdaptiveStream:          pi.AdaptiveStream,
 		AllowTCPFallback:        allowFallback,
 		TURNSEnabled:            r.config.IsTURNSEnabled(),
+		MaxSubscribeBitrate:     getMaxSubscribeBitrate(r.config.Room.MaxSubscribeBitrate, room.Options().MaxSubscribeBitrate, pi.MaxSubscribeBitrate),
+		DataTopics:              pi.DataTopics,
+		VideoSlots:              pi.VideoSlots,
+		ClientOffersSubscriber:  pi.ClientOffersSubscriber,
+		HTTPSignaled:            pi.HTTPSignaled,
+		ExternalMedia:           pi.ExternalMedia,
+		Relayed:                 pi.Relayed,
 		GetParticipantInfo: func(pID livekit.ParticipantID) *livekit.ParticipantInfo {
 			if p := room.GetParticipantBySid(pID); p != nil {
 				return p.ToProto()
@@ -316,18 +386,23 @@ func (r *RoomManager) StartSession(
 	// join room
 	opts := rtc.ParticipantOptions{
 		AutoSubscribe: pi.AutoSubscribe,
+		Relayed:       pi.Relayed,
 	}
 	if err = room.Join(participant, &opts, r.iceServersForRoom(protoRoom, iceConfig.PreferSub == types.PreferTls)); err != nil {
 		pLogger.Errorw("could not join room", err)
 		_ = participant.Close(true, types.ParticipantCloseReasonJoinFailed)
 		return err
 	}
-	if err = r.roomStore.StoreParticipant(ctx, roomName, participant.ToProto()); err != nil {
-		pLogger.Errorw("could not store participant", err)
+	r.announceWaitingTo(ctx, room, participant)
+	// the node hosting a relayed participant stores it and reports on it
+	if !pi.Relayed {
+		if err = r.roomStore.StoreParticipant(ctx, roomName, participant.ToProto()); err != nil {
+			pLogger.Errorw("could not store participant", err)
+		}
 	}
 
 	updateParticipantCount := func(proto *livekit.Room) {
-		if !participant.Hidden() {
+		if !participant.Hidden() && !pi.Relayed {
 			err = r.roomStore.StoreRoom(ctx, proto, room.Internal())
 			if err != nil {
 				logger.Errorw("could not store room", err)
@@ -339,16 +414,24 @@ func (r *RoomManager) StartSession(
 	updateParticipantCount(room.ToProto())
 
 	clientMeta := &livekit.AnalyticsClientMeta{Region: r.currentNode.Region, Node: r.currentNode.Id}
-	r.telemetry.ParticipantJoined(ctx, protoRoom, participant.ToProto(), pi.Client, clientMeta)
+	if !pi.Relayed {
+		r.telemetry.ParticipantJoined(metering.WithAPIKey(ctx, pi.APIKey), protoRoom, participant.ToProto(), pi.Client, clientMeta)
+	}
 	participant.OnClose(func(p types.LocalParticipant, disallowedSubscriptions map[livekit.TrackID]livekit.ParticipantID) {
-		if err := r.roomStore.DeleteParticipant(ctx, roomName, p.Identity()); err != nil {
-			pLogger.Errorw("could not delete participant", err)
-		}
+		if !pi.Relayed {
+			if err := r.roomStore.DeleteParticipant(ctx, roomName, p.Identity()); err != nil {
+				pLogger.Errorw("could not delete participant", err)
+			}
+			if quota := room.TenantQuota(); quota != nil {
+				quota.ParticipantLeft(p.ToProto())
+			}
 
-		// update room store with new numParticipants
-		proto := room.ToProto()
-		updateParticipantCount(proto)
-		r.telemetry.ParticipantLeft(ctx, proto, p.ToProto())
+			// update room store with new numParticipants
+			proto := room.ToProto()
+			updateParticipantCount(proto)
+			r.telemetry.ParticipantLeft(ctx, proto, p.ToProto())
+			r.getRelay().participantClosed(room, p)
+		}
 
 		room.RemoveDisallowedSubscriptions(p, disallowedSubscriptions)
 	})
@@ -367,7 +450,7 @@ func (r *RoomManager) StartSession(
 		r.lock.Unlock()
 	})
 
-	go r.rtcSessionWorker(room, participant, requestSource)
+	go r.rtcSessionWorker(room, participant, requestSource, &pi)
 	return nil
 }
 
@@ -386,6 +469,10 @@ func (r *RoomManager) getOrCreateRoom(ctx context.Context, roomName livekit.Room
 	if err != nil {
 		return nil, err
 	}
+	options, err := r.roomStore.LoadRoomOptions(ctx, roomName)
+	if err != nil {
+		return nil, err
+	}
 
 	r.lock.Lock()
 
@@ -402,9 +489,27 @@ func (r *RoomManager) getOrCreateRoom(ctx context.Context, roomName livekit.Room
 	}
 
 	// construct ice servers
-	newRoom := rtc.NewRoom(ri, internal, *r.rtcConfig, &r.config.Audio, r.serverInfo, r.telemetry, r.egressLauncher)
+	newRoom := rtc.NewRoom(ri, internal, options, *r.rtcConfig, &r.config.Audio, &r.config.Room, r.serverInfo, r.telemetry, r.egressLauncher)
+	newRoom.SetTenantQuota(r.tenancy.RoomQuota(ctx, roomName, livekit.NodeID(r.currentNode.Id)))
+	newRoom.SetSignalRateLimit(r.config.RateLimit.Signal)
+
+	relay := r.relay
+	relay.roomCreated(newRoom)
 
 	newRoom.OnClose(func() {
+		// rooms closed while participants wait, as when reaching their max duration, turn them away
+		r.rejectAllWaiting(roomName, livekit.DisconnectReason_ROOM_DELETED)
+		if relay.roomClosed(ctx, newRoom) {
+			// other nodes still host participants of the room, and keep its state
+			r.lock.Lock()
+			if r.rooms[roomName] == newRoom {
+				delete(r.rooms, roomName)
+			}
+			r.lock.Unlock()
+			newRoom.Logger.Infow("room closed on this node")
+			return
+		}
+
 		roomInfo := newRoom.ToProto()
 		r.telemetry.RoomEnded(ctx, roomInfo)
 		prometheus.RoomEnded(time.Unix(roomInfo.CreationTime, 0))
@@ -422,11 +527,19 @@ func (r *RoomManager) getOrCreateRoom(ctx context.Context, roomName livekit.Room
 	})
 
 	newRoom.OnParticipantChanged(func(p types.LocalParticipant) {
+		if relay.isRelayed(roomName, p) {
+			return
+		}
 		if p.State() != livekit.ParticipantInfo_DISCONNECTED {
-			if err := r.roomStore.StoreParticipant(ctx, roomName, p.ToProto()); err != nil {
+			info := p.ToProto()
+			if err := r.roomStore.StoreParticipant(ctx, roomName, info); err != nil {
 				newRoom.Logger.Errorw("could not handle participant change", err)
 			}
+			if quota := newRoom.TenantQuota(); quota != nil {
+				quota.ParticipantChanged(info)
+			}
 		}
+		relay.participantChanged(newRoom, p)
 	})
 
 	r.rooms[roomName] = newRoom
@@ -435,14 +548,22 @@ func (r *RoomManager) getOrCreateRoom(ctx context.Context, roomName livekit.Room
 
 	newRoom.Hold()
 
-	r.telemetry.RoomStarted(ctx, newRoom.ToProto())
+	// started and ended on the node the room is assigned to
+	if !relay.isRemoteRoom(ctx, roomName) {
+		r.telemetry.RoomStarted(ctx, newRoom.ToProto())
+	}
 	prometheus.RoomStarted()
 
 	return newRoom, nil
 }
 
 // manages an RTC session for a participant, runs on the RTC node
-func (r *RoomManager) rtcSessionWorker(room *rtc.Room, participant types.LocalParticipant, requestSource routing.MessageSource) {
+func (r *RoomManager) rtcSessionWorker(
+	room *rtc.Room,
+	participant types.LocalParticipant,
+	requestSource routing.MessageSource,
+	pi *routing.ParticipantInit,
+) {
 	defer func() {
 		logger.Infow("RTC session finishing",
 			"participant", participant.Identity(),
@@ -467,6 +588,9 @@ func (r *RoomManager) rtcSessionWorker(room *rtc.Room, participant types.LocalPa
 	defer tokenTicker.Stop()
 	stateCheckTicker := time.NewTicker(time.Millisecond * 50)
 	defer stateCheckTicker.Stop()
+	durationTicker := time.NewTicker(time.Second)
+	defer durationTicker.Stop()
+	durationWarned := false
 	for {
 		select {
 		case <-stateCheckTicker.C:
@@ -474,6 +598,28 @@ func (r *RoomManager) rtcSessionWorker(room *rtc.Room, participant types.LocalPa
 			if participant.State() == livekit.ParticipantInfo_DISCONNECTED {
 				return
 			}
+		case <-durationTicker.C:
+			limit := getMaxSessionDuration(room, pi)
+			if limit <= 0 {
+				continue
+			}
+			remaining := time.Until(participant.ConnectedAt().Add(limit))
+			if remaining > 0 {
+				if warning := room.DurationWarning(); !durationWarned && warning > 0 && remaining <= warning {
+					durationWarned = true
+					room.SendDurationWarning(participant, rtc.DurationWarningScopeParticipant, remaining)
+				}
+				continue
+			}
+
+			pLogger.Infow("participant reached its max session duration", "maxDuration", limit)
+			r.telemetry.NotifyEvent(context.Background(), &livekit.WebhookEvent{
+				Event:       telemetry.EventParticipantDurationExceeded,
+				Room:        room.ToProto(),
+				Participant: participant.ToProto(),
+			})
+			room.RemoveParticipant(participant.Identity(), types.ParticipantCloseReasonSessionDurationExceeded)
+			return
 		case <-tokenTicker.C:
 			// refresh token with the first API Key/secret pair
 			if err := r.refreshToken(participant); err != nil {
@@ -498,6 +644,12 @@ func (r *RoomManager) rtcSessionWorker(room *rtc.Room, participant types.LocalPa
 
 // handles RTC messages resulted from Room API calls
 func (r *RoomManager) handleRTCMessage(ctx context.Context, roomName livekit.RoomName, identity livekit.ParticipantIdentity, msg *livekit.RTCNodeMessage) {
+	// room requests reach the node the room is assigned to, other nodes hosting it apply them as well
+	r.getRelay().forwardRTCMessage(ctx, roomName, msg)
+	r.applyRTCMessage(ctx, roomName, identity, msg)
+}
+
+func (r *RoomManager) applyRTCMessage(ctx context.Context, roomName livekit.RoomName, identity livekit.ParticipantIdentity, msg *livekit.RTCNodeMessage) {
 	r.lock.RLock()
 	room := r.rooms[roomName]
 	r.lock.RUnlock()
@@ -564,6 +716,7 @@ func (r *RoomManager) handleRTCMessage(ctx context.Context, roomName livekit.Roo
 		}
 	case *livekit.RTCNodeMessage_DeleteRoom:
 		room.Logger.Infow("deleting room")
+		r.rejectAllWaiting(roomName, livekit.DisconnectReason_ROOM_DELETED)
 		for _, p := range room.GetParticipants() {
 			_ = p.Close(true, types.ParticipantCloseReasonServiceRequestDeleteRoom)
 		}
@@ -658,24 +811,23 @@ func (r *RoomManager) iceServersForRoom(ri *livekit.Room, tlsOnly bool) []*livek
 }
 
 func (r *RoomManager) refreshToken(participant types.LocalParticipant) error {
-	for key, secret := range r.config.Keys {
-		grants := participant.ClaimGrants()
-		token := auth.NewAccessToken(key, secret)
-		token.SetName(grants.Name).
-			SetIdentity(string(participant.Identity())).
-			SetValidFor(tokenDefaultTTL).
-			SetMetadata(grants.Metadata).
-			AddGrant(grants.Video)
-		jwt, err := token.ToJWT()
-		if err == nil {
-			err = participant.SendRefreshToken(jwt)
-		}
-		if err != nil {
-			return err
-		}
-		break
+	key, secret := r.keyProvider.GetActiveKey()
+	if key == "" {
+		return nil
 	}
-	return nil
+
+	grants := participant.ClaimGrants()
+	token := auth.NewAccessToken(key, secret)
+	token.SetName(grants.Name).
+		SetIdentity(string(participant.Identity())).
+		SetValidFor(tokenDefaultTTL).
+		SetMetadata(grants.Metadata).
+		AddGrant(grants.Video)
+	jwt, err := token.ToJWT()
+	if err != nil {
+		return err
+	}
+	return participant.SendRefreshToken(jwt)
 }
 
 func (r *RoomManager) setIceConfig(participant types.LocalParticipant) types.IceConfig {
@@ -701,3 +853,27 @@ func iceServerForStunServers(servers []string) *livekit.ICEServer {
 	}
 	return iceServer
 }
+
+// getMaxSessionDuration returns the lowest of limits of the session that are set, 0 if unlimited. Relayed
+// participants are limited by the node hosting them, and recorders only leave with the room
+func getMaxSessionDuration(room *rtc.Room, pi *routing.ParticipantInit) time.Duration {
+	if pi.Relayed || (pi.Grants.Video != nil && pi.Grants.Video.Recorder) {
+		return 0
+	}
+	maxSessionDuration := pi.MaxSessionDuration
+	if limit := room.MaxParticipantDuration(); limit > 0 && (maxSessionDuration <= 0 || limit < maxSessionDuration) {
+		maxSessionDuration = limit
+	}
+	return maxSessionDuration
+}
+
+// getMaxSubscribeBitrate returns the lowest of limits that are set, 0 if unlimited
+func getMaxSubscribeBitrate(limits ...int64) int64 {
+	maxSubscribeBitrate := int64(0)
+	for _, limit := range limits {
+		if limit > 0 && (maxSubscribeBitrate == 0 || limit < maxSubscribeBitrate) {
+			maxSubscribeBitrate = limit
+		}
+	}
+	return maxSubscribeBitrate
+}
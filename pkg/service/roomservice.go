@@ -10,6 +10,7 @@ import (
 	"github.com/twitchtv/twirp"
 	"google.golang.org/protobuf/proto"
 
+	"github.com/livekit/livekit-server/pkg/audit"
 	"github.com/livekit/livekit-server/pkg/config"
 	"github.com/livekit/livekit-server/pkg/routing"
 	"github.com/livekit/livekit-server/pkg/rtc"
@@ -28,6 +29,8 @@ type RoomService struct {
 	roomAllocator  RoomAllocator
 	roomStore      ServiceStore
 	egressLauncher rtc.EgressLauncher
+	tenancy        *Tenancy
+	auditLogger    *audit.Logger
 }
 
 func NewRoomService(
@@ -36,6 +39,8 @@ func NewRoomService(
 	roomAllocator RoomAllocator,
 	serviceStore ServiceStore,
 	egressLauncher rtc.EgressLauncher,
+	tenancy *Tenancy,
+	auditLogger *audit.Logger,
 ) (svc *RoomService, err error) {
 
 	svc = &RoomService{
@@ -44,19 +49,28 @@ func NewRoomService(
 		roomAllocator:  roomAllocator,
 		roomStore:      serviceStore,
 		egressLauncher: egressLauncher,
+		tenancy:        tenancy,
+		auditLogger:    auditLogger,
 	}
 	return
 }
 
-func (s *RoomService) CreateRoom(ctx context.Context, req *livekit.CreateRoomRequest) (*livekit.Room, error) {
+func (s *RoomService) CreateRoom(ctx context.Context, req *livekit.CreateRoomRequest) (rm *livekit.Room, err error) {
+	defer func() {
+		auditOperation(ctx, s.auditLogger, auditServiceRoom, "CreateRoom", req, rm, err)
+	}()
+
 	if err := EnsureCreatePermission(ctx); err != nil {
 		return nil, twirpAuthError(err)
 	} else if req.Egress != nil && s.egressLauncher == nil {
 		return nil, ErrEgressNotConnected
 	}
 
-	rm, err := s.roomAllocator.CreateRoom(ctx, req)
+	rm, err = s.roomAllocator.CreateRoom(ctx, req)
 	if err != nil {
+		if tErr := twirpTenancyError(err); tErr != err {
+			return nil, tErr
+		}
 		err = errors.Wrap(err, "could not create room")
 		return nil, err
 	}
@@ -113,6 +127,9 @@ func (s *RoomService) ListRooms(ctx context.Context, req *livekit.ListRoomsReque
 		// TODO: translate error codes to Twirp
 		return nil, err
 	}
+	if rooms, err = s.tenancy.FilterRooms(ctx, rooms); err != nil {
+		return nil, err
+	}
 
 	res := &livekit.ListRoomsResponse{
 		Rooms: rooms,
@@ -120,11 +137,18 @@ func (s *RoomService) ListRooms(ctx context.Context, req *livekit.ListRoomsReque
 	return res, nil
 }
 
-func (s *RoomService) DeleteRoom(ctx context.Context, req *livekit.DeleteRoomRequest) (*livekit.DeleteRoomResponse, error) {
+func (s *RoomService) DeleteRoom(ctx context.Context, req *livekit.DeleteRoomRequest) (res *livekit.DeleteRoomResponse, err error) {
+	defer func() {
+		auditOperation(ctx, s.auditLogger, auditServiceRoom, "DeleteRoom", req, res, err)
+	}()
+
 	if err := EnsureCreatePermission(ctx); err != nil {
 		return nil, twirpAuthError(err)
 	}
-	err := s.router.WriteRoomRTC(ctx, livekit.RoomName(req.Room), &livekit.RTCNodeMessage{
+	if err := s.tenancy.EnsureRoom(ctx, livekit.RoomName(req.Room)); err != nil {
+		return nil, twirpTenancyError(err)
+	}
+	err = s.router.WriteRoomRTC(ctx, livekit.RoomName(req.Room), &livekit.RTCNodeMessage{
 		Message: &livekit.RTCNodeMessage_DeleteRoom{
 			DeleteRoom: req,
 		},
@@ -152,8 +176,8 @@ func (s *RoomService) DeleteRoom(ctx context.Context, req *livekit.DeleteRoomReq
 }
 
 func (s *RoomService) ListParticipants(ctx context.Context, req *livekit.ListParticipantsRequest) (*livekit.ListParticipantsResponse, error) {
-	if err := EnsureAdminPermission(ctx, livekit.RoomName(req.Room)); err != nil {
-		return nil, twirpAuthError(err)
+	if err := s.ensureAdminPermission(ctx, livekit.RoomName(req.Room)); err != nil {
+		return nil, err
 	}
 
 	participants, err := s.roomStore.ListParticipants(ctx, livekit.RoomName(req.Room))
@@ -168,8 +192,8 @@ func (s *RoomService) ListParticipants(ctx context.Context, req *livekit.ListPar
 }
 
 func (s *RoomService) GetParticipant(ctx context.Context, req *livekit.RoomParticipantIdentity) (*livekit.ParticipantInfo, error) {
-	if err := EnsureAdminPermission(ctx, livekit.RoomName(req.Room)); err != nil {
-		return nil, twirpAuthError(err)
+	if err := s.ensureAdminPermission(ctx, livekit.RoomName(req.Room)); err != nil {
+		return nil, err
 	}
 
 	participant, err := s.roomStore.LoadParticipant(ctx, livekit.RoomName(req.Room), livekit.ParticipantIdentity(req.Identity))
@@ -180,8 +204,12 @@ func (s *RoomService) GetParticipant(ctx context.Context, req *livekit.RoomParti
 	return participant, nil
 }
 
-func (s *RoomService) RemoveParticipant(ctx context.Context, req *livekit.RoomParticipantIdentity) (*livekit.RemoveParticipantResponse, error) {
-	err := s.writeParticipantMessage(ctx, livekit.RoomName(req.Room), livekit.ParticipantIdentity(req.Identity), &livekit.RTCNodeMessage{
+func (s *RoomService) RemoveParticipant(ctx context.Context, req *livekit.RoomParticipantIdentity) (res *livekit.RemoveParticipantResponse, err error) {
+	defer func() {
+		auditOperation(ctx, s.auditLogger, auditServiceRoom, "RemoveParticipant", req, res, err)
+	}()
+
+	err = s.writeParticipantMessage(ctx, livekit.RoomName(req.Room), livekit.ParticipantIdentity(req.Identity), &livekit.RTCNodeMessage{
 		Message: &livekit.RTCNodeMessage_RemoveParticipant{
 			RemoveParticipant: req,
 		},
@@ -207,12 +235,16 @@ func (s *RoomService) RemoveParticipant(ctx context.Context, req *livekit.RoomPa
 	return &livekit.RemoveParticipantResponse{}, nil
 }
 
-func (s *RoomService) MutePublishedTrack(ctx context.Context, req *livekit.MuteRoomTrackRequest) (*livekit.MuteRoomTrackResponse, error) {
-	if err := EnsureAdminPermission(ctx, livekit.RoomName(req.Room)); err != nil {
-		return nil, twirpAuthError(err)
+func (s *RoomService) MutePublishedTrack(ctx context.Context, req *livekit.MuteRoomTrackRequest) (res *livekit.MuteRoomTrackResponse, err error) {
+	defer func() {
+		auditOperation(ctx, s.auditLogger, auditServiceRoom, "MutePublishedTrack", req, res, err)
+	}()
+
+	if err := s.ensureAdminPermission(ctx, livekit.RoomName(req.Room)); err != nil {
+		return nil, err
 	}
 
-	err := s.writeParticipantMessage(ctx, livekit.RoomName(req.Room), livekit.ParticipantIdentity(req.Identity), &livekit.RTCNodeMessage{
+	err = s.writeParticipantMessage(ctx, livekit.RoomName(req.Room), livekit.ParticipantIdentity(req.Identity), &livekit.RTCNodeMessage{
 		Message: &livekit.RTCNodeMessage_MuteTrack{
 			MuteTrack: req,
 		},
@@ -245,18 +277,22 @@ func (s *RoomService) MutePublishedTrack(ctx context.Context, req *livekit.MuteR
 		return nil, err
 	}
 
-	res := &livekit.MuteRoomTrackResponse{
+	res = &livekit.MuteRoomTrackResponse{
 		Track: track,
 	}
 	return res, nil
 }
 
-func (s *RoomService) UpdateParticipant(ctx context.Context, req *livekit.UpdateParticipantRequest) (*livekit.ParticipantInfo, error) {
+func (s *RoomService) UpdateParticipant(ctx context.Context, req *livekit.UpdateParticipantRequest) (participant *livekit.ParticipantInfo, err error) {
+	defer func() {
+		auditOperation(ctx, s.auditLogger, auditServiceRoom, "UpdateParticipant", req, participant, err)
+	}()
+
 	if s.conf.MaxMetadataSize > 0 && len(req.Metadata) > int(s.conf.MaxMetadataSize) {
 		return nil, twirp.InvalidArgumentError(ErrMetadataExceedsLimits.Error(), strconv.Itoa(int(s.conf.MaxMetadataSize)))
 	}
 
-	err := s.writeParticipantMessage(ctx, livekit.RoomName(req.Room), livekit.ParticipantIdentity(req.Identity), &livekit.RTCNodeMessage{
+	err = s.writeParticipantMessage(ctx, livekit.RoomName(req.Room), livekit.ParticipantIdentity(req.Identity), &livekit.RTCNodeMessage{
 		Message: &livekit.RTCNodeMessage_UpdateParticipant{
 			UpdateParticipant: req,
 		},
@@ -265,7 +301,6 @@ func (s *RoomService) UpdateParticipant(ctx context.Context, req *livekit.Update
 		return nil, err
 	}
 
-	var participant *livekit.ParticipantInfo
 	err = confirmExecution(func() error {
 		participant, err = s.roomStore.LoadParticipant(ctx, livekit.RoomName(req.Room), livekit.ParticipantIdentity(req.Identity))
 		if err != nil {
@@ -286,8 +321,12 @@ func (s *RoomService) UpdateParticipant(ctx context.Context, req *livekit.Update
 	return participant, nil
 }
 
-func (s *RoomService) UpdateSubscriptions(ctx context.Context, req *livekit.UpdateSubscriptionsRequest) (*livekit.UpdateSubscriptionsResponse, error) {
-	err := s.writeParticipantMessage(ctx, livekit.RoomName(req.Room), livekit.ParticipantIdentity(req.Identity), &livekit.RTCNodeMessage{
+func (s *RoomService) UpdateSubscriptions(ctx context.Context, req *livekit.UpdateSubscriptionsRequest) (res *livekit.UpdateSubscriptionsResponse, err error) {
+	defer func() {
+		auditOperation(ctx, s.auditLogger, auditServiceRoom, "UpdateSubscriptions", req, res, err)
+	}()
+
+	err = s.writeParticipantMessage(ctx, livekit.RoomName(req.Room), livekit.ParticipantIdentity(req.Identity), &livekit.RTCNodeMessage{
 		Message: &livekit.RTCNodeMessage_UpdateSubscriptions{
 			UpdateSubscriptions: req,
 		},
@@ -299,13 +338,17 @@ func (s *RoomService) UpdateSubscriptions(ctx context.Context, req *livekit.Upda
 	return &livekit.UpdateSubscriptionsResponse{}, nil
 }
 
-func (s *RoomService) SendData(ctx context.Context, req *livekit.SendDataRequest) (*livekit.SendDataResponse, error) {
+func (s *RoomService) SendData(ctx context.Context, req *livekit.SendDataRequest) (res *livekit.SendDataResponse, err error) {
+	defer func() {
+		auditOperation(ctx, s.auditLogger, auditServiceRoom, "SendData", req, res, err)
+	}()
+
 	roomName := livekit.RoomName(req.Room)
-	if err := EnsureAdminPermission(ctx, roomName); err != nil {
-		return nil, twirpAuthError(err)
+	if err := s.ensureAdminPermission(ctx, roomName); err != nil {
+		return nil, err
 	}
 
-	err := s.router.WriteRoomRTC(ctx, roomName, &livekit.RTCNodeMessage{
+	err = s.router.WriteRoomRTC(ctx, roomName, &livekit.RTCNodeMessage{
 		Message: &livekit.RTCNodeMessage_SendData{
 			SendData: req,
 		},
@@ -317,16 +360,20 @@ func (s *RoomService) SendData(ctx context.Context, req *livekit.SendDataRequest
 	return &livekit.SendDataResponse{}, nil
 }
 
-func (s *RoomService) UpdateRoomMetadata(ctx context.Context, req *livekit.UpdateRoomMetadataRequest) (*livekit.Room, error) {
+func (s *RoomService) UpdateRoomMetadata(ctx context.Context, req *livekit.UpdateRoomMetadataRequest) (room *livekit.Room, err error) {
+	defer func() {
+		auditOperation(ctx, s.auditLogger, auditServiceRoom, "UpdateRoomMetadata", req, room, err)
+	}()
+
 	if s.conf.MaxMetadataSize > 0 && len(req.Metadata) > int(s.conf.MaxMetadataSize) {
 		return nil, twirp.InvalidArgumentError(ErrMetadataExceedsLimits.Error(), strconv.Itoa(int(s.conf.MaxMetadataSize)))
 	}
 
-	if err := EnsureAdminPermission(ctx, livekit.RoomName(req.Room)); err != nil {
-		return nil, twirpAuthError(err)
+	if err := s.ensureAdminPermission(ctx, livekit.RoomName(req.Room)); err != nil {
+		return nil, err
 	}
 
-	room, _, err := s.roomStore.LoadRoom(ctx, livekit.RoomName(req.Room), false)
+	room, _, err = s.roomStore.LoadRoom(ctx, livekit.RoomName(req.Room), false)
 	if err != nil {
 		return nil, err
 	}
@@ -367,10 +414,25 @@ func (s *RoomService) UpdateRoomMetadata(ctx context.Context, req *livekit.Updat
 	return room, nil
 }
 
-func (s *RoomService) writeParticipantMessage(ctx context.Context, room livekit.RoomName, identity livekit.ParticipantIdentity, msg *livekit.RTCNodeMessage) error {
+// ensureAdminPermission returns an error unless the request may administer the room, and it's one of its tenant
+func (s *RoomService) ensureAdminPermission(ctx context.Context, room livekit.RoomName) error {
+	return ensureRoomAdminPermission(ctx, s.tenancy, room)
+}
+
+func ensureRoomAdminPermission(ctx context.Context, tenancy *Tenancy, room livekit.RoomName) error {
 	if err := EnsureAdminPermission(ctx, room); err != nil {
 		return twirpAuthError(err)
 	}
+	if err := tenancy.EnsureRoom(ctx, room); err != nil {
+		return twirpTenancyError(err)
+	}
+	return nil
+}
+
+func (s *RoomService) writeParticipantMessage(ctx context.Context, room livekit.RoomName, identity livekit.ParticipantIdentity, msg *livekit.RTCNodeMessage) error {
+	if err := s.ensureAdminPermission(ctx, room); err != nil {
+		return err
+	}
 
 	return s.router.WriteParticipantRTC(ctx, room, identity, msg)
 }
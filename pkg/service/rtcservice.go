@@ -8,10 +8,11 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/go-logr/logr"
 	"github.com/gorilla/websocket"
-	"github.com/sebest/xff"
 	"github.com/ua-parser/uap-go/uaparser"
 
 	"github.com/livekit/protocol/livekit"
@@ -36,8 +37,13 @@ type RTCService struct {
 	currentNode   routing.LocalNode
 	config        *config.Config
 	isDev         bool
-	limits        config.LimitConfig
 	parser        *uaparser.Parser
+	admission     *Admission
+	rateLimiter   *RateLimiter
+	proxies       *TrustedProxies
+
+	limitsLock sync.RWMutex
+	limits     config.LimitConfig
 }
 
 func NewRTCService(
@@ -46,6 +52,9 @@ func NewRTCService(
 	store ServiceStore,
 	router routing.MessageRouter,
 	currentNode routing.LocalNode,
+	admission *Admission,
+	rateLimiter *RateLimiter,
+	proxies *TrustedProxies,
 ) *RTCService {
 	s := &RTCService{
 		router:        router,
@@ -57,6 +66,9 @@ func NewRTCService(
 		isDev:         conf.Development,
 		limits:        conf.Limit,
 		parser:        uaparser.NewFromSaved(),
+		admission:     admission,
+		rateLimiter:   rateLimiter,
+		proxies:       proxies,
 	}
 
 	// allow connections from any origin, since script may be hosted anywhere
@@ -68,6 +80,21 @@ func NewRTCService(
 	return s
 }
 
+// SetLimits updates limits checked before joining a room
+func (s *RTCService) SetLimits(limits config.LimitConfig) {
+	s.limitsLock.Lock()
+	defer s.limitsLock.Unlock()
+
+	s.limits = limits
+}
+
+func (s *RTCService) getLimits() config.LimitConfig {
+	s.limitsLock.RLock()
+	defer s.limitsLock.RUnlock()
+
+	return s.limits
+}
+
 func (s *RTCService) Validate(w http.ResponseWriter, r *http.Request) {
 	_, _, code, err := s.validate(r)
 	if err != nil {
@@ -98,6 +125,7 @@ func (s *RTCService) validate(r *http.Request) (livekit.RoomName, routing.Partic
 	autoSubParam := r.FormValue("auto_subscribe")
 	publishParam := r.FormValue("publish")
 	adaptiveStreamParam := r.FormValue("adaptive_stream")
+	videoSlotsParam := r.FormValue("video_slots")
 	participantID := r.FormValue("sid")
 
 	if onlyName != "" {
@@ -119,7 +147,7 @@ func (s *RTCService) validate(r *http.Request) (livekit.RoomName, routing.Partic
 	if router, ok := s.router.(routing.Router); ok {
 		region = router.GetRegion()
 		if foundNode, err := router.GetNodeForRoom(r.Context(), roomName); err == nil {
-			if selector.LimitsReached(s.limits, foundNode.Stats) {
+			if selector.LimitsReached(s.getLimits(), foundNode.Stats) {
 				return "", routing.ParticipantInit{}, http.StatusServiceUnavailable, rtc.ErrLimitExceeded
 			}
 		}
@@ -133,6 +161,11 @@ func (s *RTCService) validate(r *http.Request) (livekit.RoomName, routing.Partic
 		Client:        s.ParseClientInfo(r),
 		Grants:        claims,
 		Region:        region,
+
+		MaxSubscribeBitrate: GetMaxSubscribeBitrate(r.Context()),
+		MaxSessionDuration:  GetMaxSessionDuration(r.Context()),
+		DataTopics:          GetDataTopics(r.Context()),
+		APIKey:              GetAPIKey(r.Context()),
 	}
 	if pi.Reconnect {
 		pi.ID = livekit.ParticipantID(participantID)
@@ -144,6 +177,13 @@ func (s *RTCService) validate(r *http.Request) (livekit.RoomName, routing.Partic
 	if adaptiveStreamParam != "" {
 		pi.AdaptiveStream = boolValue(adaptiveStreamParam)
 	}
+	if videoSlotsParam != "" {
+		videoSlots, err := strconv.Atoi(videoSlotsParam)
+		if err != nil || videoSlots < 0 || videoSlots > rtc.MaxVideoSlots {
+			return "", routing.ParticipantInit{}, http.StatusBadRequest, ErrInvalidVideoSlots
+		}
+		pi.VideoSlots = videoSlots
+	}
 
 	return roomName, pi, http.StatusOK, nil
 }
@@ -156,6 +196,13 @@ func (s *RTCService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// before validating, so that connections over the limit don't reach admission
+	if err := s.rateLimiter.AllowSignalConnection(r); err != nil {
+		prometheus.ServiceOperationCounter.WithLabelValues("signal_ws", "error", "rate_limited").Add(1)
+		handleError(w, http.StatusTooManyRequests, err)
+		return
+	}
+
 	roomName, pi, code, err := s.validate(r)
 	if err != nil {
 		handleError(w, code, err)
@@ -167,33 +214,12 @@ func (s *RTCService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		"participant", pi.Identity,
 		"room", roomName,
 		"remote", false,
+		"apiKey", GetAPIKey(r.Context()),
 	}
 
-	// when auto create is disabled, we'll check to ensure it's already created
-	if !s.config.Room.AutoCreate {
-		_, _, err := s.store.LoadRoom(context.Background(), roomName, false)
-		if err == ErrRoomNotFound {
-			handleError(w, 404, err, loggerFields...)
-			return
-		} else if err != nil {
-			handleError(w, 500, err, loggerFields...)
-			return
-		}
-	}
-
-	// create room if it doesn't exist, also assigns an RTC node for the room
-	rm, err := s.roomAllocator.CreateRoom(r.Context(), &livekit.CreateRoomRequest{Name: string(roomName)})
+	rm, connId, reqSink, resSource, code, err := s.startParticipantSignal(r.Context(), "signal_ws", roomName, pi)
 	if err != nil {
-		prometheus.ServiceOperationCounter.WithLabelValues("signal_ws", "error", "create_room").Add(1)
-		handleError(w, http.StatusInternalServerError, err, loggerFields...)
-		return
-	}
-
-	// this needs to be started first *before* using router functions on this node
-	connId, reqSink, resSource, err := s.router.StartParticipantSignal(r.Context(), roomName, pi)
-	if err != nil {
-		prometheus.ServiceOperationCounter.WithLabelValues("signal_ws", "error", "start_signal").Add(1)
-		handleError(w, http.StatusInternalServerError, err, loggerFields...)
+		handleError(w, code, err, loggerFields...)
 		return
 	}
 
@@ -203,6 +229,7 @@ func (s *RTCService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		"",
 		false,
 	)
+	pLogger = logger.Logger(logr.Logger(pLogger).WithValues("apiKey", GetAPIKey(r.Context())))
 
 	// wait for the first message before upgrading to websocket. If no one is
 	// responding to our connection attempt, we should terminate the connection
@@ -306,6 +333,55 @@ func (s *RTCService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// startParticipantSignal admits the participant, creates the room when needed and starts the participant's signal
+// connection, failures are counted under operation
+func (s *RTCService) startParticipantSignal(
+	ctx context.Context,
+	operation string,
+	roomName livekit.RoomName,
+	pi routing.ParticipantInit,
+) (*livekit.Room, livekit.ConnectionID, routing.MessageSink, routing.MessageSource, int, error) {
+	// resuming participants were admitted when they joined
+	if !pi.Reconnect {
+		if code, err := s.admission.Admit(ctx, roomName, &pi); err != nil {
+			prometheus.ServiceOperationCounter.WithLabelValues(operation, "error", "admission").Add(1)
+			return nil, "", nil, nil, code, err
+		}
+	}
+
+	// when auto create is disabled, we'll check to ensure it's already created
+	if !s.config.Room.AutoCreate {
+		_, _, err := s.store.LoadRoom(context.Background(), roomName, false)
+		if err == ErrRoomNotFound {
+			return nil, "", nil, nil, http.StatusNotFound, err
+		} else if err != nil {
+			return nil, "", nil, nil, http.StatusInternalServerError, err
+		}
+	}
+
+	// create room if it doesn't exist, also assigns an RTC node for the room
+	rm, err := s.roomAllocator.CreateRoom(ctx, &livekit.CreateRoomRequest{Name: string(roomName)})
+	if errors.Is(err, rtc.ErrPermissionDenied) {
+		// the room belongs to another tenant
+		prometheus.ServiceOperationCounter.WithLabelValues(operation, "error", "tenant").Add(1)
+		return nil, "", nil, nil, http.StatusForbidden, err
+	} else if errors.Is(err, ErrTenantMaxRoomsExceeded) {
+		prometheus.ServiceOperationCounter.WithLabelValues(operation, "error", "tenant_quota").Add(1)
+		return nil, "", nil, nil, http.StatusTooManyRequests, err
+	} else if err != nil {
+		prometheus.ServiceOperationCounter.WithLabelValues(operation, "error", "create_room").Add(1)
+		return nil, "", nil, nil, http.StatusInternalServerError, err
+	}
+
+	// this needs to be started first *before* using router functions on this node
+	connId, reqSink, resSource, err := s.router.StartParticipantSignal(ctx, roomName, pi)
+	if err != nil {
+		prometheus.ServiceOperationCounter.WithLabelValues(operation, "error", "start_signal").Add(1)
+		return nil, "", nil, nil, http.StatusInternalServerError, err
+	}
+	return rm, connId, reqSink, resSource, http.StatusOK, nil
+}
+
 func (s *RTCService) ParseClientInfo(r *http.Request) *livekit.ClientInfo {
 	values := r.Form
 	ci := &livekit.ClientInfo{}
@@ -335,11 +411,7 @@ func (s *RTCService) ParseClientInfo(r *http.Request) *livekit.ClientInfo {
 	ci.BrowserVersion = values.Get("browser_version")
 	ci.DeviceModel = values.Get("device_model")
 	ci.Network = values.Get("network")
-	// get real address (forwarded http header) - check Cloudflare headers first, fall back to X-Forwarded-For
-	ci.Address = r.Header.Get("CF-Connecting-IP")
-	if len(ci.Address) == 0 {
-		ci.Address = xff.GetRemoteAddr(r)
-	}
+	ci.Address = s.proxies.ClientIP(r)
 
 	// attempt to parse types for SDKs that support browser as a platform
 	if ci.Sdk == livekit.ClientInfo_JS ||
@@ -0,0 +1,584 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/pion/srtp/v2"
+	"github.com/pion/webrtc/v3"
+
+	"github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+	"github.com/livekit/protocol/utils"
+
+	"github.com/livekit/livekit-server/pkg/audit"
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/routing"
+	"github.com/livekit/livekit-server/pkg/rtc"
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+)
+
+const (
+	RTPIngestPrefix = "RI_"
+
+	rtpIngestPathPrefix = "/admin/rtp_ingest"
+
+	srtpSuiteAES128SHA1_80 = "AES_CM_128_HMAC_SHA1_80"
+	srtpSuiteAES128SHA1_32 = "AES_CM_128_HMAC_SHA1_32"
+)
+
+type StartRTPIngestRequest struct {
+	Room     string `json:"room"`
+	Identity string `json:"identity"`
+	Name     string `json:"name,omitempty"`
+	// tracks sent to the port, packets of each are recognized by payload type
+	Tracks []RTPIngestTrackRequest `json:"tracks"`
+	// receive SRTP, keyed with srtp_key or with a generated key when it's empty
+	SRTP bool `json:"srtp,omitempty"`
+	// base64 of master key and salt, as the inline key of an SDP crypto attribute
+	SRTPKey string `json:"srtp_key,omitempty"`
+	// AES_CM_128_HMAC_SHA1_80 (default) or AES_CM_128_HMAC_SHA1_32
+	SRTPSuite string `json:"srtp_suite,omitempty"`
+	// IP, or IP and port, packets are sent from. Required without SRTP, other senders are ignored
+	Source string `json:"source,omitempty"`
+}
+
+type RTPIngestTrackRequest struct {
+	Name string `json:"name"`
+	// mime type, such as audio/opus or video/H264, has to be enabled for the room
+	Codec       string `json:"codec"`
+	PayloadType uint8  `json:"payload_type"`
+	// defaults to 48000 for audio and 90000 for video
+	ClockRate uint32 `json:"clock_rate,omitempty"`
+	Channels  uint16 `json:"channels,omitempty"`
+	Fmtp      string `json:"fmtp,omitempty"`
+	// camera, microphone, screen_share or screen_share_audio. Defaults to camera or microphone
+	Source string `json:"source,omitempty"`
+}
+
+type StopRTPIngestRequest struct {
+	Room string `json:"room"`
+	ID   string `json:"id"`
+}
+
+type RTPIngestTrackInfo struct {
+	PayloadType uint8  `json:"payload_type"`
+	SSRC        uint32 `json:"ssrc"`
+	TrackSid    string `json:"track_sid"`
+}
+
+type RTPIngestInfo struct {
+	ID       string `json:"id"`
+	Room     string `json:"room"`
+	Identity string `json:"identity"`
+	// host and port to send RTP and RTCP to
+	Address   string `json:"address"`
+	SRTPSuite string `json:"srtp_suite,omitempty"`
+	// only returned when starting
+	SRTPKey    string               `json:"srtp_key,omitempty"`
+	StartedAt  int64                `json:"started_at"`
+	RemoteAddr string               `json:"remote_addr,omitempty"`
+	Packets    uint64               `json:"packets"`
+	Bytes      uint64               `json:"bytes"`
+	Dropped    uint64               `json:"dropped"`
+	Tracks     []RTPIngestTrackInfo `json:"tracks"`
+}
+
+type rtpIngestSession struct {
+	info      RTPIngestInfo
+	port      uint16
+	ingest    *rtc.RTPIngest
+	reqSink   routing.MessageSink
+	resSource routing.MessageSource
+}
+
+func (s *rtpIngestSession) ToInfo() RTPIngestInfo {
+	info := s.info
+	stats := s.ingest.Stats()
+	info.RemoteAddr = stats.RemoteAddr
+	info.Packets = stats.Packets
+	info.Bytes = stats.Bytes
+	info.Dropped = stats.Dropped
+	info.Tracks = make([]RTPIngestTrackInfo, 0, len(stats.Streams))
+	for _, st := range stats.Streams {
+		info.Tracks = append(info.Tracks, RTPIngestTrackInfo{
+			PayloadType: st.PayloadType,
+			SSRC:        st.SSRC,
+			TrackSid:    string(st.TrackID),
+		})
+	}
+	return info
+}
+
+// RTPIngestService publishes plain RTP and SDES-SRTP sent over UDP, as by ffmpeg or hardware encoders, without an
+// ingress service. Each session is a participant of a room hosted on this node, receiving on its own port.
+type RTPIngestService struct {
+	conf          config.RTPIngestConfig
+	nodeIP        string
+	roomAllocator RoomAllocator
+	router        routing.Router
+	currentNode   routing.LocalNode
+	roomManager   *RoomManager
+	auditLogger   *audit.Logger
+	ports         *udpPortRange
+
+	lock     sync.Mutex
+	sessions map[string]*rtpIngestSession
+}
+
+func NewRTPIngestService(
+	conf *config.Config,
+	roomAllocator RoomAllocator,
+	router routing.Router,
+	currentNode routing.LocalNode,
+	roomManager *RoomManager,
+	auditLogger *audit.Logger,
+) *RTPIngestService {
+	return &RTPIngestService{
+		conf:          conf.RTPIngest,
+		nodeIP:        conf.RTC.NodeIP,
+		roomAllocator: roomAllocator,
+		router:        router,
+		currentNode:   currentNode,
+		roomManager:   roomManager,
+		auditLogger:   auditLogger,
+		ports:         newUDPPortRange(conf.RTPIngest.PortRangeStart, conf.RTPIngest.PortRangeEnd),
+		sessions:      make(map[string]*rtpIngestSession),
+	}
+}
+
+func (s *RTPIngestService) PathPrefix() string {
+	return rtpIngestPathPrefix
+}
+
+func (s *RTPIngestService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if s.conf.PortRangeStart == 0 || s.conf.PortRangeEnd < s.conf.PortRangeStart {
+		handleError(w, http.StatusNotFound, ErrRTPIngestDisabled)
+		return
+	}
+
+	switch {
+	case r.URL.Path == rtpIngestPathPrefix+"/start" && r.Method == http.MethodPost:
+		s.handleStart(w, r)
+	case r.URL.Path == rtpIngestPathPrefix+"/stop" && r.Method == http.MethodPost:
+		s.handleStop(w, r)
+	case r.URL.Path == rtpIngestPathPrefix && r.Method == http.MethodGet:
+		s.handleList(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *RTPIngestService) handleStart(w http.ResponseWriter, r *http.Request) {
+	req := &StartRTPIngestRequest{}
+	var info *RTPIngestInfo
+	var err error
+	defer func() {
+		auditOperation(r.Context(), s.auditLogger, auditServiceRTPIngest, "StartRTPIngest", req, info, err)
+	}()
+
+	if err = json.NewDecoder(r.Body).Decode(req); err != nil {
+		handleError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err = EnsureAdminPermission(r.Context(), livekit.RoomName(req.Room)); err != nil {
+		handleError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	info, err = s.StartIngest(r.Context(), req)
+	if err != nil {
+		status := http.StatusBadRequest
+		switch err {
+		case ErrRoomNotOnNode, rtc.ErrAlreadyJoined:
+			status = http.StatusConflict
+		case ErrNoPortsAvailable:
+			status = http.StatusServiceUnavailable
+		}
+		handleError(w, status, err, "room", req.Room, "participant", req.Identity)
+		return
+	}
+	writeJSON(w, info)
+}
+
+func (s *RTPIngestService) handleStop(w http.ResponseWriter, r *http.Request) {
+	req := &StopRTPIngestRequest{}
+	var info *RTPIngestInfo
+	var err error
+	defer func() {
+		auditOperation(r.Context(), s.auditLogger, auditServiceRTPIngest, "StopRTPIngest", req, info, err)
+	}()
+
+	if err = json.NewDecoder(r.Body).Decode(req); err != nil {
+		handleError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err = EnsureAdminPermission(r.Context(), livekit.RoomName(req.Room)); err != nil {
+		handleError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	info, err = s.StopIngest(livekit.RoomName(req.Room), req.ID)
+	if err != nil {
+		handleError(w, http.StatusNotFound, err, "ingestID", req.ID)
+		return
+	}
+	writeJSON(w, info)
+}
+
+func (s *RTPIngestService) handleList(w http.ResponseWriter, r *http.Request) {
+	roomName := livekit.RoomName(r.URL.Query().Get("room"))
+	if err := EnsureAdminPermission(r.Context(), roomName); err != nil {
+		handleError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	writeJSON(w, s.ListIngests(roomName))
+}
+
+func (s *RTPIngestService) StartIngest(ctx context.Context, req *StartRTPIngestRequest) (*RTPIngestInfo, error) {
+	if req.Identity == "" {
+		return nil, ErrIdentityEmpty
+	}
+	if len(req.Tracks) == 0 {
+		return nil, ErrNoTracks
+	}
+	profile, suite, key, err := rtpIngestSRTP(req)
+	if err != nil {
+		return nil, err
+	}
+	source, err := rtpIngestSource(req.Source)
+	if err != nil {
+		return nil, err
+	}
+	if len(key) == 0 && source == nil {
+		return nil, rtc.ErrRTPIngestSourceRequired
+	}
+
+	// packets arrive at this node, so the room has to be hosted here
+	roomName := livekit.RoomName(req.Room)
+	protoRoom, err := s.roomAllocator.CreateRoom(ctx, &livekit.CreateRoomRequest{Name: req.Room})
+	if err != nil {
+		return nil, err
+	}
+	node, err := s.router.GetNodeForRoom(ctx, roomName)
+	if err != nil {
+		return nil, err
+	}
+	if node.Id != s.currentNode.Id {
+		return nil, ErrRoomNotOnNode
+	}
+
+	tracks, err := rtpIngestTracks(req.Tracks, protoRoom.EnabledCodecs)
+	if err != nil {
+		return nil, err
+	}
+
+	if room := s.roomManager.GetRoom(ctx, roomName); room != nil && room.GetParticipant(livekit.ParticipantIdentity(req.Identity)) != nil {
+		return nil, rtc.ErrAlreadyJoined
+	}
+
+	conn, port, err := s.ports.allocate()
+	if err != nil {
+		return nil, err
+	}
+
+	// sessions start on this node directly, media never passes through the participant's PeerConnections
+	reqSink := routing.NewMessageChannel(routing.DefaultMessageChannelSize)
+	resSource := routing.NewMessageChannel(routing.DefaultMessageChannelSize)
+	ingestID := utils.NewGuid(RTPIngestPrefix)
+	pi := routing.ParticipantInit{
+		Identity: livekit.ParticipantIdentity(req.Identity),
+		Name:     livekit.ParticipantName(req.Name),
+		Grants: &auth.ClaimGrants{
+			Identity: req.Identity,
+			Name:     req.Name,
+			Video: &auth.VideoGrant{
+				RoomJoin: true,
+				Room:     req.Room,
+			},
+		},
+		Client:        &livekit.ClientInfo{Protocol: types.CurrentProtocol},
+		ExternalMedia: true,
+		APIKey:        GetAPIKey(ctx),
+	}
+	pi.Grants.Video.SetCanPublish(true)
+	pi.Grants.Video.SetCanSubscribe(false)
+	pi.Grants.Video.SetCanPublishData(false)
+
+	release := func() {
+		_ = conn.Close()
+		s.ports.release(port)
+		reqSink.Close()
+		resSource.Close()
+	}
+	if err = s.roomManager.StartSession(ctx, roomName, pi, reqSink, resSource); err != nil {
+		release()
+		return nil, err
+	}
+
+	var publisher rtc.ExternalTrackPublisher
+	room := s.roomManager.GetRoom(ctx, roomName)
+	if room != nil {
+		publisher, _ = room.GetParticipant(pi.Identity).(rtc.ExternalTrackPublisher)
+	}
+	if publisher == nil {
+		release()
+		return nil, ErrParticipantNotFound
+	}
+
+	ingest, err := rtc.NewRTPIngest(rtc.RTPIngestParams{
+		Conn:          conn,
+		Tracks:        tracks,
+		SRTPKey:       key,
+		SRTPProfile:   profile,
+		Source:        source,
+		Publisher:     publisher,
+		BufferFactory: room.GetBufferFactory(),
+		IdleTimeout:   s.conf.IdleTimeout,
+		Logger:        logger.Logger(logr.Logger(logger.GetDefaultLogger()).WithValues("ingestID", ingestID, "room", req.Room, "participant", req.Identity)),
+	})
+	if err != nil {
+		_ = reqSink.WriteMessage(&livekit.SignalRequest{
+			Message: &livekit.SignalRequest_Leave{Leave: &livekit.LeaveRequest{}},
+		})
+		release()
+		return nil, err
+	}
+
+	session := &rtpIngestSession{
+		info: RTPIngestInfo{
+			ID:        ingestID,
+			Room:      req.Room,
+			Identity:  req.Identity,
+			Address:   net.JoinHostPort(s.nodeIP, fmt.Sprint(port)),
+			SRTPSuite: suite,
+			StartedAt: time.Now().Unix(),
+		},
+		port:      port,
+		ingest:    ingest,
+		reqSink:   reqSink,
+		resSource: resSource,
+	}
+	s.lock.Lock()
+	s.sessions[ingestID] = session
+	s.lock.Unlock()
+
+	ingest.Start()
+	go s.sessionWorker(session)
+
+	logger.Infow("rtp ingest started",
+		"ingestID", ingestID,
+		"room", req.Room,
+		"participant", req.Identity,
+		"address", session.info.Address,
+		"srtp", suite != "",
+	)
+
+	info := session.ToInfo()
+	if len(key) != 0 {
+		info.SRTPKey = base64.StdEncoding.EncodeToString(key)
+	}
+	return &info, nil
+}
+
+// sessionWorker ends the session when either the ingest or the participant does
+func (s *RTPIngestService) sessionWorker(session *rtpIngestSession) {
+	defer func() {
+		session.ingest.Close()
+		_ = session.reqSink.WriteMessage(&livekit.SignalRequest{
+			Message: &livekit.SignalRequest_Leave{Leave: &livekit.LeaveRequest{}},
+		})
+		session.reqSink.Close()
+		session.resSource.Close()
+		s.ports.release(session.port)
+
+		s.lock.Lock()
+		delete(s.sessions, session.info.ID)
+		s.lock.Unlock()
+		logger.Infow("rtp ingest finished", "ingestID", session.info.ID, "room", session.info.Room)
+	}()
+
+	for {
+		select {
+		case <-session.ingest.Done():
+			return
+		case msg := <-session.resSource.ReadChan():
+			if msg == nil {
+				return
+			}
+			if res, ok := msg.(*livekit.SignalResponse); ok && res.GetLeave() != nil {
+				return
+			}
+		}
+	}
+}
+
+func (s *RTPIngestService) StopIngest(roomName livekit.RoomName, ingestID string) (*RTPIngestInfo, error) {
+	s.lock.Lock()
+	session := s.sessions[ingestID]
+	s.lock.Unlock()
+	if session == nil || livekit.RoomName(session.info.Room) != roomName {
+		return nil, ErrRTPIngestNotFound
+	}
+
+	session.ingest.Close()
+	info := session.ToInfo()
+	return &info, nil
+}
+
+// ListIngests returns sessions of a room that are in progress
+func (s *RTPIngestService) ListIngests(roomName livekit.RoomName) []RTPIngestInfo {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	ingests := make([]RTPIngestInfo, 0)
+	for _, session := range s.sessions {
+		if livekit.RoomName(session.info.Room) == roomName {
+			ingests = append(ingests, session.ToInfo())
+		}
+	}
+	return ingests
+}
+
+// Stop ends all sessions
+func (s *RTPIngestService) Stop() {
+	s.lock.Lock()
+	sessions := make([]*rtpIngestSession, 0, len(s.sessions))
+	for _, session := range s.sessions {
+		sessions = append(sessions, session)
+	}
+	s.lock.Unlock()
+
+	for _, session := range sessions {
+		session.ingest.Close()
+	}
+}
+
+// rtpIngestSRTP returns the SRTP profile and key requested, generating a key when needed
+func rtpIngestSRTP(req *StartRTPIngestRequest) (srtp.ProtectionProfile, string, []byte, error) {
+	if !req.SRTP && req.SRTPKey == "" {
+		return 0, "", nil, nil
+	}
+
+	suite := strings.ToUpper(req.SRTPSuite)
+	var profile srtp.ProtectionProfile
+	switch suite {
+	case "", srtpSuiteAES128SHA1_80:
+		suite = srtpSuiteAES128SHA1_80
+		profile = srtp.ProtectionProfileAes128CmHmacSha1_80
+	case srtpSuiteAES128SHA1_32:
+		profile = srtp.ProtectionProfileAes128CmHmacSha1_32
+	default:
+		return 0, "", nil, rtc.ErrUnsupportedSRTPProfile
+	}
+
+	if req.SRTPKey != "" {
+		key, err := base64.StdEncoding.DecodeString(req.SRTPKey)
+		if err != nil {
+			return 0, "", nil, rtc.ErrInvalidSRTPKey
+		}
+		return profile, suite, key, nil
+	}
+
+	// 128 bit master key and 112 bit master salt
+	key := make([]byte, 30)
+	if _, err := rand.Read(key); err != nil {
+		return 0, "", nil, err
+	}
+	return profile, suite, key, nil
+}
+
+// rtpIngestSource parses the address packets are expected from, an IP with an optional port
+func rtpIngestSource(source string) (*net.UDPAddr, error) {
+	if source == "" {
+		return nil, nil
+	}
+	if ip := net.ParseIP(source); ip != nil {
+		return &net.UDPAddr{IP: ip}, nil
+	}
+
+	host, port, err := net.SplitHostPort(source)
+	if err != nil {
+		return nil, ErrInvalidSource
+	}
+	ip := net.ParseIP(host)
+	p, err := strconv.ParseUint(port, 10, 16)
+	if ip == nil || err != nil {
+		return nil, ErrInvalidSource
+	}
+	return &net.UDPAddr{IP: ip, Port: int(p)}, nil
+}
+
+// rtpIngestTracks validates requested tracks, their codecs have to be enabled for the room so subscribers can receive them
+func rtpIngestTracks(requested []RTPIngestTrackRequest, enabledCodecs []*livekit.Codec) ([]rtc.RTPIngestTrack, error) {
+	tracks := make([]rtc.RTPIngestTrack, 0, len(requested))
+	for _, t := range requested {
+		// payload types of 64-95 can't be told apart from RTCP on a shared port
+		if t.PayloadType >= 128 || (t.PayloadType >= 64 && t.PayloadType < 96) {
+			return nil, ErrInvalidPayloadType
+		}
+
+		enabled := false
+		for _, c := range enabledCodecs {
+			if strings.EqualFold(c.Mime, t.Codec) {
+				enabled = true
+				break
+			}
+		}
+		if !enabled {
+			return nil, fmt.Errorf("%w: %s", ErrCodecNotEnabled, t.Codec)
+		}
+
+		codec := webrtc.RTPCodecParameters{
+			RTPCodecCapability: webrtc.RTPCodecCapability{
+				MimeType:    t.Codec,
+				ClockRate:   t.ClockRate,
+				Channels:    t.Channels,
+				SDPFmtpLine: t.Fmtp,
+			},
+			PayloadType: webrtc.PayloadType(t.PayloadType),
+		}
+		isVideo := strings.HasPrefix(strings.ToLower(t.Codec), "video/")
+		if codec.ClockRate == 0 {
+			if isVideo {
+				codec.ClockRate = 90000
+			} else {
+				codec.ClockRate = 48000
+			}
+		}
+		if codec.Channels == 0 && strings.EqualFold(t.Codec, webrtc.MimeTypeOpus) {
+			codec.Channels = 2
+		}
+
+		source := livekit.TrackSource_MICROPHONE
+		if isVideo {
+			source = livekit.TrackSource_CAMERA
+		}
+		if t.Source != "" {
+			value, ok := livekit.TrackSource_value[strings.ToUpper(t.Source)]
+			if !ok {
+				return nil, ErrInvalidTrackSource
+			}
+			source = livekit.TrackSource(value)
+		}
+
+		tracks = append(tracks, rtc.RTPIngestTrack{
+			Name:   t.Name,
+			Source: source,
+			Codec:  codec,
+		})
+	}
+	return tracks, nil
+}
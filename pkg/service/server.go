@@ -14,12 +14,16 @@ import (
 	"github.com/pion/turn/v2"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/rs/cors"
+	"github.com/twitchtv/twirp"
 	"github.com/urfave/negroni"
 	"go.uber.org/atomic"
 	"golang.org/x/sync/errgroup"
 
+	"github.com/livekit/livekit-server/pkg/audit"
 	"github.com/livekit/livekit-server/pkg/config"
 	"github.com/livekit/livekit-server/pkg/routing"
+	"github.com/livekit/livekit-server/pkg/rpc"
+	"github.com/livekit/livekit-server/pkg/telemetry/metering"
 	"github.com/livekit/livekit-server/version"
 	"github.com/livekit/protocol/auth"
 	"github.com/livekit/protocol/livekit"
@@ -27,26 +31,51 @@ import (
 )
 
 type LivekitServer struct {
-	config         *config.Config
-	egressService  *EgressService
-	ingressService *IngressService
-	rtcService     *RTCService
-	httpServer     *http.Server
-	promServer     *http.Server
-	router         routing.Router
-	roomManager    *RoomManager
-	turnServer     *turn.Server
-	currentNode    routing.LocalNode
-	running        atomic.Bool
-	doneChan       chan struct{}
-	closedChan     chan struct{}
+	config             *config.Config
+	egressService      *EgressService
+	ingressService     *IngressService
+	rtcService         *RTCService
+	whipService        *WHIPService
+	whepService        *WHEPService
+	captureService     *CaptureService
+	rtpIngestService   *RTPIngestService
+	trackRecorder      *TrackRecorder
+	relayService       *RelayService
+	waitingRoomService *WaitingRoomService
+	meter              *metering.Meter
+	auditLogger        *audit.Logger
+	objectStore        ObjectStore
+	configReloader     *ConfigReloader
+	httpServer         *http.Server
+	promServer         *http.Server
+	router             routing.Router
+	roomManager        *RoomManager
+	turnServer         *turn.Server
+	currentNode        routing.LocalNode
+	keyProvider        auth.KeyProvider
+	running            atomic.Bool
+	doneChan           chan struct{}
+	closedChan         chan struct{}
 }
 
 func NewLivekitServer(conf *config.Config,
 	roomService livekit.RoomService,
+	waitingRoomService *WaitingRoomService,
 	egressService *EgressService,
 	ingressService *IngressService,
 	rtcService *RTCService,
+	whipService *WHIPService,
+	whepService *WHEPService,
+	captureService *CaptureService,
+	rtpIngestService *RTPIngestService,
+	trackRecorder *TrackRecorder,
+	relayService *RelayService,
+	meter *metering.Meter,
+	rateLimiter *RateLimiter,
+	trustedProxies *TrustedProxies,
+	auditLogger *audit.Logger,
+	objectStore ObjectStore,
+	configReloader *ConfigReloader,
 	keyProvider auth.KeyProvider,
 	router routing.Router,
 	roomManager *RoomManager,
@@ -54,15 +83,27 @@ func NewLivekitServer(conf *config.Config,
 	currentNode routing.LocalNode,
 ) (s *LivekitServer, err error) {
 	s = &LivekitServer{
-		config:         conf,
-		egressService:  egressService,
-		ingressService: ingressService,
-		rtcService:     rtcService,
-		router:         router,
-		roomManager:    roomManager,
+		config:             conf,
+		egressService:      egressService,
+		ingressService:     ingressService,
+		rtcService:         rtcService,
+		whipService:        whipService,
+		whepService:        whepService,
+		captureService:     captureService,
+		rtpIngestService:   rtpIngestService,
+		trackRecorder:      trackRecorder,
+		relayService:       relayService,
+		waitingRoomService: waitingRoomService,
+		meter:              meter,
+		auditLogger:        auditLogger,
+		objectStore:        objectStore,
+		configReloader:     configReloader,
+		router:             router,
+		roomManager:        roomManager,
 		// turn server starts automatically
 		turnServer:  turnServer,
 		currentNode: currentNode,
+		keyProvider: keyProvider,
 		closedChan:  make(chan struct{}),
 	}
 
@@ -75,18 +116,30 @@ func NewLivekitServer(conf *config.Config,
 				return true
 			},
 			AllowedHeaders: []string{"*"},
+			// WHIP and WHEP clients locate their session through Location
+			ExposedHeaders: []string{"Location"},
 			// allow preflight to be cached for a day
 			MaxAge: 86400,
 		}),
 	}
+	if rateLimiter != nil {
+		// before authentication, for requests with invalid tokens to be limited by client IP as well
+		middlewares = append(middlewares, rateLimiter)
+	}
 	if keyProvider != nil {
 		middlewares = append(middlewares, NewAPIKeyAuthMiddleware(keyProvider))
 	}
+	if auditLogger != nil {
+		middlewares = append(middlewares, NewAuditMiddleware(trustedProxies))
+	}
 
 	twirpLoggingHook := TwirpLogger(logger.GetDefaultLogger())
-	roomServer := livekit.NewRoomServiceServer(roomService, twirpLoggingHook)
-	egressServer := livekit.NewEgressServer(egressService, twirpLoggingHook)
-	ingressServer := livekit.NewIngressServer(ingressService, twirpLoggingHook)
+	// limits by API key and room, once requests are authenticated and decoded
+	twirpRateLimit := twirp.WithServerInterceptors(rateLimiter.TwirpInterceptor())
+	roomServer := livekit.NewRoomServiceServer(roomService, twirpLoggingHook, twirpRateLimit)
+	waitingRoomServer := rpc.NewWaitingRoomServer(waitingRoomService, twirpLoggingHook, twirpRateLimit)
+	egressServer := livekit.NewEgressServer(egressService, twirpLoggingHook, twirpRateLimit)
+	ingressServer := livekit.NewIngressServer(ingressService, twirpLoggingHook, twirpRateLimit)
 
 	mux := http.NewServeMux()
 	if conf.Development {
@@ -96,10 +149,20 @@ func NewLivekitServer(conf *config.Config,
 		mux.HandleFunc("/debug/rooms", s.debugInfo)
 	}
 	mux.Handle(roomServer.PathPrefix(), roomServer)
+	mux.Handle(waitingRoomServer.PathPrefix(), waitingRoomServer)
 	mux.Handle(egressServer.PathPrefix(), egressServer)
 	mux.Handle(ingressServer.PathPrefix(), ingressServer)
 	mux.Handle("/rtc", rtcService)
 	mux.HandleFunc("/rtc/validate", rtcService.Validate)
+	mux.Handle(whipService.PathPrefix(), whipService)
+	mux.Handle(whipService.PathPrefix()+"/", whipService)
+	mux.Handle(whepService.PathPrefix(), whepService)
+	mux.Handle(whepService.PathPrefix()+"/", whepService)
+	mux.Handle(captureService.PathPrefix(), captureService)
+	mux.Handle(captureService.PathPrefix()+"/", captureService)
+	mux.Handle(rtpIngestService.PathPrefix(), rtpIngestService)
+	mux.Handle(rtpIngestService.PathPrefix()+"/", rtpIngestService)
+	mux.Handle(configReloader.PathPrefix(), configReloader)
 	mux.HandleFunc("/", s.defaultHandler)
 
 	s.httpServer = &http.Server{
@@ -157,8 +220,15 @@ func (s *LivekitServer) Start() error {
 	if err := s.egressService.Start(); err != nil {
 		return err
 	}
+	s.trackRecorder.Start(s.roomManager)
+	s.relayService.Start(s.roomManager)
+	if err := s.waitingRoomService.Start(s.roomManager); err != nil {
+		return err
+	}
 
 	s.ingressService.Start()
+	s.meter.Start()
+	s.auditLogger.Start()
 
 	addresses := s.config.BindAddresses
 	if addresses == nil {
@@ -247,9 +317,22 @@ func (s *LivekitServer) Start() error {
 		_ = s.turnServer.Close()
 	}
 
+	s.captureService.Stop()
+	s.rtpIngestService.Stop()
+	s.trackRecorder.Stop()
 	s.roomManager.Stop()
+	s.relayService.Stop()
+	s.waitingRoomService.Stop()
+	// after participants have left, so that their usage is rolled up
+	s.meter.Stop()
 	s.egressService.Stop()
 	s.ingressService.Stop()
+	// once nothing writes to it anymore
+	if fs, ok := s.objectStore.(*FileStore); ok {
+		fs.Stop()
+	}
+	// last, for records of operations made until now to be written
+	s.auditLogger.Stop()
 
 	close(s.closedChan)
 	return nil
@@ -272,12 +355,19 @@ func (s *LivekitServer) Stop(force bool) {
 	}
 
 	s.router.Stop()
+	if keyProvider, ok := s.keyProvider.(*KeyProvider); ok {
+		keyProvider.Stop()
+	}
 	close(s.doneChan)
 
 	// wait for fully closed
 	<-s.closedChan
 }
 
+func (s *LivekitServer) ConfigReloader() *ConfigReloader {
+	return s.configReloader
+}
+
 func (s *LivekitServer) RoomManager() *RoomManager {
 	return s.roomManager
 }
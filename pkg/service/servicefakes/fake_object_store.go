@@ -6,6 +6,8 @@ import (
 	"sync"
 	"time"
 
+	"github.com/livekit/livekit-server/pkg/rpc"
+	"github.com/livekit/livekit-server/pkg/rtc"
 	"github.com/livekit/livekit-server/pkg/service"
 	"github.com/livekit/protocol/livekit"
 )
@@ -36,6 +38,19 @@ type FakeObjectStore struct {
 	deleteRoomReturnsOnCall map[int]struct {
 		result1 error
 	}
+	DeleteWaitingParticipantStub        func(context.Context, livekit.RoomName, livekit.ParticipantIdentity) error
+	deleteWaitingParticipantMutex       sync.RWMutex
+	deleteWaitingParticipantArgsForCall []struct {
+		arg1 context.Context
+		arg2 livekit.RoomName
+		arg3 livekit.ParticipantIdentity
+	}
+	deleteWaitingParticipantReturns struct {
+		result1 error
+	}
+	deleteWaitingParticipantReturnsOnCall map[int]struct {
+		result1 error
+	}
 	ListParticipantsStub        func(context.Context, livekit.RoomName) ([]*livekit.ParticipantInfo, error)
 	listParticipantsMutex       sync.RWMutex
 	listParticipantsArgsForCall []struct {
@@ -64,6 +79,20 @@ type FakeObjectStore struct {
 		result1 []*livekit.Room
 		result2 error
 	}
+	ListWaitingParticipantsStub        func(context.Context, livekit.RoomName) ([]*rpc.WaitingParticipant, error)
+	listWaitingParticipantsMutex       sync.RWMutex
+	listWaitingParticipantsArgsForCall []struct {
+		arg1 context.Context
+		arg2 livekit.RoomName
+	}
+	listWaitingParticipantsReturns struct {
+		result1 []*rpc.WaitingParticipant
+		result2 error
+	}
+	listWaitingParticipantsReturnsOnCall map[int]struct {
+		result1 []*rpc.WaitingParticipant
+		result2 error
+	}
 	LoadParticipantStub        func(context.Context, livekit.RoomName, livekit.ParticipantIdentity) (*livekit.ParticipantInfo, error)
 	loadParticipantMutex       sync.RWMutex
 	loadParticipantArgsForCall []struct {
@@ -96,6 +125,35 @@ type FakeObjectStore struct {
 		result2 *livekit.RoomInternal
 		result3 error
 	}
+	LoadRoomOptionsStub        func(context.Context, livekit.RoomName) (*rtc.RoomOptions, error)
+	loadRoomOptionsMutex       sync.RWMutex
+	loadRoomOptionsArgsForCall []struct {
+		arg1 context.Context
+		arg2 livekit.RoomName
+	}
+	loadRoomOptionsReturns struct {
+		result1 *rtc.RoomOptions
+		result2 error
+	}
+	loadRoomOptionsReturnsOnCall map[int]struct {
+		result1 *rtc.RoomOptions
+		result2 error
+	}
+	LoadWaitingParticipantStub        func(context.Context, livekit.RoomName, livekit.ParticipantIdentity) (*rpc.WaitingParticipant, error)
+	loadWaitingParticipantMutex       sync.RWMutex
+	loadWaitingParticipantArgsForCall []struct {
+		arg1 context.Context
+		arg2 livekit.RoomName
+		arg3 livekit.ParticipantIdentity
+	}
+	loadWaitingParticipantReturns struct {
+		result1 *rpc.WaitingParticipant
+		result2 error
+	}
+	loadWaitingParticipantReturnsOnCall map[int]struct {
+		result1 *rpc.WaitingParticipant
+		result2 error
+	}
 	LockRoomStub        func(context.Context, livekit.RoomName, time.Duration) (string, error)
 	lockRoomMutex       sync.RWMutex
 	lockRoomArgsForCall []struct {
@@ -137,6 +195,32 @@ type FakeObjectStore struct {
 	storeRoomReturnsOnCall map[int]struct {
 		result1 error
 	}
+	StoreRoomOptionsStub        func(context.Context, livekit.RoomName, *rtc.RoomOptions) error
+	storeRoomOptionsMutex       sync.RWMutex
+	storeRoomOptionsArgsForCall []struct {
+		arg1 context.Context
+		arg2 livekit.RoomName
+		arg3 *rtc.RoomOptions
+	}
+	storeRoomOptionsReturns struct {
+		result1 error
+	}
+	storeRoomOptionsReturnsOnCall map[int]struct {
+		result1 error
+	}
+	StoreWaitingParticipantStub        func(context.Context, livekit.RoomName, *rpc.WaitingParticipant) error
+	storeWaitingParticipantMutex       sync.RWMutex
+	storeWaitingParticipantArgsForCall []struct {
+		arg1 context.Context
+		arg2 livekit.RoomName
+		arg3 *rpc.WaitingParticipant
+	}
+	storeWaitingParticipantReturns struct {
+		result1 error
+	}
+	storeWaitingParticipantReturnsOnCall map[int]struct {
+		result1 error
+	}
 	UnlockRoomStub        func(context.Context, livekit.RoomName, string) error
 	unlockRoomMutex       sync.RWMutex
 	unlockRoomArgsForCall []struct {
@@ -279,6 +363,69 @@ func (fake *FakeObjectStore) DeleteRoomReturnsOnCall(i int, result1 error) {
 	}{result1}
 }
 
+func (fake *FakeObjectStore) DeleteWaitingParticipant(arg1 context.Context, arg2 livekit.RoomName, arg3 livekit.ParticipantIdentity) error {
+	fake.deleteWaitingParticipantMutex.Lock()
+	ret, specificReturn := fake.deleteWaitingParticipantReturnsOnCall[len(fake.deleteWaitingParticipantArgsForCall)]
+	fake.deleteWaitingParticipantArgsForCall = append(fake.deleteWaitingParticipantArgsForCall, struct {
+		arg1 context.Context
+		arg2 livekit.RoomName
+		arg3 livekit.ParticipantIdentity
+	}{arg1, arg2, arg3})
+	stub := fake.DeleteWaitingParticipantStub
+	fakeReturns := fake.deleteWaitingParticipantReturns
+	fake.recordInvocation("DeleteWaitingParticipant", []interface{}{arg1, arg2, arg3})
+	fake.deleteWaitingParticipantMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeObjectStore) DeleteWaitingParticipantCallCount() int {
+	fake.deleteWaitingParticipantMutex.RLock()
+	defer fake.deleteWaitingParticipantMutex.RUnlock()
+	return len(fake.deleteWaitingParticipantArgsForCall)
+}
+
+func (fake *FakeObjectStore) DeleteWaitingParticipantCalls(stub func(context.Context, livekit.RoomName, livekit.ParticipantIdentity) error) {
+	fake.deleteWaitingParticipantMutex.Lock()
+	defer fake.deleteWaitingParticipantMutex.Unlock()
+	fake.DeleteWaitingParticipantStub = stub
+}
+
+func (fake *FakeObjectStore) DeleteWaitingParticipantArgsForCall(i int) (context.Context, livekit.RoomName, livekit.ParticipantIdentity) {
+	fake.deleteWaitingParticipantMutex.RLock()
+	defer fake.deleteWaitingParticipantMutex.RUnlock()
+	argsForCall := fake.deleteWaitingParticipantArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+}
+
+func (fake *FakeObjectStore) DeleteWaitingParticipantReturns(result1 error) {
+	fake.deleteWaitingParticipantMutex.Lock()
+	defer fake.deleteWaitingParticipantMutex.Unlock()
+	fake.DeleteWaitingParticipantStub = nil
+	fake.deleteWaitingParticipantReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeObjectStore) DeleteWaitingParticipantReturnsOnCall(i int, result1 error) {
+	fake.deleteWaitingParticipantMutex.Lock()
+	defer fake.deleteWaitingParticipantMutex.Unlock()
+	fake.DeleteWaitingParticipantStub = nil
+	if fake.deleteWaitingParticipantReturnsOnCall == nil {
+		fake.deleteWaitingParticipantReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.deleteWaitingParticipantReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
 func (fake *FakeObjectStore) ListParticipants(arg1 context.Context, arg2 livekit.RoomName) ([]*livekit.ParticipantInfo, error) {
 	fake.listParticipantsMutex.Lock()
 	ret, specificReturn := fake.listParticipantsReturnsOnCall[len(fake.listParticipantsArgsForCall)]
@@ -414,6 +561,71 @@ func (fake *FakeObjectStore) ListRoomsReturnsOnCall(i int, result1 []*livekit.Ro
 	}{result1, result2}
 }
 
+func (fake *FakeObjectStore) ListWaitingParticipants(arg1 context.Context, arg2 livekit.RoomName) ([]*rpc.WaitingParticipant, error) {
+	fake.listWaitingParticipantsMutex.Lock()
+	ret, specificReturn := fake.listWaitingParticipantsReturnsOnCall[len(fake.listWaitingParticipantsArgsForCall)]
+	fake.listWaitingParticipantsArgsForCall = append(fake.listWaitingParticipantsArgsForCall, struct {
+		arg1 context.Context
+		arg2 livekit.RoomName
+	}{arg1, arg2})
+	stub := fake.ListWaitingParticipantsStub
+	fakeReturns := fake.listWaitingParticipantsReturns
+	fake.recordInvocation("ListWaitingParticipants", []interface{}{arg1, arg2})
+	fake.listWaitingParticipantsMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeObjectStore) ListWaitingParticipantsCallCount() int {
+	fake.listWaitingParticipantsMutex.RLock()
+	defer fake.listWaitingParticipantsMutex.RUnlock()
+	return len(fake.listWaitingParticipantsArgsForCall)
+}
+
+func (fake *FakeObjectStore) ListWaitingParticipantsCalls(stub func(context.Context, livekit.RoomName) ([]*rpc.WaitingParticipant, error)) {
+	fake.listWaitingParticipantsMutex.Lock()
+	defer fake.listWaitingParticipantsMutex.Unlock()
+	fake.ListWaitingParticipantsStub = stub
+}
+
+func (fake *FakeObjectStore) ListWaitingParticipantsArgsForCall(i int) (context.Context, livekit.RoomName) {
+	fake.listWaitingParticipantsMutex.RLock()
+	defer fake.listWaitingParticipantsMutex.RUnlock()
+	argsForCall := fake.listWaitingParticipantsArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeObjectStore) ListWaitingParticipantsReturns(result1 []*rpc.WaitingParticipant, result2 error) {
+	fake.listWaitingParticipantsMutex.Lock()
+	defer fake.listWaitingParticipantsMutex.Unlock()
+	fake.ListWaitingParticipantsStub = nil
+	fake.listWaitingParticipantsReturns = struct {
+		result1 []*rpc.WaitingParticipant
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeObjectStore) ListWaitingParticipantsReturnsOnCall(i int, result1 []*rpc.WaitingParticipant, result2 error) {
+	fake.listWaitingParticipantsMutex.Lock()
+	defer fake.listWaitingParticipantsMutex.Unlock()
+	fake.ListWaitingParticipantsStub = nil
+	if fake.listWaitingParticipantsReturnsOnCall == nil {
+		fake.listWaitingParticipantsReturnsOnCall = make(map[int]struct {
+			result1 []*rpc.WaitingParticipant
+			result2 error
+		})
+	}
+	fake.listWaitingParticipantsReturnsOnCall[i] = struct {
+		result1 []*rpc.WaitingParticipant
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *FakeObjectStore) LoadParticipant(arg1 context.Context, arg2 livekit.RoomName, arg3 livekit.ParticipantIdentity) (*livekit.ParticipantInfo, error) {
 	fake.loadParticipantMutex.Lock()
 	ret, specificReturn := fake.loadParticipantReturnsOnCall[len(fake.loadParticipantArgsForCall)]
@@ -549,6 +761,137 @@ func (fake *FakeObjectStore) LoadRoomReturnsOnCall(i int, result1 *livekit.Room,
 	}{result1, result2, result3}
 }
 
+func (fake *FakeObjectStore) LoadRoomOptions(arg1 context.Context, arg2 livekit.RoomName) (*rtc.RoomOptions, error) {
+	fake.loadRoomOptionsMutex.Lock()
+	ret, specificReturn := fake.loadRoomOptionsReturnsOnCall[len(fake.loadRoomOptionsArgsForCall)]
+	fake.loadRoomOptionsArgsForCall = append(fake.loadRoomOptionsArgsForCall, struct {
+		arg1 context.Context
+		arg2 livekit.RoomName
+	}{arg1, arg2})
+	stub := fake.LoadRoomOptionsStub
+	fakeReturns := fake.loadRoomOptionsReturns
+	fake.recordInvocation("LoadRoomOptions", []interface{}{arg1, arg2})
+	fake.loadRoomOptionsMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeObjectStore) LoadRoomOptionsCallCount() int {
+	fake.loadRoomOptionsMutex.RLock()
+	defer fake.loadRoomOptionsMutex.RUnlock()
+	return len(fake.loadRoomOptionsArgsForCall)
+}
+
+func (fake *FakeObjectStore) LoadRoomOptionsCalls(stub func(context.Context, livekit.RoomName) (*rtc.RoomOptions, error)) {
+	fake.loadRoomOptionsMutex.Lock()
+	defer fake.loadRoomOptionsMutex.Unlock()
+	fake.LoadRoomOptionsStub = stub
+}
+
+func (fake *FakeObjectStore) LoadRoomOptionsArgsForCall(i int) (context.Context, livekit.RoomName) {
+	fake.loadRoomOptionsMutex.RLock()
+	defer fake.loadRoomOptionsMutex.RUnlock()
+	argsForCall := fake.loadRoomOptionsArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeObjectStore) LoadRoomOptionsReturns(result1 *rtc.RoomOptions, result2 error) {
+	fake.loadRoomOptionsMutex.Lock()
+	defer fake.loadRoomOptionsMutex.Unlock()
+	fake.LoadRoomOptionsStub = nil
+	fake.loadRoomOptionsReturns = struct {
+		result1 *rtc.RoomOptions
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeObjectStore) LoadRoomOptionsReturnsOnCall(i int, result1 *rtc.RoomOptions, result2 error) {
+	fake.loadRoomOptionsMutex.Lock()
+	defer fake.loadRoomOptionsMutex.Unlock()
+	fake.LoadRoomOptionsStub = nil
+	if fake.loadRoomOptionsReturnsOnCall == nil {
+		fake.loadRoomOptionsReturnsOnCall = make(map[int]struct {
+			result1 *rtc.RoomOptions
+			result2 error
+		})
+	}
+	fake.loadRoomOptionsReturnsOnCall[i] = struct {
+		result1 *rtc.RoomOptions
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeObjectStore) LoadWaitingParticipant(arg1 context.Context, arg2 livekit.RoomName, arg3 livekit.ParticipantIdentity) (*rpc.WaitingParticipant, error) {
+	fake.loadWaitingParticipantMutex.Lock()
+	ret, specificReturn := fake.loadWaitingParticipantReturnsOnCall[len(fake.loadWaitingParticipantArgsForCall)]
+	fake.loadWaitingParticipantArgsForCall = append(fake.loadWaitingParticipantArgsForCall, struct {
+		arg1 context.Context
+		arg2 livekit.RoomName
+		arg3 livekit.ParticipantIdentity
+	}{arg1, arg2, arg3})
+	stub := fake.LoadWaitingParticipantStub
+	fakeReturns := fake.loadWaitingParticipantReturns
+	fake.recordInvocation("LoadWaitingParticipant", []interface{}{arg1, arg2, arg3})
+	fake.loadWaitingParticipantMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeObjectStore) LoadWaitingParticipantCallCount() int {
+	fake.loadWaitingParticipantMutex.RLock()
+	defer fake.loadWaitingParticipantMutex.RUnlock()
+	return len(fake.loadWaitingParticipantArgsForCall)
+}
+
+func (fake *FakeObjectStore) LoadWaitingParticipantCalls(stub func(context.Context, livekit.RoomName, livekit.ParticipantIdentity) (*rpc.WaitingParticipant, error)) {
+	fake.loadWaitingParticipantMutex.Lock()
+	defer fake.loadWaitingParticipantMutex.Unlock()
+	fake.LoadWaitingParticipantStub = stub
+}
+
+func (fake *FakeObjectStore) LoadWaitingParticipantArgsForCall(i int) (context.Context, livekit.RoomName, livekit.ParticipantIdentity) {
+	fake.loadWaitingParticipantMutex.RLock()
+	defer fake.loadWaitingParticipantMutex.RUnlock()
+	argsForCall := fake.loadWaitingParticipantArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+}
+
+func (fake *FakeObjectStore) LoadWaitingParticipantReturns(result1 *rpc.WaitingParticipant, result2 error) {
+	fake.loadWaitingParticipantMutex.Lock()
+	defer fake.loadWaitingParticipantMutex.Unlock()
+	fake.LoadWaitingParticipantStub = nil
+	fake.loadWaitingParticipantReturns = struct {
+		result1 *rpc.WaitingParticipant
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeObjectStore) LoadWaitingParticipantReturnsOnCall(i int, result1 *rpc.WaitingParticipant, result2 error) {
+	fake.loadWaitingParticipantMutex.Lock()
+	defer fake.loadWaitingParticipantMutex.Unlock()
+	fake.LoadWaitingParticipantStub = nil
+	if fake.loadWaitingParticipantReturnsOnCall == nil {
+		fake.loadWaitingParticipantReturnsOnCall = make(map[int]struct {
+			result1 *rpc.WaitingParticipant
+			result2 error
+		})
+	}
+	fake.loadWaitingParticipantReturnsOnCall[i] = struct {
+		result1 *rpc.WaitingParticipant
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *FakeObjectStore) LockRoom(arg1 context.Context, arg2 livekit.RoomName, arg3 time.Duration) (string, error) {
 	fake.lockRoomMutex.Lock()
 	ret, specificReturn := fake.lockRoomReturnsOnCall[len(fake.lockRoomArgsForCall)]
@@ -741,6 +1084,132 @@ func (fake *FakeObjectStore) StoreRoomReturnsOnCall(i int, result1 error) {
 	}{result1}
 }
 
+func (fake *FakeObjectStore) StoreRoomOptions(arg1 context.Context, arg2 livekit.RoomName, arg3 *rtc.RoomOptions) error {
+	fake.storeRoomOptionsMutex.Lock()
+	ret, specificReturn := fake.storeRoomOptionsReturnsOnCall[len(fake.storeRoomOptionsArgsForCall)]
+	fake.storeRoomOptionsArgsForCall = append(fake.storeRoomOptionsArgsForCall, struct {
+		arg1 context.Context
+		arg2 livekit.RoomName
+		arg3 *rtc.RoomOptions
+	}{arg1, arg2, arg3})
+	stub := fake.StoreRoomOptionsStub
+	fakeReturns := fake.storeRoomOptionsReturns
+	fake.recordInvocation("StoreRoomOptions", []interface{}{arg1, arg2, arg3})
+	fake.storeRoomOptionsMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeObjectStore) StoreRoomOptionsCallCount() int {
+	fake.storeRoomOptionsMutex.RLock()
+	defer fake.storeRoomOptionsMutex.RUnlock()
+	return len(fake.storeRoomOptionsArgsForCall)
+}
+
+func (fake *FakeObjectStore) StoreRoomOptionsCalls(stub func(context.Context, livekit.RoomName, *rtc.RoomOptions) error) {
+	fake.storeRoomOptionsMutex.Lock()
+	defer fake.storeRoomOptionsMutex.Unlock()
+	fake.StoreRoomOptionsStub = stub
+}
+
+func (fake *FakeObjectStore) StoreRoomOptionsArgsForCall(i int) (context.Context, livekit.RoomName, *rtc.RoomOptions) {
+	fake.storeRoomOptionsMutex.RLock()
+	defer fake.storeRoomOptionsMutex.RUnlock()
+	argsForCall := fake.storeRoomOptionsArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+}
+
+func (fake *FakeObjectStore) StoreRoomOptionsReturns(result1 error) {
+	fake.storeRoomOptionsMutex.Lock()
+	defer fake.storeRoomOptionsMutex.Unlock()
+	fake.StoreRoomOptionsStub = nil
+	fake.storeRoomOptionsReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeObjectStore) StoreRoomOptionsReturnsOnCall(i int, result1 error) {
+	fake.storeRoomOptionsMutex.Lock()
+	defer fake.storeRoomOptionsMutex.Unlock()
+	fake.StoreRoomOptionsStub = nil
+	if fake.storeRoomOptionsReturnsOnCall == nil {
+		fake.storeRoomOptionsReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.storeRoomOptionsReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeObjectStore) StoreWaitingParticipant(arg1 context.Context, arg2 livekit.RoomName, arg3 *rpc.WaitingParticipant) error {
+	fake.storeWaitingParticipantMutex.Lock()
+	ret, specificReturn := fake.storeWaitingParticipantReturnsOnCall[len(fake.storeWaitingParticipantArgsForCall)]
+	fake.storeWaitingParticipantArgsForCall = append(fake.storeWaitingParticipantArgsForCall, struct {
+		arg1 context.Context
+		arg2 livekit.RoomName
+		arg3 *rpc.WaitingParticipant
+	}{arg1, arg2, arg3})
+	stub := fake.StoreWaitingParticipantStub
+	fakeReturns := fake.storeWaitingParticipantReturns
+	fake.recordInvocation("StoreWaitingParticipant", []interface{}{arg1, arg2, arg3})
+	fake.storeWaitingParticipantMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeObjectStore) StoreWaitingParticipantCallCount() int {
+	fake.storeWaitingParticipantMutex.RLock()
+	defer fake.storeWaitingParticipantMutex.RUnlock()
+	return len(fake.storeWaitingParticipantArgsForCall)
+}
+
+func (fake *FakeObjectStore) StoreWaitingParticipantCalls(stub func(context.Context, livekit.RoomName, *rpc.WaitingParticipant) error) {
+	fake.storeWaitingParticipantMutex.Lock()
+	defer fake.storeWaitingParticipantMutex.Unlock()
+	fake.StoreWaitingParticipantStub = stub
+}
+
+func (fake *FakeObjectStore) StoreWaitingParticipantArgsForCall(i int) (context.Context, livekit.RoomName, *rpc.WaitingParticipant) {
+	fake.storeWaitingParticipantMutex.RLock()
+	defer fake.storeWaitingParticipantMutex.RUnlock()
+	argsForCall := fake.storeWaitingParticipantArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+}
+
+func (fake *FakeObjectStore) StoreWaitingParticipantReturns(result1 error) {
+	fake.storeWaitingParticipantMutex.Lock()
+	defer fake.storeWaitingParticipantMutex.Unlock()
+	fake.StoreWaitingParticipantStub = nil
+	fake.storeWaitingParticipantReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeObjectStore) StoreWaitingParticipantReturnsOnCall(i int, result1 error) {
+	fake.storeWaitingParticipantMutex.Lock()
+	defer fake.storeWaitingParticipantMutex.Unlock()
+	fake.StoreWaitingParticipantStub = nil
+	if fake.storeWaitingParticipantReturnsOnCall == nil {
+		fake.storeWaitingParticipantReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.storeWaitingParticipantReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
 func (fake *FakeObjectStore) UnlockRoom(arg1 context.Context, arg2 livekit.RoomName, arg3 string) error {
 	fake.unlockRoomMutex.Lock()
 	ret, specificReturn := fake.unlockRoomReturnsOnCall[len(fake.unlockRoomArgsForCall)]
@@ -811,20 +1280,32 @@ func (fake *FakeObjectStore) Invocations() map[string][][]interface{} {
 	defer fake.deleteParticipantMutex.RUnlock()
 	fake.deleteRoomMutex.RLock()
 	defer fake.deleteRoomMutex.RUnlock()
+	fake.deleteWaitingParticipantMutex.RLock()
+	defer fake.deleteWaitingParticipantMutex.RUnlock()
 	fake.listParticipantsMutex.RLock()
 	defer fake.listParticipantsMutex.RUnlock()
 	fake.listRoomsMutex.RLock()
 	defer fake.listRoomsMutex.RUnlock()
+	fake.listWaitingParticipantsMutex.RLock()
+	defer fake.listWaitingParticipantsMutex.RUnlock()
 	fake.loadParticipantMutex.RLock()
 	defer fake.loadParticipantMutex.RUnlock()
 	fake.loadRoomMutex.RLock()
 	defer fake.loadRoomMutex.RUnlock()
+	fake.loadRoomOptionsMutex.RLock()
+	defer fake.loadRoomOptionsMutex.RUnlock()
+	fake.loadWaitingParticipantMutex.RLock()
+	defer fake.loadWaitingParticipantMutex.RUnlock()
 	fake.lockRoomMutex.RLock()
 	defer fake.lockRoomMutex.RUnlock()
 	fake.storeParticipantMutex.RLock()
 	defer fake.storeParticipantMutex.RUnlock()
 	fake.storeRoomMutex.RLock()
 	defer fake.storeRoomMutex.RUnlock()
+	fake.storeRoomOptionsMutex.RLock()
+	defer fake.storeRoomOptionsMutex.RUnlock()
+	fake.storeWaitingParticipantMutex.RLock()
+	defer fake.storeWaitingParticipantMutex.RUnlock()
 	fake.unlockRoomMutex.RLock()
 	defer fake.unlockRoomMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
@@ -0,0 +1,341 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/twitchtv/twirp"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/rtc"
+)
+
+// how long a tenant is locked for its rooms to be counted and claimed
+const tenantLockDuration = 5 * time.Second
+
+// Tenancy isolates rooms, egress and ingress of tenants sharing the server, and enforces their quotas. Rooms belong
+// to the tenant that creates them, or that first joins them. Egress and ingress belong to the tenant that starts them,
+// or otherwise to the tenant of their room. Resources that don't belong to a tenant are left to any of them
+type Tenancy struct {
+	conf        config.TenancyConfig
+	claimKeys   map[string]bool
+	tenantStore TenantStore
+}
+
+func NewTenancy(conf *config.Config, tenantStore TenantStore) *Tenancy {
+	tc := conf.Tenancy
+	if !tc.Enabled {
+		return nil
+	}
+
+	claimKeys := make(map[string]bool, len(tc.ClaimKeys))
+	for _, key := range tc.ClaimKeys {
+		claimKeys[key] = true
+	}
+	return &Tenancy{
+		conf:        tc,
+		claimKeys:   claimKeys,
+		tenantStore: tenantStore,
+	}
+}
+
+// TenantOf returns the tenant of a request, that of the API key its token is signed with
+func (t *Tenancy) TenantOf(ctx context.Context) string {
+	if t == nil {
+		return ""
+	}
+
+	apiKey := GetAPIKey(ctx)
+	if tenant := GetTenantClaim(ctx); tenant != "" && t.claimKeys[apiKey] {
+		return tenant
+	}
+	if tenant, ok := t.conf.Keys[apiKey]; ok {
+		return tenant
+	}
+	return apiKey
+}
+
+func (t *Tenancy) quota(tenant string) config.TenantQuota {
+	if quota, ok := t.conf.Quotas[tenant]; ok {
+		return quota
+	}
+	return t.conf.DefaultQuota
+}
+
+// EnsureRoom returns rtc.ErrPermissionDenied when the room belongs to another tenant
+func (t *Tenancy) EnsureRoom(ctx context.Context, roomName livekit.RoomName) error {
+	if t == nil || roomName == "" {
+		return nil
+	}
+	return t.ensure(ctx, RoomTenantResource(roomName))
+}
+
+// ClaimRoom makes the room, unless it already belongs to a tenant, one of the tenant of the request. It returns
+// rtc.ErrPermissionDenied when the room belongs to another tenant, and ErrTenantMaxRoomsExceeded when the tenant is
+// at its quota of rooms. It returns true when the room is newly claimed, for the claim to be released with
+// ReleaseRoom when the room can't be created
+func (t *Tenancy) ClaimRoom(ctx context.Context, roomName livekit.RoomName) (bool, error) {
+	if t == nil {
+		return false, nil
+	}
+
+	tenant := t.TenantOf(ctx)
+	owner, err := t.tenantStore.LoadTenant(ctx, RoomTenantResource(roomName))
+	if err != nil {
+		return false, err
+	}
+	if owner == tenant {
+		return false, nil
+	} else if owner != "" {
+		return false, rtc.ErrPermissionDenied
+	}
+
+	maxRooms := t.quota(tenant).MaxRooms
+	if maxRooms <= 0 {
+		return true, t.tenantStore.StoreTenant(ctx, RoomTenantResource(roomName), tenant)
+	}
+
+	// rooms are counted and claimed under a lock of the tenant, for rooms created at once not to exceed its quota
+	token, err := t.tenantStore.LockTenant(ctx, tenant, tenantLockDuration)
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		_ = t.tenantStore.UnlockTenant(ctx, tenant, token)
+	}()
+
+	roomNames, err := t.tenantStore.ListTenantRooms(ctx, tenant)
+	if err != nil {
+		return false, err
+	}
+	if len(roomNames) >= maxRooms {
+		return false, ErrTenantMaxRoomsExceeded
+	}
+	if err = t.tenantStore.StoreTenant(ctx, RoomTenantResource(roomName), tenant); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// ReleaseRoom releases a claim of ClaimRoom, for rooms that couldn't be created not to count against quota of the
+// tenant
+func (t *Tenancy) ReleaseRoom(ctx context.Context, roomName livekit.RoomName) error {
+	if t == nil {
+		return nil
+	}
+	return t.tenantStore.DeleteTenant(ctx, RoomTenantResource(roomName), t.TenantOf(ctx))
+}
+
+// FilterRooms returns rooms that belong to the tenant of the request
+func (t *Tenancy) FilterRooms(ctx context.Context, rooms []*livekit.Room) ([]*livekit.Room, error) {
+	if t == nil {
+		return rooms, nil
+	}
+
+	roomNames, err := t.tenantStore.ListTenantRooms(ctx, t.TenantOf(ctx))
+	if err != nil {
+		return nil, err
+	}
+	owned := make(map[livekit.RoomName]bool, len(roomNames))
+	for _, roomName := range roomNames {
+		owned[roomName] = true
+	}
+
+	filtered := make([]*livekit.Room, 0, len(rooms))
+	for _, room := range rooms {
+		if owned[livekit.RoomName(room.Name)] {
+			filtered = append(filtered, room)
+		}
+	}
+	return filtered, nil
+}
+
+// ClaimEgress makes egress one of the tenant of the request
+func (t *Tenancy) ClaimEgress(ctx context.Context, info *livekit.EgressInfo) error {
+	if t == nil {
+		return nil
+	}
+	return t.tenantStore.StoreTenant(ctx, EgressTenantResource(info.EgressId), t.TenantOf(ctx))
+}
+
+// EnsureEgress returns rtc.ErrPermissionDenied when egress belongs to another tenant
+func (t *Tenancy) EnsureEgress(ctx context.Context, info *livekit.EgressInfo) error {
+	if t == nil {
+		return nil
+	}
+	return t.ensure(ctx, EgressTenantResource(info.EgressId), RoomTenantResource(livekit.RoomName(info.RoomName)))
+}
+
+// FilterEgress returns egress that belongs to the tenant of the request
+func (t *Tenancy) FilterEgress(ctx context.Context, infos []*livekit.EgressInfo) ([]*livekit.EgressInfo, error) {
+	if t == nil {
+		return infos, nil
+	}
+
+	filtered := make([]*livekit.EgressInfo, 0, len(infos))
+	for _, info := range infos {
+		if owned, err := t.owns(ctx, EgressTenantResource(info.EgressId), RoomTenantResource(livekit.RoomName(info.RoomName))); err != nil {
+			return nil, err
+		} else if owned {
+			filtered = append(filtered, info)
+		}
+	}
+	return filtered, nil
+}
+
+// ClaimIngress makes ingress one of the tenant of the request
+func (t *Tenancy) ClaimIngress(ctx context.Context, info *livekit.IngressInfo) error {
+	if t == nil {
+		return nil
+	}
+	return t.tenantStore.StoreTenant(ctx, IngressTenantResource(info.IngressId), t.TenantOf(ctx))
+}
+
+// EnsureIngress returns rtc.ErrPermissionDenied when ingress belongs to another tenant
+func (t *Tenancy) EnsureIngress(ctx context.Context, info *livekit.IngressInfo) error {
+	if t == nil {
+		return nil
+	}
+	return t.ensure(ctx, IngressTenantResource(info.IngressId), RoomTenantResource(livekit.RoomName(info.RoomName)))
+}
+
+// FilterIngress returns ingress that belongs to the tenant of the request
+func (t *Tenancy) FilterIngress(ctx context.Context, infos []*livekit.IngressInfo) ([]*livekit.IngressInfo, error) {
+	if t == nil {
+		return infos, nil
+	}
+
+	filtered := make([]*livekit.IngressInfo, 0, len(infos))
+	for _, info := range infos {
+		if owned, err := t.owns(ctx, IngressTenantResource(info.IngressId), RoomTenantResource(livekit.RoomName(info.RoomName))); err != nil {
+			return nil, err
+		} else if owned {
+			filtered = append(filtered, info)
+		}
+	}
+	return filtered, nil
+}
+
+func (t *Tenancy) ensure(ctx context.Context, resources ...TenantResource) error {
+	owner, err := t.ownerOf(ctx, resources...)
+	if err != nil {
+		return err
+	}
+	if owner != "" && owner != t.TenantOf(ctx) {
+		return rtc.ErrPermissionDenied
+	}
+	return nil
+}
+
+func (t *Tenancy) owns(ctx context.Context, resources ...TenantResource) (bool, error) {
+	owner, err := t.ownerOf(ctx, resources...)
+	if err != nil {
+		return false, err
+	}
+	return owner == t.TenantOf(ctx), nil
+}
+
+// ownerOf returns the tenant of the first resource that belongs to one
+func (t *Tenancy) ownerOf(ctx context.Context, resources ...TenantResource) (string, error) {
+	for _, resource := range resources {
+		owner, err := t.tenantStore.LoadTenant(ctx, resource)
+		if err != nil || owner != "" {
+			return owner, err
+		}
+	}
+	return "", nil
+}
+
+// RoomQuota returns quotas of participants and tracks of the tenant the room belongs to, nil if it has none. Participants
+// are counted for as long as nodeID, the node hosting the room, is alive
+func (t *Tenancy) RoomQuota(ctx context.Context, roomName livekit.RoomName, nodeID livekit.NodeID) rtc.TenantQuota {
+	if t == nil {
+		return nil
+	}
+
+	tenant, err := t.tenantStore.LoadTenant(ctx, RoomTenantResource(roomName))
+	if err != nil {
+		logger.Errorw("could not load tenant of room", err, "room", roomName)
+		return nil
+	}
+	if tenant == "" {
+		return nil
+	}
+	quota := t.quota(tenant)
+	if quota.MaxParticipants <= 0 && quota.MaxTracks <= 0 {
+		return nil
+	}
+	return &tenantQuota{
+		tenancy: t,
+		tenant:  tenant,
+		nodeID:  nodeID,
+		quota:   quota,
+	}
+}
+
+// tenantQuota counts participants and tracks of a tenant in the store, across the cluster
+type tenantQuota struct {
+	tenancy *Tenancy
+	tenant  string
+	nodeID  livekit.NodeID
+	quota   config.TenantQuota
+}
+
+func (q *tenantQuota) ParticipantsExceeded() bool {
+	if q.quota.MaxParticipants <= 0 {
+		return false
+	}
+	participants, _, err := q.tenancy.tenantStore.LoadTenantUsage(context.Background(), q.tenant)
+	if err != nil {
+		logger.Errorw("could not count participants of tenant", err, "tenant", q.tenant)
+		return false
+	}
+	return participants >= q.quota.MaxParticipants
+}
+
+func (q *tenantQuota) TracksExceeded() bool {
+	if q.quota.MaxTracks <= 0 {
+		return false
+	}
+	_, tracks, err := q.tenancy.tenantStore.LoadTenantUsage(context.Background(), q.tenant)
+	if err != nil {
+		logger.Errorw("could not count tracks of tenant", err, "tenant", q.tenant)
+		return false
+	}
+	return tracks >= q.quota.MaxTracks
+}
+
+// ParticipantChanged counts participants that are not hidden, and tracks they publish
+func (q *tenantQuota) ParticipantChanged(participant *livekit.ParticipantInfo) {
+	var err error
+	if participant.Permission.GetHidden() {
+		err = q.tenancy.tenantStore.DeleteTenantParticipant(context.Background(), q.tenant, livekit.ParticipantID(participant.Sid))
+	} else {
+		err = q.tenancy.tenantStore.StoreTenantParticipant(context.Background(), q.tenant, q.nodeID, livekit.ParticipantID(participant.Sid), len(participant.Tracks))
+	}
+	if err != nil {
+		logger.Errorw("could not count participant of tenant", err, "tenant", q.tenant, "participant", participant.Identity)
+	}
+}
+
+func (q *tenantQuota) ParticipantLeft(participant *livekit.ParticipantInfo) {
+	if err := q.tenancy.tenantStore.DeleteTenantParticipant(context.Background(), q.tenant, livekit.ParticipantID(participant.Sid)); err != nil {
+		logger.Errorw("could not stop counting participant of tenant", err, "tenant", q.tenant, "participant", participant.Identity)
+	}
+}
+
+// twirpTenancyError translates errors of tenancy to twirp errors, leaving others as they are
+func twirpTenancyError(err error) error {
+	switch {
+	case errors.Is(err, rtc.ErrPermissionDenied):
+		return twirp.NewError(twirp.PermissionDenied, err.Error())
+	case errors.Is(err, ErrTenantMaxRoomsExceeded):
+		return twirp.NewError(twirp.ResourceExhausted, err.Error())
+	default:
+		return err
+	}
+}
@@ -0,0 +1,239 @@
+package service_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+
+	"github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/rtc"
+	"github.com/livekit/livekit-server/pkg/service"
+)
+
+func TestTenancy(t *testing.T) {
+	newTenancy := func(t *testing.T, quota config.TenantQuota) (*service.Tenancy, *service.LocalStore) {
+		conf, err := config.NewConfig("", true, nil, nil)
+		require.NoError(t, err)
+		conf.Tenancy = config.TenancyConfig{
+			Enabled:      true,
+			Keys:         map[string]string{"key-a2": "a"},
+			ClaimKeys:    []string{"platform"},
+			DefaultQuota: quota,
+		}
+		store := service.NewLocalStore()
+		return service.NewTenancy(conf, store), store
+	}
+	claimRoom := func(tenancy *service.Tenancy, ctx context.Context, roomName livekit.RoomName) error {
+		_, err := tenancy.ClaimRoom(ctx, roomName)
+		return err
+	}
+
+	t.Run("not enabled", func(t *testing.T) {
+		conf, err := config.NewConfig("", true, nil, nil)
+		require.NoError(t, err)
+		tenancy := service.NewTenancy(conf, nil)
+		require.Nil(t, tenancy)
+
+		ctx := tenantContext(t, "key-a", "")
+		require.NoError(t, claimRoom(tenancy, ctx, "room"))
+		require.NoError(t, tenancy.EnsureRoom(ctx, "room"))
+		require.Nil(t, tenancy.RoomQuota(ctx, "room", "ND_1"))
+	})
+
+	t.Run("tenants of requests", func(t *testing.T) {
+		tenancy, _ := newTenancy(t, config.TenantQuota{})
+		require.Equal(t, "key-a", tenancy.TenantOf(tenantContext(t, "key-a", "")))
+		require.Equal(t, "a", tenancy.TenantOf(tenantContext(t, "key-a2", "")))
+		require.Equal(t, "b", tenancy.TenantOf(tenantContext(t, "platform", "b")))
+		// only API keys acting for any tenant are trusted with the claim
+		require.Equal(t, "key-a", tenancy.TenantOf(tenantContext(t, "key-a", "b")))
+	})
+
+	t.Run("isolates rooms", func(t *testing.T) {
+		tenancy, store := newTenancy(t, config.TenantQuota{})
+		ctxA := tenantContext(t, "key-a2", "")
+		ctxB := tenantContext(t, "platform", "b")
+
+		require.NoError(t, claimRoom(tenancy, ctxA, "room-a"))
+		require.NoError(t, claimRoom(tenancy, tenantContext(t, "key-a", "a"), "room-a2"))
+		require.NoError(t, claimRoom(tenancy, ctxB, "room-b"))
+
+		require.NoError(t, tenancy.EnsureRoom(ctxA, "room-a"))
+		require.ErrorIs(t, tenancy.EnsureRoom(ctxB, "room-a"), rtc.ErrPermissionDenied)
+		require.ErrorIs(t, claimRoom(tenancy, ctxB, "room-a"), rtc.ErrPermissionDenied)
+		// unclaimed rooms are left to any tenant
+		require.NoError(t, tenancy.EnsureRoom(ctxB, "room-c"))
+
+		rooms, err := tenancy.FilterRooms(ctxB, []*livekit.Room{{Name: "room-a"}, {Name: "room-a2"}, {Name: "room-b"}})
+		require.NoError(t, err)
+		require.Len(t, rooms, 1)
+		require.Equal(t, "room-b", rooms[0].Name)
+
+		// deleted rooms can be claimed again
+		require.NoError(t, store.StoreRoom(ctxA, &livekit.Room{Name: "room-a"}, nil))
+		require.NoError(t, store.DeleteRoom(ctxA, "room-a"))
+		require.NoError(t, claimRoom(tenancy, ctxB, "room-a"))
+	})
+
+	t.Run("isolates egress and ingress", func(t *testing.T) {
+		tenancy, _ := newTenancy(t, config.TenantQuota{})
+		ctxA := tenantContext(t, "key-a", "")
+		ctxB := tenantContext(t, "key-b", "")
+		require.NoError(t, claimRoom(tenancy, ctxA, "room-a"))
+
+		egressA := &livekit.EgressInfo{EgressId: "egress-a", RoomName: "room-a"}
+		egressB := &livekit.EgressInfo{EgressId: "egress-b"}
+		require.NoError(t, tenancy.ClaimEgress(ctxB, egressB))
+		// egress that isn't claimed belongs to the tenant of its room
+		require.NoError(t, tenancy.EnsureEgress(ctxA, egressA))
+		require.ErrorIs(t, tenancy.EnsureEgress(ctxB, egressA), rtc.ErrPermissionDenied)
+		require.ErrorIs(t, tenancy.EnsureEgress(ctxA, egressB), rtc.ErrPermissionDenied)
+		egress, err := tenancy.FilterEgress(ctxA, []*livekit.EgressInfo{egressA, egressB})
+		require.NoError(t, err)
+		require.Equal(t, []*livekit.EgressInfo{egressA}, egress)
+
+		ingressA := &livekit.IngressInfo{IngressId: "ingress-a", RoomName: "room-b"}
+		require.NoError(t, tenancy.ClaimIngress(ctxA, ingressA))
+		require.NoError(t, tenancy.EnsureIngress(ctxA, ingressA))
+		require.ErrorIs(t, tenancy.EnsureIngress(ctxB, ingressA), rtc.ErrPermissionDenied)
+		ingress, err := tenancy.FilterIngress(ctxB, []*livekit.IngressInfo{ingressA})
+		require.NoError(t, err)
+		require.Empty(t, ingress)
+	})
+
+	t.Run("limits rooms", func(t *testing.T) {
+		tenancy, _ := newTenancy(t, config.TenantQuota{MaxRooms: 1})
+		ctx := tenantContext(t, "key-a", "")
+		claimed, err := tenancy.ClaimRoom(ctx, "room-1")
+		require.NoError(t, err)
+		require.True(t, claimed)
+		// claiming its own room again doesn't count
+		claimed, err = tenancy.ClaimRoom(ctx, "room-1")
+		require.NoError(t, err)
+		require.False(t, claimed)
+		require.ErrorIs(t, claimRoom(tenancy, ctx, "room-2"), service.ErrTenantMaxRoomsExceeded)
+		require.NoError(t, claimRoom(tenancy, tenantContext(t, "key-b", ""), "room-2"))
+
+		// released rooms no longer count
+		require.NoError(t, tenancy.ReleaseRoom(ctx, "room-1"))
+		require.NoError(t, claimRoom(tenancy, ctx, "room-3"))
+		require.NoError(t, tenancy.EnsureRoom(tenantContext(t, "key-b", ""), "room-1"))
+	})
+
+	t.Run("limits rooms claimed at once", func(t *testing.T) {
+		tenancy, store := newTenancy(t, config.TenantQuota{MaxRooms: 3})
+		ctx := tenantContext(t, "key-a", "")
+
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func(roomName livekit.RoomName) {
+				defer wg.Done()
+				_, err := tenancy.ClaimRoom(ctx, roomName)
+				if err != nil {
+					require.ErrorIs(t, err, service.ErrTenantMaxRoomsExceeded)
+				}
+			}(livekit.RoomName(fmt.Sprintf("room-%d", i)))
+		}
+		wg.Wait()
+
+		rooms, err := store.ListTenantRooms(ctx, "key-a")
+		require.NoError(t, err)
+		require.Len(t, rooms, 3)
+	})
+
+	t.Run("limits participants and tracks", func(t *testing.T) {
+		tenancy, store := newTenancy(t, config.TenantQuota{MaxParticipants: 2, MaxTracks: 2})
+		ctx := tenantContext(t, "key-a", "")
+		require.NoError(t, claimRoom(tenancy, ctx, "room-1"))
+		require.NoError(t, claimRoom(tenancy, ctx, "room-2"))
+		quota := tenancy.RoomQuota(ctx, "room-1", "ND_1")
+		require.NotNil(t, quota)
+		require.Nil(t, tenancy.RoomQuota(ctx, "room-3", "ND_1"))
+
+		p1 := &livekit.ParticipantInfo{
+			Sid:      "PA_1",
+			Identity: "p1",
+			State:    livekit.ParticipantInfo_ACTIVE,
+			Tracks:   []*livekit.TrackInfo{{Sid: "t1"}},
+		}
+		quota.ParticipantChanged(p1)
+		// hidden participants don't count
+		tenancy.RoomQuota(ctx, "room-2", "ND_1").ParticipantChanged(&livekit.ParticipantInfo{
+			Sid:        "PA_hidden",
+			Identity:   "hidden",
+			State:      livekit.ParticipantInfo_ACTIVE,
+			Permission: &livekit.ParticipantPermission{Hidden: true},
+		})
+		require.False(t, quota.ParticipantsExceeded())
+		require.False(t, quota.TracksExceeded())
+
+		p2 := &livekit.ParticipantInfo{
+			Sid:      "PA_2",
+			Identity: "p2",
+			State:    livekit.ParticipantInfo_ACTIVE,
+		}
+		require.NoError(t, store.StoreParticipant(ctx, "room-2", p2))
+		tenancy.RoomQuota(ctx, "room-2", "ND_1").ParticipantChanged(p2)
+		require.True(t, quota.ParticipantsExceeded())
+		require.False(t, quota.TracksExceeded())
+
+		p2.Tracks = []*livekit.TrackInfo{{Sid: "t2"}}
+		tenancy.RoomQuota(ctx, "room-2", "ND_1").ParticipantChanged(p2)
+		require.True(t, quota.TracksExceeded())
+
+		quota.ParticipantLeft(p1)
+		require.False(t, quota.ParticipantsExceeded())
+		require.False(t, quota.TracksExceeded())
+
+		// participants left in deleted rooms no longer count
+		quota.ParticipantChanged(p1)
+		require.NoError(t, store.StoreRoom(ctx, &livekit.Room{Name: "room-2"}, nil))
+		require.NoError(t, store.DeleteRoom(ctx, "room-2"))
+		require.False(t, quota.ParticipantsExceeded())
+		rooms, err := store.ListTenantRooms(ctx, "key-a")
+		require.NoError(t, err)
+		require.Equal(t, []livekit.RoomName{"room-1"}, rooms)
+	})
+}
+
+// tenantContext returns the context of a request authenticated with a token of apiKey, naming tenant in its claims
+func tenantContext(t *testing.T, apiKey string, tenant string) context.Context {
+	secret := "secret"
+	sig, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.HS256, Key: []byte(secret)},
+		(&jose.SignerOptions{}).WithType("JWT"))
+	require.NoError(t, err)
+	token, err := jwt.Signed(sig).
+		Claims(jwt.Claims{
+			Issuer:    apiKey,
+			NotBefore: jwt.NewNumericDate(time.Now()),
+			Expiry:    jwt.NewNumericDate(time.Now().Add(time.Minute)),
+		}).
+		Claims(map[string]interface{}{
+			"video":  map[string]interface{}{"roomCreate": true},
+			"tenant": tenant,
+		}).
+		CompactSerialize()
+	require.NoError(t, err)
+
+	var ctx context.Context
+	r := &http.Request{Header: http.Header{}}
+	service.SetAuthorizationToken(r, token)
+	service.NewAPIKeyAuthMiddleware(auth.NewFileBasedKeyProviderFromMap(map[string]string{apiKey: secret})).
+		ServeHTTP(httptest.NewRecorder(), r, func(w http.ResponseWriter, r *http.Request) {
+			ctx = r.Context()
+		})
+	require.NotNil(t, ctx)
+	return ctx
+}
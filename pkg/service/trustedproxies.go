@@ -0,0 +1,82 @@
+package service
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/livekit/livekit-server/pkg/config"
+)
+
+// TrustedProxies tells addresses of clients from requests, honoring forwarding headers (CF-Connecting-IP,
+// X-Forwarded-For) only when they're set by a trusted proxy. Otherwise, anyone could claim any address
+type TrustedProxies struct {
+	networks []*net.IPNet
+}
+
+// NewTrustedProxies returns nil when no proxies are trusted, for clients to be told by the address they connect from
+func NewTrustedProxies(conf *config.Config) (*TrustedProxies, error) {
+	if len(conf.TrustedProxies) == 0 {
+		return nil, nil
+	}
+
+	p := &TrustedProxies{}
+	for _, proxy := range conf.TrustedProxies {
+		if ip := net.ParseIP(proxy); ip != nil {
+			p.networks = append(p.networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(len(ip)*8, len(ip)*8)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidTrustedProxy, proxy)
+		}
+		p.networks = append(p.networks, network)
+	}
+	return p, nil
+}
+
+// ClientIP returns the address of the client of r, without its port. Requests from trusted proxies are of the
+// client they forward for, for X-Forwarded-For that's the last address not of a trusted proxy, as the ones before
+// it could be set by the client
+func (p *TrustedProxies) ClientIP(r *http.Request) string {
+	address := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(address); err == nil {
+		address = host
+	}
+	if !p.trusted(address) {
+		return address
+	}
+
+	if forwarded := strings.TrimSpace(r.Header.Get("CF-Connecting-IP")); net.ParseIP(forwarded) != nil {
+		return forwarded
+	}
+	forwarded := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(forwarded[i])
+		if net.ParseIP(hop) == nil {
+			break
+		}
+		address = hop
+		if !p.trusted(hop) {
+			break
+		}
+	}
+	return address
+}
+
+func (p *TrustedProxies) trusted(address string) bool {
+	if p == nil {
+		return false
+	}
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return false
+	}
+	for _, network := range p.networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
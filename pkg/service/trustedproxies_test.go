@@ -0,0 +1,68 @@
+package service_test
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/service"
+)
+
+func TestTrustedProxies(t *testing.T) {
+	newTrustedProxies := func(t *testing.T, trusted ...string) *service.TrustedProxies {
+		conf, err := config.NewConfig("", true, nil, nil)
+		require.NoError(t, err)
+		conf.TrustedProxies = trusted
+		proxies, err := service.NewTrustedProxies(conf)
+		require.NoError(t, err)
+		return proxies
+	}
+	clientIP := func(proxies *service.TrustedProxies, remoteAddr string, headers map[string]string) string {
+		r := httptest.NewRequest("GET", "/rtc", nil)
+		r.RemoteAddr = remoteAddr
+		for key, value := range headers {
+			r.Header.Set(key, value)
+		}
+		return proxies.ClientIP(r)
+	}
+
+	t.Run("no trusted proxies", func(t *testing.T) {
+		proxies := newTrustedProxies(t)
+		require.Nil(t, proxies)
+		require.Equal(t, "192.0.2.1", clientIP(proxies, "192.0.2.1:1000", map[string]string{
+			"CF-Connecting-IP": "198.51.100.1",
+			"X-Forwarded-For":  "198.51.100.2",
+		}))
+	})
+
+	t.Run("forwarding headers of trusted proxies", func(t *testing.T) {
+		proxies := newTrustedProxies(t, "10.0.0.0/8", "172.16.0.1")
+		require.Equal(t, "198.51.100.1", clientIP(proxies, "10.0.0.1:1000", map[string]string{
+			"CF-Connecting-IP": "198.51.100.1",
+			"X-Forwarded-For":  "198.51.100.2",
+		}))
+		require.Equal(t, "198.51.100.2", clientIP(proxies, "172.16.0.1:1000", map[string]string{
+			"X-Forwarded-For": "198.51.100.2",
+		}))
+		// addresses before the last one not of a trusted proxy could be set by the client
+		require.Equal(t, "198.51.100.2", clientIP(proxies, "10.0.0.1:1000", map[string]string{
+			"X-Forwarded-For": "203.0.113.1, 198.51.100.2, 172.16.0.1",
+		}))
+		require.Equal(t, "10.0.0.1", clientIP(proxies, "10.0.0.1:1000", nil))
+		// headers of others are ignored
+		require.Equal(t, "192.0.2.1", clientIP(proxies, "192.0.2.1:1000", map[string]string{
+			"CF-Connecting-IP": "198.51.100.1",
+			"X-Forwarded-For":  "198.51.100.2",
+		}))
+	})
+
+	t.Run("invalid proxies", func(t *testing.T) {
+		conf, err := config.NewConfig("", true, nil, nil)
+		require.NoError(t, err)
+		conf.TrustedProxies = []string{"10.0.0.0/33"}
+		_, err = service.NewTrustedProxies(conf)
+		require.ErrorIs(t, err, service.ErrInvalidTrustedProxy)
+	})
+}
@@ -74,6 +74,10 @@ func requestReceived(ctx context.Context, requestLoggerPool *sync.Pool) (context
 		r.fields = append(r.fields, "service", svc)
 	}
 
+	if apiKey := GetAPIKey(ctx); apiKey != "" {
+		r.fields = append(r.fields, "apiKey", apiKey)
+	}
+
 	ctx = context.WithValue(ctx, logKey, r)
 	return ctx, nil
 }
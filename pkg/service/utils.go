@@ -1,10 +1,17 @@
 package service
 
 import (
+	"fmt"
+	"net"
 	"net/http"
 	"regexp"
+	"sync"
 
+	"github.com/livekit/protocol/auth"
 	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	serverwebhook "github.com/livekit/livekit-server/pkg/webhook"
 )
 
 func handleError(w http.ResponseWriter, status int, err error, keysAndValues ...interface{}) {
@@ -14,6 +21,47 @@ func handleError(w http.ResponseWriter, status int, err error, keysAndValues ...
 	_, _ = w.Write([]byte(err.Error()))
 }
 
+// webhookEndpoints returns URLs and endpoints of conf with the secrets of their keys. URLs, and endpoints without a
+// key of their own, are signed with the webhook key
+func webhookEndpoints(conf config.WebHookConfig, provider auth.KeyProvider) ([]serverwebhook.Endpoint, error) {
+	var endpoints []serverwebhook.Endpoint
+	if len(conf.URLs) > 0 {
+		secret := provider.GetSecret(conf.APIKey)
+		if secret == "" {
+			return nil, ErrWebHookMissingAPIKey
+		}
+		endpoints = serverwebhook.URLEndpoints(conf.URLs, conf.APIKey, secret)
+	}
+
+	for _, ec := range conf.Endpoints {
+		apiKey := ec.APIKey
+		if apiKey == "" {
+			apiKey = conf.APIKey
+		}
+		secret := provider.GetSecret(apiKey)
+		if secret == "" {
+			return nil, ErrWebHookMissingAPIKey
+		}
+		endpoints = append(endpoints, serverwebhook.Endpoint{
+			URL:       ec.URL,
+			APIKey:    apiKey,
+			APISecret: secret,
+			Events:    ec.Events,
+			Rooms:     ec.Rooms,
+			Timeout:   ec.Timeout,
+		})
+	}
+
+	urls := make(map[string]bool, len(endpoints))
+	for _, e := range endpoints {
+		if urls[e.URL] {
+			return nil, fmt.Errorf("%w: %s", ErrWebHookDuplicateURL, e.URL)
+		}
+		urls[e.URL] = true
+	}
+	return endpoints, nil
+}
+
 func boolValue(s string) bool {
 	return s == "1" || s == "true"
 }
@@ -22,3 +70,45 @@ func IsValidDomain(domain string) bool {
 	domainRegexp := regexp.MustCompile(`^(?i)[a-z0-9-]+(\.[a-z0-9-]+)+\.?$`)
 	return domainRegexp.MatchString(domain)
 }
+
+// udpPortRange hands out ports of a configured range, bound for UDP on all interfaces
+type udpPortRange struct {
+	start uint16
+	end   uint16
+
+	lock  sync.Mutex
+	ports map[uint16]bool
+}
+
+func newUDPPortRange(start, end uint16) *udpPortRange {
+	return &udpPortRange{
+		start: start,
+		end:   end,
+		ports: make(map[uint16]bool),
+	}
+}
+
+func (p *udpPortRange) allocate() (net.PacketConn, uint16, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	for port := int(p.start); port <= int(p.end); port++ {
+		if p.ports[uint16(port)] {
+			continue
+		}
+		conn, err := net.ListenPacket("udp", fmt.Sprintf(":%d", port))
+		if err != nil {
+			// in use by another process
+			continue
+		}
+		p.ports[uint16(port)] = true
+		return conn, uint16(port), nil
+	}
+	return nil, 0, ErrNoPortsAvailable
+}
+
+func (p *udpPortRange) release(port uint16) {
+	p.lock.Lock()
+	delete(p.ports, port)
+	p.lock.Unlock()
+}
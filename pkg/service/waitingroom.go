@@ -0,0 +1,356 @@
+package service
+
+import (
+	"context"
+	"path"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/routing"
+	"github.com/livekit/livekit-server/pkg/rpc"
+	"github.com/livekit/livekit-server/pkg/rtc"
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+)
+
+// SignalResponse field carrying a WaitingResponse, which the protocol leaves unused. Clients decoding responses as
+// protobuf read it from unknown fields
+const signalResponseWaitingField = 1000
+
+// waitingParticipant is signal connected, but not in the room until it's admitted
+type waitingParticipant struct {
+	pi            routing.ParticipantInit
+	info          *livekit.ParticipantInfo
+	requestSource routing.MessageSource
+	responseSink  routing.MessageSink
+	decisions     chan waitingDecision
+}
+
+// leave ends the signal connection of a participant that won't join, with a reason when it's turned away
+func (wp *waitingParticipant) leave(reason *livekit.DisconnectReason) {
+	if reason != nil {
+		_ = wp.responseSink.WriteMessage(&livekit.SignalResponse{
+			Message: &livekit.SignalResponse_Leave{
+				Leave: &livekit.LeaveRequest{Reason: *reason},
+			},
+		})
+	}
+	wp.requestSource.Close()
+	wp.responseSink.Close()
+}
+
+// waitingDecision admits a participant with admit applied, or rejects it for reason when admit is nil
+type waitingDecision struct {
+	admit  *rpc.AdmitParticipantRequest
+	reason livekit.DisconnectReason
+}
+
+// newWaitingResponse tells a participant it's waiting to join a room. Its own JOINING update is sent along, for
+// clients that don't know about waiting
+func newWaitingResponse(roomName livekit.RoomName, info *livekit.ParticipantInfo) *livekit.SignalResponse {
+	res := &livekit.SignalResponse{
+		Message: &livekit.SignalResponse_Update{
+			Update: &livekit.ParticipantUpdate{
+				Participants: []*livekit.ParticipantInfo{info},
+			},
+		},
+	}
+	data, err := proto.Marshal(&rpc.WaitingResponse{
+		Room:        string(roomName),
+		Participant: info,
+	})
+	if err == nil {
+		unknown := protowire.AppendTag(nil, signalResponseWaitingField, protowire.BytesType)
+		res.ProtoReflect().SetUnknown(protowire.AppendBytes(unknown, data))
+	}
+	return res
+}
+
+// getWaitingResponse returns the WaitingResponse a response carries, nil if it's not telling a participant it's waiting
+func getWaitingResponse(res *livekit.SignalResponse) *rpc.WaitingResponse {
+	unknown := res.ProtoReflect().GetUnknown()
+	for len(unknown) > 0 {
+		num, typ, n := protowire.ConsumeTag(unknown)
+		if n < 0 {
+			return nil
+		}
+		unknown = unknown[n:]
+
+		if num == signalResponseWaitingField && typ == protowire.BytesType {
+			value, n := protowire.ConsumeBytes(unknown)
+			if n < 0 {
+				return nil
+			}
+			waiting := &rpc.WaitingResponse{}
+			if err := proto.Unmarshal(value, waiting); err != nil {
+				return nil
+			}
+			return waiting
+		}
+
+		n = protowire.ConsumeFieldValue(num, typ, unknown)
+		if n < 0 {
+			return nil
+		}
+		unknown = unknown[n:]
+	}
+	return nil
+}
+
+// isGated returns true when the participant has to wait to join the room. Room admins, who admit participants,
+// join directly, as do participants that are not seen by others
+func (r *RoomManager) isGated(roomName livekit.RoomName, pi *routing.ParticipantInit) bool {
+	if pi.Relayed || pi.ExternalMedia {
+		return false
+	}
+	if video := pi.Grants.Video; video != nil && (video.RoomAdmin || video.Hidden || video.Recorder) {
+		return false
+	}
+	for _, pattern := range r.config.Room.WaitingRoom.Rooms {
+		if ok, _ := path.Match(pattern, string(roomName)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// startWaiting holds a participant until it's admitted or rejected, keeping the room open meanwhile
+func (r *RoomManager) startWaiting(
+	ctx context.Context,
+	room *rtc.Room,
+	pi routing.ParticipantInit,
+	sid livekit.ParticipantID,
+	requestSource routing.MessageSource,
+	responseSink routing.MessageSink,
+) error {
+	if !room.Hold() {
+		return rtc.ErrRoomClosed
+	}
+
+	wp := &waitingParticipant{
+		pi: pi,
+		info: &livekit.ParticipantInfo{
+			Sid:        string(sid),
+			Identity:   string(pi.Identity),
+			Name:       string(pi.Name),
+			State:      livekit.ParticipantInfo_JOINING,
+			Permission: pi.Grants.Video.ToPermission(),
+			Metadata:   pi.Grants.Metadata,
+			Region:     pi.Region,
+		},
+		requestSource: requestSource,
+		responseSink:  responseSink,
+		decisions:     make(chan waitingDecision, 1),
+	}
+
+	r.lock.Lock()
+	waiting := r.waiting[room.Name()]
+	if waiting == nil {
+		waiting = make(map[livekit.ParticipantIdentity]*waitingParticipant)
+		r.waiting[room.Name()] = waiting
+	}
+	waiting[pi.Identity] = wp
+	r.lock.Unlock()
+
+	logger.Infow("participant waiting to be admitted", "room", room.Name(), "participant", pi.Identity, "pID", sid)
+	err := r.roomStore.StoreWaitingParticipant(ctx, room.Name(), &rpc.WaitingParticipant{
+		Participant: wp.info,
+		NodeId:      r.currentNode.Id,
+	})
+	if err != nil {
+		logger.Errorw("could not store waiting participant", err, "room", room.Name(), "participant", pi.Identity)
+	}
+
+	_ = responseSink.WriteMessage(newWaitingResponse(room.Name(), wp.info))
+	r.announceWaiting(room, wp.info)
+
+	go r.waitingWorker(ctx, room, wp)
+	return nil
+}
+
+func (r *RoomManager) waitingWorker(ctx context.Context, room *rtc.Room, wp *waitingParticipant) {
+	defer room.Release()
+	defer rtc.Recover()
+
+	pLogger := rtc.LoggerWithParticipant(room.Logger, wp.pi.Identity, livekit.ParticipantID(wp.info.Sid), false)
+	for {
+		select {
+		case obj := <-wp.requestSource.ReadChan():
+			// nothing is negotiated before joining
+			if req, ok := obj.(*livekit.SignalRequest); obj == nil || (ok && req.GetLeave() != nil) {
+				pLogger.Infow("waiting participant left")
+				if r.removeWaiting(room.Name(), wp) {
+					r.forgetWaiting(ctx, room, wp, true)
+				}
+				wp.leave(nil)
+				return
+			}
+		case decision := <-wp.decisions:
+			if decision.admit == nil {
+				pLogger.Infow("waiting participant rejected", "reason", decision.reason)
+				removed := r.removeWaiting(room.Name(), wp)
+				if decision.reason == livekit.DisconnectReason_DUPLICATE_IDENTITY {
+					// the participant replacing it is stored in its place
+					r.forgetWaiting(ctx, room, wp, false)
+				} else if removed {
+					r.forgetWaiting(ctx, room, wp, true)
+				}
+				wp.leave(&decision.reason)
+				return
+			}
+
+			pLogger.Infow("waiting participant admitted")
+			pi := wp.pi
+			applyAdmission(&pi, decision.admit)
+			r.removeWaiting(room.Name(), wp)
+			if err := r.startParticipant(ctx, room, pi, livekit.ParticipantID(wp.info.Sid), wp.requestSource, wp.responseSink); err != nil {
+				pLogger.Errorw("could not start admitted participant", err)
+				r.forgetWaiting(ctx, room, wp, true)
+				reason := livekit.DisconnectReason_JOIN_FAILURE
+				wp.leave(&reason)
+			} else if err = r.roomStore.DeleteWaitingParticipant(ctx, room.Name(), wp.pi.Identity); err != nil {
+				// admins learn it joined from the room
+				pLogger.Errorw("could not delete waiting participant", err)
+			}
+			return
+		}
+	}
+}
+
+// forgetWaiting lets room admins know a participant stopped waiting, deleting it from the store unless it's been
+// replaced
+func (r *RoomManager) forgetWaiting(ctx context.Context, room *rtc.Room, wp *waitingParticipant, deleteStored bool) {
+	if deleteStored {
+		if err := r.roomStore.DeleteWaitingParticipant(ctx, room.Name(), wp.pi.Identity); err != nil {
+			logger.Errorw("could not delete waiting participant", err, "room", room.Name(), "participant", wp.pi.Identity)
+		}
+	}
+	info := proto.Clone(wp.info).(*livekit.ParticipantInfo)
+	info.State = livekit.ParticipantInfo_DISCONNECTED
+	r.announceWaiting(room, info)
+}
+
+func (r *RoomManager) removeWaiting(roomName livekit.RoomName, wp *waitingParticipant) bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	waiting := r.waiting[roomName]
+	if waiting[wp.pi.Identity] != wp {
+		return false
+	}
+	delete(waiting, wp.pi.Identity)
+	if len(waiting) == 0 {
+		delete(r.waiting, roomName)
+	}
+	return true
+}
+
+func (r *RoomManager) getWaiting(roomName livekit.RoomName, identity livekit.ParticipantIdentity) *waitingParticipant {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return r.waiting[roomName][identity]
+}
+
+// admitWaiting lets a participant waiting on this node join the room, returns false if it isn't waiting
+func (r *RoomManager) admitWaiting(roomName livekit.RoomName, identity livekit.ParticipantIdentity, admit *rpc.AdmitParticipantRequest) bool {
+	return r.decideWaiting(roomName, identity, waitingDecision{admit: admit})
+}
+
+// rejectWaiting turns a participant waiting on this node away, returns false if it isn't waiting
+func (r *RoomManager) rejectWaiting(roomName livekit.RoomName, identity livekit.ParticipantIdentity, reason livekit.DisconnectReason) bool {
+	return r.decideWaiting(roomName, identity, waitingDecision{reason: reason})
+}
+
+func (r *RoomManager) rejectAllWaiting(roomName livekit.RoomName, reason livekit.DisconnectReason) {
+	r.lock.RLock()
+	identities := make([]livekit.ParticipantIdentity, 0, len(r.waiting[roomName]))
+	for identity := range r.waiting[roomName] {
+		identities = append(identities, identity)
+	}
+	r.lock.RUnlock()
+
+	for _, identity := range identities {
+		r.rejectWaiting(roomName, identity, reason)
+	}
+}
+
+func (r *RoomManager) decideWaiting(roomName livekit.RoomName, identity livekit.ParticipantIdentity, decision waitingDecision) bool {
+	wp := r.getWaiting(roomName, identity)
+	if wp == nil {
+		return false
+	}
+	select {
+	case wp.decisions <- decision:
+	default:
+		// already decided on
+	}
+	return true
+}
+
+// announceWaiting sends room admins the info of a participant waiting on this node, as it changes. Those hosted by
+// other nodes of the room are sent it through them
+func (r *RoomManager) announceWaiting(room *rtc.Room, info *livekit.ParticipantInfo) {
+	r.announceWaitingLocally(room, info)
+
+	if waitingRoom, relay := r.getWaitingRoom(), r.getRelay(); waitingRoom != nil && relay.Enabled() {
+		waitingRoom.announce(room.Name(), info, relay.roomNodes(room.Name()))
+	}
+}
+
+// announceWaitingLocally sends room admins hosted by this node the info of a waiting participant. Relayed
+// participants are never admins
+func (r *RoomManager) announceWaitingLocally(room *rtc.Room, info *livekit.ParticipantInfo) {
+	for _, p := range room.GetParticipants() {
+		if isRoomAdmin(p) {
+			_ = p.SendParticipantUpdate([]*livekit.ParticipantInfo{info})
+		}
+	}
+}
+
+// announceWaitingTo sends a room admin joining the room participants waiting to be admitted, on any node
+func (r *RoomManager) announceWaitingTo(ctx context.Context, room *rtc.Room, participant types.LocalParticipant) {
+	if !isRoomAdmin(participant) {
+		return
+	}
+
+	waiting, err := r.roomStore.ListWaitingParticipants(ctx, room.Name())
+	if err != nil {
+		logger.Errorw("could not list waiting participants", err, "room", room.Name())
+		return
+	}
+	infos := make([]*livekit.ParticipantInfo, 0, len(waiting))
+	for _, wp := range waiting {
+		infos = append(infos, wp.Participant)
+	}
+	if len(infos) != 0 {
+		_ = participant.SendParticipantUpdate(infos)
+	}
+}
+
+func isRoomAdmin(p types.LocalParticipant) bool {
+	video := p.ClaimGrants().Video
+	return video != nil && video.RoomAdmin
+}
+
+// applyAdmission applies the name, metadata and permission a participant is admitted with to its grants
+func applyAdmission(pi *routing.ParticipantInit, admit *rpc.AdmitParticipantRequest) {
+	grants := pi.Grants.Clone()
+	if admit.Name != "" {
+		pi.Name = livekit.ParticipantName(admit.Name)
+		grants.Name = admit.Name
+	}
+	if admit.Metadata != "" {
+		grants.Metadata = admit.Metadata
+	}
+	if permission := admit.Permission; permission != nil {
+		grants.Video.SetCanSubscribe(permission.CanSubscribe)
+		grants.Video.SetCanPublish(permission.CanPublish)
+		grants.Video.SetCanPublishData(permission.CanPublishData)
+		grants.Video.Hidden = permission.Hidden
+		grants.Video.Recorder = permission.Recorder
+	}
+	pi.Grants = grants
+}
@@ -0,0 +1,271 @@
+package service
+
+import (
+	"context"
+	"strconv"
+	"sync"
+
+	"github.com/twitchtv/twirp"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+	"github.com/livekit/protocol/utils"
+
+	"github.com/livekit/livekit-server/pkg/audit"
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/routing"
+	"github.com/livekit/livekit-server/pkg/rpc"
+	"github.com/livekit/livekit-server/pkg/rtc"
+)
+
+// WaitingRoomMessages to a node are published on its channel
+func waitingRoomNodeChannel(nodeID livekit.NodeID) string {
+	return "waiting_room_channel:" + string(nodeID)
+}
+
+// WaitingRoomService decides on participants waiting to join gated rooms. Waiting participants are stored with the
+// node they are connected to, which decisions are sent to over the message bus. Without a message bus, as without
+// redis, participants can only be waiting on this node.
+type WaitingRoomService struct {
+	conf        config.RoomConfig
+	roomStore   ObjectStore
+	bus         utils.MessageBus
+	currentNode routing.LocalNode
+	tenancy     *Tenancy
+	auditLogger *audit.Logger
+
+	lock        sync.RWMutex
+	roomManager *RoomManager
+	pubsub      utils.PubSub
+}
+
+func NewWaitingRoomService(
+	conf config.RoomConfig,
+	roomStore ObjectStore,
+	bus utils.MessageBus,
+	currentNode routing.LocalNode,
+	tenancy *Tenancy,
+	auditLogger *audit.Logger,
+) *WaitingRoomService {
+	return &WaitingRoomService{
+		conf:        conf,
+		roomStore:   roomStore,
+		bus:         bus,
+		currentNode: currentNode,
+		tenancy:     tenancy,
+		auditLogger: auditLogger,
+	}
+}
+
+// Start receives messages for this node, applying them to participants waiting in rooms of the room manager
+func (s *WaitingRoomService) Start(roomManager *RoomManager) error {
+	s.lock.Lock()
+	s.roomManager = roomManager
+	s.lock.Unlock()
+	roomManager.setWaitingRoom(s)
+
+	if s.bus == nil {
+		return nil
+	}
+	pubsub, err := s.bus.Subscribe(context.Background(), waitingRoomNodeChannel(livekit.NodeID(s.currentNode.Id)))
+	if err != nil {
+		return err
+	}
+	s.lock.Lock()
+	s.pubsub = pubsub
+	s.lock.Unlock()
+
+	go s.subscribeWorker(pubsub)
+	return nil
+}
+
+func (s *WaitingRoomService) Stop() {
+	s.lock.Lock()
+	pubsub := s.pubsub
+	s.pubsub = nil
+	s.lock.Unlock()
+
+	if pubsub != nil {
+		_ = pubsub.Close()
+	}
+}
+
+func (s *WaitingRoomService) ListWaitingParticipants(ctx context.Context, req *rpc.ListWaitingParticipantsRequest) (*rpc.ListWaitingParticipantsResponse, error) {
+	if err := ensureRoomAdminPermission(ctx, s.tenancy, livekit.RoomName(req.Room)); err != nil {
+		return nil, err
+	}
+
+	waiting, err := s.roomStore.ListWaitingParticipants(ctx, livekit.RoomName(req.Room))
+	if err != nil {
+		return nil, err
+	}
+
+	res := &rpc.ListWaitingParticipantsResponse{
+		Participants: make([]*livekit.ParticipantInfo, 0, len(waiting)),
+	}
+	for _, wp := range waiting {
+		res.Participants = append(res.Participants, wp.Participant)
+	}
+	return res, nil
+}
+
+func (s *WaitingRoomService) AdmitParticipant(ctx context.Context, req *rpc.AdmitParticipantRequest) (participant *livekit.ParticipantInfo, err error) {
+	defer func() {
+		auditOperation(ctx, s.auditLogger, auditServiceWaitingRoom, "AdmitParticipant", req, participant, err)
+	}()
+
+	if s.conf.MaxMetadataSize > 0 && len(req.Metadata) > int(s.conf.MaxMetadataSize) {
+		return nil, twirp.InvalidArgumentError(ErrMetadataExceedsLimits.Error(), strconv.Itoa(int(s.conf.MaxMetadataSize)))
+	}
+	roomName := livekit.RoomName(req.Room)
+	wp, err := s.loadWaiting(ctx, roomName, livekit.ParticipantIdentity(req.Identity))
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.send(ctx, livekit.NodeID(wp.NodeId), &rpc.WaitingRoomMessage{
+		Room: req.Room,
+		Message: &rpc.WaitingRoomMessage_Admit{
+			Admit: req,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = confirmExecution(func() error {
+		participant, err = s.roomStore.LoadParticipant(ctx, roomName, livekit.ParticipantIdentity(req.Identity))
+		if err != nil {
+			return err
+		}
+		if participant.Sid != wp.Participant.Sid {
+			return ErrOperationFailed
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return participant, nil
+}
+
+func (s *WaitingRoomService) RejectParticipant(ctx context.Context, req *rpc.RejectParticipantRequest) (res *rpc.RejectParticipantResponse, err error) {
+	defer func() {
+		auditOperation(ctx, s.auditLogger, auditServiceWaitingRoom, "RejectParticipant", req, res, err)
+	}()
+
+	roomName := livekit.RoomName(req.Room)
+	wp, err := s.loadWaiting(ctx, roomName, livekit.ParticipantIdentity(req.Identity))
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.send(ctx, livekit.NodeID(wp.NodeId), &rpc.WaitingRoomMessage{
+		Room: req.Room,
+		Message: &rpc.WaitingRoomMessage_Reject{
+			Reject: req,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = confirmExecution(func() error {
+		current, err := s.roomStore.LoadWaitingParticipant(ctx, roomName, livekit.ParticipantIdentity(req.Identity))
+		if err == ErrParticipantNotWaiting {
+			return nil
+		} else if err != nil {
+			return err
+		} else if current.Participant.Sid != wp.Participant.Sid {
+			// replaced by another participant with its identity
+			return nil
+		} else {
+			return ErrOperationFailed
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &rpc.RejectParticipantResponse{}, nil
+}
+
+func (s *WaitingRoomService) loadWaiting(ctx context.Context, roomName livekit.RoomName, identity livekit.ParticipantIdentity) (*rpc.WaitingParticipant, error) {
+	if err := ensureRoomAdminPermission(ctx, s.tenancy, roomName); err != nil {
+		return nil, err
+	}
+
+	wp, err := s.roomStore.LoadWaitingParticipant(ctx, roomName, identity)
+	if err == ErrParticipantNotWaiting {
+		return nil, twirp.NotFoundError(err.Error())
+	} else if err != nil {
+		return nil, err
+	}
+	return wp, nil
+}
+
+// announce sends info of a participant that started or stopped waiting to the nodes given, for their room admins
+func (s *WaitingRoomService) announce(roomName livekit.RoomName, info *livekit.ParticipantInfo, nodeIDs []livekit.NodeID) {
+	msg := &rpc.WaitingRoomMessage{
+		Room: string(roomName),
+		Message: &rpc.WaitingRoomMessage_Update{
+			Update: info,
+		},
+	}
+	for _, nodeID := range nodeIDs {
+		if nodeID == livekit.NodeID(s.currentNode.Id) {
+			continue
+		}
+		if err := s.send(context.Background(), nodeID, msg); err != nil {
+			logger.Errorw("could not announce waiting participant", err, "room", roomName, "nodeID", nodeID)
+		}
+	}
+}
+
+func (s *WaitingRoomService) send(ctx context.Context, nodeID livekit.NodeID, msg *rpc.WaitingRoomMessage) error {
+	if s.bus == nil {
+		// nodes can't be reached, participants wait on this one
+		s.handleMessage(msg)
+		return nil
+	}
+	return s.bus.Publish(ctx, waitingRoomNodeChannel(nodeID), msg)
+}
+
+func (s *WaitingRoomService) subscribeWorker(pubsub utils.PubSub) {
+	defer rtc.Recover()
+
+	for obj := range pubsub.Channel() {
+		if obj == nil {
+			return
+		}
+		msg := &rpc.WaitingRoomMessage{}
+		if err := proto.Unmarshal(pubsub.Payload(obj), msg); err != nil {
+			logger.Errorw("could not unmarshal waiting room message", err)
+			continue
+		}
+		s.handleMessage(msg)
+	}
+}
+
+func (s *WaitingRoomService) handleMessage(msg *rpc.WaitingRoomMessage) {
+	s.lock.RLock()
+	roomManager := s.roomManager
+	s.lock.RUnlock()
+	if roomManager == nil {
+		return
+	}
+
+	roomName := livekit.RoomName(msg.Room)
+	switch m := msg.Message.(type) {
+	case *rpc.WaitingRoomMessage_Admit:
+		roomManager.admitWaiting(roomName, livekit.ParticipantIdentity(m.Admit.Identity), m.Admit)
+	case *rpc.WaitingRoomMessage_Reject:
+		roomManager.rejectWaiting(roomName, livekit.ParticipantIdentity(m.Reject.Identity), livekit.DisconnectReason_PARTICIPANT_REMOVED)
+	case *rpc.WaitingRoomMessage_Update:
+		if room := roomManager.GetRoom(context.Background(), roomName); room != nil {
+			roomManager.announceWaitingLocally(room, m.Update)
+		}
+	}
+}
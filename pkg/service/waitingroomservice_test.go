@@ -0,0 +1,165 @@
+package service_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/twitchtv/twirp"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/utils"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/rpc"
+	"github.com/livekit/livekit-server/pkg/service"
+	"github.com/livekit/livekit-server/pkg/service/servicefakes"
+)
+
+func TestWaitingParticipants(t *testing.T) {
+	waiting := &rpc.WaitingParticipant{
+		Participant: &livekit.ParticipantInfo{
+			Sid:      "PA_waiting",
+			Identity: "123",
+			State:    livekit.ParticipantInfo_JOINING,
+		},
+		NodeId: "node-2",
+	}
+	joined := &livekit.ParticipantInfo{
+		Sid:      "PA_waiting",
+		Identity: "123",
+		State:    livekit.ParticipantInfo_JOINED,
+		JoinedAt: 1,
+	}
+	ctx := service.WithGrants(context.Background(), &auth.ClaimGrants{
+		Video: &auth.VideoGrant{RoomAdmin: true, Room: "testroom"},
+	})
+
+	t.Run("lists waiting participants", func(t *testing.T) {
+		svc := newTestWaitingRoomService()
+		svc.store.ListWaitingParticipantsReturns([]*rpc.WaitingParticipant{waiting}, nil)
+		res, err := svc.ListWaitingParticipants(ctx, &rpc.ListWaitingParticipantsRequest{Room: "testroom"})
+		require.NoError(t, err)
+		require.Equal(t, []*livekit.ParticipantInfo{waiting.Participant}, res.Participants)
+	})
+
+	t.Run("admits participants on the node they wait on", func(t *testing.T) {
+		svc := newTestWaitingRoomService()
+		svc.store.LoadWaitingParticipantReturns(waiting, nil)
+		svc.store.LoadParticipantReturns(joined, nil)
+		req := &rpc.AdmitParticipantRequest{
+			Room:       "testroom",
+			Identity:   "123",
+			Permission: &livekit.ParticipantPermission{CanSubscribe: true},
+		}
+		info, err := svc.AdmitParticipant(ctx, req)
+		require.NoError(t, err)
+		require.Equal(t, joined, info)
+
+		published := svc.bus.getPublished()
+		require.Len(t, published, 1)
+		require.Equal(t, "waiting_room_channel:node-2", published[0].channel)
+		require.Equal(t, "testroom", published[0].msg.Room)
+		require.True(t, proto.Equal(req, published[0].msg.GetAdmit()))
+	})
+
+	t.Run("rejects participants on the node they wait on", func(t *testing.T) {
+		svc := newTestWaitingRoomService()
+		svc.store.LoadWaitingParticipantReturnsOnCall(0, waiting, nil)
+		svc.store.LoadWaitingParticipantReturnsOnCall(1, nil, service.ErrParticipantNotWaiting)
+		req := &rpc.RejectParticipantRequest{
+			Room:     "testroom",
+			Identity: "123",
+		}
+		_, err := svc.RejectParticipant(ctx, req)
+		require.NoError(t, err)
+
+		published := svc.bus.getPublished()
+		require.Len(t, published, 1)
+		require.Equal(t, "waiting_room_channel:node-2", published[0].channel)
+		require.True(t, proto.Equal(req, published[0].msg.GetReject()))
+	})
+
+	t.Run("participants not waiting", func(t *testing.T) {
+		svc := newTestWaitingRoomService()
+		svc.store.LoadWaitingParticipantReturns(nil, service.ErrParticipantNotWaiting)
+		_, err := svc.AdmitParticipant(ctx, &rpc.AdmitParticipantRequest{Room: "testroom", Identity: "123"})
+		terr, ok := err.(twirp.Error)
+		require.True(t, ok)
+		require.Equal(t, twirp.NotFound, terr.Code())
+
+		_, err = svc.RejectParticipant(ctx, &rpc.RejectParticipantRequest{Room: "testroom", Identity: "123"})
+		terr, ok = err.(twirp.Error)
+		require.True(t, ok)
+		require.Equal(t, twirp.NotFound, terr.Code())
+		require.Empty(t, svc.bus.getPublished())
+	})
+
+	t.Run("missing permissions", func(t *testing.T) {
+		svc := newTestWaitingRoomService()
+		svc.store.LoadWaitingParticipantReturns(waiting, nil)
+		ctx := service.WithGrants(context.Background(), &auth.ClaimGrants{Video: &auth.VideoGrant{}})
+		_, err := svc.AdmitParticipant(ctx, &rpc.AdmitParticipantRequest{Room: "testroom", Identity: "123"})
+		terr, ok := err.(twirp.Error)
+		require.True(t, ok)
+		require.Equal(t, twirp.Unauthenticated, terr.Code())
+
+		_, err = svc.ListWaitingParticipants(ctx, &rpc.ListWaitingParticipantsRequest{Room: "testroom"})
+		terr, ok = err.(twirp.Error)
+		require.True(t, ok)
+		require.Equal(t, twirp.Unauthenticated, terr.Code())
+		require.Empty(t, svc.bus.getPublished())
+	})
+}
+
+func newTestWaitingRoomService() *TestWaitingRoomService {
+	store := &servicefakes.FakeObjectStore{}
+	bus := &testMessageBus{}
+	svc := service.NewWaitingRoomService(config.RoomConfig{}, store, bus, &livekit.Node{Id: "node-1"}, nil, nil)
+	return &TestWaitingRoomService{
+		WaitingRoomService: svc,
+		store:              store,
+		bus:                bus,
+	}
+}
+
+type TestWaitingRoomService struct {
+	*service.WaitingRoomService
+	store *servicefakes.FakeObjectStore
+	bus   *testMessageBus
+}
+
+type publishedMessage struct {
+	channel string
+	msg     *rpc.WaitingRoomMessage
+}
+
+// testMessageBus records messages published, without delivering them
+type testMessageBus struct {
+	lock      sync.Mutex
+	published []publishedMessage
+}
+
+func (b *testMessageBus) Subscribe(_ context.Context, _ string) (utils.PubSub, error) {
+	return nil, nil
+}
+
+func (b *testMessageBus) SubscribeQueue(_ context.Context, _ string) (utils.PubSub, error) {
+	return nil, nil
+}
+
+func (b *testMessageBus) Publish(_ context.Context, channel string, msg proto.Message) error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.published = append(b.published, publishedMessage{channel: channel, msg: msg.(*rpc.WaitingRoomMessage)})
+	return nil
+}
+
+func (b *testMessageBus) getPublished() []publishedMessage {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.published
+}
@@ -0,0 +1,196 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"sort"
+
+	"github.com/pion/sdp/v3"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/utils"
+
+	"github.com/livekit/livekit-server/pkg/rtc"
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+)
+
+const WHEPPathPrefix = "/whep/v1"
+
+// WHEPService plays tracks to WHEP (WebRTC-HTTP egress protocol) viewers. Each session is a hidden participant
+// that only subscribes, to tracks named in the request's query.
+//
+//	track: sid or name of a track, may be repeated. Without it, every track is requested
+//	participant: identity of the publisher, to select tracks from only one participant
+type WHEPService struct {
+	rtcService *RTCService
+	sessions   *httpSessions
+}
+
+func NewWHEPService(rtcService *RTCService) *WHEPService {
+	return &WHEPService{
+		rtcService: rtcService,
+		sessions:   newHTTPSessions(rtcService, "whep", WHEPPathPrefix),
+	}
+}
+
+func (s *WHEPService) PathPrefix() string {
+	return WHEPPathPrefix
+}
+
+func (s *WHEPService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != WHEPPathPrefix {
+		s.sessions.serveResource(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	// sessions are signal connections, limited as connections to /rtc are
+	if err := s.rtcService.rateLimiter.AllowSignalConnection(r); err != nil {
+		handleError(w, http.StatusTooManyRequests, err)
+		return
+	}
+
+	offer, code, err := readOffer(r)
+	if err != nil {
+		handleError(w, code, err)
+		return
+	}
+
+	roomName, pi, code, err := s.rtcService.validate(r)
+	if err != nil {
+		handleError(w, code, err)
+		return
+	}
+	if !pi.Grants.Video.GetCanSubscribe() {
+		handleError(w, http.StatusUnauthorized, rtc.ErrPermissionDenied)
+		return
+	}
+	// subscribe only, and not seen by others. A token can be used by many viewers, each gets a unique identity
+	pi.Grants.Video.Hidden = true
+	pi.Grants.Video.SetCanPublish(false)
+	pi.Grants.Video.SetCanPublishData(false)
+	pi.Identity = livekit.ParticipantIdentity(pi.Grants.Identity + "#" + utils.NewGuid(""))
+	pi.AutoSubscribe = false
+	pi.Reconnect = false
+	pi.ID = ""
+	pi.Client.Protocol = types.CurrentProtocol
+	pi.ClientOffersSubscriber = true
+
+	audioSlots, videoSlots, err := parseWHEPOffer(offer)
+	if err != nil {
+		handleError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	trackIDs, err := s.findTracks(
+		r.Context(),
+		roomName,
+		livekit.ParticipantIdentity(r.URL.Query().Get("participant")),
+		r.URL.Query()["track"],
+		audioSlots,
+		videoSlots,
+	)
+	if err != nil {
+		handleError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if len(trackIDs) == 0 {
+		handleError(w, http.StatusNotFound, ErrTrackNotFound)
+		return
+	}
+
+	requests := []*livekit.SignalRequest{
+		{
+			Message: &livekit.SignalRequest_Subscription{
+				Subscription: &livekit.UpdateSubscription{
+					TrackSids: trackIDs,
+					Subscribe: true,
+				},
+			},
+		},
+	}
+	s.sessions.start(w, r, roomName, pi, livekit.SignalTarget_SUBSCRIBER, requests, offer)
+}
+
+// findTracks returns requested tracks, as many of each kind as the offer can receive. Publishers are taken in the
+// order they joined the room, and each publisher's tracks in the order it lists them.
+func (s *WHEPService) findTracks(
+	ctx context.Context,
+	roomName livekit.RoomName,
+	identity livekit.ParticipantIdentity,
+	names []string,
+	audioSlots, videoSlots int,
+) ([]string, error) {
+	participants, err := s.rtcService.store.ListParticipants(ctx, roomName)
+	if err != nil {
+		return nil, err
+	}
+	// the store lists participants in no particular order
+	sort.SliceStable(participants, func(i, j int) bool {
+		if participants[i].JoinedAt != participants[j].JoinedAt {
+			return participants[i].JoinedAt < participants[j].JoinedAt
+		}
+		return participants[i].Identity < participants[j].Identity
+	})
+
+	requested := make(map[string]bool, len(names))
+	for _, name := range names {
+		requested[name] = true
+	}
+
+	var trackIDs []string
+	for _, p := range participants {
+		if identity != "" && livekit.ParticipantIdentity(p.Identity) != identity {
+			continue
+		}
+		for _, track := range p.Tracks {
+			if len(requested) != 0 && !requested[track.Sid] && !requested[track.Name] {
+				continue
+			}
+			switch track.Type {
+			case livekit.TrackType_AUDIO:
+				if audioSlots == 0 {
+					continue
+				}
+				audioSlots--
+			case livekit.TrackType_VIDEO:
+				if videoSlots == 0 {
+					continue
+				}
+				videoSlots--
+			default:
+				continue
+			}
+			trackIDs = append(trackIDs, track.Sid)
+		}
+	}
+	return trackIDs, nil
+}
+
+// parseWHEPOffer returns the number of audio and video tracks the client can receive
+func parseWHEPOffer(offer string) (int, int, error) {
+	sections, err := mediaSections(offer)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	audio, video := 0, 0
+	for _, md := range sections {
+		if _, ok := md.Attribute(sdp.AttrKeySendOnly); ok {
+			continue
+		}
+		if md.MediaName.Media == "audio" {
+			audio++
+		} else {
+			video++
+		}
+	}
+
+	if audio == 0 && video == 0 {
+		return 0, 0, ErrNoMediaRequested
+	}
+	return audio, video, nil
+}
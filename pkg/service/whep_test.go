@@ -0,0 +1,153 @@
+package service_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/routing"
+	"github.com/livekit/livekit-server/pkg/rtc"
+	"github.com/livekit/livekit-server/pkg/service"
+	"github.com/livekit/livekit-server/pkg/service/servicefakes"
+)
+
+const whepOffer = `v=0
+o=- 1 1 IN IP4 127.0.0.1
+s=-
+t=0 0
+a=group:BUNDLE 0
+m=video 9 UDP/TLS/RTP/SAVPF 96
+c=IN IP4 0.0.0.0
+a=mid:0
+a=recvonly
+a=rtpmap:96 VP8/90000
+`
+
+const whepAnswer = `v=0
+o=- 2 2 IN IP4 127.0.0.1
+s=-
+t=0 0
+a=group:BUNDLE 0
+m=video 9 UDP/TLS/RTP/SAVPF 96
+c=IN IP4 0.0.0.0
+a=mid:0
+a=sendonly
+a=rtpmap:96 VP8/90000
+`
+
+func TestWHEPService(t *testing.T) {
+	store := &servicefakes.FakeServiceStore{}
+	// listed out of join order
+	store.ListParticipantsReturns([]*livekit.ParticipantInfo{
+		{
+			Identity: "camera1",
+			JoinedAt: 2,
+			Tracks: []*livekit.TrackInfo{
+				{Sid: "TR_mic1", Name: "mic", Type: livekit.TrackType_AUDIO},
+				{Sid: "TR_cam1", Name: "cam", Type: livekit.TrackType_VIDEO},
+			},
+		},
+		{
+			Identity: "camera2",
+			JoinedAt: 1,
+			Tracks: []*livekit.TrackInfo{
+				{Sid: "TR_cam2", Name: "cam", Type: livekit.TrackType_VIDEO},
+			},
+		},
+	}, nil)
+
+	h := newHTTPSessionTest(t, "PA_viewer",
+		func(pi routing.ParticipantInit) {
+			require.True(t, pi.ClientOffersSubscriber)
+			require.True(t, pi.Grants.Video.Hidden)
+			require.False(t, pi.Grants.Video.GetCanPublish())
+			require.False(t, pi.AutoSubscribe)
+			require.True(t, strings.HasPrefix(string(pi.Identity), "viewer#"))
+		},
+		func(_ *livekit.SessionDescription) []*livekit.SignalResponse {
+			return []*livekit.SignalResponse{
+				// publisher candidates aren't for the viewer
+				trickleResponse("candidate:9 1 udp 2130706431 192.0.2.9 7882 typ host", livekit.SignalTarget_PUBLISHER),
+				trickleResponse("candidate:1 1 udp 2130706431 192.0.2.1 7882 typ host", livekit.SignalTarget_SUBSCRIBER),
+				answerResponse(whepAnswer),
+			}
+		},
+	)
+	whepService := service.NewWHEPService(h.newRTCService(store))
+
+	newRequest := func(method, path, contentType, body string, canSubscribe bool) *http.Request {
+		grant := &auth.VideoGrant{RoomJoin: true, Room: "room1"}
+		grant.SetCanSubscribe(canSubscribe)
+		return newHTTPSessionRequest(method, path, contentType, body, "viewer", grant)
+	}
+
+	t.Run("requires subscribe permission", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		whepService.ServeHTTP(w, newRequest(http.MethodPost, service.WHEPPathPrefix+"?track=cam", "application/sdp", whepOffer, false))
+		require.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("rejects unknown tracks", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		whepService.ServeHTTP(w, newRequest(http.MethodPost, service.WHEPPathPrefix+"?track=screen", "application/sdp", whepOffer, true))
+		require.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	var location string
+	t.Run("subscribes to requested tracks", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		path := service.WHEPPathPrefix + "?track=cam&track=mic&participant=camera1"
+		whepService.ServeHTTP(w, newRequest(http.MethodPost, path, "application/sdp", whepOffer, true))
+		require.Equal(t, http.StatusCreated, w.Code)
+		location = w.Header().Get("Location")
+		require.True(t, strings.HasPrefix(location, service.WHEPPathPrefix+"/PA_viewer/"))
+		require.Contains(t, w.Body.String(), "a=candidate:1 1 udp 2130706431 192.0.2.1 7882 typ host")
+		require.NotContains(t, w.Body.String(), "192.0.2.9")
+
+		// the offer receives a single video track
+		subscription := h.nextRequest().GetSubscription()
+		require.True(t, subscription.Subscribe)
+		require.Equal(t, []string{"TR_cam1"}, subscription.TrackSids)
+		require.Equal(t, whepOffer, h.nextRequest().GetOffer().Sdp)
+	})
+
+	t.Run("trickles remote candidates to subscriber", func(t *testing.T) {
+		frag := "a=mid:0\r\na=candidate:2 1 udp 2130706431 192.0.2.2 5000 typ host\r\n"
+		w := httptest.NewRecorder()
+		whepService.ServeHTTP(w, newRequest(http.MethodPatch, location, "application/trickle-ice-sdpfrag", frag, true))
+		require.Equal(t, http.StatusNoContent, w.Code)
+
+		trickle := h.nextRequest().GetTrickle()
+		require.Equal(t, livekit.SignalTarget_SUBSCRIBER, trickle.Target)
+		candidate, err := rtc.FromProtoTrickle(trickle)
+		require.NoError(t, err)
+		require.Equal(t, "candidate:2 1 udp 2130706431 192.0.2.2 5000 typ host", candidate.Candidate)
+	})
+
+	t.Run("deletes session", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		whepService.ServeHTTP(w, newRequest(http.MethodDelete, service.WHEPPathPrefix+"/PA_viewer", "", "", true))
+		require.Equal(t, http.StatusNotFound, w.Code)
+
+		w = httptest.NewRecorder()
+		whepService.ServeHTTP(w, newRequest(http.MethodDelete, location, "", "", true))
+		require.Equal(t, http.StatusOK, w.Code)
+		require.NotNil(t, h.nextRequest().GetLeave())
+		require.True(t, h.reqSink.IsClosed())
+	})
+
+	t.Run("takes tracks of publishers in join order", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		whepService.ServeHTTP(w, newRequest(http.MethodPost, service.WHEPPathPrefix+"?track=cam", "application/sdp", whepOffer, true))
+		require.Equal(t, http.StatusCreated, w.Code)
+
+		require.Equal(t, []string{"TR_cam2"}, h.nextRequest().GetSubscription().TrackSids)
+		require.Equal(t, whepOffer, h.nextRequest().GetOffer().Sdp)
+	})
+}
@@ -0,0 +1,124 @@
+package service
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/pion/sdp/v3"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/rtc"
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+)
+
+const WHIPPathPrefix = "/whip/v1"
+
+// WHIPService accepts media from WHIP (WebRTC-HTTP ingestion protocol) encoders. Each session is a participant
+// that only publishes, negotiated with a single offer and answer.
+type WHIPService struct {
+	rtcService *RTCService
+	sessions   *httpSessions
+}
+
+func NewWHIPService(rtcService *RTCService) *WHIPService {
+	return &WHIPService{
+		rtcService: rtcService,
+		sessions:   newHTTPSessions(rtcService, "whip", WHIPPathPrefix),
+	}
+}
+
+func (s *WHIPService) PathPrefix() string {
+	return WHIPPathPrefix
+}
+
+func (s *WHIPService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != WHIPPathPrefix {
+		s.sessions.serveResource(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	// sessions are signal connections, limited as connections to /rtc are
+	if err := s.rtcService.rateLimiter.AllowSignalConnection(r); err != nil {
+		handleError(w, http.StatusTooManyRequests, err)
+		return
+	}
+
+	offer, code, err := readOffer(r)
+	if err != nil {
+		handleError(w, code, err)
+		return
+	}
+
+	roomName, pi, code, err := s.rtcService.validate(r)
+	if err != nil {
+		handleError(w, code, err)
+		return
+	}
+	if !pi.Grants.Video.GetCanPublish() {
+		handleError(w, http.StatusUnauthorized, rtc.ErrPermissionDenied)
+		return
+	}
+	// publish only
+	pi.Grants.Video.SetCanSubscribe(false)
+	pi.AutoSubscribe = false
+	pi.Reconnect = false
+	pi.ID = ""
+	pi.Client.Protocol = types.CurrentProtocol
+
+	tracks, err := parseWHIPOffer(offer)
+	if err != nil {
+		handleError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	requests := make([]*livekit.SignalRequest, 0, len(tracks))
+	for _, track := range tracks {
+		requests = append(requests, &livekit.SignalRequest{
+			Message: &livekit.SignalRequest_AddTrack{AddTrack: track},
+		})
+	}
+	s.sessions.start(w, r, roomName, pi, livekit.SignalTarget_PUBLISHER, requests, offer)
+}
+
+// parseWHIPOffer returns tracks to announce for media sections the client sends
+func parseWHIPOffer(offer string) ([]*livekit.AddTrackRequest, error) {
+	sections, err := mediaSections(offer)
+	if err != nil {
+		return nil, err
+	}
+
+	var tracks []*livekit.AddTrackRequest
+	for _, md := range sections {
+		if _, ok := md.Attribute(sdp.AttrKeyRecvOnly); ok {
+			continue
+		}
+
+		track := &livekit.AddTrackRequest{Name: md.MediaName.Media}
+		if md.MediaName.Media == "audio" {
+			track.Type = livekit.TrackType_AUDIO
+			track.Source = livekit.TrackSource_MICROPHONE
+		} else {
+			track.Type = livekit.TrackType_VIDEO
+			track.Source = livekit.TrackSource_CAMERA
+		}
+
+		// pion identifies a track by the track ID of msid, and falls back to a pending track of the same kind
+		track.Cid, _ = md.Attribute(sdp.AttrKeyMID)
+		if msid, ok := md.Attribute(sdp.AttrKeyMsid); ok {
+			if parts := strings.Fields(msid); len(parts) == 2 {
+				track.Cid = parts[1]
+			}
+		}
+		tracks = append(tracks, track)
+	}
+
+	if len(tracks) == 0 {
+		return nil, ErrNoMediaOffered
+	}
+	return tracks, nil
+}
@@ -0,0 +1,137 @@
+package service_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/routing"
+	"github.com/livekit/livekit-server/pkg/rtc"
+	"github.com/livekit/livekit-server/pkg/service"
+)
+
+const whipOffer = `v=0
+o=- 1 1 IN IP4 127.0.0.1
+s=-
+t=0 0
+a=group:BUNDLE 0 1
+m=audio 9 UDP/TLS/RTP/SAVPF 111
+c=IN IP4 0.0.0.0
+a=mid:0
+a=sendonly
+a=msid:stream audio-track
+a=rtpmap:111 opus/48000/2
+m=video 9 UDP/TLS/RTP/SAVPF 96
+c=IN IP4 0.0.0.0
+a=mid:1
+a=sendonly
+a=msid:stream video-track
+a=rtpmap:96 VP8/90000
+`
+
+const whipAnswer = `v=0
+o=- 2 2 IN IP4 127.0.0.1
+s=-
+t=0 0
+a=group:BUNDLE 0 1
+m=audio 9 UDP/TLS/RTP/SAVPF 111
+c=IN IP4 0.0.0.0
+a=mid:0
+a=recvonly
+a=rtpmap:111 opus/48000/2
+m=video 9 UDP/TLS/RTP/SAVPF 96
+c=IN IP4 0.0.0.0
+a=mid:1
+a=recvonly
+a=rtpmap:96 VP8/90000
+`
+
+func TestWHIPService(t *testing.T) {
+	h := newHTTPSessionTest(t, "PA_test",
+		func(pi routing.ParticipantInit) {
+			require.False(t, pi.Grants.Video.GetCanSubscribe())
+			require.False(t, pi.AutoSubscribe)
+		},
+		func(_ *livekit.SessionDescription) []*livekit.SignalResponse {
+			return []*livekit.SignalResponse{
+				answerResponse(whipAnswer),
+				trickleResponse("candidate:1 1 udp 2130706431 192.0.2.1 7882 typ host", livekit.SignalTarget_PUBLISHER),
+			}
+		},
+	)
+	whipService := service.NewWHIPService(h.newRTCService(nil))
+
+	newRequest := func(method, path, contentType, body, identity string) *http.Request {
+		return newHTTPSessionRequest(method, path, contentType, body, identity, &auth.VideoGrant{RoomJoin: true, Room: "room1"})
+	}
+
+	t.Run("rejects content that isn't SDP", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		whipService.ServeHTTP(w, newRequest(http.MethodPost, service.WHIPPathPrefix, "text/plain", whipOffer, "encoder"))
+		require.Equal(t, http.StatusUnsupportedMediaType, w.Code)
+	})
+
+	var location string
+	t.Run("publishes offered tracks", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		whipService.ServeHTTP(w, newRequest(http.MethodPost, service.WHIPPathPrefix, "application/sdp", whipOffer, "encoder"))
+		require.Equal(t, http.StatusCreated, w.Code)
+		location = w.Header().Get("Location")
+		require.True(t, strings.HasPrefix(location, service.WHIPPathPrefix+"/PA_test/"))
+		require.Greater(t, len(location), len(service.WHIPPathPrefix+"/PA_test/"))
+		require.Contains(t, w.Body.String(), "a=candidate:1 1 udp 2130706431 192.0.2.1 7882 typ host")
+		require.Contains(t, w.Body.String(), "a=end-of-candidates")
+
+		audio := h.nextRequest().GetAddTrack()
+		require.Equal(t, "audio-track", audio.Cid)
+		require.Equal(t, livekit.TrackType_AUDIO, audio.Type)
+		video := h.nextRequest().GetAddTrack()
+		require.Equal(t, "video-track", video.Cid)
+		require.Equal(t, livekit.TrackType_VIDEO, video.Type)
+		require.Equal(t, whipOffer, h.nextRequest().GetOffer().Sdp)
+	})
+
+	t.Run("trickles remote candidates", func(t *testing.T) {
+		frag := "a=mid:0\r\na=candidate:2 1 udp 2130706431 192.0.2.2 5000 typ host\r\n"
+		w := httptest.NewRecorder()
+		whipService.ServeHTTP(w, newRequest(http.MethodPatch, location, "application/trickle-ice-sdpfrag", frag, "encoder"))
+		require.Equal(t, http.StatusNoContent, w.Code)
+
+		trickle := h.nextRequest().GetTrickle()
+		require.Equal(t, livekit.SignalTarget_PUBLISHER, trickle.Target)
+		candidate, err := rtc.FromProtoTrickle(trickle)
+		require.NoError(t, err)
+		require.Equal(t, "candidate:2 1 udp 2130706431 192.0.2.2 5000 typ host", candidate.Candidate)
+		require.Equal(t, "0", *candidate.SDPMid)
+	})
+
+	t.Run("requires session secret", func(t *testing.T) {
+		for _, path := range []string{service.WHIPPathPrefix + "/PA_test", service.WHIPPathPrefix + "/PA_test/guess"} {
+			w := httptest.NewRecorder()
+			whipService.ServeHTTP(w, newRequest(http.MethodDelete, path, "", "", "encoder"))
+			require.Equal(t, http.StatusNotFound, w.Code)
+		}
+	})
+
+	t.Run("deletes session", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		whipService.ServeHTTP(w, newRequest(http.MethodDelete, location, "", "", "someone-else"))
+		require.Equal(t, http.StatusUnauthorized, w.Code)
+
+		w = httptest.NewRecorder()
+		whipService.ServeHTTP(w, newRequest(http.MethodDelete, location, "", "", "encoder"))
+		require.Equal(t, http.StatusOK, w.Code)
+		require.NotNil(t, h.nextRequest().GetLeave())
+		require.True(t, h.reqSink.IsClosed())
+
+		w = httptest.NewRecorder()
+		whipService.ServeHTTP(w, newRequest(http.MethodDelete, location, "", "", "encoder"))
+		require.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
@@ -5,25 +5,26 @@ package service
 
 import (
 	"fmt"
-	"os"
 
 	"github.com/go-redis/redis/v8"
 	"github.com/google/wire"
 	"github.com/pion/turn/v2"
-	"github.com/pkg/errors"
-	"gopkg.in/yaml.v3"
 
 	"github.com/livekit/protocol/auth"
 	"github.com/livekit/protocol/egress"
 	"github.com/livekit/protocol/ingress"
 	"github.com/livekit/protocol/livekit"
 	redisLiveKit "github.com/livekit/protocol/redis"
+	"github.com/livekit/protocol/utils"
 	"github.com/livekit/protocol/webhook"
 
+	"github.com/livekit/livekit-server/pkg/audit"
 	"github.com/livekit/livekit-server/pkg/clientconfiguration"
 	"github.com/livekit/livekit-server/pkg/config"
 	"github.com/livekit/livekit-server/pkg/routing"
 	"github.com/livekit/livekit-server/pkg/telemetry"
+	"github.com/livekit/livekit-server/pkg/telemetry/metering"
+	serverwebhook "github.com/livekit/livekit-server/pkg/webhook"
 )
 
 func InitializeServer(conf *config.Config, currentNode routing.LocalNode) (*LivekitServer, error) {
@@ -32,7 +33,8 @@ func InitializeServer(conf *config.Config, currentNode routing.LocalNode) (*Live
 		createRedisClient,
 		createStore,
 		wire.Bind(new(ServiceStore), new(ObjectStore)),
-		createKeyProvider,
+		NewKeyProvider,
+		wire.Bind(new(auth.KeyProvider), new(*KeyProvider)),
 		createWebhookNotifier,
 		createClientConfiguration,
 		routing.CreateRouter,
@@ -40,9 +42,12 @@ func InitializeServer(conf *config.Config, currentNode routing.LocalNode) (*Live
 		wire.Bind(new(routing.MessageRouter), new(routing.Router)),
 		wire.Bind(new(livekit.RoomService), new(*RoomService)),
 		telemetry.NewAnalyticsService,
+		createMeter,
+		createAuditLogger,
 		telemetry.NewTelemetryService,
 		egress.NewRedisRPCClient,
 		getEgressStore,
+		NewTrackRecorder,
 		NewEgressLauncher,
 		NewEgressService,
 		ingress.NewRedisRPC,
@@ -50,10 +55,23 @@ func InitializeServer(conf *config.Config, currentNode routing.LocalNode) (*Live
 		getIngressConfig,
 		getIngressRPCClient,
 		NewIngressService,
+		getTenantStore,
+		NewTenancy,
 		NewRoomAllocator,
 		NewRoomService,
+		createMessageBus,
+		NewWaitingRoomService,
+		NewAdmission,
+		NewTrustedProxies,
+		NewRateLimiter,
 		NewRTCService,
+		NewWHIPService,
+		NewWHEPService,
 		NewLocalRoomManager,
+		NewCaptureService,
+		NewRTPIngestService,
+		NewRelayService,
+		NewConfigReloader,
 		newTurnAuthHandler,
 		newInProcessTurnServer,
 		NewLivekitServer,
@@ -74,62 +92,171 @@ func getNodeID(currentNode routing.LocalNode) livekit.NodeID {
 	return livekit.NodeID(currentNode.Id)
 }
 
-func createKeyProvider(conf *config.Config) (auth.KeyProvider, error) {
-	// prefer keyfile if set
-	if conf.KeyFile != "" {
-		if st, err := os.Stat(conf.KeyFile); err != nil {
-			return nil, err
-		} else if st.Mode().Perm() != 0600 {
-			return nil, fmt.Errorf("key file must have permission set to 600")
+// createMessageBus returns nil without redis, nodes can't message each other then
+func createMessageBus(rc redis.UniversalClient) utils.MessageBus {
+	if rc == nil {
+		return nil
+	}
+	return utils.NewRedisMessageBus(rc)
+}
+
+func createWebhookNotifier(conf *config.Config, provider auth.KeyProvider, rc redis.UniversalClient, nodeID livekit.NodeID) (webhook.Notifier, error) {
+	wc := conf.WebHook
+	endpoints, err := webhookEndpoints(wc, provider)
+	if err != nil || len(endpoints) == 0 {
+		return nil, err
+	}
+
+	spool, err := createWebhookSpool(wc.Spool, rc, nodeID, serverwebhook.SpoolNameWebhook)
+	if err != nil {
+		return nil, err
+	}
+
+	return serverwebhook.NewQueuedNotifier(endpoints, wc, spool), nil
+}
+
+func createWebhookSpool(conf config.WebHookSpoolConfig, rc redis.UniversalClient, nodeID livekit.NodeID, name string) (serverwebhook.Spool, error) {
+	kind := conf.Kind
+	if kind == "" {
+		switch {
+		case rc != nil:
+			kind = serverwebhook.SpoolKindRedis
+		case conf.Dir != "":
+			kind = serverwebhook.SpoolKindFile
+		default:
+			kind = serverwebhook.SpoolKindMemory
+		}
+	}
+
+	switch kind {
+	case serverwebhook.SpoolKindRedis:
+		if rc == nil {
+			return nil, ErrWebHookSpoolNoRedis
 		}
-		f, err := os.Open(conf.KeyFile)
+		return serverwebhook.NewRedisSpool(rc, nodeID, name, conf.MaxEvents, conf.MaxDeadLetters), nil
+	case serverwebhook.SpoolKindFile:
+		if conf.Dir == "" {
+			return nil, ErrWebHookSpoolDirEmpty
+		}
+		return serverwebhook.NewFileSpool(conf.Dir, conf.MaxEvents, conf.MaxDeadLetters)
+	case serverwebhook.SpoolKindMemory:
+		return serverwebhook.NewMemorySpool(conf.MaxEvents, conf.MaxDeadLetters), nil
+	default:
+		return nil, fmt.Errorf("unknown webhook spool kind: %s", kind)
+	}
+}
+
+func createMeter(conf *config.Config, provider auth.KeyProvider, rc redis.UniversalClient, nodeID livekit.NodeID) (*metering.Meter, error) {
+	mc := conf.Metering
+	if !mc.Enabled {
+		return nil, nil
+	}
+
+	var sink metering.Sink
+	switch mc.Sink {
+	case metering.SinkKindFile:
+		if mc.Path == "" {
+			return nil, ErrMeteringMissingPath
+		}
+		fileSink, err := metering.NewFileSink(mc.Path)
 		if err != nil {
 			return nil, err
 		}
-		defer func() {
-			_ = f.Close()
-		}()
-		decoder := yaml.NewDecoder(f)
-		if err = decoder.Decode(conf.Keys); err != nil {
+		sink = fileSink
+	case metering.SinkKindRedis:
+		if rc == nil {
+			return nil, ErrMeteringNoRedis
+		}
+		sink = metering.NewRedisSink(rc, mc.MaxRecords)
+	case metering.SinkKindWebhook:
+		secret := provider.GetSecret(mc.APIKey)
+		if secret == "" {
+			return nil, ErrMeteringMissingAPIKey
+		}
+		// posts are retried as webhooks are, but don't share their spool. records in it must survive restarts
+		if mc.Spool.Kind == serverwebhook.SpoolKindMemory || (mc.Spool.Kind == "" && rc == nil && mc.Spool.Dir == "") {
+			return nil, ErrMeteringSpoolNotDurable
+		}
+		spool, err := createWebhookSpool(mc.Spool, rc, nodeID, metering.SpoolName)
+		if err != nil {
 			return nil, err
 		}
+		wc := conf.WebHook
+		endpoints := serverwebhook.URLEndpoints(mc.URLs, mc.APIKey, secret)
+		sink = metering.NewWebhookSink(serverwebhook.NewQueuedNotifier(endpoints, wc, spool))
+	default:
+		return nil, fmt.Errorf("unknown metering sink: %s", mc.Sink)
 	}
 
-	if len(conf.Keys) == 0 {
-		return nil, errors.New("one of key-file or keys must be provided in order to support a secure installation")
-	}
-
-	return auth.NewFileBasedKeyProviderFromMap(conf.Keys), nil
+	return metering.NewMeter(conf, nodeID, sink), nil
 }
 
-func createWebhookNotifier(conf *config.Config, provider auth.KeyProvider) (webhook.Notifier, error) {
-	wc := conf.WebHook
-	if len(wc.URLs) == 0 {
+func createAuditLogger(conf *config.Config, provider auth.KeyProvider, rc redis.UniversalClient, nodeID livekit.NodeID) (*audit.Logger, error) {
+	ac := conf.Audit
+	if !ac.Enabled {
 		return nil, nil
 	}
-	secret := provider.GetSecret(wc.APIKey)
+	// keys hashes of records, as well as signing webhook posts
+	secret := provider.GetSecret(ac.APIKey)
 	if secret == "" {
-		return nil, ErrWebHookMissingAPIKey
+		return nil, ErrAuditMissingAPIKey
+	}
+
+	var sink audit.Sink
+	switch ac.Sink {
+	case audit.SinkKindFile:
+		if ac.Path == "" {
+			return nil, ErrAuditMissingPath
+		}
+		fileSink, err := audit.NewFileSink(ac.Path, ac.MaxSize, ac.MaxBackups)
+		if err != nil {
+			return nil, err
+		}
+		sink = fileSink
+	case audit.SinkKindRedis:
+		if rc == nil {
+			return nil, ErrAuditNoRedis
+		}
+		sink = audit.NewRedisSink(rc)
+	case audit.SinkKindWebhook:
+		// posts are retried as webhooks are, but don't share their spool. records in it must survive restarts
+		if ac.Spool.Kind == serverwebhook.SpoolKindMemory || (ac.Spool.Kind == "" && rc == nil && ac.Spool.Dir == "") {
+			return nil, ErrAuditSpoolNotDurable
+		}
+		spool, err := createWebhookSpool(ac.Spool, rc, nodeID, audit.SpoolName)
+		if err != nil {
+			return nil, err
+		}
+		wc := conf.WebHook
+		endpoints := serverwebhook.URLEndpoints(ac.URLs, ac.APIKey, secret)
+		sink = audit.NewWebhookSink(serverwebhook.NewQueuedNotifier(endpoints, wc, spool))
+	default:
+		return nil, fmt.Errorf("unknown audit sink: %s", ac.Sink)
 	}
 
-	return webhook.NewNotifier(wc.APIKey, secret, wc.URLs), nil
+	return audit.NewLogger(conf, nodeID, secret, sink)
 }
 
 func createRedisClient(conf *config.Config) (redis.UniversalClient, error) {
 	return redisLiveKit.GetRedisClient(&conf.Redis)
 }
 
-func createStore(rc redis.UniversalClient) ObjectStore {
+func createStore(conf *config.Config, rc redis.UniversalClient) (ObjectStore, error) {
 	if rc != nil {
-		return NewRedisStore(rc)
+		return NewRedisStore(rc), nil
+	}
+	if conf.Store.FilePath != "" {
+		return NewFileStore(conf.Store.FilePath)
 	}
-	return NewLocalStore()
+	return NewLocalStore(), nil
 }
 
 func getEgressStore(s ObjectStore) EgressStore {
 	switch store := s.(type) {
 	case *RedisStore:
 		return store
+	case *FileStore:
+		return store
 	default:
 		return nil
 	}
@@ -139,6 +266,21 @@ func getIngressStore(s ObjectStore) IngressStore {
 	switch store := s.(type) {
 	case *RedisStore:
 		return store
+	case *FileStore:
+		return store
+	default:
+		return nil
+	}
+}
+
+func getTenantStore(s ObjectStore) TenantStore {
+	switch store := s.(type) {
+	case *RedisStore:
+		return store
+	case *FileStore:
+		return store
+	case *LocalStore:
+		return store
 	default:
 		return nil
 	}
@@ -9,20 +9,21 @@ package service
 import (
 	"fmt"
 	"github.com/go-redis/redis/v8"
+	"github.com/livekit/livekit-server/pkg/audit"
 	"github.com/livekit/livekit-server/pkg/clientconfiguration"
 	"github.com/livekit/livekit-server/pkg/config"
 	"github.com/livekit/livekit-server/pkg/routing"
 	"github.com/livekit/livekit-server/pkg/telemetry"
+	"github.com/livekit/livekit-server/pkg/telemetry/metering"
+	webhook2 "github.com/livekit/livekit-server/pkg/webhook"
 	"github.com/livekit/protocol/auth"
 	"github.com/livekit/protocol/egress"
 	"github.com/livekit/protocol/ingress"
 	"github.com/livekit/protocol/livekit"
 	redis2 "github.com/livekit/protocol/redis"
+	"github.com/livekit/protocol/utils"
 	"github.com/livekit/protocol/webhook"
 	"github.com/pion/turn/v2"
-	"github.com/pkg/errors"
-	"gopkg.in/yaml.v3"
-	"os"
 )
 
 import (
@@ -37,48 +38,79 @@ func InitializeServer(conf *config.Config, currentNode routing.LocalNode) (*Live
 	if err != nil {
 		return nil, err
 	}
-	router := routing.CreateRouter(universalClient, currentNode)
-	objectStore := createStore(universalClient)
-	roomAllocator, err := NewRoomAllocator(conf, router, objectStore)
+	router := routing.CreateRouter(conf, universalClient, currentNode)
+	objectStore, err := createStore(conf, universalClient)
+	if err != nil {
+		return nil, err
+	}
+	tenantStore := getTenantStore(objectStore)
+	tenancy := NewTenancy(conf, tenantStore)
+	roomAllocator, err := NewRoomAllocator(conf, router, objectStore, tenancy)
 	if err != nil {
 		return nil, err
 	}
 	nodeID := getNodeID(currentNode)
 	rpcClient := egress.NewRedisRPCClient(nodeID, universalClient)
 	egressStore := getEgressStore(objectStore)
-	keyProvider, err := createKeyProvider(conf)
+	keyProvider, err := NewKeyProvider(conf)
 	if err != nil {
 		return nil, err
 	}
-	notifier, err := createWebhookNotifier(conf, keyProvider)
+	notifier, err := createWebhookNotifier(conf, keyProvider, universalClient, nodeID)
 	if err != nil {
 		return nil, err
 	}
 	analyticsService := telemetry.NewAnalyticsService(conf, currentNode)
-	telemetryService := telemetry.NewTelemetryService(notifier, analyticsService)
-	rtcEgressLauncher := NewEgressLauncher(rpcClient, egressStore, telemetryService)
-	roomService, err := NewRoomService(roomConfig, router, roomAllocator, objectStore, rtcEgressLauncher)
+	meter, err := createMeter(conf, keyProvider, universalClient, nodeID)
+	if err != nil {
+		return nil, err
+	}
+	telemetryService := telemetry.NewTelemetryService(notifier, analyticsService, meter)
+	trackRecorder := NewTrackRecorder(conf, egressStore, telemetryService)
+	rtcEgressLauncher := NewEgressLauncher(rpcClient, egressStore, telemetryService, trackRecorder)
+	logger, err := createAuditLogger(conf, keyProvider, universalClient, nodeID)
 	if err != nil {
 		return nil, err
 	}
-	egressService := NewEgressService(rpcClient, objectStore, egressStore, roomService, telemetryService, rtcEgressLauncher)
+	roomService, err := NewRoomService(roomConfig, router, roomAllocator, objectStore, rtcEgressLauncher, tenancy, logger)
+	if err != nil {
+		return nil, err
+	}
+	egressService := NewEgressService(rpcClient, objectStore, egressStore, roomService, telemetryService, rtcEgressLauncher, trackRecorder, tenancy, logger)
 	ingressConfig := getIngressConfig(conf)
 	rpc := ingress.NewRedisRPC(nodeID, universalClient)
 	ingressRPCClient := getIngressRPCClient(rpc)
 	ingressStore := getIngressStore(objectStore)
-	ingressService := NewIngressService(ingressConfig, ingressRPCClient, ingressStore, roomService, telemetryService)
-	rtcService := NewRTCService(conf, roomAllocator, objectStore, router, currentNode)
+	ingressService := NewIngressService(ingressConfig, ingressRPCClient, ingressStore, roomService, telemetryService, tenancy, logger)
+	admission, err := NewAdmission(conf, keyProvider)
+	if err != nil {
+		return nil, err
+	}
+	trustedProxies, err := NewTrustedProxies(conf)
+	if err != nil {
+		return nil, err
+	}
+	rateLimiter := NewRateLimiter(conf, trustedProxies)
+	rtcService := NewRTCService(conf, roomAllocator, objectStore, router, currentNode, admission, rateLimiter, trustedProxies)
+	whipService := NewWHIPService(rtcService)
+	whepService := NewWHEPService(rtcService)
 	clientConfigurationManager := createClientConfiguration()
-	roomManager, err := NewLocalRoomManager(conf, objectStore, currentNode, router, telemetryService, clientConfigurationManager, rtcEgressLauncher)
+	roomManager, err := NewLocalRoomManager(conf, objectStore, currentNode, router, telemetryService, clientConfigurationManager, rtcEgressLauncher, keyProvider, tenancy)
 	if err != nil {
 		return nil, err
 	}
+	captureService := NewCaptureService(conf, roomManager, logger)
+	rtpIngestService := NewRTPIngestService(conf, roomAllocator, router, currentNode, roomManager, logger)
+	relayService := NewRelayService(conf, universalClient, router, currentNode)
+	configReloader := NewConfigReloader(conf, keyProvider, notifier, roomAllocator, rtcService, logger)
 	authHandler := newTurnAuthHandler(objectStore)
 	server, err := newInProcessTurnServer(conf, authHandler)
 	if err != nil {
 		return nil, err
 	}
-	livekitServer, err := NewLivekitServer(conf, roomService, egressService, ingressService, rtcService, keyProvider, router, roomManager, server, currentNode)
+	messageBus := createMessageBus(universalClient)
+	waitingRoomService := NewWaitingRoomService(roomConfig, objectStore, messageBus, currentNode, tenancy, logger)
+	livekitServer, err := NewLivekitServer(conf, roomService, waitingRoomService, egressService, ingressService, rtcService, whipService, whepService, captureService, rtpIngestService, trackRecorder, relayService, meter, rateLimiter, trustedProxies, logger, objectStore, configReloader, keyProvider, router, roomManager, server, currentNode)
 	if err != nil {
 		return nil, err
 	}
@@ -90,7 +122,7 @@ func InitializeRouter(conf *config.Config, currentNode routing.LocalNode) (routi
 	if err != nil {
 		return nil, err
 	}
-	router := routing.CreateRouter(universalClient, currentNode)
+	router := routing.CreateRouter(conf, universalClient, currentNode)
 	return router, nil
 }
 
@@ -100,62 +132,171 @@ func getNodeID(currentNode routing.LocalNode) livekit.NodeID {
 	return livekit.NodeID(currentNode.Id)
 }
 
-func createKeyProvider(conf *config.Config) (auth.KeyProvider, error) {
+// createMessageBus returns nil without redis, nodes can't message each other then
+func createMessageBus(rc redis.UniversalClient) utils.MessageBus {
+	if rc == nil {
+		return nil
+	}
+	return utils.NewRedisMessageBus(rc)
+}
 
-	if conf.KeyFile != "" {
-		if st, err := os.Stat(conf.KeyFile); err != nil {
-			return nil, err
-		} else if st.Mode().Perm() != 0600 {
-			return nil, fmt.Errorf("key file must have permission set to 600")
+func createWebhookNotifier(conf *config.Config, provider auth.KeyProvider, rc redis.UniversalClient, nodeID livekit.NodeID) (webhook.Notifier, error) {
+	wc := conf.WebHook
+	endpoints, err := webhookEndpoints(wc, provider)
+	if err != nil || len(endpoints) == 0 {
+		return nil, err
+	}
+
+	spool, err := createWebhookSpool(wc.Spool, rc, nodeID, webhook2.SpoolNameWebhook)
+	if err != nil {
+		return nil, err
+	}
+
+	return webhook2.NewQueuedNotifier(endpoints, wc, spool), nil
+}
+
+func createWebhookSpool(conf config.WebHookSpoolConfig, rc redis.UniversalClient, nodeID livekit.NodeID, name string) (webhook2.Spool, error) {
+	kind := conf.Kind
+	if kind == "" {
+		switch {
+		case rc != nil:
+			kind = webhook2.SpoolKindRedis
+		case conf.Dir != "":
+			kind = webhook2.SpoolKindFile
+		default:
+			kind = webhook2.SpoolKindMemory
+		}
+	}
+
+	switch kind {
+	case webhook2.SpoolKindRedis:
+		if rc == nil {
+			return nil, ErrWebHookSpoolNoRedis
+		}
+		return webhook2.NewRedisSpool(rc, nodeID, name, conf.MaxEvents, conf.MaxDeadLetters), nil
+	case webhook2.SpoolKindFile:
+		if conf.Dir == "" {
+			return nil, ErrWebHookSpoolDirEmpty
+		}
+		return webhook2.NewFileSpool(conf.Dir, conf.MaxEvents, conf.MaxDeadLetters)
+	case webhook2.SpoolKindMemory:
+		return webhook2.NewMemorySpool(conf.MaxEvents, conf.MaxDeadLetters), nil
+	default:
+		return nil, fmt.Errorf("unknown webhook spool kind: %s", kind)
+	}
+}
+
+func createMeter(conf *config.Config, provider auth.KeyProvider, rc redis.UniversalClient, nodeID livekit.NodeID) (*metering.Meter, error) {
+	mc := conf.Metering
+	if !mc.Enabled {
+		return nil, nil
+	}
+
+	var sink metering.Sink
+	switch mc.Sink {
+	case metering.SinkKindFile:
+		if mc.Path == "" {
+			return nil, ErrMeteringMissingPath
 		}
-		f, err := os.Open(conf.KeyFile)
+		fileSink, err := metering.NewFileSink(mc.Path)
 		if err != nil {
 			return nil, err
 		}
-		defer func() {
-			_ = f.Close()
-		}()
-		decoder := yaml.NewDecoder(f)
-		if err = decoder.Decode(conf.Keys); err != nil {
+		sink = fileSink
+	case metering.SinkKindRedis:
+		if rc == nil {
+			return nil, ErrMeteringNoRedis
+		}
+		sink = metering.NewRedisSink(rc, mc.MaxRecords)
+	case metering.SinkKindWebhook:
+		secret := provider.GetSecret(mc.APIKey)
+		if secret == "" {
+			return nil, ErrMeteringMissingAPIKey
+		}
+		// posts are retried as webhooks are, but don't share their spool. records in it must survive restarts
+		if mc.Spool.Kind == webhook2.SpoolKindMemory || (mc.Spool.Kind == "" && rc == nil && mc.Spool.Dir == "") {
+			return nil, ErrMeteringSpoolNotDurable
+		}
+		spool, err := createWebhookSpool(mc.Spool, rc, nodeID, metering.SpoolName)
+		if err != nil {
 			return nil, err
 		}
+		wc := conf.WebHook
+		endpoints := webhook2.URLEndpoints(mc.URLs, mc.APIKey, secret)
+		sink = metering.NewWebhookSink(webhook2.NewQueuedNotifier(endpoints, wc, spool))
+	default:
+		return nil, fmt.Errorf("unknown metering sink: %s", mc.Sink)
 	}
 
-	if len(conf.Keys) == 0 {
-		return nil, errors.New("one of key-file or keys must be provided in order to support a secure installation")
-	}
-
-	return auth.NewFileBasedKeyProviderFromMap(conf.Keys), nil
+	return metering.NewMeter(conf, nodeID, sink), nil
 }
 
-func createWebhookNotifier(conf *config.Config, provider auth.KeyProvider) (webhook.Notifier, error) {
-	wc := conf.WebHook
-	if len(wc.URLs) == 0 {
+func createAuditLogger(conf *config.Config, provider auth.KeyProvider, rc redis.UniversalClient, nodeID livekit.NodeID) (*audit.Logger, error) {
+	ac := conf.Audit
+	if !ac.Enabled {
 		return nil, nil
 	}
-	secret := provider.GetSecret(wc.APIKey)
+	// keys hashes of records, as well as signing webhook posts
+	secret := provider.GetSecret(ac.APIKey)
 	if secret == "" {
-		return nil, ErrWebHookMissingAPIKey
+		return nil, ErrAuditMissingAPIKey
 	}
 
-	return webhook.NewNotifier(wc.APIKey, secret, wc.URLs), nil
+	var sink audit.Sink
+	switch ac.Sink {
+	case audit.SinkKindFile:
+		if ac.Path == "" {
+			return nil, ErrAuditMissingPath
+		}
+		fileSink, err := audit.NewFileSink(ac.Path, ac.MaxSize, ac.MaxBackups)
+		if err != nil {
+			return nil, err
+		}
+		sink = fileSink
+	case audit.SinkKindRedis:
+		if rc == nil {
+			return nil, ErrAuditNoRedis
+		}
+		sink = audit.NewRedisSink(rc)
+	case audit.SinkKindWebhook:
+		// posts are retried as webhooks are, but don't share their spool. records in it must survive restarts
+		if ac.Spool.Kind == webhook2.SpoolKindMemory || (ac.Spool.Kind == "" && rc == nil && ac.Spool.Dir == "") {
+			return nil, ErrAuditSpoolNotDurable
+		}
+		spool, err := createWebhookSpool(ac.Spool, rc, nodeID, audit.SpoolName)
+		if err != nil {
+			return nil, err
+		}
+		wc := conf.WebHook
+		endpoints := webhook2.URLEndpoints(ac.URLs, ac.APIKey, secret)
+		sink = audit.NewWebhookSink(webhook2.NewQueuedNotifier(endpoints, wc, spool))
+	default:
+		return nil, fmt.Errorf("unknown audit sink: %s", ac.Sink)
+	}
+
+	return audit.NewLogger(conf, nodeID, secret, sink)
 }
 
 func createRedisClient(conf *config.Config) (redis.UniversalClient, error) {
 	return redis2.GetRedisClient(&conf.Redis)
 }
 
-func createStore(rc redis.UniversalClient) ObjectStore {
+func createStore(conf *config.Config, rc redis.UniversalClient) (ObjectStore, error) {
 	if rc != nil {
-		return NewRedisStore(rc)
+		return NewRedisStore(rc), nil
 	}
-	return NewLocalStore()
+	if conf.Store.FilePath != "" {
+		return NewFileStore(conf.Store.FilePath)
+	}
+	return NewLocalStore(), nil
 }
 
 func getEgressStore(s ObjectStore) EgressStore {
 	switch store := s.(type) {
 	case *RedisStore:
 		return store
+	case *FileStore:
+		return store
 	default:
 		return nil
 	}
@@ -165,6 +306,21 @@ func getIngressStore(s ObjectStore) IngressStore {
 	switch store := s.(type) {
 	case *RedisStore:
 		return store
+	case *FileStore:
+		return store
+	default:
+		return nil
+	}
+}
+
+func getTenantStore(s ObjectStore) TenantStore {
+	switch store := s.(type) {
+	case *RedisStore:
+		return store
+	case *FileStore:
+		return store
+	case *LocalStore:
+		return store
 	default:
 		return nil
 	}
@@ -42,6 +42,8 @@ type ExtPacket struct {
 	KeyFrame             bool
 	RawPacket            []byte
 	DependencyDescriptor *dd.DependencyDescriptor
+	// audio level header extension, when the publisher sends it. Only forwarded by DownTracks relaying the track
+	AudioLevel []byte
 }
 
 // Buffer contains all packets
@@ -87,6 +89,8 @@ type Buffer struct {
 	onRtcpFeedback func([]rtcp.Packet)
 	onFpsChanged   func()
 
+	capture PacketCapture
+
 	// logger
 	logger logger.Logger
 
@@ -130,6 +134,14 @@ func (b *Buffer) SetTWCC(twcc *twcc.Responder) {
 	b.twcc = twcc
 }
 
+// SetCapture starts teeing received packets to c, nil stops it
+func (b *Buffer) SetCapture(c PacketCapture) {
+	b.Lock()
+	defer b.Unlock()
+
+	b.capture = c
+}
+
 func (b *Buffer) SetAudioLevelParams(audioLevelParams audio.AudioLevelParams) {
 	b.Lock()
 	defer b.Unlock()
@@ -229,6 +241,10 @@ func (b *Buffer) Write(pkt []byte) (n int, err error) {
 		return
 	}
 
+	if b.capture != nil {
+		b.capture.WriteRTP(pkt, time.Now().UnixNano())
+	}
+
 	if !b.bound {
 		packet := make([]byte, len(pkt))
 		copy(packet, pkt)
@@ -510,6 +526,9 @@ func (b *Buffer) getExtPacket(rtpPacket *rtp.Packet, arrivalTime int64) *ExtPack
 			Temporal: InvalidLayerTemporal,
 		},
 	}
+	if b.audioLevelExt != 0 {
+		ep.AudioLevel = rtpPacket.GetExtension(b.audioLevelExt)
+	}
 
 	if len(rtpPacket.Payload) == 0 {
 		// padding only packet, nothing else to do
@@ -541,6 +560,18 @@ func (b *Buffer) getExtPacket(rtpPacket *rtp.Packet, arrivalTime int64) *ExtPack
 			ep.Spatial = InvalidLayerSpatial // vp8 don't have spatial scalability, reset to -1
 		}
 		ep.Payload = vp8Packet
+	case "video/vp9":
+		vp9Packet := VP9{}
+		if err := vp9Packet.Unmarshal(rtpPacket.Payload); err != nil {
+			b.logger.Warnw("could not unmarshal VP9 packet", err)
+			return nil
+		}
+		ep.KeyFrame = vp9Packet.IsKeyFrame
+		if ep.DependencyDescriptor == nil && vp9Packet.L {
+			ep.Spatial = int32(vp9Packet.SID)
+			ep.Temporal = int32(vp9Packet.TID)
+		}
+		ep.Payload = vp9Packet
 	case "video/h264":
 		ep.KeyFrame = IsH264Keyframe(rtpPacket.Payload)
 	case "video/av1":
@@ -0,0 +1,9 @@
+package buffer
+
+// PacketCapture receives a copy of packets flowing through a buffer or a down track,
+// used to record streams to disk for offline debugging.
+// Implementations must not hold on to pkt after returning, and should not block.
+type PacketCapture interface {
+	WriteRTP(pkt []byte, arrivalTime int64)
+	WriteRTCP(pkt []byte, arrivalTime int64)
+}
@@ -209,6 +209,226 @@ func VP8PictureIdSizeDiff(mBit1 bool, mBit2 bool) int {
 	return -1
 }
 
+// VP9 is a helper to get layer data from VP9 packet header
+/*
+	VP9 Payload Descriptor (flexible and non-flexible mode)
+			0 1 2 3 4 5 6 7
+			+-+-+-+-+-+-+-+-+
+			|I|P|L|F|B|E|V|Z| (REQUIRED)
+			+-+-+-+-+-+-+-+-+
+		I:  |M| PICTURE ID  | (REQUIRED)
+			+-+-+-+-+-+-+-+-+
+		M:  | EXTENDED PID  | (RECOMMENDED)
+			+-+-+-+-+-+-+-+-+
+		L:  | TID |U| SID |D| (CONDITIONALLY RECOMMENDED)
+			+-+-+-+-+-+-+-+-+
+			|   TL0PICIDX   | (CONDITIONALLY REQUIRED, F=0 only)
+			+-+-+-+-+-+-+-+-+                             -\
+		P,F:| P_DIFF      |N| (CONDITIONALLY REQUIRED)    - up to 3 times
+			+-+-+-+-+-+-+-+-+                             -/
+		V:  | SS            |
+			| ..            |
+			+-+-+-+-+-+-+-+-+
+*/
+type VP9 struct {
+	I bool // picture id present
+	P bool // inter-picture predicted frame
+	L bool // layer indices present
+	F bool // flexible mode
+	B bool // start of a frame
+	E bool // end of a frame
+	V bool // scalability structure present
+	Z bool // not a reference frame for upper spatial layers
+
+	PictureID uint16 /* 7 or 15 bits, picture ID */
+	MBit      bool
+
+	TID uint8 /* 3 bits temporal layer idx */
+	U   bool  /* switching up point */
+	SID uint8 /* 3 bits spatial layer idx */
+	D   bool  /* inter-layer dependency used */
+
+	TL0PICIDX uint8 /* 8 bits temporal level zero index, non-flexible mode only */
+
+	// number of spatial layers announced in the scalability structure, 0 if not present
+	NumSpatialLayers int
+
+	HeaderSize int
+
+	// IsKeyFrame is a helper to detect if current packet is the start of a key frame
+	IsKeyFrame bool
+}
+
+// Unmarshal parses the passed byte slice and stores the result in the VP9 this method is called upon
+func (v *VP9) Unmarshal(payload []byte) error {
+	if payload == nil {
+		return errNilPacket
+	}
+
+	payloadLen := len(payload)
+	if payloadLen < 1 {
+		return errShortPacket
+	}
+
+	idx := 0
+	v.I = payload[idx]&0x80 > 0
+	v.P = payload[idx]&0x40 > 0
+	v.L = payload[idx]&0x20 > 0
+	v.F = payload[idx]&0x10 > 0
+	v.B = payload[idx]&0x08 > 0
+	v.E = payload[idx]&0x04 > 0
+	v.V = payload[idx]&0x02 > 0
+	v.Z = payload[idx]&0x01 > 0
+	idx++
+
+	if v.I {
+		if payloadLen < idx+1 {
+			return errShortPacket
+		}
+		pid := payload[idx] & 0x7f
+		if payload[idx]&0x80 > 0 {
+			idx++
+			if payloadLen < idx+1 {
+				return errShortPacket
+			}
+			v.MBit = true
+			v.PictureID = binary.BigEndian.Uint16([]byte{pid, payload[idx]})
+		} else {
+			v.PictureID = uint16(pid)
+		}
+		idx++
+	}
+
+	if v.L {
+		if payloadLen < idx+1 {
+			return errShortPacket
+		}
+		v.TID = (payload[idx] & 0xe0) >> 5
+		v.U = payload[idx]&0x10 > 0
+		v.SID = (payload[idx] & 0x0e) >> 1
+		v.D = payload[idx]&0x01 > 0
+		idx++
+
+		if !v.F {
+			if payloadLen < idx+1 {
+				return errShortPacket
+			}
+			v.TL0PICIDX = payload[idx]
+			idx++
+		}
+	}
+
+	if v.F && v.P {
+		// reference indices, up to 3, N bit indicates another one follows
+		for i := 0; i < 3; i++ {
+			if payloadLen < idx+1 {
+				return errShortPacket
+			}
+			more := payload[idx]&0x01 > 0
+			idx++
+			if !more {
+				break
+			}
+		}
+	}
+
+	if v.V {
+		var err error
+		if idx, err = v.skipScalabilityStructure(payload, idx); err != nil {
+			return err
+		}
+	}
+
+	if payloadLen < idx+1 {
+		return errShortPacket
+	}
+
+	v.HeaderSize = idx
+	v.IsKeyFrame = !v.P && v.B && v.SID == 0
+	return nil
+}
+
+func (v *VP9) skipScalabilityStructure(payload []byte, idx int) (int, error) {
+	payloadLen := len(payload)
+	if payloadLen < idx+1 {
+		return idx, errShortPacket
+	}
+
+	v.NumSpatialLayers = int(payload[idx]>>5) + 1
+	y := payload[idx]&0x10 > 0
+	g := payload[idx]&0x08 > 0
+	idx++
+
+	if y {
+		// width and height, 16 bits each, per spatial layer
+		idx += 4 * v.NumSpatialLayers
+	}
+
+	if g {
+		if payloadLen < idx+1 {
+			return idx, errShortPacket
+		}
+		numPictures := int(payload[idx])
+		idx++
+		for i := 0; i < numPictures; i++ {
+			if payloadLen < idx+1 {
+				return idx, errShortPacket
+			}
+			numRefs := int((payload[idx] & 0x0c) >> 2)
+			idx += 1 + numRefs
+		}
+	}
+
+	if payloadLen < idx {
+		return idx, errShortPacket
+	}
+	return idx, nil
+}
+
+// MarshalTo writes the picture id, layer indices and TL0PICIDX of the descriptor into buf.
+// buf is expected to hold a copy of the original descriptor, i. e. reference indices and
+// scalability structure are not re-encoded and the size of the picture id does not change.
+func (v *VP9) MarshalTo(buf []byte) error {
+	if len(buf) < v.HeaderSize {
+		return errShortPacket
+	}
+
+	idx := 0
+	buf[idx] = byte(btoi(v.I)<<7) | byte(btoi(v.P)<<6) | byte(btoi(v.L)<<5) | byte(btoi(v.F)<<4) |
+		byte(btoi(v.B)<<3) | byte(btoi(v.E)<<2) | byte(btoi(v.V)<<1) | byte(btoi(v.Z))
+	idx++
+
+	if v.I {
+		if v.MBit {
+			buf[idx] = 0x80 | byte((v.PictureID>>8)&0x7f)
+			buf[idx+1] = byte(v.PictureID & 0xff)
+			idx += 2
+		} else {
+			buf[idx] = byte(v.PictureID & 0x7f)
+			idx++
+		}
+	}
+
+	if v.L {
+		buf[idx] = (v.TID&0x7)<<5 | byte(btoi(v.U)<<4) | (v.SID&0x7)<<1 | byte(btoi(v.D))
+		idx++
+
+		if !v.F {
+			buf[idx] = v.TL0PICIDX
+		}
+	}
+
+	return nil
+}
+
+func btoi(b bool) int {
+	if b {
+		return 1
+	}
+
+	return 0
+}
+
 // IsH264Keyframe detects if h264 payload is a keyframe
 // this code was taken from https://github.com/jech/galene/blob/codecs/rtpconn/rtpreader.go#L45
 // all credits belongs to Juliusz Chroboczek @jech and the awesome Galene SFU
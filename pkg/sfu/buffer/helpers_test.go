@@ -93,4 +93,84 @@ func TestVP8Helper_Unmarshal(t *testing.T) {
 	}
 }
 
+func TestVP9Helper_Unmarshal(t *testing.T) {
+	t.Run("empty or nil payload must return error", func(t *testing.T) {
+		p := &VP9{}
+		require.Error(t, p.Unmarshal(nil))
+		require.Error(t, p.Unmarshal([]byte{}))
+	})
+
+	t.Run("truncated picture id must return error", func(t *testing.T) {
+		p := &VP9{}
+		require.Error(t, p.Unmarshal([]byte{0xaa, 0x92}))
+	})
+
+	t.Run("key frame with scalability structure", func(t *testing.T) {
+		p := &VP9{}
+		payload := []byte{
+			0xaa,       // I, L, B, V
+			0x92, 0x67, // 15 bit picture id
+			0x00, // TID 0, SID 0
+			0xb4, // TL0PICIDX
+			0x50, // N_S = 2, Y
+			0x01, 0x40, 0x00, 0xb4,
+			0x02, 0x80, 0x01, 0x68,
+			0x05, 0x00, 0x02, 0xd0,
+			0x82, 0x49, 0x83,
+		}
+		require.NoError(t, p.Unmarshal(payload))
+		require.True(t, p.IsKeyFrame)
+		require.True(t, p.MBit)
+		require.Equal(t, uint16(4711), p.PictureID)
+		require.Equal(t, uint8(180), p.TL0PICIDX)
+		require.Equal(t, 3, p.NumSpatialLayers)
+		require.Equal(t, 18, p.HeaderSize)
+	})
+
+	t.Run("inter frame in non-flexible mode", func(t *testing.T) {
+		p := &VP9{}
+		require.NoError(t, p.Unmarshal([]byte{0xec, 0x11, 0x35, 0x05, 0x00}))
+		require.False(t, p.IsKeyFrame)
+		require.False(t, p.MBit)
+		require.True(t, p.E)
+		require.Equal(t, uint16(17), p.PictureID)
+		require.Equal(t, uint8(1), p.TID)
+		require.True(t, p.U)
+		require.Equal(t, uint8(2), p.SID)
+		require.True(t, p.D)
+		require.Equal(t, uint8(5), p.TL0PICIDX)
+		require.Equal(t, 4, p.HeaderSize)
+	})
+
+	t.Run("inter frame in flexible mode with reference indices", func(t *testing.T) {
+		p := &VP9{}
+		require.NoError(t, p.Unmarshal([]byte{0xf8, 0x11, 0x20, 0x03, 0x04, 0x00}))
+		require.True(t, p.F)
+		require.Equal(t, uint8(1), p.TID)
+		require.Equal(t, 5, p.HeaderSize)
+	})
+}
+
+func TestVP9Helper_MarshalTo(t *testing.T) {
+	payload := []byte{0xaa, 0x92, 0x67, 0x00, 0xb4, 0x50,
+		0x01, 0x40, 0x00, 0xb4, 0x02, 0x80, 0x01, 0x68, 0x05, 0x00, 0x02, 0xd0, 0x82}
+	p := &VP9{}
+	require.NoError(t, p.Unmarshal(payload))
+
+	p.PictureID = 300
+	p.TL0PICIDX = 7
+
+	buf := make([]byte, len(payload))
+	copy(buf, payload)
+	require.NoError(t, p.MarshalTo(buf[:p.HeaderSize]))
+
+	translated := &VP9{}
+	require.NoError(t, translated.Unmarshal(buf))
+	require.Equal(t, uint16(300), translated.PictureID)
+	require.Equal(t, uint8(7), translated.TL0PICIDX)
+	require.Equal(t, 3, translated.NumSpatialLayers)
+	require.Equal(t, p.HeaderSize, translated.HeaderSize)
+	require.Equal(t, payload[p.HeaderSize:], buf[p.HeaderSize:])
+}
+
 // ------------------------------------------
@@ -0,0 +1,358 @@
+package capture
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"go.uber.org/atomic"
+
+	"github.com/livekit/protocol/logger"
+)
+
+type Format string
+
+const (
+	// FormatPcap writes packets as UDP datagrams in a pcap file, readable by Wireshark
+	FormatPcap Format = "pcap"
+	// FormatRTPDump writes packets in the rtptools rtpdump format, which can be replayed with rtpplay
+	FormatRTPDump Format = "rtpdump"
+
+	packetQueueSize = 1024
+
+	// ports used for packets in pcap files, so that they can be decoded as RTP/RTCP
+	rtpPort  = 5004
+	rtcpPort = 5005
+)
+
+var (
+	ErrUnsupportedFormat = errors.New("unsupported capture format")
+)
+
+type packet struct {
+	data        []byte
+	arrivalTime int64
+	isRTCP      bool
+}
+
+type WriterParams struct {
+	Path        string
+	Format      Format
+	MaxDuration time.Duration
+	MaxSize     int64
+	Logger      logger.Logger
+}
+
+type WriterStats struct {
+	Packets int64
+	Bytes   int64
+	Dropped int64
+}
+
+// Writer implements buffer.PacketCapture, writing packets to a file from a separate goroutine
+// so that capturing does not hold up forwarding. Packets are dropped when the writer falls behind.
+// The capture ends when it's closed, or once MaxDuration or MaxSize is reached.
+type Writer struct {
+	params    WriterParams
+	file      *os.File
+	w         *bufio.Writer
+	startedAt time.Time
+
+	packets   chan packet
+	closed    atomic.Bool
+	stop      chan struct{}
+	stopOnce  sync.Once
+	done      chan struct{}
+	numPkts   atomic.Int64
+	numBytes  atomic.Int64
+	numDrops  atomic.Int64
+	onCloseMu sync.Mutex
+	onClose   []func()
+}
+
+func NewWriter(params WriterParams) (*Writer, error) {
+	if params.Format == "" {
+		params.Format = FormatPcap
+	}
+	if params.Format != FormatPcap && params.Format != FormatRTPDump {
+		return nil, ErrUnsupportedFormat
+	}
+
+	file, err := os.OpenFile(params.Path, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &Writer{
+		params:    params,
+		file:      file,
+		w:         bufio.NewWriter(file),
+		startedAt: time.Now(),
+		packets:   make(chan packet, packetQueueSize),
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	if err = w.writeHeader(); err != nil {
+		_ = file.Close()
+		_ = os.Remove(params.Path)
+		return nil, err
+	}
+
+	go w.worker()
+	return w, nil
+}
+
+func (w *Writer) WriteRTP(pkt []byte, arrivalTime int64) {
+	w.enqueue(pkt, arrivalTime, false)
+}
+
+func (w *Writer) WriteRTCP(pkt []byte, arrivalTime int64) {
+	w.enqueue(pkt, arrivalTime, true)
+}
+
+// Close stops the capture, packets already queued are still written
+func (w *Writer) Close() {
+	w.stopOnce.Do(func() {
+		close(w.stop)
+	})
+}
+
+// OnClose is called once the capture file is complete, regardless of why it ended
+func (w *Writer) OnClose(f func()) {
+	w.onCloseMu.Lock()
+	if w.closed.Load() {
+		w.onCloseMu.Unlock()
+		f()
+		return
+	}
+	w.onClose = append(w.onClose, f)
+	w.onCloseMu.Unlock()
+}
+
+func (w *Writer) Done() <-chan struct{} {
+	return w.done
+}
+
+func (w *Writer) Path() string {
+	return w.params.Path
+}
+
+func (w *Writer) StartedAt() time.Time {
+	return w.startedAt
+}
+
+func (w *Writer) Stats() WriterStats {
+	return WriterStats{
+		Packets: w.numPkts.Load(),
+		Bytes:   w.numBytes.Load(),
+		Dropped: w.numDrops.Load(),
+	}
+}
+
+func (w *Writer) enqueue(pkt []byte, arrivalTime int64, isRTCP bool) {
+	if w.closed.Load() {
+		return
+	}
+
+	data := make([]byte, len(pkt))
+	copy(data, pkt)
+	select {
+	case w.packets <- packet{data: data, arrivalTime: arrivalTime, isRTCP: isRTCP}:
+	default:
+		w.numDrops.Inc()
+	}
+}
+
+func (w *Writer) worker() {
+	var timeout <-chan time.Time
+	if w.params.MaxDuration > 0 {
+		timer := time.NewTimer(w.params.MaxDuration)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	defer w.finish()
+
+	for {
+		select {
+		case p := <-w.packets:
+			if !w.writePacket(p) {
+				return
+			}
+		case <-timeout:
+			w.params.Logger.Infow("capture reached max duration", "path", w.params.Path)
+			return
+		case <-w.stop:
+			// drain what has been queued up to now
+			for {
+				select {
+				case p := <-w.packets:
+					if !w.writePacket(p) {
+						return
+					}
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// writePacket returns false when the capture should end
+func (w *Writer) writePacket(p packet) bool {
+	var n int
+	var err error
+	switch w.params.Format {
+	case FormatPcap:
+		n, err = w.writePcapRecord(p)
+	case FormatRTPDump:
+		n, err = w.writeRTPDumpRecord(p)
+	}
+	if err != nil {
+		w.params.Logger.Errorw("could not write capture", err, "path", w.params.Path)
+		return false
+	}
+
+	w.numPkts.Inc()
+	if size := w.numBytes.Add(int64(n)); w.params.MaxSize > 0 && size >= w.params.MaxSize {
+		w.params.Logger.Infow("capture reached max size", "path", w.params.Path)
+		return false
+	}
+	return true
+}
+
+func (w *Writer) finish() {
+	w.onCloseMu.Lock()
+	w.closed.Store(true)
+	onClose := w.onClose
+	w.onClose = nil
+	w.onCloseMu.Unlock()
+
+	if err := w.w.Flush(); err != nil {
+		w.params.Logger.Errorw("could not flush capture", err, "path", w.params.Path)
+	}
+	if err := w.file.Close(); err != nil {
+		w.params.Logger.Errorw("could not close capture", err, "path", w.params.Path)
+	}
+	stats := w.Stats()
+	w.params.Logger.Infow("capture complete",
+		"path", w.params.Path,
+		"packets", stats.Packets,
+		"bytes", stats.Bytes,
+		"dropped", stats.Dropped,
+		"duration", time.Since(w.startedAt),
+	)
+
+	close(w.done)
+	for _, f := range onClose {
+		f()
+	}
+}
+
+func (w *Writer) writeHeader() error {
+	var header []byte
+	switch w.params.Format {
+	case FormatPcap:
+		header = make([]byte, 24)
+		// nanosecond resolution pcap
+		binary.LittleEndian.PutUint32(header[0:], 0xa1b23c4d)
+		binary.LittleEndian.PutUint16(header[4:], 2)
+		binary.LittleEndian.PutUint16(header[6:], 4)
+		binary.LittleEndian.PutUint32(header[16:], 65535)
+		// LINKTYPE_RAW, packets start with an IPv4 header
+		binary.LittleEndian.PutUint32(header[20:], 101)
+
+	case FormatRTPDump:
+		header = []byte(fmt.Sprintf("#!rtpplay1.0 127.0.0.1/%d\n", rtpPort))
+		hdr := make([]byte, 16)
+		binary.BigEndian.PutUint32(hdr[0:], uint32(w.startedAt.Unix()))
+		binary.BigEndian.PutUint32(hdr[4:], uint32(w.startedAt.Nanosecond()/1000))
+		copy(hdr[8:], []byte{127, 0, 0, 1})
+		binary.BigEndian.PutUint16(hdr[12:], rtpPort)
+		header = append(header, hdr...)
+	}
+
+	_, err := w.w.Write(header)
+	if err == nil {
+		w.numBytes.Store(int64(len(header)))
+	}
+	return err
+}
+
+func (w *Writer) writePcapRecord(p packet) (int, error) {
+	const ipHeaderSize = 20
+	const udpHeaderSize = 8
+
+	length := ipHeaderSize + udpHeaderSize + len(p.data)
+	record := make([]byte, 16+ipHeaderSize+udpHeaderSize)
+
+	binary.LittleEndian.PutUint32(record[0:], uint32(p.arrivalTime/int64(time.Second)))
+	binary.LittleEndian.PutUint32(record[4:], uint32(p.arrivalTime%int64(time.Second)))
+	binary.LittleEndian.PutUint32(record[8:], uint32(length))
+	binary.LittleEndian.PutUint32(record[12:], uint32(length))
+
+	port := uint16(rtpPort)
+	if p.isRTCP {
+		port = rtcpPort
+	}
+
+	ip := record[16 : 16+ipHeaderSize]
+	ip[0] = 0x45 // v4, 5 words
+	binary.BigEndian.PutUint16(ip[2:], uint16(length))
+	ip[8] = 64 // ttl
+	ip[9] = 17 // udp
+	copy(ip[12:], []byte{127, 0, 0, 1})
+	copy(ip[16:], []byte{127, 0, 0, 1})
+	binary.BigEndian.PutUint16(ip[10:], ipChecksum(ip))
+
+	udp := record[16+ipHeaderSize:]
+	binary.BigEndian.PutUint16(udp[0:], port)
+	binary.BigEndian.PutUint16(udp[2:], port)
+	binary.BigEndian.PutUint16(udp[4:], uint16(udpHeaderSize+len(p.data)))
+	// checksum is optional for IPv4
+
+	if _, err := w.w.Write(record); err != nil {
+		return 0, err
+	}
+	if _, err := w.w.Write(p.data); err != nil {
+		return 0, err
+	}
+	return len(record) + len(p.data), nil
+}
+
+func (w *Writer) writeRTPDumpRecord(p packet) (int, error) {
+	record := make([]byte, 8)
+	binary.BigEndian.PutUint16(record[0:], uint16(len(record)+len(p.data)))
+	if !p.isRTCP {
+		// RTCP packets are marked with a zero RTP length
+		binary.BigEndian.PutUint16(record[2:], uint16(len(p.data)))
+	}
+	offset := time.Duration(p.arrivalTime - w.startedAt.UnixNano())
+	if offset < 0 {
+		offset = 0
+	}
+	binary.BigEndian.PutUint32(record[4:], uint32(offset.Milliseconds()))
+
+	if _, err := w.w.Write(record); err != nil {
+		return 0, err
+	}
+	if _, err := w.w.Write(p.data); err != nil {
+		return 0, err
+	}
+	return len(record) + len(p.data), nil
+}
+
+func ipChecksum(header []byte) uint16 {
+	var sum uint32
+	for i := 0; i+1 < len(header); i += 2 {
+		sum += uint32(header[i])<<8 | uint32(header[i+1])
+	}
+	for sum > 0xffff {
+		sum = (sum >> 16) + (sum & 0xffff)
+	}
+	return ^uint16(sum)
+}
@@ -0,0 +1,159 @@
+package capture
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/livekit-server/pkg/sfu/buffer"
+)
+
+var _ buffer.PacketCapture = (*Writer)(nil)
+
+func testRTPPacket(t *testing.T, sn uint16) []byte {
+	pkt := rtp.Packet{
+		Header: rtp.Header{
+			Version:        2,
+			PayloadType:    96,
+			SequenceNumber: sn,
+			Timestamp:      uint32(sn) * 3000,
+			SSRC:           12345,
+		},
+		Payload: []byte{0x01, 0x02, 0x03, 0x04},
+	}
+	buf, err := pkt.Marshal()
+	require.NoError(t, err)
+	return buf
+}
+
+func TestWriter(t *testing.T) {
+	t.Run("pcap", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "capture.pcap")
+		w, err := NewWriter(WriterParams{Path: path, Format: FormatPcap})
+		require.NoError(t, err)
+
+		rtpPkt := testRTPPacket(t, 1)
+		rtcpPkt, err := (&rtcp.PictureLossIndication{MediaSSRC: 12345}).Marshal()
+		require.NoError(t, err)
+
+		now := time.Now().UnixNano()
+		w.WriteRTP(rtpPkt, now)
+		w.WriteRTCP(rtcpPkt, now)
+		w.Close()
+		<-w.Done()
+
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		require.Equal(t, uint32(0xa1b23c4d), binary.LittleEndian.Uint32(data))
+		require.Equal(t, uint32(101), binary.LittleEndian.Uint32(data[20:]))
+
+		// records are IPv4/UDP datagrams, with RTP and RTCP on different ports
+		offset := 24
+		for _, expected := range []struct {
+			payload []byte
+			port    uint16
+		}{
+			{payload: rtpPkt, port: rtpPort},
+			{payload: rtcpPkt, port: rtcpPort},
+		} {
+			length := int(binary.LittleEndian.Uint32(data[offset+8:]))
+			require.Equal(t, 28+len(expected.payload), length)
+
+			datagram := data[offset+16 : offset+16+length]
+			require.Equal(t, byte(0x45), datagram[0])
+			require.Equal(t, uint16(0), ipChecksum(datagram[:20]))
+			require.Equal(t, expected.port, binary.BigEndian.Uint16(datagram[22:]))
+			require.Equal(t, expected.payload, datagram[28:])
+
+			offset += 16 + length
+		}
+		require.Equal(t, len(data), offset)
+		require.Equal(t, WriterStats{Packets: 2, Bytes: int64(len(data))}, w.Stats())
+	})
+
+	t.Run("rtpdump", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "capture.rtpdump")
+		w, err := NewWriter(WriterParams{Path: path, Format: FormatRTPDump})
+		require.NoError(t, err)
+
+		rtpPkt := testRTPPacket(t, 1)
+		rtcpPkt, err := (&rtcp.PictureLossIndication{MediaSSRC: 12345}).Marshal()
+		require.NoError(t, err)
+
+		w.WriteRTP(rtpPkt, time.Now().UnixNano())
+		w.WriteRTCP(rtcpPkt, time.Now().UnixNano())
+		w.Close()
+		<-w.Done()
+
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+
+		header := "#!rtpplay1.0 127.0.0.1/5004\n"
+		require.Equal(t, header, string(data[:len(header)]))
+		offset := len(header) + 16
+
+		require.Equal(t, uint16(8+len(rtpPkt)), binary.BigEndian.Uint16(data[offset:]))
+		require.Equal(t, uint16(len(rtpPkt)), binary.BigEndian.Uint16(data[offset+2:]))
+		require.Equal(t, rtpPkt, data[offset+8:offset+8+len(rtpPkt)])
+		offset += 8 + len(rtpPkt)
+
+		// RTCP is marked with a zero RTP length
+		require.Equal(t, uint16(8+len(rtcpPkt)), binary.BigEndian.Uint16(data[offset:]))
+		require.Equal(t, uint16(0), binary.BigEndian.Uint16(data[offset+2:]))
+		require.Equal(t, rtcpPkt, data[offset+8:])
+	})
+
+	t.Run("ends at max size", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "capture.pcap")
+		w, err := NewWriter(WriterParams{Path: path, MaxSize: 250})
+		require.NoError(t, err)
+
+		closed := make(chan struct{})
+		w.OnClose(func() { close(closed) })
+
+		for sn := uint16(0); sn < 10; sn++ {
+			w.WriteRTP(testRTPPacket(t, sn), time.Now().UnixNano())
+		}
+
+		select {
+		case <-closed:
+		case <-time.After(time.Second):
+			t.Fatal("capture did not end")
+		}
+
+		// 24 byte header, then 60 bytes per record
+		require.Equal(t, int64(4), w.Stats().Packets)
+		info, err := os.Stat(path)
+		require.NoError(t, err)
+		require.Equal(t, int64(24+4*60), info.Size())
+
+		// no longer accepting packets
+		w.WriteRTP(testRTPPacket(t, 10), time.Now().UnixNano())
+		require.Equal(t, int64(4), w.Stats().Packets)
+	})
+
+	t.Run("ends at max duration", func(t *testing.T) {
+		w, err := NewWriter(WriterParams{Path: filepath.Join(t.TempDir(), "capture.pcap"), MaxDuration: 50 * time.Millisecond})
+		require.NoError(t, err)
+
+		select {
+		case <-w.Done():
+		case <-time.After(time.Second):
+			t.Fatal("capture did not end")
+		}
+	})
+
+	t.Run("unsupported format", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "capture.txt")
+		_, err := NewWriter(WriterParams{Path: path, Format: "txt"})
+		require.ErrorIs(t, err, ErrUnsupportedFormat)
+		_, err = os.Stat(path)
+		require.True(t, os.IsNotExist(err))
+	})
+}
@@ -62,6 +62,9 @@ var (
 	ErrNotVP8                            = errors.New("not VP8")
 	ErrOutOfOrderVP8PictureIdCacheMiss   = errors.New("out-of-order VP8 picture id not found in cache")
 	ErrFilteredVP8TemporalLayer          = errors.New("filtered VP8 temporal layer")
+	ErrNotVP9                            = errors.New("not VP9")
+	ErrOutOfOrderVP9PictureIdCacheMiss   = errors.New("out-of-order VP9 picture id not found in cache")
+	ErrFilteredVP9TemporalLayer          = errors.New("filtered VP9 temporal layer")
 	ErrDownTrackAlreadyBound             = errors.New("already bound")
 	ErrDownTrackClosed                   = errors.New("downtrack closed")
 )
@@ -133,6 +136,7 @@ type DownTrack struct {
 	id            livekit.TrackID
 	subscriberID  livekit.ParticipantID
 	bound         atomic.Bool
+	local         atomic.Bool
 	kind          webrtc.RTPCodecType
 	mime          string
 	ssrc          uint32
@@ -149,6 +153,7 @@ type DownTrack struct {
 	rtpHeaderExtensions     []webrtc.RTPHeaderExtensionParameter
 	absSendTimeID           int
 	dependencyDescriptorID  int
+	audioLevelID            int
 	receiver                TrackReceiver
 	transceiver             *webrtc.RTPTransceiver
 	writeStream             webrtc.TrackLocalWriter
@@ -208,6 +213,9 @@ type DownTrack struct {
 
 	// update rtt
 	onRttUpdate func(dt *DownTrack, rtt uint32)
+
+	captureLock sync.RWMutex
+	capture     buffer.PacketCapture
 }
 
 // NewDownTrack returns a DownTrack.
@@ -275,6 +283,18 @@ func NewDownTrack(
 // This asserts that the code requested is supported by the remote peer.
 // If so it sets up all the state (SSRC and PayloadType) to have a call
 func (d *DownTrack) Bind(t webrtc.TrackLocalContext) (webrtc.RTPCodecParameters, error) {
+	return d.bind(t.CodecParameters(), t.SSRC(), t.WriteStream())
+}
+
+// BindLocal binds to a writer on the server instead of a PeerConnection, for consumers such as recorders.
+// Packets are written with the given SSRC and the payload type of codec
+func (d *DownTrack) BindLocal(codec webrtc.RTPCodecParameters, ssrc webrtc.SSRC, writeStream webrtc.TrackLocalWriter) error {
+	d.local.Store(true)
+	_, err := d.bind([]webrtc.RTPCodecParameters{codec}, ssrc, writeStream)
+	return err
+}
+
+func (d *DownTrack) bind(codecs []webrtc.RTPCodecParameters, ssrc webrtc.SSRC, writeStream webrtc.TrackLocalWriter) (webrtc.RTPCodecParameters, error) {
 	d.bindLock.Lock()
 	if d.bound.Load() {
 		d.bindLock.Unlock()
@@ -282,7 +302,7 @@ func (d *DownTrack) Bind(t webrtc.TrackLocalContext) (webrtc.RTPCodecParameters,
 	}
 	var codec webrtc.RTPCodecParameters
 	for _, c := range d.upstreamCodecs {
-		matchCodec, err := codecParametersFuzzySearch(c, t.CodecParameters())
+		matchCodec, err := codecParametersFuzzySearch(c, codecs)
 		if err == nil {
 			codec = matchCodec
 			break
@@ -301,16 +321,19 @@ func (d *DownTrack) Bind(t webrtc.TrackLocalContext) (webrtc.RTPCodecParameters,
 		return codec, nil
 	}
 
-	d.logger.Debugw("DownTrack.Bind", "codecs", d.upstreamCodecs, "matchCodec", codec, "ssrc", t.SSRC())
-	d.ssrc = uint32(t.SSRC())
+	d.logger.Debugw("DownTrack.Bind", "codecs", d.upstreamCodecs, "matchCodec", codec, "ssrc", ssrc)
+	d.ssrc = uint32(ssrc)
 	d.payloadType = uint8(codec.PayloadType)
-	d.writeStream = t.WriteStream()
+	d.writeStream = &captureWriteStream{TrackLocalWriter: writeStream, d: d}
 	d.mime = strings.ToLower(codec.MimeType)
-	if rr := d.bufferFactory.GetOrNew(packetio.RTCPBufferPacket, uint32(t.SSRC())).(*buffer.RTCPReader); rr != nil {
-		rr.OnPacket(func(pkt []byte) {
-			d.handleRTCP(pkt)
-		})
-		d.rtcpReader = rr
+	// local consumers don't send RTCP
+	if !d.local.Load() {
+		if rr := d.bufferFactory.GetOrNew(packetio.RTCPBufferPacket, uint32(ssrc)).(*buffer.RTCPReader); rr != nil {
+			rr.OnPacket(func(pkt []byte) {
+				d.handleRTCP(pkt)
+			})
+			d.rtcpReader = rr
+		}
 	}
 
 	if d.kind == webrtc.RTPCodecTypeAudio {
@@ -332,6 +355,21 @@ func (d *DownTrack) Bind(t webrtc.TrackLocalContext) (webrtc.RTPCodecParameters,
 	return codec, nil
 }
 
+// SetCapture tees packets sent to the subscriber, and RTCP received from it, to c. nil stops capturing
+func (d *DownTrack) SetCapture(c buffer.PacketCapture) {
+	d.captureLock.Lock()
+	defer d.captureLock.Unlock()
+
+	d.capture = c
+}
+
+func (d *DownTrack) getCapture() buffer.PacketCapture {
+	d.captureLock.RLock()
+	defer d.captureLock.RUnlock()
+
+	return d.capture
+}
+
 // Unbind implements the teardown logic when the track is no longer needed. This happens
 // because a track has been stopped.
 func (d *DownTrack) Unbind(_ webrtc.TrackLocalContext) error {
@@ -369,6 +407,12 @@ func (d *DownTrack) SetRTPHeaderExtensions(rtpHeaderExtensions []webrtc.RTPHeade
 	}
 }
 
+// ForwardAudioLevel forwards the audio level header extension of the publisher with the given ID, as relays to
+// other nodes do. Subscribers learn audio levels from speaker updates instead. Must be called before binding
+func (d *DownTrack) ForwardAudioLevel(id int) {
+	d.audioLevelID = id
+}
+
 // Kind controls if this TrackLocal is audio or video
 func (d *DownTrack) Kind() webrtc.RTPCodecType {
 	return d.kind
@@ -479,6 +523,15 @@ func (d *DownTrack) WriteRTP(extPkt *buffer.ExtPacket, layer int32) error {
 			return err
 		}
 	}
+	if tp.vp9 != nil {
+		pool = PacketFactory.Get().(*[]byte)
+		payload, err = d.translateVP9PacketTo(extPkt.Packet, tp.vp9.Header, pool)
+		if err != nil {
+			d.pktsDropped.Inc()
+			d.logger.Errorw("write rtp packet failed", err)
+			return err
+		}
+	}
 
 	var meta *packetMeta
 	if d.sequencer != nil {
@@ -486,6 +539,9 @@ func (d *DownTrack) WriteRTP(extPkt *buffer.ExtPacket, layer int32) error {
 		if meta != nil && tp.vp8 != nil {
 			meta.packVP8(tp.vp8.Header)
 		}
+		if meta != nil && tp.vp9 != nil {
+			meta.packVP9(tp.vp9.Header)
+		}
 	}
 
 	hdr, err := d.getTranslatedRTPHeader(extPkt, tp)
@@ -934,6 +990,21 @@ func (d *DownTrack) Resync() {
 	d.forwarder.Resync()
 }
 
+// SwitchSource is called when the receiver feeding this DownTrack starts forwarding a different up track of the same codec.
+// Sequence numbers and timestamps stay continuous for the subscriber, packets of the previous up track are no longer
+// retransmitted, and forwarding resumes on a key frame of the new up track, requested right away.
+func (d *DownTrack) SwitchSource() {
+	d.forwarder.Resync()
+
+	d.bindLock.Lock()
+	if d.sequencer != nil {
+		d.sequencer.clear()
+	}
+	d.bindLock.Unlock()
+
+	d.maybeStartKeyFrameRequester()
+}
+
 func (d *DownTrack) CreateSourceDescriptionChunks() []rtcp.SourceDescriptionChunk {
 	if !d.bound.Load() {
 		return nil
@@ -966,8 +1037,8 @@ func (d *DownTrack) CreateSenderReport() *rtcp.SenderReport {
 func (d *DownTrack) writeBlankFrameRTP(duration float32, generation uint32) chan struct{} {
 	done := make(chan struct{})
 	go func() {
-		// don't send if nothing has been sent
-		if !d.rtpStats.IsActive() {
+		// don't send if nothing has been sent, or to local consumers, which have no decoder to clear
+		if !d.rtpStats.IsActive() || d.local.Load() {
 			close(done)
 			return
 		}
@@ -1116,6 +1187,10 @@ func (d *DownTrack) writeH264BlankFrame(hdr *rtp.Header, frameEndNeeded bool) (i
 }
 
 func (d *DownTrack) handleRTCP(bytes []byte) {
+	if capture := d.getCapture(); capture != nil {
+		capture.WriteRTCP(bytes, time.Now().UnixNano())
+	}
+
 	pkts, err := rtcp.Unmarshal(bytes)
 	if err != nil {
 		d.logger.Errorw("unmarshal rtcp receiver packets err", err)
@@ -1214,6 +1289,21 @@ func (d *DownTrack) handleRTCP(bytes []byte) {
 	}
 }
 
+// HandleRTCP processes RTCP sent back by a local consumer that has feedback, such as a relay to another node
+func (d *DownTrack) HandleRTCP(pkt []byte) {
+	d.handleRTCP(pkt)
+}
+
+// RequestKeyFrame asks the publisher for a key frame of the layer being forwarded,
+// for consumers that don't send RTCP
+func (d *DownTrack) RequestKeyFrame() {
+	targetLayers := d.forwarder.TargetLayers()
+	if targetLayers != InvalidLayers {
+		d.receiver.SendPLI(targetLayers.Spatial, false)
+		d.rtpStats.UpdatePliTime()
+	}
+}
+
 func (d *DownTrack) SetConnected() {
 	if !d.connected.Swap(true) {
 		if d.bound.Load() && d.kind == webrtc.RTPCodecTypeVideo {
@@ -1304,6 +1394,21 @@ func (d *DownTrack) retransmitPackets(nacks []uint16) {
 				continue
 			}
 		}
+		if d.mime == "video/vp9" && len(pkt.Payload) > 0 {
+			var incomingVP9 buffer.VP9
+			if err = incomingVP9.Unmarshal(pkt.Payload); err != nil {
+				d.logger.Errorw("unmarshalling VP9 packet err", err)
+				continue
+			}
+
+			translatedVP9 := meta.unpackVP9(&incomingVP9)
+			pool = PacketFactory.Get().(*[]byte)
+			payload, err = d.translateVP9PacketTo(&pkt, translatedVP9, pool)
+			if err != nil {
+				d.logger.Errorw("translating VP9 packet err", err)
+				continue
+			}
+		}
 
 		var extraExtensions []extensionData
 		if len(meta.ddBytes) > 0 {
@@ -1381,6 +1486,12 @@ func (d *DownTrack) getTranslatedRTPHeader(extPkt *buffer.ExtPacket, tp *Transla
 	}
 
 	var extension []extensionData
+	if d.audioLevelID != 0 && len(extPkt.AudioLevel) != 0 {
+		extension = append(extension, extensionData{
+			id:      uint8(d.audioLevelID),
+			payload: extPkt.AudioLevel,
+		})
+	}
 	if d.dependencyDescriptorID != 0 && tp.ddExtension != nil {
 		bytes, err := tp.ddExtension.Marshal()
 		if err != nil {
@@ -1410,6 +1521,15 @@ func (d *DownTrack) translateVP8PacketTo(pkt *rtp.Packet, incomingVP8 *buffer.VP
 	return buf, err
 }
 
+func (d *DownTrack) translateVP9PacketTo(pkt *rtp.Packet, translatedVP9 *buffer.VP9, outbuf *[]byte) ([]byte, error) {
+	// translation keeps the size of the payload descriptor, rewrite in place on a copy of the payload
+	buf := (*outbuf)[:len(pkt.Payload)]
+	copy(buf, pkt.Payload)
+
+	err := translatedVP9.MarshalTo(buf[:translatedVP9.HeaderSize])
+	return buf, err
+}
+
 func (d *DownTrack) DebugInfo() map[string]interface{} {
 	rtpMungerParams := d.forwarder.GetRTPMungerParams()
 	stats := map[string]interface{}{
@@ -1478,3 +1598,38 @@ func (d *DownTrack) GetNackStats() (totalPackets uint32, totalRepeatedNACKs uint
 
 	return
 }
+
+// -------------------------------------------------------------------
+
+// captureWriteStream passes packets through to the transport, teeing them to the capture of the DownTrack if any
+type captureWriteStream struct {
+	webrtc.TrackLocalWriter
+	d *DownTrack
+}
+
+func (c *captureWriteStream) WriteRTP(header *rtp.Header, payload []byte) (int, error) {
+	n, err := c.TrackLocalWriter.WriteRTP(header, payload)
+	if err != nil {
+		return n, err
+	}
+
+	if capture := c.d.getCapture(); capture != nil {
+		pkt := rtp.Packet{Header: *header, Payload: payload}
+		if buf, err := pkt.Marshal(); err == nil {
+			capture.WriteRTP(buf, time.Now().UnixNano())
+		}
+	}
+	return n, nil
+}
+
+func (c *captureWriteStream) Write(b []byte) (int, error) {
+	n, err := c.TrackLocalWriter.Write(b)
+	if err != nil {
+		return n, err
+	}
+
+	if capture := c.d.getCapture(); capture != nil {
+		capture.WriteRTP(b, time.Now().UnixNano())
+	}
+	return n, nil
+}
@@ -0,0 +1,60 @@
+package sfu
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pion/webrtc/v3"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/atomic"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+)
+
+type pliCountingReceiver struct {
+	TrackReceiver
+	plis atomic.Int32
+}
+
+func (r *pliCountingReceiver) TrackID() livekit.TrackID {
+	return "TR_test"
+}
+
+func (r *pliCountingReceiver) StreamID() string {
+	return "PA_test|TR_test"
+}
+
+func (r *pliCountingReceiver) SendPLI(_ int32, _ bool) {
+	r.plis.Inc()
+}
+
+func TestDownTrackSwitchSource(t *testing.T) {
+	receiver := &pliCountingReceiver{}
+	codec := webrtc.RTPCodecParameters{
+		RTPCodecCapability: webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeVP8, ClockRate: 90000},
+	}
+	d, err := NewDownTrack([]webrtc.RTPCodecParameters{codec}, receiver, nil, "PA_sub", 500, logger.GetDefaultLogger())
+	require.NoError(t, err)
+	defer d.stopKeyFrameRequester()
+
+	d.sequencer = newSequencer(500, 0, d.logger)
+	d.bound.Store(true)
+	d.connected.Store(true)
+	// forwarding a locked layer of the previous up track
+	d.forwarder.targetLayers = VideoLayers{Spatial: 0, Temporal: 0}
+	d.forwarder.currentLayers = VideoLayers{Spatial: 0, Temporal: 0}
+	for i := uint16(1); i <= 10; i++ {
+		d.sequencer.push(i, i+100, 123, 0)
+	}
+
+	d.SwitchSource()
+
+	// packets of the previous up track are not retransmitted
+	require.Empty(t, d.sequencer.getPacketsMeta([]uint16{105, 110}))
+	// forwarding waits for a key frame of the new up track, which is requested right away
+	require.Equal(t, InvalidLayers, d.forwarder.CurrentLayers())
+	require.Eventually(t, func() bool {
+		return receiver.plis.Load() > 0
+	}, time.Second, 10*time.Millisecond)
+}
@@ -144,6 +144,7 @@ type TranslationParams struct {
 	isSwitchingToMaxLayer bool
 	rtp                   *TranslationParamsRTP
 	vp8                   *TranslationParamsVP8
+	vp9                   *TranslationParamsVP9
 	ddExtension           *dd.DependencyDescriptorExtension
 	marker                bool
 
@@ -175,11 +176,12 @@ type ForwarderState struct {
 	LastTSCalc int64
 	RTP        RTPMungerState
 	VP8        VP8MungerState
+	VP9        VP9MungerState
 }
 
 func (f ForwarderState) String() string {
-	return fmt.Sprintf("ForwarderState{started: %v, lTSCalc: %d, rtp: %s, vp8: %s}",
-		f.Started, f.LastTSCalc, f.RTP.String(), f.VP8.String())
+	return fmt.Sprintf("ForwarderState{started: %v, lTSCalc: %d, rtp: %s, vp8: %s, vp9: %s}",
+		f.Started, f.LastTSCalc, f.RTP.String(), f.VP8.String(), f.VP9.String())
 }
 
 // -------------------------------------------------------------------
@@ -213,6 +215,9 @@ type Forwarder struct {
 	isTemporalSupported bool
 
 	ddLayerSelector *DDVideoLayerSelector
+
+	vp9Munger        *VP9Munger
+	vp9LayerSelector *VP9VideoLayerSelector
 }
 
 func NewForwarder(kind webrtc.RTPCodecType, logger logger.Logger) *Forwarder {
@@ -251,6 +256,10 @@ func (f *Forwarder) DetermineCodec(codec webrtc.RTPCodecCapability) {
 	case "video/vp8":
 		f.isTemporalSupported = true
 		f.vp8Munger = NewVP8Munger(f.logger)
+	case "video/vp9":
+		f.isTemporalSupported = true
+		f.vp9Munger = NewVP9Munger(f.logger)
+		f.vp9LayerSelector = NewVP9VideoLayerSelector(f.logger)
 	case "video/av1":
 		// TODO : we only enable dd layer selector for av1 now, at future we can
 		// enable it for vp9 too
@@ -275,6 +284,9 @@ func (f *Forwarder) GetState() ForwarderState {
 	if f.vp8Munger != nil {
 		state.VP8 = f.vp8Munger.GetLast()
 	}
+	if f.vp9Munger != nil {
+		state.VP9 = f.vp9Munger.GetLast()
+	}
 
 	return state
 }
@@ -292,6 +304,9 @@ func (f *Forwarder) SeedState(state ForwarderState) {
 	if f.vp8Munger != nil {
 		f.vp8Munger.SeedLast(state.VP8)
 	}
+	if f.vp9Munger != nil {
+		f.vp9Munger.SeedLast(state.VP9)
+	}
 
 	f.started = true
 }
@@ -1299,6 +1314,9 @@ func (f *Forwarder) setTargetLayers(targetLayers VideoLayers) {
 	if f.ddLayerSelector != nil {
 		f.ddLayerSelector.SelectLayer(targetLayers)
 	}
+	if f.vp9LayerSelector != nil {
+		f.vp9LayerSelector.SelectLayer(targetLayers)
+	}
 }
 
 func (f *Forwarder) Resync() {
@@ -1311,6 +1329,9 @@ func (f *Forwarder) Resync() {
 func (f *Forwarder) resyncLocked() {
 	f.currentLayers = InvalidLayers
 	f.lastSSRC = 0
+	if f.vp9LayerSelector != nil {
+		f.vp9LayerSelector.Reset()
+	}
 }
 
 func (f *Forwarder) CheckSync() (locked bool, layer int32) {
@@ -1381,6 +1402,9 @@ func (f *Forwarder) getTranslationParamsCommon(extPkt *buffer.ExtPacket, tp *Tra
 			if f.vp8Munger != nil {
 				f.vp8Munger.SetLast(extPkt)
 			}
+			if f.vp9Munger != nil {
+				f.vp9Munger.SetLast(extPkt)
+			}
 		} else {
 			// LK-TODO-START
 			// The below offset calculation is not technically correct.
@@ -1408,6 +1432,9 @@ func (f *Forwarder) getTranslationParamsCommon(extPkt *buffer.ExtPacket, tp *Tra
 			if f.vp8Munger != nil {
 				f.vp8Munger.UpdateOffsets(extPkt)
 			}
+			if f.vp9Munger != nil {
+				f.vp9Munger.UpdateOffsets(extPkt)
+			}
 		}
 
 		f.lastSSRC = extPkt.Packet.SSRC
@@ -1459,8 +1486,17 @@ func (f *Forwarder) getTranslationParamsVideo(extPkt *buffer.ExtPacket, layer in
 		}
 	}
 
+	if f.vp9LayerSelector != nil {
+		if selected := f.vp9LayerSelector.Select(extPkt, tp); !selected {
+			tp.shouldDrop = true
+			f.rtpMunger.PacketDropped(extPkt)
+			return tp, nil
+		}
+	}
+
 	if f.targetLayers.Spatial != f.currentLayers.Spatial {
-		if f.targetLayers.Spatial == layer {
+		// VP9 SVC switches at the start of a picture, i. e. on a base layer packet
+		if f.targetLayers.Spatial == layer || (f.vp9LayerSelector != nil && tp.switchingToTargetLayer) {
 			if extPkt.KeyFrame || tp.switchingToTargetLayer {
 				// lock to target layer
 				f.logger.Infow("locking to target layer", "current", f.currentLayers, "target", f.targetLayers)
@@ -1481,7 +1517,7 @@ func (f *Forwarder) getTranslationParamsVideo(extPkt *buffer.ExtPacket, layer in
 	}
 
 	// if we have layer selector, let it decide whether to drop or not
-	if f.ddLayerSelector == nil && f.currentLayers.Spatial != layer {
+	if f.ddLayerSelector == nil && f.vp9LayerSelector == nil && f.currentLayers.Spatial != layer {
 		tp.shouldDrop = true
 		return tp, nil
 	}
@@ -1511,6 +1547,9 @@ func (f *Forwarder) getTranslationParamsVideo(extPkt *buffer.ExtPacket, layer in
 	}
 
 	_, err := f.getTranslationParamsCommon(extPkt, tp)
+	if f.vp9Munger != nil && !tp.shouldDrop && len(extPkt.Packet.Payload) != 0 {
+		return f.getTranslationParamsVP9(extPkt, tp)
+	}
 	if tp.shouldDrop || f.vp8Munger == nil || len(extPkt.Packet.Payload) == 0 {
 		return tp, err
 	}
@@ -1548,6 +1587,41 @@ func (f *Forwarder) getTranslationParamsVideo(extPkt *buffer.ExtPacket, layer in
 	return tp, nil
 }
 
+// should be called with lock held
+func (f *Forwarder) getTranslationParamsVP9(extPkt *buffer.ExtPacket, tp *TranslationParams) (*TranslationParams, error) {
+	// catch up temporal layer if necessary
+	if f.currentLayers.Temporal != f.targetLayers.Temporal {
+		incomingVP9, ok := extPkt.Payload.(buffer.VP9)
+		if ok {
+			if incomingVP9.L && incomingVP9.TID <= uint8(f.targetLayers.Temporal) {
+				f.currentLayers.Temporal = f.targetLayers.Temporal
+			}
+		}
+	}
+
+	tpVP9, err := f.vp9Munger.UpdateAndGet(extPkt, tp.rtp.snOrdering, f.currentLayers.Temporal)
+	if err != nil {
+		tp.rtp = nil
+		tp.shouldDrop = true
+		if err == ErrFilteredVP9TemporalLayer || err == ErrOutOfOrderVP9PictureIdCacheMiss {
+			if err == ErrFilteredVP9TemporalLayer {
+				// filtered temporal layer, update sequence number offset to prevent holes
+				f.rtpMunger.PacketDropped(extPkt)
+			}
+			if err == ErrOutOfOrderVP9PictureIdCacheMiss {
+				tp.isDroppingRelevant = true
+			}
+			return tp, nil
+		}
+
+		tp.isDroppingRelevant = true
+		return tp, err
+	}
+
+	tp.vp9 = tpVP9
+	return tp, nil
+}
+
 func (f *Forwarder) GetSnTsForPadding(num int) ([]SnTs, error) {
 	f.lock.Lock()
 	defer f.lock.Unlock()
@@ -65,6 +65,22 @@ type TrackReceiver interface {
 	GetTemporalLayerFpsForSpatial(layer int32) []float32
 }
 
+// TrackRemote is an incoming stream of a track, a webrtc.TrackRemote or one received outside of a PeerConnection
+type TrackRemote interface {
+	ID() string
+	StreamID() string
+	RID() string
+	Msid() string
+	SSRC() webrtc.SSRC
+	Kind() webrtc.RTPCodecType
+	Codec() webrtc.RTPCodecParameters
+}
+
+// RTPReceiver provides parameters negotiated for a TrackRemote, it's a webrtc.RTPReceiver for a PeerConnection
+type RTPReceiver interface {
+	GetParameters() webrtc.RTPParameters
+}
+
 // WebRTCReceiver receives a media track
 type WebRTCReceiver struct {
 	logger logger.Logger
@@ -75,7 +91,7 @@ type WebRTCReceiver struct {
 	trackID        livekit.TrackID
 	streamID       string
 	kind           webrtc.RTPCodecType
-	receiver       *webrtc.RTPReceiver
+	receiver       RTPReceiver
 	codec          webrtc.RTPCodecParameters
 	isSimulcast    bool
 	isSVC          bool
@@ -93,9 +109,10 @@ type WebRTCReceiver struct {
 	bufferMu sync.RWMutex
 	buffers  [DefaultMaxLayerSpatial + 1]*buffer.Buffer
 	rtt      uint32
+	capture  buffer.PacketCapture
 
 	upTrackMu sync.RWMutex
-	upTracks  [DefaultMaxLayerSpatial + 1]*webrtc.TrackRemote
+	upTracks  [DefaultMaxLayerSpatial + 1]TrackRemote
 
 	lbThreshold int
 
@@ -167,8 +184,8 @@ func WithLoadBalanceThreshold(downTracks int) ReceiverOpts {
 
 // NewWebRTCReceiver creates a new webrtc track receiver
 func NewWebRTCReceiver(
-	receiver *webrtc.RTPReceiver,
-	track *webrtc.TrackRemote,
+	receiver RTPReceiver,
+	track TrackRemote,
 	trackInfo *livekit.TrackInfo,
 	logger logger.Logger,
 	twcc *twcc.Responder,
@@ -292,7 +309,7 @@ func (w *WebRTCReceiver) Kind() webrtc.RTPCodecType {
 	return w.kind
 }
 
-func (w *WebRTCReceiver) AddUpTrack(track *webrtc.TrackRemote, buff *buffer.Buffer) {
+func (w *WebRTCReceiver) AddUpTrack(track TrackRemote, buff *buffer.Buffer) {
 	if w.closed.Load() {
 		return
 	}
@@ -333,8 +350,12 @@ func (w *WebRTCReceiver) AddUpTrack(track *webrtc.TrackRemote, buff *buffer.Buff
 	w.bufferMu.Lock()
 	w.buffers[layer] = buff
 	rtt := w.rtt
+	capture := w.capture
 	w.bufferMu.Unlock()
 	buff.SetRTT(rtt)
+	if capture != nil {
+		buff.SetCapture(capture)
+	}
 
 	if w.Kind() == webrtc.RTPCodecTypeVideo && w.useTrackers {
 		w.streamTrackerManager.AddTracker(layer)
@@ -426,6 +447,20 @@ func (w *WebRTCReceiver) SendPLI(layer int32, force bool) {
 	buff.SendPLI(force)
 }
 
+// SetCapture tees packets received on all layers to c, nil stops capturing
+func (w *WebRTCReceiver) SetCapture(c buffer.PacketCapture) {
+	w.bufferMu.Lock()
+	w.capture = c
+	buffers := w.buffers
+	w.bufferMu.Unlock()
+
+	for _, buff := range buffers {
+		if buff != nil {
+			buff.SetCapture(c)
+		}
+	}
+}
+
 func (w *WebRTCReceiver) SetRTCPCh(ch chan []rtcp.Packet) {
 	w.rtcpCh = ch
 }
@@ -0,0 +1,93 @@
+package recorder
+
+import (
+	"strings"
+
+	"github.com/pion/rtp"
+	"github.com/pion/rtp/codecs"
+	"github.com/pion/webrtc/v3"
+)
+
+const (
+	h264NALUTypeIDR = 5
+	h264NALUTypeSPS = 7
+)
+
+// DefaultFormat returns the format a codec is recorded in when none is requested, empty if it can't be recorded
+func DefaultFormat(mimeType string) Format {
+	switch strings.ToLower(mimeType) {
+	case strings.ToLower(webrtc.MimeTypeOpus):
+		return FormatOgg
+	case strings.ToLower(webrtc.MimeTypeVP8):
+		return FormatWebM
+	case strings.ToLower(webrtc.MimeTypeH264):
+		return FormatIVF
+	default:
+		return ""
+	}
+}
+
+// FormatSupports returns whether a format can hold a codec
+func FormatSupports(format Format, mimeType string) bool {
+	switch strings.ToLower(mimeType) {
+	case strings.ToLower(webrtc.MimeTypeOpus):
+		return format == FormatOgg || format == FormatWebM
+	case strings.ToLower(webrtc.MimeTypeVP8):
+		return format == FormatIVF || format == FormatWebM
+	case strings.ToLower(webrtc.MimeTypeH264):
+		return format == FormatIVF
+	default:
+		return false
+	}
+}
+
+func newDepacketizer(mimeType string) rtp.Depacketizer {
+	switch strings.ToLower(mimeType) {
+	case strings.ToLower(webrtc.MimeTypeVP8):
+		return &codecs.VP8Packet{}
+	case strings.ToLower(webrtc.MimeTypeH264):
+		return &codecs.H264Packet{}
+	default:
+		return &codecs.OpusPacket{}
+	}
+}
+
+// isFrameStart returns whether a depacketized packet begins a frame
+func isFrameStart(depacketizer rtp.Depacketizer, payload []byte) bool {
+	if vp8, ok := depacketizer.(*codecs.VP8Packet); ok {
+		return vp8.S == 1 && vp8.PID == 0
+	}
+	return depacketizer.IsPartitionHead(payload)
+}
+
+func isKeyFrame(mimeType string, frame []byte) bool {
+	switch strings.ToLower(mimeType) {
+	case strings.ToLower(webrtc.MimeTypeVP8):
+		// inverse key frame flag of the frame tag
+		return len(frame) != 0 && frame[0]&0x01 == 0
+	case strings.ToLower(webrtc.MimeTypeH264):
+		// frames are in Annex B, a start code precedes each NAL unit
+		for i := 0; i+3 < len(frame); i++ {
+			if frame[i] != 0 || frame[i+1] != 0 || frame[i+2] != 1 {
+				continue
+			}
+			switch frame[i+3] & 0x1f {
+			case h264NALUTypeIDR, h264NALUTypeSPS:
+				return true
+			}
+		}
+		return false
+	default:
+		return true
+	}
+}
+
+// vp8FrameSize returns dimensions from the uncompressed header of a VP8 key frame (RFC 6386, section 9.1)
+func vp8FrameSize(frame []byte) (uint16, uint16, bool) {
+	if len(frame) < 10 || frame[0]&0x01 != 0 || frame[3] != 0x9d || frame[4] != 0x01 || frame[5] != 0x2a {
+		return 0, 0, false
+	}
+	width := (uint16(frame[6]) | uint16(frame[7])<<8) & 0x3fff
+	height := (uint16(frame[8]) | uint16(frame[9])<<8) & 0x3fff
+	return width, height, true
+}
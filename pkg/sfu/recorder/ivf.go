@@ -0,0 +1,69 @@
+package recorder
+
+import (
+	"encoding/binary"
+	"io"
+	"time"
+)
+
+const (
+	ivfFileHeaderSize  = 32
+	ivfFrameHeaderSize = 12
+)
+
+// ivfWriter writes frames in an IVF container, with timestamps in the RTP clock rate of the codec. The header is
+// written with the first frame, a key frame, so that it can carry the frame size.
+type ivfWriter struct {
+	out       io.Writer
+	fourcc    string
+	clockRate uint32
+
+	headerWritten bool
+}
+
+func newIVFWriter(out io.Writer, fourcc string, clockRate uint32) *ivfWriter {
+	return &ivfWriter{
+		out:       out,
+		fourcc:    fourcc,
+		clockRate: clockRate,
+	}
+}
+
+func (w *ivfWriter) writeFrame(frame []byte, pts time.Duration, _ bool) error {
+	if !w.headerWritten {
+		if err := w.writeHeader(frame); err != nil {
+			return err
+		}
+		w.headerWritten = true
+	}
+
+	header := make([]byte, ivfFrameHeaderSize)
+	binary.LittleEndian.PutUint32(header, uint32(len(frame)))
+	// rounded, as pts is truncated when converted from the clock rate
+	binary.LittleEndian.PutUint64(header[4:], (uint64(pts/time.Microsecond)*uint64(w.clockRate)+5e5)/1e6)
+	if _, err := w.out.Write(header); err != nil {
+		return err
+	}
+	_, err := w.out.Write(frame)
+	return err
+}
+
+func (w *ivfWriter) writeHeader(keyFrame []byte) error {
+	header := make([]byte, ivfFileHeaderSize)
+	copy(header, "DKIF")
+	binary.LittleEndian.PutUint16(header[6:], ivfFileHeaderSize)
+	copy(header[8:], w.fourcc)
+	if width, height, ok := vp8FrameSize(keyFrame); ok && w.fourcc == "VP80" {
+		binary.LittleEndian.PutUint16(header[12:], width)
+		binary.LittleEndian.PutUint16(header[14:], height)
+	}
+	binary.LittleEndian.PutUint32(header[16:], w.clockRate)
+	binary.LittleEndian.PutUint32(header[20:], 1)
+	// frame count is left at 0, as the file is written as a stream
+	_, err := w.out.Write(header)
+	return err
+}
+
+func (w *ivfWriter) close() error {
+	return nil
+}
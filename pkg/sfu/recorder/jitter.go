@@ -0,0 +1,91 @@
+package recorder
+
+import (
+	"time"
+
+	"github.com/pion/rtp"
+)
+
+type pendingPacket struct {
+	packet      *rtp.Packet
+	arrivalTime time.Time
+}
+
+// jitterBuffer puts packets back in sequence number order. A missing packet is given up on once packets after it
+// have waited for latency, or when too many are waiting.
+type jitterBuffer struct {
+	latency    time.Duration
+	maxPending int
+
+	started bool
+	// extended sequence numbers, so that ordering survives wrap around
+	highest uint64
+	next    uint64
+	pending map[uint64]pendingPacket
+}
+
+func newJitterBuffer(latency time.Duration, maxPending int) *jitterBuffer {
+	return &jitterBuffer{
+		latency:    latency,
+		maxPending: maxPending,
+		pending:    make(map[uint64]pendingPacket),
+	}
+}
+
+func (j *jitterBuffer) push(pkt *rtp.Packet, arrivalTime time.Time) {
+	var esn uint64
+	if !j.started {
+		// leave room for packets that arrive before the first one
+		esn = 1<<16 + uint64(pkt.SequenceNumber)
+		j.started = true
+		j.highest = esn
+		j.next = esn
+	} else {
+		esn = uint64(int64(j.highest) + int64(int16(pkt.SequenceNumber-uint16(j.highest))))
+		if esn > j.highest {
+			j.highest = esn
+		}
+	}
+
+	// too late, or a duplicate
+	if esn < j.next {
+		return
+	}
+	if _, ok := j.pending[esn]; ok {
+		return
+	}
+	j.pending[esn] = pendingPacket{packet: pkt, arrivalTime: arrivalTime}
+}
+
+// pop returns the next packet in order, and whether packets were lost before it. nil is returned when the next
+// packet can still arrive
+func (j *jitterBuffer) pop(now time.Time) (*rtp.Packet, bool) {
+	if p, ok := j.pending[j.next]; ok {
+		delete(j.pending, j.next)
+		j.next++
+		return p.packet, false
+	}
+	if len(j.pending) == 0 {
+		return nil, false
+	}
+
+	// packets after a gap have waited long enough, skip to the earliest of them
+	earliest := uint64(0)
+	expired := len(j.pending) > j.maxPending
+	for esn, p := range j.pending {
+		if earliest == 0 || esn < earliest {
+			earliest = esn
+		}
+		if now.Sub(p.arrivalTime) >= j.latency {
+			expired = true
+		}
+	}
+	if !expired {
+		return nil, false
+	}
+
+	p := j.pending[earliest]
+	delete(j.pending, earliest)
+	j.next = earliest + 1
+	return p.packet, true
+}
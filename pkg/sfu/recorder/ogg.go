@@ -0,0 +1,170 @@
+package recorder
+
+import (
+	"encoding/binary"
+	"io"
+	"time"
+)
+
+const (
+	oggPageHeaderSize = 27
+
+	oggFlagBeginningOfStream = 0x02
+	oggFlagEndOfStream       = 0x04
+)
+
+var oggCRCTable = func() *[256]uint32 {
+	var table [256]uint32
+	for i := range table {
+		r := uint32(i) << 24
+		for j := 0; j < 8; j++ {
+			if r&0x80000000 != 0 {
+				r = r<<1 ^ 0x04c11db7
+			} else {
+				r <<= 1
+			}
+		}
+		table[i] = r
+	}
+	return &table
+}()
+
+// oggWriter writes an Ogg Opus stream (RFC 7845), one packet to a page. The last page is held back so that it can
+// be marked as the end of the stream.
+type oggWriter struct {
+	out    io.Writer
+	serial uint32
+
+	pageIndex uint32
+	granule   uint64
+	lastPage  []byte
+}
+
+func newOggWriter(out io.Writer, channels uint16, serial uint32) (*oggWriter, error) {
+	if channels == 0 {
+		channels = 2
+	}
+	w := &oggWriter{
+		out:    out,
+		serial: serial,
+	}
+
+	if _, err := out.Write(w.page(opusHead(channels), 0, oggFlagBeginningOfStream)); err != nil {
+		return nil, err
+	}
+
+	vendor := "livekit"
+	tags := make([]byte, 8+4+len(vendor)+4)
+	copy(tags, "OpusTags")
+	binary.LittleEndian.PutUint32(tags[8:], uint32(len(vendor)))
+	copy(tags[12:], vendor)
+	if _, err := out.Write(w.page(tags, 0, 0)); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *oggWriter) writeFrame(frame []byte, _ time.Duration, _ bool) error {
+	if err := w.flushLastPage(0); err != nil {
+		return err
+	}
+	w.granule += uint64(opusPacketSamples(frame))
+	w.lastPage = w.page(frame, w.granule, 0)
+	return nil
+}
+
+func (w *oggWriter) close() error {
+	if w.lastPage == nil {
+		// nothing to end, write an empty page so the stream is still terminated
+		w.lastPage = w.page(nil, w.granule, 0)
+	}
+	return w.flushLastPage(oggFlagEndOfStream)
+}
+
+func (w *oggWriter) flushLastPage(flags byte) error {
+	if w.lastPage == nil {
+		return nil
+	}
+	page := w.lastPage
+	w.lastPage = nil
+	if flags != 0 {
+		page[5] |= flags
+		setOggChecksum(page)
+	}
+	_, err := w.out.Write(page)
+	return err
+}
+
+func (w *oggWriter) page(payload []byte, granule uint64, flags byte) []byte {
+	// lacing values, a packet ends with a segment shorter than 255
+	numSegments := len(payload)/255 + 1
+	page := make([]byte, oggPageHeaderSize+numSegments+len(payload))
+	copy(page, "OggS")
+	page[5] = flags
+	binary.LittleEndian.PutUint64(page[6:], granule)
+	binary.LittleEndian.PutUint32(page[14:], w.serial)
+	binary.LittleEndian.PutUint32(page[18:], w.pageIndex)
+	page[26] = byte(numSegments)
+	for i := 0; i < numSegments-1; i++ {
+		page[oggPageHeaderSize+i] = 255
+	}
+	page[oggPageHeaderSize+numSegments-1] = byte(len(payload) % 255)
+	copy(page[oggPageHeaderSize+numSegments:], payload)
+	setOggChecksum(page)
+
+	w.pageIndex++
+	return page
+}
+
+// opusHead returns the identification header of an Opus stream, also used as codec private data in WebM
+func opusHead(channels uint16) []byte {
+	head := make([]byte, 19)
+	copy(head, "OpusHead")
+	head[8] = 1
+	head[9] = byte(channels)
+	// input sample rate, informational only
+	binary.LittleEndian.PutUint32(head[12:], 48000)
+	return head
+}
+
+func setOggChecksum(page []byte) {
+	binary.LittleEndian.PutUint32(page[22:], 0)
+	var crc uint32
+	for _, b := range page {
+		crc = crc<<8 ^ oggCRCTable[byte(crc>>24)^b]
+	}
+	binary.LittleEndian.PutUint32(page[22:], crc)
+}
+
+// opusPacketSamples returns the number of samples at 48kHz in an Opus packet, from its TOC byte (RFC 6716)
+func opusPacketSamples(packet []byte) int {
+	if len(packet) == 0 {
+		return 0
+	}
+
+	var frameSamples int
+	config := packet[0] >> 3
+	switch {
+	case config < 12:
+		// SILK, 10, 20, 40 or 60 ms
+		frameSamples = []int{480, 960, 1920, 2880}[config&0x03]
+	case config < 16:
+		// hybrid, 10 or 20 ms
+		frameSamples = []int{480, 960}[config&0x01]
+	default:
+		// CELT, 2.5, 5, 10 or 20 ms
+		frameSamples = []int{120, 240, 480, 960}[config&0x03]
+	}
+
+	switch packet[0] & 0x03 {
+	case 0:
+		return frameSamples
+	case 1, 2:
+		return 2 * frameSamples
+	default:
+		if len(packet) < 2 {
+			return 0
+		}
+		return int(packet[1]&0x3f) * frameSamples
+	}
+}
@@ -0,0 +1,228 @@
+package recorder
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"time"
+)
+
+// Matroska element IDs used, see https://www.matroska.org/technical/elements.html
+const (
+	ebmlIDHeader             = 0x1a45dfa3
+	ebmlIDVersion            = 0x4286
+	ebmlIDReadVersion        = 0x42f7
+	ebmlIDMaxIDLength        = 0x42f2
+	ebmlIDMaxSizeLength      = 0x42f3
+	ebmlIDDocType            = 0x4282
+	ebmlIDDocTypeVersion     = 0x4287
+	ebmlIDDocTypeReadVersion = 0x4285
+
+	mkvIDSegment           = 0x18538067
+	mkvIDInfo              = 0x1549a966
+	mkvIDTimecodeScale     = 0x2ad7b1
+	mkvIDMuxingApp         = 0x4d80
+	mkvIDWritingApp        = 0x5741
+	mkvIDTracks            = 0x1654ae6b
+	mkvIDTrackEntry        = 0xae
+	mkvIDTrackNumber       = 0xd7
+	mkvIDTrackUID          = 0x73c5
+	mkvIDTrackType         = 0x83
+	mkvIDCodecID           = 0x86
+	mkvIDCodecPrivate      = 0x63a2
+	mkvIDCodecDelay        = 0x56aa
+	mkvIDSeekPreRoll       = 0x56bb
+	mkvIDVideo             = 0xe0
+	mkvIDPixelWidth        = 0xb0
+	mkvIDPixelHeight       = 0xba
+	mkvIDAudio             = 0xe1
+	mkvIDSamplingFrequency = 0xb5
+	mkvIDChannels          = 0x9f
+	mkvIDCluster           = 0x1f43b675
+	mkvIDTimecode          = 0xe7
+	mkvIDSimpleBlock       = 0xa3
+
+	mkvTrackTypeVideo = 1
+	mkvTrackTypeAudio = 2
+
+	// clusters are written as they go, without knowing their size
+	ebmlUnknownSize = 0x01ffffffffffffff
+
+	// block timecodes are 16 bit offsets from the cluster's
+	webmMaxClusterDuration = 5 * time.Second
+	opusSeekPreRoll        = 80 * time.Millisecond
+)
+
+// webmWriter writes a single Opus or VP8 track in a WebM container. Segment and clusters are of unknown size, so
+// that the file is playable without being finalized. Timecodes are in milliseconds.
+type webmWriter struct {
+	out      io.Writer
+	isVideo  bool
+	codecID  string
+	channels uint16
+
+	headerWritten bool
+	clusterOpen   bool
+	clusterStart  time.Duration
+}
+
+func newWebMWriter(out io.Writer, isVideo bool, codecID string, channels uint16) *webmWriter {
+	if channels == 0 {
+		channels = 2
+	}
+	return &webmWriter{
+		out:      out,
+		isVideo:  isVideo,
+		codecID:  codecID,
+		channels: channels,
+	}
+}
+
+func (w *webmWriter) writeFrame(frame []byte, pts time.Duration, keyFrame bool) error {
+	if !w.headerWritten {
+		if err := w.writeHeader(frame); err != nil {
+			return err
+		}
+		w.headerWritten = true
+	}
+
+	pts = pts.Truncate(time.Millisecond)
+	// clusters start at video key frames, so that players can seek to them
+	if !w.clusterOpen || pts-w.clusterStart >= webmMaxClusterDuration || (w.isVideo && keyFrame && pts > w.clusterStart) {
+		cluster := &bytes.Buffer{}
+		writeEBMLID(cluster, mkvIDCluster)
+		writeEBMLSize(cluster, ebmlUnknownSize)
+		writeEBMLUint(cluster, mkvIDTimecode, uint64(pts/time.Millisecond))
+		if _, err := w.out.Write(cluster.Bytes()); err != nil {
+			return err
+		}
+		w.clusterOpen = true
+		w.clusterStart = pts
+	}
+
+	block := make([]byte, 4, 4+len(frame))
+	// track number as a vint
+	block[0] = 0x81
+	binary.BigEndian.PutUint16(block[1:], uint16(int16((pts-w.clusterStart)/time.Millisecond)))
+	if keyFrame || !w.isVideo {
+		block[3] = 0x80
+	}
+	block = append(block, frame...)
+
+	element := &bytes.Buffer{}
+	writeEBMLElement(element, mkvIDSimpleBlock, block)
+	_, err := w.out.Write(element.Bytes())
+	return err
+}
+
+func (w *webmWriter) writeHeader(keyFrame []byte) error {
+	header := &bytes.Buffer{}
+
+	ebml := &bytes.Buffer{}
+	writeEBMLUint(ebml, ebmlIDVersion, 1)
+	writeEBMLUint(ebml, ebmlIDReadVersion, 1)
+	writeEBMLUint(ebml, ebmlIDMaxIDLength, 4)
+	writeEBMLUint(ebml, ebmlIDMaxSizeLength, 8)
+	writeEBMLElement(ebml, ebmlIDDocType, []byte("webm"))
+	writeEBMLUint(ebml, ebmlIDDocTypeVersion, 4)
+	writeEBMLUint(ebml, ebmlIDDocTypeReadVersion, 2)
+	writeEBMLElement(header, ebmlIDHeader, ebml.Bytes())
+
+	writeEBMLID(header, mkvIDSegment)
+	writeEBMLSize(header, ebmlUnknownSize)
+
+	info := &bytes.Buffer{}
+	writeEBMLUint(info, mkvIDTimecodeScale, uint64(time.Millisecond))
+	writeEBMLElement(info, mkvIDMuxingApp, []byte("livekit"))
+	writeEBMLElement(info, mkvIDWritingApp, []byte("livekit"))
+	writeEBMLElement(header, mkvIDInfo, info.Bytes())
+
+	track := &bytes.Buffer{}
+	writeEBMLUint(track, mkvIDTrackNumber, 1)
+	writeEBMLUint(track, mkvIDTrackUID, 1)
+	writeEBMLElement(track, mkvIDCodecID, []byte(w.codecID))
+	if w.isVideo {
+		writeEBMLUint(track, mkvIDTrackType, mkvTrackTypeVideo)
+		width, height, _ := vp8FrameSize(keyFrame)
+		video := &bytes.Buffer{}
+		writeEBMLUint(video, mkvIDPixelWidth, uint64(width))
+		writeEBMLUint(video, mkvIDPixelHeight, uint64(height))
+		writeEBMLElement(track, mkvIDVideo, video.Bytes())
+	} else {
+		writeEBMLUint(track, mkvIDTrackType, mkvTrackTypeAudio)
+		writeEBMLElement(track, mkvIDCodecPrivate, opusHead(w.channels))
+		writeEBMLUint(track, mkvIDCodecDelay, 0)
+		writeEBMLUint(track, mkvIDSeekPreRoll, uint64(opusSeekPreRoll))
+		audio := &bytes.Buffer{}
+		writeEBMLFloat(audio, mkvIDSamplingFrequency, 48000)
+		writeEBMLUint(audio, mkvIDChannels, uint64(w.channels))
+		writeEBMLElement(track, mkvIDAudio, audio.Bytes())
+	}
+	tracks := &bytes.Buffer{}
+	writeEBMLElement(tracks, mkvIDTrackEntry, track.Bytes())
+	writeEBMLElement(header, mkvIDTracks, tracks.Bytes())
+
+	_, err := w.out.Write(header.Bytes())
+	return err
+}
+
+func (w *webmWriter) close() error {
+	return nil
+}
+
+func writeEBMLID(b *bytes.Buffer, id uint32) {
+	switch {
+	case id > 0xffffff:
+		b.WriteByte(byte(id >> 24))
+		fallthrough
+	case id > 0xffff:
+		b.WriteByte(byte(id >> 16))
+		fallthrough
+	case id > 0xff:
+		b.WriteByte(byte(id >> 8))
+		fallthrough
+	default:
+		b.WriteByte(byte(id))
+	}
+}
+
+// writeEBMLSize writes a size as a variable length integer of the fewest bytes that hold it
+func writeEBMLSize(b *bytes.Buffer, size uint64) {
+	if size == ebmlUnknownSize {
+		b.Write([]byte{0x01, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff})
+		return
+	}
+
+	length := 1
+	// all ones is reserved for unknown sizes
+	for length < 8 && size >= 1<<(7*length)-1 {
+		length++
+	}
+	size |= 1 << (7 * length)
+	for i := length - 1; i >= 0; i-- {
+		b.WriteByte(byte(size >> (8 * i)))
+	}
+}
+
+func writeEBMLElement(b *bytes.Buffer, id uint32, data []byte) {
+	writeEBMLID(b, id)
+	writeEBMLSize(b, uint64(len(data)))
+	b.Write(data)
+}
+
+func writeEBMLUint(b *bytes.Buffer, id uint32, value uint64) {
+	data := make([]byte, 8)
+	binary.BigEndian.PutUint64(data, value)
+	i := 0
+	for i < 7 && data[i] == 0 {
+		i++
+	}
+	writeEBMLElement(b, id, data[i:])
+}
+
+func writeEBMLFloat(b *bytes.Buffer, id uint32, value float64) {
+	data := make([]byte, 8)
+	binary.BigEndian.PutUint64(data, math.Float64bits(value))
+	writeEBMLElement(b, id, data)
+}
@@ -0,0 +1,501 @@
+package recorder
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"math/rand"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v3"
+	"go.uber.org/atomic"
+
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/sfu"
+)
+
+type Format string
+
+const (
+	// FormatOgg writes Opus audio in an Ogg container
+	FormatOgg Format = "ogg"
+	// FormatIVF writes VP8 or H.264 video in an IVF container
+	FormatIVF Format = "ivf"
+	// FormatWebM writes Opus audio or VP8 video in a WebM container
+	FormatWebM Format = "webm"
+
+	packetQueueSize = 1024
+	// packets held back waiting for a missing one, beyond which it's considered lost regardless of latency
+	maxPendingPackets = 512
+	releaseInterval   = 20 * time.Millisecond
+	DefaultLatency    = 500 * time.Millisecond
+
+	keyFrameRequestInterval = time.Second
+
+	opusClockRate          = 48000
+	opusSilenceFrameLength = 960
+	// timestamp jumps larger than this are taken as a reset of the stream rather than loss to fill
+	maxSilenceFill = 10 * time.Minute
+)
+
+var (
+	ErrUnsupportedCodec  = errors.New("codec cannot be recorded")
+	ErrUnsupportedFormat = errors.New("format cannot hold codec")
+)
+
+// mediaWriter writes frames to a container
+type mediaWriter interface {
+	// writeFrame writes a complete frame, pts is relative to the first frame written
+	writeFrame(frame []byte, pts time.Duration, keyFrame bool) error
+	close() error
+}
+
+type WriterParams struct {
+	Path   string
+	Format Format
+	Codec  webrtc.RTPCodecCapability
+	// how long packets are held to be put back in order, a missing packet is considered lost after that
+	Latency time.Duration
+	// called after loss, recording of video resumes from the next key frame
+	RequestKeyFrame func()
+	Logger          logger.Logger
+}
+
+type WriterStats struct {
+	Frames int64
+	// packets lost before reaching the writer, or dropped because it fell behind
+	Lost int64
+	// frames that could not be written, as they were incomplete or depended on an incomplete frame
+	Dropped int64
+	// duration of silence inserted for lost audio
+	Filled   time.Duration
+	Duration time.Duration
+	Size     int64
+}
+
+// Writer records a track to a file. It implements webrtc.TrackLocalWriter, so that a DownTrack can forward to it.
+// Packets are put back in order and depacketized into frames from a separate goroutine. After loss, audio is
+// filled with silence to keep its timing, and video is frozen until the next key frame.
+type Writer struct {
+	params    WriterParams
+	file      *os.File
+	w         *bufio.Writer
+	mw        mediaWriter
+	isVideo   bool
+	startedAt time.Time
+
+	packets   chan *rtp.Packet
+	closed    atomic.Bool
+	stop      chan struct{}
+	stopOnce  sync.Once
+	done      chan struct{}
+	numFrames atomic.Int64
+	numLost   atomic.Int64
+	numDrops  atomic.Int64
+	filled    atomic.Duration
+	duration  atomic.Duration
+	size      atomic.Int64
+	err       error
+	onCloseMu sync.Mutex
+	onClose   []func()
+	finished  bool
+
+	// owned by worker
+	jitter              *jitterBuffer
+	depacketizer        rtp.Depacketizer
+	frame               []byte
+	frameTS             uint32
+	frameStarted        bool
+	needKeyFrame        bool
+	lastKeyFrameRequest time.Time
+	tsStarted           bool
+	firstTS             int64
+	lastTS              int64
+	nextAudioTS         int64
+}
+
+func NewWriter(params WriterParams) (*Writer, error) {
+	if params.Format == "" {
+		params.Format = DefaultFormat(params.Codec.MimeType)
+	}
+	if params.Format == "" {
+		return nil, ErrUnsupportedCodec
+	}
+	if !FormatSupports(params.Format, params.Codec.MimeType) {
+		return nil, ErrUnsupportedFormat
+	}
+	if params.Latency <= 0 {
+		params.Latency = DefaultLatency
+	}
+
+	file, err := os.OpenFile(params.Path, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &Writer{
+		params:       params,
+		file:         file,
+		isVideo:      strings.HasPrefix(strings.ToLower(params.Codec.MimeType), "video/"),
+		startedAt:    time.Now(),
+		packets:      make(chan *rtp.Packet, packetQueueSize),
+		stop:         make(chan struct{}),
+		done:         make(chan struct{}),
+		jitter:       newJitterBuffer(params.Latency, maxPendingPackets),
+		depacketizer: newDepacketizer(params.Codec.MimeType),
+	}
+	w.w = bufio.NewWriter(&countingWriter{w: file, n: &w.size})
+	w.needKeyFrame = w.isVideo
+
+	switch {
+	case params.Format == FormatOgg:
+		w.mw, err = newOggWriter(w.w, params.Codec.Channels, rand.Uint32())
+	case params.Format == FormatIVF && w.isVideo:
+		fourcc := "VP80"
+		if strings.EqualFold(params.Codec.MimeType, webrtc.MimeTypeH264) {
+			fourcc = "H264"
+		}
+		w.mw = newIVFWriter(w.w, fourcc, params.Codec.ClockRate)
+	case params.Format == FormatWebM && w.isVideo:
+		w.mw = newWebMWriter(w.w, true, "V_VP8", 0)
+	default:
+		w.mw = newWebMWriter(w.w, false, "A_OPUS", params.Codec.Channels)
+	}
+	if err != nil {
+		_ = file.Close()
+		_ = os.Remove(params.Path)
+		return nil, err
+	}
+
+	go w.worker()
+	return w, nil
+}
+
+// WriteRTP queues a packet, it's dropped when the writer falls behind
+func (w *Writer) WriteRTP(header *rtp.Header, payload []byte) (int, error) {
+	if w.closed.Load() {
+		return 0, io.ErrClosedPipe
+	}
+
+	pkt := &rtp.Packet{
+		Header:  *header,
+		Payload: make([]byte, len(payload)),
+	}
+	copy(pkt.Payload, payload)
+	select {
+	case w.packets <- pkt:
+	default:
+		w.numLost.Inc()
+	}
+	return len(payload), nil
+}
+
+func (w *Writer) Write(b []byte) (int, error) {
+	pkt := &rtp.Packet{}
+	if err := pkt.Unmarshal(b); err != nil {
+		return 0, err
+	}
+	return w.WriteRTP(&pkt.Header, pkt.Payload)
+}
+
+// Close ends the recording, packets already received are still written
+func (w *Writer) Close() {
+	w.stopOnce.Do(func() {
+		close(w.stop)
+	})
+}
+
+// OnClose is called once the file is complete, regardless of why the recording ended. It's called right away when
+// the writer is already done, Err and Stats are final either way
+func (w *Writer) OnClose(f func()) {
+	w.onCloseMu.Lock()
+	if w.finished {
+		w.onCloseMu.Unlock()
+		f()
+		return
+	}
+	w.onClose = append(w.onClose, f)
+	w.onCloseMu.Unlock()
+}
+
+func (w *Writer) Done() <-chan struct{} {
+	return w.done
+}
+
+// Err returns the error that ended the recording, once it's done
+func (w *Writer) Err() error {
+	select {
+	case <-w.done:
+		return w.err
+	default:
+		return nil
+	}
+}
+
+func (w *Writer) Path() string {
+	return w.params.Path
+}
+
+func (w *Writer) Format() Format {
+	return w.params.Format
+}
+
+func (w *Writer) StartedAt() time.Time {
+	return w.startedAt
+}
+
+func (w *Writer) Stats() WriterStats {
+	return WriterStats{
+		Frames:   w.numFrames.Load(),
+		Lost:     w.numLost.Load(),
+		Dropped:  w.numDrops.Load(),
+		Filled:   w.filled.Load(),
+		Duration: w.duration.Load(),
+		Size:     w.size.Load(),
+	}
+}
+
+func (w *Writer) worker() {
+	defer w.finish()
+
+	ticker := time.NewTicker(releaseInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case pkt := <-w.packets:
+			w.jitter.push(pkt, time.Now())
+			if !w.release(time.Now()) {
+				return
+			}
+		case <-ticker.C:
+			if !w.release(time.Now()) {
+				return
+			}
+		case <-w.stop:
+			// write what has been received, without waiting for missing packets
+			w.drain()
+			w.release(time.Now().Add(w.params.Latency))
+			return
+		}
+	}
+}
+
+func (w *Writer) drain() {
+	for {
+		select {
+		case pkt := <-w.packets:
+			w.jitter.push(pkt, time.Now())
+		default:
+			return
+		}
+	}
+}
+
+// release writes packets that are in order, returns false when the recording can't continue
+func (w *Writer) release(now time.Time) bool {
+	for {
+		pkt, lost := w.jitter.pop(now)
+		if pkt == nil {
+			return true
+		}
+		if lost {
+			w.handleLoss()
+		}
+
+		var err error
+		if w.isVideo {
+			err = w.writeVideoPacket(pkt)
+		} else {
+			err = w.writeAudioPacket(pkt)
+		}
+		if err != nil {
+			w.params.Logger.Errorw("could not write recording", err, "path", w.params.Path)
+			w.err = err
+			return false
+		}
+	}
+}
+
+func (w *Writer) handleLoss() {
+	w.numLost.Inc()
+	if !w.isVideo {
+		// timestamps tell how much silence is needed
+		return
+	}
+
+	if w.frameStarted {
+		w.frameStarted = false
+		w.numDrops.Inc()
+	}
+	// depacketizers may hold fragments of the previous frame
+	w.depacketizer = newDepacketizer(w.params.Codec.MimeType)
+	w.needKeyFrame = true
+	w.requestKeyFrame()
+}
+
+func (w *Writer) writeVideoPacket(pkt *rtp.Packet) error {
+	if w.frameStarted && pkt.Timestamp != w.frameTS {
+		// previous frame did not end
+		w.frameStarted = false
+		w.numDrops.Inc()
+		w.needKeyFrame = true
+		w.requestKeyFrame()
+	}
+
+	data, err := w.depacketizer.Unmarshal(pkt.Payload)
+	if err != nil {
+		if w.frameStarted {
+			w.frameStarted = false
+			w.numDrops.Inc()
+		}
+		w.needKeyFrame = true
+		w.requestKeyFrame()
+		return nil
+	}
+	if !w.frameStarted {
+		if !isFrameStart(w.depacketizer, pkt.Payload) {
+			// rest of a frame whose start was lost
+			return nil
+		}
+		w.frameStarted = true
+		w.frameTS = pkt.Timestamp
+		w.frame = w.frame[:0]
+	}
+	w.frame = append(w.frame, data...)
+	if !pkt.Marker {
+		return nil
+	}
+
+	w.frameStarted = false
+	keyFrame := isKeyFrame(w.params.Codec.MimeType, w.frame)
+	if w.needKeyFrame && !keyFrame {
+		w.numDrops.Inc()
+		w.requestKeyFrame()
+		return nil
+	}
+	w.needKeyFrame = false
+	return w.writeFrame(w.frame, pkt.Timestamp, keyFrame)
+}
+
+func (w *Writer) writeAudioPacket(pkt *rtp.Packet) error {
+	if len(pkt.Payload) == 0 {
+		return nil
+	}
+
+	if w.tsStarted {
+		ts := w.unwrapTS(pkt.Timestamp)
+		gap := ts - w.nextAudioTS
+		if gap >= opusSilenceFrameLength && gap < int64(maxSilenceFill.Seconds()*opusClockRate) {
+			for ; gap >= opusSilenceFrameLength; gap -= opusSilenceFrameLength {
+				if err := w.mw.writeFrame(sfu.OpusSilenceFrame, w.pts(w.nextAudioTS), true); err != nil {
+					return err
+				}
+				w.nextAudioTS += opusSilenceFrameLength
+				w.filled.Add(20 * time.Millisecond)
+			}
+		}
+	}
+
+	if err := w.writeFrame(pkt.Payload, pkt.Timestamp, true); err != nil {
+		return err
+	}
+	w.nextAudioTS = w.lastTS + int64(opusPacketSamples(pkt.Payload))
+	return nil
+}
+
+func (w *Writer) writeFrame(frame []byte, timestamp uint32, keyFrame bool) error {
+	ts := w.unwrapTS(timestamp)
+	if !w.tsStarted {
+		w.tsStarted = true
+		w.firstTS = ts
+	}
+	w.lastTS = ts
+
+	pts := w.pts(ts)
+	if err := w.mw.writeFrame(frame, pts, keyFrame); err != nil {
+		return err
+	}
+	w.numFrames.Inc()
+	w.duration.Store(pts)
+	return nil
+}
+
+// unwrapTS extends an RTP timestamp, relative to the last one written
+func (w *Writer) unwrapTS(timestamp uint32) int64 {
+	if !w.tsStarted {
+		return int64(timestamp)
+	}
+	return w.lastTS + int64(int32(timestamp-uint32(w.lastTS)))
+}
+
+func (w *Writer) pts(ts int64) time.Duration {
+	if ts < w.firstTS {
+		return 0
+	}
+	return time.Duration(ts-w.firstTS) * time.Second / time.Duration(w.params.Codec.ClockRate)
+}
+
+func (w *Writer) requestKeyFrame() {
+	if w.params.RequestKeyFrame == nil || time.Since(w.lastKeyFrameRequest) < keyFrameRequestInterval {
+		return
+	}
+	w.lastKeyFrameRequest = time.Now()
+	w.params.RequestKeyFrame()
+}
+
+func (w *Writer) finish() {
+	w.closed.Store(true)
+
+	if err := w.mw.close(); err != nil && w.err == nil {
+		w.err = err
+	}
+	if err := w.w.Flush(); err != nil && w.err == nil {
+		w.err = err
+	}
+	if err := w.file.Close(); err != nil && w.err == nil {
+		w.err = err
+	}
+	if w.err != nil {
+		w.params.Logger.Errorw("recording failed", w.err, "path", w.params.Path)
+	}
+
+	stats := w.Stats()
+	w.params.Logger.Infow("recording complete",
+		"path", w.params.Path,
+		"frames", stats.Frames,
+		"lost", stats.Lost,
+		"dropped", stats.Dropped,
+		"filled", stats.Filled,
+		"duration", stats.Duration,
+		"size", stats.Size,
+	)
+
+	close(w.done)
+
+	// callbacks registered while finishing are called too, none before the file is complete
+	w.onCloseMu.Lock()
+	w.finished = true
+	onClose := w.onClose
+	w.onClose = nil
+	w.onCloseMu.Unlock()
+	for _, f := range onClose {
+		f()
+	}
+}
+
+type countingWriter struct {
+	w io.Writer
+	n *atomic.Int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n.Add(int64(n))
+	return n, err
+}
@@ -0,0 +1,289 @@
+package recorder
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v3"
+	"github.com/pion/webrtc/v3/pkg/media/ivfreader"
+	"github.com/pion/webrtc/v3/pkg/media/oggreader"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/atomic"
+
+	"github.com/livekit/livekit-server/pkg/sfu"
+)
+
+var _ webrtc.TrackLocalWriter = (*Writer)(nil)
+
+var (
+	opusCodec = webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeOpus, ClockRate: 48000, Channels: 2}
+	vp8Codec  = webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeVP8, ClockRate: 90000}
+
+	// a CELT frame of 20ms
+	opusPayload = []byte{0xfc, 0x01, 0x02, 0x03}
+	// a 320x240 key frame
+	vp8KeyFrame   = []byte{0x10, 0x02, 0x00, 0x9d, 0x01, 0x2a, 0x40, 0x01, 0xf0, 0x00, 0x01, 0x02}
+	vp8DeltaFrame = []byte{0x11, 0x02, 0x00, 0x03}
+)
+
+func writeTestPacket(t *testing.T, w *Writer, sn uint16, ts uint32, marker bool, payload []byte) {
+	_, err := w.WriteRTP(&rtp.Header{
+		Version:        2,
+		PayloadType:    96,
+		SequenceNumber: sn,
+		Timestamp:      ts,
+		Marker:         marker,
+		SSRC:           12345,
+	}, payload)
+	require.NoError(t, err)
+}
+
+// vp8Packet prefixes part of a frame with a payload descriptor
+func vp8Packet(start bool, part []byte) []byte {
+	descriptor := byte(0x00)
+	if start {
+		descriptor = 0x10
+	}
+	return append([]byte{descriptor}, part...)
+}
+
+func closeWriter(t *testing.T, w *Writer) []byte {
+	w.Close()
+	<-w.Done()
+	require.NoError(t, w.Err())
+
+	data, err := os.ReadFile(w.Path())
+	require.NoError(t, err)
+	require.Equal(t, int64(len(data)), w.Stats().Size)
+	return data
+}
+
+func TestWriter(t *testing.T) {
+	t.Run("ogg fills gaps with silence", func(t *testing.T) {
+		w, err := NewWriter(WriterParams{
+			Path:  filepath.Join(t.TempDir(), "track.ogg"),
+			Codec: opusCodec,
+		})
+		require.NoError(t, err)
+		require.Equal(t, FormatOgg, w.Format())
+
+		// 60ms without packets after the second, as with DTX
+		writeTestPacket(t, w, 100, 1000, true, opusPayload)
+		writeTestPacket(t, w, 101, 1000+960, true, opusPayload)
+		writeTestPacket(t, w, 102, 1000+960*5, true, opusPayload)
+		data := closeWriter(t, w)
+
+		reader, header, err := oggreader.NewWith(bytes.NewReader(data))
+		require.NoError(t, err)
+		require.Equal(t, uint8(2), header.Channels)
+
+		// tags
+		_, _, err = reader.ParseNextPage()
+		require.NoError(t, err)
+
+		var granules []uint64
+		var payloads [][]byte
+		for {
+			payload, pageHeader, err := reader.ParseNextPage()
+			if err == io.EOF {
+				break
+			}
+			require.NoError(t, err)
+			granules = append(granules, pageHeader.GranulePosition)
+			payloads = append(payloads, payload)
+		}
+		require.Equal(t, []uint64{960, 1920, 2880, 3840, 4800, 5760}, granules)
+		require.Equal(t, opusPayload, payloads[1])
+		require.Equal(t, sfu.OpusSilenceFrame, payloads[2])
+		require.Equal(t, opusPayload, payloads[5])
+
+		stats := w.Stats()
+		require.Equal(t, int64(3), stats.Frames)
+		require.Equal(t, 60*time.Millisecond, stats.Filled)
+		require.Equal(t, 100*time.Millisecond, stats.Duration)
+	})
+
+	t.Run("ivf resumes from key frame after loss", func(t *testing.T) {
+		keyFrameRequests := atomic.NewInt32(0)
+		w, err := NewWriter(WriterParams{
+			Path:    filepath.Join(t.TempDir(), "track.ivf"),
+			Format:  FormatIVF,
+			Codec:   vp8Codec,
+			Latency: 10 * time.Millisecond,
+			RequestKeyFrame: func() {
+				keyFrameRequests.Inc()
+			},
+		})
+		require.NoError(t, err)
+
+		// key frame in two packets, reordered with the next frame
+		writeTestPacket(t, w, 1, 0, false, vp8Packet(true, vp8KeyFrame[:6]))
+		writeTestPacket(t, w, 3, 3000, true, vp8Packet(true, vp8DeltaFrame))
+		writeTestPacket(t, w, 2, 0, true, vp8Packet(false, vp8KeyFrame[6:]))
+		// start of the next frame is lost, and the one after depends on it
+		writeTestPacket(t, w, 5, 6000, true, vp8Packet(false, vp8DeltaFrame))
+		writeTestPacket(t, w, 6, 9000, true, vp8Packet(true, vp8DeltaFrame))
+		writeTestPacket(t, w, 7, 12000, true, vp8Packet(true, vp8KeyFrame))
+		data := closeWriter(t, w)
+
+		reader, header, err := ivfreader.NewWith(bytes.NewReader(data))
+		require.NoError(t, err)
+		require.Equal(t, "VP80", header.FourCC)
+		require.Equal(t, uint16(320), header.Width)
+		require.Equal(t, uint16(240), header.Height)
+		require.Equal(t, uint32(90000), header.TimebaseDenominator)
+
+		var timestamps []uint64
+		var frames [][]byte
+		for {
+			frame, frameHeader, err := reader.ParseNextFrame()
+			if err == io.EOF {
+				break
+			}
+			require.NoError(t, err)
+			timestamps = append(timestamps, frameHeader.Timestamp)
+			frames = append(frames, frame)
+		}
+		require.Equal(t, []uint64{0, 3000, 12000}, timestamps)
+		require.Equal(t, [][]byte{vp8KeyFrame, vp8DeltaFrame, vp8KeyFrame}, frames)
+
+		stats := w.Stats()
+		require.Equal(t, int64(3), stats.Frames)
+		require.Equal(t, int64(1), stats.Lost)
+		require.Equal(t, int64(1), stats.Dropped)
+		require.Equal(t, int32(1), keyFrameRequests.Load())
+	})
+
+	t.Run("webm", func(t *testing.T) {
+		w, err := NewWriter(WriterParams{
+			Path:   filepath.Join(t.TempDir(), "track.webm"),
+			Format: FormatWebM,
+			Codec:  vp8Codec,
+		})
+		require.NoError(t, err)
+
+		// delta frames before the first key frame are not written
+		writeTestPacket(t, w, 1, 0, true, vp8Packet(true, vp8DeltaFrame))
+		writeTestPacket(t, w, 2, 3000, true, vp8Packet(true, vp8KeyFrame))
+		writeTestPacket(t, w, 3, 6000, true, vp8Packet(true, vp8DeltaFrame))
+		data := closeWriter(t, w)
+
+		require.Equal(t, []byte{0x1a, 0x45, 0xdf, 0xa3}, data[:4])
+		require.Contains(t, string(data), "webm")
+		require.Contains(t, string(data), "V_VP8")
+		// pixel width and height
+		require.True(t, bytes.Contains(data, []byte{0xb0, 0x82, 0x01, 0x40}))
+		require.True(t, bytes.Contains(data, []byte{0xba, 0x81, 0xf0}))
+		// a cluster at the key frame, holding both frames 33ms apart
+		require.Equal(t, 1, bytes.Count(data, []byte{0x1f, 0x43, 0xb6, 0x75}))
+		require.True(t, bytes.HasSuffix(data, append([]byte{0xa3, 0x88, 0x81, 0x00, 0x21, 0x00}, vp8DeltaFrame...)))
+		require.Equal(t, int64(2), w.Stats().Frames)
+	})
+
+	t.Run("calls close handlers once complete", func(t *testing.T) {
+		w, err := NewWriter(WriterParams{
+			Path:  filepath.Join(t.TempDir(), "track.ogg"),
+			Codec: opusCodec,
+		})
+		require.NoError(t, err)
+		writeTestPacket(t, w, 1, 0, true, opusPayload)
+
+		before := make(chan int64, 1)
+		w.OnClose(func() {
+			before <- w.Stats().Frames
+		})
+		w.Close()
+
+		// registered while or after finishing, the file is complete either way
+		after := make(chan bool, 1)
+		w.OnClose(func() {
+			select {
+			case <-w.Done():
+				after <- true
+			default:
+				after <- false
+			}
+		})
+
+		select {
+		case frames := <-before:
+			require.Equal(t, int64(1), frames)
+		case <-time.After(time.Second):
+			require.Fail(t, "close handler not called")
+		}
+		select {
+		case done := <-after:
+			require.True(t, done)
+		case <-time.After(time.Second):
+			require.Fail(t, "close handler not called")
+		}
+	})
+
+	t.Run("unsupported", func(t *testing.T) {
+		_, err := NewWriter(WriterParams{
+			Path:  filepath.Join(t.TempDir(), "track"),
+			Codec: webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypePCMU, ClockRate: 8000},
+		})
+		require.ErrorIs(t, err, ErrUnsupportedCodec)
+
+		_, err = NewWriter(WriterParams{
+			Path:   filepath.Join(t.TempDir(), "track.ogg"),
+			Format: FormatOgg,
+			Codec:  vp8Codec,
+		})
+		require.ErrorIs(t, err, ErrUnsupportedFormat)
+	})
+}
+
+func TestJitterBuffer(t *testing.T) {
+	j := newJitterBuffer(100*time.Millisecond, 3)
+	now := time.Now()
+
+	pop := func(now time.Time) (uint16, bool) {
+		pkt, lost := j.pop(now)
+		if pkt == nil {
+			return 0, lost
+		}
+		return pkt.SequenceNumber, lost
+	}
+
+	// reordered across wrap around
+	for _, sn := range []uint16{65535, 1, 0, 65535} {
+		j.push(&rtp.Packet{Header: rtp.Header{SequenceNumber: sn}}, now)
+	}
+	for _, sn := range []uint16{65535, 0, 1} {
+		popped, lost := pop(now)
+		require.Equal(t, sn, popped)
+		require.False(t, lost)
+	}
+	popped, _ := pop(now)
+	require.Zero(t, popped)
+
+	// late packet is dropped
+	j.push(&rtp.Packet{Header: rtp.Header{SequenceNumber: 0}}, now)
+	popped, _ = pop(now)
+	require.Zero(t, popped)
+
+	// waits for a missing packet up to latency
+	j.push(&rtp.Packet{Header: rtp.Header{SequenceNumber: 3}}, now)
+	popped, _ = pop(now.Add(50 * time.Millisecond))
+	require.Zero(t, popped)
+	popped, lost := pop(now.Add(100 * time.Millisecond))
+	require.Equal(t, uint16(3), popped)
+	require.True(t, lost)
+
+	// or until too many are waiting
+	for _, sn := range []uint16{6, 7, 8, 5} {
+		j.push(&rtp.Packet{Header: rtp.Header{SequenceNumber: sn}}, now)
+	}
+	for _, sn := range []uint16{5, 6, 7, 8} {
+		popped, lost = pop(now)
+		require.Equal(t, sn, popped)
+		require.Equal(t, sn == 5, lost)
+	}
+}
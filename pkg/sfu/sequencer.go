@@ -90,6 +90,23 @@ func (p *packetMeta) unpackVP8() *buffer.VP8 {
 	}
 }
 
+// only picture id and TL0PICIDX are munged for VP9, rest of the descriptor is taken from the incoming packet
+func (p *packetMeta) packVP9(vp9 *buffer.VP9) {
+	p.misc = uint64(btoi(vp9.MBit)&0x1)<<55 |
+		uint64(vp9.PictureID&0x7FFF)<<32 |
+		uint64(vp9.TL0PICIDX&0xFF)<<24 |
+		uint64(vp9.HeaderSize&0xFF)<<8
+}
+
+func (p *packetMeta) unpackVP9(incoming *buffer.VP9) *buffer.VP9 {
+	vp9 := *incoming
+	vp9.MBit = itob(int((p.misc >> 55) & 0x1))
+	vp9.PictureID = uint16((p.misc >> 32) & 0x7FFF)
+	vp9.TL0PICIDX = uint8((p.misc >> 24) & 0xFF)
+	vp9.HeaderSize = int((p.misc >> 8) & 0xFF)
+	return &vp9
+}
+
 // Sequencer stores the packet sequence received by the down track
 type sequencer struct {
 	sync.Mutex
@@ -128,6 +145,16 @@ func (s *sequencer) setRTT(rtt uint32) {
 	}
 }
 
+// forget sent packets so they are not retransmitted, sequence numbers continue from head
+func (s *sequencer) clear() {
+	s.Lock()
+	defer s.Unlock()
+
+	for i := range s.seq {
+		s.seq[i] = nil
+	}
+}
+
 func (s *sequencer) push(sn, offSn uint16, timeStamp uint32, layer int8) *packetMeta {
 	s.Lock()
 	defer s.Unlock()
@@ -52,6 +52,24 @@ func Test_sequencer(t *testing.T) {
 	require.Equal(t, 1, len(m))
 }
 
+func Test_sequencer_clear(t *testing.T) {
+	seq := newSequencer(500, 0, logger.GetDefaultLogger())
+	off := uint16(15)
+
+	for i := uint16(1); i <= 10; i++ {
+		seq.push(i, i+off, 123, 2)
+	}
+	seq.clear()
+	require.Empty(t, seq.getPacketsMeta([]uint16{5 + off, 10 + off}))
+
+	// sequence numbers continue from those sent before
+	seq.push(11, 11+off, 123, 2)
+	m := seq.getPacketsMeta([]uint16{10 + off, 11 + off})
+	require.Len(t, m, 1)
+	require.Equal(t, uint16(11), m[0].sourceSeqNo)
+	require.Equal(t, uint16(11+off), m[0].targetSeqNo)
+}
+
 func Test_sequencer_getNACKSeqNo(t *testing.T) {
 	type args struct {
 		seqNo []uint16
@@ -96,6 +96,7 @@ const (
 	streamAllocatorSignalPeriodicPing
 	streamAllocatorSignalSendProbe
 	streamAllocatorSignalProbeClusterDone
+	streamAllocatorSignalSetMaxChannelCapacity
 )
 
 func (s streamAllocatorSignal) String() string {
@@ -114,6 +115,8 @@ func (s streamAllocatorSignal) String() string {
 		return "SEND_PROBE"
 	case streamAllocatorSignalProbeClusterDone:
 		return "PROBE_CLUSTER_DONE"
+	case streamAllocatorSignalSetMaxChannelCapacity:
+		return "SET_MAX_CHANNEL_CAPACITY"
 	default:
 		return fmt.Sprintf("%d", int(s))
 	}
@@ -143,6 +146,7 @@ type StreamAllocator struct {
 
 	lastReceivedEstimate     int64
 	committedChannelCapacity int64
+	maxChannelCapacity       int64
 
 	probeInterval         time.Duration
 	lastProbeStartTime    time.Time
@@ -272,6 +276,15 @@ func (s *StreamAllocator) SetTrackPriority(downTrack *DownTrack, priority uint8)
 	s.videoTracksMu.Unlock()
 }
 
+// SetMaxChannelCapacity caps channel capacity available to video tracks, regardless of estimated capacity.
+// Managed tracks are allocated lower layers to fit within the cap. A value of 0 removes the cap.
+func (s *StreamAllocator) SetMaxChannelCapacity(maxChannelCapacity int64) {
+	s.postEvent(Event{
+		Signal: streamAllocatorSignalSetMaxChannelCapacity,
+		Data:   maxChannelCapacity,
+	})
+}
+
 func (s *StreamAllocator) resetState() {
 	s.channelObserver = s.newChannelObserverNonProbe()
 	s.resetProbe()
@@ -500,6 +513,8 @@ func (s *StreamAllocator) handleEvent(event *Event) {
 		s.handleSignalSendProbe(event)
 	case streamAllocatorSignalProbeClusterDone:
 		s.handleSignalProbeClusterDone(event)
+	case streamAllocatorSignalSetMaxChannelCapacity:
+		s.handleSignalSetMaxChannelCapacity(event)
 	}
 }
 
@@ -521,7 +536,7 @@ func (s *StreamAllocator) handleSignalAllocateAllTracks(event *Event) {
 	s.isAllocateAllPending = false
 	s.videoTracksMu.Unlock()
 
-	if s.state == streamAllocatorStateDeficient {
+	if s.state == streamAllocatorStateDeficient || s.maxChannelCapacity > 0 {
 		s.allocateAllTracks()
 	}
 }
@@ -599,6 +614,31 @@ func (s *StreamAllocator) handleSignalProbeClusterDone(event *Event) {
 	s.probeEndTime = s.lastProbeStartTime.Add(queueWait)
 }
 
+func (s *StreamAllocator) handleSignalSetMaxChannelCapacity(event *Event) {
+	maxChannelCapacity, _ := event.Data.(int64)
+	if maxChannelCapacity < 0 {
+		maxChannelCapacity = 0
+	}
+	if maxChannelCapacity == s.maxChannelCapacity {
+		return
+	}
+
+	s.params.Logger.Infow(
+		"stream allocator: updating max channel capacity",
+		"old(bps)", s.maxChannelCapacity,
+		"new(bps)", maxChannelCapacity,
+	)
+	s.maxChannelCapacity = maxChannelCapacity
+
+	s.abortProbe()
+	if maxChannelCapacity > 0 || s.state == streamAllocatorStateDeficient {
+		s.allocateAllTracks()
+	} else {
+		// cap removed, tracks can go back to optimal allocation when not constrained by estimated capacity
+		s.allocateOptimalAllTracks()
+	}
+}
+
 func (s *StreamAllocator) setState(state streamAllocatorState) {
 	if s.state == state {
 		return
@@ -715,6 +755,12 @@ func (s *StreamAllocator) allocateTrack(track *Track) {
 	// abort any probe that may be running when a track specific change needs allocation
 	s.abortProbe()
 
+	// capped tracks are allocated together to fit within the cap
+	if s.maxChannelCapacity > 0 && track.IsManaged() {
+		s.allocateAllTracks()
+		return
+	}
+
 	// if not deficient, free pass allocate track
 	if !s.params.Config.Enabled || s.state == streamAllocatorStateStable || !track.IsManaged() {
 		update := NewStreamStateUpdate()
@@ -842,16 +888,7 @@ func (s *StreamAllocator) finalizeProbe() {
 }
 
 func (s *StreamAllocator) maybeBoostDeficientTracks() {
-	committedChannelCapacity := s.committedChannelCapacity
-	if s.params.Config.MinChannelCapacity > committedChannelCapacity {
-		committedChannelCapacity = s.params.Config.MinChannelCapacity
-		s.params.Logger.Debugw(
-			"stream allocator: overriding channel capacity",
-			"actual", s.committedChannelCapacity,
-			"override", committedChannelCapacity,
-		)
-	}
-	availableChannelCapacity := committedChannelCapacity - s.getExpectedBandwidthUsage()
+	availableChannelCapacity := s.getChannelCapacity() - s.getExpectedBandwidthUsage()
 	if availableChannelCapacity <= 0 {
 		return
 	}
@@ -878,7 +915,7 @@ func (s *StreamAllocator) maybeBoostDeficientTracks() {
 }
 
 func (s *StreamAllocator) allocateAllTracks() {
-	if !s.params.Config.Enabled {
+	if !s.params.Config.Enabled && s.maxChannelCapacity == 0 {
 		// nothing else to do when disabled
 		return
 	}
@@ -897,15 +934,7 @@ func (s *StreamAllocator) allocateAllTracks() {
 	//
 	update := NewStreamStateUpdate()
 
-	availableChannelCapacity := s.committedChannelCapacity
-	if s.params.Config.MinChannelCapacity > availableChannelCapacity {
-		availableChannelCapacity = s.params.Config.MinChannelCapacity
-		s.params.Logger.Debugw(
-			"stream allocator: overriding channel capacity",
-			"actual", s.committedChannelCapacity,
-			"override", availableChannelCapacity,
-		)
-	}
+	availableChannelCapacity := s.getChannelCapacity()
 
 	//
 	// This pass is to find out if there is any leftover channel capacity after allocating exempt tracks.
@@ -971,6 +1000,40 @@ func (s *StreamAllocator) allocateAllTracks() {
 	s.adjustState()
 }
 
+func (s *StreamAllocator) allocateOptimalAllTracks() {
+	update := NewStreamStateUpdate()
+	for _, track := range s.getTracks() {
+		allocation := track.AllocateOptimal(FlagAllowOvershootWhileOptimal)
+		update.HandleStreamingChange(allocation.change, track)
+	}
+	s.maybeSendUpdate(update)
+
+	s.adjustState()
+}
+
+// getChannelCapacity returns capacity that can be allocated to video tracks,
+// committed capacity bounded by configured minimum and maximum
+func (s *StreamAllocator) getChannelCapacity() int64 {
+	channelCapacity := s.committedChannelCapacity
+	if !s.params.Config.Enabled || channelCapacity == 0 {
+		// estimates not used or nothing committed yet, only bounded by maximum
+		channelCapacity = ChannelCapacityInfinity
+	}
+	if s.params.Config.MinChannelCapacity > channelCapacity {
+		channelCapacity = s.params.Config.MinChannelCapacity
+		s.params.Logger.Debugw(
+			"stream allocator: overriding channel capacity",
+			"actual", s.committedChannelCapacity,
+			"override", channelCapacity,
+		)
+	}
+	if s.maxChannelCapacity > 0 && channelCapacity > s.maxChannelCapacity {
+		channelCapacity = s.maxChannelCapacity
+	}
+
+	return channelCapacity
+}
+
 func (s *StreamAllocator) maybeSendUpdate(update *StreamStateUpdate) {
 	if update.Empty() {
 		return
@@ -1095,6 +1158,11 @@ func (s *StreamAllocator) maybeProbe() {
 		return
 	}
 
+	// no point probing for more than what is allowed
+	if s.maxChannelCapacity > 0 && s.getExpectedBandwidthUsage() >= s.maxChannelCapacity {
+		return
+	}
+
 	switch s.params.Config.ProbeMode {
 	case config.CongestionControlProbeModeMedia:
 		s.maybeProbeWithMedia()
@@ -1107,7 +1175,7 @@ func (s *StreamAllocator) maybeProbe() {
 func (s *StreamAllocator) maybeProbeWithMedia() {
 	// boost deficient track farthest from desired layers
 	for _, track := range s.getMaxDistanceSortedDeficient() {
-		allocation, boosted := track.AllocateNextHigher(ChannelCapacityInfinity, FlagAllowOvershootInCatchup)
+		allocation, boosted := track.AllocateNextHigher(s.getProbeChannelCapacity(), FlagAllowOvershootInCatchup)
 		if !boosted {
 			continue
 		}
@@ -1121,6 +1189,13 @@ func (s *StreamAllocator) maybeProbeWithMedia() {
 	}
 }
 
+func (s *StreamAllocator) getProbeChannelCapacity() int64 {
+	if s.maxChannelCapacity > 0 {
+		return s.maxChannelCapacity - s.getExpectedBandwidthUsage()
+	}
+	return ChannelCapacityInfinity
+}
+
 func (s *StreamAllocator) maybeProbeWithPadding() {
 	// use deficient track farthest from desired layers to find how much to probe
 	for _, track := range s.getMaxDistanceSortedDeficient() {
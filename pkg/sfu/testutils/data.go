@@ -68,11 +68,33 @@ func GetTestExtPacketVP8(params *TestExtPacketParams, vp8 *buffer.VP8) (*buffer.
 
 // --------------------------------------
 
+func GetTestExtPacketVP9(params *TestExtPacketParams, vp9 *buffer.VP9) (*buffer.ExtPacket, error) {
+	ep, err := GetTestExtPacket(params)
+	if err != nil {
+		return nil, err
+	}
+
+	ep.KeyFrame = vp9.IsKeyFrame
+	ep.Payload = *vp9
+	if vp9.L {
+		ep.Spatial = int32(vp9.SID)
+		ep.Temporal = int32(vp9.TID)
+	}
+	return ep, nil
+}
+
+// --------------------------------------
+
 var TestVP8Codec = webrtc.RTPCodecCapability{
 	MimeType:  "video/vp8",
 	ClockRate: 90000,
 }
 
+var TestVP9Codec = webrtc.RTPCodecCapability{
+	MimeType:  "video/vp9",
+	ClockRate: 90000,
+}
+
 var TestOpusCodec = webrtc.RTPCodecCapability{
 	MimeType:  "audio/opus",
 	ClockRate: 48000,
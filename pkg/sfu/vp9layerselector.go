@@ -0,0 +1,80 @@
+package sfu
+
+import (
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/sfu/buffer"
+)
+
+// VP9VideoLayerSelector selects spatial layers of a VP9 SVC stream using the VP9 payload descriptor.
+// All spatial layers are received on a single SSRC. Layers up to the current spatial layer are forwarded.
+// Spatial layer switches happen at picture boundaries, i. e. when the base layer frame of a picture starts.
+// Switching up requires a key frame, switching down can happen on any picture.
+// Temporal layers are filtered by the VP9Munger as picture ids need adjustment.
+type VP9VideoLayerSelector struct {
+	logger logger.Logger
+
+	targetSpatial  int32
+	currentSpatial int32
+}
+
+func NewVP9VideoLayerSelector(logger logger.Logger) *VP9VideoLayerSelector {
+	return &VP9VideoLayerSelector{
+		logger:         logger,
+		targetSpatial:  InvalidLayerSpatial,
+		currentSpatial: InvalidLayerSpatial,
+	}
+}
+
+func (s *VP9VideoLayerSelector) Select(extPkt *buffer.ExtPacket, tp *TranslationParams) (selected bool) {
+	tp.marker = extPkt.Packet.Marker
+
+	vp9, ok := extPkt.Payload.(buffer.VP9)
+	if !ok {
+		// padding only packet, let the munger decide
+		return true
+	}
+
+	if !vp9.L {
+		// no layer information, cannot filter, forward everything
+		return true
+	}
+
+	if s.currentSpatial != s.targetSpatial && vp9.B && vp9.SID == 0 {
+		switch {
+		case s.targetSpatial == InvalidLayerSpatial:
+			s.currentSpatial = InvalidLayerSpatial
+
+		case s.targetSpatial > s.currentSpatial:
+			if vp9.IsKeyFrame {
+				s.logger.Debugw("switching up spatial layer", "current", s.currentSpatial, "target", s.targetSpatial)
+				s.currentSpatial = s.targetSpatial
+				tp.switchingToTargetLayer = true
+			}
+
+		default:
+			s.logger.Debugw("switching down spatial layer", "current", s.currentSpatial, "target", s.targetSpatial)
+			s.currentSpatial = s.targetSpatial
+			tp.switchingToTargetLayer = true
+		}
+	}
+
+	if s.currentSpatial == InvalidLayerSpatial || int32(vp9.SID) > s.currentSpatial {
+		return false
+	}
+
+	// upper spatial layers are dropped, mark end of picture on the last packet of the highest forwarded layer
+	if vp9.E && int32(vp9.SID) == s.currentSpatial {
+		tp.marker = true
+	}
+
+	return true
+}
+
+func (s *VP9VideoLayerSelector) SelectLayer(layer VideoLayers) {
+	s.targetSpatial = layer.Spatial
+}
+
+func (s *VP9VideoLayerSelector) Reset() {
+	s.currentSpatial = InvalidLayerSpatial
+}
@@ -0,0 +1,198 @@
+package sfu
+
+import (
+	"fmt"
+
+	"github.com/elliotchance/orderedmap"
+
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/sfu/buffer"
+)
+
+//
+// VP9 munger
+//
+type TranslationParamsVP9 struct {
+	Header *buffer.VP9
+}
+
+// -----------------------------------------------------------
+
+type VP9MungerState struct {
+	ExtLastPictureId int32
+	PictureIdUsed    bool
+	LastTl0PicIdx    uint8
+	Tl0PicIdxUsed    bool
+}
+
+func (v VP9MungerState) String() string {
+	return fmt.Sprintf("VP9MungerState{extLastPictureId: %d, pictureIdUsed: %+v, lastTl0PicIdx: %d, tl0PicIdxUsed: %+v)",
+		v.ExtLastPictureId, v.PictureIdUsed, v.LastTl0PicIdx, v.Tl0PicIdxUsed)
+}
+
+// -----------------------------------------------------------
+
+type VP9MungerParams struct {
+	pictureIdWrapHandler VP8PictureIdWrapHandler
+	extLastPictureId     int32
+	pictureIdOffset      int32
+	pictureIdUsed        bool
+	lastTl0PicIdx        uint8
+	tl0PicIdxOffset      uint8
+	tl0PicIdxUsed        bool
+
+	missingPictureIds    *orderedmap.OrderedMap
+	lastDroppedPictureId int32
+}
+
+// VP9Munger rewrites picture id and TL0PICIDX of forwarded VP9 packets so that
+// the outgoing stream stays continuous across source switches and dropped temporal layers.
+// Picture ID of VP9 has the same 7/15 bit semantics as VP8 and hence uses the same wrap handler.
+type VP9Munger struct {
+	logger logger.Logger
+
+	VP9MungerParams
+}
+
+func NewVP9Munger(logger logger.Logger) *VP9Munger {
+	return &VP9Munger{
+		logger: logger,
+		VP9MungerParams: VP9MungerParams{
+			missingPictureIds:    orderedmap.NewOrderedMap(),
+			lastDroppedPictureId: -1,
+		},
+	}
+}
+
+func (v *VP9Munger) GetLast() VP9MungerState {
+	return VP9MungerState{
+		ExtLastPictureId: v.extLastPictureId,
+		PictureIdUsed:    v.pictureIdUsed,
+		LastTl0PicIdx:    v.lastTl0PicIdx,
+		Tl0PicIdxUsed:    v.tl0PicIdxUsed,
+	}
+}
+
+func (v *VP9Munger) SeedLast(state VP9MungerState) {
+	v.extLastPictureId = state.ExtLastPictureId
+	v.pictureIdUsed = state.PictureIdUsed
+	v.lastTl0PicIdx = state.LastTl0PicIdx
+	v.tl0PicIdxUsed = state.Tl0PicIdxUsed
+}
+
+func (v *VP9Munger) SetLast(extPkt *buffer.ExtPacket) {
+	vp9, ok := extPkt.Payload.(buffer.VP9)
+	if !ok {
+		return
+	}
+
+	v.pictureIdUsed = vp9.I
+	if v.pictureIdUsed {
+		v.pictureIdWrapHandler.Init(int32(vp9.PictureID)-1, vp9.MBit)
+		v.extLastPictureId = int32(vp9.PictureID)
+	}
+
+	v.tl0PicIdxUsed = vp9.L && !vp9.F
+	if v.tl0PicIdxUsed {
+		v.lastTl0PicIdx = vp9.TL0PICIDX
+	}
+
+	v.lastDroppedPictureId = -1
+}
+
+func (v *VP9Munger) UpdateOffsets(extPkt *buffer.ExtPacket) {
+	vp9, ok := extPkt.Payload.(buffer.VP9)
+	if !ok {
+		return
+	}
+
+	if v.pictureIdUsed {
+		v.pictureIdWrapHandler.Init(int32(vp9.PictureID)-1, vp9.MBit)
+		v.pictureIdOffset = int32(vp9.PictureID) - v.extLastPictureId - 1
+	}
+
+	if v.tl0PicIdxUsed {
+		v.tl0PicIdxOffset = vp9.TL0PICIDX - v.lastTl0PicIdx - 1
+	}
+
+	// clear missing picture ids on layer switch
+	v.missingPictureIds = orderedmap.NewOrderedMap()
+
+	v.lastDroppedPictureId = -1
+}
+
+func (v *VP9Munger) UpdateAndGet(extPkt *buffer.ExtPacket, ordering SequenceNumberOrdering, maxTemporalLayer int32) (*TranslationParamsVP9, error) {
+	vp9, ok := extPkt.Payload.(buffer.VP9)
+	if !ok {
+		return nil, ErrNotVP9
+	}
+
+	extPictureId := v.pictureIdWrapHandler.Unwrap(vp9.PictureID, vp9.MBit)
+
+	// if out-of-order, look up missing picture id cache
+	if ordering == SequenceNumberOrderingOutOfOrder {
+		value, ok := v.missingPictureIds.Get(extPictureId)
+		if !ok {
+			return nil, ErrOutOfOrderVP9PictureIdCacheMiss
+		}
+		pictureIdOffset := value.(int32)
+
+		return &TranslationParamsVP9{
+			Header: v.translate(&vp9, extPictureId-pictureIdOffset, vp9.TL0PICIDX-v.tl0PicIdxOffset),
+		}, nil
+	}
+
+	prevMaxPictureId := v.pictureIdWrapHandler.MaxPictureId()
+	v.pictureIdWrapHandler.UpdateMaxPictureId(extPictureId, vp9.MBit)
+
+	// see VP8Munger.UpdateAndGet for details on handling of gaps
+	if ordering == SequenceNumberOrderingGap {
+		if extPictureId == v.lastDroppedPictureId {
+			return nil, ErrFilteredVP9TemporalLayer
+		} else {
+			for lostPictureId := prevMaxPictureId; lostPictureId <= extPictureId; lostPictureId++ {
+				v.missingPictureIds.Set(lostPictureId, v.pictureIdOffset)
+			}
+
+			// trim cache if necessary
+			for v.missingPictureIds.Len() > 50 {
+				el := v.missingPictureIds.Front()
+				v.missingPictureIds.Delete(el.Key)
+			}
+		}
+	} else {
+		if vp9.L && vp9.TID > uint8(maxTemporalLayer) {
+			// All spatial layers of a picture share the picture id, adjust only once per picture.
+			// In flexible mode, references are signalled as picture id differences,
+			// so picture ids cannot be made contiguous without breaking references.
+			if vp9.I && !vp9.F && prevMaxPictureId != extPictureId {
+				v.lastDroppedPictureId = extPictureId
+				v.pictureIdOffset += 1
+			}
+			return nil, ErrFilteredVP9TemporalLayer
+		}
+	}
+
+	extMungedPictureId := extPictureId - v.pictureIdOffset
+	mungedTl0PicIdx := vp9.TL0PICIDX - v.tl0PicIdxOffset
+
+	v.extLastPictureId = extMungedPictureId
+	v.lastTl0PicIdx = mungedTl0PicIdx
+
+	return &TranslationParamsVP9{
+		Header: v.translate(&vp9, extMungedPictureId, mungedTl0PicIdx),
+	}, nil
+}
+
+// keeps the size of the picture id of the incoming packet so that the payload descriptor can be rewritten in place
+func (v *VP9Munger) translate(vp9 *buffer.VP9, extMungedPictureId int32, mungedTl0PicIdx uint8) *buffer.VP9 {
+	translated := *vp9
+	if vp9.MBit {
+		translated.PictureID = uint16(extMungedPictureId & 0x7fff)
+	} else {
+		translated.PictureID = uint16(extMungedPictureId & 0x7f)
+	}
+	translated.TL0PICIDX = mungedTl0PicIdx
+	return &translated
+}
@@ -0,0 +1,192 @@
+package sfu
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/sfu/buffer"
+	"github.com/livekit/livekit-server/pkg/sfu/testutils"
+)
+
+func newVP9Munger() *VP9Munger {
+	return NewVP9Munger(logger.GetDefaultLogger())
+}
+
+func TestVP9SetLastAndUpdateOffsets(t *testing.T) {
+	v := newVP9Munger()
+
+	params := &testutils.TestExtPacketParams{
+		SequenceNumber: 23333,
+		Timestamp:      0xabcdef,
+		SSRC:           0x12345678,
+	}
+	vp9 := &buffer.VP9{
+		I:          true,
+		L:          true,
+		B:          true,
+		PictureID:  13467,
+		MBit:       true,
+		TL0PICIDX:  233,
+		HeaderSize: 5,
+		IsKeyFrame: true,
+	}
+	extPkt, err := testutils.GetTestExtPacketVP9(params, vp9)
+	require.NoError(t, err)
+
+	v.SetLast(extPkt)
+	require.Equal(t, VP9MungerState{
+		ExtLastPictureId: 13467,
+		PictureIdUsed:    true,
+		LastTl0PicIdx:    233,
+		Tl0PicIdxUsed:    true,
+	}, v.GetLast())
+
+	params = &testutils.TestExtPacketParams{
+		SequenceNumber: 56789,
+		Timestamp:      0xabcdef,
+		SSRC:           0x87654321,
+	}
+	vp9 = &buffer.VP9{
+		I:          true,
+		L:          true,
+		B:          true,
+		PictureID:  345,
+		MBit:       true,
+		TL0PICIDX:  12,
+		HeaderSize: 5,
+		IsKeyFrame: true,
+	}
+	extPkt, _ = testutils.GetTestExtPacketVP9(params, vp9)
+	v.UpdateOffsets(extPkt)
+	require.Equal(t, int32(345-13467-1), v.pictureIdOffset)
+
+	// picture after switch should continue from last
+	tp, err := v.UpdateAndGet(extPkt, SequenceNumberOrderingContiguous, 2)
+	require.NoError(t, err)
+	require.Equal(t, uint16(13468), tp.Header.PictureID)
+	require.Equal(t, uint8(234), tp.Header.TL0PICIDX)
+	require.Equal(t, vp9.HeaderSize, tp.Header.HeaderSize)
+}
+
+func TestVP9TemporalLayerFiltering(t *testing.T) {
+	v := newVP9Munger()
+
+	params := &testutils.TestExtPacketParams{
+		SequenceNumber: 23333,
+		Timestamp:      0xabcdef,
+		SSRC:           0x12345678,
+	}
+	vp9 := &buffer.VP9{
+		I:          true,
+		L:          true,
+		B:          true,
+		PictureID:  13467,
+		MBit:       true,
+		TL0PICIDX:  233,
+		HeaderSize: 5,
+		IsKeyFrame: true,
+	}
+	extPkt, _ := testutils.GetTestExtPacketVP9(params, vp9)
+	v.SetLast(extPkt)
+
+	tp, err := v.UpdateAndGet(extPkt, SequenceNumberOrderingContiguous, 0)
+	require.NoError(t, err)
+	require.Equal(t, uint16(13467), tp.Header.PictureID)
+
+	// both spatial layers of a temporal layer 1 picture should be dropped, picture id adjusted only once
+	params.SequenceNumber = 23334
+	vp9 = &buffer.VP9{
+		I:          true,
+		P:          true,
+		L:          true,
+		B:          true,
+		PictureID:  13468,
+		MBit:       true,
+		TID:        1,
+		TL0PICIDX:  233,
+		HeaderSize: 5,
+	}
+	extPkt, _ = testutils.GetTestExtPacketVP9(params, vp9)
+	_, err = v.UpdateAndGet(extPkt, SequenceNumberOrderingContiguous, 0)
+	require.ErrorIs(t, err, ErrFilteredVP9TemporalLayer)
+
+	params.SequenceNumber = 23335
+	vp9.SID = 1
+	extPkt, _ = testutils.GetTestExtPacketVP9(params, vp9)
+	_, err = v.UpdateAndGet(extPkt, SequenceNumberOrderingContiguous, 0)
+	require.ErrorIs(t, err, ErrFilteredVP9TemporalLayer)
+	require.Equal(t, int32(1), v.pictureIdOffset)
+
+	// next base temporal layer picture should have contiguous picture id
+	params.SequenceNumber = 23336
+	vp9 = &buffer.VP9{
+		I:          true,
+		P:          true,
+		L:          true,
+		B:          true,
+		PictureID:  13469,
+		MBit:       true,
+		TL0PICIDX:  234,
+		HeaderSize: 5,
+	}
+	extPkt, _ = testutils.GetTestExtPacketVP9(params, vp9)
+	tp, err = v.UpdateAndGet(extPkt, SequenceNumberOrderingContiguous, 0)
+	require.NoError(t, err)
+	require.Equal(t, uint16(13468), tp.Header.PictureID)
+	require.Equal(t, uint8(234), tp.Header.TL0PICIDX)
+}
+
+func TestVP9LayerSelector(t *testing.T) {
+	s := NewVP9VideoLayerSelector(logger.GetDefaultLogger())
+	s.SelectLayer(VideoLayers{Spatial: 1, Temporal: 2})
+
+	params := &testutils.TestExtPacketParams{
+		SequenceNumber: 1,
+		Timestamp:      0xabcdef,
+		SSRC:           0x12345678,
+	}
+
+	// delta frame cannot start the stream
+	vp9 := &buffer.VP9{I: true, P: true, L: true, B: true, E: true, PictureID: 10, HeaderSize: 3}
+	extPkt, _ := testutils.GetTestExtPacketVP9(params, vp9)
+	tp := &TranslationParams{}
+	require.False(t, s.Select(extPkt, tp))
+
+	// key frame switches to target, layers above target are dropped
+	vp9 = &buffer.VP9{I: true, L: true, B: true, E: true, PictureID: 11, HeaderSize: 3, IsKeyFrame: true}
+	extPkt, _ = testutils.GetTestExtPacketVP9(params, vp9)
+	tp = &TranslationParams{}
+	require.True(t, s.Select(extPkt, tp))
+	require.True(t, tp.switchingToTargetLayer)
+	require.False(t, tp.marker)
+
+	vp9 = &buffer.VP9{I: true, L: true, B: true, E: true, D: true, SID: 1, PictureID: 11, HeaderSize: 3}
+	extPkt, _ = testutils.GetTestExtPacketVP9(params, vp9)
+	tp = &TranslationParams{}
+	require.True(t, s.Select(extPkt, tp))
+	require.True(t, tp.marker)
+
+	vp9 = &buffer.VP9{I: true, L: true, B: true, E: true, D: true, SID: 2, PictureID: 11, HeaderSize: 3}
+	params.SetMarker = true
+	extPkt, _ = testutils.GetTestExtPacketVP9(params, vp9)
+	tp = &TranslationParams{}
+	require.False(t, s.Select(extPkt, tp))
+
+	// switching down happens at next picture
+	s.SelectLayer(VideoLayers{Spatial: 0, Temporal: 2})
+	params.SetMarker = false
+	vp9 = &buffer.VP9{I: true, P: true, L: true, B: true, E: true, PictureID: 12, HeaderSize: 3}
+	extPkt, _ = testutils.GetTestExtPacketVP9(params, vp9)
+	tp = &TranslationParams{}
+	require.True(t, s.Select(extPkt, tp))
+	require.True(t, tp.switchingToTargetLayer)
+	require.True(t, tp.marker)
+
+	vp9 = &buffer.VP9{I: true, P: true, L: true, B: true, E: true, D: true, SID: 1, PictureID: 12, HeaderSize: 3}
+	extPkt, _ = testutils.GetTestExtPacketVP9(params, vp9)
+	tp = &TranslationParams{}
+	require.False(t, s.Select(extPkt, tp))
+}
@@ -6,6 +6,7 @@ import (
 
 	"google.golang.org/protobuf/types/known/timestamppb"
 
+	"github.com/livekit/livekit-server/pkg/telemetry/metering"
 	"github.com/livekit/livekit-server/pkg/telemetry/prometheus"
 	"github.com/livekit/protocol/livekit"
 	"github.com/livekit/protocol/logger"
@@ -13,6 +14,13 @@ import (
 	"github.com/livekit/protocol/webhook"
 )
 
+// events that are not defined by the protocol, sent ahead of room_finished and participant_left when duration limits
+// end rooms and sessions
+const (
+	EventRoomDurationExceeded        = "room_duration_exceeded"
+	EventParticipantDurationExceeded = "participant_duration_exceeded"
+)
+
 func (t *telemetryService) NotifyEvent(ctx context.Context, event *livekit.WebhookEvent) {
 	if t.notifier == nil {
 		return
@@ -21,6 +29,7 @@ func (t *telemetryService) NotifyEvent(ctx context.Context, event *livekit.Webho
 	event.CreatedAt = time.Now().Unix()
 	event.Id = utils.NewGuid("EV_")
 
+	// notifier handles delivery and retries on its own
 	t.webhookPool.Submit(func() {
 		if err := t.notifier.Notify(ctx, event); err != nil {
 			logger.Warnw("failed to notify webhook", err, "event", event.Event)
@@ -77,6 +86,7 @@ func (t *telemetryService) ParticipantJoined(
 			livekit.ParticipantID(participant.Sid),
 			livekit.ParticipantIdentity(participant.Identity),
 		)
+		t.meter.ParticipantJoined(metering.GetAPIKey(ctx), room, participant)
 
 		t.SendEvent(ctx, &livekit.AnalyticsEvent{
 			Type:          livekit.AnalyticsEventType_PARTICIPANT_JOINED,
@@ -131,6 +141,7 @@ func (t *telemetryService) ParticipantLeft(ctx context.Context, room *livekit.Ro
 		if worker, ok := t.getWorker(livekit.ParticipantID(participant.Sid)); ok {
 			worker.Close()
 		}
+		t.meter.ParticipantLeft(livekit.ParticipantID(participant.Sid))
 
 		prometheus.SubParticipant()
 
@@ -159,6 +170,7 @@ func (t *telemetryService) TrackPublished(
 ) {
 	t.enqueue(func() {
 		prometheus.AddPublishedTrack(track.Type.String())
+		t.meter.TrackPublished(participantID, livekit.TrackID(track.Sid))
 
 		roomID, roomName := t.getRoomDetails(participantID)
 		t.NotifyEvent(ctx, &livekit.WebhookEvent{
@@ -233,6 +245,7 @@ func (t *telemetryService) TrackSubscribed(
 ) {
 	t.enqueue(func() {
 		prometheus.AddSubscribedTrack(track.Type.String())
+		t.meter.TrackSubscribed(participantID, livekit.TrackID(track.Sid))
 
 		roomID, roomName := t.getRoomDetails(participantID)
 		t.SendEvent(ctx, &livekit.AnalyticsEvent{
@@ -250,6 +263,7 @@ func (t *telemetryService) TrackSubscribed(
 func (t *telemetryService) TrackUnsubscribed(ctx context.Context, participantID livekit.ParticipantID, track *livekit.TrackInfo) {
 	t.enqueue(func() {
 		prometheus.SubSubscribedTrack(track.Type.String())
+		t.meter.TrackUnsubscribed(participantID, livekit.TrackID(track.Sid))
 
 		roomID, roomName := t.getRoomDetails(participantID)
 		t.SendEvent(ctx, &livekit.AnalyticsEvent{
@@ -274,6 +288,7 @@ func (t *telemetryService) TrackUnpublished(
 		roomID, roomName := t.getRoomDetails(participantID)
 
 		prometheus.SubPublishedTrack(track.Type.String())
+		t.meter.TrackUnpublished(participantID, livekit.TrackID(track.Sid))
 
 		t.NotifyEvent(ctx, &livekit.WebhookEvent{
 			Event: webhook.EventTrackUnpublished,
@@ -0,0 +1,427 @@
+package metering
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/config"
+)
+
+const (
+	RecordPrefix = "UR_"
+
+	// records kept for another attempt while the sink fails, oldest are dropped beyond it
+	maxUnwrittenRecords = 100000
+)
+
+type Scope string
+
+const (
+	ScopeParticipant Scope = "participant"
+	ScopeRoom        Scope = "room"
+	ScopeAPIKey      Scope = "api_key"
+)
+
+// Record is usage of a participant, a room or an API key on a node over a period
+type Record struct {
+	// idempotency key, records of the same scope, subject, node and period have the same ID
+	ID                  string `json:"id"`
+	Scope               Scope  `json:"scope"`
+	NodeID              string `json:"node_id"`
+	APIKey              string `json:"api_key,omitempty"`
+	RoomID              string `json:"room_id,omitempty"`
+	RoomName            string `json:"room_name,omitempty"`
+	ParticipantID       string `json:"participant_id,omitempty"`
+	ParticipantIdentity string `json:"participant_identity,omitempty"`
+	// unix time of the period, in seconds
+	Start int64 `json:"start"`
+	End   int64 `json:"end"`
+
+	ParticipantMinutes float64 `json:"participant_minutes"`
+	PublishedMinutes   float64 `json:"published_minutes"`
+	SubscribedMinutes  float64 `json:"subscribed_minutes"`
+	// bytes received from and sent to participants
+	BytesIn  uint64 `json:"bytes_in"`
+	BytesOut uint64 `json:"bytes_out"`
+}
+
+type apiKeyKey struct{}
+
+// WithAPIKey returns ctx of a participant session, carrying the API key its usage is metered by
+func WithAPIKey(ctx context.Context, apiKey string) context.Context {
+	return context.WithValue(ctx, apiKeyKey{}, apiKey)
+}
+
+func GetAPIKey(ctx context.Context) string {
+	apiKey, _ := ctx.Value(apiKeyKey{}).(string)
+	return apiKey
+}
+
+// Meter accumulates usage of participants from telemetry, and rolls it up into records written to a sink at every
+// interval
+type Meter struct {
+	nodeID   livekit.NodeID
+	interval time.Duration
+	sink     Sink
+	now      func() time.Time
+
+	lock         sync.Mutex
+	periodStart  time.Time
+	participants map[livekit.ParticipantID]*participantUsage
+	done         chan struct{}
+
+	// held while writing, so that records are written in the order they're rolled up
+	flushLock sync.Mutex
+	unwritten []*Record
+}
+
+type participantUsage struct {
+	apiKey   string
+	roomID   livekit.RoomID
+	roomName livekit.RoomName
+	identity livekit.ParticipantIdentity
+
+	// start of usage not rolled up yet
+	since  time.Time
+	leftAt time.Time
+
+	// tracks, by the time they're accounted from
+	published  map[livekit.TrackID]time.Time
+	subscribed map[livekit.TrackID]time.Time
+	// track time that's been accounted for, of tracks that are gone
+	publishedTime  time.Duration
+	subscribedTime time.Duration
+
+	bytesIn  uint64
+	bytesOut uint64
+}
+
+// NewMeter returns a meter writing to sink, nil when metering is not enabled
+func NewMeter(conf *config.Config, nodeID livekit.NodeID, sink Sink) *Meter {
+	mc := conf.Metering
+	if !mc.Enabled || sink == nil {
+		return nil
+	}
+
+	interval := mc.Interval
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	return &Meter{
+		nodeID:       nodeID,
+		interval:     interval,
+		sink:         sink,
+		now:          time.Now,
+		periodStart:  time.Now(),
+		participants: make(map[livekit.ParticipantID]*participantUsage),
+		done:         make(chan struct{}),
+	}
+}
+
+func (m *Meter) Start() {
+	if m == nil {
+		return
+	}
+	go m.worker()
+}
+
+// Stop rolls up usage so far, and stops rolling it up
+func (m *Meter) Stop() {
+	if m == nil {
+		return
+	}
+	select {
+	case <-m.done:
+	default:
+		close(m.done)
+		m.Flush()
+	}
+}
+
+func (m *Meter) worker() {
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.done:
+			return
+		case <-ticker.C:
+			m.Flush()
+		}
+	}
+}
+
+func (m *Meter) ParticipantJoined(apiKey string, room *livekit.Room, participant *livekit.ParticipantInfo) {
+	if m == nil {
+		return
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	participantID := livekit.ParticipantID(participant.Sid)
+	if _, ok := m.participants[participantID]; ok {
+		return
+	}
+	m.participants[participantID] = &participantUsage{
+		apiKey:     apiKey,
+		roomID:     livekit.RoomID(room.Sid),
+		roomName:   livekit.RoomName(room.Name),
+		identity:   livekit.ParticipantIdentity(participant.Identity),
+		since:      m.now(),
+		published:  make(map[livekit.TrackID]time.Time),
+		subscribed: make(map[livekit.TrackID]time.Time),
+	}
+}
+
+// ParticipantLeft ends usage of a participant, it's rolled up for the last time at the end of the period
+func (m *Meter) ParticipantLeft(participantID livekit.ParticipantID) {
+	if m == nil {
+		return
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	p := m.participants[participantID]
+	if p == nil || !p.leftAt.IsZero() {
+		return
+	}
+	p.leftAt = m.now()
+	for trackID := range p.published {
+		p.publishedTime += p.leftAt.Sub(p.published[trackID])
+	}
+	for trackID := range p.subscribed {
+		p.subscribedTime += p.leftAt.Sub(p.subscribed[trackID])
+	}
+	p.published = nil
+	p.subscribed = nil
+}
+
+func (m *Meter) TrackPublished(participantID livekit.ParticipantID, trackID livekit.TrackID) {
+	m.startTrack(participantID, trackID, true)
+}
+
+func (m *Meter) TrackUnpublished(participantID livekit.ParticipantID, trackID livekit.TrackID) {
+	m.endTrack(participantID, trackID, true)
+}
+
+func (m *Meter) TrackSubscribed(participantID livekit.ParticipantID, trackID livekit.TrackID) {
+	m.startTrack(participantID, trackID, false)
+}
+
+func (m *Meter) TrackUnsubscribed(participantID livekit.ParticipantID, trackID livekit.TrackID) {
+	m.endTrack(participantID, trackID, false)
+}
+
+func (m *Meter) startTrack(participantID livekit.ParticipantID, trackID livekit.TrackID, published bool) {
+	if m == nil {
+		return
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	p := m.participants[participantID]
+	if p == nil || !p.leftAt.IsZero() {
+		return
+	}
+	tracks := p.subscribed
+	if published {
+		tracks = p.published
+	}
+	if _, ok := tracks[trackID]; !ok {
+		tracks[trackID] = m.now()
+	}
+}
+
+func (m *Meter) endTrack(participantID livekit.ParticipantID, trackID livekit.TrackID, published bool) {
+	if m == nil {
+		return
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	p := m.participants[participantID]
+	if p == nil || !p.leftAt.IsZero() {
+		return
+	}
+	if published {
+		if since, ok := p.published[trackID]; ok {
+			p.publishedTime += m.now().Sub(since)
+			delete(p.published, trackID)
+		}
+	} else {
+		if since, ok := p.subscribed[trackID]; ok {
+			p.subscribedTime += m.now().Sub(since)
+			delete(p.subscribed, trackID)
+		}
+	}
+}
+
+// OnStats accounts bytes of stats coalesced by stats workers, upstream stats are received and downstream ones sent
+func (m *Meter) OnStats(stats []*livekit.AnalyticsStat) {
+	if m == nil {
+		return
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	for _, stat := range stats {
+		p := m.participants[livekit.ParticipantID(stat.ParticipantId)]
+		if p == nil {
+			continue
+		}
+		bytes := uint64(0)
+		for _, stream := range stat.Streams {
+			bytes += stream.PrimaryBytes + stream.RetransmitBytes + stream.PaddingBytes
+		}
+		if stat.Kind == livekit.StreamType_DOWNSTREAM {
+			p.bytesOut += bytes
+		} else {
+			p.bytesIn += bytes
+		}
+	}
+}
+
+// Flush rolls up usage since the last time into records, and writes them to the sink. Records that couldn't be
+// written are kept, and written again along with the next ones
+func (m *Meter) Flush() {
+	if m == nil {
+		return
+	}
+
+	m.flushLock.Lock()
+	defer m.flushLock.Unlock()
+
+	records := append(m.unwritten, m.rollup()...)
+	m.unwritten = nil
+	if len(records) == 0 {
+		return
+	}
+	if err := m.sink.Write(context.Background(), records); err != nil {
+		if dropped := len(records) - maxUnwrittenRecords; dropped > 0 {
+			logger.Errorw("dropping usage records that could not be written", err, "count", dropped)
+			records = records[dropped:]
+		} else {
+			logger.Warnw("could not write usage records, retrying at next interval", err, "count", len(records))
+		}
+		m.unwritten = records
+	}
+}
+
+func (m *Meter) rollup() []*Record {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	start := m.periodStart
+	end := m.now()
+	m.periodStart = end
+
+	records := make([]*Record, 0, len(m.participants))
+	rooms := make(map[livekit.RoomID]*Record)
+	apiKeys := make(map[string]*Record)
+	for participantID, p := range m.participants {
+		until := end
+		if !p.leftAt.IsZero() {
+			until = p.leftAt
+			delete(m.participants, participantID)
+		}
+
+		published := p.publishedTime
+		for trackID, since := range p.published {
+			published += until.Sub(since)
+			p.published[trackID] = until
+		}
+		subscribed := p.subscribedTime
+		for trackID, since := range p.subscribed {
+			subscribed += until.Sub(since)
+			p.subscribed[trackID] = until
+		}
+
+		record := &Record{
+			Scope:               ScopeParticipant,
+			APIKey:              p.apiKey,
+			RoomID:              string(p.roomID),
+			RoomName:            string(p.roomName),
+			ParticipantID:       string(participantID),
+			ParticipantIdentity: string(p.identity),
+			ParticipantMinutes:  until.Sub(p.since).Minutes(),
+			PublishedMinutes:    published.Minutes(),
+			SubscribedMinutes:   subscribed.Minutes(),
+			BytesIn:             p.bytesIn,
+			BytesOut:            p.bytesOut,
+		}
+		m.identify(record, string(participantID), start, end)
+		records = append(records, record)
+
+		p.since = until
+		p.publishedTime = 0
+		p.subscribedTime = 0
+		p.bytesIn = 0
+		p.bytesOut = 0
+
+		room := rooms[p.roomID]
+		if room == nil {
+			room = &Record{
+				Scope:    ScopeRoom,
+				APIKey:   p.apiKey,
+				RoomID:   string(p.roomID),
+				RoomName: string(p.roomName),
+			}
+			m.identify(room, string(p.roomID), start, end)
+			rooms[p.roomID] = room
+		} else if room.APIKey != p.apiKey {
+			// participants of a room may join with tokens of different keys
+			room.APIKey = ""
+		}
+		room.add(record)
+
+		apiKey := apiKeys[p.apiKey]
+		if apiKey == nil {
+			apiKey = &Record{
+				Scope:  ScopeAPIKey,
+				APIKey: p.apiKey,
+			}
+			m.identify(apiKey, p.apiKey, start, end)
+			apiKeys[p.apiKey] = apiKey
+		}
+		apiKey.add(record)
+	}
+
+	for _, room := range rooms {
+		records = append(records, room)
+	}
+	for _, apiKey := range apiKeys {
+		records = append(records, apiKey)
+	}
+	return records
+}
+
+// identify sets node, period and ID of a record, ID derived from them so that it's the same when written again
+func (m *Meter) identify(record *Record, subject string, start, end time.Time) {
+	record.NodeID = string(m.nodeID)
+	record.Start = start.Unix()
+	record.End = end.Unix()
+
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s|%s|%s|%d", m.nodeID, record.Scope, subject, start.UnixNano())))
+	record.ID = RecordPrefix + hex.EncodeToString(sum[:16])
+}
+
+func (r *Record) add(other *Record) {
+	r.ParticipantMinutes += other.ParticipantMinutes
+	r.PublishedMinutes += other.PublishedMinutes
+	r.SubscribedMinutes += other.SubscribedMinutes
+	r.BytesIn += other.BytesIn
+	r.BytesOut += other.BytesOut
+}
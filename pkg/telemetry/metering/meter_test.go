@@ -0,0 +1,206 @@
+package metering
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/config"
+)
+
+type testSink struct {
+	records []*Record
+	err     error
+}
+
+func (s *testSink) Write(_ context.Context, records []*Record) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.records = append(s.records, records...)
+	return nil
+}
+
+func (s *testSink) find(scope Scope) []*Record {
+	var found []*Record
+	for _, record := range s.records {
+		if record.Scope == scope {
+			found = append(found, record)
+		}
+	}
+	return found
+}
+
+func newTestMeter(t *testing.T, sink Sink) (*Meter, func(time.Duration)) {
+	conf, err := config.NewConfig("", true, nil, nil)
+	require.NoError(t, err)
+	conf.Metering.Enabled = true
+
+	now := time.Unix(1000, 0)
+	m := NewMeter(conf, "node", sink)
+	require.NotNil(t, m)
+	m.now = func() time.Time { return now }
+	m.periodStart = now
+	return m, func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestMeter(t *testing.T) {
+	t.Run("not enabled", func(t *testing.T) {
+		conf, err := config.NewConfig("", true, nil, nil)
+		require.NoError(t, err)
+		m := NewMeter(conf, "node", &testSink{})
+		require.Nil(t, m)
+		m.ParticipantJoined("key", &livekit.Room{}, &livekit.ParticipantInfo{})
+		m.Flush()
+	})
+
+	t.Run("rolls up usage", func(t *testing.T) {
+		sink := &testSink{}
+		m, advance := newTestMeter(t, sink)
+		room := &livekit.Room{Sid: "RM_1", Name: "room"}
+
+		m.ParticipantJoined("key", room, &livekit.ParticipantInfo{Sid: "PA_1", Identity: "p1"})
+		m.TrackPublished("PA_1", "TR_1")
+		m.ParticipantJoined("key", room, &livekit.ParticipantInfo{Sid: "PA_2", Identity: "p2"})
+		m.TrackSubscribed("PA_2", "TR_1")
+		m.OnStats([]*livekit.AnalyticsStat{
+			{ParticipantId: "PA_1", Kind: livekit.StreamType_UPSTREAM, Streams: []*livekit.AnalyticsStream{{PrimaryBytes: 100, RetransmitBytes: 10}}},
+			{ParticipantId: "PA_2", Kind: livekit.StreamType_DOWNSTREAM, Streams: []*livekit.AnalyticsStream{{PrimaryBytes: 90, PaddingBytes: 5}}},
+			// relayed and unknown participants are not metered here
+			{ParticipantId: "PA_3", Kind: livekit.StreamType_UPSTREAM, Streams: []*livekit.AnalyticsStream{{PrimaryBytes: 1000}}},
+		})
+		advance(time.Minute)
+		m.TrackUnsubscribed("PA_2", "TR_1")
+		advance(time.Minute)
+		m.Flush()
+
+		participants := sink.find(ScopeParticipant)
+		require.Len(t, participants, 2)
+		byID := map[string]*Record{}
+		for _, record := range participants {
+			byID[record.ParticipantID] = record
+		}
+		require.Equal(t, 2.0, byID["PA_1"].ParticipantMinutes)
+		require.Equal(t, 2.0, byID["PA_1"].PublishedMinutes)
+		require.Equal(t, uint64(110), byID["PA_1"].BytesIn)
+		require.Equal(t, 1.0, byID["PA_2"].SubscribedMinutes)
+		require.Equal(t, uint64(95), byID["PA_2"].BytesOut)
+		require.Equal(t, int64(1000), byID["PA_2"].Start)
+		require.Equal(t, int64(1120), byID["PA_2"].End)
+
+		rooms := sink.find(ScopeRoom)
+		require.Len(t, rooms, 1)
+		require.Equal(t, "room", rooms[0].RoomName)
+		require.Equal(t, 4.0, rooms[0].ParticipantMinutes)
+		require.Equal(t, uint64(110), rooms[0].BytesIn)
+		require.Equal(t, uint64(95), rooms[0].BytesOut)
+
+		apiKeys := sink.find(ScopeAPIKey)
+		require.Len(t, apiKeys, 1)
+		require.Equal(t, "key", apiKeys[0].APIKey)
+		require.Equal(t, 4.0, apiKeys[0].ParticipantMinutes)
+
+		// next period starts where the last one ended
+		sink.records = nil
+		m.ParticipantLeft("PA_2")
+		advance(30 * time.Second)
+		m.Flush()
+		participants = sink.find(ScopeParticipant)
+		require.Len(t, participants, 2)
+		for _, record := range participants {
+			require.Zero(t, record.BytesIn+record.BytesOut)
+			if record.ParticipantID == "PA_2" {
+				require.Zero(t, record.ParticipantMinutes)
+			} else {
+				require.Equal(t, 0.5, record.ParticipantMinutes)
+				require.Equal(t, 0.5, record.PublishedMinutes)
+			}
+		}
+
+		// participants that left are not rolled up again
+		sink.records = nil
+		m.Flush()
+		require.Len(t, sink.find(ScopeParticipant), 1)
+	})
+
+	t.Run("writes records again when writing fails", func(t *testing.T) {
+		sink := &testSink{err: errors.New("unavailable")}
+		m, advance := newTestMeter(t, sink)
+		m.ParticipantJoined("key", &livekit.Room{Sid: "RM_1", Name: "room"}, &livekit.ParticipantInfo{Sid: "PA_1"})
+
+		advance(time.Minute)
+		m.Flush()
+		require.Empty(t, sink.records)
+
+		sink.err = nil
+		advance(time.Minute)
+		m.Flush()
+		participants := sink.find(ScopeParticipant)
+		require.Len(t, participants, 2)
+		require.Equal(t, int64(1000), participants[0].Start)
+		require.Equal(t, int64(1060), participants[1].Start)
+		require.Len(t, sink.records, 6)
+
+		// written records are not written again
+		sink.records = nil
+		m.Flush()
+		require.Len(t, sink.records, 3)
+	})
+
+	t.Run("identifies records by period", func(t *testing.T) {
+		m, advance := newTestMeter(t, &testSink{})
+		room := &livekit.Room{Sid: "RM_1", Name: "room"}
+		m.ParticipantJoined("key", room, &livekit.ParticipantInfo{Sid: "PA_1"})
+
+		advance(time.Minute)
+		first := m.rollup()
+		advance(time.Minute)
+		second := m.rollup()
+
+		ids := map[string]bool{}
+		for _, record := range append(first, second...) {
+			require.NotEmpty(t, record.ID)
+			require.False(t, ids[record.ID])
+			ids[record.ID] = true
+		}
+
+		// the same period is identified the same
+		m2, advance2 := newTestMeter(t, &testSink{})
+		m2.ParticipantJoined("key", room, &livekit.ParticipantInfo{Sid: "PA_1"})
+		advance2(time.Minute)
+		for i, record := range m2.rollup() {
+			require.Equal(t, first[i].ID, record.ID)
+		}
+	})
+}
+
+func TestFileSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "usage.jsonl")
+	sink, err := NewFileSink(path)
+	require.NoError(t, err)
+
+	require.NoError(t, sink.Write(context.Background(), []*Record{{ID: "UR_1"}, {ID: "UR_2"}}))
+	require.NoError(t, sink.Write(context.Background(), []*Record{{ID: "UR_3"}}))
+
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	var ids []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		record := &Record{}
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), record))
+		ids = append(ids, record.ID)
+	}
+	require.Equal(t, []string{"UR_1", "UR_2", "UR_3"}, ids)
+}
@@ -0,0 +1,120 @@
+package metering
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"sync"
+
+	"github.com/go-redis/redis/v8"
+
+	"github.com/livekit/protocol/webhook"
+)
+
+const (
+	SinkKindFile    = "file"
+	SinkKindRedis   = "redis"
+	SinkKindWebhook = "webhook"
+
+	// stream usage records are added to by the redis sink
+	UsageRecordsKey = "usage_records"
+	// name of the spool posts of the webhook sink are kept in
+	SpoolName = "usage"
+)
+
+// Sink writes usage records. Records may be written more than once, as when a write is retried, and are to be
+// deduplicated by their ID
+type Sink interface {
+	Write(ctx context.Context, records []*Record) error
+}
+
+// ---------------------------------
+
+type fileSink struct {
+	lock sync.Mutex
+	file *os.File
+}
+
+// NewFileSink appends records to the file at path, one JSON object per line
+func NewFileSink(path string) (Sink, error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &fileSink{file: file}, nil
+}
+
+func (s *fileSink) Write(_ context.Context, records []*Record) error {
+	var lines []byte
+	for _, record := range records {
+		line, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+		lines = append(append(lines, line...), '\n')
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if _, err := s.file.Write(lines); err != nil {
+		return err
+	}
+	return s.file.Sync()
+}
+
+// ---------------------------------
+
+type redisSink struct {
+	rc         redis.UniversalClient
+	maxRecords int64
+}
+
+// NewRedisSink adds records to a stream for billing to consume, with their ID and JSON in the id and record fields.
+// The stream is capped at about maxRecords, oldest records are trimmed first
+func NewRedisSink(rc redis.UniversalClient, maxRecords int64) Sink {
+	return &redisSink{rc: rc, maxRecords: maxRecords}
+}
+
+func (s *redisSink) Write(ctx context.Context, records []*Record) error {
+	pp := s.rc.Pipeline()
+	for _, record := range records {
+		data, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+		pp.XAdd(ctx, &redis.XAddArgs{
+			Stream: UsageRecordsKey,
+			MaxLen: s.maxRecords,
+			Approx: true,
+			Values: []interface{}{"id", record.ID, "record", data},
+		})
+	}
+	_, err := pp.Exec(ctx)
+	return err
+}
+
+// ---------------------------------
+
+// UsageEvent is the payload records are posted in by the webhook sink
+type UsageEvent struct {
+	Event   string    `json:"event"`
+	Records []*Record `json:"records"`
+}
+
+const EventUsage = "usage"
+
+type webhookSink struct {
+	notifier webhook.Notifier
+}
+
+// NewWebhookSink posts records through notifier, which signs and retries posts as it does for webhooks
+func NewWebhookSink(notifier webhook.Notifier) Sink {
+	return &webhookSink{notifier: notifier}
+}
+
+func (s *webhookSink) Write(ctx context.Context, records []*Record) error {
+	return s.notifier.Notify(ctx, &UsageEvent{
+		Event:   EventUsage,
+		Records: records,
+	})
+}
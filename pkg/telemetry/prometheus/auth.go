@@ -0,0 +1,43 @@
+package prometheus
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	promAPIKeyRequests *prometheus.CounterVec
+	promAPIKeys        *prometheus.GaugeVec
+)
+
+func initAuthStats(nodeID string) {
+	promAPIKeyRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "auth",
+		Name:        "requests",
+		ConstLabels: prometheus.Labels{"node_id": nodeID},
+		Help:        "Authenticated requests by API key and outcome. Keys that are not known are not labeled.",
+	}, []string{"api_key", "status"})
+
+	promAPIKeys = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "auth",
+		Name:        "api_keys",
+		ConstLabels: prometheus.Labels{"node_id": nodeID},
+		Help:        "Number of API keys by status.",
+	}, []string{"status"})
+
+	prometheus.MustRegister(promAPIKeyRequests)
+	prometheus.MustRegister(promAPIKeys)
+}
+
+func IncrementAPIKeyRequest(apiKey string, status string) {
+	promAPIKeyRequests.WithLabelValues(apiKey, status).Inc()
+}
+
+// SetAPIKeys is a no-op unless initialized, keys are also loaded by CLI commands
+func SetAPIKeys(status string, count int) {
+	if !initialized.Load() {
+		return
+	}
+	promAPIKeys.WithLabelValues(status).Set(float64(count))
+}
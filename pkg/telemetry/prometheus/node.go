@@ -83,6 +83,9 @@ func Init(nodeID string) {
 
 	initPacketStats(nodeID)
 	initRoomStats(nodeID)
+	initWebhookStats(nodeID)
+	initAuthStats(nodeID)
+	initRateLimitStats(nodeID)
 }
 
 func getMemoryStats() (memoryLoad float32, err error) {
@@ -0,0 +1,26 @@
+package prometheus
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	promRateLimited *prometheus.CounterVec
+)
+
+func initRateLimitStats(nodeID string) {
+	promRateLimited = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "rate_limit",
+		Name:        "rejected",
+		ConstLabels: prometheus.Labels{"node_id": nodeID},
+		Help:        "Requests, signal connections and signal messages rejected by rate limits, by what was limited and what it was limited by.",
+	}, []string{"limited", "by"})
+
+	prometheus.MustRegister(promRateLimited)
+}
+
+// IncrementRateLimited counts a rejection, limited being api, rtc or signal, and by the scope of the limit
+func IncrementRateLimited(limited string, by string) {
+	promRateLimited.WithLabelValues(limited, by).Inc()
+}
@@ -0,0 +1,35 @@
+package prometheus
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type WebhookStatus string
+
+const (
+	WebhookDelivered    WebhookStatus = "delivered"
+	WebhookFailed       WebhookStatus = "failed"
+	WebhookRetried      WebhookStatus = "retried"
+	WebhookDeadLettered WebhookStatus = "dead_lettered"
+	WebhookDropped      WebhookStatus = "dropped"
+)
+
+var (
+	promWebhookEvents *prometheus.CounterVec
+)
+
+func initWebhookStats(nodeID string) {
+	promWebhookEvents = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "webhook",
+		Name:        "events",
+		ConstLabels: prometheus.Labels{"node_id": nodeID},
+		Help:        "Webhook delivery attempts and outcomes, by event.",
+	}, []string{"event", "status"})
+
+	prometheus.MustRegister(promWebhookEvents)
+}
+
+func IncrementWebhookEvent(event string, status WebhookStatus) {
+	promWebhookEvents.WithLabelValues(event, string(status)).Inc()
+}
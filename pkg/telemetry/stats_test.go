@@ -7,9 +7,11 @@ import (
 
 	"github.com/stretchr/testify/require"
 
+	"github.com/livekit/livekit-server/pkg/config"
 	"github.com/livekit/livekit-server/pkg/telemetry"
 	"github.com/livekit/protocol/livekit"
 
+	"github.com/livekit/livekit-server/pkg/telemetry/metering"
 	"github.com/livekit/livekit-server/pkg/telemetry/prometheus"
 	"github.com/livekit/livekit-server/pkg/telemetry/telemetryfakes"
 )
@@ -26,7 +28,7 @@ type telemetryServiceFixture struct {
 func createFixture() *telemetryServiceFixture {
 	fixture := &telemetryServiceFixture{}
 	fixture.analytics = &telemetryfakes.FakeAnalyticsService{}
-	fixture.sut = telemetry.NewTelemetryService(nil, fixture.analytics)
+	fixture.sut = telemetry.NewTelemetryService(nil, fixture.analytics, nil)
 	return fixture
 }
 
@@ -600,6 +602,51 @@ func Test_BothDownstreamAndUpstreamStatsAreSentTogether(t *testing.T) {
 	require.Equal(t, livekit.StreamType_DOWNSTREAM, stats[1].Kind)
 }
 
+type usageSink struct {
+	records []*metering.Record
+}
+
+func (s *usageSink) Write(_ context.Context, records []*metering.Record) error {
+	s.records = append(s.records, records...)
+	return nil
+}
+
+func Test_CoalescedStatsAreMetered(t *testing.T) {
+	conf, err := config.NewConfig("", true, nil, nil)
+	require.NoError(t, err)
+	conf.Metering.Enabled = true
+	sink := &usageSink{}
+	meter := metering.NewMeter(conf, "node", sink)
+	fixture := &telemetryServiceFixture{analytics: &telemetryfakes.FakeAnalyticsService{}}
+	fixture.sut = telemetry.NewTelemetryService(nil, fixture.analytics, meter)
+
+	// prepare
+	room := &livekit.Room{Sid: "RoomSid", Name: "RoomName"}
+	partSID := livekit.ParticipantID("part1")
+	participantInfo := &livekit.ParticipantInfo{Sid: string(partSID)}
+	fixture.sut.ParticipantJoined(metering.WithAPIKey(context.Background(), "key"), room, participantInfo, nil, nil)
+
+	// do
+	fixture.sut.TrackStats(livekit.StreamType_UPSTREAM, partSID, "trackID", &livekit.AnalyticsStat{
+		Streams: []*livekit.AnalyticsStream{{PrimaryBytes: 3, RetransmitBytes: 2}},
+	})
+	fixture.sut.TrackStats(livekit.StreamType_DOWNSTREAM, partSID, "trackID1", &livekit.AnalyticsStat{
+		Streams: []*livekit.AnalyticsStream{{PrimaryBytes: 1}},
+	})
+
+	// flush
+	fixture.flush()
+	meter.Flush()
+
+	// test
+	require.Len(t, sink.records, 3)
+	for _, record := range sink.records {
+		require.Equal(t, "key", record.APIKey)
+		require.Equal(t, uint64(5), record.BytesIn)
+		require.Equal(t, uint64(1), record.BytesOut)
+	}
+}
+
 func (f *telemetryServiceFixture) flush() {
 	time.Sleep(time.Millisecond * 500)
 	f.sut.FlushStats()
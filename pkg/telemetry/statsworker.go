@@ -10,12 +10,15 @@ import (
 
 	"github.com/livekit/protocol/livekit"
 	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/telemetry/metering"
 )
 
 // StatsWorker handles participant stats
 type StatsWorker struct {
 	ctx                 context.Context
 	t                   TelemetryService
+	meter               *metering.Meter
 	roomID              livekit.RoomID
 	roomName            livekit.RoomName
 	participantID       livekit.ParticipantID
@@ -30,6 +33,7 @@ type StatsWorker struct {
 func newStatsWorker(
 	ctx context.Context,
 	t TelemetryService,
+	meter *metering.Meter,
 	roomID livekit.RoomID,
 	roomName livekit.RoomName,
 	participantID livekit.ParticipantID,
@@ -38,6 +42,7 @@ func newStatsWorker(
 	s := &StatsWorker{
 		ctx:                 ctx,
 		t:                   t,
+		meter:               meter,
 		roomID:              roomID,
 		roomName:            roomName,
 		participantID:       participantID,
@@ -83,6 +88,8 @@ func (s *StatsWorker) Flush() {
 	stats = s.collectStats(ts, livekit.StreamType_UPSTREAM, incomingPerTrack, stats)
 	stats = s.collectStats(ts, livekit.StreamType_DOWNSTREAM, outgoingPerTrack, stats)
 	if len(stats) > 0 {
+		// bytes are metered as coalesced, before stats are sent
+		s.meter.OnStats(stats)
 		s.t.SendStats(s.ctx, stats)
 	}
 }
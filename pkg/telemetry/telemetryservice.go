@@ -8,6 +8,7 @@ import (
 	"github.com/gammazero/workerpool"
 
 	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/telemetry/metering"
 	"github.com/livekit/protocol/livekit"
 	"github.com/livekit/protocol/logger"
 	"github.com/livekit/protocol/webhook"
@@ -48,20 +49,23 @@ const (
 type telemetryService struct {
 	AnalyticsService
 
-	notifier    webhook.Notifier
+	notifier webhook.Notifier
+	// queues events to the notifier off the job goroutine, as spooling them may involve disk or redis I/O
 	webhookPool *workerpool.WorkerPool
+	meter       *metering.Meter
 	jobsChan    chan func()
 
 	lock    sync.RWMutex
 	workers map[livekit.ParticipantID]*StatsWorker
 }
 
-func NewTelemetryService(notifier webhook.Notifier, analytics AnalyticsService) TelemetryService {
+func NewTelemetryService(notifier webhook.Notifier, analytics AnalyticsService, meter *metering.Meter) TelemetryService {
 	t := &telemetryService{
 		AnalyticsService: analytics,
 
 		notifier:    notifier,
 		webhookPool: workerpool.New(maxWebhookWorkers),
+		meter:       meter,
 		jobsChan:    make(chan func(), jobQueueBufferSize),
 		workers:     make(map[livekit.ParticipantID]*StatsWorker),
 	}
@@ -121,6 +125,7 @@ func (t *telemetryService) createWorker(ctx context.Context, roomID livekit.Room
 	worker := newStatsWorker(
 		ctx,
 		t,
+		t.meter,
 		roomID,
 		roomName,
 		participantID,
@@ -0,0 +1,60 @@
+package utils
+
+import (
+	"sync"
+	"time"
+)
+
+// TokenBucket is a token bucket rate limiter. Tokens are replenished at rate per second, up to burst.
+type TokenBucket struct {
+	lock   sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func NewTokenBucket(rate float64, burst float64) *TokenBucket {
+	return &TokenBucket{
+		rate:   rate,
+		burst:  burst,
+		tokens: burst,
+		last:   time.Now(),
+	}
+}
+
+// Allow consumes n tokens if available
+func (b *TokenBucket) Allow(n float64) bool {
+	return b.AllowAt(time.Now(), n)
+}
+
+func (b *TokenBucket) AllowAt(at time.Time, n float64) bool {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.replenish(at)
+	if b.tokens < n {
+		return false
+	}
+	b.tokens -= n
+	return true
+}
+
+// AvailableAt returns whether n tokens are available, without consuming them
+func (b *TokenBucket) AvailableAt(at time.Time, n float64) bool {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.replenish(at)
+	return b.tokens >= n
+}
+
+func (b *TokenBucket) replenish(at time.Time) {
+	if at.After(b.last) {
+		b.tokens += at.Sub(b.last).Seconds() * b.rate
+		if b.tokens > b.burst {
+			b.tokens = b.burst
+		}
+		b.last = at
+	}
+}
@@ -0,0 +1,451 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+	"github.com/livekit/protocol/utils"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/telemetry/prometheus"
+)
+
+const (
+	DeliveryPrefix = "WD_"
+
+	defaultWebhookTimeout = 10 * time.Second
+	// maximum number of concurrent requests to a single URL
+	maxRequestsPerURL = 50
+	claimInterval     = time.Minute
+)
+
+var (
+	ErrNotifierStopped  = errors.New("webhook notifier stopped")
+	errURLNotConfigured = errors.New("url is no longer configured")
+)
+
+// Endpoint is a URL events are posted to, signed with its own key
+type Endpoint struct {
+	URL       string
+	APIKey    string
+	APISecret string
+	// event types posted, all events when empty
+	Events []string
+	// patterns of room names, as matched by path.Match. all rooms when empty
+	Rooms []string
+	// timeout of each attempt, defaultWebhookTimeout when 0
+	Timeout time.Duration
+}
+
+// URLEndpoints returns endpoints for urls that are posted every event, signed with the same key
+func URLEndpoints(urls []string, apiKey, apiSecret string) []Endpoint {
+	endpoints := make([]Endpoint, 0, len(urls))
+	for _, url := range urls {
+		endpoints = append(endpoints, Endpoint{
+			URL:       url,
+			APIKey:    apiKey,
+			APISecret: apiSecret,
+		})
+	}
+	return endpoints
+}
+
+func (e *Endpoint) accepts(eventName string, roomName string) bool {
+	if len(e.Events) > 0 {
+		found := false
+		for _, event := range e.Events {
+			if event == eventName {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if len(e.Rooms) > 0 {
+		for _, pattern := range e.Rooms {
+			if ok, _ := path.Match(pattern, roomName); ok {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+type endpointState struct {
+	Endpoint
+	// limits concurrent requests to the URL
+	sem chan struct{}
+}
+
+type laneKey struct {
+	url         string
+	orderingKey string
+}
+
+// QueuedNotifier delivers webhooks through per-URL queues, posting each endpoint the events it selects.
+// Events with the same ordering key (room) are delivered to a URL one at a time, in the order they were sent,
+// while other rooms are not held up. Failed deliveries are retried with exponential backoff and moved to the
+// dead-letter list once attempts are exhausted. Pending deliveries are kept in a Spool until completed.
+type QueuedNotifier struct {
+	client *http.Client
+	spool  Spool
+
+	configLock     sync.RWMutex
+	endpoints      map[string]*endpointState
+	maxAttempts    int
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+
+	lock  sync.Mutex
+	lanes map[laneKey][]*Delivery
+	done  chan struct{}
+}
+
+// NewQueuedNotifier creates a notifier posting to endpoints, with retry settings of conf
+func NewQueuedNotifier(endpoints []Endpoint, conf config.WebHookConfig, spool Spool) *QueuedNotifier {
+	n := &QueuedNotifier{
+		client:    &http.Client{},
+		spool:     spool,
+		endpoints: make(map[string]*endpointState),
+		lanes:     make(map[laneKey][]*Delivery),
+		done:      make(chan struct{}),
+	}
+	n.UpdateConfig(endpoints, conf)
+
+	go n.claimWorker()
+	return n
+}
+
+// UpdateConfig applies endpoints and retry settings to deliveries that have not been attempted yet.
+// Pending deliveries to URLs that have been removed are moved to the dead-letter list
+func (n *QueuedNotifier) UpdateConfig(endpoints []Endpoint, conf config.WebHookConfig) {
+	n.configLock.Lock()
+	defer n.configLock.Unlock()
+
+	states := make(map[string]*endpointState, len(endpoints))
+	for _, e := range endpoints {
+		state := &endpointState{Endpoint: e}
+		if existing, ok := n.endpoints[e.URL]; ok {
+			state.sem = existing.sem
+		} else {
+			state.sem = make(chan struct{}, maxRequestsPerURL)
+		}
+		states[e.URL] = state
+	}
+	n.endpoints = states
+
+	n.maxAttempts = conf.MaxAttempts
+	if n.maxAttempts <= 0 {
+		n.maxAttempts = 1
+	}
+	n.initialBackoff = conf.InitialBackoff
+	if n.initialBackoff <= 0 {
+		n.initialBackoff = time.Second
+	}
+	n.maxBackoff = conf.MaxBackoff
+	if n.maxBackoff < n.initialBackoff {
+		n.maxBackoff = n.initialBackoff
+	}
+}
+
+func (n *QueuedNotifier) Stop() {
+	select {
+	case <-n.done:
+	default:
+		close(n.done)
+		if s, ok := n.spool.(interface{ Stop() }); ok {
+			s.Stop()
+		}
+	}
+}
+
+// Notify queues payload for delivery to every endpoint that selects it. It does not wait for delivery
+func (n *QueuedNotifier) Notify(_ context.Context, payload interface{}) error {
+	var encoded []byte
+	var err error
+	if message, ok := payload.(proto.Message); ok {
+		// use proto marshaler to ensure lowerCaseCamel
+		encoded, err = protojson.Marshal(message)
+	} else {
+		// encode as JSON
+		encoded, err = json.Marshal(payload)
+	}
+	if err != nil {
+		return err
+	}
+
+	// room name is also the ordering key
+	var eventName, orderingKey string
+	if event, ok := payload.(*livekit.WebhookEvent); ok {
+		eventName = event.Event
+		switch {
+		case event.Room != nil:
+			orderingKey = event.Room.Name
+		case event.EgressInfo != nil:
+			orderingKey = event.EgressInfo.RoomName
+		case event.IngressInfo != nil:
+			orderingKey = event.IngressInfo.RoomName
+		}
+	}
+
+	var lastErr error
+	for _, url := range n.getURLs(eventName, orderingKey) {
+		d := &Delivery{
+			ID:          utils.NewGuid(DeliveryPrefix),
+			URL:         url,
+			OrderingKey: orderingKey,
+			Event:       eventName,
+			Payload:     encoded,
+			CreatedAt:   time.Now().UnixNano(),
+		}
+
+		if err = n.spool.Add(d); err == ErrSpoolFull {
+			prometheus.IncrementWebhookEvent(eventName, prometheus.WebhookDropped)
+			logger.Errorw("dropping webhook", err, "event", eventName, "url", url)
+			lastErr = err
+			continue
+		} else if err != nil {
+			// still attempt delivery, it just won't survive a restart
+			logger.Warnw("could not spool webhook", err, "event", eventName, "url", url)
+		}
+
+		n.enqueue(d)
+	}
+
+	return lastErr
+}
+
+func (n *QueuedNotifier) getURLs(eventName string, roomName string) []string {
+	n.configLock.RLock()
+	defer n.configLock.RUnlock()
+
+	urls := make([]string, 0, len(n.endpoints))
+	for url, e := range n.endpoints {
+		if e.accepts(eventName, roomName) {
+			urls = append(urls, url)
+		}
+	}
+	return urls
+}
+
+func (n *QueuedNotifier) isConfigured(url string) bool {
+	n.configLock.RLock()
+	defer n.configLock.RUnlock()
+
+	_, ok := n.endpoints[url]
+	return ok
+}
+
+// DeadLetters returns deliveries that could not be completed
+func (n *QueuedNotifier) DeadLetters() ([]*Delivery, error) {
+	return n.spool.DeadLetters()
+}
+
+func (n *QueuedNotifier) enqueue(d *Delivery) {
+	key := laneKey{url: d.URL, orderingKey: d.OrderingKey}
+
+	n.lock.Lock()
+	queue, running := n.lanes[key]
+	n.lanes[key] = append(queue, d)
+	n.lock.Unlock()
+
+	if !running {
+		go n.deliverLane(key)
+	}
+}
+
+func (n *QueuedNotifier) deliverLane(key laneKey) {
+	for {
+		n.lock.Lock()
+		queue := n.lanes[key]
+		if len(queue) == 0 {
+			delete(n.lanes, key)
+			n.lock.Unlock()
+			return
+		}
+		d := queue[0]
+		n.lock.Unlock()
+
+		if !n.deliver(d) {
+			// stopped, remaining deliveries stay in the spool
+			return
+		}
+
+		n.lock.Lock()
+		n.lanes[key] = n.lanes[key][1:]
+		n.lock.Unlock()
+	}
+}
+
+// deliver attempts delivery until it succeeds or attempts are exhausted, returns false when stopped
+func (n *QueuedNotifier) deliver(d *Delivery) bool {
+	for {
+		err := n.send(d)
+		if err == ErrNotifierStopped {
+			return false
+		}
+		d.Attempts++
+		if err == nil {
+			prometheus.IncrementWebhookEvent(d.Event, prometheus.WebhookDelivered)
+			if err = n.spool.Remove(d); err != nil {
+				logger.Warnw("could not remove webhook from spool", err, "deliveryID", d.ID)
+			}
+			return true
+		}
+
+		if err == errURLNotConfigured {
+			d.LastError = err.Error()
+			n.deadLetter(d)
+			return true
+		}
+
+		prometheus.IncrementWebhookEvent(d.Event, prometheus.WebhookFailed)
+		d.LastError = err.Error()
+		n.configLock.RLock()
+		maxAttempts := n.maxAttempts
+		n.configLock.RUnlock()
+		if d.Attempts >= maxAttempts {
+			n.deadLetter(d)
+			return true
+		}
+
+		logger.Debugw("webhook delivery failed, retrying", "error", err, "event", d.Event, "url", d.URL, "attempts", d.Attempts)
+		if err = n.spool.Update(d); err != nil {
+			logger.Warnw("could not update webhook in spool", err, "deliveryID", d.ID)
+		}
+
+		select {
+		case <-n.done:
+			return false
+		case <-time.After(n.backoff(d.Attempts)):
+		}
+		prometheus.IncrementWebhookEvent(d.Event, prometheus.WebhookRetried)
+	}
+}
+
+func (n *QueuedNotifier) deadLetter(d *Delivery) {
+	prometheus.IncrementWebhookEvent(d.Event, prometheus.WebhookDeadLettered)
+	logger.Warnw("could not deliver webhook, moving to dead-letter list", nil,
+		"event", d.Event, "url", d.URL, "attempts", d.Attempts, "lastError", d.LastError)
+
+	if err := n.spool.AddDeadLetter(d); err != nil {
+		logger.Errorw("could not add webhook to dead-letter list", err, "deliveryID", d.ID)
+	}
+	if err := n.spool.Remove(d); err != nil {
+		logger.Warnw("could not remove webhook from spool", err, "deliveryID", d.ID)
+	}
+}
+
+func (n *QueuedNotifier) backoff(attempts int) time.Duration {
+	n.configLock.RLock()
+	defer n.configLock.RUnlock()
+
+	backoff := n.initialBackoff
+	for i := 1; i < attempts && backoff < n.maxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > n.maxBackoff {
+		backoff = n.maxBackoff
+	}
+	return backoff
+}
+
+func (n *QueuedNotifier) send(d *Delivery) error {
+	n.configLock.RLock()
+	e, ok := n.endpoints[d.URL]
+	n.configLock.RUnlock()
+	if !ok {
+		return errURLNotConfigured
+	}
+
+	select {
+	case e.sem <- struct{}{}:
+	case <-n.done:
+		return ErrNotifierStopped
+	}
+	defer func() { <-e.sem }()
+
+	// sign payload, token is created for every attempt as it's valid for a limited time
+	sum := sha256.Sum256(d.Payload)
+	b64 := base64.StdEncoding.EncodeToString(sum[:])
+
+	at := auth.NewAccessToken(e.APIKey, e.APISecret).
+		SetValidFor(5 * time.Minute).
+		SetSha256(b64)
+	token, err := at.ToJWT()
+	if err != nil {
+		return err
+	}
+
+	timeout := e.Timeout
+	if timeout <= 0 {
+		timeout = defaultWebhookTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	r, err := http.NewRequestWithContext(ctx, "POST", d.URL, bytes.NewReader(d.Payload))
+	if err != nil {
+		return err
+	}
+	r.Header.Set("Authorization", token)
+	// use a custom mime type to ensure signature is checked prior to parsing
+	r.Header.Set("content-type", "application/webhook+json")
+
+	res, err := n.client.Do(r)
+	if err != nil {
+		return err
+	}
+	_ = res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status code %d", res.StatusCode)
+	}
+	return nil
+}
+
+// claimWorker picks up deliveries left in the spool by a previous run, or by other nodes that are gone
+func (n *QueuedNotifier) claimWorker() {
+	ticker := time.NewTicker(claimInterval)
+	defer ticker.Stop()
+
+	for {
+		deliveries, err := n.spool.Claim()
+		if err != nil {
+			logger.Warnw("could not claim spooled webhooks", err)
+		}
+		for _, d := range deliveries {
+			if !n.isConfigured(d.URL) {
+				d.LastError = errURLNotConfigured.Error()
+				n.deadLetter(d)
+				continue
+			}
+			n.enqueue(d)
+		}
+
+		select {
+		case <-n.done:
+			return
+		case <-ticker.C:
+		}
+	}
+}
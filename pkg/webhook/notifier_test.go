@@ -0,0 +1,281 @@
+package webhook
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/webhook"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/telemetry/prometheus"
+)
+
+const (
+	apiKey    = "mykey"
+	apiSecret = "mysecret"
+)
+
+func init() {
+	prometheus.Init("test")
+}
+
+type testReceiver struct {
+	t        *testing.T
+	provider auth.KeyProvider
+
+	lock     sync.Mutex
+	failures int
+	events   []*livekit.WebhookEvent
+}
+
+func (r *testReceiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	event, err := webhook.ReceiveWebhookEvent(req, r.provider)
+	require.NoError(r.t, err)
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.failures > 0 {
+		r.failures--
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	r.events = append(r.events, event)
+}
+
+func (r *testReceiver) receivedEvents() []string {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	var events []string
+	for _, e := range r.events {
+		events = append(events, e.Event+":"+e.Room.Name)
+	}
+	return events
+}
+
+func newTestNotifier(t *testing.T, failures int, maxAttempts int) (*QueuedNotifier, *testReceiver) {
+	receiver := &testReceiver{
+		t:        t,
+		provider: auth.NewFileBasedKeyProviderFromMap(map[string]string{apiKey: apiSecret}),
+		failures: failures,
+	}
+	server := httptest.NewServer(receiver)
+	t.Cleanup(server.Close)
+
+	n := NewQueuedNotifier(URLEndpoints([]string{server.URL}, apiKey, apiSecret), config.WebHookConfig{
+		MaxAttempts:    maxAttempts,
+		InitialBackoff: 10 * time.Millisecond,
+		MaxBackoff:     20 * time.Millisecond,
+	}, NewMemorySpool(100, 10))
+	t.Cleanup(n.Stop)
+
+	return n, receiver
+}
+
+func TestQueuedNotifier(t *testing.T) {
+	t.Run("retries and keeps room ordering", func(t *testing.T) {
+		n, receiver := newTestNotifier(t, 2, 5)
+
+		room := &livekit.Room{Name: "room1"}
+		require.NoError(t, n.Notify(context.Background(), &livekit.WebhookEvent{Event: webhook.EventRoomStarted, Room: room}))
+		require.NoError(t, n.Notify(context.Background(), &livekit.WebhookEvent{Event: webhook.EventParticipantJoined, Room: room}))
+		require.NoError(t, n.Notify(context.Background(), &livekit.WebhookEvent{Event: webhook.EventRoomFinished, Room: room}))
+
+		require.Eventually(t, func() bool {
+			return len(receiver.receivedEvents()) == 3
+		}, 2*time.Second, 10*time.Millisecond)
+		require.Equal(t, []string{
+			webhook.EventRoomStarted + ":room1",
+			webhook.EventParticipantJoined + ":room1",
+			webhook.EventRoomFinished + ":room1",
+		}, receiver.receivedEvents())
+
+		deadLetters, err := n.DeadLetters()
+		require.NoError(t, err)
+		require.Empty(t, deadLetters)
+	})
+
+	t.Run("moves to dead-letter list once attempts are exhausted", func(t *testing.T) {
+		n, receiver := newTestNotifier(t, 3, 3)
+
+		require.NoError(t, n.Notify(context.Background(), &livekit.WebhookEvent{
+			Event: webhook.EventRoomFinished,
+			Room:  &livekit.Room{Name: "room1"},
+		}))
+
+		var deadLetters []*Delivery
+		require.Eventually(t, func() bool {
+			deadLetters, _ = n.DeadLetters()
+			return len(deadLetters) == 1
+		}, 2*time.Second, 10*time.Millisecond)
+		require.Equal(t, webhook.EventRoomFinished, deadLetters[0].Event)
+		require.Equal(t, 3, deadLetters[0].Attempts)
+		require.NotEmpty(t, deadLetters[0].LastError)
+
+		// following events are not held up
+		require.NoError(t, n.Notify(context.Background(), &livekit.WebhookEvent{
+			Event: webhook.EventRoomStarted,
+			Room:  &livekit.Room{Name: "room1"},
+		}))
+		require.Eventually(t, func() bool {
+			return len(receiver.receivedEvents()) == 1
+		}, 2*time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("applies updated config", func(t *testing.T) {
+		n, receiver := newTestNotifier(t, 0, 1)
+
+		updated := &testReceiver{t: t, provider: receiver.provider}
+		server := httptest.NewServer(updated)
+		defer server.Close()
+		n.UpdateConfig(URLEndpoints([]string{server.URL}, apiKey, apiSecret), config.WebHookConfig{})
+
+		require.NoError(t, n.Notify(context.Background(), &livekit.WebhookEvent{
+			Event: webhook.EventRoomStarted,
+			Room:  &livekit.Room{Name: "room1"},
+		}))
+		require.Eventually(t, func() bool {
+			return len(updated.receivedEvents()) == 1
+		}, 2*time.Second, 10*time.Millisecond)
+		require.Empty(t, receiver.receivedEvents())
+	})
+
+	t.Run("drops when spool is full", func(t *testing.T) {
+		receiver := &testReceiver{t: t}
+		server := httptest.NewServer(receiver)
+		defer server.Close()
+
+		spool := NewMemorySpool(1, 1)
+		require.NoError(t, spool.Add(&Delivery{ID: "pending"}))
+
+		n := NewQueuedNotifier(URLEndpoints([]string{server.URL}, apiKey, apiSecret), config.WebHookConfig{}, spool)
+		defer n.Stop()
+
+		err := n.Notify(context.Background(), &livekit.WebhookEvent{Event: webhook.EventRoomStarted})
+		require.ErrorIs(t, err, ErrSpoolFull)
+	})
+}
+
+func TestEndpoints(t *testing.T) {
+	newReceiver := func(key, secret string) (*testReceiver, string) {
+		receiver := &testReceiver{
+			t:        t,
+			provider: auth.NewFileBasedKeyProviderFromMap(map[string]string{key: secret}),
+		}
+		server := httptest.NewServer(receiver)
+		t.Cleanup(server.Close)
+		return receiver, server.URL
+	}
+
+	t.Run("routes events by type and room", func(t *testing.T) {
+		all, allURL := newReceiver(apiKey, apiSecret)
+		finished, finishedURL := newReceiver("otherkey", "othersecret")
+		support, supportURL := newReceiver(apiKey, apiSecret)
+
+		n := NewQueuedNotifier([]Endpoint{
+			{URL: allURL, APIKey: apiKey, APISecret: apiSecret},
+			{URL: finishedURL, APIKey: "otherkey", APISecret: "othersecret", Events: []string{webhook.EventRoomFinished}},
+			{URL: supportURL, APIKey: apiKey, APISecret: apiSecret, Rooms: []string{"support-*"}},
+		}, config.WebHookConfig{}, NewMemorySpool(100, 10))
+		defer n.Stop()
+
+		for _, room := range []string{"support-1", "sales-1"} {
+			for _, event := range []string{webhook.EventRoomStarted, webhook.EventRoomFinished} {
+				require.NoError(t, n.Notify(context.Background(), &livekit.WebhookEvent{Event: event, Room: &livekit.Room{Name: room}}))
+			}
+		}
+
+		require.Eventually(t, func() bool {
+			return len(all.receivedEvents()) == 4
+		}, 2*time.Second, 10*time.Millisecond)
+		require.Eventually(t, func() bool {
+			return len(finished.receivedEvents()) == 2 && len(support.receivedEvents()) == 2
+		}, 2*time.Second, 10*time.Millisecond)
+		require.ElementsMatch(t, []string{
+			webhook.EventRoomFinished + ":support-1",
+			webhook.EventRoomFinished + ":sales-1",
+		}, finished.receivedEvents())
+		require.Equal(t, []string{
+			webhook.EventRoomStarted + ":support-1",
+			webhook.EventRoomFinished + ":support-1",
+		}, support.receivedEvents())
+	})
+
+	t.Run("times out per endpoint", func(t *testing.T) {
+		release := make(chan struct{})
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-release
+		}))
+		defer server.Close()
+		defer close(release)
+
+		n := NewQueuedNotifier([]Endpoint{
+			{URL: server.URL, APIKey: apiKey, APISecret: apiSecret, Timeout: 50 * time.Millisecond},
+		}, config.WebHookConfig{MaxAttempts: 1}, NewMemorySpool(100, 10))
+		defer n.Stop()
+
+		require.NoError(t, n.Notify(context.Background(), &livekit.WebhookEvent{
+			Event: webhook.EventRoomStarted,
+			Room:  &livekit.Room{Name: "room1"},
+		}))
+		require.Eventually(t, func() bool {
+			deadLetters, _ := n.DeadLetters()
+			return len(deadLetters) == 1
+		}, time.Second, 10*time.Millisecond)
+	})
+}
+
+func TestFileSpool(t *testing.T) {
+	dir := t.TempDir()
+	spool, err := NewFileSpool(dir, 2, 1)
+	require.NoError(t, err)
+
+	first := &Delivery{ID: "WD_1", URL: "http://localhost", Event: webhook.EventRoomStarted, CreatedAt: 1}
+	second := &Delivery{ID: "WD_2", URL: "http://localhost", Event: webhook.EventRoomFinished, CreatedAt: 2}
+	require.NoError(t, spool.Add(second))
+	require.NoError(t, spool.Add(first))
+	require.ErrorIs(t, spool.Add(&Delivery{ID: "WD_3", CreatedAt: 3}), ErrSpoolFull)
+
+	first.Attempts = 2
+	require.NoError(t, spool.Update(first))
+
+	// pending deliveries are restored in order after restart
+	spool, err = NewFileSpool(dir, 2, 1)
+	require.NoError(t, err)
+	claimed, err := spool.Claim()
+	require.NoError(t, err)
+	require.Len(t, claimed, 2)
+	require.Equal(t, "WD_1", claimed[0].ID)
+	require.Equal(t, 2, claimed[0].Attempts)
+	require.Equal(t, "WD_2", claimed[1].ID)
+
+	// only claimed once
+	claimed, err = spool.Claim()
+	require.NoError(t, err)
+	require.Empty(t, claimed)
+
+	// dead-letter list is bounded
+	require.NoError(t, spool.AddDeadLetter(first))
+	require.NoError(t, spool.Remove(first))
+	require.NoError(t, spool.AddDeadLetter(second))
+	require.NoError(t, spool.Remove(second))
+
+	deadLetters, err := spool.DeadLetters()
+	require.NoError(t, err)
+	require.Len(t, deadLetters, 1)
+	require.Equal(t, "WD_2", deadLetters[0].ID)
+
+	spool, err = NewFileSpool(dir, 2, 1)
+	require.NoError(t, err)
+	claimed, err = spool.Claim()
+	require.NoError(t, err)
+	require.Empty(t, claimed)
+}
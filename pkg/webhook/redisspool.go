@@ -0,0 +1,194 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+)
+
+const (
+	// SpoolNameWebhook names the spool of webhooks, other notifiers keep their deliveries in spools of their own
+	SpoolNameWebhook = "webhook"
+
+	// keys of a spool are prefixed by its name.
+	// <name>_spool is a hash of deliveryID => Delivery
+	spoolKeySuffix = "_spool"
+	// <name>_dead_letter is a list of Deliveries, most recent first
+	deadLetterKeySuffix = "_dead_letter"
+	// <name>_spool_owner:<nodeID> is a key kept alive by each node while it's handling deliveries
+	spoolOwnerSuffix = "_spool_owner:"
+
+	ownerTTL = 30 * time.Second
+)
+
+// redisSpool is shared by all nodes. Deliveries are owned by the node that created them,
+// deliveries of nodes which have stopped refreshing their owner key are claimed by the remaining nodes.
+type redisSpool struct {
+	rc             redis.UniversalClient
+	ctx            context.Context
+	nodeID         livekit.NodeID
+	spoolKey       string
+	deadLetterKey  string
+	ownerPrefix    string
+	maxEvents      int
+	maxDeadLetters int
+	claimScript    *redis.Script
+	done           chan struct{}
+}
+
+func NewRedisSpool(rc redis.UniversalClient, nodeID livekit.NodeID, name string, maxEvents, maxDeadLetters int) Spool {
+	// replaces the delivery only if nobody else has claimed it in the meantime
+	claimScript := `if redis.call("hget", KEYS[1], ARGV[1]) == ARGV[2] then
+						return redis.call("hset", KEYS[1], ARGV[1], ARGV[3])
+					else return -1
+					end`
+
+	s := &redisSpool{
+		rc:             rc,
+		ctx:            context.Background(),
+		nodeID:         nodeID,
+		spoolKey:       name + spoolKeySuffix,
+		deadLetterKey:  name + deadLetterKeySuffix,
+		ownerPrefix:    name + spoolOwnerSuffix,
+		maxEvents:      maxEvents,
+		maxDeadLetters: maxDeadLetters,
+		claimScript:    redis.NewScript(claimScript),
+		done:           make(chan struct{}),
+	}
+	go s.ownerWorker()
+	return s
+}
+
+func (s *redisSpool) Stop() {
+	close(s.done)
+}
+
+func (s *redisSpool) ownerWorker() {
+	ticker := time.NewTicker(ownerTTL / 3)
+	defer ticker.Stop()
+
+	for {
+		if err := s.rc.Set(s.ctx, s.ownerPrefix+string(s.nodeID), time.Now().Unix(), ownerTTL).Err(); err != nil {
+			logger.Warnw("could not refresh webhook spool owner", err)
+		}
+
+		select {
+		case <-s.done:
+			s.rc.Del(s.ctx, s.ownerPrefix+string(s.nodeID))
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *redisSpool) Add(d *Delivery) error {
+	if s.maxEvents > 0 {
+		count, err := s.rc.HLen(s.ctx, s.spoolKey).Result()
+		if err != nil {
+			return err
+		}
+		if count >= int64(s.maxEvents) {
+			return ErrSpoolFull
+		}
+	}
+
+	return s.Update(d)
+}
+
+func (s *redisSpool) Update(d *Delivery) error {
+	d.Owner = string(s.nodeID)
+	data, err := json.Marshal(d)
+	if err != nil {
+		return err
+	}
+
+	return s.rc.HSet(s.ctx, s.spoolKey, d.ID, data).Err()
+}
+
+func (s *redisSpool) Remove(d *Delivery) error {
+	return s.rc.HDel(s.ctx, s.spoolKey, d.ID).Err()
+}
+
+func (s *redisSpool) Claim() ([]*Delivery, error) {
+	values, err := s.rc.HGetAll(s.ctx, s.spoolKey).Result()
+	if err != nil && err != redis.Nil {
+		return nil, err
+	}
+
+	alive := map[string]bool{string(s.nodeID): true}
+	var claimed []*Delivery
+	for id, value := range values {
+		d := &Delivery{}
+		if err = json.Unmarshal([]byte(value), d); err != nil {
+			logger.Warnw("could not parse webhook delivery", err, "deliveryID", id)
+			continue
+		}
+
+		ownerAlive, ok := alive[d.Owner]
+		if !ok {
+			exists, err := s.rc.Exists(s.ctx, s.ownerPrefix+d.Owner).Result()
+			if err != nil {
+				return nil, err
+			}
+			ownerAlive = exists == 1
+			alive[d.Owner] = ownerAlive
+		}
+		if ownerAlive {
+			continue
+		}
+
+		d.Owner = string(s.nodeID)
+		data, err := json.Marshal(d)
+		if err != nil {
+			return nil, err
+		}
+		res, err := s.claimScript.Run(s.ctx, s.rc, []string{s.spoolKey}, id, value, data).Int64()
+		if err != nil {
+			return nil, err
+		}
+		if res >= 0 {
+			claimed = append(claimed, d)
+		}
+	}
+
+	sortDeliveries(claimed)
+	return claimed, nil
+}
+
+func (s *redisSpool) AddDeadLetter(d *Delivery) error {
+	data, err := json.Marshal(d)
+	if err != nil {
+		return err
+	}
+
+	tx := s.rc.TxPipeline()
+	tx.LPush(s.ctx, s.deadLetterKey, data)
+	if s.maxDeadLetters > 0 {
+		tx.LTrim(s.ctx, s.deadLetterKey, 0, int64(s.maxDeadLetters-1))
+	}
+	_, err = tx.Exec(s.ctx)
+	return err
+}
+
+func (s *redisSpool) DeadLetters() ([]*Delivery, error) {
+	values, err := s.rc.LRange(s.ctx, s.deadLetterKey, 0, -1).Result()
+	if err != nil && err != redis.Nil {
+		return nil, err
+	}
+
+	deadLetters := make([]*Delivery, 0, len(values))
+	for _, value := range values {
+		d := &Delivery{}
+		if err = json.Unmarshal([]byte(value), d); err != nil {
+			return nil, err
+		}
+		deadLetters = append(deadLetters, d)
+	}
+	sortDeliveries(deadLetters)
+	return deadLetters, nil
+}
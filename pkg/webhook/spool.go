@@ -0,0 +1,315 @@
+package webhook
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+var ErrSpoolFull = errors.New("webhook spool is full")
+
+const (
+	SpoolKindMemory = "memory"
+	SpoolKindFile   = "file"
+	SpoolKindRedis  = "redis"
+)
+
+// Delivery is a single event to be delivered to a single URL
+type Delivery struct {
+	ID  string `json:"id"`
+	URL string `json:"url"`
+	// deliveries with the same key are delivered to a URL in order, typically the room name
+	OrderingKey string `json:"ordering_key,omitempty"`
+	Event       string `json:"event"`
+	Payload     []byte `json:"payload"`
+	Attempts    int    `json:"attempts"`
+	LastError   string `json:"last_error,omitempty"`
+	// unix nanos, used to restore ordering when reloading from a spool
+	CreatedAt int64 `json:"created_at"`
+	// node currently responsible for the delivery, used by shared spools
+	Owner string `json:"owner,omitempty"`
+}
+
+// Spool keeps deliveries until they are completed, so that they survive receiver outages and restarts
+type Spool interface {
+	// Add stores a new delivery, returns ErrSpoolFull once capacity is reached
+	Add(d *Delivery) error
+	// Update stores progress of a delivery that is being retried
+	Update(d *Delivery) error
+	Remove(d *Delivery) error
+	// Claim returns deliveries not handled by any notifier, such as ones left over by a previous run.
+	// Claimed deliveries are owned by the caller from then on.
+	Claim() ([]*Delivery, error)
+
+	AddDeadLetter(d *Delivery) error
+	DeadLetters() ([]*Delivery, error)
+}
+
+func sortDeliveries(deliveries []*Delivery) {
+	sort.SliceStable(deliveries, func(i, j int) bool {
+		return deliveries[i].CreatedAt < deliveries[j].CreatedAt
+	})
+}
+
+// ---------------------------------
+
+// memorySpool bounds the number of pending deliveries, but does not persist them
+type memorySpool struct {
+	maxEvents      int
+	maxDeadLetters int
+
+	lock        sync.Mutex
+	pending     map[string]struct{}
+	deadLetters []*Delivery
+}
+
+func NewMemorySpool(maxEvents, maxDeadLetters int) Spool {
+	return &memorySpool{
+		maxEvents:      maxEvents,
+		maxDeadLetters: maxDeadLetters,
+		pending:        make(map[string]struct{}),
+	}
+}
+
+func (s *memorySpool) Add(d *Delivery) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.maxEvents > 0 && len(s.pending) >= s.maxEvents {
+		return ErrSpoolFull
+	}
+	s.pending[d.ID] = struct{}{}
+	return nil
+}
+
+func (s *memorySpool) Update(_ *Delivery) error {
+	return nil
+}
+
+func (s *memorySpool) Remove(d *Delivery) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	delete(s.pending, d.ID)
+	return nil
+}
+
+func (s *memorySpool) Claim() ([]*Delivery, error) {
+	// pending deliveries never outlive the notifier that created them
+	return nil, nil
+}
+
+func (s *memorySpool) AddDeadLetter(d *Delivery) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.deadLetters = append(s.deadLetters, d)
+	if s.maxDeadLetters > 0 && len(s.deadLetters) > s.maxDeadLetters {
+		s.deadLetters = s.deadLetters[len(s.deadLetters)-s.maxDeadLetters:]
+	}
+	return nil
+}
+
+func (s *memorySpool) DeadLetters() ([]*Delivery, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	deadLetters := make([]*Delivery, len(s.deadLetters))
+	copy(deadLetters, s.deadLetters)
+	return deadLetters, nil
+}
+
+// ---------------------------------
+
+const (
+	pendingDir    = "pending"
+	deadLetterDir = "dead"
+)
+
+// fileSpool keeps every delivery in its own file, pending deliveries and dead letters in separate directories.
+// File names start with the creation time so that a directory listing restores ordering.
+type fileSpool struct {
+	dir            string
+	maxEvents      int
+	maxDeadLetters int
+
+	lock        sync.Mutex
+	numPending  int
+	deadLetters []string
+	claimed     bool
+}
+
+func NewFileSpool(dir string, maxEvents, maxDeadLetters int) (Spool, error) {
+	s := &fileSpool{
+		dir:            dir,
+		maxEvents:      maxEvents,
+		maxDeadLetters: maxDeadLetters,
+	}
+
+	for _, d := range []string{pendingDir, deadLetterDir} {
+		if err := os.MkdirAll(filepath.Join(dir, d), 0700); err != nil {
+			return nil, err
+		}
+	}
+
+	pending, err := s.list(pendingDir)
+	if err != nil {
+		return nil, err
+	}
+	s.numPending = len(pending)
+
+	if s.deadLetters, err = s.list(deadLetterDir); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (s *fileSpool) Add(d *Delivery) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.maxEvents > 0 && s.numPending >= s.maxEvents {
+		return ErrSpoolFull
+	}
+	if err := s.write(pendingDir, d); err != nil {
+		return err
+	}
+	s.numPending++
+	return nil
+}
+
+func (s *fileSpool) Update(d *Delivery) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.write(pendingDir, d)
+}
+
+func (s *fileSpool) Remove(d *Delivery) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	err := os.Remove(filepath.Join(s.dir, pendingDir, fileName(d)))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err == nil {
+		s.numPending--
+	}
+	return nil
+}
+
+func (s *fileSpool) Claim() ([]*Delivery, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	// everything left in the directory belongs to a previous run, deliveries added since are already being handled
+	if s.claimed {
+		return nil, nil
+	}
+	s.claimed = true
+
+	names, err := s.list(pendingDir)
+	if err != nil {
+		return nil, err
+	}
+	return s.read(pendingDir, names)
+}
+
+func (s *fileSpool) AddDeadLetter(d *Delivery) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if err := s.write(deadLetterDir, d); err != nil {
+		return err
+	}
+	s.deadLetters = append(s.deadLetters, fileName(d))
+
+	for s.maxDeadLetters > 0 && len(s.deadLetters) > s.maxDeadLetters {
+		if err := os.Remove(filepath.Join(s.dir, deadLetterDir, s.deadLetters[0])); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		s.deadLetters = s.deadLetters[1:]
+	}
+	return nil
+}
+
+func (s *fileSpool) DeadLetters() ([]*Delivery, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.read(deadLetterDir, s.deadLetters)
+}
+
+func (s *fileSpool) list(dir string) ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(s.dir, dir))
+	if err != nil {
+		return nil, err
+	}
+
+	// ReadDir returns entries sorted by name
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".json") {
+			continue
+		}
+		names = append(names, e.Name())
+	}
+	return names, nil
+}
+
+func (s *fileSpool) read(dir string, names []string) ([]*Delivery, error) {
+	deliveries := make([]*Delivery, 0, len(names))
+	for _, name := range names {
+		data, err := os.ReadFile(filepath.Join(s.dir, dir, name))
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		d := &Delivery{}
+		if err = json.Unmarshal(data, d); err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+	sortDeliveries(deliveries)
+	return deliveries, nil
+}
+
+// write replaces the file atomically, so that a crash never leaves a partially written delivery
+func (s *fileSpool) write(dir string, d *Delivery) error {
+	data, err := json.Marshal(d)
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(s.dir, dir, fileName(d))
+	tmpPath := path + ".tmp"
+	f, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err = f.Write(data); err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
+
+func fileName(d *Delivery) string {
+	return fmt.Sprintf("%020d_%s.json", d.CreatedAt, d.ID)
+}